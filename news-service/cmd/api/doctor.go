@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/config"
+	"news-system/internal/ingest"
+	"news-system/internal/migrate"
+	"news-system/internal/plans"
+	"news-system/internal/repo"
+	"news-system/internal/services/llm"
+	"news-system/internal/services/news"
+	"news-system/internal/storage"
+	"news-system/migrations"
+)
+
+// doctorCheckTimeout bounds each check that talks to a dependency
+const doctorCheckTimeout = 10 * time.Second
+
+// doctorSampleCategory is queried to confirm the repository serves articles;
+// the sample data loaded by -ingest contains several
+const doctorSampleCategory = "Technology"
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+)
+
+type doctorCheck struct {
+	Name   string
+	Status checkStatus
+	Detail string
+}
+
+// doctorReport collects the outcome of every check. Warnings flag things
+// worth a look but don't fail the report.
+type doctorReport struct {
+	checks []doctorCheck
+}
+
+func (r *doctorReport) add(status checkStatus, name, format string, args ...interface{}) {
+	r.checks = append(r.checks, doctorCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (r *doctorReport) pass(name, format string, args ...interface{}) {
+	r.add(checkPass, name, format, args...)
+}
+
+func (r *doctorReport) warn(name, format string, args ...interface{}) {
+	r.add(checkWarn, name, format, args...)
+}
+
+func (r *doctorReport) fail(name, format string, args ...interface{}) {
+	r.add(checkFail, name, format, args...)
+}
+
+// OK reports whether no check failed
+func (r *doctorReport) OK() bool {
+	for _, check := range r.checks {
+		if check.Status == checkFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes one line per check followed by a summary
+func (r *doctorReport) Print(w io.Writer) {
+	counts := make(map[checkStatus]int)
+	for _, check := range r.checks {
+		fmt.Fprintf(w, "%-4s  %-24s %s\n", check.Status, check.Name, check.Detail)
+		counts[check.Status]++
+	}
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n", counts[checkPass], counts[checkWarn], counts[checkFail])
+}
+
+// runDoctor checks the configuration and every dependency the service needs,
+// without starting it. It keeps going after a failure so one run reports
+// every problem, skipping only the checks that depend on a failed one.
+func runDoctor(ctx context.Context) *doctorReport {
+	report := &doctorReport{}
+
+	cfg, err := config.Load()
+	if err != nil {
+		report.fail("config", "%v", err)
+		return report
+	}
+	report.pass("config", "loaded, %s storage backend", cfg.Database.Backend)
+
+	checkSettings(ctx, report, cfg)
+
+	redisCache := checkRedis(ctx, report, cfg)
+	if redisCache != nil {
+		defer redisCache.Close()
+	}
+
+	var repository repo.Repository
+	switch cfg.Database.Backend {
+	case "postgres":
+		db := checkPostgres(ctx, report, cfg)
+		if db != nil {
+			defer db.Close()
+			repository = repo.NewPostgresRepository(db)
+		}
+	default:
+		if redisCache != nil {
+			checkRedisIndexes(ctx, report, redisCache)
+			repository = repo.NewRepository(redisCache)
+		}
+	}
+
+	checkLLM(ctx, report, cfg)
+
+	if repository == nil {
+		report.fail("sample query", "skipped, the %s repository is unavailable", cfg.Database.Backend)
+		return report
+	}
+	checkSampleQuery(ctx, report, repository)
+	return report
+}
+
+// checkSettings validates settings that are only parsed once a feature starts
+func checkSettings(ctx context.Context, report *doctorReport, cfg *config.Config) {
+	if keys, err := plans.ParseKeys(cfg.APIPlans.Keys); err != nil {
+		report.fail("API_KEYS", "%v", err)
+	} else {
+		report.pass("API_KEYS", "%d key(s), key required: %t", len(keys), cfg.APIPlans.RequireKey)
+	}
+
+	if _, err := ingest.ParseSchedule(cfg.IngestDaemon.Schedule); err != nil {
+		report.fail("INGESTD_SCHEDULE", "%v", err)
+	} else {
+		report.pass("INGESTD_SCHEDULE", "%q", cfg.IngestDaemon.Schedule)
+	}
+
+	if server, err := newInternalServer(cfg.Internal, nil); err != nil {
+		report.fail("internal listener", "%v", err)
+	} else {
+		mode := "plaintext"
+		switch {
+		case cfg.Internal.ClientCAFile != "":
+			mode = "mTLS"
+		case server.TLSConfig != nil:
+			mode = "TLS"
+		}
+		report.pass("internal listener", "%s on %s", mode, cfg.Internal.Addr)
+	}
+
+	if cfg.Shadow.Strategy != "" {
+		known := false
+		for _, name := range news.ShadowStrategies() {
+			known = known || name == cfg.Shadow.Strategy
+		}
+		if known {
+			report.pass("SHADOW_STRATEGY", "%s for %g of queries", cfg.Shadow.Strategy, cfg.Shadow.SampleRate)
+		} else {
+			report.fail("SHADOW_STRATEGY", "unknown strategy %q: expected one of %s", cfg.Shadow.Strategy, strings.Join(news.ShadowStrategies(), ", "))
+		}
+	}
+
+	storeCtx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+	backend := cfg.ObjectStorage.Backend
+	if backend == "" {
+		backend = "local"
+	}
+	if _, err := storage.New(storeCtx, objectStorageConfig(cfg)); err != nil {
+		report.fail("object storage", "%s: %v", backend, err)
+	} else {
+		report.pass("object storage", "%s", backend)
+	}
+}
+
+// checkRedis connects to the Redis primary and replicas and confirms they
+// support every command the cache issues. It returns the primary, or nil if
+// it is unreachable.
+func checkRedis(ctx context.Context, report *doctorReport, cfg *config.Config) *cache.RedisCache {
+	opts := redisOptions(cfg)
+
+	primary := checkRedisServer(ctx, report, "redis primary", cfg.Redis.Addr, cfg, opts)
+	for i, addr := range cfg.Redis.ReplicaAddrs {
+		if replica := checkRedisServer(ctx, report, fmt.Sprintf("redis replica %d", i), addr, cfg, opts); replica != nil {
+			replica.Close()
+		}
+	}
+	return primary
+}
+
+func checkRedisServer(ctx context.Context, report *doctorReport, name, addr string, cfg *config.Config, opts cache.Options) *cache.RedisCache {
+	redisCache, err := cache.NewRedisCache(addr, cfg.Redis.Password, cfg.Redis.DB, opts)
+	if err != nil {
+		report.fail(name, "%s: %v", addr, err)
+		return nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	version, err := redisCache.ServerVersion(checkCtx)
+	if err != nil {
+		version = "unknown version"
+	}
+	missing, err := redisCache.MissingCommands(checkCtx)
+	switch {
+	case err != nil:
+		report.warn(name, "%s (%s), could not list supported commands: %v", addr, version, err)
+	case len(missing) > 0:
+		report.fail(name, "%s (%s) does not support %s", addr, version, strings.Join(missing, ", "))
+	default:
+		report.pass(name, "%s (%s)", addr, version)
+	}
+	return redisCache
+}
+
+// checkRedisIndexes confirms the Redis repository's article index exists
+func checkRedisIndexes(ctx context.Context, report *doctorReport, redisCache *cache.RedisCache) {
+	checkCtx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	exists, err := redisCache.Exists(checkCtx, "articles:all")
+	switch {
+	case err != nil:
+		report.fail("redis indexes", "%v", err)
+	case !exists:
+		report.warn("redis indexes", "no articles indexed yet; load some with -ingest or -load")
+	default:
+		report.pass("redis indexes", "articles:all present")
+	}
+}
+
+// checkPostgres connects to the Postgres primary and replicas and confirms
+// the schema is fully migrated. It returns the primary, or nil if it is
+// unreachable.
+func checkPostgres(ctx context.Context, report *doctorReport, cfg *config.Config) *repo.DB {
+	opts := postgresPoolOptions(cfg)
+
+	db, err := repo.NewDB(cfg.Database.URL, opts)
+	if err != nil {
+		report.fail("postgres primary", "%v", err)
+		return nil
+	}
+	report.pass("postgres primary", "connected")
+
+	for i, replicaURL := range cfg.Database.ReplicaURLs {
+		name := fmt.Sprintf("postgres replica %d", i)
+		replicaDB, err := repo.NewDB(replicaURL, opts)
+		if err != nil {
+			report.fail(name, "%v", err)
+			continue
+		}
+		replicaDB.Close()
+		report.pass(name, "connected")
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+	pending, err := migrate.Pending(checkCtx, cfg.Database.URL, migrations.FS)
+	switch {
+	case err != nil:
+		report.fail("postgres schema", "%v", err)
+	case len(pending) > 0:
+		names := make([]string, len(pending))
+		for i, m := range pending {
+			names[i] = m.Name
+		}
+		report.fail("postgres schema", "%d pending migration(s), run -migrate: %s", len(pending), strings.Join(names, ", "))
+	default:
+		report.pass("postgres schema", "all migrations applied")
+	}
+	return db
+}
+
+// checkLLM confirms the provider accepts the key for the configured model
+func checkLLM(ctx context.Context, report *doctorReport, cfg *config.Config) {
+	client, err := llm.NewClient(llmOptions(cfg))
+	if err != nil {
+		report.fail("llm credentials", "%v", err)
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+	if err := client.CheckCredentials(checkCtx); err != nil {
+		report.fail("llm credentials", "%v", err)
+		return
+	}
+	if cfg.LLM.Provider == llm.ProviderOllama {
+		report.pass("llm credentials", "ollama serving the model at %s", cfg.LLM.OllamaURL)
+		return
+	}
+	report.pass("llm credentials", "%s key accepted", cfg.LLM.Provider)
+}
+
+// checkSampleQuery reads articles through the repository as a request would
+func checkSampleQuery(ctx context.Context, report *doctorReport, repository repo.Repository) {
+	checkCtx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	articles, err := repository.GetArticlesByCategory(checkCtx, repo.GetArticlesByCategoryParams{
+		Name:  doctorSampleCategory,
+		Limit: 5,
+	})
+	took := time.Since(start).Round(time.Millisecond)
+	switch {
+	case err != nil:
+		report.fail("sample query", "category %s: %v", doctorSampleCategory, err)
+	case len(articles) == 0:
+		report.warn("sample query", "category %s returned no articles in %s; load the sample data with -ingest", doctorSampleCategory, took)
+	default:
+		report.pass("sample query", "category %s returned %d article(s) in %s", doctorSampleCategory, len(articles), took)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"news-system/internal/config"
+	"news-system/internal/ingest"
+)
+
+// runIngestDaemon runs scheduled ingestion until SIGINT or SIGTERM, then lets
+// a run in progress finish within the configured shutdown timeout
+func runIngestDaemon(ctx context.Context, cfg *config.Config, loader *ingest.Loader) {
+	schedule, err := ingest.ParseSchedule(cfg.IngestDaemon.Schedule)
+	if err != nil {
+		log.Fatalf("Invalid INGESTD_SCHEDULE: %v", err)
+	}
+
+	var providers []ingest.Provider
+	if cfg.NewsAPI.APIKey != "" {
+		providers = append(providers, newsAPIProvider(cfg))
+	}
+	if len(providers) == 0 && cfg.IngestDaemon.LoadPath == "" {
+		log.Fatalf("Nothing to ingest: set NEWSAPI_API_KEY or INGESTD_LOAD_PATH")
+	}
+
+	daemon := ingest.NewDaemon(loader, schedule, cfg.IngestDaemon.LoadPath, ingest.LoadOptions{}, providers...)
+	daemon.Start(ctx, cfg.IngestDaemon.RunOnStart)
+	log.Printf("Ingestion daemon running on schedule %q", cfg.IngestDaemon.Schedule)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down ingestion daemon...")
+	if err := daemon.Stop(cfg.IngestDaemon.ShutdownTimeout); err != nil {
+		log.Printf("Ingestion daemon shutdown error: %v", err)
+	}
+	log.Println("Ingestion daemon stopped")
+}
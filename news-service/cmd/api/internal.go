@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"news-system/internal/config"
+)
+
+// newInternalServer creates the server for admin and metrics routes. With a
+// certificate it serves TLS, and with a client CA it only accepts callers
+// presenting a certificate that CA signed.
+func newInternalServer(cfg config.InternalServerConfig, handler http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      handler,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	if cfg.TLSCertFile == "" {
+		return server, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load internal TLS certificate: %w", err)
+	}
+	server.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read internal client CA: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in internal client CA file %s", cfg.ClientCAFile)
+		}
+		server.TLSConfig.ClientCAs = clientCAs
+		server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return server, nil
+}
+
+// serveInternal runs the internal server until it is shut down
+func serveInternal(server *http.Server) {
+	mode := "plaintext"
+	switch {
+	case server.TLSConfig != nil && server.TLSConfig.ClientAuth == tls.RequireAndVerifyClientCert:
+		mode = "mTLS"
+	case server.TLSConfig != nil:
+		mode = "TLS"
+	}
+	log.Printf("Starting internal server on %s (%s)", server.Addr, mode)
+
+	var err error
+	if server.TLSConfig != nil {
+		// The certificate is already loaded into TLSConfig
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Failed to start internal server: %v", err)
+	}
+}
@@ -3,31 +3,121 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
+	// Embedded zone database, so ?tz= resolves on images without one
+	_ "time/tzdata"
 
+	"news-system/internal/bus"
 	"news-system/internal/cache"
 	"news-system/internal/config"
+	"news-system/internal/eventqueue"
 	httphandler "news-system/internal/http"
 	"news-system/internal/ingest"
+	"news-system/internal/jobqueue"
+	"news-system/internal/metrics"
+	"news-system/internal/middleware"
+	"news-system/internal/migrate"
+	"news-system/internal/plans"
 	"news-system/internal/repo"
+	"news-system/internal/services/categorize"
+	"news-system/internal/services/embeddings"
+	"news-system/internal/services/entities"
+	"news-system/internal/services/geocode"
 	"news-system/internal/services/llm"
+	"news-system/internal/services/moderation"
 	"news-system/internal/services/news"
+	"news-system/internal/services/queryaudit"
+	"news-system/internal/services/readers"
+	"news-system/internal/services/searchtrends"
+	"news-system/internal/services/shadow"
+	"news-system/internal/services/summaries"
 	"news-system/internal/services/trending"
+	"news-system/internal/storage"
+	"news-system/internal/subscriptions"
+	"news-system/internal/version"
+	"news-system/internal/webhooks"
+	"news-system/migrations"
+
+	zlog "github.com/rs/zerolog/log"
 )
 
 func main() {
 	// Parse command line flags
 	var (
 		ingestData = flag.Bool("ingest", false, "Load sample data into the database")
+		loadPath   = flag.String("load", "", "Load articles from a JSON, NDJSON or CSV file, or a directory of them, and exit")
+		batchSize  = flag.Int("batch-size", 500, "With -load or -synthetic, articles written per bulk upsert (-load: NDJSON and CSV files)")
+		csvMapping = flag.String("csv-mapping", "", "With -load, JSON file mapping CSV columns to article fields")
+		dryRun     = flag.Bool("ingest-dry-run", false, "With -load, validate the files and print a summary without writing anything")
+		synthetic  = flag.Int("synthetic", 0, "Generate this many synthetic articles into the database for load tests and exit")
+		synthSeed  = flag.Int64("synthetic-seed", 0, "With -synthetic, seed for a reproducible data set (0 picks one)")
+		runMigrate = flag.Bool("migrate", false, "Apply pending database migrations and exit")
+		exportDir  = flag.String("export", "", "Export all articles with content hashes to this directory and exit")
+		importDir  = flag.String("import", "", "Verify and import an export directory and exit")
+		partial    = flag.Bool("import-partial", false, "With -import, load the intact records even if some fail verification")
+		doctor     = flag.Bool("doctor", false, "Check configuration and every dependency, print a pass/fail report and exit")
 		port       = flag.String("port", "8080", "Port to run the server on")
+		showVer    = flag.Bool("version", false, "Print the build version and exit")
+		mode       = flag.String("mode", "api", "Process to run: \"api\" serves HTTP, \"ingestd\" only runs scheduled ingestion")
 	)
 	flag.Parse()
 
+	build := version.Get()
+	if *showVer {
+		fmt.Printf("news-service %s (commit %s, built %s, %s)\n", build.Version, build.Commit, build.BuildDate, build.GoVersion)
+		return
+	}
+	// Tag every structured log line and the build info metric with the running build
+	zlog.Logger = zlog.With().Str("version", build.Version).Logger()
+	metrics.BuildInfo.WithLabelValues(build.Version, build.Commit, build.BuildDate, build.GoVersion).Set(1)
+	log.Printf("news-service %s (commit %s, built %s)", build.Version, build.Commit, build.BuildDate)
+
+	if *mode != "api" && *mode != "ingestd" {
+		log.Fatalf("-mode must be \"api\" or \"ingestd\", got %q", *mode)
+	}
+
+	// A dry run only parses files, so it needs neither configuration nor connections
+	if *dryRun {
+		if *loadPath == "" {
+			log.Fatalf("-ingest-dry-run requires -load")
+		}
+		opts := ingest.LoadOptions{}
+		if *csvMapping != "" {
+			mapping, err := ingest.LoadCSVMapping(*csvMapping)
+			if err != nil {
+				log.Fatalf("Failed to load CSV mapping: %v", err)
+			}
+			opts.CSV = mapping
+		}
+		report, err := ingest.NewLoader(nil, nil).Validate(context.Background(), *loadPath, opts)
+		if err != nil {
+			log.Fatalf("Failed to validate %s: %v", *loadPath, err)
+		}
+		report.Print(os.Stdout)
+		if !report.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The doctor reports configuration errors instead of exiting on the first one
+	if *doctor {
+		report := runDoctor(context.Background())
+		report.Print(os.Stdout)
+		if !report.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -38,35 +128,248 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize database
-	db, err := repo.NewDB(cfg.Database.URL)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	// If migrate flag is set, bring the Postgres schema up to date and exit
+	if *runMigrate {
+		applied, err := migrate.Up(ctx, cfg.Database.URL, migrations.FS)
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		log.Printf("Applied %d migration(s)", applied)
+		return
 	}
-	// Note: db.Close() is not needed for mock DB
 
-	// Initialize repository
-	repository := repo.NewRepository(db)
+	// Connection pool and timeout settings shared by primaries and replicas
+	dbPool := postgresPoolOptions(cfg)
+	redisOpts := redisOptions(cfg)
 
 	// Initialize Redis cache
-	redisCache, err := cache.NewRedisCache(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB, redisOpts)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	defer redisCache.Close()
+	registerRedisPoolMetrics("primary", redisCache)
+
+	// Connect Redis replicas, which serve repository reads and hedged hot reads
+	var redisReplicas []*cache.RedisCache
+	for i, replicaAddr := range cfg.Redis.ReplicaAddrs {
+		replicaCache, err := cache.NewRedisCache(replicaAddr, cfg.Redis.Password, cfg.Redis.DB, redisOpts)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis replica %s: %v", replicaAddr, err)
+		}
+		defer replicaCache.Close()
+		registerRedisPoolMetrics(fmt.Sprintf("replica-%d", i), replicaCache)
+		redisReplicas = append(redisReplicas, replicaCache)
+	}
+	redisCache.EnableHedging(redisReplicas, cfg.Redis.HedgeDelay)
+
+	// Initialize repository, routing reads to replicas when configured
+	var repository repo.Repository
+	var replicas []repo.ReadRepository
+	var urlFilter *repo.URLFilter
+	switch cfg.Database.Backend {
+	case "postgres":
+		db, err := repo.NewDB(cfg.Database.URL, dbPool)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		registerPostgresPoolMetrics("primary", db)
+
+		partitions := repo.NewPartitionMaintainer(db, cfg.Database.PartitionMonthsAhead)
+		partitions.Start(ctx, cfg.Database.PartitionCheckInterval)
+		defer partitions.Stop()
+
+		// Roll user events up into hourly aggregates and prune them past retention
+		if cfg.EventRetention.DownsampleInterval > 0 {
+			downsampler := repo.NewEventDownsampler(db, repo.EventDownsamplerOptions{
+				Precision:       slices.Max(append([]int{trending.DefaultPrecision}, cfg.Trending.Precisions...)),
+				RawRetention:    cfg.EventRetention.RawRetention,
+				RollupRetention: cfg.EventRetention.RollupRetention,
+			})
+			downsampler.Start(ctx, cfg.EventRetention.DownsampleInterval)
+			defer downsampler.Stop()
+		}
+
+		for i, replicaURL := range cfg.Database.ReplicaURLs {
+			replicaDB, err := repo.NewDB(replicaURL, dbPool)
+			if err != nil {
+				log.Fatalf("Failed to connect to database replica: %v", err)
+			}
+			defer replicaDB.Close()
+			registerPostgresPoolMetrics(fmt.Sprintf("replica-%d", i), replicaDB)
+			replicas = append(replicas, repo.NewPostgresRepository(replicaDB))
+		}
+		repository = repo.NewSplitRepository(repo.NewPostgresRepository(db), replicas...)
+	default:
+		for _, replicaCache := range redisReplicas {
+			replicas = append(replicas, repo.NewRepository(replicaCache))
+		}
+		primary := repo.NewRepository(redisCache)
+		if cfg.URLFilter.Capacity > 0 {
+			urlFilter = repo.NewURLFilter(redisCache, cfg.URLFilter.Capacity, cfg.URLFilter.FalsePositiveRate)
+			urlFilter.Start(ctx, cfg.URLFilter.RebuildInterval)
+			defer urlFilter.Stop()
+			primary = repo.NewRepositoryWithURLFilter(redisCache, urlFilter)
+		}
+		repository = repo.NewSplitRepository(primary, replicas...)
+	}
+	repository = repo.NewTimeoutRepository(repository, cfg.Database.OperationTimeout)
+	log.Printf("Using %s repository with %d read replica(s)", cfg.Database.Backend, len(replicas))
+
+	// Initialize object storage for large artifacts
+	objectStore, err := storage.New(ctx, objectStorageConfig(cfg))
+	if err != nil {
+		log.Fatalf("Failed to initialize object storage: %v", err)
+	}
 
 	// Initialize LLM client
-	llmClient, err := llm.NewOpenAIClient(cfg.OpenAI.APIKey, cfg.OpenAI.Model)
+	llmOpts := llmOptions(cfg)
+	llmClient, err := llm.NewClient(llmOpts)
 	if err != nil {
 		log.Fatalf("Failed to create LLM client: %v", err)
 	}
+	// A local model server is usually started alongside the service, so give it time
+	if cfg.LLM.Provider == llm.ProviderOllama {
+		if err := waitForLLM(ctx, llmClient, cfg.LLM.OllamaStartupTimeout); err != nil {
+			log.Fatalf("Ollama is not ready: %v", err)
+		}
+		log.Printf("Ollama at %s is serving the configured model", cfg.LLM.OllamaURL)
+	}
+	// Count tokens beneath the retries, so every attempt is counted, and
+	// beneath the cache, so cached extractions cost nothing
+	llmUsage := llm.NewUsageTracker(llmClient, llm.Pricing{
+		PromptPerMillion:     cfg.LLM.PromptTokenPrice,
+		CompletionPerMillion: cfg.LLM.CompletionTokenPrice,
+		EmbeddingPerMillion:  cfg.LLM.EmbeddingTokenPrice,
+	})
+	llmClient = llmUsage
+	// Retry transient failures and fail fast through an outage; the cache
+	// wraps this, so cached extractions are served even while the circuit is open
+	llmClient = llm.NewResilientClient(llmClient, llm.ResilienceOptions{
+		MaxRetries:       cfg.LLM.MaxRetries,
+		RetryBaseDelay:   cfg.LLM.RetryBaseDelay,
+		SummaryTimeout:   cfg.LLM.SummaryTimeout,
+		FailureThreshold: cfg.LLM.CircuitFailureThreshold,
+		OpenDuration:     cfg.LLM.CircuitOpenDuration,
+	})
+	if cfg.LLM.ExtractCacheTTL > 0 {
+		llmClient = llm.NewCachedClient(llmClient, redisCache, cfg.LLM.ExtractCacheTTL, llmOpts.Provider+"|"+llmOpts.Model)
+	}
+
+	// Initialize the domain event bus, relayed across instances over Redis when configured
+	events := bus.New("")
+	defer events.Close()
+	if cfg.Events.RedisChannel != "" {
+		bridge := bus.NewRedisBridge(events, redisCache, cfg.Events.RedisChannel)
+		bridge.Start(ctx)
+		defer bridge.Stop()
+	}
+
+	// Keep the URL filter current with articles stored by other instances
+	if urlFilter != nil {
+		unsubscribe := events.Subscribe("url-filter", func(_ context.Context, event bus.Event) {
+			var payload bus.ArticlePayload
+			if err := event.Decode(&payload); err == nil && payload.URL != "" {
+				urlFilter.Add(repo.URLHash(payload.URL))
+			}
+		}, bus.ArticleCreated, bus.ArticleUpdated)
+		defer unsubscribe()
+	}
 
 	// Initialize services
-	newsService := news.NewNewsService(repository, redisCache, llmClient)
-	trendingScorer := trending.NewTrendingScorer(repository, redisCache)
+	readerCounter := readers.NewCounter(redisCache, cfg.Trending.Precisions)
+	newsService := news.NewNewsService(repository, redisCache, llmClient, events, readerCounter)
+	newsService.SetRankingWeights(news.RankingWeights{
+		Text:            cfg.Ranking.TextWeight,
+		Semantic:        cfg.Ranking.SemanticWeight,
+		Relevance:       cfg.Ranking.RelevanceWeight,
+		Recency:         cfg.Ranking.RecencyWeight,
+		RecencyHalfLife: cfg.Ranking.RecencyHalfLife,
+	})
+	trendingScorer := trending.NewTrendingScorer(repository, redisCache, events, cfg.Trending.Precisions, readerCounter)
+	if cfg.Trending.UniqueReaders {
+		trendingScorer.EnableUniqueReaderWeighting()
+	}
+	if cfg.Trending.NeighborTiles {
+		trendingScorer.EnableNeighborTiles()
+	}
+	trendingScorer.EnableEventFilter(trending.EventFilterOptions{
+		DedupWindow:        cfg.Trending.EventDedupWindow,
+		MaxEventsPerMinute: cfg.Trending.BotMaxEventsPerMinute,
+		BotBlockDuration:   cfg.Trending.BotBlockDuration,
+	})
+	if cfg.Trending.FullRecomputeInterval > 0 {
+		trendingScorer.EnableIncrementalUpdates(cfg.Trending.FullRecomputeInterval)
+	}
+	var hotQueries *searchtrends.HotQueries
+	if cfg.SearchTrends.HotWindow > 0 {
+		hotQueries = searchtrends.NewHotQueries(cfg.SearchTrends.HotWindow, cfg.SearchTrends.HotThreshold, cfg.SearchTrends.HotTopK)
+	}
+	searchTrends := searchtrends.NewTracker(redisCache, cfg.SearchTrends.Window, cfg.SearchTrends.RegionPrecision, hotQueries)
+	if hotQueries != nil {
+		newsService.EnableHotQueryCache(searchTrends)
+	}
+	var queryAudit *queryaudit.Log
+	if cfg.QueryAudit.SampleRate > 0 {
+		queryAudit = queryaudit.NewLog(redisCache, cfg.QueryAudit.SampleRate, cfg.QueryAudit.Retention, cfg.QueryAudit.MaxEntries)
+		newsService.EnableQueryAudit(queryAudit)
+	}
+	var shadowLog *shadow.Log
+	if cfg.Shadow.Strategy != "" {
+		shadowLog = shadow.NewLog(redisCache, cfg.Shadow.MaxResults)
+		if err := newsService.EnableShadowStrategy(cfg.Shadow.Strategy, cfg.Shadow.SampleRate, cfg.Shadow.MaxInFlight, shadowLog); err != nil {
+			log.Fatalf("Invalid SHADOW_STRATEGY: %v", err)
+		}
+		log.Printf("Shadow strategy %s runs for %g of queries", cfg.Shadow.Strategy, cfg.Shadow.SampleRate)
+	}
+	moderationQueue := moderation.NewQueue(redisCache, moderation.Options{
+		Threshold:       cfg.Moderation.FlagThreshold,
+		AutoDemote:      cfg.Moderation.AutoDemote,
+		AuditMaxEntries: cfg.Moderation.AuditMaxEntries,
+	})
+	newsService.EnableModeration(moderationQueue)
+	if cfg.Moderation.Screening {
+		unsubscribe := moderation.NewScreener(moderationQueue, repository, cfg.Moderation.Blocklist).Subscribe(events)
+		defer unsubscribe()
+	}
+	if cfg.LLM.Sentiment {
+		newsService.EnableSentiment()
+	}
+	semanticSearch := cfg.SemanticSearch.MinResults > 0 && llmClient.EmbeddingModel() != ""
+	if semanticSearch {
+		newsService.EnableSemanticSearch(llmClient.EmbeddingModel(), cfg.SemanticSearch.MinResults, cfg.SemanticSearch.MinSimilarity)
+	} else if cfg.SemanticSearch.MinResults > 0 {
+		log.Printf("Semantic search is unavailable: the %s provider has no embedding model configured", cfg.LLM.Provider)
+	}
+	var geocoder geocode.Geocoder
+	if cfg.Geocoder.URL != "" {
+		geocoder = geocode.NewCachedGeocoder(geocode.NewNominatimGeocoder(cfg.Geocoder.URL, cfg.Geocoder.Timeout), redisCache)
+		newsService.EnableNearbyExpansion(geocoder, cfg.Geocoder.MaxRadiusKm)
+		newsService.EnablePlaceResolution(geocoder, cfg.Geocoder.AmbiguityRatio)
+	}
+	if cfg.RadiusExpansion.MaxRadiusKm > 0 {
+		newsService.EnableRadiusExpansion(cfg.RadiusExpansion.Factor, cfg.RadiusExpansion.MaxRadiusKm)
+	}
+	if cfg.UnlocatedMix.Policy != news.UnlocatedMixOff {
+		newsService.EnableUnlocatedMix(cfg.UnlocatedMix.Policy, cfg.UnlocatedMix.MinResults, cfg.UnlocatedMix.MinRelevance)
+	}
+	if cfg.Archive.HotWindow > 0 {
+		newsService.EnableArchiveSearch(cfg.Archive.HotWindow)
+	}
 
 	// Initialize ingestion loader
-	loader := ingest.NewLoader(repository)
+	loader := ingest.NewLoader(repository, events)
+	if cfg.Geocoder.ArticlePlaces {
+		tagger := geocode.NewTagger(geocoder)
+		loader.EnableGeocoding(tagger)
+		newsService.EnableGeocoding(tagger)
+	}
+	if cfg.LLM.Categorize {
+		classifier := categorize.NewClassifier(llmClient, cfg.LLM.CategorizeConcurrency)
+		loader.EnableCategorization(classifier)
+		newsService.EnableCategorization(classifier)
+	}
 
 	// If ingest flag is set, load sample data and exit
 	if *ingestData {
@@ -78,10 +381,233 @@ func main() {
 		return
 	}
 
+	// If a load path is given, ingest it and exit
+	if *loadPath != "" {
+		opts := ingest.LoadOptions{BatchSize: *batchSize}
+		if *csvMapping != "" {
+			if opts.CSV, err = ingest.LoadCSVMapping(*csvMapping); err != nil {
+				log.Fatalf("Failed to load CSV mapping: %v", err)
+			}
+		}
+		if err := loader.LoadPath(ctx, *loadPath, opts); err != nil {
+			log.Fatalf("Failed to load %s: %v", *loadPath, err)
+		}
+		return
+	}
+
+	// Synthetic articles aren't announced: millions of events would flood the
+	// bus and every subscriber
+	if *synthetic > 0 {
+		_, err := ingest.NewLoader(repository, nil).GenerateSynthetic(ctx, ingest.SyntheticOptions{
+			Count: *synthetic,
+			Seed:  *synthSeed,
+			Load:  ingest.LoadOptions{BatchSize: *batchSize},
+		})
+		if err != nil {
+			log.Fatalf("Failed to generate synthetic articles: %v", err)
+		}
+		return
+	}
+
+	// If export or import is requested, move the corpus and exit
+	if *exportDir != "" {
+		if _, err := loader.Export(ctx, *exportDir); err != nil {
+			log.Fatalf("Failed to export articles: %v", err)
+		}
+		return
+	}
+	if *importDir != "" {
+		report, err := loader.Import(ctx, *importDir, *partial)
+		if report != nil {
+			for _, fileErr := range report.FileErrors {
+				log.Printf("Corrupt file: %s", fileErr)
+			}
+			for _, record := range report.Corrupt {
+				log.Printf("Corrupt record %s[%d] (id=%s url=%s): %s", record.File, record.Index, record.ID, record.URL, record.Reason)
+			}
+			log.Printf("Verified %d records, %d corrupt; created %d, updated %d, failed %d",
+				report.Verified, len(report.Corrupt), report.Created, report.Updated, report.Failed)
+		}
+		if err != nil {
+			log.Fatalf("Failed to import %s: %v", *importDir, err)
+		}
+		return
+	}
+
+	// In ingestd mode only the ingestion pipeline runs, on its schedule
+	if *mode == "ingestd" {
+		runIngestDaemon(ctx, cfg, loader)
+		return
+	}
+
+	// Drop tiles keyed by the old geohash before the warm-up writes new ones
+	if deleted, err := cache.MigrateGeohashKeys(ctx, redisCache, searchtrends.GlobalRegion); err != nil {
+		log.Printf("Failed to migrate geohash keys, stale tiles expire with their TTL: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Deleted %d key(s) of the old geohash tiling", deleted)
+	}
+
+	// Rebuild trending state from stored events before serving /trending
+	if err := trendingScorer.WarmUp(ctx, cfg.Trending.WarmUpTimeout); err != nil {
+		log.Printf("Trending warm-up failed, tiles will fill on the next tick: %v", err)
+	}
+
 	// Start trending scorer
 	trendingScorer.Start(ctx, cfg.Trending.WorkerInterval)
 	defer trendingScorer.Stop()
 
+	// Consume user events from Kafka or NATS when configured
+	if cfg.EventQueue.Backend != "" {
+		var source eventqueue.Source
+		switch cfg.EventQueue.Backend {
+		case "kafka":
+			source = eventqueue.NewKafkaSource(cfg.EventQueue.KafkaBrokers, cfg.EventQueue.Topic, cfg.EventQueue.Group)
+		case "nats":
+			if source, err = eventqueue.NewNATSSource(cfg.EventQueue.NATSURL, cfg.EventQueue.Topic, cfg.EventQueue.Group); err != nil {
+				log.Fatalf("Failed to start event queue consumer: %v", err)
+			}
+		}
+		consumer := eventqueue.NewConsumer(source, trendingScorer, eventqueue.Options{Retries: cfg.EventQueue.Retries})
+		consumer.Start(ctx)
+		defer consumer.Stop()
+	}
+
+	// Deliver domain events to webhook endpoints
+	if len(cfg.Webhooks.URLs) > 0 {
+		var types []bus.EventType
+		for _, t := range cfg.Webhooks.EventTypes {
+			types = append(types, bus.EventType(t))
+		}
+		dispatcher := webhooks.NewDispatcher(events, redisCache, cfg.Webhooks.URLs, webhooks.Options{
+			Secret:           cfg.Webhooks.Secret,
+			Types:            types,
+			Timeout:          cfg.Webhooks.Timeout,
+			MaxAttempts:      cfg.Webhooks.MaxAttempts,
+			InitialBackoff:   cfg.Webhooks.InitialBackoff,
+			BreakerThreshold: cfg.Webhooks.BreakerThreshold,
+			BreakerCooldown:  cfg.Webhooks.BreakerCooldown,
+		})
+		dispatcher.Start(ctx)
+		defer dispatcher.Stop()
+	}
+
+	// Run export jobs, resuming any left unfinished by a previous instance
+	exportJobs := ingest.NewExportJobs(loader, redisCache, objectStore, cfg.ExportJobs.RowsPerSecond, cfg.ObjectStorage.URLExpiry)
+	exportJobs.Start(ctx)
+	defer exportJobs.Stop()
+
+	// Poll NewsAPI for live articles when a key is configured, unless an ingestion daemon does
+	if cfg.NewsAPI.APIKey != "" && cfg.NewsAPI.PollInterval > 0 {
+		poller := ingest.NewPoller(loader, newsAPIProvider(cfg))
+		poller.Start(ctx, cfg.NewsAPI.PollInterval)
+		defer poller.Stop()
+	}
+
+	// Write sampled query audit entries
+	if queryAudit != nil {
+		queryAudit.Start(ctx)
+		defer queryAudit.Stop()
+	}
+
+	// Start search trend detection
+	searchTrends.Start(ctx, cfg.SearchTrends.WorkerInterval)
+	defer searchTrends.Stop()
+
+	// Summarize articles nobody has queried yet, so results rarely wait on the LLM
+	if cfg.Backfill.Rate > 0 {
+		backfill := summaries.NewBackfiller(repository, newsService, summaries.Options{
+			Rate:         cfg.Backfill.Rate,
+			Concurrency:  cfg.Backfill.Concurrency,
+			BatchSize:    cfg.Backfill.BatchSize,
+			IdleInterval: cfg.Backfill.IdleInterval,
+			RetryAfter:   cfg.Backfill.RetryAfter,
+		})
+		backfill.Start(llm.WithEndpoint(ctx, "summary-backfill"))
+		defer backfill.Stop()
+	}
+
+	// Embed articles for semantic search as they arrive
+	if semanticSearch {
+		embedder := embeddings.NewBackfiller(repository, llmClient, embeddings.Options{
+			BatchSize:    cfg.SemanticSearch.BatchSize,
+			IdleInterval: cfg.SemanticSearch.IdleInterval,
+		})
+		embedder.Start(llm.WithEndpoint(ctx, "embedding-backfill"))
+		defer embedder.Stop()
+	}
+
+	// Extract the entities articles mention for the entities filter
+	if cfg.Entities.Enabled {
+		extractor := entities.NewBackfiller(repository, llmClient, entities.Options{
+			BatchSize:    cfg.Entities.BatchSize,
+			Concurrency:  cfg.Entities.Concurrency,
+			IdleInterval: cfg.Entities.IdleInterval,
+		})
+		extractor.Start(llm.WithEndpoint(ctx, "entity-backfill"))
+		defer extractor.Stop()
+	}
+
+	// Place the articles stored before geocoding was enabled
+	if cfg.Geocoder.ArticlePlaces {
+		placer := geocode.NewBackfiller(repository, geocoder, geocode.BackfillOptions{
+			BatchSize:    cfg.Geocoder.BackfillBatchSize,
+			IdleInterval: cfg.Geocoder.BackfillIdleInterval,
+		})
+		placer.Start(ctx)
+		defer placer.Stop()
+	}
+
+	// Manage digest and alert subscriptions, delivering them through the configured channels
+	var calendars map[string]*ingest.Calendar
+	if cfg.Subscriptions.CalendarsPath != "" {
+		if calendars, err = ingest.LoadCalendars(cfg.Subscriptions.CalendarsPath); err != nil {
+			log.Fatalf("Failed to load subscription calendars: %v", err)
+		}
+	}
+	senders := map[string]subscriptions.Sender{
+		repo.ChannelWebhook: subscriptions.NewWebhookSender(cfg.Subscriptions.DeliveryTimeout),
+	}
+	if cfg.Subscriptions.SMTPAddr != "" {
+		senders[repo.ChannelEmail] = subscriptions.NewEmailSender(cfg.Subscriptions.SMTPAddr, cfg.Subscriptions.SMTPFrom,
+			cfg.Subscriptions.SMTPUsername, cfg.Subscriptions.SMTPPassword)
+	}
+	if cfg.Subscriptions.PushGatewayURL != "" {
+		senders[repo.ChannelPush] = subscriptions.NewPushSender(cfg.Subscriptions.PushGatewayURL, cfg.Subscriptions.DeliveryTimeout)
+	}
+	subscriptionService := subscriptions.NewService(repository, senders, calendars, subscriptions.Options{
+		PublicURL:    cfg.Subscriptions.PublicURL,
+		ImageURL:     cfg.Subscriptions.ImageURL,
+		Attempts:     cfg.Subscriptions.DeliveryAttempts,
+		RetryBackoff: cfg.Subscriptions.RetryBackoff,
+		DisableAfter: cfg.Subscriptions.DisableAfter,
+	})
+	if cfg.Subscriptions.DeliveryInterval > 0 {
+		deliverer := subscriptions.NewDeliverer(subscriptionService, events, redisCache, subscriptions.DelivererOptions{
+			Interval:   cfg.Subscriptions.DeliveryInterval,
+			Timeout:    cfg.Subscriptions.DeliveryTimeout,
+			DigestSize: cfg.Subscriptions.DigestSize,
+		})
+		deliverer.Start(ctx)
+		defer deliverer.Stop()
+	}
+
+	// Work background jobs off Redis Streams; with no concurrency this
+	// instance only enqueues them, for other instances to work
+	jobQueues := jobqueue.New(redisCache, jobqueue.Options{
+		VisibilityTimeout: cfg.JobQueue.VisibilityTimeout,
+		MaxAttempts:       cfg.JobQueue.MaxAttempts,
+		RetryBackoff:      cfg.JobQueue.RetryBackoff,
+		MaxLen:            int64(cfg.JobQueue.MaxLen),
+	})
+	jobQueues.Register(news.SummaryQueue, cfg.JobQueue.Concurrency, newsService.RunSummaryJob)
+	if err := metrics.RegisterJobQueues(jobQueues.Depths); err != nil {
+		log.Printf("Failed to register job queue metrics: %v", err)
+	}
+	if cfg.JobQueue.Concurrency > 0 {
+		jobQueues.Start(ctx)
+		defer jobQueues.Stop()
+	}
+
 	// Simulate some user events for trending
 	go func() {
 		time.Sleep(2 * time.Second) // Wait for services to be ready
@@ -90,14 +616,65 @@ func main() {
 		}
 	}()
 
-	// Initialize HTTP router
-	router := httphandler.NewRouter()
-	
+	// Initialize HTTP router, enforcing the plan of each API key
+	if err := plans.SetSummaryDefaults(cfg.APIPlans.SummaryDefaults); err != nil {
+		log.Fatalf("Invalid API_PLAN_SUMMARY_DEFAULTS: %v", err)
+	}
+	apiKeys, err := plans.ParseKeys(cfg.APIPlans.Keys)
+	if err != nil {
+		log.Fatalf("Invalid API_KEYS: %v", err)
+	}
+	router := httphandler.NewRouter(middleware.NewPlanEnforcer(apiKeys, cfg.APIPlans.RequireKey))
+
 	// Register routes
-	newsHandler := httphandler.NewNewsHandler(newsService)
+	newsHandler := httphandler.NewNewsHandler(newsService, trendingScorer, searchTrends, events)
 	router.RegisterNewsRoutes(newsHandler)
+	router.RegisterAccountRoutes(httphandler.NewAccountHandler())
+	router.RegisterFeedbackRoutes(httphandler.NewFeedbackHandler(newsService))
+	router.RegisterEventRoutes(httphandler.NewEventHandler(trendingScorer))
+	router.RegisterReportRoutes(httphandler.NewReportHandler(newsService),
+		middleware.NewClientLimit("reports", cfg.Moderation.ReportsPerMinute, cfg.Moderation.ReportBurst))
+	subscriptionHandler := httphandler.NewSubscriptionHandler(subscriptionService)
+	router.RegisterSubscriptionRoutes(subscriptionHandler)
+	router.RegisterUnsubscribeRoutes(subscriptionHandler)
+	if cfg.IngestWebhook.Secret != "" {
+		router.RegisterIngestRoutes(httphandler.NewIngestHandler(loader, redisCache, cfg.IngestWebhook.Secret, cfg.IngestWebhook.Tolerance))
+	}
 	router.RegisterHealthRoutes()
-	router.RegisterMetricsRoutes()
+	router.RegisterVersionRoutes()
+
+	// Operational routes live on a separate listener that is never exposed through the public ingress
+	internalRouter := httphandler.NewInternalRouter()
+	adminHandler := httphandler.NewAdminHandler(exportJobs)
+	adminHandler.EnableLLMUsage(llmUsage)
+	adminHandler.EnableSummaryRegeneration(newsService)
+	adminHandler.EnableFeedbackReport(newsService)
+	adminHandler.EnableModeration(moderationQueue, newsService)
+	adminHandler.EnableDeliveries(subscriptionService)
+	adminHandler.EnableJobQueues(jobQueues)
+	if queryAudit != nil {
+		adminHandler.EnableQueryAudit(queryAudit)
+	}
+	if shadowLog != nil {
+		adminHandler.EnableShadowResults(shadowLog)
+	}
+	internalRouter.RegisterAdminRoutes(adminHandler)
+	internalRouter.RegisterHealthRoutes()
+	internalRouter.RegisterVersionRoutes()
+	internalRouter.RegisterMetricsRoutes()
+	internalServer, err := newInternalServer(cfg.Internal, internalRouter)
+	if err != nil {
+		log.Fatalf("Failed to configure internal server: %v", err)
+	}
+
+	// Local object storage serves its own signed download links
+	if localStore, ok := objectStore.(*storage.LocalStore); ok {
+		publicURL, err := url.Parse(cfg.ObjectStorage.PublicURL)
+		if err != nil {
+			log.Fatalf("Invalid OBJECT_STORAGE_PUBLIC_URL: %v", err)
+		}
+		router.RegisterFileRoutes(publicURL.Path, localStore.Handler())
+	}
 
 	// Create HTTP server
 	server := &http.Server{
@@ -116,6 +693,8 @@ func main() {
 		}
 	}()
 
+	go serveInternal(internalServer)
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -131,6 +710,127 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Server shutdown error: %v", err)
 	}
+	if err := internalServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Internal server shutdown error: %v", err)
+	}
 
 	log.Println("Server stopped")
 }
+
+// newsAPIProvider creates the NewsAPI provider from configuration
+func newsAPIProvider(cfg *config.Config) *ingest.NewsAPIProvider {
+	return ingest.NewNewsAPIProvider(ingest.NewsAPIOptions{
+		APIKey:            cfg.NewsAPI.APIKey,
+		BaseURL:           cfg.NewsAPI.BaseURL,
+		Country:           cfg.NewsAPI.Country,
+		Categories:        cfg.NewsAPI.Categories,
+		Sources:           cfg.NewsAPI.Sources,
+		Query:             cfg.NewsAPI.Query,
+		Language:          cfg.NewsAPI.Language,
+		Domains:           cfg.NewsAPI.Domains,
+		MaxPages:          cfg.NewsAPI.MaxPages,
+		RequestsPerMinute: cfg.NewsAPI.RequestsPerMinute,
+	})
+}
+
+// postgresPoolOptions returns the pool settings shared by the Postgres primary and replicas
+func postgresPoolOptions(cfg *config.Config) repo.PoolOptions {
+	return repo.PoolOptions{
+		MaxConns:        int32(cfg.Database.MaxConns),
+		MinConns:        int32(cfg.Database.MinConns),
+		MaxConnLifetime: cfg.Database.MaxConnLifetime,
+		MaxConnIdleTime: cfg.Database.MaxConnIdleTime,
+		ConnectTimeout:  cfg.Database.ConnectTimeout,
+		AcquireTimeout:  cfg.Database.AcquireTimeout,
+	}
+}
+
+// redisOptions returns the client settings shared by the Redis primary and replicas
+func redisOptions(cfg *config.Config) cache.Options {
+	return cache.Options{
+		PoolSize:        cfg.Redis.PoolSize,
+		MinIdleConns:    cfg.Redis.MinIdleConns,
+		ConnMaxIdleTime: cfg.Redis.ConnMaxIdleTime,
+		ConnMaxLifetime: cfg.Redis.ConnMaxLifetime,
+		DialTimeout:     cfg.Redis.DialTimeout,
+		PoolTimeout:     cfg.Redis.PoolTimeout,
+
+		OperationTimeout: cfg.Redis.OperationTimeout,
+		Namespace:        cfg.Redis.KeyNamespace,
+		Codec:            cache.Codecs[cfg.Redis.Codec],
+	}
+}
+
+// objectStorageConfig returns the object storage settings from configuration
+func objectStorageConfig(cfg *config.Config) storage.Config {
+	return storage.Config{
+		Backend:    cfg.ObjectStorage.Backend,
+		Bucket:     cfg.ObjectStorage.Bucket,
+		Prefix:     cfg.ObjectStorage.Prefix,
+		LocalDir:   cfg.ObjectStorage.LocalDir,
+		PublicURL:  cfg.ObjectStorage.PublicURL,
+		SigningKey: cfg.ObjectStorage.SigningKey,
+		S3Region:   cfg.ObjectStorage.S3Region,
+		S3Endpoint: cfg.ObjectStorage.S3Endpoint,
+	}
+}
+
+// waitForLLM checks the LLM client every few seconds until it passes or timeout elapses
+func waitForLLM(ctx context.Context, client llm.Client, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		err := client.CheckCredentials(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Waiting for LLM provider: %v", err)
+
+		select {
+		case <-time.After(3 * time.Second):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// llmOptions returns the settings of the configured LLM provider
+func llmOptions(cfg *config.Config) llm.Options {
+	model, embeddingModel := cfg.LLM.Model, cfg.LLM.EmbeddingModel
+	if cfg.LLM.Provider == llm.ProviderAzure {
+		model, embeddingModel = cfg.LLM.AzureDeployment, cfg.LLM.AzureEmbeddingDeployment
+	}
+	return llm.Options{
+		Provider:        cfg.LLM.Provider,
+		APIKey:          cfg.LLM.APIKey(),
+		Model:           model,
+		EmbeddingModel:  embeddingModel,
+		AzureEndpoint:   cfg.LLM.AzureEndpoint,
+		AzureAPIVersion: cfg.LLM.AzureAPIVersion,
+
+		OllamaURL:            cfg.LLM.OllamaURL,
+		OllamaExtractTimeout: cfg.LLM.OllamaExtractTimeout,
+		Summary: llm.SummaryOptions{
+			SystemPrompt:        cfg.LLM.SummarySystemPrompt,
+			TemplateFile:        cfg.LLM.SummaryTemplateFile,
+			MaxTokens:           cfg.LLM.SummaryMaxTokens,
+			Temperature:         cfg.LLM.SummaryTemperature,
+			MaxDescriptionChars: cfg.LLM.SummaryMaxDescriptionChars,
+		},
+	}
+}
+
+// registerPostgresPoolMetrics exports pool utilization for a Postgres pool on /metrics
+func registerPostgresPoolMetrics(name string, db *repo.DB) {
+	if err := metrics.RegisterPostgresPool(name, db.Stat); err != nil {
+		log.Printf("Failed to register Postgres pool metrics for %s: %v", name, err)
+	}
+}
+
+// registerRedisPoolMetrics exports pool utilization for a Redis pool on /metrics
+func registerRedisPoolMetrics(name string, redisCache *cache.RedisCache) {
+	if err := metrics.RegisterRedisPool(name, redisCache.PoolSize(), redisCache.PoolStats); err != nil {
+		log.Printf("Failed to register Redis pool metrics for %s: %v", name, err)
+	}
+}
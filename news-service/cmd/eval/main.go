@@ -0,0 +1,92 @@
+// Command eval scores the service's answers to labeled queries with NDCG,
+// MRR, recall and precision, optionally against the report of an earlier
+// run, so ranking changes come with before and after numbers.
+//
+//	eval -judgments eval/judgments.ndjson -target http://localhost:8080 -out before.json
+//	eval -judgments eval/judgments.ndjson -target http://localhost:8080 -baseline before.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"news-system/internal/eval"
+	"news-system/internal/replay"
+	"news-system/internal/version"
+)
+
+func main() {
+	var (
+		judgmentsPath = flag.String("judgments", "", "Labeled queries: a JSON array or one judgment per line")
+		target        = flag.String("target", "http://localhost:8080", "Base URL of the service to evaluate")
+		apiKey        = flag.String("api-key", os.Getenv("EVAL_API_KEY"), "API key for -target")
+		limit         = flag.Int("limit", 10, "Results requested, and the cutoff k, for judgments that don't set one")
+		concurrency   = flag.Int("concurrency", 4, "Queries in flight at once")
+		timeout       = flag.Duration("timeout", 30*time.Second, "Timeout for each request")
+		show          = flag.Int("show", 10, "Queries to list under worst, regressed and improved")
+		out           = flag.String("out", "", "Write the full report as JSON to this file, to use as a later -baseline")
+		baseline      = flag.String("baseline", "", "Report of an earlier run to compare with")
+		maxDrop       = flag.Float64("max-ndcg-drop", -1, "With -baseline, exit with status 2 when mean NDCG drops by more than this (negative disables)")
+	)
+	flag.Parse()
+
+	if *judgmentsPath == "" {
+		log.Fatalf("-judgments is required")
+	}
+	judgments, err := eval.ReadJudgments(*judgmentsPath, *limit)
+	if err != nil {
+		log.Fatalf("Failed to read judgments: %v", err)
+	}
+	if len(judgments) == 0 {
+		log.Fatalf("No judgments in %s", *judgmentsPath)
+	}
+
+	var before *eval.Report
+	if *baseline != "" {
+		if before, err = eval.ReadReport(*baseline); err != nil {
+			log.Fatalf("Failed to read baseline: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report := eval.Run(ctx, &replay.Target{
+		BaseURL:   *target,
+		APIKey:    *apiKey,
+		Client:    &http.Client{Timeout: *timeout},
+		UserAgent: "news-eval/" + version.Version,
+	}, judgments, *concurrency)
+	if ctx.Err() != nil {
+		log.Fatalf("Evaluation interrupted")
+	}
+	report.Print(os.Stdout, *show)
+
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		if err := os.WriteFile(*out, data, 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+
+	if before == nil {
+		return
+	}
+	comparison := eval.Compare(before, report)
+	comparison.Print(os.Stdout, *show)
+	if *maxDrop >= 0 && -comparison.Delta.NDCG > *maxDrop {
+		fmt.Fprintf(os.Stderr, "mean NDCG dropped by %.4f, more than %.4f\n", -comparison.Delta.NDCG, *maxDrop)
+		os.Exit(2)
+	}
+}
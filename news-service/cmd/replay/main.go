@@ -0,0 +1,120 @@
+// Command replay sends queries recorded by the query audit log to another
+// environment and reports how its results and latency differ, to validate
+// ranking or infrastructure changes before they roll out.
+//
+//	replay -audit-url http://api-internal:9090 -since 24h -target http://staging:8080
+//	replay -file audit.json -baseline http://prod:8080 -target http://canary:8080
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"news-system/internal/replay"
+	"news-system/internal/services/queryaudit"
+)
+
+func main() {
+	var (
+		file        = flag.String("file", "", "Read entries from this file: an admin API response, a JSON array or NDJSON (\"-\" for stdin)")
+		auditURL    = flag.String("audit-url", "", "Read entries from the admin API of this internal listener, e.g. http://localhost:9090")
+		query       = flag.String("q", "", "With -audit-url, only entries whose query contains this text")
+		strategy    = flag.String("strategy", "", "With -audit-url, only entries answered with this strategy")
+		since       = flag.String("since", "", "With -audit-url, only entries after this RFC 3339 time or this long ago (e.g. 24h)")
+		until       = flag.String("until", "", "With -audit-url, only entries before this RFC 3339 time or this long ago")
+		limit       = flag.Int("limit", 500, "With -audit-url, maximum entries to read (1-500)")
+		target      = flag.String("target", "", "Base URL of the environment to replay against")
+		targetKey   = flag.String("target-key", os.Getenv("REPLAY_TARGET_KEY"), "API key for -target")
+		baseline    = flag.String("baseline", "", "Base URL of an environment to compare with; by default the recorded results are the baseline")
+		baselineKey = flag.String("baseline-key", os.Getenv("REPLAY_BASELINE_KEY"), "API key for -baseline")
+		concurrency = flag.Int("concurrency", 4, "Queries in flight at once")
+		rate        = flag.Float64("rate", 0, "Maximum queries per second (0 for no limit)")
+		timeout     = flag.Duration("timeout", 30*time.Second, "Timeout for each request")
+		show        = flag.Int("show", 20, "Changed or failed queries to print in detail")
+		jsonOut     = flag.String("json", "", "Also write the full report, with every result, to this file")
+		minOverlap  = flag.Float64("min-overlap", 0, "Exit with status 2 when the mean overlap is below this (0 to 1)")
+	)
+	flag.Parse()
+
+	if *target == "" {
+		log.Fatalf("-target is required")
+	}
+	if (*file == "") == (*auditURL == "") {
+		log.Fatalf("exactly one of -file and -audit-url is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	client := &http.Client{Timeout: *timeout}
+
+	var entries []queryaudit.Entry
+	var err error
+	if *file != "" {
+		entries, err = replay.ReadFile(*file)
+	} else {
+		auditQuery := replay.AuditQuery{Query: *query, Strategy: *strategy, Limit: *limit}
+		if auditQuery.Since, err = parseTime(*since); err != nil {
+			log.Fatalf("invalid -since: %v", err)
+		}
+		if auditQuery.Until, err = parseTime(*until); err != nil {
+			log.Fatalf("invalid -until: %v", err)
+		}
+		entries, err = replay.FetchAudit(ctx, client, *auditURL, auditQuery)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read entries: %v", err)
+	}
+	if len(entries) == 0 {
+		log.Fatalf("No entries to replay")
+	}
+	log.Printf("Replaying %d entries against %s", len(entries), *target)
+
+	var baselineTarget *replay.Target
+	if *baseline != "" {
+		baselineTarget = &replay.Target{BaseURL: *baseline, APIKey: *baselineKey, Client: client}
+	}
+	candidate := &replay.Target{BaseURL: *target, APIKey: *targetKey, Client: client}
+
+	report := replay.Replay(ctx, entries, baselineTarget, candidate, replay.Options{
+		Concurrency: *concurrency,
+		Rate:        *rate,
+	})
+	report.Print(os.Stdout, *show)
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		if err := os.WriteFile(*jsonOut, data, 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		log.Fatalf("Replay interrupted")
+	}
+	if report.MeanOverlap < *minOverlap {
+		fmt.Fprintf(os.Stderr, "mean overlap %.3f is below %.3f\n", report.MeanOverlap, *minOverlap)
+		os.Exit(2)
+	}
+}
+
+// parseTime reads an RFC 3339 time or a duration before now
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,250 @@
+// Package bus is the internal domain event bus. Producers publish events
+// (article created, summary generated, trending recomputed, ...) without
+// knowing who listens; subsystems such as the SSE stream, webhooks, cache
+// invalidation and alerting subscribe to the event types they care about.
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EventType names a kind of domain event
+type EventType string
+
+const (
+	ArticleCreated     EventType = "article.created"
+	ArticleUpdated     EventType = "article.updated"
+	ArticleDeleted     EventType = "article.deleted"
+	SummaryGenerated   EventType = "summary.generated"
+	TrendingRecomputed EventType = "trending.recomputed"
+)
+
+// subscriberBuffer is the number of events a slow subscriber may fall behind before events are dropped for it
+const subscriberBuffer = 256
+
+// Event is a domain event. The payload is kept as JSON so events cross the
+// Redis bridge unchanged; use Decode to read it.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       EventType       `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+	// Origin is the instance that published the event
+	Origin string `json:"origin"`
+}
+
+// ArticlePayload is carried by ArticleCreated, ArticleUpdated and ArticleDeleted
+type ArticlePayload struct {
+	ArticleID  string   `json:"article_id"`
+	Title      string   `json:"title"`
+	URL        string   `json:"url"`
+	SourceName string   `json:"source_name"`
+	Category   []string `json:"category"`
+}
+
+// SummaryPayload is carried by SummaryGenerated
+type SummaryPayload struct {
+	ArticleID string `json:"article_id"`
+	Model     string `json:"model,omitempty"`
+}
+
+// TrendingPayload is carried by TrendingRecomputed
+type TrendingPayload struct {
+	Tiles      int   `json:"tiles"`
+	Events     int   `json:"events"`
+	Precisions []int `json:"precisions"`
+}
+
+// NewEvent creates an event of the given type with a JSON-encoded payload
+func NewEvent(eventType EventType, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+	return Event{
+		ID:         newID(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Payload:    data,
+	}, nil
+}
+
+// Decode unmarshals the event payload into v
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Handler consumes events delivered to a subscription
+type Handler func(ctx context.Context, event Event)
+
+type subscription struct {
+	name    string
+	types   map[EventType]bool
+	events  chan Event
+	handler Handler
+}
+
+// Bus is an in-process publish/subscribe event bus. Every subscription gets
+// its own buffered queue and goroutine, so a slow consumer only delays (and
+// eventually drops) its own events, never the publisher or other consumers.
+type Bus struct {
+	origin string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// New creates an event bus. origin identifies this instance on events it publishes.
+func New(origin string) *Bus {
+	if origin == "" {
+		origin = newID()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bus{
+		origin: origin,
+		ctx:    ctx,
+		cancel: cancel,
+		subs:   make(map[*subscription]struct{}),
+	}
+}
+
+// Origin returns the identifier stamped on events published by this instance
+func (b *Bus) Origin() string {
+	return b.origin
+}
+
+// Subscribe registers handler for the given event types (all types when none
+// are given). The returned function cancels the subscription.
+func (b *Bus) Subscribe(name string, handler Handler, types ...EventType) func() {
+	sub := &subscription{
+		name:    name,
+		events:  make(chan Event, subscriberBuffer),
+		handler: handler,
+	}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return func() {}
+	}
+	b.subs[sub] = struct{}{}
+	b.wg.Add(1)
+	b.mu.Unlock()
+
+	go b.run(sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[sub]; ok {
+				delete(b.subs, sub)
+				close(sub.events)
+			}
+		})
+	}
+}
+
+// run delivers queued events to a subscription until it is cancelled
+func (b *Bus) run(sub *subscription) {
+	defer b.wg.Done()
+	for event := range sub.events {
+		b.dispatch(sub, event)
+	}
+}
+
+// dispatch calls the handler, containing panics to the one event
+func (b *Bus) dispatch(sub *subscription, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Interface("panic", r).Str("subscriber", sub.name).Str("event", string(event.Type)).Msg("Event handler panicked")
+		}
+	}()
+	sub.handler(b.ctx, event)
+}
+
+// Publish delivers an event to every matching subscription without blocking.
+// Events published locally are stamped with this bus's origin.
+func (b *Bus) Publish(event Event) {
+	if event.Origin == "" {
+		event.Origin = b.origin
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	metrics.BusEventsPublished.WithLabelValues(string(event.Type)).Inc()
+	for sub := range b.subs {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			metrics.BusEventsDropped.WithLabelValues(sub.name, string(event.Type)).Inc()
+			log.Warn().Str("subscriber", sub.name).Str("event", string(event.Type)).Msg("Event subscriber is behind, dropping event")
+		}
+	}
+}
+
+// Emit builds an event from payload and publishes it, logging encoding failures
+func (b *Bus) Emit(eventType EventType, payload interface{}) {
+	if b == nil {
+		return
+	}
+	event, err := NewEvent(eventType, payload)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build event")
+		return
+	}
+	b.Publish(event)
+}
+
+// Close stops accepting events and waits for subscribers to drain their queues
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		close(sub.events)
+		delete(b.subs, sub)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	b.cancel()
+}
+
+// newID returns a random 16-byte hex identifier
+func newID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,102 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+
+	"news-system/internal/cache"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RedisBridge relays events between the local bus and a Redis pub/sub channel
+// so that every API instance sees events published by any of them. Events
+// published locally are forwarded to Redis; events received from Redis that
+// originated elsewhere are republished locally.
+type RedisBridge struct {
+	bus     *Bus
+	cache   *cache.RedisCache
+	channel string
+	cancel  context.CancelFunc
+	unsub   func()
+	done    chan bool
+}
+
+// NewRedisBridge creates a bridge between bus and the given Redis channel
+func NewRedisBridge(bus *Bus, cache *cache.RedisCache, channel string) *RedisBridge {
+	return &RedisBridge{
+		bus:     bus,
+		cache:   cache,
+		channel: channel,
+		done:    make(chan bool),
+	}
+}
+
+// Start begins relaying events in both directions
+func (rb *RedisBridge) Start(ctx context.Context) {
+	ctx, rb.cancel = context.WithCancel(ctx)
+
+	rb.unsub = rb.bus.Subscribe("redis-bridge", rb.forward)
+
+	pubsub := rb.cache.Subscribe(ctx, rb.channel)
+	go func() {
+		defer close(rb.done)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				rb.receive(msg.Payload)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Str("channel", rb.channel).Msg("Event bus Redis bridge started")
+}
+
+// Stop stops relaying events
+func (rb *RedisBridge) Stop() {
+	if rb.unsub != nil {
+		rb.unsub()
+	}
+	if rb.cancel != nil {
+		rb.cancel()
+		<-rb.done
+	}
+	log.Info().Msg("Event bus Redis bridge stopped")
+}
+
+// forward publishes locally originated events to Redis
+func (rb *RedisBridge) forward(ctx context.Context, event Event) {
+	if event.Origin != rb.bus.Origin() {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Str("event", string(event.Type)).Msg("Failed to marshal event for Redis")
+		return
+	}
+	if err := rb.cache.Publish(ctx, rb.channel, data); err != nil {
+		log.Warn().Err(err).Str("event", string(event.Type)).Msg("Failed to forward event to Redis")
+	}
+}
+
+// receive republishes an event from another instance on the local bus
+func (rb *RedisBridge) receive(payload string) {
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Warn().Err(err).Msg("Ignoring malformed event from Redis")
+		return
+	}
+	// Our own events come back on the channel; they were already delivered locally
+	if event.Origin == "" || event.Origin == rb.bus.Origin() {
+		return
+	}
+	rb.bus.Publish(event)
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes the payloads cached with SetPayload. Every codec reads the
+// entries of the others, so switching codecs needs no flush: entries are
+// rewritten in the new codec as they expire.
+type Codec interface {
+	// Name is the codec's REDIS_CODEC value
+	Name() string
+	// encode wraps the payload value, tagged with version, into an entry
+	encode(version int, value interface{}) ([]byte, error)
+}
+
+// JSONCodec caches payloads as JSON, readable with redis-cli. It is the default.
+var JSONCodec Codec = jsonCodec{}
+
+// MsgpackCodec caches payloads as MessagePack, which is smaller and cheaper
+// to encode and decode than JSON. Fields are named by their json tags.
+var MsgpackCodec Codec = msgpackCodec{}
+
+// Codecs lists the codecs by name
+var Codecs = map[string]Codec{
+	JSONCodec.Name():    JSONCodec,
+	MsgpackCodec.Name(): MsgpackCodec,
+}
+
+// msgpackMarker starts every MessagePack entry. It is never the first byte
+// of JSON, nor used by MessagePack at all, so entries of either codec, and
+// unversioned JSON, are told apart by it.
+const msgpackMarker = 0xc1
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) encode(version int, value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(payloadEnvelope{Version: version, Data: data})
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+// msgpackEnvelope wraps a MessagePack payload with the version of its schema
+type msgpackEnvelope struct {
+	Version int                `msgpack:"v"`
+	Data    msgpack.RawMessage `msgpack:"data"`
+}
+
+func (msgpackCodec) encode(version int, value interface{}) ([]byte, error) {
+	data, err := marshalMsgpack(value)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := marshalMsgpack(msgpackEnvelope{Version: version, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{msgpackMarker}, envelope...), nil
+}
+
+// marshalMsgpack encodes value as MessagePack, naming fields by their json
+// tags so both codecs agree on them
+func marshalMsgpack(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(enc)
+	enc.Reset(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes MessagePack written by marshalMsgpack into target
+func unmarshalMsgpack(data []byte, target interface{}) error {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+	dec.Reset(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(target)
+}
+
+// entry is a cached payload decoded from either codec
+type entry struct {
+	version int
+	data    []byte
+	msgpack bool
+}
+
+// readEntry splits a cached payload into its version and data. Anything
+// that isn't a versioned entry is taken for unversioned JSON, version 0.
+func readEntry(raw []byte) (entry, error) {
+	if len(raw) > 0 && raw[0] == msgpackMarker {
+		var envelope msgpackEnvelope
+		if err := unmarshalMsgpack(raw[1:], &envelope); err != nil {
+			return entry{}, fmt.Errorf("invalid msgpack entry: %w", err)
+		}
+		return entry{version: envelope.Version, data: envelope.Data, msgpack: true}, nil
+	}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		var versioned payloadEnvelope
+		if err := json.Unmarshal(trimmed, &versioned); err == nil && versioned.Version > 0 && versioned.Data != nil {
+			return entry{version: versioned.Version, data: versioned.Data}, nil
+		}
+	}
+	return entry{data: raw}, nil
+}
+
+// decode decodes the entry's data into target
+func (e entry) decode(target interface{}) error {
+	if e.msgpack {
+		return unmarshalMsgpack(e.data, target)
+	}
+	return json.Unmarshal(e.data, target)
+}
+
+// json returns the entry's data as JSON, for migrations. MessagePack data
+// is converted through a generic value, which only old entries pay for.
+func (e entry) json() (json.RawMessage, error) {
+	if !e.msgpack {
+		return e.data, nil
+	}
+	var value interface{}
+	if err := unmarshalMsgpack(e.data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchmarkArticle mirrors the articles of a cached search result
+type benchmarkArticle struct {
+	ID              string    `json:"id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	URL             string    `json:"url"`
+	PublicationDate time.Time `json:"publication_date"`
+	SourceName      string    `json:"source_name"`
+	Category        []string  `json:"category"`
+	RelevanceScore  float64   `json:"relevance_score"`
+	Latitude        float64   `json:"latitude"`
+	Longitude       float64   `json:"longitude"`
+	LLMSummary      string    `json:"llm_summary,omitempty"`
+}
+
+type benchmarkResponse struct {
+	Articles []benchmarkArticle `json:"articles"`
+	Meta     struct {
+		Total    int      `json:"total"`
+		Strategy string   `json:"strategy"`
+		Entities []string `json:"entities"`
+	} `json:"meta"`
+}
+
+// benchmarkPayload is a 20-article search result, the payload the codecs are
+// compared on in the README
+func benchmarkPayload() benchmarkResponse {
+	var resp benchmarkResponse
+	published := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		resp.Articles = append(resp.Articles, benchmarkArticle{
+			ID:              fmt.Sprintf("3f2a9c1e-7b4d-4e8a-9c3f-%012d", i),
+			Title:           "City council approves new transit plan for downtown corridor",
+			Description:     "The plan adds dedicated bus lanes and extends light rail service to the eastern neighborhoods by 2027, officials said on Thursday.",
+			URL:             fmt.Sprintf("https://news.example.com/local/transit-plan-%d", i),
+			PublicationDate: published.Add(-time.Duration(i) * time.Hour),
+			SourceName:      "Example Tribune",
+			Category:        []string{"politics", "local"},
+			RelevanceScore:  0.92 - float64(i)*0.01,
+			Latitude:        37.7749,
+			Longitude:       -122.4194,
+			LLMSummary:      "The council approved bus lanes and a light rail extension for the downtown corridor.",
+		})
+	}
+	resp.Meta.Total = 20
+	resp.Meta.Strategy = "category"
+	resp.Meta.Entities = []string{"City Council", "Downtown"}
+	return resp
+}
+
+func BenchmarkCodec(b *testing.B) {
+	payload := benchmarkPayload()
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec} {
+		raw, err := codec.encode(1, payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(codec.Name()+"/encode", func(b *testing.B) {
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(raw)), "bytes/entry")
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.encode(1, payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(codec.Name()+"/decode", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				e, err := readEntry(raw)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var decoded benchmarkResponse
+				if err := e.decode(&decoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
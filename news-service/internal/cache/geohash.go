@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mmcloughlin/geohash"
+)
+
+// MaxGeohashPrecision is the longest geohash, in characters, that fits the
+// 64 bits geohashes are computed in (~3.7cm tiles)
+const MaxGeohashPrecision = 12
+
+// GenerateGeohash returns the geohash of the tile containing lat/lon at
+// precision characters, clamped to 1..MaxGeohashPrecision
+func GenerateGeohash(lat, lon float64, precision int) string {
+	if precision < 1 {
+		precision = 1
+	} else if precision > MaxGeohashPrecision {
+		precision = MaxGeohashPrecision
+	}
+	return geohash.EncodeWithPrecision(lat, lon, uint(precision))
+}
+
+// ParseGeohash returns the centre of a geohash tile
+func ParseGeohash(hash string) (float64, float64, error) {
+	if err := validateGeohash(hash); err != nil {
+		return 0, 0, err
+	}
+	lat, lon := geohash.DecodeCenter(hash)
+	return lat, lon, nil
+}
+
+// GeohashBoundingBox returns the bounds of a geohash tile as minLat, minLon,
+// maxLat, maxLon
+func GeohashBoundingBox(hash string) (float64, float64, float64, float64, error) {
+	if err := validateGeohash(hash); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	box := geohash.BoundingBox(hash)
+	return box.MinLat, box.MinLng, box.MaxLat, box.MaxLng, nil
+}
+
+// GeohashNeighbors returns the tiles of the same precision around a geohash,
+// clockwise from north. Longitude wraps around the antimeridian; tiles past
+// a pole don't exist, so a tile on the top or bottom row has only five.
+func GeohashNeighbors(hash string) ([]string, error) {
+	if err := validateGeohash(hash); err != nil {
+		return nil, err
+	}
+
+	box := geohash.BoundingBox(hash)
+	lat, lon := box.Center()
+	latDelta := box.MaxLat - box.MinLat
+	lonDelta := box.MaxLng - box.MinLng
+	precision := uint(len(hash))
+
+	// Offsets in tiles: N, NE, E, SE, S, SW, W, NW
+	offsets := [8][2]float64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	neighbors := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		nLat := lat + offset[0]*latDelta
+		if nLat > 90 || nLat < -90 {
+			continue
+		}
+		nLon := lon + offset[1]*lonDelta
+		if nLon > 180 {
+			nLon -= 360
+		} else if nLon < -180 {
+			nLon += 360
+		}
+		neighbors = append(neighbors, geohash.EncodeWithPrecision(nLat, nLon, precision))
+	}
+	return neighbors, nil
+}
+
+// validateGeohash checks hash is a geohash of 1..MaxGeohashPrecision characters
+func validateGeohash(hash string) error {
+	if hash == "" {
+		return fmt.Errorf("empty geohash")
+	}
+	if len(hash) > MaxGeohashPrecision {
+		return fmt.Errorf("geohash %q is longer than %d characters", hash, MaxGeohashPrecision)
+	}
+	if err := geohash.Validate(hash); err != nil {
+		return fmt.Errorf("invalid geohash %q: %w", hash, err)
+	}
+	return nil
+}
+
+// geohashScheme names the tiling geohash-keyed entries were written with.
+// Keys from before it used a hash that didn't follow the geohash grid, and
+// their names can collide with real tiles.
+const geohashScheme = "geohash-v2"
+
+// geohashKeyPatterns match the entries keyed by geohash tile. Trending tiles
+// and topics are recomputed from events on the next tick; tile reader counts
+// restart from the migration.
+var geohashKeyPatterns = []string{
+	"trending:geohash:*",
+	"trending:topics:geohash:*",
+	"readers:geohash:*",
+	"geo:hash:*",
+}
+
+// MigrateGeohashKeys deletes the geohash-keyed entries written before the
+// current tiling, once per Redis: the regional search trends of every region
+// but keepRegion (the one without a location) and the entries matching
+// geohashKeyPatterns. It returns the number of keys deleted, 0 when the
+// migration already ran.
+func MigrateGeohashKeys(ctx context.Context, c *RedisCache, keepRegion string) (int, error) {
+	scheme, err := c.Get(ctx, GeohashSchemeKey())
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return 0, err
+	}
+	if string(scheme) == geohashScheme {
+		return 0, nil
+	}
+
+	deleted := 0
+	drop := func(keys []string) error {
+		if err := c.Del(ctx, keys...); err != nil {
+			return err
+		}
+		deleted += len(keys)
+		return nil
+	}
+	for _, pattern := range geohashKeyPatterns {
+		if err := c.ScanKeys(ctx, pattern, drop); err != nil {
+			return deleted, err
+		}
+	}
+
+	regions, err := c.SMembers(ctx, SearchRegionsKey())
+	if err != nil {
+		return deleted, fmt.Errorf("failed to list search regions: %w", err)
+	}
+	for _, region := range regions {
+		if region == keepRegion {
+			continue
+		}
+		for _, pattern := range []string{fmt.Sprintf("search:terms:%s:*", region), SearchTrendsKey(region)} {
+			if err := c.ScanKeys(ctx, pattern, drop); err != nil {
+				return deleted, err
+			}
+		}
+		if err := c.SRem(ctx, SearchRegionsKey(), region); err != nil {
+			return deleted, err
+		}
+	}
+
+	// Recorded last, so an interrupted migration runs again
+	if err := c.Set(ctx, GeohashSchemeKey(), geohashScheme, 0); err != nil {
+		return deleted, err
+	}
+	return deleted, nil
+}
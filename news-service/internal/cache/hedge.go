@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"news-system/internal/metrics"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// hedging sends reads that are still outstanding after delay to a replica as
+// well, so an occasional slow command on one server doesn't set the tail latency
+type hedging struct {
+	replicas []*RedisCache
+	delay    time.Duration
+	next     atomic.Uint64
+}
+
+// EnableHedging makes the hedged read methods send a second request to one of
+// replicas, in turn, when the first hasn't answered within delay. A delay of
+// zero or no replicas leaves hedging off.
+func (c *RedisCache) EnableHedging(replicas []*RedisCache, delay time.Duration) {
+	if delay <= 0 || len(replicas) == 0 {
+		c.hedge = nil
+		return
+	}
+	c.hedge = &hedging{replicas: replicas, delay: delay}
+}
+
+// GetHedged is Get, hedged to a replica when the primary is slow. A replica
+// that has not replicated the key yet never wins over the primary.
+func (c *RedisCache) GetHedged(ctx context.Context, key string) ([]byte, error) {
+	return hedged(ctx, c, "get", func(ctx context.Context, rc *RedisCache) ([]byte, error) {
+		return rc.Get(ctx, key)
+	}, nil)
+}
+
+// ZRevRangeWithScoresHedged is ZRevRangeWithScores, hedged to a replica when
+// the primary is slow. An empty range from a replica may just be lag, so it
+// never wins over the primary either.
+func (c *RedisCache) ZRevRangeWithScoresHedged(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return hedged(ctx, c, "zrevrange", func(ctx context.Context, rc *RedisCache) ([]redis.Z, error) {
+		return rc.ZRevRangeWithScores(ctx, key, start, stop)
+	}, func(members []redis.Z) bool { return len(members) == 0 })
+}
+
+type hedgeResult[T any] struct {
+	value T
+	err   error
+	hedge bool
+}
+
+// hedged runs read against the primary and, if it hasn't answered after the
+// hedge delay, against a replica too. The first successful answer wins and
+// the other request is cancelled; if both fail, the primary's error is returned.
+// A replica's answer that missing reports as a miss counts as a failure, since
+// only the primary is authoritative for missing data.
+func hedged[T any](ctx context.Context, c *RedisCache, op string, read func(context.Context, *RedisCache) (T, error), missing func(T) bool) (T, error) {
+	h := c.hedge
+	if h == nil {
+		return read(ctx, c)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult[T], 2)
+	go func() {
+		value, err := read(ctx, c)
+		results <- hedgeResult[T]{value: value, err: err}
+	}()
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	select {
+	case res := <-results:
+		return res.value, res.err
+	case <-timer.C:
+	}
+
+	replica := h.replicas[h.next.Add(1)%uint64(len(h.replicas))]
+	go func() {
+		value, err := read(ctx, replica)
+		if err == nil && missing != nil && missing(value) {
+			err = ErrKeyNotFound
+		}
+		results <- hedgeResult[T]{value: value, err: err, hedge: true}
+	}()
+
+	var zero T
+	var primaryErr error
+	for pending := 2; pending > 0; pending-- {
+		res := <-results
+		if res.err == nil {
+			winner := "primary"
+			if res.hedge {
+				winner = "hedge"
+			}
+			metrics.HedgedReads.WithLabelValues(op, winner).Inc()
+			return res.value, nil
+		}
+		if !res.hedge {
+			primaryErr = res.err
+			if errors.Is(res.err, ErrKeyNotFound) {
+				// The primary is authoritative for missing keys
+				return zero, res.err
+			}
+		}
+	}
+	return zero, primaryErr
+}
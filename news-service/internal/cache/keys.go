@@ -8,6 +8,7 @@ import (
 )
 
 const (
+	// ArticleTTL and SummaryTTL apply to settled content; see AdaptiveTTL for recent articles
 	ArticleTTL        = 6 * time.Hour
 	SummaryTTL        = 7 * 24 * time.Hour
 	SearchTTL         = 90 * time.Second
@@ -18,6 +19,9 @@ const (
 	TrendingTTL       = 2 * time.Minute
 	GeohashTTL        = 1 * time.Hour
 	UserEventTTL      = 24 * time.Hour
+	SearchTrendsTTL   = 2 * time.Hour
+	// ReadersTTL keeps hourly unique-reader counts for the 24h window they are summed over
+	ReadersTTL        = 25 * time.Hour
 )
 
 // ArticleKey generates Redis key for article cache
@@ -30,9 +34,18 @@ func SummaryKey(id string) string {
 	return fmt.Sprintf("news:summary:%s", id)
 }
 
-// SearchKey generates Redis key for search results cache
-func SearchKey(query string, limit int) string {
-	hash := sha1.Sum([]byte(fmt.Sprintf("%s|%d", query, limit)))
+// SearchKey generates Redis key for search results cache. publishedAfter and
+// publishedBefore bound the publication window, nil when open.
+func SearchKey(query, language, country, sentiment string, entities []string, publishedAfter, publishedBefore *time.Time, limit int) string {
+	window := ""
+	if publishedAfter != nil {
+		window += publishedAfter.UTC().Format(time.RFC3339)
+	}
+	window += "/"
+	if publishedBefore != nil {
+		window += publishedBefore.UTC().Format(time.RFC3339)
+	}
+	hash := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%s|%s|%s|%s|%d", query, language, country, sentiment, strings.Join(entities, ","), window, limit)))
 	return fmt.Sprintf("cache:v1:search:%x", hash)
 }
 
@@ -65,91 +78,246 @@ func TrendingKey(geohash string, limit int) string {
 	return fmt.Sprintf("trending:geohash:%s:limit:%d", geohash, limit)
 }
 
+// TrendingTopicsKey generates Redis key for the trending categories of a geohash tile
+func TrendingTopicsKey(geohash string) string {
+	return fmt.Sprintf("trending:topics:geohash:%s", geohash)
+}
+
+// TrendingWindowKey generates Redis key for the trending data stored under
+// key for the default 24h window, over another window such as 1h
+func TrendingWindowKey(key, window string) string {
+	return fmt.Sprintf("%s:window:%s", key, window)
+}
+
+// CategoryTrendingKey generates Redis key for the trending articles of one
+// category in a geohash tile
+func CategoryTrendingKey(geohash, category string) string {
+	return fmt.Sprintf("trending:geohash:%s:category:%s", geohash, category)
+}
+
+// GlobalTrendingKey generates Redis key for the trending articles
+// everywhere, or of one category everywhere when category isn't ""
+func GlobalTrendingKey(category string) string {
+	if category == "" {
+		return "trending:global"
+	}
+	return fmt.Sprintf("trending:global:category:%s", category)
+}
+
+// GlobalTrendingTopicsKey generates Redis key for the trending categories everywhere
+func GlobalTrendingTopicsKey() string {
+	return "trending:topics:global"
+}
+
+// PlaceTrendingKey generates Redis key for the trending articles of a
+// country, or of one of its regions when region isn't ""
+func PlaceTrendingKey(country, region string) string {
+	if region == "" {
+		return fmt.Sprintf("trending:place:%s", country)
+	}
+	return fmt.Sprintf("trending:place:%s:%s", country, region)
+}
+
+// TrendingStateKey generates Redis key for where incremental trending
+// updates left off
+func TrendingStateKey() string {
+	return "trending:incremental:state"
+}
+
+// TrendingLeaseKey generates Redis key for the lease of the instance updating trending
+func TrendingLeaseKey() string {
+	return "trending:incremental:lease"
+}
+
+// TrendingSetsKey generates Redis key for the sorted set of trending keys
+// incremental updates decay, scored by when they were last written
+func TrendingSetsKey() string {
+	return "trending:incremental:keys"
+}
+
+// TrendingWeightsKey generates Redis key for the unique reader weights of
+// the last full trending recompute
+func TrendingWeightsKey() string {
+	return "trending:incremental:weights"
+}
+
 // GeohashKey generates Redis key for geohash data
 func GeohashKey(geohash string) string {
 	return fmt.Sprintf("geo:hash:%s", geohash)
 }
 
+// GeohashSchemeKey generates Redis key for the tiling geohash-keyed entries
+// were last migrated to
+func GeohashSchemeKey() string {
+	return "geo:scheme"
+}
+
 // UserEventKey generates Redis key for user events
 func UserEventKey(articleID string) string {
 	return fmt.Sprintf("events:article:%s", articleID)
 }
 
-// RateLimitKey generates Redis key for rate limiting
-func RateLimitKey(clientIP string) string {
-	return fmt.Sprintf("ratelimit:ip:%s", clientIP)
+// EventSeenKey generates Redis key marking that a reader reported an event
+// of a type on an article, to drop repeats
+func EventSeenKey(reader, articleID, event string) string {
+	return fmt.Sprintf("eventfilter:seen:%s:%s:%s", reader, articleID, event)
 }
 
-// Helper function to generate geohash from lat/lon
-// This is a simplified implementation - in production, use a proper geohash library
-func GenerateGeohash(lat, lon float64, precision int) string {
-	// Simplified geohash implementation
-	// In production, use github.com/mmcloughlin/geohash or similar
-	
-	// Base32 characters for geohash
-	const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
-	
-	// Simple hash-based approach for demo purposes
-	// This is NOT a proper geohash implementation
-	latHash := int((lat + 90.0) * 1000000) % 1000000
-	lonHash := int((lon + 180.0) * 1000000) % 1000000
-	
-	combined := latHash*1000000 + lonHash
-	geohash := ""
-	
-	for i := 0; i < precision; i++ {
-		geohash += string(base32[combined%32])
-		combined /= 32
-	}
-	
-	return geohash
+// EventRateKey generates Redis key for the number of events a reader
+// reported during a minute, counted from the Unix epoch
+func EventRateKey(reader string, minute int64) string {
+	return fmt.Sprintf("eventfilter:rate:%s:%d", reader, minute)
 }
 
-// ParseGeohash parses a geohash back to lat/lon (simplified)
-func ParseGeohash(geohash string) (float64, float64, error) {
-	// This is a simplified implementation
-	// In production, use a proper geohash library
-	
-	if len(geohash) == 0 {
-		return 0, 0, fmt.Errorf("empty geohash")
-	}
-	
-	// Simple reverse hash for demo purposes
-	// This is NOT accurate geohash parsing
-	hash := 0
-	for i, char := range geohash {
-		hash += int(char) * (i + 1)
-	}
-	
-	// Convert hash back to approximate coordinates
-	lat := float64(hash%180000-90000) / 1000.0
-	lon := float64(hash%360000-180000) / 1000.0
-	
-	return lat, lon, nil
-}
-
-// GeohashBoundingBox returns the bounding box for a geohash
-func GeohashBoundingBox(geohash string) (float64, float64, float64, float64, error) {
-	// This is a simplified implementation
-	// In production, use a proper geohash library
-	
-	lat, lon, err := ParseGeohash(geohash)
-	if err != nil {
-		return 0, 0, 0, 0, err
+// EventBotKey generates Redis key flagging a reader as a likely bot
+func EventBotKey(reader string) string {
+	return fmt.Sprintf("eventfilter:bot:%s", reader)
+}
+
+// ArticleReadersKey generates Redis key for the HyperLogLog of an article's readers during an hour
+func ArticleReadersKey(articleID string, hour int64) string {
+	return fmt.Sprintf("readers:article:%s:%d", articleID, hour)
+}
+
+// TileReadersKey generates Redis key for the HyperLogLog of the readers in a geohash tile during an hour
+func TileReadersKey(geohash string, hour int64) string {
+	return fmt.Sprintf("readers:geohash:%s:%d", geohash, hour)
+}
+
+// GeocodeKey generates Redis key for the administrative areas around a
+// location, rounded to about a kilometre
+func GeocodeKey(lat, lon float64) string {
+	return fmt.Sprintf("geocode:areas:%.2f:%.2f", lat, lon)
+}
+
+// PlaceKey generates Redis key for the place names at a location, rounded to
+// about a kilometre
+func PlaceKey(lat, lon float64) string {
+	return fmt.Sprintf("geocode:place:%.2f:%.2f", lat, lon)
+}
+
+// GeocodeSearchKey generates Redis key for the places named name, in country
+// when it isn't ""
+func GeocodeSearchKey(name, country string) string {
+	return fmt.Sprintf("geocode:search:%s:%s", country, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// SearchTermsKey generates Redis key for the normalized search terms logged in a region during a window
+func SearchTermsKey(region string, bucket int64) string {
+	return fmt.Sprintf("search:terms:%s:%d", region, bucket)
+}
+
+// SearchTrendsKey generates Redis key for the rising search terms of a region
+func SearchTrendsKey(region string) string {
+	return fmt.Sprintf("search:trends:%s", region)
+}
+
+// SearchRegionsKey generates Redis key for the set of regions with logged searches
+func SearchRegionsKey() string {
+	return "search:regions"
+}
+
+// WebhookClaimKey generates Redis key for an instance's claim on delivering an event to a webhook endpoint
+func WebhookClaimKey(endpointID, eventID string) string {
+	return fmt.Sprintf("webhook:claim:%s:%s", endpointID, eventID)
+}
+
+// SubscriptionAlertClaimKey generates Redis key for an instance's claim on alerting a subscription to an article
+func SubscriptionAlertClaimKey(subscriptionID, articleID string) string {
+	return fmt.Sprintf("subscription:claim:%s:%s", subscriptionID, articleID)
+}
+
+// JobQueueKey generates Redis key for the stream of a job queue's jobs of
+// one priority; normal priority jobs keep the queue's plain key
+func JobQueueKey(queue, priority string) string {
+	if priority == "normal" {
+		return fmt.Sprintf("jobs:%s", queue)
 	}
-	
-	// Approximate bounding box (very rough)
-	precision := len(geohash)
-	// Use simple division instead of bit shift to avoid type issues
-	latDelta := 180.0 / float64(precision*precision)
-	lonDelta := 360.0 / float64(precision*precision)
-	
-	minLat := lat - latDelta/2
-	maxLat := lat + latDelta/2
-	minLon := lon - lonDelta/2
-	maxLon := lon + lonDelta/2
-	
-	return minLat, minLon, maxLat, maxLon, nil
+	return fmt.Sprintf("jobs:%s:%s", queue, priority)
+}
+
+// JobThroughputKey generates Redis key for the count of a job queue's job
+// attempts finished in one minute, numbered from the Unix epoch
+func JobThroughputKey(queue string, minute int64) string {
+	return fmt.Sprintf("jobs:%s:done:%d", queue, minute)
+}
+
+// JobDelayedKey generates Redis key for the sorted set of a job queue's jobs not due yet
+func JobDelayedKey(queue string) string {
+	return fmt.Sprintf("jobs:%s:delayed", queue)
+}
+
+// JobDeadLetterKey generates Redis key for the stream of a job queue's failed jobs
+func JobDeadLetterKey(queue string) string {
+	return fmt.Sprintf("jobs:%s:dead", queue)
+}
+
+// ExportJobKey generates Redis key for the state of an export job
+func ExportJobKey(id string) string {
+	return fmt.Sprintf("export:job:%s", id)
+}
+
+// ExportJobLeaseKey generates Redis key for the lease of the instance running an export job
+func ExportJobLeaseKey(id string) string {
+	return fmt.Sprintf("export:job:lease:%s", id)
+}
+
+// ExportJobsActiveKey generates Redis key for the set of export jobs that have not finished
+func ExportJobsActiveKey() string {
+	return "export:jobs:active"
+}
+
+// QueryAuditEntryKey generates Redis key for an entry of the query audit log
+func QueryAuditEntryKey(id string) string {
+	return fmt.Sprintf("audit:query:%s", id)
+}
+
+// QueryAuditIndexKey generates Redis key for the query audit log's entries ordered by time
+func QueryAuditIndexKey() string {
+	return "audit:queries"
+}
+
+// ShadowResultsKey generates Redis key for the newest shadow strategy comparisons
+func ShadowResultsKey() string {
+	return "shadow:results"
+}
+
+// ModerationReportsKey generates Redis key for the reports of an article, one per client
+func ModerationReportsKey(articleID string) string {
+	return fmt.Sprintf("moderation:reports:%s", articleID)
+}
+
+// ModerationQueueKey generates Redis key for the articles flagged for moderation
+func ModerationQueueKey() string {
+	return "moderation:queue"
+}
+
+// ModerationDemotedKey generates Redis key for the set of demoted articles
+func ModerationDemotedKey() string {
+	return "moderation:demoted"
+}
+
+// ModerationAuditKey generates Redis key for the list of moderation reviews, newest first
+func ModerationAuditKey() string {
+	return "moderation:audit"
+}
+
+// LLMExtractKey generates Redis key for a cached LLM extraction; hash
+// identifies the query together with the provider, model and prompt
+func LLMExtractKey(hash string) string {
+	return fmt.Sprintf("cache:v1:llm:extract:%s", hash)
+}
+
+// IngestWebhookKey generates Redis key marking a signed ingestion request,
+// identified by its verified timestamp and body, as received
+func IngestWebhookKey(timestamp int64, body []byte) string {
+	hash := sha1.Sum(body)
+	return fmt.Sprintf("ingest:webhook:%d:%x", timestamp, hash)
+}
+
+// RateLimitKey generates Redis key for rate limiting
+func RateLimitKey(clientIP string) string {
+	return fmt.Sprintf("ratelimit:ip:%s", clientIP)
 }
 
 // GetTTL returns the appropriate TTL for a given key
@@ -169,12 +337,16 @@ func GetTTL(key string) time.Duration {
 		return ScoreTTL
 	case strings.Contains(key, "cache:v1:nearby:"):
 		return NearbyTTL
-	case strings.Contains(key, "trending:geohash:"):
+	case strings.Contains(key, "trending:geohash:"), strings.Contains(key, "trending:topics:"), strings.Contains(key, "trending:place:"):
 		return TrendingTTL
 	case strings.Contains(key, "geo:hash:"):
 		return GeohashTTL
 	case strings.Contains(key, "events:article:"):
 		return UserEventTTL
+	case strings.Contains(key, "search:trends:"):
+		return SearchTrendsTTL
+	case strings.Contains(key, "readers:"):
+		return ReadersTTL
 	default:
 		return 5 * time.Minute // default TTL
 	}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// releaseScript deletes a lease only while it still holds the caller's token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// AcquireLease takes the lease at key for ttl unless another holder has it,
+// returning the token to release it with
+func (c *RedisCache) AcquireLease(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, fmt.Errorf("failed to generate lease token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	acquired, err := c.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !acquired {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// ReleaseLease gives up the lease at key if it is still held with token. A
+// holder that outlived its lease can't release the one taken after it expired.
+func (c *RedisCache) ReleaseLease(ctx context.Context, key, token string) error {
+	return releaseScript.Run(ctx, c.client, []string{key}, token).Err()
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// keySpec locates the keys among a command's arguments: every step-th
+// argument from first through last, where a last of -1 means the final one
+type keySpec struct {
+	first, last, step int
+}
+
+var (
+	singleKey = keySpec{1, 1, 1}
+	allKeys   = keySpec{1, -1, 1}
+	// subcommandKey is the key of a container command, e.g. XGROUP CREATE key
+	subcommandKey = keySpec{2, 2, 1}
+	// streamKeys marks the keys following STREAMS, which take the first
+	// half of the remaining arguments; the second half are entry IDs
+	streamKeys = keySpec{-1, -1, 1}
+	// storeKeys marks the destination of a ZUNIONSTORE or ZINTERSTORE and
+	// the numkeys source keys that follow it
+	storeKeys = keySpec{-2, -2, 1}
+	// scriptKeys marks the numkeys keys of an EVAL or EVALSHA, which follow
+	// the script and numkeys
+	scriptKeys = keySpec{-3, -3, 1}
+)
+
+// commandKeys lists where the keys are for every command the cache issues.
+// Commands that are neither listed here nor in keylessCommands are rejected
+// by a namespaced client rather than silently escaping the namespace, so add
+// new commands here when using them.
+var commandKeys = map[string]keySpec{
+	"get": singleKey, "set": singleKey, "setnx": singleKey, "setex": singleKey, "getdel": singleKey,
+	"incr": singleKey, "incrby": singleKey, "decr": singleKey, "decrby": singleKey,
+	"expire": singleKey, "pexpire": singleKey, "ttl": singleKey, "pttl": singleKey, "persist": singleKey, "type": singleKey,
+	"del": allKeys, "unlink": allKeys, "exists": allKeys, "touch": allKeys, "mget": allKeys,
+	"mset":   {1, -1, 2},
+	"rename": {1, 2, 1},
+
+	"sadd": singleKey, "srem": singleKey, "smembers": singleKey, "sismember": singleKey, "scard": singleKey,
+	"sinter": allKeys,
+
+	"zadd": singleKey, "zrem": singleKey, "zincrby": singleKey, "zscore": singleKey, "zcard": singleKey, "zcount": singleKey,
+	"zrange": singleKey, "zrevrange": singleKey, "zrangebyscore": singleKey, "zrevrangebyscore": singleKey,
+	"zremrangebyscore": singleKey, "zremrangebyrank": singleKey,
+	"zunionstore": storeKeys, "zinterstore": storeKeys,
+
+	"hset": singleKey, "hget": singleKey, "hmget": singleKey, "hgetall": singleKey, "hdel": singleKey, "hincrby": singleKey, "hlen": singleKey,
+
+	"lpush": singleKey, "rpush": singleKey, "lpop": singleKey, "rpop": singleKey, "lrange": singleKey, "ltrim": singleKey, "llen": singleKey,
+
+	"geoadd": singleKey, "georadius": singleKey, "georadius_ro": singleKey, "geopos": singleKey, "geodist": singleKey,
+
+	"pfadd": singleKey, "pfcount": allKeys, "pfmerge": allKeys,
+
+	"xadd": singleKey, "xack": singleKey, "xdel": singleKey, "xlen": singleKey, "xtrim": singleKey,
+	"xrange": singleKey, "xrevrange": singleKey, "xpending": singleKey, "xautoclaim": singleKey,
+	"xgroup": subcommandKey, "xinfo": subcommandKey,
+	"xreadgroup": streamKeys,
+
+	"eval": scriptKeys, "evalsha": scriptKeys,
+
+	// Pub/sub channels are namespaced like keys
+	"publish": singleKey,
+}
+
+// keylessCommands take no keys and pass through unchanged
+var keylessCommands = map[string]bool{
+	"ping": true, "echo": true, "info": true, "multi": true, "exec": true, "discard": true,
+	"hello": true, "auth": true, "select": true, "client": true, "quit": true, "command": true,
+	// ScanKeys namespaces the MATCH pattern itself
+	"scan": true,
+}
+
+// normalizeNamespace turns a namespace such as "prod:eu-west-1" into the key
+// prefix "prod:eu-west-1:"; an empty namespace yields no prefix
+func normalizeNamespace(namespace string) string {
+	namespace = strings.Trim(strings.TrimSpace(namespace), ":")
+	if namespace == "" {
+		return ""
+	}
+	return namespace + ":"
+}
+
+// namespaceHook prefixes the keys of every command, including pipelined ones,
+// with the client's namespace, so several environments, regions or tenants
+// can share a Redis deployment without reading or overwriting each other's data
+type namespaceHook struct {
+	prefix string
+}
+
+func (h namespaceHook) apply(cmd redis.Cmder) error {
+	name := cmd.Name()
+	spec, ok := commandKeys[name]
+	if !ok {
+		if keylessCommands[name] {
+			return nil
+		}
+		return fmt.Errorf("redis command %q is not supported in a namespaced cache", name)
+	}
+
+	args := cmd.Args()
+	switch spec {
+	case streamKeys:
+		spec = streamKeySpec(args)
+	case storeKeys:
+		spec = storeKeySpec(args)
+	case scriptKeys:
+		spec = scriptKeySpec(args)
+	}
+	last := spec.last
+	if last < 0 || last >= len(args) {
+		last = len(args) - 1
+	}
+	for i := spec.first; i <= last; i += spec.step {
+		if key, ok := args[i].(string); ok {
+			args[i] = h.prefix + key
+		}
+	}
+	return nil
+}
+
+// streamKeySpec locates the keys of an XREADGROUP: the first half of the
+// arguments after STREAMS
+func streamKeySpec(args []interface{}) keySpec {
+	for i, arg := range args {
+		if name, ok := arg.(string); ok && strings.EqualFold(name, "streams") {
+			n := (len(args) - i - 1) / 2
+			return keySpec{i + 1, i + n, 1}
+		}
+	}
+	// No keys: an empty range
+	return keySpec{len(args), len(args), 1}
+}
+
+// storeKeySpec locates the keys of a ZUNIONSTORE or ZINTERSTORE: the
+// destination, then numkeys sources. numkeys itself isn't a string, so it
+// is passed over.
+func storeKeySpec(args []interface{}) keySpec {
+	if len(args) < 3 {
+		return keySpec{1, 1, 1}
+	}
+	var n int
+	switch numkeys := args[2].(type) {
+	case int:
+		n = numkeys
+	case int64:
+		n = int(numkeys)
+	}
+	return keySpec{1, 2 + n, 1}
+}
+
+// scriptKeySpec locates the keys of an EVAL or EVALSHA: the numkeys
+// arguments after numkeys. A script without keys yields an empty range.
+func scriptKeySpec(args []interface{}) keySpec {
+	var n int
+	if len(args) >= 3 {
+		switch numkeys := args[2].(type) {
+		case int:
+			n = numkeys
+		case int64:
+			n = int(numkeys)
+		}
+	}
+	if n == 0 {
+		return keySpec{len(args), len(args), 1}
+	}
+	return keySpec{3, 2 + n, 1}
+}
+
+func (h namespaceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h namespaceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.apply(cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h namespaceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := h.apply(cmd); err != nil {
+				cmd.SetErr(err)
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
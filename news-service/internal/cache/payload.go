@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"news-system/internal/metrics"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Schema describes the JSON layout of a kind of cached payload, such as a
+// cached article, so entries written by an older or newer deploy are still
+// read safely. Bump Version when a change to the cached struct can't be
+// decoded from the old JSON, e.g. a field changing type, and add the
+// Migration from the previous version.
+type Schema struct {
+	// Name labels the payloads in metrics
+	Name string
+	// Version is the version this deploy writes, from 1
+	Version int
+	// Migrations turns the JSON of version v into that of version v+1,
+	// keyed by v. Version 0 is the unversioned JSON cached before payloads
+	// were versioned.
+	Migrations map[int]Migration
+}
+
+// Migration rewrites the JSON of one payload version into the next
+type Migration func(data json.RawMessage) (json.RawMessage, error)
+
+// SameShape is the Migration of a version whose JSON the next version
+// reads unchanged
+func SameShape(data json.RawMessage) (json.RawMessage, error) {
+	return data, nil
+}
+
+// payloadEnvelope wraps a cached payload with the version of its schema
+type payloadEnvelope struct {
+	Version int             `json:"v"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// SetPayload caches value under key with the cache's codec, tagged with
+// schema's version
+func (c *RedisCache) SetPayload(ctx context.Context, key string, schema Schema, value interface{}, ttl time.Duration) error {
+	data, err := c.codec.encode(schema.Version, value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s payload: %w", schema.Name, err)
+	}
+	return c.client.Set(ctx, key, data, ttl).Err()
+}
+
+// GetPayload decodes the payload cached under key into target, whichever
+// codec wrote it. An entry of an older version is migrated to schema's version first, and fields the
+// target doesn't know are ignored, so an entry of a newer deploy still
+// decodes when it can. An entry that can't be decoded is deleted, so the
+// caller refreshes it, and reported as ErrKeyNotFound like a miss.
+func (c *RedisCache) GetPayload(ctx context.Context, key string, schema Schema, target interface{}) error {
+	raw, err := c.Get(ctx, key)
+	if err == ErrKeyNotFound {
+		metrics.CacheRequests.WithLabelValues(schema.Name, "miss").Inc()
+		return err
+	}
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues(schema.Name, "error").Inc()
+		return err
+	}
+
+	result, err := decodePayload(raw, schema, target)
+	metrics.CachePayloads.WithLabelValues(schema.Name, result).Inc()
+	if err == nil {
+		metrics.CacheRequests.WithLabelValues(schema.Name, "hit").Inc()
+		return nil
+	}
+	metrics.CacheRequests.WithLabelValues(schema.Name, "miss").Inc()
+	log.Debug().Err(err).Str("key", key).Str("result", result).Msg("Cached payload is incompatible, refreshing")
+	if result == "discarded" {
+		if err := c.Del(ctx, key); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to discard incompatible cached payload")
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// decodePayload decodes a cached entry into target, migrating it to
+// schema's version, and returns how it went for metrics. An entry of a
+// newer version that doesn't decode is left for the deploy that wrote it.
+func decodePayload(raw []byte, schema Schema, target interface{}) (string, error) {
+	e, err := readEntry(raw)
+	if err != nil {
+		return "discarded", fmt.Errorf("%s payload: %w", schema.Name, err)
+	}
+
+	if e.version > schema.Version {
+		if err := e.decode(target); err != nil {
+			return "newer", fmt.Errorf("%s payload of newer version %d: %w", schema.Name, e.version, err)
+		}
+		return "newer", nil
+	}
+	if e.version == schema.Version {
+		if err := e.decode(target); err != nil {
+			return "discarded", fmt.Errorf("invalid %s payload of version %d: %w", schema.Name, e.version, err)
+		}
+		return "current", nil
+	}
+
+	// Migrations rewrite JSON, whichever codec wrote the entry
+	data, err := e.json()
+	if err != nil {
+		return "discarded", fmt.Errorf("invalid %s payload of version %d: %w", schema.Name, e.version, err)
+	}
+	for v := e.version; v < schema.Version; v++ {
+		migrate, ok := schema.Migrations[v]
+		if !ok {
+			return "discarded", fmt.Errorf("no migration of %s payload from version %d", schema.Name, v)
+		}
+		var err error
+		if data, err = migrate(data); err != nil {
+			return "discarded", fmt.Errorf("failed to migrate %s payload from version %d: %w", schema.Name, v, err)
+		}
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return "discarded", fmt.Errorf("invalid %s payload of version %d: %w", schema.Name, e.version, err)
+	}
+	return "migrated", nil
+}
@@ -4,23 +4,68 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+
 	"github.com/go-redis/redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
 type RedisCache struct {
 	client *redis.Client
+	prefix string
+	hedge  *hedging
+	codec  Codec
+}
+
+// Options tunes the client's connection pool and timeouts. Zero values keep the go-redis defaults.
+type Options struct {
+	PoolSize        int
+	MinIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+	DialTimeout     time.Duration
+	// PoolTimeout bounds how long a command waits for a free connection
+	PoolTimeout time.Duration
+	// OperationTimeout bounds every command (or pipeline) end to end
+	OperationTimeout time.Duration
+	// Namespace prefixes every key and pub/sub channel, e.g. "prod:eu-west-1"
+	Namespace string
+	// Codec encodes the payloads cached with SetPayload; nil is JSONCodec
+	Codec Codec
 }
 
-func NewRedisCache(addr, password string, db int) (*RedisCache, error) {
+func NewRedisCache(addr, password string, db int, opts Options) (*RedisCache, error) {
+	poolSize := opts.PoolSize
+	if poolSize <= 0 {
+		poolSize = 10
+	}
+
 	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
-		PoolSize: 10,
+		Addr:            addr,
+		Password:        password,
+		DB:              db,
+		PoolSize:        poolSize,
+		MinIdleConns:    opts.MinIdleConns,
+		ConnMaxIdleTime: opts.ConnMaxIdleTime,
+		ConnMaxLifetime: opts.ConnMaxLifetime,
+		DialTimeout:     opts.DialTimeout,
+		PoolTimeout:     opts.PoolTimeout,
+		// Without this go-redis ignores context deadlines on socket reads and writes
+		ContextTimeoutEnabled: true,
 	})
+	client.AddHook(poolExhaustedHook{addr: addr, size: poolSize})
+	if opts.OperationTimeout > 0 {
+		client.AddHook(timeoutHook{timeout: opts.OperationTimeout})
+	}
+	prefix := normalizeNamespace(opts.Namespace)
+	if prefix != "" {
+		client.AddHook(namespaceHook{prefix: prefix})
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -29,14 +74,158 @@ func NewRedisCache(addr, password string, db int) (*RedisCache, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Info().Msg("Redis connection established")
-	return &RedisCache{client: client}, nil
+	codec := opts.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	log.Info().Str("namespace", prefix).Str("codec", codec.Name()).Msg("Redis connection established")
+	return &RedisCache{client: client, prefix: prefix, codec: codec}, nil
 }
 
 func (c *RedisCache) Close() error {
 	return c.client.Close()
 }
 
+// Namespace returns the prefix applied to every key, empty when keys are not namespaced
+func (c *RedisCache) Namespace() string {
+	return c.prefix
+}
+
+// PoolStats returns the client's connection pool statistics
+func (c *RedisCache) PoolStats() *redis.PoolStats {
+	return c.client.PoolStats()
+}
+
+// PoolSize returns the maximum number of connections in the pool
+func (c *RedisCache) PoolSize() int {
+	return c.client.Options().PoolSize
+}
+
+// ServerVersion returns the version reported by the Redis server
+func (c *RedisCache) ServerVersion(ctx context.Context) (string, error) {
+	info, err := c.client.Info(ctx, "server").Result()
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:"); ok {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("server did not report redis_version")
+}
+
+// MissingCommands returns the commands the cache issues that the server does
+// not support, such as GEO or HyperLogLog commands on an old or stripped-down
+// Redis-compatible server
+func (c *RedisCache) MissingCommands(ctx context.Context) ([]string, error) {
+	names := make([]string, 0, len(commandKeys))
+	for name := range commandKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []interface{}{"command", "info"}
+	for _, name := range names {
+		args = append(args, name)
+	}
+	infos, err := c.client.Do(ctx, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for i, info := range infos {
+		// Unknown commands come back as nil entries
+		if info == nil && i < len(names) {
+			missing = append(missing, names[i])
+		}
+	}
+	return missing, nil
+}
+
+// poolExhaustedHook turns go-redis' pool timeout into ErrPoolExhausted so callers
+// can tell a saturated pool apart from a slow or unreachable server
+type poolExhaustedHook struct {
+	addr string
+	size int
+}
+
+func (h poolExhaustedHook) wrap(err error) error {
+	if err != nil && err.Error() == "redis: connection pool timeout" {
+		return fmt.Errorf("%w: all %d connections to %s in use", ErrPoolExhausted, h.size, h.addr)
+	}
+	return err
+}
+
+func (h poolExhaustedHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h poolExhaustedHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return h.wrap(next(ctx, cmd))
+	}
+}
+
+func (h poolExhaustedHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return h.wrap(next(ctx, cmds))
+	}
+}
+
+// timeoutHook gives every command its own deadline on top of the caller's context
+type timeoutHook struct {
+	timeout time.Duration
+}
+
+func (h timeoutHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h timeoutHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		opCtx, cancel := context.WithTimeout(ctx, h.timeout+blockDuration(cmd))
+		defer cancel()
+		err := next(opCtx, cmd)
+		h.observe(ctx, opCtx, err, cmd.Name())
+		return err
+	}
+}
+
+func (h timeoutHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		opCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+		err := next(opCtx, cmds)
+		h.observe(ctx, opCtx, err, "pipeline")
+		return err
+	}
+}
+
+// blockDuration is how long a blocking command such as XREADGROUP ... BLOCK
+// may wait on the server before it replies, 0 for other commands
+func blockDuration(cmd redis.Cmder) time.Duration {
+	args := cmd.Args()
+	for i := 1; i+1 < len(args); i++ {
+		if name, ok := args[i].(string); ok && strings.EqualFold(name, "block") {
+			if ms, ok := args[i+1].(int64); ok {
+				return time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return 0
+}
+
+// observe counts calls cut off by the hook's own deadline rather than the caller's
+func (h timeoutHook) observe(ctx, opCtx context.Context, err error, op string) {
+	if err != nil && opCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		metrics.DeadlineExceeded.WithLabelValues("redis", op).Inc()
+		log.Warn().Str("command", op).Dur("timeout", h.timeout).Msg("Redis operation timed out")
+	}
+}
+
 func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	val, err := c.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
@@ -48,6 +237,30 @@ func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return val, nil
 }
 
+// MGet returns the values of several keys in one round trip; missing keys yield nil
+func (c *RedisCache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %d keys: %w", len(keys), err)
+	}
+	values := make([][]byte, len(vals))
+	for i, val := range vals {
+		if str, ok := val.(string); ok {
+			values[i] = []byte(str)
+		}
+	}
+	return values, nil
+}
+
+// Pipelined sends every command queued by fn in a single round trip
+func (c *RedisCache) Pipelined(ctx context.Context, fn func(pipe redis.Pipeliner) error) error {
+	_, err := c.client.Pipelined(ctx, fn)
+	return err
+}
+
 func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	var data []byte
 	var err error
@@ -102,16 +315,54 @@ func (c *RedisCache) ZAdd(ctx context.Context, key string, members ...redis.Z) e
 	return c.client.ZAdd(ctx, key, members...).Err()
 }
 
+// ZRem removes members from a sorted set
+func (c *RedisCache) ZRem(ctx context.Context, key string, members ...interface{}) error {
+	return c.client.ZRem(ctx, key, members...).Err()
+}
+
+// ZRemCount removes members from a sorted set and returns how many of them
+// it held, so that of several callers removing the same member only one
+// sees it removed
+func (c *RedisCache) ZRemCount(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return c.client.ZRem(ctx, key, members...).Result()
+}
+
+// ZCard returns the number of members in a sorted set
+func (c *RedisCache) ZCard(ctx context.Context, key string) (int64, error) {
+	return c.client.ZCard(ctx, key).Result()
+}
+
+// Incr increments the integer at key, starting from 0, and returns its new value
+func (c *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.client.Incr(ctx, key).Result()
+}
+
+// ZRemRangeByRank removes the members of a sorted set ranked start to stop,
+// lowest score first; negative ranks count from the highest
+func (c *RedisCache) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) error {
+	return c.client.ZRemRangeByRank(ctx, key, start, stop).Err()
+}
+
 // SAdd adds members to a set
 func (c *RedisCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
 	return c.client.SAdd(ctx, key, members...).Err()
 }
 
+// SRem removes members from a set
+func (c *RedisCache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return c.client.SRem(ctx, key, members...).Err()
+}
+
 // SMembers returns all members of a set
 func (c *RedisCache) SMembers(ctx context.Context, key string) ([]string, error) {
 	return c.client.SMembers(ctx, key).Result()
 }
 
+// SInter returns the members common to every one of the sets
+func (c *RedisCache) SInter(ctx context.Context, keys ...string) ([]string, error) {
+	return c.client.SInter(ctx, keys...).Result()
+}
+
 // ZRangeByScore returns members with scores in the given range
 func (c *RedisCache) ZRangeByScore(ctx context.Context, key string, min, max float64, limit int64) ([]string, error) {
 	query := &redis.ZRangeBy{
@@ -131,6 +382,120 @@ func (c *RedisCache) ZRevRangeWithScores(ctx context.Context, key string, start,
 	return c.client.ZRevRangeWithScores(ctx, key, start, stop).Result()
 }
 
+// IncrSortedSetMember increments a member's score and refreshes the key's TTL in one round trip
+func (c *RedisCache) IncrSortedSetMember(ctx context.Context, key, member string, increment float64, ttl time.Duration) error {
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZIncrBy(ctx, key, increment, member)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// ScaleSortedSets multiplies the score of every member of each of keys by
+// factor and refreshes their TTL, in one round trip. Keys that have expired
+// stay gone.
+func (c *RedisCache) ScaleSortedSets(ctx context.Context, keys []string, factor float64, ttl time.Duration) error {
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZUnionStore(ctx, key, &redis.ZStore{Keys: []string{key}, Weights: []float64{factor}})
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scale %d sorted sets: %w", len(keys), err)
+	}
+	return nil
+}
+
+// ReplaceSortedSet replaces the contents of a sorted set and refreshes its TTL.
+// Members are written into a temporary key first and then RENAMEd over the live
+// key inside a MULTI/EXEC block, so readers always see either the previous or the
+// new complete snapshot, never an empty or partially populated set.
+func (c *RedisCache) ReplaceSortedSet(ctx context.Context, key string, members []redis.Z, ttl time.Duration) error {
+	if len(members) == 0 {
+		return c.client.Del(ctx, key).Err()
+	}
+
+	tmpKey := fmt.Sprintf("%s:tmp:%d", key, time.Now().UnixNano())
+
+	// Build the snapshot in chunks so very large tiles don't produce a single huge command
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for start := 0; start < len(members); start += sortedSetChunkSize {
+			end := start + sortedSetChunkSize
+			if end > len(members) {
+				end = len(members)
+			}
+			pipe.ZAdd(ctx, tmpKey, members[start:end]...)
+		}
+		// Make sure an abandoned temp key cleans itself up
+		pipe.Expire(ctx, tmpKey, ttl)
+		return nil
+	})
+	if err != nil {
+		c.client.Del(ctx, tmpKey)
+		return fmt.Errorf("failed to build sorted set %s: %w", key, err)
+	}
+
+	// Swap the snapshot in atomically
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Rename(ctx, tmpKey, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		c.client.Del(ctx, tmpKey)
+		return fmt.Errorf("failed to swap sorted set %s: %w", key, err)
+	}
+	return nil
+}
+
+// Publish sends a message on a pub/sub channel
+func (c *RedisCache) Publish(ctx context.Context, channel string, message interface{}) error {
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe opens a pub/sub subscription to the given channels. The caller must close it.
+// Subscriptions bypass command hooks, so channels are namespaced here rather than by namespaceHook.
+func (c *RedisCache) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	if c.prefix != "" {
+		namespaced := make([]string, len(channels))
+		for i, channel := range channels {
+			namespaced[i] = c.prefix + channel
+		}
+		channels = namespaced
+	}
+	return c.client.Subscribe(ctx, channels...)
+}
+
+// ScanKeys calls fn for every key matching pattern, a batch at a time, without
+// blocking the server like KEYS does. Keys are passed without the namespace.
+// SCAN's MATCH pattern is not a key, so it is namespaced here rather than by namespaceHook.
+func (c *RedisCache) ScanKeys(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, c.prefix+pattern, 1000).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan keys %s: %w", pattern, err)
+		}
+		if c.prefix != "" {
+			for i, key := range keys {
+				keys[i] = strings.TrimPrefix(key, c.prefix)
+			}
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
 func (c *RedisCache) Expire(ctx context.Context, key string, ttl time.Duration) error {
 	return c.client.Expire(ctx, key, ttl).Err()
 }
@@ -200,3 +565,9 @@ func (c *RedisCache) GetOrSet(ctx context.Context, key string, ttl time.Duration
 
 var ErrKeyNotFound = fmt.Errorf("key not found")
 
+// ErrPoolExhausted is returned when no pooled connection frees up within PoolTimeout
+var ErrPoolExhausted = errs.New(errs.ErrUnavailable, "redis connection pool exhausted")
+
+// sortedSetChunkSize bounds the number of members sent in a single ZADD
+const sortedSetChunkSize = 500
+
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// XAdd appends an entry with values to a stream and returns its ID. When
+// maxLen is positive the stream is trimmed to about that many entries.
+func (c *RedisCache) XAdd(ctx context.Context, stream string, values map[string]interface{}, maxLen int64) (string, error) {
+	return c.client.XAdd(ctx, xAddArgs(stream, values, maxLen)).Result()
+}
+
+// XGroupCreate creates a consumer group reading a stream from its start,
+// creating the stream when it doesn't exist. A group that already exists
+// is left as it is.
+func (c *RedisCache) XGroupCreate(ctx context.Context, stream, group string) error {
+	err := c.client.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// XReadGroup reads up to count entries of each stream never delivered to
+// group, as consumer, waiting up to block for one to arrive; a negative
+// block doesn't wait. Entries are returned by stream, and none when none
+// arrived in time.
+func (c *RedisCache) XReadGroup(ctx context.Context, group, consumer string, count int64, block time.Duration, streams ...string) (map[string][]redis.XMessage, error) {
+	args := &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  make([]string, 0, 2*len(streams)),
+		Count:    count,
+		Block:    block,
+	}
+	args.Streams = append(args.Streams, streams...)
+	for range streams {
+		args.Streams = append(args.Streams, ">")
+	}
+	read, err := c.client.XReadGroup(ctx, args).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string][]redis.XMessage, len(read))
+	for _, stream := range read {
+		// Replies name the streams with their namespace
+		messages[strings.TrimPrefix(stream.Stream, c.prefix)] = stream.Messages
+	}
+	return messages, nil
+}
+
+// XAutoClaim transfers to consumer up to count entries delivered to group
+// but left unacknowledged for at least minIdle, e.g. by a consumer that
+// crashed
+func (c *RedisCache) XAutoClaim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]redis.XMessage, error) {
+	messages, _, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    count,
+	}).Result()
+	return messages, err
+}
+
+// XDeliveries returns how many times an unacknowledged entry was delivered
+// to group, 0 when it isn't pending
+func (c *RedisCache) XDeliveries(ctx context.Context, stream, group, id string) (int64, error) {
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	return pending[0].RetryCount, nil
+}
+
+// XAckDel acknowledges entries delivered to group and deletes them from the stream
+func (c *RedisCache) XAckDel(ctx context.Context, stream, group string, ids ...string) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, stream, group, ids...)
+		pipe.XDel(ctx, stream, ids...)
+		return nil
+	})
+	return err
+}
+
+// XMove acknowledges and deletes entry id of a stream read by group, and
+// appends values to stream to in the same transaction, so the entry is
+// never lost nor left in both. to is trimmed like XAdd.
+func (c *RedisCache) XMove(ctx context.Context, stream, group, id, to string, values map[string]interface{}, maxLen int64) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, xAddArgs(to, values, maxLen))
+		pipe.XAck(ctx, stream, group, id)
+		pipe.XDel(ctx, stream, id)
+		return nil
+	})
+	return err
+}
+
+// XDefer acknowledges and deletes entry id of a stream read by group, and
+// adds member to sorted set with score at in the same transaction, e.g. to
+// schedule it to be added back to the stream later
+func (c *RedisCache) XDefer(ctx context.Context, stream, group, id, set, member string, at float64) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, set, redis.Z{Score: at, Member: member})
+		pipe.XAck(ctx, stream, group, id)
+		pipe.XDel(ctx, stream, id)
+		return nil
+	})
+	return err
+}
+
+// XLen returns the number of entries in a stream, 0 when it doesn't exist
+func (c *RedisCache) XLen(ctx context.Context, stream string) (int64, error) {
+	return c.client.XLen(ctx, stream).Result()
+}
+
+// XRange returns up to count entries of a stream with IDs from start to
+// end, oldest first; "-" and "+" stand for the ends of the stream
+func (c *RedisCache) XRange(ctx context.Context, stream, start, end string, count int64) ([]redis.XMessage, error) {
+	return c.client.XRangeN(ctx, stream, start, end, count).Result()
+}
+
+// XRevRange returns up to count entries of a stream with IDs from end down
+// to start, newest first
+func (c *RedisCache) XRevRange(ctx context.Context, stream, end, start string, count int64) ([]redis.XMessage, error) {
+	return c.client.XRevRangeN(ctx, stream, end, start, count).Result()
+}
+
+// XDel deletes entries from a stream
+func (c *RedisCache) XDel(ctx context.Context, stream string, ids ...string) error {
+	return c.client.XDel(ctx, stream, ids...).Err()
+}
+
+// XInfoGroups describes the consumer groups of a stream: their consumers,
+// pending entries and lag. A stream that doesn't exist has none.
+func (c *RedisCache) XInfoGroups(ctx context.Context, stream string) ([]redis.XInfoGroup, error) {
+	groups, err := c.client.XInfoGroups(ctx, stream).Result()
+	if err != nil && strings.Contains(err.Error(), "no such key") {
+		return nil, nil
+	}
+	return groups, err
+}
+
+// xAddArgs builds an XADD of values to stream, trimmed to about maxLen
+// entries when it is positive
+func xAddArgs(stream string, values map[string]interface{}, maxLen int64) *redis.XAddArgs {
+	args := &redis.XAddArgs{Stream: stream, Values: values}
+	if maxLen > 0 {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+	return args
+}
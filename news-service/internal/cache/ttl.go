@@ -0,0 +1,62 @@
+package cache
+
+import "time"
+
+// ContentKind is the kind of cached article content a TTL is chosen for
+type ContentKind int
+
+const (
+	ArticleContent ContentKind = iota
+	SummaryContent
+)
+
+// TTLPolicy decides how long cached article content lives, given when the
+// article was published
+type TTLPolicy func(kind ContentKind, publishedAt, now time.Time) time.Duration
+
+// ContentTTLPolicy is the policy behind ArticleTTLFor and SummaryTTLFor
+var ContentTTLPolicy TTLPolicy = AdaptiveTTL
+
+// ttlTier applies to content published less than maxAge ago
+type ttlTier struct {
+	maxAge     time.Duration
+	articleTTL time.Duration
+	summaryTTL time.Duration
+}
+
+// adaptiveTiers go from breaking news, which is still being corrected and
+// re-summarized, to settled content; anything older gets ArticleTTL and SummaryTTL
+var adaptiveTiers = []ttlTier{
+	{maxAge: 3 * time.Hour, articleTTL: 5 * time.Minute, summaryTTL: 15 * time.Minute},
+	{maxAge: 24 * time.Hour, articleTTL: 30 * time.Minute, summaryTTL: 2 * time.Hour},
+	{maxAge: 7 * 24 * time.Hour, articleTTL: 2 * time.Hour, summaryTTL: 24 * time.Hour},
+}
+
+// AdaptiveTTL gives recently published articles short TTLs, so corrections and
+// new summaries show up quickly, and older, stable articles long ones. Articles
+// dated in the future are treated as just published.
+func AdaptiveTTL(kind ContentKind, publishedAt, now time.Time) time.Duration {
+	age := now.Sub(publishedAt)
+	for _, tier := range adaptiveTiers {
+		if age < tier.maxAge {
+			if kind == SummaryContent {
+				return tier.summaryTTL
+			}
+			return tier.articleTTL
+		}
+	}
+	if kind == SummaryContent {
+		return SummaryTTL
+	}
+	return ArticleTTL
+}
+
+// ArticleTTLFor returns the TTL for a cached article published at publishedAt
+func ArticleTTLFor(publishedAt time.Time) time.Duration {
+	return ContentTTLPolicy(ArticleContent, publishedAt, time.Now())
+}
+
+// SummaryTTLFor returns the TTL for the cached summary of an article published at publishedAt
+func SummaryTTLFor(publishedAt time.Time) time.Duration {
+	return ContentTTLPolicy(SummaryContent, publishedAt, time.Now())
+}
@@ -0,0 +1,53 @@
+// Package errs defines the domain error kinds shared by the repository,
+// service and HTTP layers. Lower layers tag their errors with a kind; the
+// HTTP layer maps kinds to status codes in one place.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds, matched with errors.Is
+var (
+	// ErrNotFound means the requested entity does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the write clashes with existing state
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable means a backend is down, saturated or timed out; retrying may succeed
+	ErrUnavailable = errors.New("unavailable")
+	// ErrInvalid means the caller supplied a malformed or unsupported argument
+	ErrInvalid = errors.New("invalid argument")
+	// ErrForbidden means the caller's plan doesn't allow the request
+	ErrForbidden = errors.New("forbidden")
+)
+
+// kindError carries a kind without changing the message of the error it wraps
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+func (e *kindError) Unwrap() error { return e.err }
+
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// New returns an error with the given message, classified as kind
+func New(kind error, message string) error {
+	return &kindError{kind: kind, err: errors.New(message)}
+}
+
+// Errorf formats an error like fmt.Errorf (including %w wrapping) and classifies it as kind
+func Errorf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// Wrap classifies err as kind, keeping its message. A nil err stays nil.
+func Wrap(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
@@ -0,0 +1,223 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"news-system/internal/replay"
+)
+
+// QueryResult is the answer to one judgment and how it scored
+type QueryResult struct {
+	ID         string   `json:"id"`
+	Query      string   `json:"query"`
+	K          int      `json:"k"`
+	Strategy   string   `json:"strategy,omitempty"`
+	ArticleIDs []string `json:"article_ids"`
+	DurationMs float64  `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+	Scores     Scores   `json:"scores"`
+}
+
+// Report holds the scores of every judgment and their means
+type Report struct {
+	Target  string    `json:"target"`
+	Time    time.Time `json:"time"`
+	Queries int       `json:"queries"`
+	// Errors counts queries the service failed to answer; they're left out of Mean
+	Errors  int           `json:"errors"`
+	Mean    Scores        `json:"mean"`
+	Results []QueryResult `json:"results"`
+}
+
+// Run asks target every judged query, concurrency at a time, and scores the answers
+func Run(ctx context.Context, target *replay.Target, judgments []Judgment, concurrency int) *Report {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]QueryResult, len(judgments))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = evaluate(ctx, target, judgments[i])
+			}
+		}()
+	}
+	for i := range judgments {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &Report{Target: target.BaseURL, Time: time.Now().UTC(), Queries: len(results), Results: results}
+	var answered int
+	for _, result := range results {
+		if result.Error != "" {
+			report.Errors++
+			continue
+		}
+		answered++
+		report.Mean.NDCG += result.Scores.NDCG
+		report.Mean.MRR += result.Scores.MRR
+		report.Mean.Recall += result.Scores.Recall
+		report.Mean.Precision += result.Scores.Precision
+	}
+	if answered > 0 {
+		report.Mean.NDCG /= float64(answered)
+		report.Mean.MRR /= float64(answered)
+		report.Mean.Recall /= float64(answered)
+		report.Mean.Precision /= float64(answered)
+	}
+	return report
+}
+
+// evaluate asks one judged query and scores the answer
+func evaluate(ctx context.Context, target *replay.Target, judgment Judgment) QueryResult {
+	answer := target.Query(ctx, judgment.Request())
+	result := QueryResult{
+		ID:         judgment.ID,
+		Query:      judgment.Query,
+		K:          judgment.Limit,
+		Strategy:   answer.Strategy,
+		ArticleIDs: answer.ArticleIDs,
+		DurationMs: answer.DurationMs,
+		Error:      answer.Error,
+	}
+	if result.Error == "" {
+		result.Scores = score(answer.ArticleIDs, judgment.grades(), judgment.Limit)
+	}
+	return result
+}
+
+// Print writes the mean scores followed by the worst queries by NDCG, up to show
+func (r *Report) Print(w io.Writer, show int) {
+	fmt.Fprintf(w, "%d queries against %s, %d errors\n", r.Queries, r.Target, r.Errors)
+	fmt.Fprintf(w, "  ndcg       %.4f\n  mrr        %.4f\n  recall     %.4f\n  precision  %.4f\n",
+		r.Mean.NDCG, r.Mean.MRR, r.Mean.Recall, r.Mean.Precision)
+
+	worst := make([]QueryResult, len(r.Results))
+	copy(worst, r.Results)
+	sort.SliceStable(worst, func(i, j int) bool {
+		// Failed queries first, then by NDCG
+		if failedI, failedJ := worst[i].Error != "", worst[j].Error != ""; failedI != failedJ {
+			return failedI
+		}
+		return worst[i].Scores.NDCG < worst[j].Scores.NDCG
+	})
+	if len(worst) > show {
+		worst = worst[:show]
+	}
+	if len(worst) > 0 {
+		fmt.Fprintf(w, "\nworst queries:\n")
+	}
+	for _, result := range worst {
+		if result.Error != "" {
+			fmt.Fprintf(w, "  %-40q error: %s\n", result.ID, result.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  %-40q ndcg %.3f  mrr %.3f  recall %.3f  (%s)\n",
+			result.ID, result.Scores.NDCG, result.Scores.MRR, result.Scores.Recall, result.Strategy)
+	}
+}
+
+// ReadReport reads a report written as JSON by an earlier run
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Change is how one query's scores moved between two reports
+type Change struct {
+	ID     string  `json:"id"`
+	Before Scores  `json:"before"`
+	After  Scores  `json:"after"`
+	NDCG   float64 `json:"ndcg_delta"`
+}
+
+// Comparison is the difference between a baseline report and a later one
+type Comparison struct {
+	// Delta is the later mean minus the baseline mean
+	Delta Scores `json:"delta"`
+	// Changes lists queries present in both reports whose NDCG moved, worst first
+	Changes []Change `json:"changes"`
+}
+
+// Compare measures how after moved relative to before, matching queries by ID
+func Compare(before, after *Report) Comparison {
+	comparison := Comparison{
+		Delta: Scores{
+			NDCG:      after.Mean.NDCG - before.Mean.NDCG,
+			MRR:       after.Mean.MRR - before.Mean.MRR,
+			Recall:    after.Mean.Recall - before.Mean.Recall,
+			Precision: after.Mean.Precision - before.Mean.Precision,
+		},
+		Changes: []Change{},
+	}
+
+	previous := make(map[string]QueryResult, len(before.Results))
+	for _, result := range before.Results {
+		previous[result.ID] = result
+	}
+	for _, result := range after.Results {
+		old, ok := previous[result.ID]
+		if !ok || old.Error != "" || result.Error != "" {
+			continue
+		}
+		if delta := result.Scores.NDCG - old.Scores.NDCG; delta != 0 {
+			comparison.Changes = append(comparison.Changes, Change{ID: result.ID, Before: old.Scores, After: result.Scores, NDCG: delta})
+		}
+	}
+	sort.Slice(comparison.Changes, func(i, j int) bool {
+		return comparison.Changes[i].NDCG < comparison.Changes[j].NDCG
+	})
+	return comparison
+}
+
+// Print writes the mean deltas followed by the largest regressions and improvements, up to show each
+func (c Comparison) Print(w io.Writer, show int) {
+	fmt.Fprintf(w, "\nchange from baseline:\n")
+	fmt.Fprintf(w, "  ndcg       %+.4f\n  mrr        %+.4f\n  recall     %+.4f\n  precision  %+.4f\n",
+		c.Delta.NDCG, c.Delta.MRR, c.Delta.Recall, c.Delta.Precision)
+
+	var regressed, improved []Change
+	for _, change := range c.Changes {
+		if change.NDCG < 0 {
+			regressed = append(regressed, change)
+		} else {
+			improved = append([]Change{change}, improved...)
+		}
+	}
+	for _, group := range []struct {
+		name    string
+		changes []Change
+	}{{"regressed", regressed}, {"improved", improved}} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", group.name, len(group.changes))
+		for i, change := range group.changes {
+			if i == show {
+				break
+			}
+			fmt.Fprintf(w, "  %-40q ndcg %.3f -> %.3f\n", change.ID, change.Before.NDCG, change.After.NDCG)
+		}
+	}
+}
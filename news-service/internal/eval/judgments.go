@@ -0,0 +1,118 @@
+// Package eval measures retrieval quality against labeled queries: each
+// judgment lists the articles relevant to a query, optionally graded, and the
+// service's answer is scored with NDCG, MRR, recall and precision so ranking
+// changes come with before and after numbers.
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"news-system/internal/services/news"
+)
+
+// Judgment is a labeled query
+type Judgment struct {
+	// ID names the judgment in reports; it defaults to the query
+	ID       string   `json:"id,omitempty"`
+	Query    string   `json:"query"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	RadiusKm *float64 `json:"radius_km,omitempty"`
+	Lang     string   `json:"lang,omitempty"`
+	// Limit is the number of results requested and the cutoff k of every metric
+	Limit int `json:"limit,omitempty"`
+	// Relevant lists articles relevant with grade 1
+	Relevant []string `json:"relevant,omitempty"`
+	// Grades maps article IDs to graded relevance, e.g. 1 (related) to 3
+	// (exactly what was asked for); it overrides Relevant for the same ID
+	Grades map[string]int `json:"grades,omitempty"`
+}
+
+// grades merges Relevant and Grades
+func (j Judgment) grades() map[string]int {
+	grades := make(map[string]int, len(j.Relevant)+len(j.Grades))
+	for _, id := range j.Relevant {
+		grades[id] = 1
+	}
+	for id, grade := range j.Grades {
+		grades[id] = grade
+	}
+	return grades
+}
+
+// hasRelevant reports whether any article is graded above 0
+func (j Judgment) hasRelevant() bool {
+	for _, grade := range j.grades() {
+		if grade > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Request builds the query the judgment labels
+func (j Judgment) Request() news.QueryRequest {
+	return news.QueryRequest{
+		Query:  j.Query,
+		Lat:    j.Lat,
+		Lon:    j.Lon,
+		Radius: j.RadiusKm,
+		Limit:  j.Limit,
+		Lang:   j.Lang,
+	}
+}
+
+// ReadJudgments reads judgments from a JSON array or one judgment per line,
+// giving each a limit of defaultLimit unless it sets one
+func ReadJudgments(path string, defaultLimit int) ([]Judgment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+
+	var judgments []Judgment
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &judgments); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 || text[0] == '#' {
+				continue
+			}
+			var judgment Judgment
+			if err := json.Unmarshal(text, &judgment); err != nil {
+				return nil, fmt.Errorf("failed to parse %s line %d: %w", path, line, err)
+			}
+			judgments = append(judgments, judgment)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	for i := range judgments {
+		j := &judgments[i]
+		if j.Query == "" {
+			return nil, fmt.Errorf("judgment %d in %s has no query", i+1, path)
+		}
+		if j.ID == "" {
+			j.ID = j.Query
+		}
+		if j.Limit <= 0 {
+			j.Limit = defaultLimit
+		}
+		if !j.hasRelevant() {
+			return nil, fmt.Errorf("judgment %q in %s lists no relevant articles", j.ID, path)
+		}
+	}
+	return judgments, nil
+}
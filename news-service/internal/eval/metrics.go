@@ -0,0 +1,68 @@
+package eval
+
+import (
+	"math"
+	"sort"
+)
+
+// Scores are the quality metrics of one ranked answer, cut off at k
+type Scores struct {
+	// NDCG is the graded, rank-discounted gain relative to the ideal ranking
+	NDCG float64 `json:"ndcg"`
+	// MRR is the reciprocal rank of the first relevant article, 0 if none is returned
+	MRR float64 `json:"mrr"`
+	// Recall is the share of relevant articles returned
+	Recall float64 `json:"recall"`
+	// Precision is the share of the k slots holding a relevant article
+	Precision float64 `json:"precision"`
+}
+
+// score rates ranked against grades. Articles graded 0 or not graded at all
+// count as irrelevant.
+func score(ranked []string, grades map[string]int, k int) Scores {
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	var relevant []int
+	for _, grade := range grades {
+		if grade > 0 {
+			relevant = append(relevant, grade)
+		}
+	}
+	if len(relevant) == 0 || k <= 0 {
+		return Scores{}
+	}
+
+	var scores Scores
+	var dcg float64
+	found := 0
+	for i, id := range ranked {
+		grade := grades[id]
+		if grade <= 0 {
+			continue
+		}
+		dcg += gain(grade, i)
+		found++
+		if scores.MRR == 0 {
+			scores.MRR = 1 / float64(i+1)
+		}
+	}
+
+	// The ideal ranking puts the highest grades first
+	sort.Sort(sort.Reverse(sort.IntSlice(relevant)))
+	var idcg float64
+	for i := 0; i < len(relevant) && i < k; i++ {
+		idcg += gain(relevant[i], i)
+	}
+
+	scores.NDCG = dcg / idcg
+	scores.Recall = float64(found) / float64(len(relevant))
+	scores.Precision = float64(found) / float64(k)
+	return scores
+}
+
+// gain is the discounted gain of an article of grade at zero-based rank
+func gain(grade, rank int) float64 {
+	return (math.Pow(2, float64(grade)) - 1) / math.Log2(float64(rank)+2)
+}
@@ -0,0 +1,173 @@
+// Package eventqueue ingests user events from a message queue, so that high
+// volume click streams reach the trending subsystem asynchronously instead
+// of through one HTTP request per event. Kafka and NATS are supported as
+// sources; each message holds one event in the JSON of POST /api/v1/events.
+package eventqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+	"news-system/internal/services/trending"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Source delivers messages from a queue
+type Source interface {
+	// Name identifies the source in logs and metrics, e.g. "kafka"
+	Name() string
+	// Next blocks until the next message arrives or ctx is done
+	Next(ctx context.Context) (Message, error)
+	// Close releases the connection
+	Close() error
+}
+
+// Message is one message read from a source
+type Message struct {
+	Data []byte
+	// Ack marks the message as processed, so it isn't delivered again; nil
+	// for sources that don't redeliver
+	Ack func(ctx context.Context) error
+}
+
+// Options configures a Consumer
+type Options struct {
+	// Retries is how many more times an event is tried when storing it fails
+	// for a reason other than the event itself; it is dropped after
+	Retries int
+	// Backoff is the delay before the first retry, doubling per attempt
+	Backoff time.Duration
+}
+
+// Defaults for unset Options
+const (
+	defaultBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// Consumer reads events from a source and records them with the trending
+// scorer. Messages are handled one at a time, in order; run more instances,
+// which share the source's consumer group, to consume faster.
+type Consumer struct {
+	source Source
+	scorer *trending.TrendingScorer
+	opts   Options
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewConsumer creates a consumer recording the events of source
+func NewConsumer(source Source, scorer *trending.TrendingScorer, opts Options) *Consumer {
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	return &Consumer{source: source, scorer: scorer, opts: opts, done: make(chan struct{})}
+}
+
+// Start begins consuming in the background
+func (c *Consumer) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(c.done)
+		c.run(ctx)
+	}()
+	log.Info().Str("source", c.source.Name()).Msg("Event queue consumer started")
+}
+
+// Stop stops consuming, waits for the message in hand and closes the source
+func (c *Consumer) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+	if err := c.source.Close(); err != nil {
+		log.Warn().Err(err).Str("source", c.source.Name()).Msg("Failed to close event queue")
+	}
+	log.Info().Str("source", c.source.Name()).Msg("Event queue consumer stopped")
+}
+
+// run reads messages until ctx is done, backing off while the source fails
+func (c *Consumer) run(ctx context.Context) {
+	backoff := c.opts.Backoff
+	for {
+		msg, err := c.source.Next(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("source", c.source.Name()).Dur("retry_in", backoff).Msg("Failed to read from event queue")
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(2*backoff, maxBackoff)
+			continue
+		}
+		backoff = c.opts.Backoff
+
+		status := c.handle(ctx, msg.Data)
+		if ctx.Err() != nil {
+			// Unacknowledged, the message is delivered again after a restart
+			return
+		}
+		metrics.EventQueueMessages.WithLabelValues(c.source.Name(), status).Inc()
+		if msg.Ack != nil {
+			if err := msg.Ack(ctx); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Str("source", c.source.Name()).Msg("Failed to acknowledge event queue message")
+			}
+		}
+	}
+}
+
+// handle records the event in data, retrying failures that aren't the
+// event's fault, and returns the message's status for the metrics: stored,
+// rejected (invalid, or for an unknown article), ignored (a duplicate or
+// from a likely bot) or failed
+func (c *Consumer) handle(ctx context.Context, data []byte) string {
+	var req trending.EventRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		log.Debug().Err(err).Str("source", c.source.Name()).Msg("Rejected malformed event queue message")
+		return "rejected"
+	}
+
+	backoff := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		_, err := c.scorer.IngestEvent(ctx, req)
+		var ignored *trending.IgnoredError
+		switch {
+		case err == nil:
+			return "stored"
+		case errors.As(err, &ignored):
+			return "ignored"
+		case errors.Is(err, errs.ErrInvalid) || errors.Is(err, errs.ErrNotFound):
+			log.Debug().Err(err).Str("article_id", req.ArticleID).Msg("Rejected event from queue")
+			return "rejected"
+		case attempt >= c.opts.Retries:
+			log.Error().Err(err).Str("article_id", req.ArticleID).Int("attempts", attempt+1).Msg("Dropped event from queue")
+			return "failed"
+		}
+		if !sleep(ctx, backoff) {
+			return "failed"
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// sleep waits for d, returning false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
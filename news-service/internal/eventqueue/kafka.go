@@ -0,0 +1,52 @@
+package eventqueue
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSource reads a Kafka topic as a member of a consumer group, so the
+// topic's partitions are spread across instances. Offsets are committed as
+// messages are acknowledged, so a message is delivered at least once.
+type KafkaSource struct {
+	reader *kafka.Reader
+}
+
+// NewKafkaSource creates a source reading topic from brokers in group. A
+// new group starts at the end of the topic rather than replaying its
+// history.
+func NewKafkaSource(brokers []string, topic, group string) *KafkaSource {
+	return &KafkaSource{reader: kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     group,
+		StartOffset: kafka.LastOffset,
+		MinBytes:    1,
+		MaxBytes:    10 << 20,
+	})}
+}
+
+// Name returns "kafka"
+func (s *KafkaSource) Name() string {
+	return "kafka"
+}
+
+// Next fetches the next message of the group's partitions
+func (s *KafkaSource) Next(ctx context.Context) (Message, error) {
+	msg, err := s.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Data: msg.Value,
+		Ack: func(ctx context.Context) error {
+			return s.reader.CommitMessages(ctx, msg)
+		},
+	}, nil
+}
+
+// Close leaves the consumer group
+func (s *KafkaSource) Close() error {
+	return s.reader.Close()
+}
@@ -0,0 +1,51 @@
+package eventqueue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSource reads a NATS subject as a member of a queue group, so each
+// message goes to one instance. Core NATS doesn't redeliver, so messages
+// published while no instance is subscribed, or beyond what a slow instance
+// buffers, are lost.
+type NATSSource struct {
+	conn *nats.Conn
+	sub  *nats.Subscription
+}
+
+// NewNATSSource connects to the server at url and subscribes to subject in
+// queue group
+func NewNATSSource(url, subject, queue string) (*NATSSource, error) {
+	conn, err := nats.Connect(url, nats.Name("news-service"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	sub, err := conn.QueueSubscribeSync(subject, queue)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	return &NATSSource{conn: conn, sub: sub}, nil
+}
+
+// Name returns "nats"
+func (s *NATSSource) Name() string {
+	return "nats"
+}
+
+// Next waits for the next message on the subject
+func (s *NATSSource) Next(ctx context.Context) (Message, error) {
+	msg, err := s.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Data: msg.Data}, nil
+}
+
+// Close drains the subscription and closes the connection
+func (s *NATSSource) Close() error {
+	return s.conn.Drain()
+}
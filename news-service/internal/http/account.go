@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"news-system/internal/plans"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AccountHandler describes the calling API key's entitlements
+type AccountHandler struct{}
+
+// NewAccountHandler creates a new AccountHandler
+func NewAccountHandler() *AccountHandler {
+	return &AccountHandler{}
+}
+
+// AccountResponse is the response body of GET /api/v1/account
+type AccountResponse struct {
+	// APIKey is the caller's key with all but its last characters masked; empty for anonymous callers
+	APIKey    string     `json:"api_key,omitempty"`
+	Anonymous bool       `json:"anonymous"`
+	Plan      plans.Plan `json:"plan"`
+}
+
+// RegisterRoutes registers account routes
+func (h *AccountHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/api/v1/account", h.GetAccount)
+}
+
+// GetAccount returns the plan the caller is served under
+func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	caller := plans.FromContext(r.Context())
+	resp := AccountResponse{
+		Anonymous: caller.Key == "",
+		Plan:      caller.Plan,
+	}
+	if caller.Key != "" {
+		resp.APIKey = plans.MaskKey(caller.Key)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,574 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/ingest"
+	"news-system/internal/jobqueue"
+	"news-system/internal/repo"
+	"news-system/internal/services/llm"
+	"news-system/internal/services/moderation"
+	"news-system/internal/services/news"
+	"news-system/internal/services/queryaudit"
+	"news-system/internal/services/shadow"
+	"news-system/internal/subscriptions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminHandler handles operational endpoints under /admin
+type AdminHandler struct {
+	exportJobs *ingest.ExportJobs
+	queryAudit *queryaudit.Log
+	shadow     *shadow.Log
+	llmUsage   *llm.UsageTracker
+	summaries  *news.NewsService
+	feedback   *news.NewsService
+	moderation *moderation.Queue
+	articles   *news.NewsService
+	deliveries *subscriptions.Service
+	jobs       *jobqueue.Queues
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(exportJobs *ingest.ExportJobs) *AdminHandler {
+	return &AdminHandler{
+		exportJobs: exportJobs,
+	}
+}
+
+// EnableQueryAudit serves a search over the query audit log
+func (h *AdminHandler) EnableQueryAudit(queryAudit *queryaudit.Log) {
+	h.queryAudit = queryAudit
+}
+
+// EnableShadowResults serves the shadow strategy comparisons
+func (h *AdminHandler) EnableShadowResults(shadowLog *shadow.Log) {
+	h.shadow = shadowLog
+}
+
+// EnableLLMUsage serves the LLM token usage recorded by tracker
+func (h *AdminHandler) EnableLLMUsage(tracker *llm.UsageTracker) {
+	h.llmUsage = tracker
+}
+
+// EnableSummaryRegeneration lets editors regenerate article summaries
+func (h *AdminHandler) EnableSummaryRegeneration(newsService *news.NewsService) {
+	h.summaries = newsService
+}
+
+// EnableFeedbackReport serves the aggregated reader feedback
+func (h *AdminHandler) EnableFeedbackReport(newsService *news.NewsService) {
+	h.feedback = newsService
+}
+
+// EnableModeration serves the queue of flagged articles and its audit log,
+// rejecting articles by deleting them from newsService
+func (h *AdminHandler) EnableModeration(queue *moderation.Queue, newsService *news.NewsService) {
+	h.moderation = queue
+	h.articles = newsService
+}
+
+// EnableDeliveries serves the delivery attempts of every subscription and
+// re-enabling the subscriptions disabled for failing persistently
+func (h *AdminHandler) EnableDeliveries(service *subscriptions.Service) {
+	h.deliveries = service
+}
+
+// EnableJobQueues serves the status and dead letters of the job queues, and
+// lets summaries be regenerated in the background
+func (h *AdminHandler) EnableJobQueues(queues *jobqueue.Queues) {
+	h.jobs = queues
+}
+
+// RegisterRoutes registers admin routes
+func (h *AdminHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/export-jobs", h.CreateExportJob)
+		r.Get("/export-jobs/{id}", h.GetExportJob)
+		r.Post("/export-jobs/{id}/resume", h.ResumeExportJob)
+		if h.queryAudit != nil {
+			r.Get("/query-audit", h.SearchQueryAudit)
+		}
+		if h.shadow != nil {
+			r.Get("/shadow", h.GetShadowResults)
+		}
+		if h.llmUsage != nil {
+			r.Get("/llm/usage", h.GetLLMUsage)
+		}
+		if h.summaries != nil {
+			r.Post("/articles/{id}/summarize", h.RegenerateSummary)
+		}
+		if h.feedback != nil {
+			r.Get("/feedback", h.GetFeedbackReport)
+		}
+		if h.moderation != nil {
+			r.Get("/moderation", h.GetModerationQueue)
+			r.Get("/moderation/audit", h.GetModerationAudit)
+			r.Post("/moderation/{id}/approve", h.ApproveArticle)
+			r.Post("/moderation/{id}/demote", h.DemoteArticle)
+			r.Post("/moderation/{id}/reject", h.RejectArticle)
+		}
+		if h.deliveries != nil {
+			r.Get("/deliveries", h.SearchDeliveries)
+			r.Post("/subscriptions/{id}/enable", h.EnableSubscription)
+		}
+		if h.jobs != nil {
+			r.Get("/queues", h.GetQueues)
+			r.Get("/queues/stats", h.GetQueueScalerStats)
+			r.Get("/queues/{name}/dead", h.GetDeadJobs)
+			r.Post("/queues/{name}/dead/{id}/retry", h.RetryDeadJob)
+		}
+	})
+}
+
+// CreateExportJob starts an export of every article to object storage
+func (h *AdminHandler) CreateExportJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.exportJobs.Create(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetExportJob reports the progress of an export job
+func (h *AdminHandler) GetExportJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.exportJobs.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// ResumeExportJob restarts a failed or stalled export job where it stopped
+func (h *AdminHandler) ResumeExportJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.exportJobs.Resume(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// SearchQueryAudit returns the newest audited queries matching the q (query
+// substring), strategy, article_id, since and until (RFC 3339) parameters
+func (h *AdminHandler) SearchQueryAudit(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	filter := queryaudit.Filter{
+		Query:     params.Get("q"),
+		Strategy:  params.Get("strategy"),
+		ArticleID: params.Get("article_id"),
+		Limit:     50,
+	}
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := params.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid %s %q: expected an RFC 3339 time", name, value))
+				return
+			}
+			*t = parsed
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 500 {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid limit %q: expected 1-500", value))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.queryAudit.Search(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// writeJSON writes v as a JSON response with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// GetShadowResults summarizes the newest shadow comparisons, up to limit
+// (default 1000), and returns the newest of them up to show (default 20)
+func (h *AdminHandler) GetShadowResults(w http.ResponseWriter, r *http.Request) {
+	limit, show := 1000, 20
+	for name, value := range map[string]*int{"limit": &limit, "show": &show} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > 10000 {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid %s %q: expected 0-10000", name, raw))
+			return
+		}
+		*value = parsed
+	}
+	if limit < 1 {
+		writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid limit 0: expected 1-10000"))
+		return
+	}
+
+	results, summary, err := h.shadow.Recent(r.Context(), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(results) > show {
+		results = results[:show]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"summary": summary,
+		"results": results,
+	})
+}
+
+// GetLLMUsage reports the tokens and estimated cost of the LLM calls made
+// since this instance started, by model, operation and endpoint
+func (h *AdminHandler) GetLLMUsage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.llmUsage.Report())
+}
+
+// regenerateSummaryRequest optionally overrides how a summary is regenerated
+type regenerateSummaryRequest struct {
+	// Style is brief, detailed or bullets; empty uses the configured prompt
+	Style string `json:"style"`
+	// Model replaces the configured model, or deployment for Azure OpenAI
+	Model string `json:"model"`
+}
+
+// RegenerateSummary summarizes an article again right away, replacing its
+// stored summary, and returns the new one. The body is optional. With
+// async=true the summary is regenerated by a job queue worker instead,
+// ahead of background jobs, and the queued job is returned.
+func (h *AdminHandler) RegenerateSummary(w http.ResponseWriter, r *http.Request) {
+	var req regenerateSummaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+	if r.URL.Query().Get("async") == "true" {
+		if h.jobs == nil {
+			badRequest(w, r, "async summaries need the job queue")
+			return
+		}
+		if err := llm.ValidateSummaryStyle(req.Style); err != nil {
+			writeError(w, r, errs.Wrap(errs.ErrInvalid, err))
+			return
+		}
+		job, err := h.jobs.Enqueue(r.Context(), news.SummaryQueue, news.SummaryJob{
+			ArticleID: chi.URLParam(r, "id"),
+			Style:     req.Style,
+			Model:     req.Model,
+		}, jobqueue.EnqueueOptions{Priority: jobqueue.PriorityHigh})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	ctx := llm.WithEndpoint(r.Context(), "admin-summarize")
+	summary, err := h.summaries.RegenerateSummary(ctx, chi.URLParam(r, "id"), llm.SummaryOverride{
+		Style: req.Style,
+		Model: req.Model,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// GetFeedbackReport counts the reader feedback given since since (RFC 3339,
+// default 7 days ago) and lists up to limit (default 50) stored summaries
+// with at least min_down (default 2) thumbs down since they were generated,
+// most disliked first, to regenerate
+func (h *AdminHandler) GetFeedbackReport(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	if value := params.Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid since %q: expected an RFC 3339 time", value))
+			return
+		}
+		since = parsed
+	}
+	minDown, limit := 2, 50
+	for name, value := range map[string]*int{"min_down": &minDown, "limit": &limit} {
+		raw := params.Get(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 500 {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid %s %q: expected 1-500", name, raw))
+			return
+		}
+		*value = parsed
+	}
+
+	report, err := h.feedback.FeedbackReport(r.Context(), since, int64(minDown), int32(limit))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// GetModerationQueue lists up to limit (default 50) articles flagged by
+// reader reports, spam heuristics or the safety blocklist, most reported first
+func (h *AdminHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	limit, ok := moderationLimit(w, r)
+	if !ok {
+		return
+	}
+
+	flagged, err := h.moderation.Flagged(r.Context(), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"articles": flagged,
+		"total":    len(flagged),
+	})
+}
+
+// GetModerationAudit lists up to limit (default 50) of the newest reviews,
+// of one article with article_id
+func (h *AdminHandler) GetModerationAudit(w http.ResponseWriter, r *http.Request) {
+	limit, ok := moderationLimit(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.moderation.Audit(r.Context(), r.URL.Query().Get("article_id"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// moderationLimit parses the limit of the moderation listings, writing an
+// error when it's invalid
+func moderationLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 50, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > 500 {
+		writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid limit %q: expected 1-500", value))
+		return 0, false
+	}
+	return limit, true
+}
+
+// ApproveArticle keeps a flagged article as it is, dropping its reports and
+// lifting its demotion. The body, {"reviewer", "note"}, is optional, as for
+// the other reviews, which return the audit entry they recorded.
+func (h *AdminHandler) ApproveArticle(w http.ResponseWriter, r *http.Request) {
+	h.reviewArticle(w, r, h.moderation.Approve)
+}
+
+// DemoteArticle ranks an article below the rest of its results page and
+// takes it off the moderation queue
+func (h *AdminHandler) DemoteArticle(w http.ResponseWriter, r *http.Request) {
+	h.reviewArticle(w, r, h.moderation.Demote)
+}
+
+// RejectArticle deletes a flagged article
+func (h *AdminHandler) RejectArticle(w http.ResponseWriter, r *http.Request) {
+	h.reviewArticle(w, r, func(ctx context.Context, articleID string, review moderation.Review) (moderation.AuditEntry, error) {
+		return h.moderation.Reject(ctx, articleID, review, h.articles.DeleteArticle)
+	})
+}
+
+// reviewArticle applies a review action to the article in the path
+func (h *AdminHandler) reviewArticle(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, articleID string, review moderation.Review) (moderation.AuditEntry, error)) {
+	var review moderation.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	entry, err := action(r.Context(), chi.URLParam(r, "id"), review)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// SearchDeliveries lists the newest attempts to send subscription messages,
+// filtered by subscription_id, channel, status (delivered or failed) and
+// since (RFC 3339), up to limit (default 50, at most 500) from offset
+func (h *AdminHandler) SearchDeliveries(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	arg := repo.SearchSubscriptionDeliveriesParams{Limit: 50}
+	for name, filter := range map[string]**string{"subscription_id": &arg.SubscriptionID, "channel": &arg.Channel, "status": &arg.Status} {
+		if value := params.Get(name); value != "" {
+			*filter = &value
+		}
+	}
+	if value := params.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid since %q: expected an RFC 3339 time", value))
+			return
+		}
+		arg.Since = &since
+	}
+	limit, ok := moderationLimit(w, r)
+	if !ok {
+		return
+	}
+	arg.Limit = int32(limit)
+	if value := params.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid offset %q: expected a non-negative integer", value))
+			return
+		}
+		arg.Offset = int32(offset)
+	}
+
+	deliveries, err := h.deliveries.SearchDeliveries(r.Context(), arg)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// EnableSubscription re-enables any subscription disabled for failing
+// persistently, clearing its failures
+func (h *AdminHandler) EnableSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, err := h.deliveries.EnableAny(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// GetQueues reports the length, pending jobs, consumers, oldest job and
+// dead letters of every job queue
+func (h *AdminHandler) GetQueues(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.jobs.Stats(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"queues": stats,
+		"total":  len(stats),
+	})
+}
+
+// queueScalerStats is the backlog of a queue in the flat form external
+// autoscalers, such as KEDA's metrics-api scaler, read a value from
+type queueScalerStats struct {
+	// Backlog is the jobs waiting for a worker or running
+	Backlog          int64   `json:"backlog"`
+	Waiting          int64   `json:"waiting"`
+	Running          int64   `json:"running"`
+	Delayed          int64   `json:"delayed"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+	ProcessingRate   float64 `json:"processing_rate"`
+	Consumers        int64   `json:"consumers"`
+}
+
+// GetQueueScalerStats reports the backlog of every job queue keyed by
+// queue name, e.g. for a KEDA valueLocation of "queues.summaries.backlog",
+// or of the one queue given as ?queue= at the top level
+func (h *AdminHandler) GetQueueScalerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.jobs.Stats(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	queues := make(map[string]queueScalerStats, len(stats))
+	for _, s := range stats {
+		var waiting int64
+		for _, n := range s.Waiting {
+			waiting += n
+		}
+		queues[s.Name] = queueScalerStats{
+			Backlog:          waiting + s.Pending,
+			Waiting:          waiting,
+			Running:          s.Pending,
+			Delayed:          s.Delayed,
+			OldestAgeSeconds: s.OldestAgeSeconds,
+			ProcessingRate:   s.ProcessingRate,
+			Consumers:        s.Consumers,
+		}
+	}
+
+	if name := r.URL.Query().Get("queue"); name != "" {
+		queue, ok := queues[name]
+		if !ok {
+			writeError(w, r, errs.Errorf(errs.ErrNotFound, "queue not found: %s", name))
+			return
+		}
+		writeJSON(w, http.StatusOK, queue)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"queues": queues})
+}
+
+// GetDeadJobs lists up to limit (default 50, at most 500) jobs of a queue
+// that failed every attempt, newest first
+func (h *AdminHandler) GetDeadJobs(w http.ResponseWriter, r *http.Request) {
+	limit, ok := moderationLimit(w, r)
+	if !ok {
+		return
+	}
+	jobs, err := h.jobs.DeadLetters(r.Context(), chi.URLParam(r, "name"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"jobs":  jobs,
+		"total": len(jobs),
+	})
+}
+
+// RetryDeadJob moves a dead job back onto its queue to be attempted again
+func (h *AdminHandler) RetryDeadJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Requeue(r.Context(), chi.URLParam(r, "name"), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
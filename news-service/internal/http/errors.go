@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"news-system/internal/errs"
+	"news-system/internal/services/news"
+	"news-system/internal/validation"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errorStatus maps a domain error kind to its HTTP status and error code
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errs.ErrInvalid):
+		return http.StatusBadRequest, news.ErrCodeValidation
+	case errors.Is(err, errs.ErrForbidden):
+		return http.StatusForbidden, news.ErrCodeForbidden
+	case errors.Is(err, errs.ErrNotFound):
+		return http.StatusNotFound, news.ErrCodeNotFound
+	case errors.Is(err, errs.ErrConflict):
+		return http.StatusConflict, news.ErrCodeConflict
+	case errors.Is(err, errs.ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, news.ErrCodeUnavailable
+	default:
+		return http.StatusInternalServerError, news.ErrCodeInternal
+	}
+}
+
+// writeError writes err as a JSON error response with the status its kind maps to.
+// Internal errors are logged and replaced with a generic message, and
+// validation errors list the fields that failed.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := errorStatus(err)
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Error().Err(err).Str("method", r.Method).Str("url", r.URL.String()).Msg("Request failed")
+		message = "internal server error"
+	}
+
+	resp := news.NewErrorResponse(code, message)
+	resp.Error.Details = validation.Details(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// badRequest writes a validation error response
+func badRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeError(w, r, errs.New(errs.ErrInvalid, message))
+}
+
+// errorInfo describes err for one item of a batch response, hiding internal
+// errors the way writeError does
+func errorInfo(r *http.Request, err error) *news.ErrorInfo {
+	status, code := errorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Error().Err(err).Str("method", r.Method).Str("url", r.URL.String()).Msg("Batch item failed")
+		message = "internal server error"
+	}
+	return &news.ErrorInfo{Code: code, Message: message, Details: validation.Details(err)}
+}
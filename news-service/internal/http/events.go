@@ -0,0 +1,157 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"news-system/internal/repo"
+	"news-system/internal/services/news"
+	"news-system/internal/services/trending"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxBatchEvents bounds the number of events in one POST /api/v1/events:batch
+const maxBatchEvents = 500
+
+// maxEventBodyBytes bounds the size of an event request body
+const maxEventBodyBytes = 1 << 20
+
+// EventHandler takes the views and clicks reported by clients
+type EventHandler struct {
+	scorer *trending.TrendingScorer
+}
+
+// NewEventHandler creates a new EventHandler
+func NewEventHandler(scorer *trending.TrendingScorer) *EventHandler {
+	return &EventHandler{scorer: scorer}
+}
+
+// batchEventsRequest is the body of POST /api/v1/events:batch
+type batchEventsRequest struct {
+	Events []trending.EventRequest `json:"events"`
+}
+
+// batchEventResult is the outcome of one event of POST /api/v1/events:batch
+type batchEventResult struct {
+	Index  int             `json:"index"`
+	Status string          `json:"status"`
+	Event  *repo.UserEvent `json:"event,omitempty"`
+	// Reason is why an ignored event wasn't stored: duplicate or bot
+	Reason string          `json:"reason,omitempty"`
+	Error  *news.ErrorInfo `json:"error,omitempty"`
+}
+
+// batchEventsResponse reports every event of POST /api/v1/events:batch in request order
+type batchEventsResponse struct {
+	Results []batchEventResult `json:"results"`
+	Created int                `json:"created"`
+	Ignored int                `json:"ignored"`
+	Failed  int                `json:"failed"`
+}
+
+// ignoredEventResponse answers an event that was accepted but not stored
+type ignoredEventResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// RegisterRoutes registers event ingestion routes
+func (h *EventHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/api/v1/events", h.CreateEvent)
+	r.Post("/api/v1/events:batch", h.CreateEvents)
+}
+
+// CreateEvent records one view or click
+func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxEventBodyBytes)
+
+	var req trending.EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	describeClient(r, &req)
+	event, err := h.scorer.IngestEvent(r.Context(), req)
+	var ignored *trending.IgnoredError
+	if errors.As(err, &ignored) {
+		writeJSON(w, http.StatusAccepted, ignoredEventResponse{Status: "ignored", Reason: ignored.Reason})
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, event)
+}
+
+// CreateEvents records up to maxBatchEvents views and clicks, reporting the
+// outcome of each one. The response is 200 even when some events failed.
+func (h *EventHandler) CreateEvents(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxEventBodyBytes)
+
+	var req batchEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			badRequest(w, r, fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return
+		}
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+	if len(req.Events) == 0 {
+		badRequest(w, r, "events must not be empty")
+		return
+	}
+	if len(req.Events) > maxBatchEvents {
+		badRequest(w, r, fmt.Sprintf("at most %d events per batch", maxBatchEvents))
+		return
+	}
+
+	for i := range req.Events {
+		describeClient(r, &req.Events[i])
+	}
+	results, err := h.scorer.IngestEvents(r.Context(), req.Events)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := batchEventsResponse{Results: make([]batchEventResult, len(results))}
+	for i, result := range results {
+		item := batchEventResult{Index: result.Index}
+		var ignored *trending.IgnoredError
+		if errors.As(result.Err, &ignored) {
+			resp.Ignored++
+			item.Status = "ignored"
+			item.Reason = ignored.Reason
+		} else if result.Err != nil {
+			resp.Failed++
+			item.Status = "failed"
+			item.Error = errorInfo(r, result.Err)
+		} else {
+			resp.Created++
+			item.Status = "created"
+			event := result.Event
+			item.Event = &event
+		}
+		resp.Results[i] = item
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// describeClient sets the client IP and User-Agent of an event reported in
+// r, which the trending event filter tells readers and bots apart by.
+// RealIP has already resolved proxies into RemoteAddr.
+func describeClient(r *http.Request, req *trending.EventRequest) {
+	req.ClientIP = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		req.ClientIP = host
+	}
+	req.UserAgent = r.UserAgent()
+}
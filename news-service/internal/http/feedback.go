@@ -0,0 +1,42 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"news-system/internal/services/news"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FeedbackHandler takes readers' feedback on summaries and results
+type FeedbackHandler struct {
+	newsService *news.NewsService
+}
+
+// NewFeedbackHandler creates a new FeedbackHandler
+func NewFeedbackHandler(newsService *news.NewsService) *FeedbackHandler {
+	return &FeedbackHandler{newsService: newsService}
+}
+
+// RegisterRoutes registers feedback routes
+func (h *FeedbackHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/api/v1/feedback", h.SubmitFeedback)
+}
+
+// SubmitFeedback stores a thumbs up or down on an article's summary or on its
+// relevance as a result
+func (h *FeedbackHandler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	var req news.FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	feedback, err := h.newsService.SubmitFeedback(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, feedback)
+}
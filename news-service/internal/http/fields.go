@@ -0,0 +1,133 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"news-system/internal/services/news"
+)
+
+// fieldAliases are the shorter names fields= accepts for article fields
+var fieldAliases = map[string]string{
+	"summary": "llm_summary",
+}
+
+// articleLists are the response members holding articles, whose articles
+// fields= narrows
+var articleLists = map[string]bool{"articles": true, "top": true, "trending": true}
+
+// optionalMembers are the response members fields= leaves out unless named
+var optionalMembers = map[string]bool{"meta": true, "trending_topics": true}
+
+// articleFields are the JSON names of the fields of an article
+var articleFields = jsonFields(reflect.TypeOf(news.ArticleDTO{}))
+
+// jsonFields returns the JSON names of the fields of struct type t
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// fieldsParam reads the comma-separated fields query parameter, e.g.
+// fields=id,title,url,summary, which narrows every article of the response
+// to those fields and leaves out meta and trending_topics unless named. It
+// returns nil when the parameter is absent, and an error naming any field
+// articles don't have.
+func fieldsParam(r *http.Request) (map[string]bool, error) {
+	value := r.URL.Query().Get("fields")
+	if value == "" {
+		return nil, nil
+	}
+	fields := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := fieldAliases[name]; ok {
+			name = alias
+		}
+		if name == "" {
+			continue
+		}
+		if !articleFields[name] && !optionalMembers[name] {
+			return nil, fmt.Errorf("unknown field %q in fields (expected one of %s)", name, strings.Join(selectableFields(), ", "))
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// selectableFields lists the names fields= accepts, sorted
+func selectableFields() []string {
+	names := make([]string, 0, len(articleFields)+len(optionalMembers)+len(fieldAliases))
+	for _, set := range []map[string]bool{articleFields, optionalMembers} {
+		for name := range set {
+			names = append(names, name)
+		}
+	}
+	for alias := range fieldAliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeFields writes v as JSON like writeJSON, narrowed to fields when
+// they were asked for
+func writeFields(w http.ResponseWriter, r *http.Request, status int, v interface{}, fields map[string]bool) {
+	if fields == nil {
+		writeJSON(w, status, v)
+		return
+	}
+	selected, err := selectFields(v, fields)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, status, selected)
+}
+
+// selectFields narrows the articles of response v to fields, and drops its
+// optional members that weren't named. Other members, such as cursors, are
+// kept.
+func selectFields(v interface{}, fields map[string]bool) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("failed to select fields: %w", err)
+	}
+
+	for name, value := range members {
+		switch {
+		case optionalMembers[name] && !fields[name]:
+			delete(members, name)
+		case articleLists[name]:
+			var articles []map[string]json.RawMessage
+			if err := json.Unmarshal(value, &articles); err != nil {
+				return nil, fmt.Errorf("failed to select fields of %s: %w", name, err)
+			}
+			for _, article := range articles {
+				for field := range article {
+					if !fields[field] {
+						delete(article, field)
+					}
+				}
+			}
+			if members[name], err = json.Marshal(articles); err != nil {
+				return nil, fmt.Errorf("failed to select fields of %s: %w", name, err)
+			}
+		}
+	}
+	return members, nil
+}
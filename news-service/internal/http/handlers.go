@@ -2,173 +2,574 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"news-system/internal/bus"
+	"news-system/internal/errs"
+	"news-system/internal/middleware"
+	"news-system/internal/plans"
+	"news-system/internal/services/llm"
 	"news-system/internal/services/news"
+	"news-system/internal/services/searchtrends"
+	"news-system/internal/services/trending"
+	"news-system/internal/validation"
 	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
 )
 
 // NewsHandler handles news-related HTTP requests
 type NewsHandler struct {
-	newsService *news.NewsService
+	newsService    *news.NewsService
+	trendingScorer *trending.TrendingScorer
+	searchTrends   *searchtrends.Tracker
+	events         *bus.Bus
 }
 
 // NewNewsHandler creates a new NewsHandler
-func NewNewsHandler(newsService *news.NewsService) *NewsHandler {
-	return &NewsHandler{newsService: newsService}
+func NewNewsHandler(newsService *news.NewsService, trendingScorer *trending.TrendingScorer, searchTrends *searchtrends.Tracker, events *bus.Bus) *NewsHandler {
+	return &NewsHandler{
+		newsService:    newsService,
+		trendingScorer: trendingScorer,
+		searchTrends:   searchTrends,
+		events:         events,
+	}
 }
 
 // RegisterRoutes registers all news routes
 func (h *NewsHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/api/v1/news", func(r chi.Router) {
-		r.Post("/query", h.Query)
-		r.Get("/query", h.Query)
-		r.Get("/trending", h.Trending)
+		r.With(llmEndpoint("query")).Post("/query", h.Query)
+		r.With(middleware.EnforceMaxLimit, llmEndpoint("query")).Get("/query", h.Query)
+		r.With(middleware.EnforceMaxLimit, llmEndpoint("trending")).Get("/trending", h.Trending)
+		r.Get("/search-trends", h.SearchTrends)
+		r.With(middleware.EnforceMaxLimit).Get("/regions/{country_code}", h.Region)
+		r.With(middleware.RequireStreaming).Get("/stream", h.Stream)
+		r.Post("/articles:batch", h.CreateArticles)
+		r.Get("/articles/{id}", h.GetArticle)
+		r.Put("/articles/{id}", h.UpdateArticle)
+		r.Delete("/articles/{id}", h.DeleteArticle)
 	})
 }
 
+// llmEndpoint attributes the LLM calls a route makes to endpoint in the token usage
+func llmEndpoint(endpoint string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(llm.WithEndpoint(r.Context(), endpoint)))
+		})
+	}
+}
+
+// entitiesParam reads the comma-separated entities query parameter, e.g.
+// entities=SpaceX,NASA
+func entitiesParam(r *http.Request) []string {
+	if value := r.URL.Query().Get("entities"); value != "" {
+		return strings.Split(value, ",")
+	}
+	return nil
+}
+
+// includeSummaryParam reads the include_summary query parameter, nil when
+// it is absent
+func includeSummaryParam(r *http.Request) (*bool, error) {
+	value := r.URL.Query().Get("include_summary")
+	if value == "" {
+		return nil, nil
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include_summary value (must be true or false)")
+	}
+	return &include, nil
+}
+
+// floatParam reads the number in query parameter name, nil when it is absent
+func floatParam(r *http.Request, name string) (*float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value (must be a number)", name)
+	}
+	return &number, nil
+}
+
+// intParam reads the integer in query parameter name, 0 when it is absent
+func intParam(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value (must be an integer)", name)
+	}
+	return number, nil
+}
+
 // Query handles unified news queries
 func (h *NewsHandler) Query(w http.ResponseWriter, r *http.Request) {
 	var req news.QueryRequest
+	fields, err := fieldsParam(r)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	includeSummary, err := includeSummaryParam(r)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
 
 	// Handle both GET and POST requests
 	if r.Method == "GET" {
 		// Parse query parameters
 		req.Query = r.URL.Query().Get("query")
-		if req.Query == "" {
-			http.Error(w, "query parameter is required", http.StatusBadRequest)
+		req.Cursor = r.URL.Query().Get("cursor")
+		req.Lang = r.URL.Query().Get("lang")
+		req.Country = r.URL.Query().Get("country")
+		req.Sentiment = r.URL.Query().Get("sentiment")
+		req.Entities = entitiesParam(r)
+		req.TZ = r.URL.Query().Get("tz")
+
+		// Parse optional parameters; their ranges are checked with the body's
+		if req.Lat, err = floatParam(r, "lat"); err != nil {
+			badRequest(w, r, err.Error())
 			return
 		}
-
-		// Parse optional parameters
-		if latStr := r.URL.Query().Get("lat"); latStr != "" {
-			if lat, err := strconv.ParseFloat(latStr, 64); err == nil && lat >= -90 && lat <= 90 {
-				req.Lat = &lat
-			} else {
-				http.Error(w, "invalid latitude value", http.StatusBadRequest)
-				return
-			}
+		if req.Lon, err = floatParam(r, "lon"); err != nil {
+			badRequest(w, r, err.Error())
+			return
 		}
-
-		if lonStr := r.URL.Query().Get("lon"); lonStr != "" {
-			if lon, err := strconv.ParseFloat(lonStr, 64); err == nil && lon >= -180 && lon <= 180 {
-				req.Lon = &lon
-			} else {
-				http.Error(w, "invalid longitude value", http.StatusBadRequest)
-				return
-			}
+		if req.Radius, err = floatParam(r, "radius"); err != nil {
+			badRequest(w, r, err.Error())
+			return
 		}
-
-		if radiusStr := r.URL.Query().Get("radius"); radiusStr != "" {
-			if radius, err := strconv.ParseFloat(radiusStr, 64); err == nil && radius > 0 && radius <= 200 {
-				req.Radius = &radius
-			} else {
-				http.Error(w, "invalid radius value (must be 0.1-200 km)", http.StatusBadRequest)
-				return
-			}
+		if req.Limit, err = intParam(r, "limit"); err != nil {
+			badRequest(w, r, err.Error())
+			return
 		}
-
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 50 {
-				req.Limit = limit
-			} else {
-				http.Error(w, "invalid limit value (must be 1-50)", http.StatusBadRequest)
-				return
-			}
+		if req.MinResults, err = intParam(r, "min_results"); err != nil {
+			badRequest(w, r, err.Error())
+			return
 		}
 	} else {
 		// Parse JSON body for POST requests
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid request body", http.StatusBadRequest)
+			badRequest(w, r, "invalid request body")
 			return
 		}
 	}
-
-	// Validate request
-	if req.Query == "" {
-		http.Error(w, "query is required", http.StatusBadRequest)
-		return
+	if req.IncludeSummary == nil {
+		req.IncludeSummary = includeSummary
 	}
 
 	// Set default limit
-	if req.Limit <= 0 {
+	if req.Limit == 0 {
 		req.Limit = 5
 	}
 
+	// Validate request; follow-up pages carry their query in the cursor
+	if err := validation.Struct(req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Hold the caller to their plan; GET limits were already checked by middleware
+	plan := plans.FromContext(r.Context()).Plan
+	if req.Limit > plan.MaxLimit {
+		writeError(w, r, errs.Errorf(errs.ErrForbidden, "limit %d exceeds the %s plan maximum of %d", req.Limit, plan.Name, plan.MaxLimit))
+		return
+	}
+	req.SkipSummaries = !plan.IncludeSummaries(req.IncludeSummary)
+
 	// Process the query
 	response, err := h.newsService.Query(r.Context(), req)
 	if err != nil {
-		// Log the error for debugging
-		fmt.Printf("Error processing query: %v\n", err)
-		http.Error(w, fmt.Sprintf("Failed to process query: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
+	// Log the (privacy-scrubbed) query for search trend detection, once per search rather than per page
+	if req.Cursor == "" {
+		if err := h.searchTrends.Record(r.Context(), req.Query, req.Lat, req.Lon); err != nil {
+			fmt.Printf("Failed to record search query: %v\n", err)
+		}
 	}
+
+	// Return response
+	writeFields(w, r, http.StatusOK, response, fields)
 }
 
-// Trending handles the bonus trending news endpoint
+// trendingCandidates is how many of a tile's trending articles are read for
+// one page, so that filters still leave enough of them to fill it
+const trendingCandidates = 50
+
+// Trending handles the bonus trending news endpoint: the articles trending
+// around a location, or everywhere with mode=global, by their trending
+// score, optionally only those of one category
 func (h *NewsHandler) Trending(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	latStr := r.URL.Query().Get("lat")
 	lonStr := r.URL.Query().Get("lon")
-	limitStr := r.URL.Query().Get("limit")
-	
-	if latStr == "" || lonStr == "" {
-		http.Error(w, "latitude and longitude are required", http.StatusBadRequest)
+	category := trending.NormalizeCategory(r.URL.Query().Get("category"))
+	window, ok := trending.ParseWindow(r.URL.Query().Get("window"))
+	if !ok {
+		badRequest(w, r, "invalid window (must be 1h, 6h or 24h)")
 		return
 	}
-	
-	lat, err := strconv.ParseFloat(latStr, 64)
-	if err != nil || lat < -90 || lat > 90 {
-		http.Error(w, "invalid latitude", http.StatusBadRequest)
+	fields, err := fieldsParam(r)
+	if err != nil {
+		badRequest(w, r, err.Error())
 		return
 	}
-	
-	lon, err := strconv.ParseFloat(lonStr, 64)
-	if err != nil || lon < -180 || lon > 180 {
-		http.Error(w, "invalid longitude", http.StatusBadRequest)
+	includeSummary, err := includeSummaryParam(r)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = news.TrendingLocal
+	}
+	if mode != news.TrendingLocal && mode != news.TrendingGlobal {
+		badRequest(w, r, "invalid mode (must be local or global)")
 		return
 	}
 	
-	limit := 5 // Default limit
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
-			limit = l
+	var lat, lon float64
+	if mode == news.TrendingLocal {
+		if latStr == "" || lonStr == "" {
+			badRequest(w, r, "latitude and longitude are required")
+			return
+		}
+
+		var err error
+		lat, err = strconv.ParseFloat(latStr, 64)
+		if err != nil {
+			badRequest(w, r, "invalid latitude")
+			return
+		}
+
+		lon, err = strconv.ParseFloat(lonStr, 64)
+		if err != nil {
+			badRequest(w, r, "invalid longitude")
+			return
 		}
 	}
 	
-	// Create a trending query request
-	req := news.QueryRequest{
-		Query:  "trending news near me",
-		Lat:    &lat,
-		Lon:    &lon,
-		Radius: float64Ptr(50.0), // 50km radius
-		Limit:  limit,
+	limit, err := intParam(r, "limit")
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = 5 // Default limit
+	}
+	req := news.TrendingRequest{
+		Lat:       lat,
+		Lon:       lon,
+		Limit:     limit,
+		Lang:      r.URL.Query().Get("lang"),
+		Country:   r.URL.Query().Get("country"),
+		Sentiment: r.URL.Query().Get("sentiment"),
+		Entities:  entitiesParam(r),
+		TZ:        r.URL.Query().Get("tz"),
+		Category:  category,
+		Mode:      mode,
+		Window:    window.Name,
+
+		SkipSummaries: !plans.FromContext(r.Context()).Plan.IncludeSummaries(includeSummary),
+	}
+	if err := validation.Struct(req); err != nil {
+		writeError(w, r, err)
+		return
 	}
 	
-	// Process the trending query
-	response, err := h.newsService.Query(r.Context(), req)
+	// Serve the finest tile with data around the location, and note how many read there
+	var (
+		geohash   string
+		precision int
+		scores    []trending.TrendingScore
+	)
+	switch {
+	case mode == news.TrendingGlobal:
+		scores, err = h.trendingScorer.GetGlobalTrendingScores(r.Context(), window, category, trendingCandidates)
+	case category != "":
+		geohash, precision = h.trendingScorer.ResolveCategoryTile(r.Context(), window, lat, lon, category)
+		scores, err = h.trendingScorer.GetNearbyCategoryTrendingScores(r.Context(), window, lat, lon, geohash, category, trendingCandidates)
+	default:
+		geohash, precision = h.trendingScorer.ResolveTile(r.Context(), window, lat, lon)
+		scores, err = h.trendingScorer.GetNearbyTrendingScores(r.Context(), window, lat, lon, geohash, trendingCandidates)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	candidates := make([]news.TrendingArticle, len(scores))
+	for i, score := range scores {
+		candidates[i] = news.TrendingArticle{ArticleID: score.ArticleID, Score: score.Score}
+	}
+
+	response, err := h.newsService.GetTrending(r.Context(), req, candidates)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
+
+	// Surface the categories trending in that tile, or everywhere, for discovery UIs
+	var topics []trending.TrendingTopic
+	if mode == news.TrendingGlobal {
+		topics, err = h.trendingScorer.GetGlobalTrendingTopics(r.Context(), window, 10)
+	} else {
+		response.Meta.Geohash, response.Meta.GeohashPrecision = geohash, precision
+		if readers, err := h.trendingScorer.TileReaders(r.Context(), geohash); err == nil {
+			response.Meta.UniqueReaders = &readers
+		}
+		topics, err = h.trendingScorer.GetNearbyTrendingTopics(r.Context(), window, lat, lon, response.Meta.Geohash, 10)
+	}
+	if err == nil {
+		for _, topic := range topics {
+			response.TrendingTopics = append(response.TrendingTopics, news.TrendingTopicDTO{
+				Category: topic.Category,
+				Score:    topic.Score,
+			})
+		}
+	}
 	
 	// Return response
+	writeFields(w, r, http.StatusOK, response, fields)
+}
+
+// Region returns the top and trending articles placed in a country, or in
+// the region of it given as ?region=, for clients without a precise location
+func (h *NewsHandler) Region(w http.ResponseWriter, r *http.Request) {
+	country := strings.ToUpper(chi.URLParam(r, "country_code"))
+	region := strings.TrimSpace(r.URL.Query().Get("region"))
+	fields, err := fieldsParam(r)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+
+	limit := 10
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		} else {
+			badRequest(w, r, "invalid limit value (must be 1-50)")
+			return
+		}
+	}
+
+	// Trending is best-effort: the top articles are still worth serving
+	var trendingIDs []string
+	scores, err := h.trendingScorer.GetPlaceTrendingScores(r.Context(), country, region, limit)
+	if err != nil {
+		log.Warn().Err(err).Str("country", country).Str("region", region).Msg("Failed to get place trending scores")
+	}
+	for _, score := range scores {
+		trendingIDs = append(trendingIDs, score.ArticleID)
+	}
+
+	response, err := h.newsService.GetRegion(r.Context(), country, region, limit, trendingIDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeFields(w, r, http.StatusOK, response, fields)
+}
+
+// SearchTrendsResponse lists the rising search terms of a region
+type SearchTrendsResponse struct {
+	Region      string                     `json:"region"`
+	Window      string                     `json:"window"`
+	Trends      []searchtrends.SearchTrend `json:"trends"`
+	// Hot lists the queries searched most right now across all regions,
+	// scored by estimated searches over HotWindow
+	Hot         []searchtrends.SearchTrend `json:"hot,omitempty"`
+	HotWindow   string                     `json:"hot_window,omitempty"`
+	GeneratedAt time.Time                  `json:"generated_at"`
+}
+
+// SearchTrends returns what people are searching for in a region, for editorial dashboards
+func (h *NewsHandler) SearchTrends(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+
+	// Allow resolving the region from coordinates instead
+	if region == "" {
+		var lat, lon *float64
+		if latStr, lonStr := r.URL.Query().Get("lat"), r.URL.Query().Get("lon"); latStr != "" && lonStr != "" {
+			latVal, latErr := strconv.ParseFloat(latStr, 64)
+			lonVal, lonErr := strconv.ParseFloat(lonStr, 64)
+			if latErr != nil || lonErr != nil || latVal < -90 || latVal > 90 || lonVal < -180 || lonVal > 180 {
+				badRequest(w, r, "invalid latitude or longitude")
+				return
+			}
+			lat, lon = &latVal, &lonVal
+		}
+		region = h.searchTrends.Region(lat, lon)
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		} else {
+			badRequest(w, r, "invalid limit value (must be 1-100)")
+			return
+		}
+	}
+
+	trends, err := h.searchTrends.GetTrends(r.Context(), region, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := SearchTrendsResponse{
+		Region:      region,
+		Window:      h.searchTrends.Window().String(),
+		Trends:      trends,
+		Hot:         h.searchTrends.HotQueries(limit),
+		GeneratedAt: time.Now(),
+	}
+	if window := h.searchTrends.HotWindow(); window > 0 {
+		response.HotWindow = window.String()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// Helper function for creating float64 pointers
-func float64Ptr(f float64) *float64 {
-	return &f
+// GetArticle returns a single article with its summary and recent engagement
+func (h *NewsHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
+	article, err := h.newsService.GetArticle(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(article)
+}
+
+// UpdateArticle replaces the content of an article
+func (h *NewsHandler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
+	var req news.ArticleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	article, err := h.newsService.UpdateArticle(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(article)
+}
+
+// maxBatchArticles bounds the number of articles in one POST /articles:batch
+const maxBatchArticles = 1000
+
+// maxBatchBodyBytes bounds the size of a POST /articles:batch body
+const maxBatchBodyBytes = 16 << 20
+
+// batchArticlesRequest is the body of POST /articles:batch
+type batchArticlesRequest struct {
+	Articles []news.ArticleRequest `json:"articles"`
+}
+
+// batchArticleResult is the outcome of one article of POST /articles:batch
+type batchArticleResult struct {
+	Index   int              `json:"index"`
+	Status  news.BatchStatus `json:"status"`
+	Article *news.ArticleDTO `json:"article,omitempty"`
+	Error   *news.ErrorInfo  `json:"error,omitempty"`
+}
+
+// batchArticlesResponse reports every article of POST /articles:batch in request order
+type batchArticlesResponse struct {
+	Results []batchArticleResult `json:"results"`
+	Created int                  `json:"created"`
+	Updated int                  `json:"updated"`
+	Failed  int                  `json:"failed"`
+}
+
+// CreateArticles creates or updates up to maxBatchArticles articles, reporting
+// the outcome of each one. The response is 200 even when some articles failed.
+func (h *NewsHandler) CreateArticles(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)
+
+	var req batchArticlesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			badRequest(w, r, fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return
+		}
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+	if len(req.Articles) == 0 {
+		badRequest(w, r, "articles must not be empty")
+		return
+	}
+	if len(req.Articles) > maxBatchArticles {
+		badRequest(w, r, fmt.Sprintf("at most %d articles per batch", maxBatchArticles))
+		return
+	}
+
+	results, err := h.newsService.CreateArticles(r.Context(), req.Articles)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := batchArticlesResponse{Results: make([]batchArticleResult, len(results))}
+	for i, result := range results {
+		item := batchArticleResult{
+			Index:   result.Index,
+			Status:  result.Status,
+			Article: result.Article,
+		}
+		switch result.Status {
+		case news.BatchCreated:
+			resp.Created++
+		case news.BatchUpdated:
+			resp.Updated++
+		default:
+			resp.Failed++
+			item.Error = errorInfo(r, result.Err)
+		}
+		resp.Results[i] = item
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DeleteArticle removes an article together with its summary and events
+func (h *NewsHandler) DeleteArticle(w http.ResponseWriter, r *http.Request) {
+	if err := h.newsService.DeleteArticle(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
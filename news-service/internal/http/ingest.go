@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/ingest"
+	"news-system/internal/repo"
+	"news-system/internal/services/news"
+	"news-system/pkg/webhook"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// IngestHandler accepts articles pushed by external publishers
+type IngestHandler struct {
+	loader    *ingest.Loader
+	cache     *cache.RedisCache
+	secret    string
+	tolerance time.Duration
+}
+
+// NewIngestHandler creates a new IngestHandler. Requests must be signed with
+// secret using the scheme of pkg/webhook, with a timestamp within tolerance.
+func NewIngestHandler(loader *ingest.Loader, cache *cache.RedisCache, secret string, tolerance time.Duration) *IngestHandler {
+	if tolerance <= 0 {
+		tolerance = webhook.DefaultTolerance
+	}
+	return &IngestHandler{
+		loader:    loader,
+		cache:     cache,
+		secret:    secret,
+		tolerance: tolerance,
+	}
+}
+
+// RegisterRoutes registers ingestion routes
+func (h *IngestHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/api/v1/ingest", func(r chi.Router) {
+		r.Post("/webhook", h.Webhook)
+	})
+}
+
+// ingestArticleResult is the outcome of one article of POST /ingest/webhook
+type ingestArticleResult struct {
+	Index     int              `json:"index"`
+	Status    news.BatchStatus `json:"status"`
+	ArticleID string           `json:"article_id,omitempty"`
+	Error     *news.ErrorInfo  `json:"error,omitempty"`
+}
+
+// ingestResponse reports every article of POST /ingest/webhook in request order
+type ingestResponse struct {
+	Results []ingestArticleResult `json:"results"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Skipped int                   `json:"skipped"`
+	Failed  int                   `json:"failed"`
+}
+
+// Webhook verifies a signed push of up to maxBatchArticles articles and loads
+// them. A request is accepted once: replays of the same signed body are
+// rejected across instances until its timestamp falls outside the tolerance.
+func (h *IngestHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("request body exceeds %d bytes", maxBatchBodyBytes))
+		return
+	}
+
+	signature := r.Header.Get(webhook.SignatureHeader)
+	signedAt, err := webhook.VerifyTimestamp(h.secret, signature, body, h.tolerance, time.Now())
+	if err != nil {
+		log.Warn().Err(err).Str("remote_addr", r.RemoteAddr).Msg("Rejected unsigned or invalid ingestion webhook")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(news.NewErrorResponse(news.ErrCodeUnauthorized, err.Error()))
+		return
+	}
+
+	first, err := h.cache.SetNX(r.Context(), cache.IngestWebhookKey(signedAt.Unix(), body), "1", 2*h.tolerance)
+	if err != nil {
+		writeError(w, r, errs.Wrap(errs.ErrUnavailable, err))
+		return
+	}
+	if !first {
+		writeError(w, r, errs.Wrap(errs.ErrConflict, webhook.ErrReplayed))
+		return
+	}
+
+	var req batchArticlesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+	if len(req.Articles) == 0 {
+		badRequest(w, r, "articles must not be empty")
+		return
+	}
+	if len(req.Articles) > maxBatchArticles {
+		badRequest(w, r, fmt.Sprintf("at most %d articles per request", maxBatchArticles))
+		return
+	}
+
+	resp := ingestResponse{Results: make([]ingestArticleResult, len(req.Articles))}
+	for i, article := range req.Articles {
+		result := ingestArticleResult{Index: i}
+		err := article.Validate()
+		if err == nil {
+			var stored repo.Article
+			stored, result.Status, err = h.loader.LoadArticle(r.Context(), article.ArticleDTO())
+			result.ArticleID = stored.ID
+		}
+
+		switch {
+		case err != nil:
+			resp.Failed++
+			result.Status = news.BatchFailed
+			result.Error = errorInfo(r, err)
+		case result.Status == news.BatchUpdated:
+			resp.Updated++
+		case result.Status == news.BatchSkipped:
+			resp.Skipped++
+		default:
+			resp.Created++
+		}
+		resp.Results[i] = result
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"news-system/internal/middleware"
+	"news-system/internal/services/news"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ReportHandler takes readers' reports of bad articles
+type ReportHandler struct {
+	newsService *news.NewsService
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(newsService *news.NewsService) *ReportHandler {
+	return &ReportHandler{newsService: newsService}
+}
+
+// RegisterRoutes registers report routes
+func (h *ReportHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/api/v1/articles/{id}/report", h.ReportArticle)
+}
+
+// ReportArticle records a report of an article as spam, offensive, in the
+// wrong category or linking to a broken page
+func (h *ReportHandler) ReportArticle(w http.ResponseWriter, r *http.Request) {
+	var req news.ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := h.newsService.ReportArticle(r.Context(), id, middleware.ClientID(r), req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"article_id": id,
+		"reason":     req.Reason,
+		"status":     "received",
+	})
+}
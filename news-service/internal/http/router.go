@@ -2,6 +2,7 @@ package http
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"news-system/internal/middleware"
@@ -9,13 +10,17 @@ import (
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Router struct {
 	chi.Router
+	plans *middleware.PlanEnforcer
 }
 
-func NewRouter() *Router {
+// NewRouter creates the public router; API routes are served under the plan
+// the enforcer resolves for each caller
+func NewRouter(plans *middleware.PlanEnforcer) *Router {
 	r := chi.NewRouter()
 	
 	// Use chi middleware with aliases to avoid conflicts
@@ -29,24 +34,84 @@ func NewRouter() *Router {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "X-API-Plan"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 	
 	// Custom middleware
-	r.Use(middleware.RateLimit)
 	r.Use(middleware.Logging)
+	r.Use(middleware.Metrics)
 	
-	return &Router{r}
+	return &Router{Router: r, plans: plans}
 }
 
-// RegisterNewsRoutes registers news-related routes
+// NewInternalRouter creates the router for the internal listener. It carries
+// operational routes only and has no rate limiting or CORS, as it is never
+// exposed to browsers or the public ingress.
+func NewInternalRouter() *Router {
+	r := chi.NewRouter()
+
+	r.Use(chimiddleware.RequestID)
+	r.Use(chimiddleware.Recoverer)
+	r.Use(chimiddleware.Timeout(60 * time.Second))
+	r.Use(middleware.Logging)
+	r.Use(middleware.Metrics)
+
+	return &Router{Router: r}
+}
+
+// RegisterNewsRoutes registers news-related routes, rate limited by the caller's plan
 func (r *Router) RegisterNewsRoutes(newsHandler *NewsHandler) {
-	newsHandler.RegisterRoutes(r)
+	r.With(r.plans.Authenticate).Group(newsHandler.RegisterRoutes)
+}
+
+// RegisterAccountRoutes registers the endpoint describing the caller's plan
+func (r *Router) RegisterAccountRoutes(accountHandler *AccountHandler) {
+	r.With(r.plans.Authenticate).Group(accountHandler.RegisterRoutes)
+}
+
+// RegisterFeedbackRoutes registers the reader feedback endpoint, rate limited by the caller's plan
+func (r *Router) RegisterFeedbackRoutes(feedbackHandler *FeedbackHandler) {
+	r.With(r.plans.Authenticate).Group(feedbackHandler.RegisterRoutes)
+}
+
+// RegisterEventRoutes registers the view and click ingestion endpoints, rate
+// limited by the caller's plan
+func (r *Router) RegisterEventRoutes(eventHandler *EventHandler) {
+	r.With(r.plans.Authenticate).Group(eventHandler.RegisterRoutes)
+}
+
+// RegisterReportRoutes registers the article report endpoint, rate limited by
+// the caller's plan and by limit
+func (r *Router) RegisterReportRoutes(reportHandler *ReportHandler, limit *middleware.ClientLimit) {
+	r.With(r.plans.Authenticate, limit.Limit).Group(reportHandler.RegisterRoutes)
+}
+
+// RegisterSubscriptionRoutes registers the subscription management routes,
+// rate limited by the caller's plan
+func (r *Router) RegisterSubscriptionRoutes(subscriptionHandler *SubscriptionHandler) {
+	r.With(r.plans.Authenticate).Group(subscriptionHandler.RegisterRoutes)
+}
+
+// RegisterUnsubscribeRoutes registers the unsubscribe links sent in
+// messages; they carry their own token instead of an API key
+func (r *Router) RegisterUnsubscribeRoutes(subscriptionHandler *SubscriptionHandler) {
+	r.With(middleware.RateLimit).Group(subscriptionHandler.RegisterUnsubscribeRoutes)
+}
+
+// RegisterIngestRoutes registers routes for pushed ingestion
+func (r *Router) RegisterIngestRoutes(ingestHandler *IngestHandler) {
+	r.With(middleware.RateLimit).Group(ingestHandler.RegisterRoutes)
+}
+
+// RegisterAdminRoutes registers operational routes; they belong on the internal router
+func (r *Router) RegisterAdminRoutes(adminHandler *AdminHandler) {
+	adminHandler.RegisterRoutes(r)
 }
 
+
 // RegisterHealthRoutes registers health check routes
 func (r *Router) RegisterHealthRoutes() {
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -62,12 +127,20 @@ func (r *Router) RegisterHealthRoutes() {
 	})
 }
 
-// RegisterMetricsRoutes registers metrics routes
+// RegisterVersionRoutes registers the endpoint reporting the running build
+func (r *Router) RegisterVersionRoutes() {
+	r.Get("/version", getVersion)
+}
+
+// RegisterMetricsRoutes registers metrics routes; they belong on the internal router
 func (r *Router) RegisterMetricsRoutes() {
-	r.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement Prometheus metrics endpoint
-		w.Header().Set("Content-Type", "text/plain")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("# Metrics endpoint - implement Prometheus metrics here\n"))
-	})
+	// Connection pool collectors are registered in main; request metrics are
+	// recorded by middleware.Metrics on both routers
+	r.Handle("/metrics", promhttp.Handler())
+}
+
+// RegisterFileRoutes serves signed object downloads under prefix
+func (r *Router) RegisterFileRoutes(prefix string, handler http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	r.Handle(prefix+"/*", http.StripPrefix(prefix, handler))
 }
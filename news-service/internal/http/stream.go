@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"news-system/internal/bus"
+)
+
+// streamKeepAlive is how often an idle event stream sends a comment to keep proxies from closing it
+const streamKeepAlive = 15 * time.Second
+
+// streamBuffer is the number of events a stream client may fall behind before it is disconnected
+const streamBuffer = 64
+
+// Stream pushes domain events to the client as Server-Sent Events. The optional
+// types parameter is a comma-separated list of event types to receive.
+func (h *NewsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var types []bus.EventType
+	if typesStr := r.URL.Query().Get("types"); typesStr != "" {
+		for _, t := range strings.Split(typesStr, ",") {
+			eventType := bus.EventType(strings.TrimSpace(t))
+			switch eventType {
+			case bus.ArticleCreated, bus.ArticleUpdated, bus.ArticleDeleted, bus.SummaryGenerated, bus.TrendingRecomputed:
+				types = append(types, eventType)
+			default:
+				badRequest(w, r, fmt.Sprintf("unknown event type: %s", eventType))
+				return
+			}
+		}
+	}
+
+	rc := http.NewResponseController(w)
+
+	// The bus never blocks on a subscriber, so hand events over through a
+	// bounded channel and drop the client if it can't keep up
+	events := make(chan bus.Event, streamBuffer)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	unsubscribe := h.events.Subscribe("sse", func(_ context.Context, event bus.Event) {
+		select {
+		case events <- event:
+		default:
+			cancel()
+		}
+	}, types...)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		// Streams outlive the server's write timeout, so extend it per write
+		rc.SetWriteDeadline(time.Now().Add(streamKeepAlive * 2))
+
+		select {
+		case event := <-events:
+			data, err := json.Marshal(event.Payload)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-ctx.Done():
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"news-system/internal/errs"
+	"news-system/internal/plans"
+	"news-system/internal/subscriptions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SubscriptionHandler manages the calling API key's digest and alert subscriptions
+type SubscriptionHandler struct {
+	service *subscriptions.Service
+}
+
+// NewSubscriptionHandler creates a new SubscriptionHandler
+func NewSubscriptionHandler(service *subscriptions.Service) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service}
+}
+
+// VerifyRequest is the request body of POST /api/v1/subscriptions/{id}/verify
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+// RegisterRoutes registers subscription routes
+func (h *SubscriptionHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/api/v1/subscriptions", func(r chi.Router) {
+		r.Post("/", h.CreateSubscription)
+		r.Get("/", h.ListSubscriptions)
+		r.Get("/{id}", h.GetSubscription)
+		r.Put("/{id}", h.UpdateSubscription)
+		r.Delete("/{id}", h.DeleteSubscription)
+		r.Post("/{id}/verify", h.VerifySubscription)
+		r.Post("/{id}/verification", h.ResendVerification)
+		r.Get("/{id}/deliveries", h.ListDeliveries)
+		r.Post("/{id}/enable", h.EnableSubscription)
+	})
+	r.Route("/api/v1/templates", func(r chi.Router) {
+		r.Get("/", h.ListTemplates)
+		r.Get("/{name}", h.GetTemplate)
+		r.Put("/{name}", h.PutTemplate)
+		r.Delete("/{name}", h.DeleteTemplate)
+		r.Post("/{name}/preview", h.PreviewTemplate)
+	})
+}
+
+// RegisterUnsubscribeRoutes registers the unsubscribe link, which
+// recipients follow without an API key
+func (h *SubscriptionHandler) RegisterUnsubscribeRoutes(r chi.Router) {
+	r.Get("/api/v1/unsubscribe/{id}", h.Unsubscribe)
+	r.Post("/api/v1/unsubscribe/{id}", h.Unsubscribe)
+}
+
+// CreateSubscription creates a subscription and sends its verification token to the target
+func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	var req subscriptions.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	subscription, err := h.service.Create(r.Context(), owner, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// ListSubscriptions lists the caller's subscriptions, up to limit (default 50) from offset
+func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := subscriptionPage(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.List(r.Context(), owner, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"subscriptions": list})
+}
+
+// GetSubscription returns one of the caller's subscriptions
+func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	subscription, err := h.service.Get(r.Context(), owner, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// UpdateSubscription replaces one of the caller's subscriptions
+func (h *SubscriptionHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	var req subscriptions.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	subscription, err := h.service.Update(r.Context(), owner, chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// DeleteSubscription deletes one of the caller's subscriptions
+func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), owner, chi.URLParam(r, "id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifySubscription confirms a subscription with the token sent to its target
+func (h *SubscriptionHandler) VerifySubscription(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		badRequest(w, r, "invalid JSON body: expected {\"token\": \"...\"}")
+		return
+	}
+
+	subscription, err := h.service.Verify(r.Context(), owner, chi.URLParam(r, "id"), req.Token)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// ResendVerification sends a new verification token to an unverified subscription's target
+func (h *SubscriptionHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.ResendVerification(r.Context(), owner, chi.URLParam(r, "id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// EnableSubscription re-enables one of the caller's subscriptions after it
+// was disabled for failing persistently
+func (h *SubscriptionHandler) EnableSubscription(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	subscription, err := h.service.Enable(r.Context(), owner, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// ListDeliveries lists the messages sent for one of the caller's
+// subscriptions, newest first, up to limit (default 50) from offset
+func (h *SubscriptionHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := subscriptionPage(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.Deliveries(r.Context(), owner, chi.URLParam(r, "id"), limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deliveries": deliveries})
+}
+
+// ListTemplates lists the templates the caller's messages are rendered with
+func (h *SubscriptionHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	templates, err := h.service.ListTemplates(r.Context(), owner)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"templates": templates})
+}
+
+// GetTemplate returns the template the caller's messages of one kind are rendered with
+func (h *SubscriptionHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	template, err := h.service.GetTemplate(r.Context(), owner, chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+// PutTemplate overrides the template of one kind of the caller's messages
+func (h *SubscriptionHandler) PutTemplate(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	var req subscriptions.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	template, err := h.service.PutTemplate(r.Context(), owner, chi.URLParam(r, "name"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+// DeleteTemplate restores the built-in template of one kind of the caller's messages
+func (h *SubscriptionHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteTemplate(r.Context(), owner, chi.URLParam(r, "name")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewTemplate renders a template against sample data: the one in the
+// body, or the caller's current template when the body is empty
+func (h *SubscriptionHandler) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	owner, ok := subscriptionOwner(w, r)
+	if !ok {
+		return
+	}
+	var req subscriptions.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(w, r, "invalid JSON body")
+		return
+	}
+
+	rendered, err := h.service.PreviewTemplate(r.Context(), owner, chi.URLParam(r, "name"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rendered)
+}
+
+// Unsubscribe deletes the subscription of an unsubscribe link, authenticated
+// by the link's token
+func (h *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		badRequest(w, r, "token is required")
+		return
+	}
+	if err := h.service.Unsubscribe(r.Context(), chi.URLParam(r, "id"), token); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"unsubscribed": true})
+}
+
+// subscriptionOwner returns the owner of the caller's subscriptions, writing
+// an error response for anonymous callers, who can't own any
+func subscriptionOwner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	caller := plans.FromContext(r.Context())
+	if caller.Key == "" {
+		writeError(w, r, errs.Errorf(errs.ErrForbidden, "subscriptions require an API key"))
+		return "", false
+	}
+	return subscriptions.Owner(caller.Key), true
+}
+
+// subscriptionPage parses the limit (1-100, default 50) and offset of the
+// subscription listings, writing an error response when either is invalid
+func subscriptionPage(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	limit, offset := 50, 0
+	params := r.URL.Query()
+	if value := params.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid limit %q: expected 1-100", value))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if value := params.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, r, errs.Errorf(errs.ErrInvalid, "invalid offset %q: expected a non-negative integer", value))
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
@@ -0,0 +1,12 @@
+package http
+
+import (
+	"net/http"
+
+	"news-system/internal/version"
+)
+
+// getVersion returns the build the service is running
+func getVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
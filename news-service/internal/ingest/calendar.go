@@ -0,0 +1,197 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Day-off policies: what a calendar does with a run that falls on a weekend or holiday
+const (
+	// DayOffSkip drops the run
+	DayOffSkip = "skip"
+	// DayOffNext moves the run to the same time on the next working day
+	DayOffNext = "next"
+	// DayOffPrevious moves the run to the same time on the previous working day
+	DayOffPrevious = "previous"
+)
+
+// Calendar holds a region's working days, for schedules such as digests that
+// should follow them instead of a fixed cron
+type Calendar struct {
+	// Weekend lists the days off every week by English name, Saturday and
+	// Sunday when empty
+	Weekend []string `json:"weekend"`
+	// Holidays are days off given as YYYY-MM-DD, or as MM-DD for every year
+	Holidays []string `json:"holidays"`
+	// OnDayOff is DayOffSkip (the default), DayOffNext or DayOffPrevious
+	OnDayOff string `json:"on_day_off"`
+	// EarlierBeforeDayOff moves runs on the last working day before a day
+	// off earlier by a duration such as "2h", e.g. to send on Friday afternoon
+	EarlierBeforeDayOff string `json:"earlier_before_day_off"`
+
+	weekend  [7]bool
+	dates    map[string]bool
+	earlier  time.Duration
+	resolved bool
+}
+
+// LoadCalendars reads a JSON file mapping region names, e.g. ISO 3166-1
+// country codes, to calendars
+func LoadCalendars(path string) (map[string]*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendars %s: %w", path, err)
+	}
+	var calendars map[string]*Calendar
+	if err := json.Unmarshal(data, &calendars); err != nil {
+		return nil, fmt.Errorf("failed to decode calendars %s: %w", path, err)
+	}
+	for region, calendar := range calendars {
+		if err := calendar.resolve(); err != nil {
+			return nil, fmt.Errorf("invalid calendar %s: %w", region, err)
+		}
+	}
+	return calendars, nil
+}
+
+// resolve validates the calendar and parses its fields for lookups
+func (c *Calendar) resolve() error {
+	if c.resolved {
+		return nil
+	}
+
+	weekend := c.Weekend
+	if len(weekend) == 0 {
+		weekend = []string{"saturday", "sunday"}
+	}
+	for _, name := range weekend {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return fmt.Errorf("unknown weekend day %q", name)
+		}
+		c.weekend[day] = true
+	}
+	if c.weekend == [7]bool{true, true, true, true, true, true, true} {
+		return fmt.Errorf("weekend covers every day")
+	}
+
+	c.dates = make(map[string]bool, len(c.Holidays))
+	for _, holiday := range c.Holidays {
+		holiday = strings.TrimSpace(holiday)
+		layout := "2006-01-02"
+		if len(holiday) == len("01-02") {
+			layout = "01-02"
+		}
+		if _, err := time.Parse(layout, holiday); err != nil {
+			return fmt.Errorf("holiday %q is neither YYYY-MM-DD nor MM-DD", holiday)
+		}
+		c.dates[holiday] = true
+	}
+
+	switch c.OnDayOff {
+	case "":
+		c.OnDayOff = DayOffSkip
+	case DayOffSkip, DayOffNext, DayOffPrevious:
+	default:
+		return fmt.Errorf("on_day_off must be %q, %q or %q, got %q", DayOffSkip, DayOffNext, DayOffPrevious, c.OnDayOff)
+	}
+
+	if c.EarlierBeforeDayOff != "" {
+		earlier, err := time.ParseDuration(c.EarlierBeforeDayOff)
+		if err != nil || earlier < 0 || earlier >= 24*time.Hour {
+			return fmt.Errorf("earlier_before_day_off must be a duration under 24h, got %q", c.EarlierBeforeDayOff)
+		}
+		c.earlier = earlier
+	}
+	c.resolved = true
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// DayOff reports whether the day of t, in t's location, is a weekend day or holiday
+func (c *Calendar) DayOff(t time.Time) bool {
+	return c.weekend[t.Weekday()] || c.dates[t.Format("2006-01-02")] || c.dates[t.Format("01-02")]
+}
+
+// Apply returns schedule following the calendar in loc: runs are evaluated
+// on loc's days, moved or dropped on days off, and brought forward before
+// them. A nil calendar returns schedule in loc unchanged.
+func (c *Calendar) Apply(schedule Schedule, loc *time.Location) (Schedule, error) {
+	if c == nil {
+		return calendarSchedule{schedule: schedule, loc: loc}, nil
+	}
+	if err := c.resolve(); err != nil {
+		return nil, err
+	}
+	return calendarSchedule{schedule: schedule, calendar: c, loc: loc}, nil
+}
+
+// calendarSchedule adjusts the runs of a schedule to a calendar
+type calendarSchedule struct {
+	schedule Schedule
+	calendar *Calendar
+	loc      *time.Location
+}
+
+// maxCalendarRuns bounds the runs of the underlying schedule looked at for
+// one Next, so a calendar without working days at the scheduled times ends
+// the schedule rather than looping
+const maxCalendarRuns = 1000
+
+// Next returns the first adjusted run strictly after t. Runs of the
+// underlying schedule are taken in order; a run moved onto a time already
+// passed, such as one moved to a day that already had its own run, is dropped.
+func (s calendarSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	if s.calendar == nil {
+		return s.schedule.Next(t)
+	}
+
+	// A run brought forward may come due before its original time
+	run := t.Add(-s.calendar.earlier)
+	for i := 0; i < maxCalendarRuns; i++ {
+		run = s.schedule.Next(run)
+		if run.IsZero() {
+			return run
+		}
+		adjusted, ok := s.adjust(run)
+		if ok && adjusted.After(t) {
+			return adjusted
+		}
+	}
+	return time.Time{}
+}
+
+// adjust applies the calendar to one run, reporting false when it is dropped
+func (s calendarSchedule) adjust(run time.Time) (time.Time, bool) {
+	c := s.calendar
+	if c.DayOff(run) {
+		step := 0
+		switch c.OnDayOff {
+		case DayOffNext:
+			step = 1
+		case DayOffPrevious:
+			step = -1
+		default:
+			return time.Time{}, false
+		}
+		// A year without working days would be a broken calendar
+		for i := 0; i < 366 && c.DayOff(run); i++ {
+			run = run.AddDate(0, 0, step)
+		}
+		if c.DayOff(run) {
+			return time.Time{}, false
+		}
+	}
+	if c.earlier > 0 && c.DayOff(run.AddDate(0, 0, 1)) {
+		run = run.Add(-c.earlier)
+	}
+	return run, true
+}
@@ -0,0 +1,307 @@
+package ingest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"news-system/internal/services/language"
+	"news-system/internal/services/news"
+)
+
+// CSVMapping names the CSV column that holds each article field. Column names
+// are matched against the header row case-insensitively; an empty name means
+// the file has no such column.
+type CSVMapping struct {
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	URL             string `json:"url"`
+	PublicationDate string `json:"publication_date"`
+	SourceName      string `json:"source_name"`
+	Category        string `json:"category"`
+	RelevanceScore  string `json:"relevance_score"`
+	Latitude        string `json:"latitude"`
+	Longitude       string `json:"longitude"`
+	Language        string `json:"language"`
+
+	// CategorySeparator splits the category column into several categories, ";" by default
+	CategorySeparator string `json:"category_separator"`
+	// DateLayouts are tried in order to parse the publication date; Go reference layouts
+	DateLayouts []string `json:"date_layouts"`
+	// Delimiter separates fields, "," by default; use "\t" for tab-separated exports
+	Delimiter string `json:"delimiter"`
+}
+
+// csvDateLayouts are the date formats spreadsheets commonly export
+var csvDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+}
+
+// DefaultCSVMapping expects columns named like the fields of the JSON ingestion format
+func DefaultCSVMapping() CSVMapping {
+	return CSVMapping{
+		Title:             "title",
+		Description:       "description",
+		URL:               "url",
+		PublicationDate:   "publication_date",
+		SourceName:        "source_name",
+		Category:          "category",
+		RelevanceScore:    "relevance_score",
+		Latitude:          "latitude",
+		Longitude:         "longitude",
+		Language:          "language",
+		CategorySeparator: ";",
+		DateLayouts:       csvDateLayouts,
+		Delimiter:         ",",
+	}
+}
+
+// LoadCSVMapping reads a column mapping from a JSON file. Fields it leaves out
+// keep their DefaultCSVMapping values; set a column to "" to ignore it.
+func LoadCSVMapping(path string) (CSVMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CSVMapping{}, fmt.Errorf("failed to read CSV mapping %s: %w", path, err)
+	}
+	mapping := DefaultCSVMapping()
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return CSVMapping{}, fmt.Errorf("failed to decode CSV mapping %s: %w", path, err)
+	}
+	return mapping, nil
+}
+
+// csvColumns holds the index of each mapped column in a file, -1 when absent
+type csvColumns struct {
+	title, description, url, publicationDate, sourceName, category, relevanceScore, latitude, longitude, language int
+}
+
+// resolve finds the mapped columns in a header row. Title, URL and publication
+// date are required; the source defaults to the URL's host.
+func (m CSVMapping) resolve(header []string) (csvColumns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	var missing []string
+	lookup := func(column string, required bool) int {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if i, ok := index[column]; ok && column != "" {
+			return i
+		}
+		if required {
+			missing = append(missing, fmt.Sprintf("%q", column))
+		}
+		return -1
+	}
+
+	cols := csvColumns{
+		title:           lookup(m.Title, true),
+		description:     lookup(m.Description, false),
+		url:             lookup(m.URL, true),
+		publicationDate: lookup(m.PublicationDate, true),
+		sourceName:      lookup(m.SourceName, false),
+		category:        lookup(m.Category, false),
+		relevanceScore:  lookup(m.RelevanceScore, false),
+		latitude:        lookup(m.Latitude, false),
+		longitude:       lookup(m.Longitude, false),
+		language:        lookup(m.Language, false),
+	}
+	if len(missing) > 0 {
+		return csvColumns{}, fmt.Errorf("header is missing required columns %s", strings.Join(missing, ", "))
+	}
+	return cols, nil
+}
+
+// article converts a CSV row to the ingestion format
+func (m CSVMapping) article(cols csvColumns, row []string) (news.ArticleDTO, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	article := news.ArticleDTO{
+		Title:      field(cols.title),
+		URL:        field(cols.url),
+		SourceName: field(cols.sourceName),
+	}
+	if article.Title == "" {
+		return article, violation("missing title", "title is missing")
+	}
+	if article.URL == "" {
+		return article, violation("missing url", "url is missing")
+	}
+	if article.SourceName == "" {
+		if u, err := url.Parse(article.URL); err == nil && u.Hostname() != "" {
+			article.SourceName = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+
+	if desc := field(cols.description); desc != "" {
+		article.Description = &desc
+	}
+
+	published, err := m.parseDate(field(cols.publicationDate))
+	if err != nil {
+		return article, err
+	}
+	article.PublicationDate = published
+
+	if categories := field(cols.category); categories != "" {
+		separator := m.CategorySeparator
+		if separator == "" {
+			separator = ";"
+		}
+		for _, category := range strings.Split(categories, separator) {
+			if category = strings.TrimSpace(category); category != "" {
+				article.Category = append(article.Category, category)
+			}
+		}
+	}
+
+	if score := field(cols.relevanceScore); score != "" {
+		value, err := strconv.ParseFloat(score, 64)
+		if err != nil || value < 0 || value > 1 {
+			return article, violation("relevance score out of range", "relevance score %q is not a number between 0 and 1", score)
+		}
+		article.RelevanceScore = value
+	}
+
+	lat, lon := field(cols.latitude), field(cols.longitude)
+	if lat != "" || lon != "" {
+		latitude, latErr := strconv.ParseFloat(lat, 64)
+		longitude, lonErr := strconv.ParseFloat(lon, 64)
+		if latErr != nil || lonErr != nil || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			return article, violation("invalid coordinates", "coordinates %q, %q are not a valid latitude and longitude", lat, lon)
+		}
+		article.Latitude, article.Longitude = &latitude, &longitude
+	}
+
+	article.Language = field(cols.language)
+	if _, ok := language.Normalize(article.Language); !ok {
+		return article, violation("invalid language", "language %q is not an ISO 639-1 code", article.Language)
+	}
+
+	return article, nil
+}
+
+// parseDate parses a publication date with the first matching layout
+func (m CSVMapping) parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, violation("missing publication date", "publication date is missing")
+	}
+	layouts := m.DateLayouts
+	if len(layouts) == 0 {
+		layouts = csvDateLayouts
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, violation("invalid publication date", "publication date %q matches none of the date layouts", value)
+}
+
+// newCSVReader creates a reader for CSV with the mapping's delimiter. A zero
+// mapping is replaced by DefaultCSVMapping, which is returned with the reader.
+func newCSVReader(r io.Reader, mapping CSVMapping) (*csv.Reader, CSVMapping, error) {
+	if mapping.Title == "" && mapping.URL == "" {
+		mapping = DefaultCSVMapping()
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	if mapping.Delimiter != "" {
+		delimiter, size := utf8.DecodeRuneInString(strings.ReplaceAll(mapping.Delimiter, `\t`, "\t"))
+		if size == 0 || delimiter == utf8.RuneError {
+			return nil, mapping, fmt.Errorf("invalid CSV delimiter %q", mapping.Delimiter)
+		}
+		reader.Comma = delimiter
+	}
+	return reader, mapping, nil
+}
+
+// LoadFromCSV streams articles from a CSV file with a header row and upserts
+// them in batches, mapping columns to fields with opts.CSV. Rows that cannot
+// be converted are reported and skipped rather than aborting the load.
+func (l *Loader) LoadFromCSV(ctx context.Context, filePath string, opts LoadOptions) (LoadProgress, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return LoadProgress{}, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	reader, mapping, err := newCSVReader(file, opts.CSV)
+	if err != nil {
+		return LoadProgress{}, err
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return LoadProgress{}, fmt.Errorf("failed to read CSV header from %s: %w", filePath, err)
+	}
+	cols, err := mapping.resolve(header)
+	if err != nil {
+		return LoadProgress{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	var totalBytes int64
+	if info, err := file.Stat(); err == nil {
+		totalBytes = info.Size()
+	}
+	w := l.newBatchWriter(filePath, totalBytes, opts)
+	// Count the header so reported line numbers match the file
+	w.progress.Lines = 1
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			w.fail(parseErr.Err)
+		case err != nil:
+			return w.progress, fmt.Errorf("failed to read %s: %w", filePath, err)
+		default:
+			article, err := mapping.article(cols, row)
+			if err != nil {
+				w.fail(err)
+			} else if err := w.add(ctx, article); err != nil {
+				return w.progress, err
+			}
+		}
+
+		if err := w.advance(ctx, reader.InputOffset()); err != nil {
+			return w.progress, err
+		}
+	}
+
+	// The header is not a record
+	w.progress.Lines--
+	return w.finish(ctx)
+}
@@ -0,0 +1,135 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Daemon runs the ingestion pipeline on a schedule: it polls every provider
+// and, when a load path is set, reloads the files under it. Runs never
+// overlap; a run that outlasts the gap to the next scheduled time makes the
+// daemon skip ahead to the first time after the run finished.
+type Daemon struct {
+	loader    *Loader
+	schedule  Schedule
+	providers []Provider
+	loadPath  string
+	loadOpts  LoadOptions
+
+	cancelRun context.CancelFunc
+	done      chan bool
+	wg        sync.WaitGroup
+}
+
+// NewDaemon creates a daemon that runs on schedule. loadPath may be empty to
+// only poll providers.
+func NewDaemon(loader *Loader, schedule Schedule, loadPath string, loadOpts LoadOptions, providers ...Provider) *Daemon {
+	return &Daemon{
+		loader:    loader,
+		schedule:  schedule,
+		providers: providers,
+		loadPath:  loadPath,
+		loadOpts:  loadOpts,
+		done:      make(chan bool),
+	}
+}
+
+// Start begins running on schedule, with a first run right away when runNow is set
+func (d *Daemon) Start(ctx context.Context, runNow bool) {
+	// Runs get their own context so Stop can let one finish before cancelling it
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	d.cancelRun = cancel
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		if runNow {
+			d.run(runCtx)
+		}
+		for {
+			next := d.schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Error().Msg("Ingestion schedule has no upcoming runs")
+				return
+			}
+			log.Info().Time("next_run", next).Msg("Next ingestion run scheduled")
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				d.run(runCtx)
+			case <-d.done:
+				timer.Stop()
+				return
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	log.Info().Int("providers", len(d.providers)).Str("load_path", d.loadPath).Msg("Ingestion daemon started")
+}
+
+// Stop stops scheduling runs and waits up to timeout for a run in progress to
+// finish, cancelling it if it doesn't
+func (d *Daemon) Stop(timeout time.Duration) error {
+	close(d.done)
+
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-time.After(timeout):
+		d.cancelRun()
+		<-finished
+		err = fmt.Errorf("ingestion run interrupted after waiting %s to shut down", timeout)
+	}
+	if d.cancelRun != nil {
+		d.cancelRun()
+	}
+	log.Info().Msg("Ingestion daemon stopped")
+	return err
+}
+
+// run executes the pipeline once, logging rather than returning failures so a
+// bad run doesn't stop later ones
+func (d *Daemon) run(ctx context.Context) {
+	start := time.Now()
+	created, updated, skipped, failures := 0, 0, 0, 0
+
+	for _, provider := range d.providers {
+		c, u, s, err := d.loader.LoadFromProvider(ctx, provider)
+		created += c
+		updated += u
+		skipped += s
+		if err != nil {
+			failures++
+			log.Error().Err(err).Str("provider", provider.Name()).Msg("Scheduled provider poll failed")
+		}
+	}
+
+	if d.loadPath != "" {
+		if err := d.loader.LoadPath(ctx, d.loadPath, d.loadOpts); err != nil {
+			failures++
+			log.Error().Err(err).Str("path", d.loadPath).Msg("Scheduled file load failed")
+		}
+	}
+
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("created", created).
+		Int("updated", updated).
+		Int("skipped", skipped).
+		Int("failures", failures).
+		Msg("Ingestion run finished")
+}
@@ -0,0 +1,321 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"news-system/internal/repo"
+	"news-system/internal/services/news"
+)
+
+// ExportFormatVersion is the version of the export layout written by Export
+const ExportFormatVersion = 1
+
+const (
+	exportManifestFile = "manifest.json"
+	exportArticlesFile = "articles.json"
+	exportPageSize     = 500
+)
+
+// ErrCorruptExport is returned by Import when records fail verification
+var ErrCorruptExport = errors.New("export failed integrity verification")
+
+// ExportRecord is an article in an export together with the hash of its content.
+// Exported article files are plain JSON arrays, so LoadFromFile can still read them.
+type ExportRecord struct {
+	news.ArticleDTO
+	ContentHash string `json:"content_hash"`
+}
+
+// Manifest describes the files of an export and how to verify them
+type Manifest struct {
+	FormatVersion int            `json:"format_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Files         []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file of an export with its record count and SHA-256
+type ManifestFile struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+	SHA256  string `json:"sha256"`
+}
+
+// CorruptRecord identifies a record that failed verification
+type CorruptRecord struct {
+	File   string `json:"file"`
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport summarizes the verification and loading of an export
+type ImportReport struct {
+	Verified int `json:"verified"`
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+	// Skipped counts records whose content matched the stored article
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+	// FileErrors are whole-file problems: checksum or record count mismatches
+	FileErrors []string        `json:"file_errors,omitempty"`
+	Corrupt    []CorruptRecord `json:"corrupt,omitempty"`
+}
+
+// Corrupted reports whether any part of the export failed verification
+func (r *ImportReport) Corrupted() bool {
+	return len(r.FileErrors) > 0 || len(r.Corrupt) > 0
+}
+
+// ContentHash returns the SHA-256 of an article's content. IDs, summaries and
+// query-time fields are excluded, so the hash identifies the same article
+// across environments.
+func ContentHash(article news.ArticleDTO) string {
+	content := struct {
+		Title           string   `json:"title"`
+		Description     *string  `json:"description"`
+		URL             string   `json:"url"`
+		PublicationDate string   `json:"publication_date"`
+		SourceName      string   `json:"source_name"`
+		Category        []string `json:"category"`
+		RelevanceScore  float64  `json:"relevance_score"`
+		Latitude        *float64 `json:"latitude"`
+		Longitude       *float64 `json:"longitude"`
+	}{
+		Title:           article.Title,
+		Description:     article.Description,
+		URL:             article.URL,
+		PublicationDate: article.PublicationDate.UTC().Format(time.RFC3339Nano),
+		SourceName:      article.SourceName,
+		Category:        article.Category,
+		RelevanceScore:  article.RelevanceScore,
+		Latitude:        article.Latitude,
+		Longitude:       article.Longitude,
+	}
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Export writes every article to dir as articles.json, with a manifest
+// holding the file's checksum and record count
+func (l *Loader) Export(ctx context.Context, dir string) (*Manifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	file, err := os.Create(filepath.Join(dir, exportArticlesFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", exportArticlesFile, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	w := io.MultiWriter(file, hash)
+
+	records := 0
+	io.WriteString(w, "[\n")
+	page := repo.ListArticlesParams{Limit: exportPageSize}
+	for {
+		articles, err := l.repo.ListArticles(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list articles: %w", err)
+		}
+
+		for _, article := range articles {
+			dto := articleToDTO(article)
+			data, err := json.Marshal(ExportRecord{ArticleDTO: dto, ContentHash: ContentHash(dto)})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode article %s: %w", article.ID, err)
+			}
+			if records > 0 {
+				io.WriteString(w, ",\n")
+			}
+			w.Write(data)
+			records++
+		}
+
+		if len(articles) < exportPageSize {
+			break
+		}
+		last := articles[len(articles)-1]
+		page.BeforeDate, page.BeforeID = &last.PublicationDate, last.ID
+	}
+	io.WriteString(w, "\n]\n")
+
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", exportArticlesFile, err)
+	}
+
+	manifest := &Manifest{
+		FormatVersion: ExportFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Files: []ManifestFile{{
+			Name:    exportArticlesFile,
+			Records: records,
+			SHA256:  hex.EncodeToString(hash.Sum(nil)),
+		}},
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, exportManifestFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	fmt.Printf("Exported %d articles to %s\n", records, dir)
+	return manifest, nil
+}
+
+// encodeExportFile encodes articles as an export file in the layout Export writes
+func encodeExportFile(articles []repo.Article) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, article := range articles {
+		dto := articleToDTO(article)
+		data, err := json.Marshal(ExportRecord{ArticleDTO: dto, ContentHash: ContentHash(dto)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode article %s: %w", article.ID, err)
+		}
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.Write(data)
+	}
+	buf.WriteString("\n]\n")
+	return buf.Bytes(), nil
+}
+
+// Import verifies an export written by Export and loads its articles. Every
+// file checksum and record hash is checked before anything is written; if any
+// fail, nothing is loaded and ErrCorruptExport is returned with the report,
+// unless partial is set, in which case the intact records are still loaded.
+func (l *Loader) Import(ctx context.Context, dir string, partial bool) (*ImportReport, error) {
+	data, err := os.ReadFile(filepath.Join(dir, exportManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.FormatVersion != ExportFormatVersion {
+		return nil, fmt.Errorf("unsupported export format version %d", manifest.FormatVersion)
+	}
+
+	report := &ImportReport{}
+	var intact []news.ArticleDTO
+	for _, mf := range manifest.Files {
+		articles, err := verifyExportFile(dir, mf, report)
+		if err != nil {
+			return report, err
+		}
+		intact = append(intact, articles...)
+	}
+	report.Verified = len(intact)
+
+	if report.Corrupted() && !partial {
+		return report, ErrCorruptExport
+	}
+
+	for _, article := range intact {
+		_, status, err := l.LoadArticle(ctx, article)
+		switch {
+		case err != nil:
+			report.Failed++
+			fmt.Printf("Failed to import article %s: %v\n", article.URL, err)
+		case status == news.BatchUpdated:
+			report.Updated++
+		case status == news.BatchSkipped:
+			report.Skipped++
+		default:
+			report.Created++
+		}
+	}
+
+	fmt.Printf("Imported %d new and updated %d existing articles from %s, skipped %d unchanged\n", report.Created, report.Updated, dir, report.Skipped)
+	if report.Corrupted() {
+		return report, ErrCorruptExport
+	}
+	return report, nil
+}
+
+// verifyExportFile checks a file against its manifest entry and returns the
+// records whose content hash matches, adding every problem to report
+func verifyExportFile(dir string, mf ManifestFile, report *ImportReport) ([]news.ArticleDTO, error) {
+	if filepath.Base(mf.Name) != mf.Name {
+		return nil, fmt.Errorf("invalid file name in manifest: %q", mf.Name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, mf.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", mf.Name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != mf.SHA256 {
+		report.FileErrors = append(report.FileErrors, fmt.Sprintf("%s: checksum %s does not match manifest %s", mf.Name, actual, mf.SHA256))
+	}
+
+	// Decode records individually so one damaged record doesn't hide the rest
+	var raw []json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&raw); err != nil {
+		report.FileErrors = append(report.FileErrors, fmt.Sprintf("%s: not a JSON array: %v", mf.Name, err))
+		return nil, nil
+	}
+	if len(raw) != mf.Records {
+		report.FileErrors = append(report.FileErrors, fmt.Sprintf("%s: %d records, manifest lists %d", mf.Name, len(raw), mf.Records))
+	}
+
+	var intact []news.ArticleDTO
+	for i, item := range raw {
+		var record ExportRecord
+		if err := json.Unmarshal(item, &record); err != nil {
+			report.Corrupt = append(report.Corrupt, CorruptRecord{File: mf.Name, Index: i, Reason: fmt.Sprintf("invalid JSON: %v", err)})
+			continue
+		}
+
+		corrupt := CorruptRecord{File: mf.Name, Index: i, ID: record.ID, URL: record.URL}
+		switch actual := ContentHash(record.ArticleDTO); {
+		case record.ContentHash == "":
+			corrupt.Reason = "missing content_hash"
+		case actual != record.ContentHash:
+			corrupt.Reason = fmt.Sprintf("content hash %s does not match %s", actual, record.ContentHash)
+		default:
+			intact = append(intact, record.ArticleDTO)
+			continue
+		}
+		report.Corrupt = append(report.Corrupt, corrupt)
+	}
+	return intact, nil
+}
+
+// articleToDTO converts a stored article to the ingestion format
+func articleToDTO(article repo.Article) news.ArticleDTO {
+	return news.ArticleDTO{
+		ID:              article.ID,
+		Title:           article.Title,
+		Description:     article.Description,
+		URL:             article.URL,
+		PublicationDate: article.PublicationDate,
+		SourceName:      article.SourceName,
+		Category:        article.Category,
+		RelevanceScore:  article.RelevanceScore,
+		Latitude:        article.Latitude,
+		Longitude:       article.Longitude,
+		Language:        article.Language,
+		City:            article.City,
+		Region:          article.Region,
+		Country:         article.Country,
+	}
+}
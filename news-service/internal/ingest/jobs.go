@@ -0,0 +1,416 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/repo"
+	"news-system/internal/storage"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// exportJobTTL is how long a job's state is kept after it was last updated
+	exportJobTTL = 7 * 24 * time.Hour
+	// exportJobLeaseTTL is how long an instance holds a job without making progress
+	exportJobLeaseTTL = 2 * time.Minute
+	// exportJobPageAttempts is the number of tries for a page before the job fails
+	exportJobPageAttempts = 5
+)
+
+// ExportJobStatus is the state of an export job
+type ExportJobStatus string
+
+const (
+	ExportJobRunning   ExportJobStatus = "running"
+	ExportJobCompleted ExportJobStatus = "completed"
+	ExportJobFailed    ExportJobStatus = "failed"
+)
+
+// ExportJob is an export written page by page to object storage. Each page is
+// a separate export file, so a job interrupted by a restart or a storage error
+// resumes after Cursor instead of starting over. A completed job has the same
+// layout as Export: a manifest.json listing every file with its checksum.
+type ExportJob struct {
+	ID          string          `json:"id"`
+	Status      ExportJobStatus `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	// Offset is the number of articles exported so far
+	Offset int32 `json:"offset"`
+	// Cursor is the last article exported, where the job resumes. Articles
+	// inserted meanwhile don't shift the remaining pages.
+	Cursor *ExportCursor  `json:"cursor,omitempty"`
+	Files  []ManifestFile `json:"files"`
+	Error  string         `json:"error,omitempty"`
+
+	// ManifestURL is a signed download link, set on completed jobs when read
+	ManifestURL string `json:"manifest_url,omitempty"`
+}
+
+// ExportCursor is a position in the publication_date DESC, id DESC order export pages follow
+type ExportCursor struct {
+	PublicationDate time.Time `json:"publication_date"`
+	ID              string    `json:"id"`
+}
+
+// ExportJobs runs export jobs in the background. Job state lives in Redis, so
+// any instance can report on a job and unfinished jobs are resumed by the next
+// instance to start; a lease keeps two instances from running the same job.
+type ExportJobs struct {
+	loader    *Loader
+	cache     *cache.RedisCache
+	store     storage.Store
+	limiter   *rate.Limiter
+	urlExpiry time.Duration
+
+	mu      sync.Mutex
+	running map[string]bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewExportJobs creates an export job runner that reads at most rowsPerSecond
+// articles per second across all of its jobs
+func NewExportJobs(loader *Loader, cache *cache.RedisCache, store storage.Store, rowsPerSecond int, urlExpiry time.Duration) *ExportJobs {
+	if rowsPerSecond < exportPageSize {
+		rowsPerSecond = exportPageSize
+	}
+	if urlExpiry <= 0 {
+		urlExpiry = 15 * time.Minute
+	}
+	return &ExportJobs{
+		loader:    loader,
+		cache:     cache,
+		store:     store,
+		limiter:   rate.NewLimiter(rate.Limit(rowsPerSecond), exportPageSize),
+		urlExpiry: urlExpiry,
+		running:   make(map[string]bool),
+	}
+}
+
+// Start resumes every unfinished job, including those left behind by stopped instances
+func (j *ExportJobs) Start(ctx context.Context) {
+	j.ctx, j.cancel = context.WithCancel(ctx)
+
+	ids, err := j.cache.SMembers(ctx, cache.ExportJobsActiveKey())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list unfinished export jobs")
+	}
+	for _, id := range ids {
+		job, err := j.load(ctx, id)
+		if err != nil {
+			log.Warn().Err(err).Str("job_id", id).Msg("Failed to load export job")
+			continue
+		}
+		if job.Status == ExportJobRunning {
+			j.run(job)
+		}
+	}
+
+	log.Info().Int("resumed", len(ids)).Msg("Export jobs started")
+}
+
+// Stop interrupts running jobs, which keep their progress and resume on the next Start
+func (j *ExportJobs) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+	log.Info().Msg("Export jobs stopped")
+}
+
+// Create starts a new export job
+func (j *ExportJobs) Create(ctx context.Context) (*ExportJob, error) {
+	now := time.Now().UTC()
+	job := &ExportJob{
+		ID:        repo.NewArticleID(),
+		Status:    ExportJobRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Files:     []ManifestFile{},
+	}
+	if err := j.save(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := j.cache.SAdd(ctx, cache.ExportJobsActiveKey(), job.ID); err != nil {
+		return nil, fmt.Errorf("failed to register export job: %w", err)
+	}
+
+	created := *job
+	j.run(job)
+	return &created, nil
+}
+
+// Get returns a job's progress, with a signed manifest link once it has completed
+func (j *ExportJobs) Get(ctx context.Context, id string) (*ExportJob, error) {
+	job, err := j.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == ExportJobCompleted {
+		url, err := j.store.SignedURL(ctx, storage.ExportFileKey(job.ID, exportManifestFile), j.urlExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign manifest URL: %w", err)
+		}
+		job.ManifestURL = url
+	}
+	return job, nil
+}
+
+// Resume restarts a failed or stalled job from its last completed page
+func (j *ExportJobs) Resume(ctx context.Context, id string) (*ExportJob, error) {
+	job, err := j.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == ExportJobCompleted {
+		return nil, errs.Errorf(errs.ErrConflict, "export job %s has already completed", id)
+	}
+
+	job.Status = ExportJobRunning
+	job.Error = ""
+	job.UpdatedAt = time.Now().UTC()
+	if err := j.save(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := j.cache.SAdd(ctx, cache.ExportJobsActiveKey(), job.ID); err != nil {
+		return nil, fmt.Errorf("failed to register export job: %w", err)
+	}
+
+	resumed := *job
+	j.run(job)
+	return &resumed, nil
+}
+
+// run executes a job in the background unless this instance is already running it
+func (j *ExportJobs) run(job *ExportJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ctx == nil || j.running[job.ID] {
+		return
+	}
+	j.running[job.ID] = true
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer func() {
+			j.mu.Lock()
+			delete(j.running, job.ID)
+			j.mu.Unlock()
+		}()
+
+		if err := j.execute(j.ctx, job); err != nil && j.ctx.Err() == nil {
+			log.Error().Err(err).Str("job_id", job.ID).Msg("Export job failed")
+		}
+	}()
+}
+
+// execute exports the remaining pages of a job, saving progress after each one
+func (j *ExportJobs) execute(ctx context.Context, job *ExportJob) error {
+	leaseKey := cache.ExportJobLeaseKey(job.ID)
+	job, err := j.acquire(ctx, job, leaseKey)
+	if err != nil || job == nil {
+		return err
+	}
+	defer j.cache.Del(context.WithoutCancel(ctx), leaseKey)
+
+	log.Info().Str("job_id", job.ID).Int32("offset", job.Offset).Msg("Export job running")
+	for {
+		done, err := j.exportPageWithRetry(ctx, job)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Interrupted by shutdown: the job stays running and resumes on the next Start
+				return err
+			}
+			return j.finish(job, ExportJobFailed, err)
+		}
+		if done {
+			break
+		}
+		j.cache.Expire(ctx, leaseKey, exportJobLeaseTTL)
+	}
+
+	if err := j.writeManifest(ctx, job); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return j.finish(job, ExportJobFailed, err)
+	}
+	return j.finish(job, ExportJobCompleted, nil)
+}
+
+// acquire takes a job's lease. While another instance holds it, the job is
+// checked again once the lease could have expired, so a job left behind by an
+// instance that crashed is picked up. It returns the job's latest state, or
+// nil once another instance has finished it.
+func (j *ExportJobs) acquire(ctx context.Context, job *ExportJob, leaseKey string) (*ExportJob, error) {
+	for {
+		acquired, err := j.cache.SetNX(ctx, leaseKey, "1", exportJobLeaseTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire export job lease: %w", err)
+		}
+		if acquired {
+			return job, nil
+		}
+
+		log.Info().Str("job_id", job.ID).Msg("Export job is running on another instance, checking again when its lease expires")
+		select {
+		case <-time.After(exportJobLeaseTTL):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// Continue from the other instance's progress
+		job, err = j.load(ctx, job.ID)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != ExportJobRunning {
+			return nil, nil
+		}
+	}
+}
+
+// exportPageWithRetry exports the page at the job's offset, retrying storage
+// and database blips with exponential backoff. It reports whether the page
+// was the last one.
+func (j *ExportJobs) exportPageWithRetry(ctx context.Context, job *ExportJob) (bool, error) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		done, err := j.exportPage(ctx, job)
+		if err == nil || ctx.Err() != nil || attempt == exportJobPageAttempts {
+			return done, err
+		}
+
+		log.Warn().Err(err).Str("job_id", job.ID).Int32("offset", job.Offset).Int("attempt", attempt).Msg("Export job page failed, retrying")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// exportPage writes the next page of articles as one file and records it in the job
+func (j *ExportJobs) exportPage(ctx context.Context, job *ExportJob) (bool, error) {
+	if err := j.limiter.WaitN(ctx, exportPageSize); err != nil {
+		return false, err
+	}
+
+	page := repo.ListArticlesParams{Limit: exportPageSize}
+	if job.Cursor != nil {
+		page.BeforeDate, page.BeforeID = &job.Cursor.PublicationDate, job.Cursor.ID
+	}
+	articles, err := j.loader.repo.ListArticles(ctx, page)
+	if err != nil {
+		return false, fmt.Errorf("failed to list articles: %w", err)
+	}
+	if len(articles) == 0 {
+		return true, nil
+	}
+
+	data, err := encodeExportFile(articles)
+	if err != nil {
+		return false, err
+	}
+	name := fmt.Sprintf("articles-%05d.json", len(job.Files)+1)
+	if err := j.store.Put(ctx, storage.ExportFileKey(job.ID, name), bytes.NewReader(data), "application/json"); err != nil {
+		return false, fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	job.Files = append(job.Files, ManifestFile{Name: name, Records: len(articles), SHA256: hex.EncodeToString(sum[:])})
+	cursor := job.Cursor
+	last := articles[len(articles)-1]
+	job.Cursor = &ExportCursor{PublicationDate: last.PublicationDate, ID: last.ID}
+	job.Offset += int32(len(articles))
+	job.UpdatedAt = time.Now().UTC()
+	if err := j.save(ctx, job); err != nil {
+		// The file is rewritten under the same name when the page is retried
+		job.Files = job.Files[:len(job.Files)-1]
+		job.Cursor = cursor
+		job.Offset -= int32(len(articles))
+		return false, err
+	}
+
+	return len(articles) < exportPageSize, nil
+}
+
+// writeManifest uploads the manifest listing every file of a job
+func (j *ExportJobs) writeManifest(ctx context.Context, job *ExportJob) error {
+	data, err := json.MarshalIndent(Manifest{
+		FormatVersion: ExportFormatVersion,
+		CreatedAt:     job.CreatedAt,
+		Files:         job.Files,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := j.store.Put(ctx, storage.ExportFileKey(job.ID, exportManifestFile), bytes.NewReader(data), "application/json"); err != nil {
+		return fmt.Errorf("failed to upload manifest: %w", err)
+	}
+	return nil
+}
+
+// finish records the final status of a job and removes it from the active set
+func (j *ExportJobs) finish(job *ExportJob, status ExportJobStatus, jobErr error) error {
+	// Record the outcome even if the job's context was cancelled meanwhile
+	ctx := context.WithoutCancel(j.ctx)
+
+	now := time.Now().UTC()
+	job.Status = status
+	job.UpdatedAt = now
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	} else {
+		job.CompletedAt = &now
+	}
+	if err := j.save(ctx, job); err != nil {
+		return err
+	}
+	if err := j.cache.SRem(ctx, cache.ExportJobsActiveKey(), job.ID); err != nil {
+		return fmt.Errorf("failed to unregister export job: %w", err)
+	}
+
+	log.Info().Str("job_id", job.ID).Str("status", string(status)).Int32("articles", job.Offset).Int("files", len(job.Files)).Msg("Export job finished")
+	return jobErr
+}
+
+func (j *ExportJobs) save(ctx context.Context, job *ExportJob) error {
+	if err := j.cache.Set(ctx, cache.ExportJobKey(job.ID), job, exportJobTTL); err != nil {
+		return fmt.Errorf("failed to save export job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+func (j *ExportJobs) load(ctx context.Context, id string) (*ExportJob, error) {
+	data, err := j.cache.Get(ctx, cache.ExportJobKey(id))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return nil, errs.Errorf(errs.ErrNotFound, "export job not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export job %s: %w", id, err)
+	}
+
+	var job ExportJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode export job %s: %w", id, err)
+	}
+	return &job, nil
+}
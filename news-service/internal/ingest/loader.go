@@ -2,8 +2,6 @@ package ingest
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/fs"
@@ -12,36 +10,105 @@ import (
 	"strings"
 	"time"
 
+	"news-system/internal/bus"
+	"news-system/internal/metrics"
 	"news-system/internal/repo"
+	"news-system/internal/services/categorize"
+	"news-system/internal/services/geocode"
+	"news-system/internal/services/language"
 	"news-system/internal/services/news"
 )
 
 // Loader handles data ingestion from JSON files
 type Loader struct {
-	repo repo.Repository
+	repo       repo.Repository
+	events     *bus.Bus
+	classifier *categorize.Classifier
+	tagger     *geocode.Tagger
 }
 
-// NewLoader creates a new Loader instance
-func NewLoader(repo repo.Repository) *Loader {
-	return &Loader{repo: repo}
+// NewLoader creates a new Loader instance. Created and updated articles are
+// announced on events; a nil bus disables publishing.
+func NewLoader(repo repo.Repository, events *bus.Bus) *Loader {
+	return &Loader{repo: repo, events: events}
 }
 
-// LoadFromDirectory loads all JSON files from a directory
+// EnableCategorization classifies articles that arrive without categories
+// with classifier before storing them
+func (l *Loader) EnableCategorization(classifier *categorize.Classifier) {
+	l.classifier = classifier
+}
+
+// EnableGeocoding fills in the place of articles that arrive with coordinates
+// but without one with tagger before storing them
+func (l *Loader) EnableGeocoding(tagger *geocode.Tagger) {
+	l.tagger = tagger
+}
+
+// LoadPath loads a JSON, NDJSON or CSV file, or every such file under a directory
+func (l *Loader) LoadPath(ctx context.Context, path string, opts LoadOptions) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return l.loadDirectory(ctx, path, opts)
+	}
+	switch {
+	case isNDJSON(path):
+		_, err := l.LoadFromNDJSON(ctx, path, opts)
+		return err
+	case isCSV(path):
+		_, err := l.LoadFromCSV(ctx, path, opts)
+		return err
+	}
+	return l.LoadFromFile(ctx, path)
+}
+
+// LoadFromDirectory loads all JSON, NDJSON and CSV files from a directory
 func (l *Loader) LoadFromDirectory(ctx context.Context, dirPath string) error {
+	return l.loadDirectory(ctx, dirPath, LoadOptions{})
+}
+
+func (l *Loader) loadDirectory(ctx context.Context, dirPath string, opts LoadOptions) error {
 	return filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		
-		if d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".json") {
+		if d.IsDir() {
 			return nil
 		}
 		
-		fmt.Printf("Loading file: %s\n", path)
-		return l.LoadFromFile(ctx, path)
+		switch {
+		case isNDJSON(path):
+			fmt.Printf("Streaming file: %s\n", path)
+			_, err := l.LoadFromNDJSON(ctx, path, opts)
+			return err
+		case isCSV(path):
+			fmt.Printf("Streaming file: %s\n", path)
+			_, err := l.LoadFromCSV(ctx, path, opts)
+			return err
+		case strings.HasSuffix(strings.ToLower(path), ".json"):
+			fmt.Printf("Loading file: %s\n", path)
+			return l.LoadFromFile(ctx, path)
+		}
+		return nil
 	})
 }
 
+// isNDJSON reports whether a file holds newline-delimited JSON, judging by its extension
+func isNDJSON(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".ndjson") || strings.HasSuffix(lower, ".jsonl")
+}
+
+// isCSV reports whether a file holds comma- or tab-separated values, judging by its extension
+func isCSV(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".csv") || strings.HasSuffix(lower, ".tsv")
+}
+
 // LoadFromFile loads articles from a single JSON file
 func (l *Loader) LoadFromFile(ctx context.Context, filePath string) error {
 	file, err := os.Open(filePath)
@@ -57,50 +124,100 @@ func (l *Loader) LoadFromFile(ctx context.Context, filePath string) error {
 
 	fmt.Printf("Found %d articles in %s\n", len(articles), filePath)
 	
+	seen := make(map[string]bool)
+	created, updated, skipped := 0, 0, 0
 	for i, article := range articles {
-		if err := l.LoadArticle(ctx, article); err != nil {
+		// Skip repeats of the same canonical URL within the file
+		urlHash := repo.URLHash(article.URL)
+		if seen[urlHash] {
+			fmt.Printf("Skipping duplicate article %d: %s\n", i, article.URL)
+			metrics.IngestedArticles.WithLabelValues(string(news.BatchSkipped)).Inc()
+			skipped++
+			continue
+		}
+		seen[urlHash] = true
+
+		_, status, err := l.LoadArticle(ctx, article)
+		if err != nil {
 			fmt.Printf("Failed to load article %d: %v\n", i, err)
 			continue
 		}
-		fmt.Printf("Loaded article: %s\n", article.Title)
+		switch status {
+		case news.BatchUpdated:
+			updated++
+			fmt.Printf("Updated article: %s\n", article.Title)
+		case news.BatchSkipped:
+			skipped++
+		default:
+			created++
+			fmt.Printf("Loaded article: %s\n", article.Title)
+		}
 	}
 	
+	fmt.Printf("Loaded %d new and updated %d existing articles from %s, skipped %d unchanged or repeated\n", created, updated, filePath, skipped)
 	return nil
 }
 
-// LoadArticle loads a single article into the database
-func (l *Loader) LoadArticle(ctx context.Context, article news.ArticleDTO) error {
-	// Generate a unique ID for the article
-	id := generateID()
+// LoadArticle upserts a single article into the database. It reports whether
+// the article was created, updated in place of the one stored under the same
+// canonical URL, or skipped because its content hash matches the stored one.
+// A skipped article is not written; only its ID is set on the returned article.
+func (l *Loader) LoadArticle(ctx context.Context, article news.ArticleDTO) (repo.Article, news.BatchStatus, error) {
+	url := strings.TrimSpace(article.URL)
+	versions, err := l.repo.GetArticleVersions(ctx, []string{repo.URLHash(url)})
+	if err != nil {
+		metrics.IngestedArticles.WithLabelValues("failed").Inc()
+		return repo.Article{}, news.BatchFailed, fmt.Errorf("failed to look up article: %w", err)
+	}
+	if versions[0].ContentHash == repo.ArticleContentHash(article.Title, article.Description, url) {
+		metrics.IngestedArticles.WithLabelValues(string(news.BatchSkipped)).Inc()
+		return repo.Article{ID: versions[0].ID}, news.BatchSkipped, nil
+	}
+
+	// Generate a unique ID for the article; an existing article keeps its own
+	id := repo.NewArticleID()
 	
 	// Convert DTO to database model
 	dbArticle := repo.CreateArticleParams{
 		ID:              id,
 		Title:           article.Title,
 		Description:     article.Description,
-		URL:             article.URL,
+		URL:             url,
 		PublicationDate: article.PublicationDate,
 		SourceName:      article.SourceName,
 		Category:        article.Category,
 		RelevanceScore:  article.RelevanceScore,
 		Latitude:        article.Latitude,
 		Longitude:       article.Longitude,
+		Language:        language.ForArticle(article.Language, article.Title, article.Description),
+		City:            article.City,
+		Region:          article.Region,
+		Country:         article.Country,
 	}
+	l.classifier.Classify(ctx, &dbArticle)
+	l.tagger.Tag(ctx, &dbArticle)
 
-	// Create the article
-	_, err := l.repo.CreateArticle(ctx, dbArticle)
+	// Create the article, or update the one stored under the same canonical URL
+	stored, err := l.repo.CreateArticle(ctx, dbArticle)
 	if err != nil {
-		return fmt.Errorf("failed to create article: %w", err)
+		metrics.IngestedArticles.WithLabelValues("failed").Inc()
+		return repo.Article{}, news.BatchFailed, fmt.Errorf("failed to create article: %w", err)
 	}
 
-	return nil
-}
+	status, eventType := news.BatchCreated, bus.ArticleCreated
+	if stored.ID != id {
+		status, eventType = news.BatchUpdated, bus.ArticleUpdated
+	}
+	metrics.IngestedArticles.WithLabelValues(string(status)).Inc()
+	l.events.Emit(eventType, bus.ArticlePayload{
+		ArticleID:  stored.ID,
+		Title:      stored.Title,
+		URL:        stored.URL,
+		SourceName: stored.SourceName,
+		Category:   stored.Category,
+	})
 
-// generateID generates a simple unique ID
-func generateID() string {
-	bytes := make([]byte, 8)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
+	return stored, status, nil
 }
 
 // GenerateSampleData generates 20 sample articles for testing
@@ -331,7 +448,7 @@ func (l *Loader) GenerateSampleData(ctx context.Context) error {
 	fmt.Printf("Generating %d sample articles...\n", len(sampleArticles))
 	
 	for i, article := range sampleArticles {
-		if err := l.LoadArticle(ctx, article); err != nil {
+		if _, _, err := l.LoadArticle(ctx, article); err != nil {
 			fmt.Printf("Failed to load sample article %d: %v\n", i, err)
 			continue
 		}
@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"news-system/internal/services/news"
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// LoadFromNDJSON streams articles from a newline-delimited JSON file, one
+// article object per line, and upserts them in batches. Memory use depends on
+// the batch size, not the file size, so multi-GB dumps can be loaded. Invalid
+// lines are reported and skipped rather than aborting the load.
+func (l *Loader) LoadFromNDJSON(ctx context.Context, filePath string, opts LoadOptions) (LoadProgress, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return LoadProgress{}, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var totalBytes int64
+	if info, err := file.Stat(); err == nil {
+		totalBytes = info.Size()
+	}
+	w := l.newBatchWriter(filePath, totalBytes, opts)
+
+	counter := &countingReader{r: file}
+	reader := bufio.NewReaderSize(counter, 1<<20)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return w.progress, fmt.Errorf("failed to read %s: %w", filePath, readErr)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var article news.ArticleDTO
+			if err := json.Unmarshal(line, &article); err != nil {
+				w.fail(fmt.Errorf("invalid JSON: %w", err))
+			} else if err := w.add(ctx, article); err != nil {
+				return w.progress, err
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if err := w.advance(ctx, counter.n-int64(reader.Buffered())); err != nil {
+			return w.progress, err
+		}
+	}
+
+	return w.finish(ctx)
+}
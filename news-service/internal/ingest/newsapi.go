@@ -0,0 +1,308 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/services/news"
+	"news-system/internal/version"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// newsAPIMaxPageSize is the largest page NewsAPI returns
+const newsAPIMaxPageSize = 100
+
+// newsAPICategories maps NewsAPI's top-headlines categories to ours
+var newsAPICategories = map[string]string{
+	"business":      "Business",
+	"entertainment": "Entertainment",
+	"general":       "World",
+	"health":        "Health",
+	"science":       "Science",
+	"sports":        "Sports",
+	"technology":    "Technology",
+}
+
+var (
+	// errNewsAPIRateLimited is returned while NewsAPI is rejecting requests for exceeding the plan's quota
+	errNewsAPIRateLimited = errors.New("newsapi rate limit reached")
+	// errNewsAPIResultCap is returned when paging past the number of results the plan allows
+	errNewsAPIResultCap = errors.New("newsapi result cap reached")
+)
+
+// NewsAPIOptions configures a NewsAPIProvider
+type NewsAPIOptions struct {
+	APIKey  string
+	BaseURL string
+	// Country and Categories select top headlines; Sources replaces both, as NewsAPI doesn't allow mixing them
+	Country    string
+	Categories []string
+	Sources    []string
+	// Query enables the everything endpoint, restricted to Language and Domains when set
+	Query    string
+	Language string
+	Domains  []string
+	// MaxPages bounds the pages fetched per endpoint and category on each poll
+	MaxPages int
+	PageSize int
+	// RequestsPerMinute spaces out requests to stay within the plan's quota
+	RequestsPerMinute int
+}
+
+// NewsAPIProvider fetches articles from NewsAPI.org's top-headlines and
+// everything endpoints. Headlines are fetched per configured category so the
+// category can be recorded on the article; everything results are fetched
+// incrementally from the time of the previous successful poll.
+type NewsAPIProvider struct {
+	opts    NewsAPIOptions
+	client  *http.Client
+	limiter *rate.Limiter
+
+	mu           sync.Mutex
+	lastFetch    time.Time
+	blockedUntil time.Time
+}
+
+// NewNewsAPIProvider creates a NewsAPI provider
+func NewNewsAPIProvider(opts NewsAPIOptions) *NewsAPIProvider {
+	if opts.BaseURL == "" {
+		opts.BaseURL = "https://newsapi.org/v2"
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = 5
+	}
+	if opts.PageSize <= 0 || opts.PageSize > newsAPIMaxPageSize {
+		opts.PageSize = newsAPIMaxPageSize
+	}
+	if opts.RequestsPerMinute <= 0 {
+		opts.RequestsPerMinute = 30
+	}
+	return &NewsAPIProvider{
+		opts:    opts,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(opts.RequestsPerMinute)), 1),
+	}
+}
+
+// Name identifies the provider in logs
+func (p *NewsAPIProvider) Name() string {
+	return "newsapi"
+}
+
+// Fetch returns the current top headlines and, with a query configured, the
+// articles matching it since the previous fetch
+func (p *NewsAPIProvider) Fetch(ctx context.Context) ([]news.ArticleDTO, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if wait := time.Until(p.blockedUntil); wait > 0 {
+		return nil, fmt.Errorf("%w, retrying in %s", errNewsAPIRateLimited, wait.Round(time.Second))
+	}
+
+	started := time.Now()
+	var articles []news.ArticleDTO
+
+	// Headlines: one pass per category, or a single pass for sources or country alone
+	categories := p.opts.Categories
+	if len(p.opts.Sources) > 0 || len(categories) == 0 {
+		categories = []string{""}
+	}
+	for _, category := range categories {
+		params := url.Values{}
+		switch {
+		case len(p.opts.Sources) > 0:
+			params.Set("sources", strings.Join(p.opts.Sources, ","))
+		default:
+			if p.opts.Country != "" {
+				params.Set("country", p.opts.Country)
+			}
+			if category != "" {
+				params.Set("category", category)
+			}
+		}
+
+		fetched, err := p.fetchPages(ctx, "top-headlines", params, category, 0.9)
+		articles = append(articles, fetched...)
+		if err != nil {
+			return articles, err
+		}
+	}
+
+	if p.opts.Query != "" {
+		params := url.Values{}
+		params.Set("q", p.opts.Query)
+		params.Set("sortBy", "publishedAt")
+		if p.opts.Language != "" {
+			params.Set("language", p.opts.Language)
+		}
+		if len(p.opts.Domains) > 0 {
+			params.Set("domains", strings.Join(p.opts.Domains, ","))
+		}
+		from := p.lastFetch
+		if from.IsZero() {
+			from = started.Add(-24 * time.Hour)
+		}
+		params.Set("from", from.UTC().Format(time.RFC3339))
+
+		fetched, err := p.fetchPages(ctx, "everything", params, "", 0.7)
+		articles = append(articles, fetched...)
+		if err != nil {
+			return articles, err
+		}
+	}
+
+	p.lastFetch = started
+	return articles, nil
+}
+
+// newsAPIResponse is the body of every NewsAPI response
+type newsAPIResponse struct {
+	Status       string           `json:"status"`
+	Code         string           `json:"code"`
+	Message      string           `json:"message"`
+	TotalResults int              `json:"totalResults"`
+	Articles     []newsAPIArticle `json:"articles"`
+}
+
+type newsAPIArticle struct {
+	Source struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"source"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// fetchPages pages through an endpoint until it runs out of results, reaches
+// MaxPages or hits the plan's result cap. Articles are scored from base down
+// by their position, as NewsAPI orders results by prominence or relevance.
+func (p *NewsAPIProvider) fetchPages(ctx context.Context, endpoint string, params url.Values, category string, base float64) ([]news.ArticleDTO, error) {
+	var articles []news.ArticleDTO
+	position := 0
+	for page := 1; page <= p.opts.MaxPages; page++ {
+		params.Set("page", strconv.Itoa(page))
+		params.Set("pageSize", strconv.Itoa(p.opts.PageSize))
+
+		resp, err := p.get(ctx, endpoint, params)
+		if errors.Is(err, errNewsAPIResultCap) {
+			break
+		}
+		if err != nil {
+			return articles, err
+		}
+
+		for _, item := range resp.Articles {
+			article, ok := newsAPIToDTO(item, category, base, position)
+			position++
+			if ok {
+				articles = append(articles, article)
+			}
+		}
+
+		if len(resp.Articles) < p.opts.PageSize || page*p.opts.PageSize >= resp.TotalResults {
+			break
+		}
+	}
+	return articles, nil
+}
+
+// get performs one rate-limited request
+func (p *NewsAPIProvider) get(ctx context.Context, endpoint string, params url.Values) (*newsAPIResponse, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.opts.BaseURL+"/"+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Api-Key", p.opts.APIKey)
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	httpResp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call newsapi %s: %w", endpoint, err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp newsAPIResponse
+	err = json.NewDecoder(io.LimitReader(httpResp.Body, 16<<20)).Decode(&resp)
+	if err != nil && httpResp.StatusCode != http.StatusTooManyRequests {
+		return nil, fmt.Errorf("failed to decode newsapi %s response (HTTP %d): %w", endpoint, httpResp.StatusCode, err)
+	}
+
+	switch {
+	case httpResp.StatusCode == http.StatusTooManyRequests || resp.Code == "rateLimited":
+		// Quotas are daily or hourly; without a Retry-After, back off for an hour
+		wait := time.Hour
+		if seconds, err := strconv.Atoi(httpResp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		p.blockedUntil = time.Now().Add(wait)
+		log.Warn().Dur("retry_in", wait).Msg("NewsAPI rate limit reached, pausing requests")
+		return nil, errNewsAPIRateLimited
+	case resp.Code == "maximumResultsReached":
+		return nil, errNewsAPIResultCap
+	case resp.Status != "ok":
+		return nil, fmt.Errorf("newsapi %s failed (HTTP %d, %s): %s", endpoint, httpResp.StatusCode, resp.Code, resp.Message)
+	}
+	return &resp, nil
+}
+
+// newsAPIToDTO maps a NewsAPI article to the ingestion format. Articles that
+// were taken down or lack a URL or title are skipped.
+func newsAPIToDTO(item newsAPIArticle, category string, base float64, position int) (news.ArticleDTO, bool) {
+	title := strings.TrimSpace(item.Title)
+	if title == "" || title == "[Removed]" || item.URL == "" || item.URL == "https://removed.com" {
+		return news.ArticleDTO{}, false
+	}
+
+	sourceName := item.Source.Name
+	if sourceName == "" {
+		sourceName = item.Source.ID
+	}
+	if sourceName == "" {
+		if u, err := url.Parse(item.URL); err == nil {
+			sourceName = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+
+	var categories []string
+	if mapped, ok := newsAPICategories[category]; ok {
+		categories = []string{mapped}
+	}
+
+	var description *string
+	if desc := strings.TrimSpace(item.Description); desc != "" {
+		description = &desc
+	}
+
+	publishedAt := item.PublishedAt
+	if publishedAt.IsZero() {
+		publishedAt = time.Now().UTC()
+	}
+
+	return news.ArticleDTO{
+		Title:           title,
+		Description:     description,
+		URL:             item.URL,
+		PublicationDate: publishedAt,
+		SourceName:      sourceName,
+		Category:        categories,
+		RelevanceScore:  max(base-0.002*float64(position), 0.3),
+	}, true
+}
@@ -0,0 +1,243 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"news-system/internal/bus"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/language"
+	"news-system/internal/services/news"
+)
+
+// LoadOptions configures the streaming loaders, LoadFromNDJSON and LoadFromCSV
+type LoadOptions struct {
+	// BatchSize is the number of articles written per bulk upsert
+	BatchSize int
+	// ProgressInterval is the minimum time between progress reports
+	ProgressInterval time.Duration
+	// Progress receives progress reports; nil prints them to stdout
+	Progress func(LoadProgress)
+	// CSV maps columns to article fields for CSV files; zero value uses DefaultCSVMapping
+	CSV CSVMapping
+}
+
+// LoadProgress reports how far a streaming load has got
+type LoadProgress struct {
+	// Lines counts records read: lines of NDJSON, rows of CSV
+	Lines   int
+	Created int
+	Updated int
+	// Failed counts records that could not be parsed and articles the repository rejected
+	Failed int
+	// Skipped counts unchanged articles and repeats of a URL within the same batch
+	Skipped    int
+	BytesRead  int64
+	TotalBytes int64
+	Elapsed    time.Duration
+	Done       bool
+}
+
+// String formats the progress for logs
+func (p LoadProgress) String() string {
+	percent := ""
+	if p.TotalBytes > 0 {
+		percent = fmt.Sprintf(" (%.1f%%)", 100*float64(p.BytesRead)/float64(p.TotalBytes))
+	}
+	rate := 0.0
+	if p.Elapsed > 0 {
+		rate = float64(p.Lines) / p.Elapsed.Seconds()
+	}
+	return fmt.Sprintf("%d lines, %d MB%s: %d created, %d updated, %d skipped, %d failed [%.0f lines/s]",
+		p.Lines, p.BytesRead>>20, percent, p.Created, p.Updated, p.Skipped, p.Failed, rate)
+}
+
+// batchWriter collects the articles of a streaming load into batches, writes
+// each full batch and reports progress
+type batchWriter struct {
+	loader   *Loader
+	filePath string
+	opts     LoadOptions
+
+	progress   LoadProgress
+	batch      []news.ArticleDTO
+	started    time.Time
+	lastReport time.Time
+}
+
+func (l *Loader) newBatchWriter(filePath string, totalBytes int64, opts LoadOptions) *batchWriter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = 10 * time.Second
+	}
+	if opts.Progress == nil {
+		opts.Progress = func(p LoadProgress) {
+			fmt.Printf("%s: %s\n", filePath, p)
+		}
+	}
+	now := time.Now()
+	return &batchWriter{
+		loader:     l,
+		filePath:   filePath,
+		opts:       opts,
+		progress:   LoadProgress{TotalBytes: totalBytes},
+		batch:      make([]news.ArticleDTO, 0, opts.BatchSize),
+		started:    now,
+		lastReport: now,
+	}
+}
+
+// add queues an article read from record number Lines, writing the batch once it is full
+func (w *batchWriter) add(ctx context.Context, article news.ArticleDTO) error {
+	w.progress.Lines++
+	w.batch = append(w.batch, article)
+	if len(w.batch) >= w.opts.BatchSize {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+// fail counts a record that could not be parsed and reports why
+func (w *batchWriter) fail(err error) {
+	w.progress.Lines++
+	w.progress.Failed++
+	fmt.Printf("%s:%d: %v\n", w.filePath, w.progress.Lines, err)
+}
+
+// flush writes the queued articles
+func (w *batchWriter) flush(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	created, updated, skipped, failed, err := w.loader.loadBatch(ctx, w.batch)
+	if err != nil {
+		return fmt.Errorf("failed to load batch ending at line %d of %s: %w", w.progress.Lines, w.filePath, err)
+	}
+	w.progress.Created += created
+	w.progress.Updated += updated
+	w.progress.Skipped += skipped
+	w.progress.Failed += failed
+	w.batch = w.batch[:0]
+	return nil
+}
+
+// advance records how much of the file has been read and reports progress when due
+func (w *batchWriter) advance(ctx context.Context, bytesRead int64) error {
+	w.progress.BytesRead = bytesRead
+	w.progress.Elapsed = time.Since(w.started)
+	if time.Since(w.lastReport) >= w.opts.ProgressInterval {
+		w.opts.Progress(w.progress)
+		w.lastReport = time.Now()
+	}
+	return ctx.Err()
+}
+
+// finish writes the last partial batch and sends the final report
+func (w *batchWriter) finish(ctx context.Context) (LoadProgress, error) {
+	if err := w.flush(ctx); err != nil {
+		return w.progress, err
+	}
+	w.progress.BytesRead = max(w.progress.BytesRead, w.progress.TotalBytes)
+	w.progress.Elapsed = time.Since(w.started)
+	w.progress.Done = true
+	w.opts.Progress(w.progress)
+	return w.progress, nil
+}
+
+// loadBatch upserts a batch of articles in one bulk write and announces them.
+// Articles whose content hash matches the stored version are skipped, as are
+// repeats of a URL within the batch; across batches a repeat is compared with
+// what the earlier batch stored like any re-ingested article.
+func (l *Loader) loadBatch(ctx context.Context, articles []news.ArticleDTO) (created, updated, skipped, failed int, err error) {
+	defer func() {
+		metrics.IngestedArticles.WithLabelValues(string(news.BatchCreated)).Add(float64(created))
+		metrics.IngestedArticles.WithLabelValues(string(news.BatchUpdated)).Add(float64(updated))
+		metrics.IngestedArticles.WithLabelValues(string(news.BatchSkipped)).Add(float64(skipped))
+		metrics.IngestedArticles.WithLabelValues("failed").Add(float64(failed))
+	}()
+
+	seen := make(map[string]bool, len(articles))
+	candidates := make([]repo.CreateArticleParams, 0, len(articles))
+	hashes := make([]string, 0, len(articles))
+	for _, article := range articles {
+		url := strings.TrimSpace(article.URL)
+		urlHash := repo.URLHash(url)
+		if seen[urlHash] {
+			skipped++
+			continue
+		}
+		seen[urlHash] = true
+
+		// A fresh ID tells a created article from an updated one, which keeps its own
+		candidates = append(candidates, repo.CreateArticleParams{
+			ID:              repo.NewArticleID(),
+			Title:           article.Title,
+			Description:     article.Description,
+			URL:             url,
+			PublicationDate: article.PublicationDate,
+			SourceName:      article.SourceName,
+			Category:        article.Category,
+			RelevanceScore:  article.RelevanceScore,
+			Latitude:        article.Latitude,
+			Longitude:       article.Longitude,
+			Language:        language.ForArticle(article.Language, article.Title, article.Description),
+			City:            article.City,
+			Region:          article.Region,
+			Country:         article.Country,
+		})
+		hashes = append(hashes, urlHash)
+	}
+
+	// Only write the articles that are new or whose content changed
+	versions, err := l.repo.GetArticleVersions(ctx, hashes)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	params := candidates[:0]
+	for i, candidate := range candidates {
+		if versions[i].ContentHash == repo.ArticleContentHash(candidate.Title, candidate.Description, candidate.URL) {
+			skipped++
+			continue
+		}
+		params = append(params, candidate)
+	}
+	if len(params) == 0 {
+		return created, updated, skipped, failed, nil
+	}
+	l.classifier.ClassifyAll(ctx, params)
+	l.tagger.TagAll(ctx, params)
+
+	results, err := l.repo.BulkCreateArticles(ctx, params)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	for i, res := range results {
+		if res.Err != nil {
+			failed++
+			fmt.Printf("Failed to load article %s: %v\n", params[i].URL, res.Err)
+			continue
+		}
+
+		eventType := bus.ArticleCreated
+		if res.Article.ID != params[i].ID {
+			eventType = bus.ArticleUpdated
+			updated++
+		} else {
+			created++
+		}
+		l.events.Emit(eventType, bus.ArticlePayload{
+			ArticleID:  res.Article.ID,
+			Title:      res.Article.Title,
+			URL:        res.Article.URL,
+			SourceName: res.Article.SourceName,
+			Category:   res.Article.Category,
+		})
+	}
+	return created, updated, skipped, failed, nil
+}
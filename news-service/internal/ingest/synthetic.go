@@ -0,0 +1,329 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"news-system/internal/services/news"
+)
+
+// SyntheticOptions configures GenerateSynthetic
+type SyntheticOptions struct {
+	// Count is the number of articles to generate
+	Count int
+	// Seed makes a run reproducible: the same seed and options generate the
+	// same articles, so a second run updates nothing. 0 picks one from the clock.
+	Seed int64
+	// Sources is the number of distinct publishers, default 500
+	Sources int
+	// SourceSkew and CategorySkew are the Zipf exponents (above 1) of how
+	// articles spread over publishers and categories, default 1.1 and 1.3;
+	// higher values concentrate them on the first few
+	SourceSkew   float64
+	CategorySkew float64
+	// Span is how far back publication dates reach, default 30 days
+	Span time.Duration
+	// GeoShare is the share of articles with coordinates, default 0.8
+	GeoShare float64
+	// Load sets the batch size and progress reporting of the writes
+	Load LoadOptions
+}
+
+// syntheticCity is a center articles cluster around, weighted by how much news it makes
+type syntheticCity struct {
+	name     string
+	lat, lon float64
+	weight   float64
+	// spread is the standard deviation of article locations in degrees
+	spread float64
+}
+
+var syntheticCities = []syntheticCity{
+	{"New York", 40.7128, -74.0060, 10, 0.15},
+	{"London", 51.5074, -0.1278, 9, 0.15},
+	{"Washington", 38.9072, -77.0369, 7, 0.1},
+	{"Tokyo", 35.6762, 139.6503, 6, 0.2},
+	{"Paris", 48.8566, 2.3522, 6, 0.12},
+	{"Los Angeles", 34.0522, -118.2437, 6, 0.25},
+	{"San Francisco", 37.7749, -122.4194, 5, 0.12},
+	{"Beijing", 39.9042, 116.4074, 5, 0.2},
+	{"Berlin", 52.5200, 13.4050, 4, 0.12},
+	{"Mumbai", 19.0760, 72.8777, 4, 0.15},
+	{"Delhi", 28.6139, 77.2090, 4, 0.2},
+	{"Chicago", 41.8781, -87.6298, 4, 0.15},
+	{"Sydney", -33.8688, 151.2093, 3, 0.15},
+	{"Sao Paulo", -23.5505, -46.6333, 3, 0.2},
+	{"Toronto", 43.6532, -79.3832, 3, 0.12},
+	{"Moscow", 55.7558, 37.6173, 3, 0.15},
+	{"Brussels", 50.8503, 4.3517, 2, 0.08},
+	{"Singapore", 1.3521, 103.8198, 2, 0.05},
+	{"Hong Kong", 22.3193, 114.1694, 2, 0.05},
+	{"Dubai", 25.2048, 55.2708, 2, 0.1},
+	{"Seoul", 37.5665, 126.9780, 2, 0.12},
+	{"Mexico City", 19.4326, -99.1332, 2, 0.15},
+	{"Lagos", 6.5244, 3.3792, 2, 0.15},
+	{"Nairobi", -1.2921, 36.8219, 1, 0.1},
+	{"Cairo", 30.0444, 31.2357, 1, 0.12},
+	{"Buenos Aires", -34.6037, -58.3816, 1, 0.15},
+	{"Madrid", 40.4168, -3.7038, 1, 0.1},
+	{"Rome", 41.9028, 12.4964, 1, 0.1},
+	{"Geneva", 46.2044, 6.1432, 1, 0.05},
+	{"Cape Canaveral", 28.3922, -80.6077, 0.5, 0.05},
+}
+
+// syntheticCategories are ordered by how often they appear, most common first
+var syntheticCategories = []string{
+	"Politics", "Business", "Technology", "Sports", "Entertainment", "Health",
+	"World", "Science", "Finance", "Environment", "AI", "Education", "Travel",
+	"Crime", "Weather", "Space", "Energy", "Culture", "Automotive", "General",
+}
+
+// syntheticSubjects holds the actors and topics of titles, by category; other
+// categories use the General list
+var syntheticSubjects = map[string][]string{
+	"Politics":      {"Parliament", "The governor", "Opposition leaders", "The mayor", "Senate committee", "Coalition partners"},
+	"Business":      {"Retail giant", "Local startup", "Shipping firm", "Bank regulators", "Airline", "Grocery chain"},
+	"Technology":    {"Chipmaker", "Social network", "Cloud provider", "Phone maker", "Open source project", "Software company"},
+	"Sports":        {"Home team", "Star striker", "Tennis champion", "League officials", "Marathon runners", "Rookie pitcher"},
+	"Entertainment": {"Film festival", "Pop star", "Streaming service", "Broadway show", "Award-winning director", "Music label"},
+	"Health":        {"Hospital network", "Health officials", "Vaccine maker", "Researchers", "Nurses union", "Drug regulator"},
+	"Science":       {"Physicists", "Marine biologists", "University lab", "Archaeologists", "Astronomers", "Geologists"},
+	"Finance":       {"Central bank", "Stock market", "Bond traders", "Pension fund", "Crypto exchange", "Insurers"},
+	"Environment":   {"Climate scientists", "Wildfire crews", "Conservation group", "River authority", "Solar farm", "City planners"},
+	"AI":            {"AI lab", "Chatbot maker", "Robotics startup", "Ethics board", "Model developers", "Regulators"},
+	"General":       {"Residents", "City council", "Officials", "Volunteers", "Community leaders", "Local businesses"},
+}
+
+var syntheticActions = []string{
+	"announces", "unveils", "rejects", "approves", "delays", "expands", "warns about",
+	"launches", "investigates", "celebrates", "cuts", "doubles down on", "pauses", "wins",
+}
+
+var syntheticObjects = []string{
+	"new plan", "record budget", "major partnership", "sweeping reform", "long-awaited deal",
+	"safety review", "expansion", "surprise proposal", "pilot program", "merger talks",
+	"breakthrough", "strike action", "funding round", "policy shift", "recall",
+}
+
+var syntheticSourceWords = [][]string{
+	{"Daily", "Morning", "Evening", "Global", "Metro", "National", "City", "Weekly", "Independent", "Sunday"},
+	{"Herald", "Times", "Post", "Tribune", "Chronicle", "Ledger", "Gazette", "Observer", "Wire", "Journal", "Dispatch", "Courier"},
+}
+
+// syntheticSource is a publisher; most of its articles come from around its home city
+type syntheticSource struct {
+	name string
+	slug string
+	home int
+}
+
+// SyntheticGenerator produces articles whose publishers and categories follow
+// Zipf distributions, whose locations cluster around cities, and whose
+// publication dates favor recent days, local daytime and weekdays, as real
+// feeds do. It isn't safe for concurrent use.
+type SyntheticGenerator struct {
+	opts       SyntheticOptions
+	rng        *rand.Rand
+	sources    []syntheticSource
+	sourceZipf *rand.Zipf
+	catZipf    *rand.Zipf
+	// cityCDF is the cumulative city weight, for picking cities by weight
+	cityCDF []float64
+	now     time.Time
+	n       int
+}
+
+// NewSyntheticGenerator validates opts, fills in defaults and prepares the publishers
+func NewSyntheticGenerator(opts SyntheticOptions) (*SyntheticGenerator, error) {
+	if opts.Sources <= 0 {
+		opts.Sources = 500
+	}
+	if opts.SourceSkew == 0 {
+		opts.SourceSkew = 1.1
+	}
+	if opts.CategorySkew == 0 {
+		opts.CategorySkew = 1.3
+	}
+	if opts.Span <= 0 {
+		opts.Span = 30 * 24 * time.Hour
+	}
+	if opts.GeoShare == 0 {
+		opts.GeoShare = 0.8
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.SourceSkew <= 1 || opts.CategorySkew <= 1 {
+		return nil, fmt.Errorf("zipf skews must be above 1, got %g and %g", opts.SourceSkew, opts.CategorySkew)
+	}
+	if opts.GeoShare < 0 || opts.GeoShare > 1 {
+		return nil, fmt.Errorf("geo share must be between 0 and 1, got %g", opts.GeoShare)
+	}
+
+	g := &SyntheticGenerator{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)),
+		// Dates are relative to the hour, so reruns with the same seed within it match
+		now: time.Now().UTC().Truncate(time.Hour),
+	}
+	g.sourceZipf = rand.NewZipf(g.rng, opts.SourceSkew, 1, uint64(opts.Sources-1))
+	g.catZipf = rand.NewZipf(g.rng, opts.CategorySkew, 1, uint64(len(syntheticCategories)-1))
+
+	var total float64
+	for _, city := range syntheticCities {
+		total += city.weight
+		g.cityCDF = append(g.cityCDF, total)
+	}
+
+	first, second := syntheticSourceWords[0], syntheticSourceWords[1]
+	for i := 0; i < opts.Sources; i++ {
+		home := g.city()
+		name := syntheticCities[home].name + " " + first[i%len(first)] + " " + second[(i/len(first))%len(second)]
+		if round := i / (len(first) * len(second)); round > 0 {
+			name = fmt.Sprintf("%s %d", name, round+1)
+		}
+		g.sources = append(g.sources, syntheticSource{name: name, slug: slugify(name), home: home})
+	}
+	return g, nil
+}
+
+// Next generates the next article
+func (g *SyntheticGenerator) Next() news.ArticleDTO {
+	g.n++
+	source := g.sources[g.sourceZipf.Uint64()]
+
+	categories := []string{syntheticCategories[g.catZipf.Uint64()]}
+	if g.rng.Float64() < 0.4 {
+		if extra := syntheticCategories[g.catZipf.Uint64()]; extra != categories[0] {
+			categories = append(categories, extra)
+		}
+	}
+
+	// Most stories are local to the publisher; the rest are picked up from elsewhere
+	city := source.home
+	if g.rng.Float64() < 0.3 {
+		city = g.city()
+	}
+	published := g.publicationDate(syntheticCities[city].lon)
+
+	subjects, ok := syntheticSubjects[categories[0]]
+	if !ok {
+		subjects = syntheticSubjects["General"]
+	}
+	subject := subjects[g.rng.Intn(len(subjects))]
+	action := syntheticActions[g.rng.Intn(len(syntheticActions))]
+	object := syntheticObjects[g.rng.Intn(len(syntheticObjects))]
+	place := syntheticCities[city].name
+	title := fmt.Sprintf("%s %s %s in %s", subject, action, object, place)
+	description := fmt.Sprintf("%s %s %s on %s, according to %s. The %s story is developing.",
+		subject, action, object, published.Format("January 2"), source.name, strings.ToLower(categories[0]))
+
+	article := news.ArticleDTO{
+		Title:           title,
+		Description:     &description,
+		URL:             fmt.Sprintf("https://%s.example.com/%s/%s-%x-%d", source.slug, published.Format("2006/01/02"), slugify(title), uint32(g.opts.Seed), g.n),
+		PublicationDate: published,
+		SourceName:      source.name,
+		Category:        categories,
+		// Averaging two draws peaks scores in the middle of the range, as editors' ratings do
+		RelevanceScore: math.Round((0.3+0.7*(g.rng.Float64()+g.rng.Float64())/2)*100) / 100,
+	}
+	if g.rng.Float64() < g.opts.GeoShare {
+		spread := syntheticCities[city].spread
+		lat := clamp(syntheticCities[city].lat+g.rng.NormFloat64()*spread, -90, 90)
+		lon := clamp(syntheticCities[city].lon+g.rng.NormFloat64()*spread, -180, 180)
+		article.Latitude = float64Ptr(math.Round(lat*1e5) / 1e5)
+		article.Longitude = float64Ptr(math.Round(lon*1e5) / 1e5)
+	}
+	return article
+}
+
+// city picks a city index by weight
+func (g *SyntheticGenerator) city() int {
+	target := g.rng.Float64() * g.cityCDF[len(g.cityCDF)-1]
+	for i, cumulative := range g.cityCDF {
+		if target < cumulative {
+			return i
+		}
+	}
+	return len(g.cityCDF) - 1
+}
+
+// publicationDate draws a date within the span that favors recent days, and
+// daytime and weekdays at longitude lon
+func (g *SyntheticGenerator) publicationDate(lon float64) time.Time {
+	// Solar time is close enough to local time for the shape of the day
+	offset := time.Duration(lon / 15 * float64(time.Hour)).Round(time.Hour)
+	for {
+		// Volume decays with age, a quarter of the span per e-fold
+		age := time.Duration(g.rng.ExpFloat64() * float64(g.opts.Span) / 4)
+		if age > g.opts.Span {
+			continue
+		}
+		published := g.now.Add(-age).Add(-time.Duration(g.rng.Int63n(int64(time.Hour))))
+		local := published.Add(offset)
+
+		// Accept in proportion to the hour's share of a day's news: quiet
+		// overnight, peaking mid-morning and early evening
+		hour := float64(local.Hour()) + float64(local.Minute())/60
+		weight := 0.1 + 0.6*gaussian(hour, 10, 3) + 0.3*gaussian(hour, 18, 2)
+		if day := local.Weekday(); day == time.Saturday || day == time.Sunday {
+			weight *= 0.6
+		}
+		if g.rng.Float64() < weight {
+			return published.Truncate(time.Second)
+		}
+	}
+}
+
+// gaussian is an unnormalized bell curve over x peaking at 1 at mean
+func gaussian(x, mean, stddev float64) float64 {
+	d := (x - mean) / stddev
+	return math.Exp(-d * d / 2)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// slugify lowercases s and joins its words with hyphens, for URLs
+func slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// GenerateSynthetic streams opts.Count generated articles into the repository
+// in batches, for capacity tests. Memory use depends on the batch size, not
+// the count.
+func (l *Loader) GenerateSynthetic(ctx context.Context, opts SyntheticOptions) (LoadProgress, error) {
+	generator, err := NewSyntheticGenerator(opts)
+	if err != nil {
+		return LoadProgress{}, err
+	}
+
+	label := fmt.Sprintf("synthetic (seed %d)", generator.opts.Seed)
+	w := l.newBatchWriter(label, 0, opts.Load)
+	for i := 0; i < opts.Count; i++ {
+		if err := w.add(ctx, generator.Next()); err != nil {
+			return w.progress, err
+		}
+		if err := w.advance(ctx, 0); err != nil {
+			return w.progress, err
+		}
+	}
+	return w.finish(ctx)
+}
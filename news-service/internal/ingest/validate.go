@@ -0,0 +1,352 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"news-system/internal/repo"
+	"news-system/internal/services/geocode"
+	"news-system/internal/services/language"
+	"news-system/internal/services/news"
+)
+
+// maxReportedViolations bounds the violations a report lists; all are counted
+const maxReportedViolations = 100
+
+// maxFutureSkew is how far in the future a publication date may be, allowing for time zones
+const maxFutureSkew = 24 * time.Hour
+
+// fieldError is a schema violation of one record: problem names its kind for
+// the summary and detail describes this occurrence
+type fieldError struct {
+	problem string
+	detail  string
+}
+
+func (e *fieldError) Error() string {
+	return e.detail
+}
+
+func violation(problem, format string, args ...interface{}) *fieldError {
+	return &fieldError{problem: problem, detail: fmt.Sprintf(format, args...)}
+}
+
+// Violation is a record that would be rejected, or loaded with bad data
+type Violation struct {
+	File string `json:"file"`
+	// Record is the 1-based position of the record: array index, NDJSON line or CSV line
+	Record  int    `json:"record"`
+	URL     string `json:"url,omitempty"`
+	Problem string `json:"problem"`
+	Detail  string `json:"detail"`
+}
+
+// ValidationReport summarizes a dry run of ingestion files
+type ValidationReport struct {
+	Files   int `json:"files"`
+	Records int `json:"records"`
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+	// Duplicates counts valid records whose canonical URL appeared earlier; they would update that article
+	Duplicates int `json:"duplicates"`
+	// ByProblem counts violations by kind; a record can have several
+	ByProblem map[string]int `json:"by_problem"`
+	// Violations lists the first maxReportedViolations violations
+	Violations []Violation `json:"violations"`
+
+	seen map[string]bool
+}
+
+// OK reports whether every record is valid
+func (r *ValidationReport) OK() bool {
+	return r.Invalid == 0
+}
+
+// Print writes a human-readable summary of the report
+func (r *ValidationReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "Validated %d records in %d files: %d valid (%d duplicate URLs), %d invalid\n",
+		r.Records, r.Files, r.Valid, r.Duplicates, r.Invalid)
+	if len(r.ByProblem) == 0 {
+		return
+	}
+
+	problems := make([]string, 0, len(r.ByProblem))
+	for problem := range r.ByProblem {
+		problems = append(problems, problem)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		return r.ByProblem[problems[i]] > r.ByProblem[problems[j]]
+	})
+	fmt.Fprintln(w, "Violations by kind:")
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  %-32s %d\n", problem, r.ByProblem[problem])
+	}
+
+	fmt.Fprintln(w, "Violations:")
+	for _, v := range r.Violations {
+		fmt.Fprintf(w, "  %s:%d: %s", v.File, v.Record, v.Detail)
+		if v.URL != "" {
+			fmt.Fprintf(w, " (%s)", v.URL)
+		}
+		fmt.Fprintln(w)
+	}
+	total := 0
+	for _, count := range r.ByProblem {
+		total += count
+	}
+	if total > len(r.Violations) {
+		fmt.Fprintf(w, "  ... and %d more\n", total-len(r.Violations))
+	}
+}
+
+// record checks one parsed record, or records why it could not be parsed
+func (r *ValidationReport) record(file string, position int, article news.ArticleDTO, parseErr error) {
+	r.Records++
+
+	var problems []*fieldError
+	if parseErr != nil {
+		var fe *fieldError
+		if !errors.As(parseErr, &fe) {
+			fe = violation("unparseable record", "%v", parseErr)
+		}
+		problems = append(problems, fe)
+	} else {
+		problems = validateArticle(article)
+	}
+
+	if len(problems) == 0 {
+		r.Valid++
+		urlHash := repo.URLHash(article.URL)
+		if r.seen[urlHash] {
+			r.Duplicates++
+		}
+		r.seen[urlHash] = true
+		return
+	}
+
+	r.Invalid++
+	for _, problem := range problems {
+		r.ByProblem[problem.problem]++
+		if len(r.Violations) < maxReportedViolations {
+			r.Violations = append(r.Violations, Violation{
+				File:    file,
+				Record:  position,
+				URL:     article.URL,
+				Problem: problem.problem,
+				Detail:  problem.detail,
+			})
+		}
+	}
+}
+
+// validateArticle returns every schema violation of an article, checked
+// against the same rules as the article API
+func validateArticle(a news.ArticleDTO) []*fieldError {
+	var problems []*fieldError
+
+	switch title := strings.TrimSpace(a.Title); {
+	case title == "":
+		problems = append(problems, violation("missing title", "title is missing"))
+	case len(title) > 500:
+		problems = append(problems, violation("title too long", "title is %d characters (max 500)", len(title)))
+	}
+
+	if raw := strings.TrimSpace(a.URL); raw == "" {
+		problems = append(problems, violation("missing url", "url is missing"))
+	} else if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, violation("invalid url", "url %q is not an absolute http(s) URL", raw))
+	}
+
+	switch {
+	case a.PublicationDate.IsZero():
+		problems = append(problems, violation("missing publication date", "publication_date is missing"))
+	case a.PublicationDate.After(time.Now().Add(maxFutureSkew)):
+		problems = append(problems, violation("publication date in the future", "publication_date %s is in the future", a.PublicationDate.Format(time.RFC3339)))
+	}
+
+	switch source := strings.TrimSpace(a.SourceName); {
+	case source == "":
+		problems = append(problems, violation("missing source name", "source_name is missing"))
+	case len(source) > 100:
+		problems = append(problems, violation("source name too long", "source_name is %d characters (max 100)", len(source)))
+	}
+
+	if a.RelevanceScore < 0 || a.RelevanceScore > 1 {
+		problems = append(problems, violation("relevance score out of range", "relevance_score %g is not between 0 and 1", a.RelevanceScore))
+	}
+
+	switch {
+	case (a.Latitude == nil) != (a.Longitude == nil):
+		problems = append(problems, violation("incomplete coordinates", "latitude and longitude must be given together"))
+	case a.Latitude != nil:
+		if *a.Latitude < -90 || *a.Latitude > 90 {
+			problems = append(problems, violation("latitude out of range", "latitude %g is not between -90 and 90", *a.Latitude))
+		}
+		if *a.Longitude < -180 || *a.Longitude > 180 {
+			problems = append(problems, violation("longitude out of range", "longitude %g is not between -180 and 180", *a.Longitude))
+		}
+	}
+
+	if _, ok := language.Normalize(a.Language); !ok {
+		problems = append(problems, violation("invalid language", "language %q is not an ISO 639-1 code", a.Language))
+	}
+	if country, ok := geocode.NormalizeCountry(a.Country); !ok || country != a.Country {
+		problems = append(problems, violation("invalid country", "country %q is not an uppercase ISO 3166-1 alpha-2 code", a.Country))
+	}
+
+	return problems
+}
+
+// decodeArticle parses one JSON article, telling bad dates apart from malformed JSON
+func decodeArticle(data []byte) (news.ArticleDTO, error) {
+	var article news.ArticleDTO
+	if err := json.Unmarshal(data, &article); err != nil {
+		var timeErr *time.ParseError
+		if errors.As(err, &timeErr) {
+			return article, violation("invalid publication date", "publication_date %q is not an RFC 3339 date", timeErr.Value)
+		}
+		return article, violation("invalid JSON", "invalid JSON: %v", err)
+	}
+	return article, nil
+}
+
+// Validate parses a JSON, NDJSON or CSV file, or every such file under a
+// directory, and reports schema violations such as missing URLs, bad dates
+// and out-of-range coordinates, without writing anything. It only fails for
+// files that cannot be read at all; invalid records are reported.
+func (l *Loader) Validate(ctx context.Context, path string, opts LoadOptions) (*ValidationReport, error) {
+	report := &ValidationReport{
+		ByProblem: make(map[string]int),
+		seen:      make(map[string]bool),
+	}
+
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// A file given explicitly is validated whatever its extension
+		lower := strings.ToLower(file)
+		if file != path && !isNDJSON(file) && !isCSV(file) && !strings.HasSuffix(lower, ".json") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		report.Files++
+		switch {
+		case isNDJSON(file):
+			return validateNDJSON(file, report)
+		case isCSV(file):
+			return validateCSV(file, opts.CSV, report)
+		default:
+			return validateJSON(file, report)
+		}
+	})
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// validateJSON checks every element of a JSON array file
+func validateJSON(file string, report *ValidationReport) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		// The file as a whole is unusable; report it as a single bad record
+		report.record(file, 0, news.ArticleDTO{}, violation("invalid JSON", "file is not a JSON array of articles: %v", err))
+		return nil
+	}
+	for i, raw := range records {
+		article, err := decodeArticle(raw)
+		report.record(file, i+1, article, err)
+	}
+	return nil
+}
+
+// validateNDJSON checks every line of an NDJSON file
+func validateNDJSON(file string, report *ValidationReport) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", file, err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, 1<<20)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("failed to read %s: %w", file, readErr)
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			article, err := decodeArticle(line)
+			report.record(file, lineNo, article, err)
+		}
+		if errors.Is(readErr, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// validateCSV checks every row of a CSV file against a column mapping
+func validateCSV(file string, mapping CSVMapping, report *ValidationReport) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", file, err)
+	}
+	defer f.Close()
+
+	reader, mapping, err := newCSVReader(f, mapping)
+	if err != nil {
+		return err
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header from %s: %w", file, err)
+	}
+	cols, err := mapping.resolve(header)
+	if err != nil {
+		// Without the required columns no row can be loaded
+		report.record(file, 1, news.ArticleDTO{}, violation("missing columns", "%v", err))
+		return nil
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			report.record(file, parseErr.Line, news.ArticleDTO{}, violation("malformed CSV", "%v", parseErr.Err))
+		case err != nil:
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		default:
+			line, _ := reader.FieldPos(0)
+			article, err := mapping.article(cols, row)
+			report.record(file, line, article, err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+)
+
+// Stats describes a queue for operators and autoscalers
+type Stats struct {
+	Name string `json:"name"`
+	// Length is the number of jobs waiting or running, Waiting those not
+	// yet delivered to a worker, by priority, and Pending those delivered
+	// but not yet finished
+	Length  int64            `json:"length"`
+	Waiting map[string]int64 `json:"waiting"`
+	Pending int64            `json:"pending"`
+	// Consumers is the number of consumers that ever read the queue
+	Consumers int64 `json:"consumers"`
+	// OldestAge is how long the oldest waiting or running job has been queued
+	OldestAge        string  `json:"oldest_age,omitempty"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+	// Delayed is the number of jobs, including retries, not due yet
+	Delayed int64 `json:"delayed"`
+	// DeadLetters is the number of jobs that failed every attempt
+	DeadLetters int64 `json:"dead_letters"`
+	// ProcessingRate is the job attempts every instance finished per
+	// second over the last complete rateWindow minutes
+	ProcessingRate float64 `json:"processing_rate"`
+}
+
+// rateWindow is how many minutes ProcessingRate is averaged over
+const rateWindow = 5
+
+// Stats describes the queues this instance works on
+func (q *Queues) Stats(ctx context.Context) ([]Stats, error) {
+	names := q.Queues()
+	stats := make([]Stats, len(names))
+	for i, name := range names {
+		s, err := q.stats(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if s.ProcessingRate, err = q.rate(ctx, name); err != nil {
+			return nil, err
+		}
+		stats[i] = s
+	}
+	return stats, nil
+}
+
+// Depths reports the backlog of the queues this instance works on, for
+// metrics.RegisterJobQueues
+func (q *Queues) Depths(ctx context.Context) ([]metrics.JobQueueDepth, error) {
+	names := q.Queues()
+	depths := make([]metrics.JobQueueDepth, len(names))
+	for i, name := range names {
+		s, err := q.stats(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		depths[i] = metrics.JobQueueDepth{
+			Queue:       name,
+			Waiting:     s.Waiting,
+			Running:     s.Pending,
+			Delayed:     s.Delayed,
+			DeadLetters: s.DeadLetters,
+			OldestAge:   time.Duration(s.OldestAgeSeconds * float64(time.Second)),
+		}
+	}
+	return depths, nil
+}
+
+// stats describes one queue, but for its processing rate
+func (q *Queues) stats(ctx context.Context, queue string) (Stats, error) {
+	s := Stats{Name: queue, Waiting: make(map[string]int64, len(priorities))}
+	var oldest time.Time
+	for _, priority := range priorities {
+		stream := cache.JobQueueKey(queue, priority)
+		length, err := q.cache.XLen(ctx, stream)
+		if err != nil {
+			return Stats{}, fmt.Errorf("failed to read %s queue length: %w", queue, err)
+		}
+		s.Length += length
+		s.Waiting[priority] = length
+		groups, err := q.cache.XInfoGroups(ctx, stream)
+		if err != nil {
+			return Stats{}, fmt.Errorf("failed to read %s consumer groups: %w", queue, err)
+		}
+		for _, g := range groups {
+			if g.Name == group {
+				s.Pending += g.Pending
+				s.Waiting[priority] -= g.Pending
+				// Every worker reads all of a queue's streams
+				if g.Consumers > s.Consumers {
+					s.Consumers = g.Consumers
+				}
+			}
+		}
+		first, err := q.cache.XRange(ctx, stream, "-", "+", 1)
+		if err != nil {
+			return Stats{}, fmt.Errorf("failed to read %s oldest job: %w", queue, err)
+		}
+		if len(first) > 0 {
+			if at, ok := entryTime(first[0].ID); ok && (oldest.IsZero() || at.Before(oldest)) {
+				oldest = at
+			}
+		}
+	}
+	if !oldest.IsZero() {
+		age := time.Since(oldest)
+		s.OldestAge = age.Round(time.Second).String()
+		s.OldestAgeSeconds = age.Seconds()
+	}
+	var err error
+	if s.Delayed, err = q.cache.ZCard(ctx, cache.JobDelayedKey(queue)); err != nil {
+		return Stats{}, fmt.Errorf("failed to read %s delayed jobs: %w", queue, err)
+	}
+	if s.DeadLetters, err = q.cache.XLen(ctx, cache.JobDeadLetterKey(queue)); err != nil {
+		return Stats{}, fmt.Errorf("failed to read %s dead letters: %w", queue, err)
+	}
+	return s, nil
+}
+
+// rate returns the job attempts of queue every instance finished per
+// second over the last complete rateWindow minutes
+func (q *Queues) rate(ctx context.Context, queue string) (float64, error) {
+	minute := time.Now().Unix() / 60
+	keys := make([]string, rateWindow)
+	for i := range keys {
+		keys[i] = cache.JobThroughputKey(queue, minute-int64(i)-1)
+	}
+	counts, err := q.cache.MGet(ctx, keys...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s processing rate: %w", queue, err)
+	}
+	var total int64
+	for _, count := range counts {
+		if n, err := strconv.ParseInt(string(count), 10, 64); err == nil {
+			total += n
+		}
+	}
+	return float64(total) / (rateWindow * 60), nil
+}
+
+// DeadLetters returns up to limit jobs of queue that failed every attempt,
+// newest first
+func (q *Queues) DeadLetters(ctx context.Context, queue string, limit int) ([]Job, error) {
+	if err := q.known(queue); err != nil {
+		return nil, err
+	}
+	messages, err := q.cache.XRevRange(ctx, cache.JobDeadLetterKey(queue), "+", "-", int64(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s dead letters: %w", queue, err)
+	}
+	jobs := make([]Job, 0, len(messages))
+	for _, message := range messages {
+		if job, err := decode(message); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Requeue moves dead job id of queue back onto the queue at its priority,
+// to run right away with its attempts counted from 1 again
+func (q *Queues) Requeue(ctx context.Context, queue, id string) (Job, error) {
+	if err := q.known(queue); err != nil {
+		return Job{}, err
+	}
+	dead := cache.JobDeadLetterKey(queue)
+	// Dead-letter streams are capped, so finding the job by ID reads all of
+	// one at most, which the rarity of requeues affords
+	messages, err := q.cache.XRange(ctx, dead, "-", "+", q.opts.MaxLen)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to read %s dead letters: %w", queue, err)
+	}
+	for _, message := range messages {
+		job, err := decode(message)
+		if err != nil || job.ID != id {
+			continue
+		}
+		job.Attempt, job.Error, job.FailedAt = 1, "", nil
+		job.EnqueuedAt = time.Now().UTC()
+		if err := q.add(ctx, job); err != nil {
+			return Job{}, err
+		}
+		if err := q.cache.XDel(ctx, dead, message.ID); err != nil {
+			return Job{}, fmt.Errorf("failed to remove requeued %s job %s from dead letters: %w", queue, id, err)
+		}
+		return job, nil
+	}
+	return Job{}, errs.Errorf(errs.ErrNotFound, "dead %s job not found: %s", queue, id)
+}
+
+// known reports an error unless this instance works on queue
+func (q *Queues) known(queue string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.handlers[queue]; !ok {
+		return errs.Errorf(errs.ErrNotFound, "queue not found: %s", queue)
+	}
+	return nil
+}
+
+// entryTime is the time a stream entry was added, from its ID
+func entryTime(id string) (time.Time, bool) {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(n), true
+}
@@ -0,0 +1,602 @@
+// Package jobqueue runs background jobs from queues shared by every
+// instance. Each queue is a Redis stream read by one consumer group, so a
+// job is handled by a single instance. A job left unacknowledged past the
+// visibility timeout, because its handler hung or its instance died, is
+// claimed by another consumer. Failed jobs are retried after a growing
+// backoff up to a number of attempts and then moved to the queue's
+// dead-letter stream, where operators can inspect and requeue them.
+//
+// A queue has a stream for each priority, and workers take the jobs of a
+// higher priority first. Jobs that should run later wait in a sorted set
+// by the time they are due, and are moved onto their stream once due.
+package jobqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// group is the consumer group every instance reads the queues with
+const group = "workers"
+
+const (
+	// pollBlock is how long a worker waits for a new job before checking
+	// for jobs to reclaim again
+	pollBlock = 2 * time.Second
+	// errorBackoff is the pause after failing to read a queue
+	errorBackoff = time.Second
+	// promoteInterval is how often jobs that became due are moved onto
+	// their streams, and promoteBatch the most moved at a time
+	promoteInterval = time.Second
+	promoteBatch    = 100
+	// maxRetryBackoff caps the wait before retrying a failed job
+	maxRetryBackoff = time.Hour
+)
+
+// Statuses of finished job attempts, as counted in metrics.JobsProcessed
+const (
+	statusSucceeded    = "succeeded"
+	statusRetried      = "retried"
+	statusDeadLettered = "dead_lettered"
+)
+
+// Job priorities, from the first taken to the last
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// priorities lists the priorities in the order workers take jobs
+var priorities = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// Job is one unit of work on a queue
+type Job struct {
+	// ID identifies the job across its attempts and in the dead-letter stream
+	ID      string          `json:"id"`
+	Queue   string          `json:"queue"`
+	Payload json.RawMessage `json:"payload"`
+	// Priority is PriorityHigh, PriorityNormal or PriorityLow
+	Priority string `json:"priority"`
+	// Attempt is the number of the attempt being made, from 1
+	Attempt    int       `json:"attempt"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	// RunAt is when a delayed job, or the retry of a failed one, is due
+	RunAt *time.Time `json:"run_at,omitempty"`
+	// Error is why the previous attempt failed, and FailedAt when a dead
+	// job failed its last one
+	Error    string     `json:"error,omitempty"`
+	FailedAt *time.Time `json:"failed_at,omitempty"`
+}
+
+// Decode unmarshals the job's payload into v
+func (j Job) Decode(v interface{}) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid payload of %s job %s: %w", j.Queue, j.ID, err))
+	}
+	return nil
+}
+
+// Handler runs a job. Returning an error fails the attempt; the job is
+// retried unless the error is Permanent or it was the last attempt. ctx is
+// cancelled when the visibility timeout expires or the queues stop.
+type Handler func(ctx context.Context, job Job) error
+
+// PermanentError is a job failure that retrying won't fix
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent marks err as not worth retrying, sending the job straight to
+// the dead-letter stream
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// Options tunes Queues. Zero values use the defaults noted on each field.
+type Options struct {
+	// Consumer names this instance in the consumer groups, default the
+	// hostname and process ID
+	Consumer string
+	// VisibilityTimeout is how long a job may run before it is cancelled
+	// and another consumer may claim it, default 5m
+	VisibilityTimeout time.Duration
+	// MaxAttempts is how many times a job is tried before it is
+	// dead-lettered, default 5
+	MaxAttempts int
+	// RetryBackoff is the wait before retrying a failed job, doubling
+	// after each further failure up to an hour, default 10s
+	RetryBackoff time.Duration
+	// MaxLen caps each queue and dead-letter stream at about this many
+	// jobs, dropping the oldest, default 100000
+	MaxLen int64
+}
+
+// registration is a queue this instance works on
+type registration struct {
+	handler     Handler
+	concurrency int
+}
+
+// Queues enqueues jobs and runs the handlers registered for them
+type Queues struct {
+	cache *cache.RedisCache
+	opts  Options
+
+	mu       sync.Mutex
+	handlers map[string]registration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates job queues stored in redisCache
+func New(redisCache *cache.RedisCache, opts Options) *Queues {
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 5 * time.Minute
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 5
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 10 * time.Second
+	}
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = 100000
+	}
+	return &Queues{
+		cache:    redisCache,
+		opts:     opts,
+		handlers: make(map[string]registration),
+	}
+}
+
+// Register has this instance run handler on the jobs of queue, with
+// concurrency jobs at a time. Queues are registered before Start.
+func (q *Queues) Register(queue string, concurrency int, handler Handler) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[queue] = registration{handler: handler, concurrency: concurrency}
+}
+
+// EnqueueOptions sets when and before what else a job runs
+type EnqueueOptions struct {
+	// Priority is PriorityHigh, PriorityNormal or PriorityLow, default
+	// PriorityNormal
+	Priority string
+	// Delay holds the job back for a while, or At until a time; the job
+	// runs right away when neither is set or At has passed
+	Delay time.Duration
+	At    time.Time
+}
+
+// Enqueue adds a job with payload, marshalled as JSON, to queue. Any
+// instance that registered the queue may run it.
+func (q *Queues) Enqueue(ctx context.Context, queue string, payload interface{}, opts EnqueueOptions) (Job, error) {
+	if queue == "" || strings.ContainsAny(queue, ": ") {
+		return Job{}, errs.Errorf(errs.ErrInvalid, "invalid queue name %q", queue)
+	}
+	if opts.Priority == "" {
+		opts.Priority = PriorityNormal
+	}
+	if !validPriority(opts.Priority) {
+		return Job{}, errs.Errorf(errs.ErrInvalid, "invalid priority %q: expected high, normal or low", opts.Priority)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to marshal %s job: %w", queue, err)
+	}
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	now := time.Now().UTC()
+	job := Job{ID: id, Queue: queue, Payload: data, Priority: opts.Priority, Attempt: 1, EnqueuedAt: now}
+	at := opts.At
+	if opts.Delay > 0 {
+		at = now.Add(opts.Delay)
+	}
+	if at.After(now) {
+		at = at.UTC()
+		job.RunAt = &at
+		if err := q.schedule(ctx, job); err != nil {
+			return Job{}, err
+		}
+		metrics.JobsEnqueued.WithLabelValues(queue, job.Priority).Inc()
+		return job, nil
+	}
+	if err := q.add(ctx, job); err != nil {
+		return Job{}, err
+	}
+	metrics.JobsEnqueued.WithLabelValues(queue, job.Priority).Inc()
+	return job, nil
+}
+
+// add appends job to the stream of its queue and priority
+func (q *Queues) add(ctx context.Context, job Job) error {
+	values, err := encode(job)
+	if err != nil {
+		return err
+	}
+	if _, err := q.cache.XAdd(ctx, cache.JobQueueKey(job.Queue, job.Priority), values, q.opts.MaxLen); err != nil {
+		return fmt.Errorf("failed to enqueue %s job: %w", job.Queue, err)
+	}
+	return nil
+}
+
+// schedule holds job back until job.RunAt
+func (q *Queues) schedule(ctx context.Context, job Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	err = q.cache.ZAdd(ctx, cache.JobDelayedKey(job.Queue), redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: string(data)})
+	if err != nil {
+		return fmt.Errorf("failed to schedule %s job: %w", job.Queue, err)
+	}
+	return nil
+}
+
+// Start runs the registered handlers until Stop is called or ctx is cancelled
+func (q *Queues) Start(ctx context.Context) {
+	ctx, q.cancel = context.WithCancel(ctx)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for queue, reg := range q.handlers {
+		q.wg.Add(1)
+		go func(queue string) {
+			defer q.wg.Done()
+			q.promote(ctx, queue)
+		}(queue)
+		for i := 0; i < reg.concurrency; i++ {
+			q.wg.Add(1)
+			go func(queue string, handler Handler) {
+				defer q.wg.Done()
+				q.work(ctx, queue, handler)
+			}(queue, reg.handler)
+		}
+	}
+	log.Info().Int("queues", len(q.handlers)).Str("consumer", q.opts.Consumer).Msg("Job queues started")
+}
+
+// Stop stops the workers and waits for the jobs in progress to finish.
+// A job cut short is claimed again after the visibility timeout.
+func (q *Queues) Stop() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+	log.Info().Msg("Job queues stopped")
+}
+
+// Queues returns the names of the queues this instance works on
+func (q *Queues) Queues() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	names := make([]string, 0, len(q.handlers))
+	for name := range q.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// work runs jobs of queue one at a time until ctx is cancelled
+func (q *Queues) work(ctx context.Context, queue string, handler Handler) {
+	streams := make([]string, len(priorities))
+	for i, priority := range priorities {
+		streams[i] = cache.JobQueueKey(queue, priority)
+	}
+	needGroups := true
+	for ctx.Err() == nil {
+		if needGroups {
+			if err := q.createGroups(ctx, streams); err != nil {
+				q.pause(ctx, err, queue, "Failed to create job queue consumer group")
+				continue
+			}
+			needGroups = false
+		}
+
+		messages, err := q.next(ctx, streams)
+		if err != nil {
+			// The streams and their group are gone if the keys were deleted
+			if strings.Contains(err.Error(), "NOGROUP") {
+				needGroups = true
+			}
+			q.pause(ctx, err, queue, "Failed to read job queue")
+			continue
+		}
+		// Several priorities may arrive in one wait; the highest goes first
+		for _, stream := range streams {
+			for _, message := range messages[stream] {
+				q.process(ctx, queue, stream, handler, message)
+			}
+		}
+	}
+}
+
+// createGroups creates the consumer group of each of a queue's streams
+func (q *Queues) createGroups(ctx context.Context, streams []string) error {
+	for _, stream := range streams {
+		if err := q.cache.XGroupCreate(ctx, stream, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next returns a job whose visibility timeout expired, or else a new one,
+// taking streams in order of priority. With neither, it waits up to
+// pollBlock for a new job on any of them.
+func (q *Queues) next(ctx context.Context, streams []string) (map[string][]redis.XMessage, error) {
+	for _, stream := range streams {
+		claimed, err := q.cache.XAutoClaim(ctx, stream, group, q.opts.Consumer, q.opts.VisibilityTimeout, 1)
+		if err != nil || len(claimed) > 0 {
+			return map[string][]redis.XMessage{stream: claimed}, err
+		}
+	}
+	for _, stream := range streams[:len(streams)-1] {
+		messages, err := q.cache.XReadGroup(ctx, group, q.opts.Consumer, 1, -1, stream)
+		if err != nil || len(messages) > 0 {
+			return messages, err
+		}
+	}
+	// The lowest priority is only read in the wait, along with the others
+	return q.cache.XReadGroup(ctx, group, q.opts.Consumer, 1, pollBlock, streams...)
+}
+
+// promote moves the delayed jobs of queue onto their streams as they fall
+// due, until ctx is cancelled
+func (q *Queues) promote(ctx context.Context, queue string) {
+	ticker := time.NewTicker(promoteInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.promoteDue(ctx, queue); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Str("queue", queue).Msg("Failed to promote delayed jobs")
+			}
+		}
+	}
+}
+
+// promoteDue moves the delayed jobs of queue that are due onto their
+// streams. Every instance promotes, and only the one that removes a job
+// from the delayed set adds it.
+func (q *Queues) promoteDue(ctx context.Context, queue string) error {
+	delayed := cache.JobDelayedKey(queue)
+	due, err := q.cache.ZRangeByScore(ctx, delayed, 0, float64(time.Now().UnixMilli()), promoteBatch)
+	if err != nil {
+		return err
+	}
+	for _, member := range due {
+		var job Job
+		if err := json.Unmarshal([]byte(member), &job); err != nil {
+			log.Error().Err(err).Str("queue", queue).Msg("Dropping undecodable delayed job")
+			_ = q.cache.ZRem(ctx, delayed, member)
+			continue
+		}
+		removed, err := q.cache.ZRemCount(ctx, delayed, member)
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := q.add(ctx, job); err != nil {
+			// Put it back to be promoted on the next tick
+			if zerr := q.schedule(ctx, job); zerr != nil {
+				log.Error().Err(zerr).Str("queue", queue).Str("job_id", job.ID).Msg("Lost delayed job")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// process runs one job read from queue and acknowledges it, retrying or
+// dead-lettering it when it fails
+func (q *Queues) process(ctx context.Context, queue, stream string, handler Handler, message redis.XMessage) {
+	logger := log.With().Str("queue", queue).Str("entry", message.ID).Logger()
+
+	job, err := decode(message)
+	if err != nil {
+		// Nothing can run it; keep it for inspection
+		logger.Error().Err(err).Msg("Dead-lettering undecodable job")
+		q.deadLetter(ctx, stream, message.ID, Job{Queue: queue, Error: err.Error()})
+		q.finished(ctx, queue, statusDeadLettered, 0)
+		return
+	}
+	logger = logger.With().Str("job_id", job.ID).Int("attempt", job.Attempt).Logger()
+
+	// An entry delivered before was claimed from a consumer that never
+	// finished it, and that attempt counts too
+	if deliveries, err := q.cache.XDeliveries(ctx, stream, group, message.ID); err == nil && deliveries > 1 {
+		job.Attempt += int(deliveries) - 1
+		job.Error = "visibility timeout expired"
+		if job.Attempt > q.opts.MaxAttempts {
+			logger.Warn().Msg("Dead-lettering job whose visibility timeout kept expiring")
+			q.deadLetter(ctx, stream, message.ID, job)
+			q.finished(ctx, queue, statusDeadLettered, 0)
+			return
+		}
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, q.opts.VisibilityTimeout)
+	started := time.Now()
+	err = q.run(jobCtx, handler, job)
+	elapsed := time.Since(started)
+	cancel()
+	if ctx.Err() != nil {
+		// Stopping: leave the job pending for another consumer to claim
+		return
+	}
+	if err == nil {
+		if err := q.cache.XAckDel(ctx, stream, group, message.ID); err != nil {
+			logger.Warn().Err(err).Msg("Failed to acknowledge job")
+		}
+		q.finished(ctx, queue, statusSucceeded, elapsed)
+		return
+	}
+
+	job.Error = err.Error()
+	var permanent *PermanentError
+	if errors.As(err, &permanent) || job.Attempt >= q.opts.MaxAttempts {
+		logger.Warn().Err(err).Msg("Job failed, dead-lettering")
+		q.deadLetter(ctx, stream, message.ID, job)
+		q.finished(ctx, queue, statusDeadLettered, elapsed)
+		return
+	}
+	backoff := q.retryBackoff(job.Attempt)
+	logger.Warn().Err(err).Dur("backoff", backoff).Msg("Job failed, retrying")
+	job.Attempt++
+	runAt := time.Now().Add(backoff).UTC()
+	job.RunAt = &runAt
+	data, err := json.Marshal(job)
+	if err == nil {
+		err = q.cache.XDefer(ctx, stream, group, message.ID, cache.JobDelayedKey(queue), string(data), float64(runAt.UnixMilli()))
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to requeue job")
+	}
+	q.finished(ctx, queue, statusRetried, elapsed)
+}
+
+// finished counts a job attempt of queue that ended with status after
+// running for elapsed, 0 when it never ran, in the metrics of this instance
+// and in the processing rate shared by every instance
+func (q *Queues) finished(ctx context.Context, queue, status string, elapsed time.Duration) {
+	metrics.JobsProcessed.WithLabelValues(queue, status).Inc()
+	if elapsed > 0 {
+		metrics.JobDuration.WithLabelValues(queue).Observe(elapsed.Seconds())
+	}
+	key := cache.JobThroughputKey(queue, time.Now().Unix()/60)
+	err := q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, 2*rateWindow*time.Minute)
+		return nil
+	})
+	if err != nil {
+		log.Debug().Err(err).Str("queue", queue).Msg("Failed to count finished job")
+	}
+}
+
+// retryBackoff is the wait before retrying a job that failed attempt
+func (q *Queues) retryBackoff(attempt int) time.Duration {
+	backoff := q.opts.RetryBackoff
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// run calls handler, turning a panic into a failed attempt
+func (q *Queues) run(ctx context.Context, handler Handler, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// deadLetter moves entry of stream, as job, to the dead-letter stream of job.Queue
+func (q *Queues) deadLetter(ctx context.Context, stream, entry string, job Job) {
+	now := time.Now().UTC()
+	job.FailedAt, job.RunAt = &now, nil
+	values, err := encode(job)
+	if err == nil {
+		err = q.cache.XMove(ctx, stream, group, entry, cache.JobDeadLetterKey(job.Queue), values, q.opts.MaxLen)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("queue", job.Queue).Str("job_id", job.ID).Msg("Failed to dead-letter job")
+	}
+}
+
+// pause logs a failure to read queue and waits errorBackoff, or until ctx is done
+func (q *Queues) pause(ctx context.Context, err error, queue, msg string) {
+	if ctx.Err() != nil {
+		return
+	}
+	log.Warn().Err(err).Str("queue", queue).Msg(msg)
+	select {
+	case <-time.After(errorBackoff):
+	case <-ctx.Done():
+	}
+}
+
+// encode returns the stream entry fields of job
+func encode(job Job) (map[string]interface{}, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return map[string]interface{}{"job": data}, nil
+}
+
+// decode returns the job of a stream entry
+func decode(message redis.XMessage) (Job, error) {
+	data, ok := message.Values["job"].(string)
+	if !ok {
+		return Job{}, fmt.Errorf("entry %s has no job", message.ID)
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return Job{}, fmt.Errorf("invalid job in entry %s: %w", message.ID, err)
+	}
+	if job.Priority == "" {
+		// Enqueued before jobs had priorities
+		job.Priority = PriorityNormal
+	}
+	return job, nil
+}
+
+// validPriority reports whether priority is one jobs can have
+func validPriority(priority string) bool {
+	for _, p := range priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a random job ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// QueryAuditEntries counts sampled query audit entries by result: written,
+// failed, or dropped because the write queue was full
+var QueryAuditEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_query_audit_entries_total",
+	Help: "Sampled query audit entries by result.",
+}, []string{"result"})
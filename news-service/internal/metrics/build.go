@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// BuildInfo is always 1; its labels identify the running build so dashboards
+// can break other series down by version
+var BuildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "news_build_info",
+	Help: "Build information of the running binary; the value is always 1.",
+}, []string{"version", "commit", "build_date", "go_version"})
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// BusEventsPublished counts domain events published on the internal bus, by type
+var BusEventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_bus_events_published_total",
+	Help: "Domain events published on the internal event bus.",
+}, []string{"type"})
+
+// BusEventsDropped counts events dropped because a subscriber's queue was full
+var BusEventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_bus_events_dropped_total",
+	Help: "Domain events dropped for a subscriber that fell behind.",
+}, []string{"subscriber", "type"})
+
+// WebhookDeliveries counts webhook deliveries by endpoint and outcome
+// (delivered, failed after all attempts, or dropped with the circuit open)
+var WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_webhook_deliveries_total",
+	Help: "Webhook deliveries by endpoint and outcome.",
+}, []string{"endpoint", "outcome"})
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// EventRollupHours counts hours of user events rolled up into hourly aggregates
+var EventRollupHours = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "news_event_rollup_hours_total",
+	Help: "Hours of user events rolled up into hourly aggregates.",
+})
+
+// EventRollupWatermark is the end of the last hour rolled up, as a Unix timestamp
+var EventRollupWatermark = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "news_event_rollup_watermark_seconds",
+	Help: "End of the last hour of user events rolled up, as a Unix timestamp.",
+})
+
+// EventsPruned counts rows deleted past their retention, by kind: raw events
+// or hourly rollups
+var EventsPruned = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_events_pruned_total",
+	Help: "User event rows deleted past their retention, by kind.",
+}, []string{"kind"})
+
+// EventsIngested counts user events reported by clients, by event type and
+// status: stored, rejected, or ignored as a duplicate or from a bot
+var EventsIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_events_ingested_total",
+	Help: "User events reported by clients, by event type and status.",
+}, []string{"event", "status"})
+
+// EventQueueMessages counts the messages consumed from the user event queue,
+// by source (kafka or nats) and status: stored, rejected, ignored or failed
+var EventQueueMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_event_queue_messages_total",
+	Help: "Messages consumed from the user event queue, by source and status.",
+}, []string{"source", "status"})
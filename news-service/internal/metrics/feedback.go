@@ -0,0 +1,12 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Feedback counts reader feedback by target (summary or result) and rating
+var Feedback = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_feedback_total",
+	Help: "Reader feedback by target and rating.",
+}, []string{"target", "rating"})
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// NearbyExpansions counts nearby queries that found nothing and were widened
+// to a surrounding area, by outcome: the level of the area whose articles were
+// returned (city, county or state), empty when no area had any, or error
+var NearbyExpansions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_nearby_expansions_total",
+	Help: "Empty nearby queries widened to a surrounding area by outcome.",
+}, []string{"outcome"})
+
+// RadiusExpansions counts nearby queries that found fewer than their
+// min_results and were searched again over wider radii, by outcome: satisfied
+// when a radius found enough, short when the maximum radius still didn't, or
+// error
+var RadiusExpansions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_nearby_radius_expansions_total",
+	Help: "Thin nearby queries searched again over wider radii by outcome.",
+}, []string{"outcome"})
+
+// PlaceResolutions counts place names in queries looked up to tell which
+// place they refer to, by outcome: resolved when one place was clearly the
+// most important, ambiguous when several were returned as candidates,
+// unknown when none was found, or error
+var PlaceResolutions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_place_resolutions_total",
+	Help: "Place names in queries looked up to tell which place they refer to, by outcome.",
+}, []string{"outcome"})
+
+// UnlocatedMixes counts thin nearby and trending results that articles without
+// coordinates were blended into, by outcome: the policy they were blended in
+// by (fill or interleave), empty when none was relevant enough, or error
+var UnlocatedMixes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_unlocated_mixes_total",
+	Help: "Thin nearby and trending results blended with articles without coordinates by outcome.",
+}, []string{"outcome"})
+
+// ArticlesGeocoded counts articles with coordinates whose place was looked up
+// at ingest, by result: geocoded, unknown when the location is in no country,
+// or failed
+var ArticlesGeocoded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_ingest_geocoded_total",
+	Help: "Articles with coordinates reverse geocoded at ingest, by result.",
+}, []string{"result"})
+
+// PlaceBackfill counts articles reverse geocoded by the background backfill,
+// by result: geocoded, unknown when the location is in no country, or error
+var PlaceBackfill = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_place_backfill_total",
+	Help: "Articles reverse geocoded by the background place backfill, by result.",
+}, []string{"result"})
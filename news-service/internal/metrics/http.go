@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HTTPRequests counts the requests served, by route pattern, method and
+// status. Requests no route matched are labelled "unmatched".
+var HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_http_requests_total",
+	Help: "HTTP requests served, by route, method and status.",
+}, []string{"route", "method", "status"})
+
+// HTTPRequestDuration observes how long requests took to serve, by route
+// pattern and method
+var HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_http_request_duration_seconds",
+	Help:    "Duration of HTTP requests, by route and method.",
+	Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+}, []string{"route", "method"})
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// IngestedArticles counts articles processed by the ingestion loader, by
+// result: created, updated, skipped (unchanged or repeated) or failed
+var IngestedArticles = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_ingest_articles_total",
+	Help: "Articles processed by the ingestion loader, by result.",
+}, []string{"result"})
+
+// ArticlesCategorized counts articles that arrived without categories and
+// were classified by the LLM at ingest, by result: categorized or failed
+var ArticlesCategorized = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_ingest_categorized_total",
+	Help: "Articles without categories classified by the LLM at ingest, by result.",
+}, []string{"result"})
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+// JobsEnqueued counts jobs added to the job queues, by queue and priority
+var JobsEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_job_queue_enqueued_total",
+	Help: "Jobs added to the job queues, by queue and priority.",
+}, []string{"queue", "priority"})
+
+// JobsProcessed counts job attempts this instance finished, by queue and
+// status: succeeded, retried or dead_lettered
+var JobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_job_queue_processed_total",
+	Help: "Job attempts finished, by queue and status.",
+}, []string{"queue", "status"})
+
+// JobDuration observes how long job attempts ran, by queue
+var JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_job_queue_job_duration_seconds",
+	Help:    "Duration of job attempts, by queue.",
+	Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+}, []string{"queue"})
+
+// JobQueueDepth is the backlog of one job queue, shared by every instance
+type JobQueueDepth struct {
+	Queue string
+	// Waiting counts the jobs not yet delivered to a worker, by priority
+	Waiting map[string]int64
+	// Running counts the jobs delivered to a worker and not yet finished
+	Running     int64
+	Delayed     int64
+	DeadLetters int64
+	// OldestAge is how long the oldest waiting or running job has been queued
+	OldestAge time.Duration
+}
+
+// jobQueueCollector exports the backlog of the job queues, read from Redis
+// at scrape time
+type jobQueueCollector struct {
+	depths func(ctx context.Context) ([]JobQueueDepth, error)
+
+	waiting     *prometheus.Desc
+	running     *prometheus.Desc
+	delayed     *prometheus.Desc
+	deadLetters *prometheus.Desc
+	oldestAge   *prometheus.Desc
+}
+
+// jobQueueScrapeTimeout bounds reading the job queues' backlog for a scrape
+const jobQueueScrapeTimeout = 2 * time.Second
+
+// RegisterJobQueues exports the backlog of the job queues depths reports,
+// so that worker deployments can scale on it
+func RegisterJobQueues(depths func(ctx context.Context) ([]JobQueueDepth, error)) error {
+	desc := func(metric, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc("news_job_queue_"+metric, help, append([]string{"queue"}, labels...), nil)
+	}
+
+	return prometheus.Register(&jobQueueCollector{
+		depths:      depths,
+		waiting:     desc("waiting_jobs", "Jobs not yet delivered to a worker, by queue and priority.", "priority"),
+		running:     desc("running_jobs", "Jobs delivered to a worker and not yet finished, by queue."),
+		delayed:     desc("delayed_jobs", "Delayed jobs and retries not due yet, by queue."),
+		deadLetters: desc("dead_letters", "Jobs that failed every attempt, by queue."),
+		oldestAge:   desc("oldest_job_age_seconds", "Age of the oldest waiting or running job, by queue."),
+	})
+}
+
+func (c *jobQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.waiting
+	ch <- c.running
+	ch <- c.delayed
+	ch <- c.deadLetters
+	ch <- c.oldestAge
+}
+
+func (c *jobQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobQueueScrapeTimeout)
+	defer cancel()
+	depths, err := c.depths(ctx)
+	if err != nil {
+		// Leave the series out rather than report an empty backlog
+		log.Warn().Err(err).Msg("Failed to read job queue backlog for metrics")
+		return
+	}
+
+	for _, d := range depths {
+		for priority, waiting := range d.Waiting {
+			ch <- prometheus.MustNewConstMetric(c.waiting, prometheus.GaugeValue, float64(waiting), d.Queue, priority)
+		}
+		ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(d.Running), d.Queue)
+		ch <- prometheus.MustNewConstMetric(c.delayed, prometheus.GaugeValue, float64(d.Delayed), d.Queue)
+		ch <- prometheus.MustNewConstMetric(c.deadLetters, prometheus.GaugeValue, float64(d.DeadLetters), d.Queue)
+		ch <- prometheus.MustNewConstMetric(c.oldestAge, prometheus.GaugeValue, d.OldestAge.Seconds(), d.Queue)
+	}
+}
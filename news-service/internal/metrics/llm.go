@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// LLMFallbacks counts LLM calls answered by the local fallback because the
+// provider failed or returned an unusable response, by operation
+var LLMFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_llm_fallbacks_total",
+	Help: "LLM calls answered by the local fallback after a provider failure, by operation.",
+}, []string{"operation"})
+
+// LLMCacheRequests counts extraction cache lookups by result: hit, miss, or
+// error when Redis couldn't be read
+var LLMCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_llm_cache_requests_total",
+	Help: "LLM extraction cache lookups by result.",
+}, []string{"result"})
+
+// SummaryRequests counts article summaries served with query results by
+// result: stored when an earlier summary was reused, generated when the LLM
+// wrote a new one, fallback when the LLM failed and the article's own text
+// was served instead, or error
+var SummaryRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_summary_requests_total",
+	Help: "Article summaries served with query results by result.",
+}, []string{"result"})
+
+// SummaryBackfill counts articles handled by the summary backfill by result:
+// generated, stored when a query summarized the article first, or error
+var SummaryBackfill = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_summary_backfill_total",
+	Help: "Articles handled by the summary backfill by result.",
+}, []string{"result"})
+
+// EmbeddingBackfill counts articles handled by the embedding backfill by
+// result: embedded, or error when embedding or storing the batch failed
+var EmbeddingBackfill = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_embedding_backfill_total",
+	Help: "Articles handled by the embedding backfill by result.",
+}, []string{"result"})
+
+// SemanticSearches counts keyword searches that returned too few results on
+// their first page by outcome: used when the semantic strategy found more,
+// kept when it didn't, or error
+var SemanticSearches = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_semantic_searches_total",
+	Help: "Semantic fallbacks of sparse keyword searches by outcome.",
+}, []string{"outcome"})
+
+// LLMRetries counts LLM calls retried after a rate limit, server error or
+// network failure, by operation
+var LLMRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_llm_retries_total",
+	Help: "LLM calls retried after a transient failure, by operation.",
+}, []string{"operation"})
+
+// LLMCircuitState is the LLM circuit breaker's state: 0 closed, 1 open, 2 half-open
+var LLMCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "news_llm_circuit_state",
+	Help: "LLM circuit breaker state: 0 closed, 1 open, 2 half-open.",
+})
+
+// LLMCircuitRejections counts LLM calls failed fast because the circuit was open
+var LLMCircuitRejections = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "news_llm_circuit_rejections_total",
+	Help: "LLM calls failed fast while the circuit breaker was open.",
+})
+
+// LLMTokens counts the tokens providers reported using, by model, operation
+// (extract or summarize), endpoint that needed the call, and kind (prompt or
+// completion)
+var LLMTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_llm_tokens_total",
+	Help: "LLM tokens used by model, operation, endpoint and kind.",
+}, []string{"model", "operation", "endpoint", "kind"})
+
+// LLMCost is the estimated cost of the tokens in LLMTokens in USD, at the
+// configured prices
+var LLMCost = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_llm_cost_usd_total",
+	Help: "Estimated LLM cost in USD by model, operation and endpoint.",
+}, []string{"model", "operation", "endpoint"})
+
+// LLMCallDuration observes how long provider calls took, by model,
+// operation and result: ok or error. Each retried attempt is observed.
+var LLMCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_llm_call_duration_seconds",
+	Help:    "Duration of LLM provider calls, by model, operation and result.",
+	Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+}, []string{"model", "operation", "result"})
+
+// EntityBackfill counts articles handled by the entity backfill by result:
+// extracted, or error when extracting or storing their entities failed
+var EntityBackfill = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_entity_backfill_total",
+	Help: "Articles handled by the entity backfill by result.",
+}, []string{"result"})
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ArticleReports counts readers' reports of articles by reason
+var ArticleReports = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_article_reports_total",
+	Help: "Reader reports of articles by reason.",
+}, []string{"reason"})
+
+// ArticlesFlagged counts articles flagged for moderation by source: reader
+// (reports reached the threshold), spam or safety
+var ArticlesFlagged = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_articles_flagged_total",
+	Help: "Articles flagged for moderation by source.",
+}, []string{"source"})
+
+// ArticlesDemoted counts demoted articles by source: auto (reports reached
+// the threshold) or admin
+var ArticlesDemoted = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_articles_demoted_total",
+	Help: "Articles demoted in results by source.",
+}, []string{"source"})
+
+// ModerationReviews counts editors' reviews of flagged articles by action:
+// approve, demote or reject
+var ModerationReviews = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_moderation_reviews_total",
+	Help: "Reviews of flagged articles by action.",
+}, []string{"action"})
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"github.com/go-redis/redis/v9"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// postgresPoolCollector exports pgxpool statistics, read at scrape time
+type postgresPoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	maxConns        *prometheus.Desc
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	utilization     *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// RegisterPostgresPool exports the statistics of a Postgres pool under the given pool label
+func RegisterPostgresPool(name string, stat func() *pgxpool.Stat) error {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("news_postgres_pool_"+metric, help, nil, labels)
+	}
+
+	return prometheus.Register(&postgresPoolCollector{
+		stat:            stat,
+		maxConns:        desc("max_conns", "Maximum size of the pool."),
+		acquiredConns:   desc("acquired_conns", "Connections currently checked out of the pool."),
+		idleConns:       desc("idle_conns", "Idle connections in the pool."),
+		totalConns:      desc("total_conns", "Total connections in the pool."),
+		utilization:     desc("utilization_ratio", "Acquired connections as a fraction of the maximum pool size."),
+		acquireCount:    desc("acquires_total", "Successful connection acquires."),
+		acquireDuration: desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquire:    desc("empty_acquires_total", "Acquires that had to wait because the pool had no idle connection."),
+		canceledAcquire: desc("canceled_acquires_total", "Acquires canceled by their context, including pool exhaustion timeouts."),
+	})
+}
+
+func (c *postgresPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.utilization
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquire
+	ch <- c.canceledAcquire
+}
+
+func (c *postgresPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+
+	var utilization float64
+	if s.MaxConns() > 0 {
+		utilization = float64(s.AcquiredConns()) / float64(s.MaxConns())
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.utilization, prometheus.GaugeValue, utilization)
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
+
+// redisPoolCollector exports go-redis pool statistics, read at scrape time
+type redisPoolCollector struct {
+	stats func() *redis.PoolStats
+	size  int
+
+	poolSize    *prometheus.Desc
+	totalConns  *prometheus.Desc
+	idleConns   *prometheus.Desc
+	utilization *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	timeouts    *prometheus.Desc
+	staleConns  *prometheus.Desc
+}
+
+// RegisterRedisPool exports the statistics of a Redis pool of the given size under the given pool label
+func RegisterRedisPool(name string, size int, stats func() *redis.PoolStats) error {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("news_redis_pool_"+metric, help, nil, labels)
+	}
+
+	return prometheus.Register(&redisPoolCollector{
+		stats:       stats,
+		size:        size,
+		poolSize:    desc("max_conns", "Maximum size of the pool."),
+		totalConns:  desc("total_conns", "Total connections in the pool."),
+		idleConns:   desc("idle_conns", "Idle connections in the pool."),
+		utilization: desc("utilization_ratio", "Busy connections as a fraction of the maximum pool size."),
+		hits:        desc("hits_total", "Times an idle connection was found in the pool."),
+		misses:      desc("misses_total", "Times no idle connection was found in the pool."),
+		timeouts:    desc("timeouts_total", "Times a command gave up waiting for a free connection."),
+		staleConns:  desc("stale_conns_total", "Stale connections removed from the pool."),
+	})
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.poolSize
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.utilization
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.staleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+
+	var utilization float64
+	if c.size > 0 && s.TotalConns >= s.IdleConns {
+		utilization = float64(s.TotalConns-s.IdleConns) / float64(c.size)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.poolSize, prometheus.GaugeValue, float64(c.size))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.utilization, prometheus.GaugeValue, utilization)
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(s.StaleConns))
+}
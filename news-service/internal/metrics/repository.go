@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RepositoryDuration observes how long repository calls took, by method and
+// result: ok or error
+var RepositoryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_repository_duration_seconds",
+	Help:    "Duration of repository calls, by operation and result.",
+	Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+}, []string{"operation", "result"})
+
+// CacheRequests counts versioned cache lookups, by schema and result: hit,
+// miss when the entry is absent or couldn't be decoded, or error when Redis
+// couldn't be read
+var CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_cache_requests_total",
+	Help: "Versioned cache lookups, by schema and result.",
+}, []string{"schema", "result"})
+
+// ArchiveReads counts query pages that read archived articles, published
+// before the hot window, by reason: past_hot when the page ran past the last
+// article of the hot window, or window when its publication window lay wholly
+// before the hot window
+var ArchiveReads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_archive_reads_total",
+	Help: "Query pages that read archived articles by reason.",
+}, []string{"reason"})
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ShadowRuns counts shadow strategy runs by shadow and result: recorded,
+// failed (the shadow run errored), unrecorded (Redis rejected the result) or
+// dropped (too many shadow runs were in flight)
+var ShadowRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_shadow_runs_total",
+	Help: "Shadow strategy runs by shadow and result.",
+}, []string{"shadow", "result"})
+
+// ShadowOverlap observes the share of articles a shadow run had in common
+// with the production run
+var ShadowOverlap = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_shadow_overlap_ratio",
+	Help:    "Share of articles shadow runs had in common with production.",
+	Buckets: prometheus.LinearBuckets(0, 0.1, 11),
+}, []string{"shadow"})
+
+// ShadowDuration observes planning, retrieval and ranking time of the
+// production and shadow side of each shadow comparison
+var ShadowDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_shadow_duration_seconds",
+	Help:    "Planning, retrieval and ranking time of shadow comparisons by side.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"shadow", "side"})
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SubscriptionDeliveries counts messages sent to subscriptions by channel,
+// kind (verification, digest or alert) and status (delivered or failed)
+var SubscriptionDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_subscription_deliveries_total",
+	Help: "Messages sent to subscriptions by channel, kind and status.",
+}, []string{"channel", "kind", "status"})
+
+// SubscriptionDeliveryLatency observes the duration of each attempt to send
+// a message to a subscription, by channel
+var SubscriptionDeliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_subscription_delivery_duration_seconds",
+	Help:    "Duration of attempts to send a message to a subscription, by channel.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"channel"})
+
+// SubscriptionDeliveryRetries counts attempts to send a message to a
+// subscription after its first attempt failed, by channel
+var SubscriptionDeliveryRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_subscription_delivery_retries_total",
+	Help: "Retried attempts to send a message to a subscription, by channel.",
+}, []string{"channel"})
+
+// SubscriptionsDisabled counts subscriptions disabled after failing
+// persistently, by channel
+var SubscriptionsDisabled = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_subscriptions_disabled_total",
+	Help: "Subscriptions disabled after failing persistently, by channel.",
+}, []string{"channel"})
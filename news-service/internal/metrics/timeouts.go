@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DeadlineExceeded counts external calls cut off by their per-operation timeout.
+// component is "repository" or "redis"; operation is the repository method or Redis command.
+var DeadlineExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_deadline_exceeded_total",
+	Help: "External calls that hit their per-operation timeout.",
+}, []string{"component", "operation"})
+
+// HedgedReads counts Redis reads that were still outstanding after the hedge
+// delay, by the copy that answered first: "primary" or "hedge"
+var HedgedReads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_redis_hedged_reads_total",
+	Help: "Redis reads hedged to a replica, by which request answered first.",
+}, []string{"operation", "winner"})
+
+// CachePayloads counts versioned cache entries read, by schema and result:
+// "current", "migrated" from an older version, "newer" from a later deploy,
+// or "discarded" because it couldn't be decoded
+var CachePayloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_cache_payloads_total",
+	Help: "Versioned cache entries read, by schema and result.",
+}, []string{"schema", "result"})
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// TrendingComputeDuration observes how long trending computations took, by
+// kind: full for a recompute of every window, or incremental for applying
+// the events stored since the last run
+var TrendingComputeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "news_trending_compute_duration_seconds",
+	Help:    "Duration of trending computations, by kind.",
+	Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+}, []string{"kind"})
+
+// TrendingEvents counts the events trending computations scored, by kind
+var TrendingEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_trending_events_total",
+	Help: "Events scored by trending computations, by kind.",
+}, []string{"kind"})
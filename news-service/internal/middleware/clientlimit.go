@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"news-system/internal/plans"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ClientLimit rate limits one group of routes per client, on top of the
+// plan's limit, for actions such as reporting that no plan should be able to
+// make often. Clients are identified by API key, or by IP when they have none.
+type ClientLimit struct {
+	name              string
+	requestsPerMinute int
+	burst             int
+	limiters          *limiterSet
+}
+
+// NewClientLimit creates a limit of requestsPerMinute, with bursts of up to
+// burst requests; name describes the limited action in errors
+func NewClientLimit(name string, requestsPerMinute, burst int) *ClientLimit {
+	return &ClientLimit{
+		name:              name,
+		requestsPerMinute: requestsPerMinute,
+		burst:             burst,
+		limiters:          newLimiterSet(),
+	}
+}
+
+// ClientID identifies the caller of r as the client limits and deduplication
+// see it: by the API key Authenticate resolved, or else by IP
+func ClientID(r *http.Request) string {
+	if key := plans.FromContext(r.Context()).Key; key != "" {
+		return "key:" + key
+	}
+	return "ip:" + getClientIP(r)
+}
+
+// Limit rejects requests beyond the client's limit; it must run after
+// PlanEnforcer.Authenticate
+func (c *ClientLimit) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := c.limiters.allow(ClientID(r), c.requestsPerMinute, c.burst); !ok {
+			log.Warn().
+				Str("limit", c.name).
+				Str("client_ip", getClientIP(r)).
+				Str("url", r.URL.String()).
+				Msg("Client rate limit exceeded")
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writePlanError(w, http.StatusTooManyRequests, "RATE_LIMIT",
+				fmt.Sprintf("rate limit of %d %s per minute exceeded", c.requestsPerMinute, c.name))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/plans"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// limiterIdleTTL is how long a caller's limiter is kept after its last request
+const limiterIdleTTL = 10 * time.Minute
+
+// PlanEnforcer identifies callers by API key and enforces their plan's
+// entitlements. Rate limits are kept in memory, so each instance enforces its
+// own share of a caller's budget.
+type PlanEnforcer struct {
+	keys       plans.Keys
+	requireKey bool
+	limiters   *limiterSet
+}
+
+// NewPlanEnforcer creates an enforcer for keys. Unless requireKey is set,
+// requests without a key are served under the free plan and rate limited by IP.
+func NewPlanEnforcer(keys plans.Keys, requireKey bool) *PlanEnforcer {
+	return &PlanEnforcer{
+		keys:       keys,
+		requireKey: requireKey,
+		limiters:   newLimiterSet(),
+	}
+}
+
+// Authenticate resolves the caller's plan, applies its rate limit and stores
+// the caller in the request context for handlers and later middleware
+func (p *PlanEnforcer) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := apiKey(r)
+		caller := plans.Caller{Plan: plans.Free}
+		limiterKey := "ip:" + getClientIP(r)
+		switch {
+		case key != "":
+			plan, ok := p.keys[key]
+			if !ok {
+				writePlanError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid API key")
+				return
+			}
+			caller = plans.Caller{Key: key, Plan: plan}
+			limiterKey = "key:" + key
+		case p.requireKey:
+			writePlanError(w, http.StatusUnauthorized, "UNAUTHORIZED", "an API key is required")
+			return
+		}
+
+		if ok, retryAfter := p.limiters.allow(limiterKey, caller.Plan.RequestsPerMinute, caller.Plan.Burst); !ok {
+			log.Warn().
+				Str("plan", caller.Plan.Name).
+				Str("client_ip", getClientIP(r)).
+				Str("url", r.URL.String()).
+				Msg("Plan rate limit exceeded")
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writePlanError(w, http.StatusTooManyRequests, "RATE_LIMIT",
+				fmt.Sprintf("rate limit of %d requests per minute exceeded for the %s plan", caller.Plan.RequestsPerMinute, caller.Plan.Name))
+			return
+		}
+
+		w.Header().Set("X-API-Plan", caller.Plan.Name)
+		next.ServeHTTP(w, r.WithContext(plans.WithCaller(r.Context(), caller)))
+	})
+}
+
+// RequireStreaming rejects callers whose plan doesn't include streaming
+func RequireStreaming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if plan := plans.FromContext(r.Context()).Plan; !plan.Streaming {
+			writePlanError(w, http.StatusForbidden, "FORBIDDEN",
+				fmt.Sprintf("streaming is not included in the %s plan", plan.Name))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// EnforceMaxLimit rejects a limit query parameter above the caller's plan maximum
+func EnforceMaxLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := plans.FromContext(r.Context()).Plan
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if limit, err := strconv.Atoi(limitStr); err == nil && limit > plan.MaxLimit {
+				writePlanError(w, http.StatusForbidden, "FORBIDDEN",
+					fmt.Sprintf("limit %d exceeds the %s plan maximum of %d", limit, plan.Name, plan.MaxLimit))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limiterSet holds a token bucket per caller, dropping those of callers that went quiet
+type limiterSet struct {
+	mu        sync.Mutex
+	limiters  map[string]*callerLimiter
+	lastSweep time.Time
+}
+
+type callerLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newLimiterSet() *limiterSet {
+	return &limiterSet{
+		limiters:  make(map[string]*callerLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token from the caller's limiter, created with
+// requestsPerMinute and burst on first use, returning how long to wait when
+// none is left
+func (s *limiterSet) allow(limiterKey string, requestsPerMinute, burst int) (bool, time.Duration) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop limiters of callers that went quiet so anonymous IPs don't accumulate
+	if now.Sub(s.lastSweep) > limiterIdleTTL {
+		for k, l := range s.limiters {
+			if now.Sub(l.lastSeen) > limiterIdleTTL {
+				delete(s.limiters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	l, ok := s.limiters[limiterKey]
+	if !ok {
+		l = &callerLimiter{limiter: rate.NewLimiter(rate.Limit(float64(requestsPerMinute)/60), burst)}
+		s.limiters[limiterKey] = l
+	}
+	l.lastSeen = now
+
+	reservation := l.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// apiKey reads the caller's key from X-API-Key or a bearer Authorization header
+func apiKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// writePlanError writes a JSON error in the API's error format
+func writePlanError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	errorResponse := map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	}
+	json.NewEncoder(w).Encode(errorResponse)
+}
@@ -0,0 +1,219 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// advisoryLockID serializes concurrent migration runs across instances
+const advisoryLockID = 728364519
+
+// Migration is a single versioned SQL file, or a Go function for data changes
+// SQL can't express
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	Func    Func
+}
+
+// Func is a migration written in Go. It runs in the migration's transaction.
+type Func func(ctx context.Context, tx pgx.Tx) error
+
+// registered holds the Go migrations added with Register
+var registered []Migration
+
+// Register adds a Go migration, applied in version order with the SQL files.
+// It is meant to be called from init in the package that embeds them.
+func Register(version int, name string, fn Func) {
+	registered = append(registered, Migration{Version: version, Name: name, Func: fn})
+}
+
+// Load reads NNNN_name.sql migrations from a filesystem, together with the
+// registered Go migrations, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s must be named NNNN_description.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", entry.Name(), err)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		sql, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    entry.Name(),
+			SQL:     string(sql),
+		})
+	}
+
+	for _, m := range registered {
+		if other, dup := seen[m.Version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, m.Name, m.Version)
+		}
+		seen[m.Version] = m.Name
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every migration newer than the schema's current version. Each
+// migration runs in its own transaction and is recorded in schema_migrations.
+func Up(ctx context.Context, databaseURL string, fsys fs.FS) (int, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return 0, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	if _, err := conn.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version    INTEGER PRIMARY KEY,
+  name       TEXT NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+
+		if err := apply(ctx, conn, m); err != nil {
+			return count, err
+		}
+		count++
+		log.Info().Int("version", m.Version).Str("name", m.Name).Msg("Applied migration")
+	}
+
+	return count, nil
+}
+
+// Pending returns the migrations not yet applied to the database, without
+// changing anything. A database that was never migrated has every migration
+// pending.
+func Pending(ctx context.Context, databaseURL string, fsys fs.FS) ([]Migration, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	if !exists {
+		return migrations, nil
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// appliedVersions reads the versions recorded in schema_migrations
+func appliedVersions(ctx context.Context, conn *pgx.Conn) (map[int]bool, error) {
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a migration and records it atomically
+func apply(ctx context.Context, conn *pgx.Conn, m Migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", m.Name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if m.Func != nil {
+		err = m.Func(ctx, tx)
+	} else {
+		_, err = tx.Exec(ctx, m.SQL)
+	}
+	if err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.Name, err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,157 @@
+package plans
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Plan describes what a caller is entitled to
+type Plan struct {
+	Name string `json:"name"`
+	// RequestsPerMinute and Burst bound the caller's request rate
+	RequestsPerMinute int `json:"requests_per_minute"`
+	Burst             int `json:"burst"`
+	// MaxLimit is the largest page size the caller may request
+	MaxLimit int `json:"max_limit"`
+	// LLMEnrichment allows LLM-generated summaries on query results
+	LLMEnrichment bool `json:"llm_enrichment"`
+	// SummariesByDefault includes those summaries in results unless a
+	// request asks for none with include_summary=false; otherwise only
+	// requests with include_summary=true get them
+	SummariesByDefault bool `json:"summaries_by_default"`
+	// Streaming allows the Server-Sent Events stream
+	Streaming bool `json:"streaming"`
+}
+
+// Plan tiers, from least to most generous
+var (
+	Free = Plan{
+		Name:              "free",
+		RequestsPerMinute: 30,
+		Burst:             10,
+		MaxLimit:          10,
+	}
+	Pro = Plan{
+		Name:               "pro",
+		RequestsPerMinute:  300,
+		Burst:              50,
+		MaxLimit:           25,
+		LLMEnrichment:      true,
+		SummariesByDefault: true,
+		Streaming:          true,
+	}
+	Enterprise = Plan{
+		Name:               "enterprise",
+		RequestsPerMinute:  3000,
+		Burst:              500,
+		MaxLimit:           50,
+		LLMEnrichment:      true,
+		SummariesByDefault: true,
+		Streaming:          true,
+	}
+)
+
+// ByName returns the tier with the given name
+func ByName(name string) (Plan, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case Free.Name:
+		return Free, true
+	case Pro.Name:
+		return Pro, true
+	case Enterprise.Name:
+		return Enterprise, true
+	}
+	return Plan{}, false
+}
+
+// SetSummaryDefaults overrides whether each tier includes summaries when a
+// request doesn't say, from "plan:true" or "plan:false" pairs such as those
+// in API_PLAN_SUMMARY_DEFAULTS. It is meant to run once at startup, before
+// API keys are parsed.
+func SetSummaryDefaults(pairs []string) error {
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, ":")
+		on, err := strconv.ParseBool(strings.TrimSpace(value))
+		if !ok || err != nil {
+			return fmt.Errorf("invalid summary default %q: expected plan:true or plan:false", pair)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case Free.Name:
+			Free.SummariesByDefault = on
+		case Pro.Name:
+			Pro.SummariesByDefault = on
+		case Enterprise.Name:
+			Enterprise.SummariesByDefault = on
+		default:
+			return fmt.Errorf("invalid summary default %q: unknown plan %q", pair, name)
+		}
+	}
+	return nil
+}
+
+// IncludeSummaries reports whether results carry LLM summaries for a
+// request that set include_summary to include, nil when it didn't say. A
+// request can't get summaries its plan doesn't allow.
+func (p Plan) IncludeSummaries(include *bool) bool {
+	if include != nil {
+		return *include && p.LLMEnrichment
+	}
+	return p.SummariesByDefault && p.LLMEnrichment
+}
+
+// Keys maps API keys to their plans
+type Keys map[string]Plan
+
+// ParseKeys parses "key:plan" pairs, such as those in API_KEYS
+func ParseKeys(pairs []string) (Keys, error) {
+	keys := make(Keys, len(pairs))
+	for _, pair := range pairs {
+		key, name, ok := strings.Cut(pair, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid API key entry %q: expected key:plan", pair)
+		}
+		plan, ok := ByName(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid API key entry for %s: unknown plan %q", MaskKey(key), name)
+		}
+		if _, dup := keys[key]; dup {
+			return nil, fmt.Errorf("API key %s is listed more than once", MaskKey(key))
+		}
+		keys[key] = plan
+	}
+	return keys, nil
+}
+
+// MaskKey hides all but the last four characters of an API key so it can be logged or echoed back
+func MaskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
+
+// Caller identifies who made a request and the plan it is served under
+type Caller struct {
+	// Key is the caller's API key, empty for anonymous callers
+	Key  string
+	Plan Plan
+}
+
+type contextKey struct{}
+
+// WithCaller returns a context carrying caller
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, contextKey{}, caller)
+}
+
+// FromContext returns the caller stored in ctx; requests that never passed
+// through the plan middleware are treated as anonymous free callers
+func FromContext(ctx context.Context) Caller {
+	if caller, ok := ctx.Value(contextKey{}).(Caller); ok {
+		return caller
+	}
+	return Caller{Plan: Free}
+}
@@ -0,0 +1,198 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/services/news"
+	"news-system/internal/services/queryaudit"
+	"news-system/internal/version"
+)
+
+// maxErrorBody bounds how much of an error response is kept
+const maxErrorBody = 512
+
+// Target is an environment queries are replayed against
+type Target struct {
+	BaseURL string
+	// APIKey is sent as X-API-Key when set
+	APIKey string
+	Client *http.Client
+	// UserAgent defaults to news-replay/<version>
+	UserAgent string
+}
+
+// Answer is what an environment returned for a query, or what was recorded
+type Answer struct {
+	Strategy   string   `json:"strategy,omitempty"`
+	ArticleIDs []string `json:"article_ids"`
+	// DurationMs is measured by the replay client, so it includes the network;
+	// recorded answers carry the server-side duration
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Query sends a query to the target's unified query endpoint
+func (t *Target) Query(ctx context.Context, query news.QueryRequest) Answer {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return Answer{Error: err.Error()}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(t.BaseURL, "/")+"/api/v1/news/query", bytes.NewReader(body))
+	if err != nil {
+		return Answer{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	userAgent := t.UserAgent
+	if userAgent == "" {
+		userAgent = "news-replay/" + version.Version
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if t.APIKey != "" {
+		req.Header.Set("X-API-Key", t.APIKey)
+	}
+
+	start := time.Now()
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return Answer{DurationMs: millis(time.Since(start)), Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return Answer{
+			DurationMs: millis(time.Since(start)),
+			Error:      fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(detail))),
+		}
+	}
+	var decoded news.QueryResponse
+	err = json.NewDecoder(resp.Body).Decode(&decoded)
+	took := time.Since(start)
+	if err != nil {
+		return Answer{DurationMs: millis(took), Error: fmt.Sprintf("failed to decode response: %v", err)}
+	}
+
+	answer := Answer{Strategy: decoded.Meta.Strategy, ArticleIDs: []string{}, DurationMs: millis(took)}
+	for _, article := range decoded.Articles {
+		answer.ArticleIDs = append(answer.ArticleIDs, article.ID)
+	}
+	return answer
+}
+
+// Request rebuilds the request of an audited query
+func Request(entry queryaudit.Entry) news.QueryRequest {
+	return news.QueryRequest{
+		Query:  entry.Query,
+		Lat:    entry.Lat,
+		Lon:    entry.Lon,
+		Radius: entry.RadiusKm,
+		Limit:  entry.Limit,
+		Lang:   entry.Lang,
+	}
+}
+
+// Recorded returns the answer the audit log recorded for entry
+func Recorded(entry queryaudit.Entry) Answer {
+	ids := entry.ArticleIDs
+	if ids == nil {
+		ids = []string{}
+	}
+	return Answer{
+		Strategy:   entry.Strategy,
+		ArticleIDs: ids,
+		DurationMs: float64(entry.DurationMs),
+		Error:      entry.Error,
+	}
+}
+
+// Options controls a replay
+type Options struct {
+	// Concurrency is the number of queries in flight at once
+	Concurrency int
+	// Rate caps queries per second across workers; 0 sends as fast as the workers allow
+	Rate float64
+}
+
+// Replay sends every entry to candidate and compares each answer with
+// baseline's, or with the recorded answer when baseline is nil. Entries for
+// later pages are skipped: their cursors aren't recorded, so they can't be
+// replayed faithfully.
+func Replay(ctx context.Context, entries []queryaudit.Entry, baseline, candidate *Target, opts Options) *Report {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	report := &Report{Results: []Result{}}
+	var replayable []queryaudit.Entry
+	for _, entry := range entries {
+		if entry.Offset > 0 || entry.Query == "" {
+			report.Skipped++
+			continue
+		}
+		replayable = append(replayable, entry)
+	}
+
+	var throttle <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	results := make([]Result, len(replayable))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry := replayable[i]
+				var base Answer
+				if baseline != nil {
+					base = baseline.Query(ctx, Request(entry))
+				} else {
+					base = Recorded(entry)
+				}
+				results[i] = compare(entry, base, candidate.Query(ctx, Request(entry)))
+			}
+		}()
+	}
+
+	sent := 0
+feed:
+	for i := range replayable {
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case jobs <- i:
+			sent++
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report.Results = append(report.Results, results[:sent]...)
+	report.summarize()
+	return report
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
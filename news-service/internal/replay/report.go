@@ -0,0 +1,210 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"news-system/internal/services/queryaudit"
+)
+
+// Outcomes of comparing a candidate answer with its baseline
+const (
+	// OutcomeIdentical means the same articles in the same order
+	OutcomeIdentical = "identical"
+	// OutcomeReordered means the same articles in a different order
+	OutcomeReordered = "reordered"
+	// OutcomeChanged means articles were added or removed
+	OutcomeChanged = "changed"
+	// OutcomeError means either side failed to answer
+	OutcomeError = "error"
+)
+
+// Result compares the answers to one replayed query
+type Result struct {
+	ID        string `json:"id"`
+	Query     string `json:"query"`
+	Outcome   string `json:"outcome"`
+	Baseline  Answer `json:"baseline"`
+	Candidate Answer `json:"candidate"`
+	// Overlap is the share of articles both answers returned, relative to the larger answer
+	Overlap float64 `json:"overlap"`
+	// Added and Removed are the articles only the candidate or only the baseline returned
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// compare diffs the result sets of two answers to entry
+func compare(entry queryaudit.Entry, baseline, candidate Answer) Result {
+	result := Result{ID: entry.ID, Query: entry.Query, Baseline: baseline, Candidate: candidate}
+	if baseline.Error != "" || candidate.Error != "" {
+		result.Outcome = OutcomeError
+		return result
+	}
+
+	inBaseline := make(map[string]bool, len(baseline.ArticleIDs))
+	for _, id := range baseline.ArticleIDs {
+		inBaseline[id] = true
+	}
+	inCandidate := make(map[string]bool, len(candidate.ArticleIDs))
+	shared := 0
+	for _, id := range candidate.ArticleIDs {
+		inCandidate[id] = true
+		if inBaseline[id] {
+			shared++
+		} else {
+			result.Added = append(result.Added, id)
+		}
+	}
+	for _, id := range baseline.ArticleIDs {
+		if !inCandidate[id] {
+			result.Removed = append(result.Removed, id)
+		}
+	}
+
+	larger := len(baseline.ArticleIDs)
+	if len(candidate.ArticleIDs) > larger {
+		larger = len(candidate.ArticleIDs)
+	}
+	result.Overlap = 1
+	if larger > 0 {
+		result.Overlap = float64(shared) / float64(larger)
+	}
+
+	switch {
+	case len(result.Added) > 0 || len(result.Removed) > 0:
+		result.Outcome = OutcomeChanged
+	case strings.Join(baseline.ArticleIDs, ",") != strings.Join(candidate.ArticleIDs, ","):
+		result.Outcome = OutcomeReordered
+	default:
+		result.Outcome = OutcomeIdentical
+	}
+	return result
+}
+
+// Latency summarizes the durations of one side's answers, in milliseconds
+type Latency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+}
+
+// Report summarizes a replay
+type Report struct {
+	Replayed int            `json:"replayed"`
+	Skipped  int            `json:"skipped"`
+	Outcomes map[string]int `json:"outcomes"`
+	// MeanOverlap averages Overlap over the queries both sides answered
+	MeanOverlap float64 `json:"mean_overlap"`
+	// StrategyChanges counts queries the candidate routed to a different strategy
+	StrategyChanges  int      `json:"strategy_changes"`
+	BaselineLatency  Latency  `json:"baseline_latency"`
+	CandidateLatency Latency  `json:"candidate_latency"`
+	Results          []Result `json:"results"`
+}
+
+// summarize fills in the totals from Results
+func (r *Report) summarize() {
+	r.Replayed = len(r.Results)
+	r.Outcomes = map[string]int{OutcomeIdentical: 0, OutcomeReordered: 0, OutcomeChanged: 0, OutcomeError: 0}
+
+	var overlap float64
+	var answered int
+	var baseline, candidate []float64
+	for _, result := range r.Results {
+		r.Outcomes[result.Outcome]++
+		if result.Outcome == OutcomeError {
+			continue
+		}
+		answered++
+		overlap += result.Overlap
+		if result.Baseline.Strategy != result.Candidate.Strategy {
+			r.StrategyChanges++
+		}
+		baseline = append(baseline, result.Baseline.DurationMs)
+		candidate = append(candidate, result.Candidate.DurationMs)
+	}
+	if answered > 0 {
+		r.MeanOverlap = overlap / float64(answered)
+	}
+	r.BaselineLatency = summarizeLatency(baseline)
+	r.CandidateLatency = summarizeLatency(candidate)
+}
+
+func summarizeLatency(durations []float64) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sort.Float64s(durations)
+	var total float64
+	for _, d := range durations {
+		total += d
+	}
+	return Latency{
+		Mean: total / float64(len(durations)),
+		P50:  percentile(durations, 0.50),
+		P95:  percentile(durations, 0.95),
+		P99:  percentile(durations, 0.99),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// Print writes the summary followed by up to maxDiffs changed or failed queries
+func (r *Report) Print(w io.Writer, maxDiffs int) {
+	fmt.Fprintf(w, "replayed %d queries, skipped %d\n", r.Replayed, r.Skipped)
+	for _, outcome := range []string{OutcomeIdentical, OutcomeReordered, OutcomeChanged, OutcomeError} {
+		fmt.Fprintf(w, "  %-10s %d\n", outcome, r.Outcomes[outcome])
+	}
+	fmt.Fprintf(w, "mean overlap %.3f, %d strategy change(s)\n", r.MeanOverlap, r.StrategyChanges)
+	fmt.Fprintf(w, "latency ms   %8s %8s %8s %8s\n", "mean", "p50", "p95", "p99")
+	for _, side := range []struct {
+		name    string
+		latency Latency
+	}{{"baseline", r.BaselineLatency}, {"candidate", r.CandidateLatency}} {
+		fmt.Fprintf(w, "  %-10s %8.1f %8.1f %8.1f %8.1f\n", side.name, side.latency.Mean, side.latency.P50, side.latency.P95, side.latency.P99)
+	}
+
+	shown := 0
+	for _, result := range r.Results {
+		if result.Outcome != OutcomeChanged && result.Outcome != OutcomeError {
+			continue
+		}
+		if shown == maxDiffs {
+			fmt.Fprintf(w, "\n(more differences omitted)\n")
+			break
+		}
+		shown++
+
+		fmt.Fprintf(w, "\n%s %q [%s]\n", result.Outcome, result.Query, result.ID)
+		if result.Outcome == OutcomeError {
+			if result.Baseline.Error != "" {
+				fmt.Fprintf(w, "  baseline:  %s\n", result.Baseline.Error)
+			}
+			if result.Candidate.Error != "" {
+				fmt.Fprintf(w, "  candidate: %s\n", result.Candidate.Error)
+			}
+			continue
+		}
+		if result.Baseline.Strategy != result.Candidate.Strategy {
+			fmt.Fprintf(w, "  strategy %s -> %s\n", result.Baseline.Strategy, result.Candidate.Strategy)
+		}
+		fmt.Fprintf(w, "  overlap %.2f\n", result.Overlap)
+		if len(result.Added) > 0 {
+			fmt.Fprintf(w, "  + %s\n", strings.Join(result.Added, ", "))
+		}
+		if len(result.Removed) > 0 {
+			fmt.Fprintf(w, "  - %s\n", strings.Join(result.Removed, ", "))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package replay sends queries recorded by the query audit log to another
+// environment and compares what it returns with the recorded answers, or
+// with a baseline environment queried alongside it.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"news-system/internal/services/queryaudit"
+	"news-system/internal/version"
+)
+
+// ReadFile reads entries from a file written from the audit log: the admin
+// API's response, a JSON array of entries, or one entry per line. A path of
+// "-" reads standard input.
+func ReadFile(path string) ([]queryaudit.Entry, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	entries, err := parseEntries(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// parseEntries accepts any of the formats ReadFile reads
+func parseEntries(data []byte) ([]queryaudit.Entry, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	switch data[0] {
+	case '[':
+		var entries []queryaudit.Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	case '{':
+		// A single document with an "entries" field is an admin API response
+		var response struct {
+			Entries []queryaudit.Entry `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &response); err == nil && response.Entries != nil {
+			return response.Entries, nil
+		}
+	}
+
+	var entries []queryaudit.Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var entry queryaudit.Entry
+		if err := json.Unmarshal(text, &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// AuditQuery selects the entries FetchAudit reads, with the parameters of
+// GET /admin/query-audit
+type AuditQuery struct {
+	Query    string
+	Strategy string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// FetchAudit reads entries from the admin API of the internal listener at baseURL
+func FetchAudit(ctx context.Context, client *http.Client, baseURL string, query AuditQuery) ([]queryaudit.Entry, error) {
+	params := url.Values{}
+	if query.Query != "" {
+		params.Set("q", query.Query)
+	}
+	if query.Strategy != "" {
+		params.Set("strategy", query.Strategy)
+	}
+	if !query.Since.IsZero() {
+		params.Set("since", query.Since.Format(time.RFC3339))
+	}
+	if !query.Until.IsZero() {
+		params.Set("until", query.Until.Format(time.RFC3339))
+	}
+	if query.Limit > 0 {
+		params.Set("limit", fmt.Sprint(query.Limit))
+	}
+
+	endpoint := strings.TrimRight(baseURL, "/") + "/admin/query-audit?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "news-replay/"+version.Version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query audit log: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query audit log: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query audit log returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return parseEntries(data)
+}
@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/cache"
+
+	"github.com/rs/zerolog/log"
+)
+
+// urlKeyPrefix is the prefix of the Redis keys that map canonical URL hashes to article IDs
+const urlKeyPrefix = "articles:url:"
+
+// URLFilter is an in-process Bloom filter of the canonical URL hashes stored
+// in Redis. Dedup lookups consult it first, so the common case of a URL never
+// seen before is answered without a round trip; a "maybe" still goes to Redis,
+// so false positives only cost the lookup the filter would have saved.
+//
+// The filter is seeded by scanning Redis and rebuilt on an interval, which also
+// drops URLs whose keys expired or were deleted. Between rebuilds, URLs stored by
+// this instance are added as they are written and those stored by other
+// instances must be added with Add, e.g. from article events. Until the first
+// rebuild completes every URL is reported as possibly present.
+//
+// Those events can be missed, so "not stored" is only a hint: writers still
+// claim a URL's key with SETNX before creating an article under it.
+type URLFilter struct {
+	cache             *cache.RedisCache
+	capacity          int
+	falsePositiveRate float64
+
+	mu         sync.RWMutex
+	bits       []uint64
+	hashes     int
+	count      int
+	ready      bool
+	rebuilding bool
+	// pending holds URLs added while a rebuild is scanning, replayed into the new filter
+	pending []string
+
+	ticker *time.Ticker
+	done   chan bool
+	wg     sync.WaitGroup
+}
+
+// NewURLFilter creates a filter sized for capacity URLs at the given false
+// positive rate. Rebuilds grow it when the stored URLs outnumber capacity.
+func NewURLFilter(redisCache *cache.RedisCache, capacity int, falsePositiveRate float64) *URLFilter {
+	if capacity <= 0 {
+		capacity = 1_000_000
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+	f := &URLFilter{
+		cache:             redisCache,
+		capacity:          capacity,
+		falsePositiveRate: falsePositiveRate,
+		done:              make(chan bool),
+	}
+	f.bits, f.hashes = bloomSize(capacity, falsePositiveRate)
+	return f
+}
+
+// bloomSize returns the bit array and number of hash functions that hold n
+// entries at false positive rate p
+func bloomSize(n int, p float64) ([]uint64, int) {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return make([]uint64, (int(m)+63)/64), k
+}
+
+// bloomHashes derives the two base hashes for double hashing. URL hashes are
+// hex SHA-256 digests, so their bytes are already uniformly distributed.
+func bloomHashes(urlHash string) (uint64, uint64) {
+	if len(urlHash) >= 32 {
+		if raw, err := hex.DecodeString(urlHash[:32]); err == nil {
+			return binary.BigEndian.Uint64(raw[:8]), binary.BigEndian.Uint64(raw[8:]) | 1
+		}
+	}
+	h := fnv.New128a()
+	h.Write([]byte(urlHash))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+// setBits sets a URL hash's bits in bits
+func setBits(bits []uint64, hashes int, urlHash string) {
+	h1, h2 := bloomHashes(urlHash)
+	m := uint64(len(bits)) * 64
+	for i := 0; i < hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % m
+		bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Add records a canonical URL hash as stored
+func (f *URLFilter) Add(urlHash string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	setBits(f.bits, f.hashes, urlHash)
+	f.count++
+	if f.rebuilding {
+		f.pending = append(f.pending, urlHash)
+	}
+}
+
+// MayContain reports whether a canonical URL hash may be stored. False means
+// it definitely is not, as of the last rebuild plus the URLs added since.
+func (f *URLFilter) MayContain(urlHash string) bool {
+	h1, h2 := bloomHashes(urlHash)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !f.ready {
+		return true
+	}
+	m := uint64(len(f.bits)) * 64
+	for i := 0; i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Rebuild replaces the filter with one built from the URL keys currently in
+// Redis, sized for twice the URLs last seen so it has room to grow
+func (f *URLFilter) Rebuild(ctx context.Context) error {
+	f.mu.Lock()
+	if f.rebuilding {
+		f.mu.Unlock()
+		return nil
+	}
+	f.rebuilding = true
+	f.pending = nil
+	size := max(f.capacity, 2*f.count)
+	f.mu.Unlock()
+
+	started := time.Now()
+	bits, hashes := bloomSize(size, f.falsePositiveRate)
+	count := 0
+	err := f.cache.ScanKeys(ctx, urlKeyPrefix+"*", func(keys []string) error {
+		for _, key := range keys {
+			setBits(bits, hashes, strings.TrimPrefix(key, urlKeyPrefix))
+		}
+		count += len(keys)
+		return nil
+	})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebuilding = false
+	if err != nil {
+		f.pending = nil
+		return err
+	}
+	for _, urlHash := range f.pending {
+		setBits(bits, hashes, urlHash)
+	}
+	f.bits, f.hashes, f.count, f.ready = bits, hashes, count+len(f.pending), true
+	f.pending = nil
+
+	log.Info().Int("urls", count).Int("capacity", size).Dur("took", time.Since(started)).Msg("URL filter rebuilt")
+	return nil
+}
+
+// Start builds the filter immediately and rebuilds it once per interval
+func (f *URLFilter) Start(ctx context.Context, interval time.Duration) {
+	f.ticker = time.NewTicker(interval)
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		if err := f.Rebuild(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to build URL filter")
+		}
+		for {
+			select {
+			case <-f.ticker.C:
+				if err := f.Rebuild(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to rebuild URL filter")
+				}
+			case <-f.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops rebuilding and waits for a rebuild in progress to finish
+func (f *URLFilter) Stop() {
+	if f.ticker != nil {
+		f.ticker.Stop()
+	}
+	close(f.done)
+	f.wg.Wait()
+}
@@ -7,38 +7,146 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"news-system/internal/cache"
+	"news-system/internal/errs"
 	"github.com/go-redis/redis/v9"
 )
 
 // DB represents a database connection
 type DB struct {
-	pool *pgxpool.Pool
+	pool           *pgxpool.Pool
+	acquireTimeout time.Duration
 }
 
-// NewDB creates a new database connection
-func NewDB(databaseURL string) (*DB, error) {
-	// For now, return a mock DB since we're using in-memory storage
-	return &DB{}, nil
+// PoolOptions tunes the Postgres connection pool. Zero values keep the pgxpool defaults.
+type PoolOptions struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	// ConnectTimeout bounds dialing a new connection
+	ConnectTimeout time.Duration
+	// AcquireTimeout bounds how long a query waits for a free connection
+	AcquireTimeout time.Duration
 }
 
-// Repository interface for database operations
-type Repository interface {
-	CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error)
+// ErrPoolExhausted is returned when no pooled connection frees up within AcquireTimeout
+var ErrPoolExhausted = errs.New(errs.ErrUnavailable, "postgres connection pool exhausted")
+
+// NewDB creates a new Postgres connection pool
+func NewDB(databaseURL string, opts PoolOptions) (*DB, error) {
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %w", err)
+	}
+	if opts.MaxConns > 0 {
+		poolConfig.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		poolConfig.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = opts.ConnectTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	return &DB{pool: pool, acquireTimeout: opts.AcquireTimeout}, nil
+}
+
+// Close closes all connections in the pool
+func (db *DB) Close() {
+	if db.pool != nil {
+		db.pool.Close()
+	}
+}
+
+// Stat returns a snapshot of the connection pool statistics
+func (db *DB) Stat() *pgxpool.Stat {
+	return db.pool.Stat()
+}
+
+// ReadRepository holds the read-only queries, which may be served by replicas
+type ReadRepository interface {
 	GetArticleByID(ctx context.Context, id string) (Article, error)
 	GetArticlesByCategory(ctx context.Context, arg GetArticlesByCategoryParams) ([]Article, error)
 	GetArticlesBySource(ctx context.Context, arg GetArticlesBySourceParams) ([]Article, error)
 	GetArticlesByScore(ctx context.Context, arg GetArticlesByScoreParams) ([]Article, error)
+	GetArticlesByPlace(ctx context.Context, arg GetArticlesByPlaceParams) ([]Article, error)
 	SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error)
 	GetNearbyArticles(ctx context.Context, arg GetNearbyArticlesParams) ([]GetNearbyArticlesRow, error)
 	GetRecentEventsByGeohash(ctx context.Context, since time.Time) ([]GetRecentEventsByGeohashRow, error)
-	CreateArticleSummary(ctx context.Context, arg CreateArticleSummaryParams) (ArticleSummary, error)
 	GetArticleSummary(ctx context.Context, articleID string) (ArticleSummary, error)
-	CreateUserEvent(ctx context.Context, arg CreateUserEventParams) (UserEvent, error)
 	GetArticlesWithoutSummary(ctx context.Context, limit int32) ([]Article, error)
+	GetArticleEventCounts(ctx context.Context, arg GetArticleEventCountsParams) ([]ArticleEventCount, error)
+	ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error)
+	GetArticleVersions(ctx context.Context, urlHashes []string) ([]ArticleVersion, error)
+	SemanticSearchArticles(ctx context.Context, arg SemanticSearchArticlesParams) ([]SemanticSearchArticlesRow, error)
+	GetArticlesWithoutEmbedding(ctx context.Context, arg GetArticlesWithoutEmbeddingParams) ([]Article, error)
+	GetArticleEntities(ctx context.Context, articleID string) ([]ArticleEntity, error)
+	GetArticlesWithoutEntities(ctx context.Context, limit int32) ([]Article, error)
+	GetArticlesWithoutPlace(ctx context.Context, limit int32) ([]Article, error)
+	GetFeedbackStats(ctx context.Context, since time.Time) ([]FeedbackStat, error)
+	GetSummariesWithNegativeFeedback(ctx context.Context, arg GetSummariesWithNegativeFeedbackParams) ([]SummaryFeedback, error)
+	GetSubscription(ctx context.Context, id string) (Subscription, error)
+	ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error)
+	GetDueSubscriptions(ctx context.Context, arg GetDueSubscriptionsParams) ([]Subscription, error)
+	GetAlertSubscriptions(ctx context.Context) ([]Subscription, error)
+	ListSubscriptionDeliveries(ctx context.Context, arg ListSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error)
+	SearchSubscriptionDeliveries(ctx context.Context, arg SearchSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error)
+	GetNotificationTemplate(ctx context.Context, arg GetNotificationTemplateParams) (NotificationTemplate, error)
+	ListNotificationTemplates(ctx context.Context, owner string) ([]NotificationTemplate, error)
+}
+
+// WriteRepository holds the mutating queries, which must go to the primary
+type WriteRepository interface {
+	CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error)
+	BulkCreateArticles(ctx context.Context, args []CreateArticleParams) ([]BulkCreateResult, error)
+	CreateArticleSummary(ctx context.Context, arg CreateArticleSummaryParams) (ArticleSummary, error)
+	CreateArticleEmbeddings(ctx context.Context, args []CreateArticleEmbeddingParams) error
+	ReplaceArticleEntities(ctx context.Context, args []ReplaceArticleEntitiesParams) error
+	SetArticlePlaces(ctx context.Context, args []SetArticlePlaceParams) error
+	CreateUserEvent(ctx context.Context, arg CreateUserEventParams) (UserEvent, error)
+	CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (Feedback, error)
+	UpdateArticle(ctx context.Context, arg UpdateArticleParams) (Article, error)
+	DeleteArticle(ctx context.Context, id string) error
+	CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error)
+	UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	ClaimSubscriptionRun(ctx context.Context, arg ClaimSubscriptionRunParams) (bool, error)
+	CreateSubscriptionDelivery(ctx context.Context, arg CreateSubscriptionDeliveryParams) (SubscriptionDelivery, error)
+	RecordSubscriptionFailure(ctx context.Context, arg RecordSubscriptionFailureParams) (Subscription, error)
+	ResetSubscriptionFailures(ctx context.Context, id string) error
+	EnableSubscription(ctx context.Context, id string) (Subscription, error)
+	UpsertNotificationTemplate(ctx context.Context, arg UpsertNotificationTemplateParams) (NotificationTemplate, error)
+	DeleteNotificationTemplate(ctx context.Context, arg DeleteNotificationTemplateParams) error
+}
+
+// Repository interface for database operations
+type Repository interface {
+	ReadRepository
+	WriteRepository
 }
 
 // Article represents a news article
@@ -53,6 +161,13 @@ type Article struct {
 	RelevanceScore  float64    `json:"relevance_score"`
 	Latitude        *float64   `json:"latitude"`
 	Longitude       *float64   `json:"longitude"`
+	// Language is the article's ISO 639-1 code, "" when unknown
+	Language        string     `json:"language"`
+	// City, Region and Country name the place at the article's coordinates;
+	// Country is an ISO 3166-1 alpha-2 code. "" when unknown.
+	City            string     `json:"city"`
+	Region          string     `json:"region"`
+	Country         string     `json:"country"`
 }
 
 // ArticleSummary represents an article summary
@@ -61,6 +176,11 @@ type ArticleSummary struct {
 	LLMSummary  string    `json:"llm_summary"`
 	Model       string    `json:"model"`
 	GeneratedAt time.Time `json:"generated_at"`
+	// Sentiment is the article's positive, negative or neutral tone, judged
+	// when it was summarized, and SentimentScore its strength from -1 to 1;
+	// nil when it couldn't be judged
+	Sentiment      *string  `json:"sentiment,omitempty"`
+	SentimentScore *float64 `json:"sentiment_score,omitempty"`
 }
 
 // UserEvent represents a user interaction event
@@ -73,6 +193,154 @@ type UserEvent struct {
 	UserLon     *float64   `json:"user_lon"`
 }
 
+// User event types
+const (
+	EventView  = "view"
+	EventClick = "click"
+)
+
+// Feedback targets: an article's summary, or its relevance as a result
+const (
+	FeedbackTargetSummary = "summary"
+	FeedbackTargetResult  = "result"
+)
+
+// Feedback ratings
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
+// Feedback is a reader's thumbs up or down on an article's summary or on its
+// relevance as a result
+type Feedback struct {
+	ID        string `json:"id"`
+	ArticleID string `json:"article_id"`
+	Target    string `json:"target"`
+	Rating    string `json:"rating"`
+	// Query is the query the article was returned for
+	Query   *string `json:"query,omitempty"`
+	Comment *string `json:"comment,omitempty"`
+	// SummaryModel is the model of the summary rated
+	SummaryModel *string   `json:"summary_model,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// FeedbackStat counts the feedback on one target, and for summaries one model
+type FeedbackStat struct {
+	Target string `json:"target"`
+	// Model is the model of the summaries rated, "" for results
+	Model string `json:"model,omitempty"`
+	Up    int64  `json:"up"`
+	Down  int64  `json:"down"`
+}
+
+// SummaryFeedback counts the feedback on a stored summary since it was generated
+type SummaryFeedback struct {
+	ArticleID   string    `json:"article_id"`
+	Model       string    `json:"model"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Up          int64     `json:"up"`
+	Down        int64     `json:"down"`
+}
+
+// Subscription kinds: a digest of the matching articles on a schedule, or an
+// alert for each matching article as it is stored
+const (
+	SubscriptionDigest = "digest"
+	SubscriptionAlert  = "alert"
+)
+
+// Subscription channels
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelPush    = "push"
+)
+
+// Subscription delivery kinds, besides the subscription kinds
+const DeliveryVerification = "verification"
+
+// Subscription delivery statuses
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Subscription delivers the articles matching its filters to an email
+// address, webhook endpoint or push device token
+type Subscription struct {
+	ID string `json:"id"`
+	// Owner identifies the API key that created the subscription
+	Owner   string `json:"owner"`
+	Kind    string `json:"kind"`
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+	// Query, Category and the location select articles; Language and Country
+	// restrict them. Each is nil when not set.
+	Query     *string  `json:"query,omitempty"`
+	Category  *string  `json:"category,omitempty"`
+	Language  *string  `json:"language,omitempty"`
+	Country   *string  `json:"country,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	RadiusKm  *float64 `json:"radius_km,omitempty"`
+	// Schedule is the cron expression of a digest, run in Timezone on the
+	// working days of Calendar, when set
+	Schedule *string `json:"schedule,omitempty"`
+	Timezone string  `json:"timezone"`
+	Calendar *string `json:"calendar,omitempty"`
+	// Secret signs webhook deliveries
+	Secret string `json:"secret"`
+	// VerificationHash is the hash of the verification token sent last,
+	// nil once verified
+	VerificationHash *string    `json:"verification_hash,omitempty"`
+	VerifiedAt       *time.Time `json:"verified_at,omitempty"`
+	// NextRunAt is when a verified digest is sent next, and LastRunAt when
+	// it was last sent
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// Failures counts the messages that failed in a row. DisabledAt is set,
+	// with the reason, once too many did; nothing is sent until it is
+	// enabled again.
+	Failures       int32      `json:"failures"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason *string    `json:"disabled_reason,omitempty"`
+}
+
+// SubscriptionDelivery records one attempt to send a message for a subscription
+type SubscriptionDelivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	Kind           string `json:"kind"`
+	Status         string `json:"status"`
+	// Articles is the number of articles the message carried
+	Articles    int32     `json:"articles"`
+	Error       *string   `json:"error,omitempty"`
+	DeliveredAt time.Time `json:"delivered_at"`
+	// Channel is the channel the attempt went through, and Attempt its
+	// number among the attempts to send the message, from 1
+	Channel string `json:"channel"`
+	Attempt int32  `json:"attempt"`
+	// LatencyMs is how long the attempt took, nil when nothing was sent
+	LatencyMs *int32 `json:"latency_ms,omitempty"`
+	// Response is what the endpoint answered, e.g. an HTTP status
+	Response *string `json:"response,omitempty"`
+}
+
+// NotificationTemplate is an owner's override of the template that renders
+// one kind of subscription message; Name is the message kind
+type NotificationTemplate struct {
+	Owner     string    `json:"owner"`
+	Name      string    `json:"name"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // Search result with score
 type SearchArticlesRow struct {
 	Article
@@ -85,11 +353,41 @@ type GetNearbyArticlesRow struct {
 	DistanceMeters float64 `json:"distance_meters"`
 }
 
+// Semantic search result with the cosine similarity of its embedding to the query's
+type SemanticSearchArticlesRow struct {
+	Article
+	Similarity float64 `json:"similarity"`
+}
+
+// ArticleEmbedding is the embedding of an article's content from one model
+type ArticleEmbedding struct {
+	ArticleID   string    `json:"article_id"`
+	Model       string    `json:"model"`
+	ContentHash string    `json:"content_hash"`
+	Embedding   []float32 `json:"embedding"`
+}
+
 // Event with article location
 type GetRecentEventsByGeohashRow struct {
 	UserEvent
 	Latitude  *float64 `json:"latitude"`
 	Longitude *float64 `json:"longitude"`
+	// Count is the number of events the row stands for: 1 for a raw event, or
+	// an hour's events in one tile for an hourly rollup (see EventDownsampler)
+	Count int64 `json:"count"`
+}
+
+// ArticleVersion identifies the stored version of an article: its ID and the
+// ArticleContentHash of its content. Both are empty for unknown articles.
+type ArticleVersion struct {
+	ID          string
+	ContentHash string
+}
+
+// ArticleEventCount is the number of user events of one type recorded for an article
+type ArticleEventCount struct {
+	Event string `json:"event"`
+	Count int64  `json:"count"`
 }
 
 // Parameter structs for queries
@@ -104,39 +402,309 @@ type CreateArticleParams struct {
 	RelevanceScore  float64
 	Latitude        *float64
 	Longitude       *float64
+	Language        string
+	City            string
+	Region          string
+	Country         string
+}
+
+// BulkCreateResult is the outcome of one article of BulkCreateArticles
+type BulkCreateResult struct {
+	Article Article
+	Err     error
+}
+
+// UpdateArticleParams replaces every editable field of the article with ID
+type UpdateArticleParams struct {
+	ID              string
+	Title           string
+	Description     *string
+	URL             string
+	PublicationDate time.Time
+	SourceName      string
+	Category        []string
+	RelevanceScore  float64
+	Latitude        *float64
+	Longitude       *float64
+	Language        string
+	City            string
+	Region          string
+	Country         string
 }
 
+// The list and search params take an optional Language, Country and
+// Sentiment; "" matches every article. An article's sentiment is stored with its summary,
+// so filtering by sentiment leaves out articles without one. Entities, when
+// given, are NormalizeEntity names the articles must all mention, so the
+// filter leaves out articles whose entities weren't extracted yet.
+// PublishedAfter and PublishedBefore, when set, bound the publication date to
+// [PublishedAfter, PublishedBefore).
+
 type GetArticlesByCategoryParams struct {
-	Name  string
-	Limit int32
+	Name            string
+	Language        string
+	Country         string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	Offset          int32
+	Limit           int32
 }
 
 type GetArticlesBySourceParams struct {
-	Name  string
-	Limit int32
+	Name            string
+	Language        string
+	Country         string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	Offset          int32
+	Limit           int32
 }
 
 type GetArticlesByScoreParams struct {
-	Min   float64
-	Limit int32
+	Min             float64
+	Language        string
+	Country         string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	// Unlocated keeps only the articles without coordinates, which nearby
+	// queries can't find
+	Unlocated       bool
+	Offset          int32
+	Limit           int32
+}
+
+// GetArticlesByPlaceParams selects the articles placed in Country, an ISO
+// 3166-1 alpha-2 code, in Region, and in a city or region named Place
+// (case-insensitively); each is skipped when ""
+type GetArticlesByPlaceParams struct {
+	Country         string
+	Region          string
+	Place           string
+	Language        string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	Offset          int32
+	Limit           int32
+}
+
+// ListArticlesParams pages through articles newest first. A page starts after
+// BeforeDate and BeforeID, the last article of the previous page; a nil
+// BeforeDate starts at the newest article.
+type ListArticlesParams struct {
+	BeforeDate *time.Time
+	BeforeID   string
+	Limit      int32
 }
 
 type SearchArticlesParams struct {
-	Query string
-	Limit int32
+	Query           string
+	Language        string
+	Country         string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	Offset          int32
+	Limit           int32
 }
 
 type GetNearbyArticlesParams struct {
-	Lat    float64
-	Lon    float64
-	Radius float64
-	Limit  int32
+	Lat             float64
+	Lon             float64
+	Radius          float64
+	Language        string
+	Country         string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	Offset          int32
+	Limit           int32
 }
 
 type CreateArticleSummaryParams struct {
-	ArticleID  string
-	LLMSummary string
-	Model      string
+	ArticleID      string
+	LLMSummary     string
+	Model          string
+	Sentiment      *string
+	SentimentScore *float64
+}
+
+// CreateArticleEmbeddingParams stores the embedding of an article's content,
+// identified by its ArticleContentHash, from model
+type CreateArticleEmbeddingParams struct {
+	ArticleID   string
+	Model       string
+	ContentHash string
+	Embedding   []float32
+}
+
+// GetArticlesWithoutEmbeddingParams selects articles lacking a current embedding from Model
+type GetArticlesWithoutEmbeddingParams struct {
+	Model string
+	Limit int32
+}
+
+// SemanticSearchArticlesParams ranks articles by the similarity of their
+// embedding from Model to Embedding, leaving out those below MinSimilarity
+type SemanticSearchArticlesParams struct {
+	Embedding       []float32
+	Model           string
+	MinSimilarity   float64
+	Language        string
+	Country         string
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Sentiment       string
+	Entities        []string
+	Limit           int32
+	Offset          int32
+}
+
+type CreateFeedbackParams struct {
+	ArticleID    string
+	Target       string
+	Rating       string
+	Query        *string
+	Comment      *string
+	SummaryModel *string
+}
+
+type CreateSubscriptionParams struct {
+	Owner            string
+	Kind             string
+	Channel          string
+	Target           string
+	Query            *string
+	Category         *string
+	Language         *string
+	Country          *string
+	Latitude         *float64
+	Longitude        *float64
+	RadiusKm         *float64
+	Schedule         *string
+	Timezone         string
+	Calendar         *string
+	Secret           string
+	VerificationHash *string
+	VerifiedAt       *time.Time
+	NextRunAt        *time.Time
+}
+
+type ListSubscriptionsParams struct {
+	Owner  string
+	Offset int32
+	Limit  int32
+}
+
+// UpdateSubscriptionParams replaces every field of a subscription but its
+// owner and secret
+type UpdateSubscriptionParams struct {
+	Kind             string
+	Channel          string
+	Target           string
+	Query            *string
+	Category         *string
+	Language         *string
+	Country          *string
+	Latitude         *float64
+	Longitude        *float64
+	RadiusKm         *float64
+	Schedule         *string
+	Timezone         string
+	Calendar         *string
+	VerificationHash *string
+	VerifiedAt       *time.Time
+	NextRunAt        *time.Time
+	ID               string
+}
+
+// GetDueSubscriptionsParams selects the verified digests due at Before
+type GetDueSubscriptionsParams struct {
+	Before time.Time
+	Limit  int32
+}
+
+// ClaimSubscriptionRunParams moves a digest's next run from Due to Next, nil
+// when its schedule has no more runs
+type ClaimSubscriptionRunParams struct {
+	Next *time.Time
+	Due  time.Time
+	ID   string
+}
+
+type CreateSubscriptionDeliveryParams struct {
+	SubscriptionID string
+	Kind           string
+	Status         string
+	Articles       int32
+	Error          *string
+	Channel        string
+	Attempt        int32
+	LatencyMs      *int32
+	Response       *string
+}
+
+// SearchSubscriptionDeliveriesParams filters deliveries across
+// subscriptions; nil fields match every delivery
+type SearchSubscriptionDeliveriesParams struct {
+	SubscriptionID *string
+	Channel        *string
+	Status         *string
+	Since          *time.Time
+	Offset         int32
+	Limit          int32
+}
+
+// RecordSubscriptionFailureParams counts a failed message of subscription
+// ID, disabling it with Reason at Threshold failures in a row (0 never does)
+type RecordSubscriptionFailureParams struct {
+	Threshold int32
+	Reason    *string
+	ID        string
+}
+
+type ListSubscriptionDeliveriesParams struct {
+	SubscriptionID string
+	Offset         int32
+	Limit          int32
+}
+
+type GetNotificationTemplateParams struct {
+	Owner string
+	Name  string
+}
+
+type UpsertNotificationTemplateParams struct {
+	Owner   string
+	Name    string
+	Subject string
+	Body    string
+}
+
+type DeleteNotificationTemplateParams struct {
+	Owner string
+	Name  string
+}
+
+// GetSummariesWithNegativeFeedbackParams selects summaries with at least
+// MinDown thumbs down since they were generated
+type GetSummariesWithNegativeFeedbackParams struct {
+	MinDown int64
+	Limit   int32
+}
+
+type GetArticleEventCountsParams struct {
+	ArticleID string
+	Since     time.Time
 }
 
 type CreateUserEventParams struct {
@@ -146,45 +714,178 @@ type CreateUserEventParams struct {
 	UserLon   *float64
 }
 
+// contentKeyPrefix is the prefix of the Redis keys that map canonical URL hashes to article content hashes
+const contentKeyPrefix = "articles:content:"
+
 // Repository implementation
 type repository struct {
-	db *DB
 	// Redis cache for persistent storage
 	cache *cache.RedisCache
-	// In-memory storage for testing
+	// In-memory storage for testing. Articles are written by concurrent
+	// ingest workers and the HTTP handlers, so they're locked.
 	articles map[string]Article
-	nextID   int64
+	// Canonical URL hash -> article ID, for in-memory dedup, locked with articles
+	byURL      map[string]string
+	articlesMu sync.RWMutex
+	// Article ID -> summary, for in-memory storage. Summaries are written by
+	// concurrent queries and the backfill, so they're locked too.
+	summaries   map[string]ArticleSummary
+	summariesMu sync.Mutex
+	// Article ID -> embedding, for in-memory storage, locked like summaries
+	embeddings   map[string]ArticleEmbedding
+	embeddingsMu sync.Mutex
+	// Article ID -> extracted entities, for in-memory storage, locked like summaries
+	entities   map[string]storedEntities
+	entitiesMu sync.Mutex
+	// Article ID -> coordinates its place was geocoded at, for in-memory storage, locked like summaries
+	geocodes   map[string]geocodedAt
+	geocodesMu sync.Mutex
+	// Feedback, for in-memory storage, locked like summaries
+	feedback   []Feedback
+	feedbackMu sync.Mutex
+	// Subscriptions, their deliveries (newest last) and their owners'
+	// notification templates, for in-memory storage, locked like summaries
+	subscriptions   map[string]Subscription
+	deliveries      map[string][]SubscriptionDelivery
+	templates       map[ownerTemplate]NotificationTemplate
+	subscriptionsMu sync.Mutex
+	// User events, oldest first, and the ID of the next one, for in-memory
+	// storage, locked like summaries
+	events      []UserEvent
+	nextEventID int64
+	eventsMu    sync.Mutex
+	// Optional filter that answers "URL never stored" without a Redis lookup
+	urls *URLFilter
 }
 
-func NewRepository(db *DB) Repository {
-	// Create a Redis cache instance for the repository
-	// Use the Docker service name 'redis' and default port 6379
-	redisCache, err := cache.NewRedisCache("redis:6379", "", 0)
-	if err != nil {
-		// Fallback to in-memory if Redis is not available
+// NewRepository creates a Redis-backed repository. When redisCache is nil
+// (Redis not available) it falls back to in-memory storage.
+func NewRepository(redisCache *cache.RedisCache) Repository {
+	if redisCache == nil {
 		return &repository{
-			db:       db,
-			articles: make(map[string]Article),
-			nextID:   1,
+			articles:      make(map[string]Article),
+			byURL:         make(map[string]string),
+			summaries:     make(map[string]ArticleSummary),
+			embeddings:    make(map[string]ArticleEmbedding),
+			entities:      make(map[string]storedEntities),
+			subscriptions: make(map[string]Subscription),
+			deliveries:    make(map[string][]SubscriptionDelivery),
+			templates:     make(map[ownerTemplate]NotificationTemplate),
+			nextEventID:   1,
 		}
 	}
 	
 	return &repository{
-		db:       db,
 		cache:    redisCache,
-		nextID:   1,
 	}
 }
 
-// CreateArticle creates or updates an article
+// NewRepositoryWithURLFilter creates a Redis-backed repository that consults
+// filter before looking up canonical URLs in Redis and adds the URLs it stores
+func NewRepositoryWithURLFilter(redisCache *cache.RedisCache, filter *URLFilter) Repository {
+	return &repository{
+		cache: redisCache,
+		urls:  filter,
+	}
+}
+
+// CreateArticle creates an article, or updates the one with the same canonical URL
 func (r *repository) CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error) {
-	// Generate ID if not provided
-	if arg.ID == "" {
-		arg.ID = fmt.Sprintf("article_%d", r.nextID)
-		r.nextID++
+	results, err := r.BulkCreateArticles(ctx, []CreateArticleParams{arg})
+	if err != nil {
+		return Article{}, err
+	}
+	return results[0].Article, results[0].Err
+}
+
+// BulkCreateArticles creates or updates many articles. Existing articles are
+// resolved with two MGETs and every write goes out in one pipeline, instead of
+// several round trips per article.
+func (r *repository) BulkCreateArticles(ctx context.Context, args []CreateArticleParams) ([]BulkCreateResult, error) {
+	// Re-ingesting a known article updates it under its original ID
+	hashes := make([]string, len(args))
+	for i, arg := range args {
+		hashes[i] = URLHash(arg.URL)
+	}
+	existingIDs, err := r.articleIDsByURL(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkCreateResult, len(args))
+	articles := make([]Article, len(args))
+	batchIDs := make(map[string]string)
+	for i, arg := range args {
+		switch {
+		case existingIDs[i] != "":
+			arg.ID = existingIDs[i]
+		case batchIDs[hashes[i]] != "":
+			// A repeat of a URL earlier in the batch updates that article
+			arg.ID = batchIDs[hashes[i]]
+		case arg.ID == "":
+			// Generate ID if not provided. IDs are random so instances sharing
+			// Redis, or restarting, never hand out one that's taken.
+			arg.ID = NewArticleID()
+		}
+		batchIDs[hashes[i]] = arg.ID
+
+		articles[i] = Article{
+			ID:              arg.ID,
+			Title:           arg.Title,
+			Description:     arg.Description,
+			URL:             arg.URL,
+			PublicationDate: arg.PublicationDate,
+			SourceName:      arg.SourceName,
+			Category:        arg.Category,
+			RelevanceScore:  arg.RelevanceScore,
+			Latitude:        arg.Latitude,
+			Longitude:       arg.Longitude,
+			Language:        arg.Language,
+			City:            arg.City,
+			Region:          arg.Region,
+			Country:         arg.Country,
+		}
+		results[i].Article = articles[i]
+	}
+
+	// The lookup may have missed URLs stored by other instances, so new
+	// articles claim their URL before being written and become updates when
+	// another article already holds it
+	claimed, err := r.claimURLs(ctx, hashes, existingIDs, batchIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range articles {
+		if owner := claimed[hashes[i]]; owner != "" && owner != articles[i].ID {
+			existingIDs[i] = owner
+			articles[i].ID = owner
+			results[i].Article = articles[i]
+		}
+	}
+
+	// Drop the previous versions' index entries so changed categories don't linger
+	previous, err := r.articlesByID(ctx, existingIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.saveArticles(ctx, previous, articles); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpdateArticle replaces the fields of an existing article
+func (r *repository) UpdateArticle(ctx context.Context, arg UpdateArticleParams) (Article, error) {
+	previous, err := r.GetArticleByID(ctx, arg.ID)
+	if err != nil {
+		return Article{}, err
+	}
+
+	// The new URL must not belong to another article
+	if ownerID := r.articleIDByURL(ctx, URLHash(arg.URL)); ownerID != "" && ownerID != arg.ID {
+		return Article{}, errs.Errorf(errs.ErrConflict, "article %s already has url %s", ownerID, arg.URL)
 	}
 
-	// Create article
 	article := Article{
 		ID:              arg.ID,
 		Title:           arg.Title,
@@ -196,48 +897,393 @@ func (r *repository) CreateArticle(ctx context.Context, arg CreateArticleParams)
 		RelevanceScore:  arg.RelevanceScore,
 		Latitude:        arg.Latitude,
 		Longitude:       arg.Longitude,
+		Language:        arg.Language,
+		City:            arg.City,
+		Region:          arg.Region,
+		Country:         arg.Country,
 	}
 
-	// Store in Redis
-	if r.cache != nil {
-		articleData, err := json.Marshal(article)
-		if err == nil {
-			// Store individual article
-			r.cache.Set(ctx, fmt.Sprintf("article:%s", arg.ID), articleData, 24*time.Hour)
-			
-			// Store in article list
-			r.cache.SAdd(ctx, "articles:all", arg.ID)
-			
-			// Store by category
-			for _, category := range article.Category {
-				r.cache.SAdd(ctx, fmt.Sprintf("articles:category:%s", strings.ToLower(category)), arg.ID)
-			}
-			
-			// Store by source
-			r.cache.SAdd(ctx, fmt.Sprintf("articles:source:%s", strings.ToLower(article.SourceName)), arg.ID)
-			
-			// Store by score
-			r.cache.ZAdd(ctx, "articles:by_score", redis.Z{
-				Score:  article.RelevanceScore,
-				Member: arg.ID,
-			})
+	if err := r.saveArticles(ctx, []Article{previous}, []Article{article}); err != nil {
+		return Article{}, err
+	}
+	return article, nil
+}
+
+// DeleteArticle removes an article and every index entry pointing at it
+func (r *repository) DeleteArticle(ctx context.Context, id string) error {
+	article, err := r.GetArticleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if r.cache == nil {
+		r.articlesMu.Lock()
+		r.unindexInMemory(article)
+		delete(r.articles, id)
+		r.articlesMu.Unlock()
+		r.summariesMu.Lock()
+		delete(r.summaries, id)
+		r.summariesMu.Unlock()
+		r.embeddingsMu.Lock()
+		delete(r.embeddings, id)
+		r.embeddingsMu.Unlock()
+		r.entitiesMu.Lock()
+		delete(r.entities, id)
+		r.entitiesMu.Unlock()
+		r.geocodesMu.Lock()
+		delete(r.geocodes, id)
+		r.geocodesMu.Unlock()
+		r.deleteFeedbackInMemory(id)
+		r.deleteUserEventsInMemory(id)
+		return nil
+	}
+	entityNames := r.storedEntityNames(ctx, id)
+	err = r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		unindexArticle(ctx, pipe, article)
+		for _, name := range entityNames {
+			pipe.SRem(ctx, entityIndexKey(name), id)
 		}
-	} else {
+		pipe.Del(ctx, fmt.Sprintf("article:%s", id), summaryKey(id), embeddingKey(id), entitiesKey(id), geocodeKey(id))
+		pipe.SRem(ctx, "articles:all", id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete article %s: %w", id, err)
+	}
+	if err := r.deleteFeedback(ctx, id); err != nil {
+		return err
+	}
+	return r.deleteUserEvents(ctx, id)
+}
+
+// saveArticles replaces the previous versions of articles with the new ones,
+// rewriting every index entry. previous may hold fewer articles than articles.
+func (r *repository) saveArticles(ctx context.Context, previous, articles []Article) error {
+	if r.cache == nil {
 		// Fallback to in-memory storage
-		if r.articles == nil {
-			r.articles = make(map[string]Article)
+		r.articlesMu.Lock()
+		defer r.articlesMu.Unlock()
+		for _, article := range previous {
+			r.unindexInMemory(article)
+		}
+		for _, article := range articles {
+			r.indexInMemory(article)
 		}
-		r.articles[arg.ID] = article
+		return nil
 	}
 
-	return article, nil
+	err := r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, article := range previous {
+			unindexArticle(ctx, pipe, article)
+		}
+		for _, article := range articles {
+			if err := indexArticle(ctx, pipe, article); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store %d articles: %w", len(articles), err)
+	}
+	if r.urls != nil {
+		for _, article := range articles {
+			r.urls.Add(URLHash(article.URL))
+		}
+	}
+	return nil
+}
+
+// indexArticle queues the writes that store an article and add it to the URL,
+// category, source and score indexes
+func indexArticle(ctx context.Context, pipe redis.Pipeliner, article Article) error {
+	articleData, err := json.Marshal(article)
+	if err != nil {
+		return fmt.Errorf("failed to marshal article %s: %w", article.ID, err)
+	}
+
+	// Store individual article
+	pipe.Set(ctx, fmt.Sprintf("article:%s", article.ID), articleData, 24*time.Hour)
+
+	// Store in article list
+	pipe.SAdd(ctx, "articles:all", article.ID)
+
+	// Index by canonical URL for dedup
+	pipe.Set(ctx, fmt.Sprintf("articles:url:%s", URLHash(article.URL)), article.ID, 24*time.Hour)
+	pipe.Set(ctx, contentKeyPrefix+URLHash(article.URL), ArticleContentHash(article.Title, article.Description, article.URL), 24*time.Hour)
+
+	// Store by category
+	for _, category := range article.Category {
+		pipe.SAdd(ctx, fmt.Sprintf("articles:category:%s", strings.ToLower(category)), article.ID)
+	}
+
+	// Store by source
+	pipe.SAdd(ctx, fmt.Sprintf("articles:source:%s", strings.ToLower(article.SourceName)), article.ID)
+
+	// Store by score
+	pipe.ZAdd(ctx, "articles:by_score", redis.Z{
+		Score:  article.RelevanceScore,
+		Member: article.ID,
+	})
+	return nil
+}
+
+// unindexArticle queues the removal of an article from the URL, category,
+// source and score indexes. The article record itself is left for the caller
+// to overwrite or delete.
+func unindexArticle(ctx context.Context, pipe redis.Pipeliner, article Article) {
+	pipe.Del(ctx, fmt.Sprintf("articles:url:%s", URLHash(article.URL)), contentKeyPrefix+URLHash(article.URL))
+	for _, category := range article.Category {
+		pipe.SRem(ctx, fmt.Sprintf("articles:category:%s", strings.ToLower(category)), article.ID)
+	}
+	pipe.SRem(ctx, fmt.Sprintf("articles:source:%s", strings.ToLower(article.SourceName)), article.ID)
+	pipe.ZRem(ctx, "articles:by_score", article.ID)
+}
+
+// indexInMemory stores an article in the in-memory fallback. The caller holds articlesMu.
+func (r *repository) indexInMemory(article Article) {
+	if r.articles == nil {
+		r.articles = make(map[string]Article)
+	}
+	if r.byURL == nil {
+		r.byURL = make(map[string]string)
+	}
+	r.articles[article.ID] = article
+	r.byURL[URLHash(article.URL)] = article.ID
+}
+
+// inMemoryArticle returns the article stored in the in-memory fallback under id
+func (r *repository) inMemoryArticle(id string) (Article, bool) {
+	r.articlesMu.RLock()
+	defer r.articlesMu.RUnlock()
+	article, ok := r.articles[id]
+	return article, ok
+}
+
+// inMemoryArticles returns a copy of the articles in the in-memory fallback,
+// so callers can filter them without holding articlesMu
+func (r *repository) inMemoryArticles() []Article {
+	r.articlesMu.RLock()
+	defer r.articlesMu.RUnlock()
+	articles := make([]Article, 0, len(r.articles))
+	for _, article := range r.articles {
+		articles = append(articles, article)
+	}
+	return articles
+}
+
+// unindexInMemory removes an article from the in-memory URL index. The caller holds articlesMu.
+func (r *repository) unindexInMemory(article Article) {
+	if urlHash := URLHash(article.URL); r.byURL[urlHash] == article.ID {
+		delete(r.byURL, urlHash)
+	}
+}
+
+// claimURLs takes the URL keys of the articles a bulk create found no stored
+// article for, with SETNX so dedup doesn't depend on the URL filter or race
+// other instances. It returns the owners of the URLs that were already taken.
+// In memory the URL index is claimed the same way, under articlesMu.
+func (r *repository) claimURLs(ctx context.Context, hashes, existingIDs []string, batchIDs map[string]string) (map[string]string, error) {
+	if r.cache == nil {
+		r.articlesMu.Lock()
+		defer r.articlesMu.Unlock()
+		owners := make(map[string]string)
+		for i, hash := range hashes {
+			if existingIDs[i] != "" {
+				continue
+			}
+			if owner := r.byURL[hash]; owner != "" {
+				owners[hash] = owner
+			} else {
+				r.byURL[hash] = batchIDs[hash]
+			}
+		}
+		return owners, nil
+	}
+
+	var claims []string
+	seen := make(map[string]bool)
+	for i, hash := range hashes {
+		if existingIDs[i] == "" && !seen[hash] {
+			seen[hash] = true
+			claims = append(claims, hash)
+		}
+	}
+	if len(claims) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redis.BoolCmd, len(claims))
+	err := r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, hash := range claims {
+			cmds[i] = pipe.SetNX(ctx, urlKeyPrefix+hash, batchIDs[hash], 24*time.Hour)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim article URLs: %w", err)
+	}
+
+	var taken []string
+	for i, cmd := range cmds {
+		if !cmd.Val() {
+			taken = append(taken, claims[i])
+		}
+	}
+	if len(taken) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(taken))
+	for i, hash := range taken {
+		keys[i] = urlKeyPrefix + hash
+	}
+	values, err := r.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve article URLs: %w", err)
+	}
+	owners := make(map[string]string, len(taken))
+	for i, value := range values {
+		owners[taken[i]] = string(value)
+	}
+	return owners, nil
+}
+
+// articleIDsByURL resolves canonical URL hashes to stored article IDs; unknown
+// hashes map to "". Hashes the URL filter rules out aren't looked up, so an ID
+// may be missing for a URL another instance stored since the last rebuild;
+// writers must not rely on "" to create an article.
+func (r *repository) articleIDsByURL(ctx context.Context, hashes []string) ([]string, error) {
+	ids := make([]string, len(hashes))
+	if r.cache == nil {
+		r.articlesMu.RLock()
+		defer r.articlesMu.RUnlock()
+		for i, hash := range hashes {
+			ids[i] = r.byURL[hash]
+		}
+		return ids, nil
+	}
+
+	// Only look up the URLs the filter can't rule out
+	var keys []string
+	var positions []int
+	for i, hash := range hashes {
+		if r.urls != nil && !r.urls.MayContain(hash) {
+			continue
+		}
+		keys = append(keys, urlKeyPrefix+hash)
+		positions = append(positions, i)
+	}
+	if len(keys) == 0 {
+		return ids, nil
+	}
+	values, err := r.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve article URLs: %w", err)
+	}
+	for i, value := range values {
+		ids[positions[i]] = string(value)
+	}
+	return ids, nil
+}
+
+// articlesByID loads the stored articles for the non-empty IDs, skipping missing ones and repeats
+func (r *repository) articlesByID(ctx context.Context, ids []string) ([]Article, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	var articles []Article
+	if r.cache == nil {
+		for _, id := range unique {
+			if article, ok := r.inMemoryArticle(id); ok {
+				articles = append(articles, article)
+			}
+		}
+		return articles, nil
+	}
+
+	keys := make([]string, len(unique))
+	for i, id := range unique {
+		keys[i] = fmt.Sprintf("article:%s", id)
+	}
+	values, err := r.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing articles: %w", err)
+	}
+	for _, value := range values {
+		var article Article
+		if value != nil && json.Unmarshal(value, &article) == nil {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+// GetArticleVersions returns the stored version of the article under each
+// canonical URL hash, aligned with urlHashes
+func (r *repository) GetArticleVersions(ctx context.Context, urlHashes []string) ([]ArticleVersion, error) {
+	ids, err := r.articleIDsByURL(ctx, urlHashes)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]ArticleVersion, len(urlHashes))
+
+	if r.cache == nil {
+		for i, id := range ids {
+			if article, ok := r.inMemoryArticle(id); ok {
+				versions[i] = ArticleVersion{ID: id, ContentHash: ArticleContentHash(article.Title, article.Description, article.URL)}
+			}
+		}
+		return versions, nil
+	}
+
+	var keys []string
+	var positions []int
+	for i, id := range ids {
+		if id != "" {
+			keys = append(keys, contentKeyPrefix+urlHashes[i])
+			positions = append(positions, i)
+		}
+	}
+	if len(keys) == 0 {
+		return versions, nil
+	}
+	values, err := r.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load article content hashes: %w", err)
+	}
+	for i, value := range values {
+		versions[positions[i]] = ArticleVersion{ID: ids[positions[i]], ContentHash: string(value)}
+	}
+	return versions, nil
+}
+
+// articleIDByURL returns the ID of the article stored under a canonical URL hash, if any
+func (r *repository) articleIDByURL(ctx context.Context, urlHash string) string {
+	if r.cache != nil {
+		// The URL filter isn't consulted: a false negative would let an update
+		// take a URL another instance stored
+		id, err := r.cache.Get(ctx, fmt.Sprintf("articles:url:%s", urlHash))
+		if err != nil {
+			return ""
+		}
+		return string(id)
+	}
+	r.articlesMu.RLock()
+	defer r.articlesMu.RUnlock()
+	return r.byURL[urlHash]
 }
 
 // GetArticleByID retrieves an article by ID
 func (r *repository) GetArticleByID(ctx context.Context, id string) (Article, error) {
 	if r.cache != nil {
 		// Try Redis first
-		if articleData, err := r.cache.Get(ctx, fmt.Sprintf("article:%s", id)); err == nil {
+		if articleData, err := r.cache.GetHedged(ctx, fmt.Sprintf("article:%s", id)); err == nil {
 			var article Article
 			if err := json.Unmarshal(articleData, &article); err == nil {
 				return article, nil
@@ -246,15 +1292,15 @@ func (r *repository) GetArticleByID(ctx context.Context, id string) (Article, er
 	}
 	
 	// Fallback to in-memory
-	if r.articles != nil {
-		article, exists := r.articles[id]
+	if r.cache == nil {
+		article, exists := r.inMemoryArticle(id)
 		if !exists {
-			return Article{}, fmt.Errorf("article not found: %s", id)
+			return Article{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", id)
 		}
 		return article, nil
 	}
 	
-	return Article{}, fmt.Errorf("article not found: %s", id)
+	return Article{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", id)
 }
 
 // GetArticlesByCategory retrieves articles by category
@@ -266,32 +1312,39 @@ func (r *repository) GetArticlesByCategory(ctx context.Context, arg GetArticlesB
 		if err == nil && len(articleIDs) > 0 {
 			var articles []Article
 			for _, id := range articleIDs {
-				if article, err := r.GetArticleByID(ctx, id); err == nil {
+				if article, err := r.GetArticleByID(ctx, id); err == nil && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
 					articles = append(articles, article)
-					if len(articles) >= int(arg.Limit) {
-						break
-					}
 				}
 			}
-			return articles, nil
+			sortArticles(articles, byDate)
+			articles, err := filterByEnrichment(ctx, r, articles, arg.Sentiment, arg.Entities)
+			if err != nil {
+				return nil, err
+			}
+			return paginate(articles, arg.Offset, arg.Limit), nil
 		}
 	}
 	
 	// Fallback to in-memory
-	if r.articles != nil {
+	if r.cache == nil {
 		var results []Article
-		for _, article := range r.articles {
+		for _, article := range r.inMemoryArticles() {
+			if !matchesLocale(article, arg.Language, arg.Country) || !publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
+				continue
+			}
 			for _, category := range article.Category {
 				if strings.Contains(strings.ToLower(category), strings.ToLower(arg.Name)) {
 					results = append(results, article)
 					break
 				}
 			}
-			if len(results) >= int(arg.Limit) {
-				break
-			}
 		}
-		return results, nil
+		sortArticles(results, byDate)
+		results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+		if err != nil {
+			return nil, err
+		}
+		return paginate(results, arg.Offset, arg.Limit), nil
 	}
 	
 	return []Article{}, nil
@@ -306,29 +1359,33 @@ func (r *repository) GetArticlesBySource(ctx context.Context, arg GetArticlesByS
 		if err == nil && len(articleIDs) > 0 {
 			var articles []Article
 			for _, id := range articleIDs {
-				if article, err := r.GetArticleByID(ctx, id); err == nil {
+				if article, err := r.GetArticleByID(ctx, id); err == nil && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
 					articles = append(articles, article)
-					if len(articles) >= int(arg.Limit) {
-						break
-					}
 				}
 			}
-			return articles, nil
+			sortArticles(articles, byDate)
+			articles, err := filterByEnrichment(ctx, r, articles, arg.Sentiment, arg.Entities)
+			if err != nil {
+				return nil, err
+			}
+			return paginate(articles, arg.Offset, arg.Limit), nil
 		}
 	}
 	
 	// Fallback to in-memory
-	if r.articles != nil {
+	if r.cache == nil {
 		var results []Article
-		for _, article := range r.articles {
-			if strings.Contains(strings.ToLower(article.SourceName), strings.ToLower(arg.Name)) {
+		for _, article := range r.inMemoryArticles() {
+			if strings.Contains(strings.ToLower(article.SourceName), strings.ToLower(arg.Name)) && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
 				results = append(results, article)
-				if len(results) >= int(arg.Limit) {
-					break
-				}
 			}
 		}
-		return results, nil
+		sortArticles(results, byDate)
+		results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+		if err != nil {
+			return nil, err
+		}
+		return paginate(results, arg.Offset, arg.Limit), nil
 	}
 	
 	return []Article{}, nil
@@ -338,33 +1395,37 @@ func (r *repository) GetArticlesBySource(ctx context.Context, arg GetArticlesByS
 func (r *repository) GetArticlesByScore(ctx context.Context, arg GetArticlesByScoreParams) ([]Article, error) {
 	if r.cache != nil {
 		// Try Redis first
-		articleIDs, err := r.cache.ZRangeByScore(ctx, "articles:by_score", arg.Min, 1.0, int64(arg.Limit))
+		articleIDs, err := r.cache.ZRangeByScore(ctx, "articles:by_score", arg.Min, 1.0, 0)
 		if err == nil && len(articleIDs) > 0 {
 			var articles []Article
 			for _, id := range articleIDs {
-				if article, err := r.GetArticleByID(ctx, id); err == nil {
+				if article, err := r.GetArticleByID(ctx, id); err == nil && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) && (!arg.Unlocated || article.Latitude == nil || article.Longitude == nil) {
 					articles = append(articles, article)
-					if len(articles) >= int(arg.Limit) {
-						break
-					}
 				}
 			}
-			return articles, nil
+			sortArticles(articles, byScore)
+			articles, err := filterByEnrichment(ctx, r, articles, arg.Sentiment, arg.Entities)
+			if err != nil {
+				return nil, err
+			}
+			return paginate(articles, arg.Offset, arg.Limit), nil
 		}
 	}
 	
 	// Fallback to in-memory
-	if r.articles != nil {
+	if r.cache == nil {
 		var results []Article
-		for _, article := range r.articles {
-			if article.RelevanceScore >= arg.Min {
+		for _, article := range r.inMemoryArticles() {
+			if article.RelevanceScore >= arg.Min && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) && (!arg.Unlocated || article.Latitude == nil || article.Longitude == nil) {
 				results = append(results, article)
-				if len(results) >= int(arg.Limit) {
-					break
-				}
 			}
 		}
-		return results, nil
+		sortArticles(results, byScore)
+		results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+		if err != nil {
+			return nil, err
+		}
+		return paginate(results, arg.Offset, arg.Limit), nil
 	}
 	
 	return []Article{}, nil
@@ -380,7 +1441,7 @@ func (r *repository) SearchArticles(ctx context.Context, arg SearchArticlesParam
 			query := strings.ToLower(arg.Query)
 			
 			for _, id := range articleIDs {
-				if article, err := r.GetArticleByID(ctx, id); err == nil {
+				if article, err := r.GetArticleByID(ctx, id); err == nil && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
 					// Simple text search in title and description
 					titleMatch := strings.Contains(strings.ToLower(article.Title), query)
 					descMatch := false
@@ -403,23 +1464,27 @@ func (r *repository) SearchArticles(ctx context.Context, arg SearchArticlesParam
 							Article:    article,
 							SearchScore: score,
 						})
-						
-						if len(results) >= int(arg.Limit) {
-							break
-						}
 					}
 				}
 			}
-			return results, nil
+			sortSearchResults(results)
+			results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+			if err != nil {
+				return nil, err
+			}
+			return paginate(results, arg.Offset, arg.Limit), nil
 		}
 	}
 	
 	// Fallback to in-memory
-	if r.articles != nil {
+	if r.cache == nil {
 		var results []SearchArticlesRow
 		query := strings.ToLower(arg.Query)
 		
-		for _, article := range r.articles {
+		for _, article := range r.inMemoryArticles() {
+			if !matchesLocale(article, arg.Language, arg.Country) || !publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
+				continue
+			}
 			// Simple text search in title and description
 			titleMatch := strings.Contains(strings.ToLower(article.Title), query)
 			descMatch := false
@@ -442,13 +1507,14 @@ func (r *repository) SearchArticles(ctx context.Context, arg SearchArticlesParam
 					Article:    article,
 					SearchScore: score,
 				})
-				
-				if len(results) >= int(arg.Limit) {
-					break
-				}
 			}
 		}
-		return results, nil
+		sortSearchResults(results)
+		results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+		if err != nil {
+			return nil, err
+		}
+		return paginate(results, arg.Offset, arg.Limit), nil
 	}
 	
 	return []SearchArticlesRow{}, nil
@@ -470,16 +1536,14 @@ func (r *repository) GetNearbyArticles(ctx context.Context, arg GetNearbyArticle
 				}
 			}
 		}
-	} else if r.articles != nil {
+	} else {
 		// Fallback to in-memory
-		for _, article := range r.articles {
-			articles = append(articles, article)
-		}
+		articles = r.inMemoryArticles()
 	}
 	
 	// Process articles and calculate distances
 	for _, article := range articles {
-		if article.Latitude != nil && article.Longitude != nil {
+		if article.Latitude != nil && article.Longitude != nil && matchesLocale(article, arg.Language, arg.Country) && publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
 			// Calculate distance using Haversine formula
 			distance := haversineDistance(arg.Lat, arg.Lon, *article.Latitude, *article.Longitude)
 			
@@ -488,84 +1552,334 @@ func (r *repository) GetNearbyArticles(ctx context.Context, arg GetNearbyArticle
 					Article:        article,
 					DistanceMeters: distance * 1000, // Convert km to meters
 				})
-				
-				if len(results) >= int(arg.Limit) {
-					break
-				}
 			}
 		}
 	}
 	
 	// Sort by distance
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].DistanceMeters < results[j].DistanceMeters
+		if results[i].DistanceMeters != results[j].DistanceMeters {
+			return results[i].DistanceMeters < results[j].DistanceMeters
+		}
+		return results[i].ID < results[j].ID
 	})
 	
-	return results, nil
-}
-
-// GetRecentEventsByGeohash retrieves recent events for trending calculation
-func (r *repository) GetRecentEventsByGeohash(ctx context.Context, since time.Time) ([]GetRecentEventsByGeohashRow, error) {
-	// For now, return empty results
-	return []GetRecentEventsByGeohashRow{}, nil
+	results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(results, arg.Offset, arg.Limit), nil
 }
 
 // CreateArticleSummary creates or updates an article summary
 func (r *repository) CreateArticleSummary(ctx context.Context, arg CreateArticleSummaryParams) (ArticleSummary, error) {
 	summary := ArticleSummary{
-		ArticleID:   arg.ArticleID,
-		LLMSummary:  arg.LLMSummary,
-		Model:       arg.Model,
-		GeneratedAt: time.Now(),
+		ArticleID:      arg.ArticleID,
+		LLMSummary:     arg.LLMSummary,
+		Model:          arg.Model,
+		GeneratedAt:    time.Now(),
+		Sentiment:      arg.Sentiment,
+		SentimentScore: arg.SentimentScore,
+	}
+	if r.cache == nil {
+		r.summariesMu.Lock()
+		r.summaries[arg.ArticleID] = summary
+		r.summariesMu.Unlock()
+		return summary, nil
+	}
+	if err := r.cache.Set(ctx, summaryKey(arg.ArticleID), summary, 0); err != nil {
+		return ArticleSummary{}, fmt.Errorf("failed to store summary for %s: %w", arg.ArticleID, err)
 	}
 	return summary, nil
 }
 
 // GetArticleSummary retrieves an article summary
 func (r *repository) GetArticleSummary(ctx context.Context, articleID string) (ArticleSummary, error) {
-	return ArticleSummary{}, fmt.Errorf("not implemented")
-}
+	if r.cache == nil {
+		r.summariesMu.Lock()
+		summary, ok := r.summaries[articleID]
+		r.summariesMu.Unlock()
+		if ok {
+			return summary, nil
+		}
+		return ArticleSummary{}, errs.Errorf(errs.ErrNotFound, "summary not found: %s", articleID)
+	}
 
-// CreateUserEvent creates a user event
-func (r *repository) CreateUserEvent(ctx context.Context, arg CreateUserEventParams) (UserEvent, error) {
-	event := UserEvent{
-		ID:          r.nextID,
-		ArticleID:   arg.ArticleID,
-		Event:       arg.Event,
-		OccurredAt:  time.Now(),
-		UserLat:     arg.UserLat,
-		UserLon:     arg.UserLon,
+	data, err := r.cache.Get(ctx, summaryKey(articleID))
+	if err == cache.ErrKeyNotFound {
+		return ArticleSummary{}, errs.Errorf(errs.ErrNotFound, "summary not found: %s", articleID)
+	}
+	if err != nil {
+		return ArticleSummary{}, err
+	}
+	var summary ArticleSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return ArticleSummary{}, fmt.Errorf("failed to decode summary for %s: %w", articleID, err)
 	}
-	r.nextID++
-	return event, nil
+	return summary, nil
+}
+
+// summaryKey is the Redis key of an article's stored summary
+func summaryKey(articleID string) string {
+	return fmt.Sprintf("article_summary:%s", articleID)
 }
 
-// GetArticlesWithoutSummary retrieves articles without summaries
+// GetArticlesWithoutSummary retrieves the newest articles without summaries
 func (r *repository) GetArticlesWithoutSummary(ctx context.Context, limit int32) ([]Article, error) {
 	var results []Article
-	if r.cache != nil {
-		// Try Redis first
+	if r.cache == nil {
+		r.summariesMu.Lock()
+		for _, article := range r.inMemoryArticles() {
+			if _, ok := r.summaries[article.ID]; !ok {
+				results = append(results, article)
+			}
+		}
+		r.summariesMu.Unlock()
+	} else {
 		articleIDs, err := r.cache.SMembers(ctx, "articles:all")
-		if err == nil && len(articleIDs) > 0 {
-			for _, id := range articleIDs {
-				if article, err := r.GetArticleByID(ctx, id); err == nil {
+		if err != nil {
+			return nil, err
+		}
+		// Check summaries and read articles in chunks, one round trip each
+		const chunk = 500
+		for start := 0; start < len(articleIDs); start += chunk {
+			ids := articleIDs[start:min(start+chunk, len(articleIDs))]
+			keys := make([]string, len(ids))
+			for i, id := range ids {
+				keys[i] = summaryKey(id)
+			}
+			summaries, err := r.cache.MGet(ctx, keys...)
+			if err != nil {
+				return nil, err
+			}
+			keys = keys[:0]
+			for i, id := range ids {
+				if summaries[i] == nil {
+					keys = append(keys, fmt.Sprintf("article:%s", id))
+				}
+			}
+			values, err := r.cache.MGet(ctx, keys...)
+			if err != nil {
+				return nil, err
+			}
+			for _, data := range values {
+				var article Article
+				if data != nil && json.Unmarshal(data, &article) == nil {
 					results = append(results, article)
-					if len(results) >= int(limit) {
-						break
-					}
 				}
 			}
 		}
-	} else if r.articles != nil {
-		// Fallback to in-memory
-		for _, article := range r.articles {
-			results = append(results, article)
-			if len(results) >= int(limit) {
-				break
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].PublicationDate.After(results[j].PublicationDate)
+	})
+	if len(results) > int(limit) {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// GetArticlesByPlace returns the top articles placed in a country, region or
+// named city
+func (r *repository) GetArticlesByPlace(ctx context.Context, arg GetArticlesByPlaceParams) ([]Article, error) {
+	articles, err := r.ListArticles(ctx, ListArticlesParams{})
+	if err != nil {
+		return nil, err
+	}
+	var results []Article
+	for _, article := range articles {
+		if !matchesLocale(article, arg.Language, arg.Country) || !publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) || (arg.Region != "" && article.Region != arg.Region) {
+			continue
+		}
+		if arg.Place != "" && !strings.EqualFold(article.City, arg.Place) && !strings.EqualFold(article.Region, arg.Place) {
+			continue
+		}
+		results = append(results, article)
+	}
+	sortArticles(results, byScore)
+	results, err = filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(results, arg.Offset, arg.Limit), nil
+}
+
+// ListArticles pages through every article, newest first
+func (r *repository) ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error) {
+	var articles []Article
+	if r.cache != nil {
+		articleIDs, err := r.cache.SMembers(ctx, "articles:all")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list articles: %w", err)
+		}
+		for _, id := range articleIDs {
+			if article, err := r.GetArticleByID(ctx, id); err == nil {
+				articles = append(articles, article)
 			}
 		}
+	} else {
+		articles = r.inMemoryArticles()
 	}
-	return results, nil
+	sortArticles(articles, byDateKeyset)
+	if arg.BeforeDate != nil {
+		start := sort.Search(len(articles), func(i int) bool {
+			return articleBefore(articles[i], *arg.BeforeDate, arg.BeforeID)
+		})
+		articles = articles[start:]
+	}
+	return paginate(articles, 0, arg.Limit), nil
+}
+
+// articleBefore reports whether an article comes after the keyset position
+// (date, id) in publication_date DESC, id DESC order
+func articleBefore(article Article, date time.Time, id string) bool {
+	if !article.PublicationDate.Equal(date) {
+		return article.PublicationDate.Before(date)
+	}
+	return article.ID < id
+}
+
+// Orderings used by the list queries; they match the ORDER BY clauses in
+// queries.sql so both backends page through results the same way.
+const (
+	byDate       = iota // publication_date DESC, id
+	byScore             // relevance_score DESC, publication_date DESC, id
+	byDateKeyset        // publication_date DESC, id DESC
+)
+
+// sortArticles orders articles deterministically so offsets are stable between pages
+func sortArticles(articles []Article, order int) {
+	sort.Slice(articles, func(i, j int) bool {
+		a, b := articles[i], articles[j]
+		if order == byScore && a.RelevanceScore != b.RelevanceScore {
+			return a.RelevanceScore > b.RelevanceScore
+		}
+		if !a.PublicationDate.Equal(b.PublicationDate) {
+			return a.PublicationDate.After(b.PublicationDate)
+		}
+		if order == byDateKeyset {
+			return a.ID > b.ID
+		}
+		return a.ID < b.ID
+	})
+}
+
+// sortSearchResults orders search hits by score, then relevance, then recency
+func sortSearchResults(results []SearchArticlesRow) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.SearchScore != b.SearchScore {
+			return a.SearchScore > b.SearchScore
+		}
+		if a.RelevanceScore != b.RelevanceScore {
+			return a.RelevanceScore > b.RelevanceScore
+		}
+		if !a.PublicationDate.Equal(b.PublicationDate) {
+			return a.PublicationDate.After(b.PublicationDate)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// filterBySentiment keeps the items whose article's stored summary has the
+// given sentiment, where "" keeps every item
+func filterBySentiment[T interface{ articleID() string }](ctx context.Context, r *repository, items []T, sentiment string) ([]T, error) {
+	if sentiment == "" || len(items) == 0 {
+		return items, nil
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.articleID()
+	}
+	sentiments, err := r.summarySentiments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	filtered := items[:0]
+	for _, item := range items {
+		if sentiments[item.articleID()] == sentiment {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// filterByEnrichment applies the sentiment and entity filters of a list or
+// search, which both depend on data stored beside the articles
+func filterByEnrichment[T interface{ articleID() string }](ctx context.Context, r *repository, items []T, sentiment string, entities []string) ([]T, error) {
+	items, err := filterBySentiment(ctx, r, items, sentiment)
+	if err != nil {
+		return nil, err
+	}
+	return filterByEntities(ctx, r, items, entities)
+}
+
+// summarySentiments returns the sentiment stored with the summary of each of
+// the articles that has one, reading summaries from Redis in chunks
+func (r *repository) summarySentiments(ctx context.Context, articleIDs []string) (map[string]string, error) {
+	sentiments := make(map[string]string, len(articleIDs))
+	if r.cache == nil {
+		r.summariesMu.Lock()
+		defer r.summariesMu.Unlock()
+		for _, id := range articleIDs {
+			if summary, ok := r.summaries[id]; ok && summary.Sentiment != nil {
+				sentiments[id] = *summary.Sentiment
+			}
+		}
+		return sentiments, nil
+	}
+
+	const chunk = 500
+	for start := 0; start < len(articleIDs); start += chunk {
+		ids := articleIDs[start:min(start+chunk, len(articleIDs))]
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = summaryKey(id)
+		}
+		values, err := r.cache.MGet(ctx, keys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read summaries: %w", err)
+		}
+		for i, value := range values {
+			var summary ArticleSummary
+			if value != nil && json.Unmarshal(value, &summary) == nil && summary.Sentiment != nil {
+				sentiments[ids[i]] = *summary.Sentiment
+			}
+		}
+	}
+	return sentiments, nil
+}
+
+// articleID identifies the article, and the rows embedding it, to filterByEnrichment
+func (a Article) articleID() string {
+	return a.ID
+}
+
+// matchesLocale reports whether article passes the language and country
+// filters, where "" matches every article
+func matchesLocale(article Article, language, country string) bool {
+	return (language == "" || article.Language == language) && (country == "" || article.Country == country)
+}
+
+// publishedWithin reports whether article was published in [after, before);
+// a nil bound is open
+func publishedWithin(article Article, after, before *time.Time) bool {
+	return (after == nil || !article.PublicationDate.Before(*after)) && (before == nil || article.PublicationDate.Before(*before))
+}
+
+// paginate returns the page of items starting at offset, at most limit long
+func paginate[T any](items []T, offset, limit int32) []T {
+	if int(offset) >= len(items) {
+		return items[len(items):]
+	}
+	if offset > 0 {
+		items = items[offset:]
+	}
+	if limit > 0 && int(limit) < len(items) {
+		items = items[:limit]
+	}
+	return items
 }
 
 // haversineDistance calculates the distance between two points using the Haversine formula
@@ -0,0 +1,236 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/metrics"
+	"news-system/internal/repo/sqlcdb"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// rollupDelay is how long after an hour ends it is rolled up, leaving time
+// for events recorded at its very end to commit
+const rollupDelay = 5 * time.Minute
+
+// rollupInsertChunk bounds the rollup rows written per statement
+const rollupInsertChunk = 5000
+
+// EventDownsamplerOptions tunes an EventDownsampler
+type EventDownsamplerOptions struct {
+	// Precision is the geohash precision of the rollup tiles. Trending reads
+	// rollups at every precision up to this one, so it should be at least the
+	// finest trending precision; default 5.
+	Precision int
+	// RawRetention is how long raw events are kept; zero keeps them forever.
+	// Events are only deleted once they have been rolled up.
+	RawRetention time.Duration
+	// RollupRetention is how long hourly rollups are kept; zero keeps them forever
+	RollupRetention time.Duration
+}
+
+// EventDownsampler rolls each complete hour of raw user events up into one
+// row per article, tile and event type, holding the count and the centroid of
+// the readers' locations, then deletes raw events and rollups past their
+// retention. The watermark it advances after each hour tells the event
+// queries which hours to read from the rollups, so trending and event counts
+// keep working after the raw events are gone.
+type EventDownsampler struct {
+	q    *sqlcdb.Queries
+	opts EventDownsamplerOptions
+
+	ticker *time.Ticker
+	done   chan bool
+	wg     sync.WaitGroup
+}
+
+// NewEventDownsampler creates a downsampler for the user events in db
+func NewEventDownsampler(db *DB, opts EventDownsamplerOptions) *EventDownsampler {
+	if opts.Precision < 1 || opts.Precision > 12 {
+		opts.Precision = 5
+	}
+	return &EventDownsampler{
+		q:    sqlcdb.New(db),
+		opts: opts,
+		done: make(chan bool),
+	}
+}
+
+// Run rolls up every complete hour since the watermark, then prunes raw events
+// and rollups past their retention
+func (d *EventDownsampler) Run(ctx context.Context) error {
+	watermark, err := d.q.GetEventRollupWatermark(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// First run: start from the hour of the oldest event
+		first, err := d.q.GetFirstEventTime(ctx)
+		if err != nil {
+			return classifyPgError(fmt.Errorf("failed to find the oldest event: %w", err))
+		}
+		watermark = first.UTC().Truncate(time.Hour)
+	} else if err != nil {
+		return classifyPgError(fmt.Errorf("failed to read rollup watermark: %w", err))
+	}
+
+	hours := 0
+	for end := watermark.Add(time.Hour); !end.After(time.Now().Add(-rollupDelay)); end = end.Add(time.Hour) {
+		select {
+		case <-d.done:
+			return nil
+		default:
+		}
+		if err := d.rollUpHour(ctx, watermark); err != nil {
+			return err
+		}
+		if err := d.q.SetEventRollupWatermark(ctx, end); err != nil {
+			return classifyPgError(fmt.Errorf("failed to advance rollup watermark: %w", err))
+		}
+		watermark = end
+		hours++
+		metrics.EventRollupHours.Inc()
+	}
+	metrics.EventRollupWatermark.Set(float64(watermark.Unix()))
+
+	var rawDeleted, rollupsDeleted int64
+	if d.opts.RawRetention > 0 {
+		cutoff := time.Now().Add(-d.opts.RawRetention)
+		if cutoff.After(watermark) {
+			cutoff = watermark
+		}
+		if rawDeleted, err = d.q.DeleteEventsBefore(ctx, cutoff); err != nil {
+			return classifyPgError(fmt.Errorf("failed to delete raw events: %w", err))
+		}
+		metrics.EventsPruned.WithLabelValues("raw").Add(float64(rawDeleted))
+	}
+	if d.opts.RollupRetention > 0 {
+		if rollupsDeleted, err = d.q.DeleteEventRollupsBefore(ctx, time.Now().Add(-d.opts.RollupRetention)); err != nil {
+			return classifyPgError(fmt.Errorf("failed to delete event rollups: %w", err))
+		}
+		metrics.EventsPruned.WithLabelValues("rollup").Add(float64(rollupsDeleted))
+	}
+
+	if hours > 0 || rawDeleted > 0 || rollupsDeleted > 0 {
+		log.Info().
+			Int("hours", hours).
+			Time("rolled_up_to", watermark).
+			Int64("raw_deleted", rawDeleted).
+			Int64("rollups_deleted", rollupsDeleted).
+			Msg("Downsampled user events")
+	}
+	return nil
+}
+
+// rollupKey identifies one rollup row within an hour
+type rollupKey struct {
+	articleID string
+	geohash   string
+	event     string
+}
+
+// rollupSums accumulates one rollup row
+type rollupSums struct {
+	count          int64
+	latSum, lonSum float64
+}
+
+// rollUpHour replaces the rollups of the hour starting at bucket with ones
+// computed from its raw events
+func (d *EventDownsampler) rollUpHour(ctx context.Context, bucket time.Time) error {
+	events, err := d.q.GetEventsBetween(ctx, sqlcdb.GetEventsBetweenParams{
+		StartTime: bucket,
+		EndTime:   bucket.Add(time.Hour),
+	})
+	if err != nil {
+		return classifyPgError(fmt.Errorf("failed to read events from %s: %w", bucket.Format(time.RFC3339), err))
+	}
+
+	sums := make(map[rollupKey]*rollupSums)
+	for _, event := range events {
+		key := rollupKey{articleID: event.ArticleID, event: string(event.Event)}
+		located := event.UserLat != nil && event.UserLon != nil
+		if located {
+			key.geohash = cache.GenerateGeohash(*event.UserLat, *event.UserLon, d.opts.Precision)
+		}
+		sum, ok := sums[key]
+		if !ok {
+			sum = &rollupSums{}
+			sums[key] = sum
+		}
+		sum.count++
+		if located {
+			sum.latSum += *event.UserLat
+			sum.lonSum += *event.UserLon
+		}
+	}
+
+	// Rollups past the watermark are never read, so a run that stopped
+	// midway through this hour left nothing visible to clean up first
+	if err := d.q.DeleteEventRollupBucket(ctx, bucket); err != nil {
+		return classifyPgError(fmt.Errorf("failed to clear rollups for %s: %w", bucket.Format(time.RFC3339), err))
+	}
+
+	arg := sqlcdb.InsertEventRollupsParams{Bucket: bucket}
+	flush := func() error {
+		if len(arg.ArticleIds) == 0 {
+			return nil
+		}
+		if err := d.q.InsertEventRollups(ctx, arg); err != nil {
+			return classifyPgError(fmt.Errorf("failed to write rollups for %s: %w", bucket.Format(time.RFC3339), err))
+		}
+		arg = sqlcdb.InsertEventRollupsParams{Bucket: bucket}
+		return nil
+	}
+	for key, sum := range sums {
+		arg.ArticleIds = append(arg.ArticleIds, key.articleID)
+		arg.Geohashes = append(arg.Geohashes, key.geohash)
+		arg.Events = append(arg.Events, key.event)
+		arg.Counts = append(arg.Counts, sum.count)
+		arg.UserLats = append(arg.UserLats, sum.latSum/float64(sum.count))
+		arg.UserLons = append(arg.UserLons, sum.lonSum/float64(sum.count))
+		if len(arg.ArticleIds) == rollupInsertChunk {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// Start downsamples immediately and again once per interval
+func (d *EventDownsampler) Start(ctx context.Context, interval time.Duration) {
+	d.ticker = time.NewTicker(interval)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		if err := d.Run(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to downsample user events")
+		}
+		for {
+			select {
+			case <-d.ticker.C:
+				if err := d.Run(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to downsample user events")
+				}
+			case <-d.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the downsampler, waiting for the hour being rolled up to finish
+func (d *EventDownsampler) Stop() {
+	if d.ticker != nil {
+		d.ticker.Stop()
+	}
+	close(d.done)
+	d.wg.Wait()
+}
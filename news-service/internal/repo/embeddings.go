@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// embeddingChunk bounds the embeddings read from Redis per round trip
+const embeddingChunk = 500
+
+// embeddingKey is the Redis key of an article's stored embedding
+func embeddingKey(articleID string) string {
+	return fmt.Sprintf("article_embedding:%s", articleID)
+}
+
+// CreateArticleEmbeddings stores the embeddings, replacing earlier ones of the
+// same articles. Like summaries, they're kept until the article is deleted.
+func (r *repository) CreateArticleEmbeddings(ctx context.Context, args []CreateArticleEmbeddingParams) error {
+	if r.cache == nil {
+		r.embeddingsMu.Lock()
+		defer r.embeddingsMu.Unlock()
+		if r.embeddings == nil {
+			r.embeddings = make(map[string]ArticleEmbedding)
+		}
+		for _, arg := range args {
+			r.embeddings[arg.ArticleID] = ArticleEmbedding(arg)
+		}
+		return nil
+	}
+
+	err := r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, arg := range args {
+			data, err := json.Marshal(ArticleEmbedding(arg))
+			if err != nil {
+				return fmt.Errorf("failed to marshal embedding of %s: %w", arg.ArticleID, err)
+			}
+			pipe.Set(ctx, embeddingKey(arg.ArticleID), data, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store %d embeddings: %w", len(args), err)
+	}
+	return nil
+}
+
+// GetArticlesWithoutEmbedding returns the newest articles with no embedding
+// from arg.Model, or whose content changed since they were embedded
+func (r *repository) GetArticlesWithoutEmbedding(ctx context.Context, arg GetArticlesWithoutEmbeddingParams) ([]Article, error) {
+	current := func(article Article, embedding ArticleEmbedding) bool {
+		return embedding.Model == arg.Model && embedding.ContentHash == ArticleContentHash(article.Title, article.Description, article.URL)
+	}
+
+	var results []Article
+	if r.cache == nil {
+		r.embeddingsMu.Lock()
+		for _, article := range r.inMemoryArticles() {
+			if embedding, ok := r.embeddings[article.ID]; !ok || !current(article, embedding) {
+				results = append(results, article)
+			}
+		}
+		r.embeddingsMu.Unlock()
+	} else {
+		err := r.scanEmbeddings(ctx, func(article Article, embedding *ArticleEmbedding) {
+			if embedding == nil || !current(article, *embedding) {
+				results = append(results, article)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortArticles(results, byDate)
+	return paginate(results, 0, arg.Limit), nil
+}
+
+// SemanticSearchArticles ranks articles by the cosine similarity of their
+// embedding from arg.Model to arg.Embedding, comparing against every stored
+// embedding like the other searches of this backend
+func (r *repository) SemanticSearchArticles(ctx context.Context, arg SemanticSearchArticlesParams) ([]SemanticSearchArticlesRow, error) {
+	var results []SemanticSearchArticlesRow
+	match := func(article Article, embedding ArticleEmbedding) {
+		if embedding.Model != arg.Model || !matchesLocale(article, arg.Language, arg.Country) || !publishedWithin(article, arg.PublishedAfter, arg.PublishedBefore) {
+			return
+		}
+		if similarity := cosineSimilarity(arg.Embedding, embedding.Embedding); similarity >= arg.MinSimilarity {
+			results = append(results, SemanticSearchArticlesRow{Article: article, Similarity: similarity})
+		}
+	}
+
+	if r.cache == nil {
+		r.embeddingsMu.Lock()
+		for id, embedding := range r.embeddings {
+			if article, ok := r.inMemoryArticle(id); ok {
+				match(article, embedding)
+			}
+		}
+		r.embeddingsMu.Unlock()
+	} else {
+		err := r.scanEmbeddings(ctx, func(article Article, embedding *ArticleEmbedding) {
+			if embedding != nil {
+				match(article, *embedding)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Similarity != results[j].Similarity {
+			return results[i].Similarity > results[j].Similarity
+		}
+		return results[i].ID < results[j].ID
+	})
+	results, err := filterByEnrichment(ctx, r, results, arg.Sentiment, arg.Entities)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(results, arg.Offset, arg.Limit), nil
+}
+
+// scanEmbeddings calls fn with every stored article and its embedding, or nil
+// when it has none, reading both in chunks
+func (r *repository) scanEmbeddings(ctx context.Context, fn func(article Article, embedding *ArticleEmbedding)) error {
+	articleIDs, err := r.cache.SMembers(ctx, "articles:all")
+	if err != nil {
+		return fmt.Errorf("failed to list articles: %w", err)
+	}
+	for start := 0; start < len(articleIDs); start += embeddingChunk {
+		ids := articleIDs[start:min(start+embeddingChunk, len(articleIDs))]
+		keys := make([]string, 0, 2*len(ids))
+		for _, id := range ids {
+			keys = append(keys, fmt.Sprintf("article:%s", id), embeddingKey(id))
+		}
+		values, err := r.cache.MGet(ctx, keys...)
+		if err != nil {
+			return fmt.Errorf("failed to read article embeddings: %w", err)
+		}
+		for i := 0; i < len(values); i += 2 {
+			var article Article
+			if values[i] == nil || json.Unmarshal(values[i], &article) != nil {
+				continue
+			}
+			var embedding *ArticleEmbedding
+			if values[i+1] != nil {
+				var stored ArticleEmbedding
+				if json.Unmarshal(values[i+1], &stored) == nil {
+					embedding = &stored
+				}
+			}
+			fn(article, embedding)
+		}
+	}
+	return nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 when
+// their lengths differ or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// vectorLiteral formats an embedding as a pgvector text literal, e.g. [0.1,0.2]
+func vectorLiteral(embedding []float32) string {
+	var b strings.Builder
+	b.Grow(len(embedding) * 10)
+	b.WriteByte('[')
+	for i, value := range embedding {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(value), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,256 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"news-system/internal/cache"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Entity types
+const (
+	EntityPerson       = "person"
+	EntityOrganization = "organization"
+	EntityLocation     = "location"
+)
+
+// ArticleEntity is a person, organization or location an article mentions
+type ArticleEntity struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// ReplaceArticleEntitiesParams stores the entities extracted from an
+// article's content, identified by its ArticleContentHash, replacing earlier
+// ones. Entities may be empty when the article mentions none.
+type ReplaceArticleEntitiesParams struct {
+	ArticleID   string
+	ContentHash string
+	Entities    []ArticleEntity
+}
+
+// storedEntities is an article's entities as kept by the Redis and in-memory
+// backends, with the content hash they were extracted from
+type storedEntities struct {
+	ContentHash string          `json:"content_hash"`
+	Entities    []ArticleEntity `json:"entities"`
+}
+
+// NormalizeEntity is the form entity names are matched in: lowercased, with
+// runs of whitespace collapsed to one space
+func NormalizeEntity(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// entitiesKey is the Redis key of an article's stored entities
+func entitiesKey(articleID string) string {
+	return fmt.Sprintf("article_entities:%s", articleID)
+}
+
+// entityIndexKey is the Redis set of the articles mentioning an entity, by its normalized name
+func entityIndexKey(normalized string) string {
+	return fmt.Sprintf("articles:entity:%s", normalized)
+}
+
+// normalizedNames returns the distinct normalized names of entities
+func normalizedNames(entities []ArticleEntity) []string {
+	var names []string
+	for _, entity := range entities {
+		if name := NormalizeEntity(entity.Name); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ReplaceArticleEntities stores the entities of each article, replacing the
+// earlier ones and their entries in the entity index. Like summaries, they're
+// kept until the article is deleted.
+func (r *repository) ReplaceArticleEntities(ctx context.Context, args []ReplaceArticleEntitiesParams) error {
+	if r.cache == nil {
+		r.entitiesMu.Lock()
+		defer r.entitiesMu.Unlock()
+		if r.entities == nil {
+			r.entities = make(map[string]storedEntities)
+		}
+		for _, arg := range args {
+			r.entities[arg.ArticleID] = storedEntities{ContentHash: arg.ContentHash, Entities: arg.Entities}
+		}
+		return nil
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = entitiesKey(arg.ArticleID)
+	}
+	previous, err := r.cache.MGet(ctx, keys...)
+	if err != nil {
+		return fmt.Errorf("failed to read previous entities: %w", err)
+	}
+
+	err = r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, arg := range args {
+			var stored storedEntities
+			if previous[i] != nil && json.Unmarshal(previous[i], &stored) == nil {
+				for _, name := range normalizedNames(stored.Entities) {
+					pipe.SRem(ctx, entityIndexKey(name), arg.ArticleID)
+				}
+			}
+			data, err := json.Marshal(storedEntities{ContentHash: arg.ContentHash, Entities: arg.Entities})
+			if err != nil {
+				return fmt.Errorf("failed to marshal entities of %s: %w", arg.ArticleID, err)
+			}
+			pipe.Set(ctx, keys[i], data, 0)
+			for _, name := range normalizedNames(arg.Entities) {
+				pipe.SAdd(ctx, entityIndexKey(name), arg.ArticleID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store entities of %d articles: %w", len(args), err)
+	}
+	return nil
+}
+
+// GetArticleEntities returns the entities stored for an article, by type and
+// name; none when it wasn't extracted yet
+func (r *repository) GetArticleEntities(ctx context.Context, articleID string) ([]ArticleEntity, error) {
+	var stored storedEntities
+	if r.cache == nil {
+		r.entitiesMu.Lock()
+		stored = r.entities[articleID]
+		r.entitiesMu.Unlock()
+	} else {
+		data, err := r.cache.Get(ctx, entitiesKey(articleID))
+		switch {
+		case err == cache.ErrKeyNotFound:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read entities of %s: %w", articleID, err)
+		default:
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return nil, fmt.Errorf("failed to decode entities of %s: %w", articleID, err)
+			}
+		}
+	}
+
+	entities := append([]ArticleEntity{}, stored.Entities...)
+	sort.Slice(entities, func(i, j int) bool {
+		if entities[i].Type != entities[j].Type {
+			return entities[i].Type < entities[j].Type
+		}
+		return entities[i].Name < entities[j].Name
+	})
+	return entities, nil
+}
+
+// GetArticlesWithoutEntities returns the newest articles whose entities were
+// never extracted, or whose content changed since they were
+func (r *repository) GetArticlesWithoutEntities(ctx context.Context, limit int32) ([]Article, error) {
+	current := func(article Article, stored storedEntities) bool {
+		return stored.ContentHash == ArticleContentHash(article.Title, article.Description, article.URL)
+	}
+
+	var results []Article
+	if r.cache == nil {
+		r.entitiesMu.Lock()
+		for _, article := range r.inMemoryArticles() {
+			if stored, ok := r.entities[article.ID]; !ok || !current(article, stored) {
+				results = append(results, article)
+			}
+		}
+		r.entitiesMu.Unlock()
+	} else {
+		articleIDs, err := r.cache.SMembers(ctx, "articles:all")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list articles: %w", err)
+		}
+		for start := 0; start < len(articleIDs); start += embeddingChunk {
+			ids := articleIDs[start:min(start+embeddingChunk, len(articleIDs))]
+			keys := make([]string, 0, 2*len(ids))
+			for _, id := range ids {
+				keys = append(keys, fmt.Sprintf("article:%s", id), entitiesKey(id))
+			}
+			values, err := r.cache.MGet(ctx, keys...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read article entities: %w", err)
+			}
+			for i := 0; i < len(values); i += 2 {
+				var article Article
+				if values[i] == nil || json.Unmarshal(values[i], &article) != nil {
+					continue
+				}
+				var stored storedEntities
+				if values[i+1] == nil || json.Unmarshal(values[i+1], &stored) != nil || !current(article, stored) {
+					results = append(results, article)
+				}
+			}
+		}
+	}
+
+	sortArticles(results, byDate)
+	return paginate(results, 0, limit), nil
+}
+
+// filterByEntities keeps the items whose article mentions every one of the
+// normalized entity names, where none keeps every item
+func filterByEntities[T interface{ articleID() string }](ctx context.Context, r *repository, items []T, entities []string) ([]T, error) {
+	if len(entities) == 0 || len(items) == 0 {
+		return items, nil
+	}
+
+	mentioning := make(map[string]bool)
+	if r.cache == nil {
+		r.entitiesMu.Lock()
+		for id, stored := range r.entities {
+			names := normalizedNames(stored.Entities)
+			if !slices.ContainsFunc(entities, func(entity string) bool { return !slices.Contains(names, entity) }) {
+				mentioning[id] = true
+			}
+		}
+		r.entitiesMu.Unlock()
+	} else {
+		keys := make([]string, len(entities))
+		for i, entity := range entities {
+			keys[i] = entityIndexKey(entity)
+		}
+		ids, err := r.cache.SInter(ctx, keys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entity index: %w", err)
+		}
+		for _, id := range ids {
+			mentioning[id] = true
+		}
+	}
+
+	filtered := items[:0]
+	for _, item := range items {
+		if mentioning[item.articleID()] {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// storedEntityNames returns the normalized names an article is indexed
+// under in Redis, none when its entities weren't extracted or can't be read
+func (r *repository) storedEntityNames(ctx context.Context, articleID string) []string {
+	data, err := r.cache.Get(ctx, entitiesKey(articleID))
+	if err != nil {
+		return nil
+	}
+	var stored storedEntities
+	if json.Unmarshal(data, &stored) != nil {
+		return nil
+	}
+	return normalizedNames(stored.Entities)
+}
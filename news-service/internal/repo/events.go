@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// userEventsKey is the Redis sorted set of user events, scored by the Unix
+// time they occurred at, and userEventIDKey the counter their IDs come from
+const (
+	userEventsKey  = "user_events"
+	userEventIDKey = "user_events:id"
+)
+
+// userEventLogSize is the most user events kept by the Redis and in-memory
+// backends; older ones are dropped as new ones arrive. They keep no hourly
+// rollups, so the trending windows can only reach as far back as these go.
+const userEventLogSize = 200000
+
+// CreateUserEvent records a user event. Events are indexed by the time
+// they occurred, for GetRecentEventsByGeohash and GetArticleEventCounts.
+func (r *repository) CreateUserEvent(ctx context.Context, arg CreateUserEventParams) (UserEvent, error) {
+	event := UserEvent{
+		ArticleID:  arg.ArticleID,
+		Event:      arg.Event,
+		OccurredAt: time.Now().UTC(),
+		UserLat:    arg.UserLat,
+		UserLon:    arg.UserLon,
+	}
+
+	if r.cache == nil {
+		r.eventsMu.Lock()
+		defer r.eventsMu.Unlock()
+		event.ID = r.nextEventID
+		r.nextEventID++
+		r.events = append(r.events, event)
+		if excess := len(r.events) - userEventLogSize; excess > 0 {
+			r.events = append(r.events[:0], r.events[excess:]...)
+		}
+		return event, nil
+	}
+
+	id, err := r.cache.Incr(ctx, userEventIDKey)
+	if err != nil {
+		return UserEvent{}, fmt.Errorf("failed to allocate user event ID: %w", err)
+	}
+	event.ID = id
+	data, err := json.Marshal(event)
+	if err != nil {
+		return UserEvent{}, fmt.Errorf("failed to marshal user event: %w", err)
+	}
+	err = r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, userEventsKey, redis.Z{Score: eventScore(event.OccurredAt), Member: data})
+		pipe.ZRemRangeByRank(ctx, userEventsKey, 0, -userEventLogSize-1)
+		return nil
+	})
+	if err != nil {
+		return UserEvent{}, fmt.Errorf("failed to store user event on %s: %w", arg.ArticleID, err)
+	}
+	return event, nil
+}
+
+// GetRecentEventsByGeohash returns the located events since since, newest
+// first, with the coordinates of their article, which the trending scorer
+// groups into geohash tiles. Events of articles without coordinates, or
+// no longer stored, are left out, like the Postgres join does.
+func (r *repository) GetRecentEventsByGeohash(ctx context.Context, since time.Time) ([]GetRecentEventsByGeohashRow, error) {
+	events, err := r.eventsSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var located []UserEvent
+	var articleIDs []string
+	for _, event := range events {
+		if event.UserLat != nil && event.UserLon != nil {
+			located = append(located, event)
+			articleIDs = append(articleIDs, event.ArticleID)
+		}
+	}
+	articles, err := r.articlesByID(ctx, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Article, len(articles))
+	for _, article := range articles {
+		if article.Latitude != nil && article.Longitude != nil {
+			byID[article.ID] = article
+		}
+	}
+
+	rows := make([]GetRecentEventsByGeohashRow, 0, len(located))
+	for i := len(located) - 1; i >= 0; i-- {
+		event := located[i]
+		article, ok := byID[event.ArticleID]
+		if !ok {
+			continue
+		}
+		rows = append(rows, GetRecentEventsByGeohashRow{
+			UserEvent: event,
+			Latitude:  article.Latitude,
+			Longitude: article.Longitude,
+			Count:     1,
+		})
+	}
+	return rows, nil
+}
+
+// GetArticleEventCounts counts an article's user events since arg.Since by type
+func (r *repository) GetArticleEventCounts(ctx context.Context, arg GetArticleEventCountsParams) ([]ArticleEventCount, error) {
+	events, err := r.eventsSince(ctx, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	var counts []ArticleEventCount
+	index := make(map[string]int)
+	for _, event := range events {
+		if event.ArticleID != arg.ArticleID {
+			continue
+		}
+		i, ok := index[event.Event]
+		if !ok {
+			i = len(counts)
+			index[event.Event] = i
+			counts = append(counts, ArticleEventCount{Event: event.Event})
+		}
+		counts[i].Count++
+	}
+	if counts == nil {
+		counts = []ArticleEventCount{}
+	}
+	return counts, nil
+}
+
+// eventsSince returns the user events that occurred at or after since, oldest first
+func (r *repository) eventsSince(ctx context.Context, since time.Time) ([]UserEvent, error) {
+	if r.cache == nil {
+		r.eventsMu.Lock()
+		defer r.eventsMu.Unlock()
+		var results []UserEvent
+		for _, event := range r.events {
+			if !event.OccurredAt.Before(since) {
+				results = append(results, event)
+			}
+		}
+		return results, nil
+	}
+
+	from := math.Inf(-1)
+	if !since.IsZero() {
+		from = eventScore(since)
+	}
+	members, err := r.cache.ZRangeByScore(ctx, userEventsKey, from, math.Inf(1), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user events: %w", err)
+	}
+	results := make([]UserEvent, 0, len(members))
+	for _, member := range members {
+		var event UserEvent
+		if err := json.Unmarshal([]byte(member), &event); err == nil {
+			results = append(results, event)
+		}
+	}
+	return results, nil
+}
+
+// deleteUserEvents removes the user events on a deleted article. It reads
+// all events to find them, which the rarity of deletions affords.
+func (r *repository) deleteUserEvents(ctx context.Context, articleID string) error {
+	members, err := r.cache.ZRangeByScore(ctx, userEventsKey, math.Inf(-1), math.Inf(1), 0)
+	if err != nil {
+		return fmt.Errorf("failed to read user events: %w", err)
+	}
+	var remove []interface{}
+	for _, member := range members {
+		var event UserEvent
+		if json.Unmarshal([]byte(member), &event) == nil && event.ArticleID == articleID {
+			remove = append(remove, member)
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+	if err := r.cache.ZRem(ctx, userEventsKey, remove...); err != nil {
+		return fmt.Errorf("failed to delete user events on %s: %w", articleID, err)
+	}
+	return nil
+}
+
+// deleteUserEventsInMemory removes the user events on a deleted article from memory
+func (r *repository) deleteUserEventsInMemory(articleID string) {
+	r.eventsMu.Lock()
+	defer r.eventsMu.Unlock()
+	kept := r.events[:0]
+	for _, event := range r.events {
+		if event.ArticleID != articleID {
+			kept = append(kept, event)
+		}
+	}
+	r.events = kept
+}
+
+// eventScore is the sorted set score of a user event that occurred at t
+func eventScore(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
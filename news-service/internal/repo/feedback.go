@@ -0,0 +1,206 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// feedbackKey is the Redis sorted set of all feedback, scored by the Unix
+// time it was given
+const feedbackKey = "feedback:all"
+
+// CreateFeedback stores feedback on an article
+func (r *repository) CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (Feedback, error) {
+	if _, err := r.GetArticleByID(ctx, arg.ArticleID); err != nil {
+		return Feedback{}, err
+	}
+	feedback := Feedback{
+		ID:           NewArticleID(),
+		ArticleID:    arg.ArticleID,
+		Target:       arg.Target,
+		Rating:       arg.Rating,
+		Query:        arg.Query,
+		Comment:      arg.Comment,
+		SummaryModel: arg.SummaryModel,
+		CreatedAt:    time.Now(),
+	}
+
+	if r.cache == nil {
+		r.feedbackMu.Lock()
+		r.feedback = append(r.feedback, feedback)
+		r.feedbackMu.Unlock()
+		return feedback, nil
+	}
+
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return Feedback{}, fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+	member := redis.Z{Score: float64(feedback.CreatedAt.UnixMicro()) / 1e6, Member: data}
+	if err := r.cache.ZAdd(ctx, feedbackKey, member); err != nil {
+		return Feedback{}, fmt.Errorf("failed to store feedback on %s: %w", arg.ArticleID, err)
+	}
+	return feedback, nil
+}
+
+// GetFeedbackStats counts the feedback given since since by target and summary model
+func (r *repository) GetFeedbackStats(ctx context.Context, since time.Time) ([]FeedbackStat, error) {
+	all, err := r.feedbackSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[[2]string]*FeedbackStat)
+	for _, feedback := range all {
+		model := ""
+		if feedback.SummaryModel != nil {
+			model = *feedback.SummaryModel
+		}
+		key := [2]string{feedback.Target, model}
+		stat, ok := counts[key]
+		if !ok {
+			stat = &FeedbackStat{Target: feedback.Target, Model: model}
+			counts[key] = stat
+		}
+		countRating(feedback.Rating, &stat.Up, &stat.Down)
+	}
+
+	stats := make([]FeedbackStat, 0, len(counts))
+	for _, stat := range counts {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Target != stats[j].Target {
+			return stats[i].Target < stats[j].Target
+		}
+		return stats[i].Model < stats[j].Model
+	})
+	return stats, nil
+}
+
+// GetSummariesWithNegativeFeedback returns the most disliked stored summaries,
+// counting only the feedback given since each was generated
+func (r *repository) GetSummariesWithNegativeFeedback(ctx context.Context, arg GetSummariesWithNegativeFeedbackParams) ([]SummaryFeedback, error) {
+	all, err := r.feedbackSince(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	byArticle := make(map[string][]Feedback)
+	for _, feedback := range all {
+		if feedback.Target == FeedbackTargetSummary {
+			byArticle[feedback.ArticleID] = append(byArticle[feedback.ArticleID], feedback)
+		}
+	}
+
+	var results []SummaryFeedback
+	for articleID, feedback := range byArticle {
+		summary, err := r.GetArticleSummary(ctx, articleID)
+		if err != nil {
+			// Feedback on a summary that was never stored has nothing to regenerate
+			continue
+		}
+		result := SummaryFeedback{ArticleID: articleID, Model: summary.Model, GeneratedAt: summary.GeneratedAt}
+		for _, f := range feedback {
+			if !f.CreatedAt.Before(summary.GeneratedAt) {
+				countRating(f.Rating, &result.Up, &result.Down)
+			}
+		}
+		if result.Down >= arg.MinDown && result.Down > 0 {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Down-a.Up != b.Down-b.Up {
+			return a.Down-a.Up > b.Down-b.Up
+		}
+		return a.ArticleID < b.ArticleID
+	})
+	return paginate(results, 0, arg.Limit), nil
+}
+
+// feedbackSince returns the feedback given since since, oldest first
+func (r *repository) feedbackSince(ctx context.Context, since time.Time) ([]Feedback, error) {
+	if r.cache == nil {
+		r.feedbackMu.Lock()
+		defer r.feedbackMu.Unlock()
+		var results []Feedback
+		for _, feedback := range r.feedback {
+			if !feedback.CreatedAt.Before(since) {
+				results = append(results, feedback)
+			}
+		}
+		return results, nil
+	}
+
+	from := math.Inf(-1)
+	if !since.IsZero() {
+		from = float64(since.UnixMicro()) / 1e6
+	}
+	members, err := r.cache.ZRangeByScore(ctx, feedbackKey, from, math.Inf(1), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+	results := make([]Feedback, 0, len(members))
+	for _, member := range members {
+		var feedback Feedback
+		if err := json.Unmarshal([]byte(member), &feedback); err == nil {
+			results = append(results, feedback)
+		}
+	}
+	return results, nil
+}
+
+// deleteFeedback removes the feedback on a deleted article. It reads all
+// feedback to find it, which the rarity of deletions affords.
+func (r *repository) deleteFeedback(ctx context.Context, articleID string) error {
+	members, err := r.cache.ZRangeByScore(ctx, feedbackKey, math.Inf(-1), math.Inf(1), 0)
+	if err != nil {
+		return fmt.Errorf("failed to read feedback: %w", err)
+	}
+	var remove []interface{}
+	for _, member := range members {
+		var feedback Feedback
+		if json.Unmarshal([]byte(member), &feedback) == nil && feedback.ArticleID == articleID {
+			remove = append(remove, member)
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+	if err := r.cache.ZRem(ctx, feedbackKey, remove...); err != nil {
+		return fmt.Errorf("failed to delete feedback on %s: %w", articleID, err)
+	}
+	return nil
+}
+
+// deleteFeedbackInMemory removes the feedback on a deleted article from memory
+func (r *repository) deleteFeedbackInMemory(articleID string) {
+	r.feedbackMu.Lock()
+	defer r.feedbackMu.Unlock()
+	kept := r.feedback[:0]
+	for _, feedback := range r.feedback {
+		if feedback.ArticleID != articleID {
+			kept = append(kept, feedback)
+		}
+	}
+	r.feedback = kept
+}
+
+// countRating adds one rating to the up or down count
+func countRating(rating string, up, down *int64) {
+	switch rating {
+	case FeedbackUp:
+		*up++
+	case FeedbackDown:
+		*down++
+	}
+}
@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"news-system/internal/repo/sqlcdb"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// partitionedTables are the Postgres tables partitioned by month
+var partitionedTables = []string{"articles", "user_events"}
+
+// PartitionMaintainer creates the monthly partitions of articles and
+// user_events ahead of time, so new rows land in a partition of their own
+// month rather than the default partition, where they would stop that
+// month's partition from ever being created.
+type PartitionMaintainer struct {
+	q           *sqlcdb.Queries
+	monthsAhead int
+
+	ticker *time.Ticker
+	done   chan bool
+	wg     sync.WaitGroup
+}
+
+// NewPartitionMaintainer creates a maintainer that keeps the current month
+// and the monthsAhead months after it partitioned
+func NewPartitionMaintainer(db *DB, monthsAhead int) *PartitionMaintainer {
+	if monthsAhead < 1 {
+		monthsAhead = 3
+	}
+	return &PartitionMaintainer{
+		q:           sqlcdb.New(db),
+		monthsAhead: monthsAhead,
+		done:        make(chan bool),
+	}
+}
+
+// EnsurePartitions creates any missing partitions from the current month
+// through monthsAhead months from now, returning how many it created
+func (m *PartitionMaintainer) EnsurePartitions(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	created := 0
+	for _, table := range partitionedTables {
+		for i := 0; i <= m.monthsAhead; i++ {
+			month := first.AddDate(0, i, 0)
+			ok, err := m.q.CreateMonthlyPartition(ctx, sqlcdb.CreateMonthlyPartitionParams{
+				Parent: table,
+				Month:  pgtype.Date{Time: month, Valid: true},
+			})
+			if err != nil {
+				return created, classifyPgError(fmt.Errorf("failed to create %s partition for %s: %w", table, month.Format("2006-01"), err))
+			}
+			if ok {
+				created++
+				log.Info().Str("table", table).Str("month", month.Format("2006-01")).Msg("Created partition")
+			}
+		}
+	}
+	return created, nil
+}
+
+// Start ensures partitions immediately and again once per interval
+func (m *PartitionMaintainer) Start(ctx context.Context, interval time.Duration) {
+	m.ticker = time.NewTicker(interval)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if _, err := m.EnsurePartitions(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to create partitions")
+		}
+		for {
+			select {
+			case <-m.ticker.C:
+				if _, err := m.EnsurePartitions(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to create partitions")
+				}
+			case <-m.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the maintainer and waits for a run in progress to finish
+func (m *PartitionMaintainer) Stop() {
+	if m.ticker != nil {
+		m.ticker.Stop()
+	}
+	close(m.done)
+	m.wg.Wait()
+}
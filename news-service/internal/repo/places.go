@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// SetArticlePlaceParams stores the place reverse geocoded from the article's
+// coordinates, Latitude and Longitude as they were read. Places may be ""
+// when the location has none, e.g. at sea; the coordinates are still recorded
+// as geocoded.
+type SetArticlePlaceParams struct {
+	City      string
+	Region    string
+	Country   string
+	ArticleID string
+	Latitude  float64
+	Longitude float64
+}
+
+// geocodedAt is the coordinates an article was last geocoded at, as kept by
+// the Redis and in-memory backends
+type geocodedAt struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// geocodeKey is the Redis key of the coordinates an article was geocoded at
+func geocodeKey(articleID string) string {
+	return fmt.Sprintf("article_geocode:%s", articleID)
+}
+
+// needsPlace reports whether article has coordinates but no place, and they
+// weren't geocoded yet at where it is now
+func needsPlace(article Article, geocoded *geocodedAt) bool {
+	if article.Latitude == nil || article.Longitude == nil || article.Country != "" {
+		return false
+	}
+	return geocoded == nil || geocoded.Latitude != *article.Latitude || geocoded.Longitude != *article.Longitude
+}
+
+// SetArticlePlaces stores the place of each article and records its
+// coordinates as geocoded. Articles deleted or moved since they were read are
+// skipped.
+func (r *repository) SetArticlePlaces(ctx context.Context, args []SetArticlePlaceParams) error {
+	var previous, articles []Article
+	for _, arg := range args {
+		article, err := r.GetArticleByID(ctx, arg.ArticleID)
+		if err != nil || article.Latitude == nil || article.Longitude == nil ||
+			*article.Latitude != arg.Latitude || *article.Longitude != arg.Longitude {
+			continue
+		}
+		previous = append(previous, article)
+		article.City, article.Region, article.Country = arg.City, arg.Region, arg.Country
+		articles = append(articles, article)
+	}
+	if len(articles) == 0 {
+		return nil
+	}
+	if err := r.saveArticles(ctx, previous, articles); err != nil {
+		return err
+	}
+
+	if r.cache == nil {
+		r.geocodesMu.Lock()
+		defer r.geocodesMu.Unlock()
+		if r.geocodes == nil {
+			r.geocodes = make(map[string]geocodedAt)
+		}
+		for _, article := range articles {
+			r.geocodes[article.ID] = geocodedAt{Latitude: *article.Latitude, Longitude: *article.Longitude}
+		}
+		return nil
+	}
+	err := r.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, article := range articles {
+			data, err := json.Marshal(geocodedAt{Latitude: *article.Latitude, Longitude: *article.Longitude})
+			if err != nil {
+				return fmt.Errorf("failed to marshal geocode of %s: %w", article.ID, err)
+			}
+			pipe.Set(ctx, geocodeKey(article.ID), data, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record geocodes of %d articles: %w", len(articles), err)
+	}
+	return nil
+}
+
+// GetArticlesWithoutPlace returns the newest articles with coordinates but no
+// place whose coordinates were never geocoded, or moved since they were
+func (r *repository) GetArticlesWithoutPlace(ctx context.Context, limit int32) ([]Article, error) {
+	var results []Article
+	if r.cache == nil {
+		r.geocodesMu.Lock()
+		for _, article := range r.inMemoryArticles() {
+			var geocoded *geocodedAt
+			if at, ok := r.geocodes[article.ID]; ok {
+				geocoded = &at
+			}
+			if needsPlace(article, geocoded) {
+				results = append(results, article)
+			}
+		}
+		r.geocodesMu.Unlock()
+	} else {
+		articleIDs, err := r.cache.SMembers(ctx, "articles:all")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list articles: %w", err)
+		}
+		for start := 0; start < len(articleIDs); start += embeddingChunk {
+			ids := articleIDs[start:min(start+embeddingChunk, len(articleIDs))]
+			keys := make([]string, 0, 2*len(ids))
+			for _, id := range ids {
+				keys = append(keys, fmt.Sprintf("article:%s", id), geocodeKey(id))
+			}
+			values, err := r.cache.MGet(ctx, keys...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read article geocodes: %w", err)
+			}
+			for i := 0; i < len(values); i += 2 {
+				var article Article
+				if values[i] == nil || json.Unmarshal(values[i], &article) != nil {
+					continue
+				}
+				var geocoded *geocodedAt
+				if values[i+1] != nil {
+					var at geocodedAt
+					if json.Unmarshal(values[i+1], &at) == nil {
+						geocoded = &at
+					}
+				}
+				if needsPlace(article, geocoded) {
+					results = append(results, article)
+				}
+			}
+		}
+	}
+
+	sortArticles(results, byDate)
+	return paginate(results, 0, limit), nil
+}
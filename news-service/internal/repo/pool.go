@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB satisfies sqlcdb.DBTX itself rather than handing the pool to sqlc directly,
+// so that every query checks its connection out through acquire.
+
+// acquire checks a connection out of the pool. With an AcquireTimeout set, a
+// saturated pool fails fast with ErrPoolExhausted instead of queueing the
+// request until its own deadline expires.
+func (db *DB) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if db.acquireTimeout <= 0 {
+		return db.pool.Acquire(ctx)
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, db.acquireTimeout)
+	defer cancel()
+
+	conn, err := db.pool.Acquire(acquireCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(acquireCtx.Err(), context.DeadlineExceeded) {
+		stat := db.pool.Stat()
+		return nil, fmt.Errorf("%w: no connection free after %s (%d/%d in use)",
+			ErrPoolExhausted, db.acquireTimeout, stat.AcquiredConns(), stat.MaxConns())
+	}
+	return conn, err
+}
+
+// Exec runs a statement on a pooled connection
+func (db *DB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer conn.Release()
+
+	return conn.Exec(ctx, sql, args...)
+}
+
+// Query runs a query on a pooled connection, which is released when the rows are closed
+func (db *DB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return &pooledRows{Rows: rows, conn: conn}, nil
+}
+
+// QueryRow runs a single-row query on a pooled connection, which is released after Scan
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return &pooledRow{row: conn.QueryRow(ctx, sql, args...), conn: conn}
+}
+
+// SendBatch pipelines a batch on a pooled connection, which is released when the results are closed
+func (db *DB) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return errBatchResults{err: err}
+	}
+	return &pooledBatchResults{BatchResults: conn.SendBatch(ctx, batch), conn: conn}
+}
+
+// pooledRows releases its connection once the result set is exhausted or closed
+type pooledRows struct {
+	pgx.Rows
+	conn *pgxpool.Conn
+}
+
+func (r *pooledRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r *pooledRows) Close() {
+	r.Rows.Close()
+	if r.conn != nil {
+		r.conn.Release()
+		r.conn = nil
+	}
+}
+
+// pooledRow releases its connection after the row has been scanned
+type pooledRow struct {
+	row  pgx.Row
+	conn *pgxpool.Conn
+}
+
+func (r *pooledRow) Scan(dest ...interface{}) error {
+	defer r.conn.Release()
+	return r.row.Scan(dest...)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// pooledBatchResults releases its connection once the batch results are closed
+type pooledBatchResults struct {
+	pgx.BatchResults
+	conn *pgxpool.Conn
+}
+
+func (r *pooledBatchResults) Close() error {
+	err := r.BatchResults.Close()
+	if r.conn != nil {
+		r.conn.Release()
+		r.conn = nil
+	}
+	return err
+}
+
+// errBatchResults fails every result of a batch that could not be sent
+type errBatchResults struct {
+	err error
+}
+
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, r.err }
+func (r errBatchResults) Query() (pgx.Rows, error)         { return nil, r.err }
+func (r errBatchResults) QueryRow() pgx.Row                { return errRow{err: r.err} }
+func (r errBatchResults) Close() error                     { return r.err }
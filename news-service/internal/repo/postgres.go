@@ -0,0 +1,814 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/repo/sqlcdb"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// postgresRepository implements Repository on top of the sqlc-generated
+// queries in sqlcdb (see queries.sql and sqlc.yaml; regenerate with
+// `sqlc generate`). pgx prepares and caches each statement per connection.
+type postgresRepository struct {
+	q *sqlcdb.Queries
+}
+
+// NewPostgresRepository creates a Repository backed by Postgres
+func NewPostgresRepository(db *DB) Repository {
+	return &postgresRepository{q: sqlcdb.New(db)}
+}
+
+// The generated article rows share Article's layout, so they convert directly
+func articlesFromRows[T sqlcdb.GetArticleByIDRow | sqlcdb.GetArticlesByCategoryRow | sqlcdb.GetArticlesBySourceRow |
+	sqlcdb.GetArticlesByScoreRow | sqlcdb.GetArticlesByPlaceRow | sqlcdb.GetArticlesWithoutSummaryRow | sqlcdb.GetArticlesWithoutEmbeddingRow |
+	sqlcdb.GetArticlesWithoutEntitiesRow | sqlcdb.GetArticlesWithoutPlaceRow | sqlcdb.ListArticlesRow](rows []T) []Article {
+	articles := make([]Article, len(rows))
+	for i, row := range rows {
+		articles[i] = Article(sqlcdb.GetArticleByIDRow(row))
+	}
+	return articles
+}
+
+// classifyPgError tags Postgres failures with their domain error kind
+func classifyPgError(err error) error {
+	var pgErr *pgconn.PgError
+	var connectErr *pgconn.ConnectError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23505": // unique_violation
+		return errs.Wrap(errs.ErrConflict, err)
+	case errors.As(err, &pgErr) && pgErr.Code == "23503": // foreign_key_violation
+		return errs.Wrap(errs.ErrNotFound, err)
+	case errors.As(err, &pgErr) && pgErr.Code == "22P02": // invalid_text_representation, e.g. a malformed UUID
+		return errs.Wrap(errs.ErrInvalid, err)
+	case errors.As(err, &connectErr), pgconn.Timeout(err), errors.Is(err, ErrPoolExhausted):
+		return errs.Wrap(errs.ErrUnavailable, err)
+	}
+	return err
+}
+
+func summaryFromRow(row sqlcdb.ArticleSummary) ArticleSummary {
+	return ArticleSummary{
+		ArticleID:      row.ArticleID,
+		LLMSummary:     row.LlmSummary,
+		Model:          row.Model,
+		GeneratedAt:    row.GeneratedAt,
+		Sentiment:      row.Sentiment,
+		SentimentScore: row.SentimentScore,
+	}
+}
+
+// CreateArticle creates an article, or updates the one with the same canonical URL
+func (r *postgresRepository) CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error) {
+	row, err := r.q.CreateArticle(ctx, sqlcdb.CreateArticleParams{
+		ID:              arg.ID,
+		Title:           arg.Title,
+		Description:     arg.Description,
+		URL:             arg.URL,
+		PublicationDate: arg.PublicationDate,
+		SourceName:      arg.SourceName,
+		Category:        arg.Category,
+		RelevanceScore:  arg.RelevanceScore,
+		Latitude:        arg.Latitude,
+		Longitude:       arg.Longitude,
+		URLHash:         URLHash(arg.URL),
+		ContentHash:     ArticleContentHash(arg.Title, arg.Description, arg.URL),
+		Language:        arg.Language,
+		City:            arg.City,
+		Region:          arg.Region,
+		Country:         arg.Country,
+	})
+	if err != nil {
+		return Article{}, classifyPgError(fmt.Errorf("failed to create article: %w", err))
+	}
+	return Article(row), nil
+}
+
+// bulkCreateChunkSize bounds how many upserts share one pipelined batch
+const bulkCreateChunkSize = 250
+
+// BulkCreateArticles creates or updates many articles, pipelining the upserts
+// in chunks. A batch runs as one implicit transaction, so a failing article
+// aborts its whole chunk; that chunk is then retried one article at a time to
+// find out which articles actually failed.
+func (r *postgresRepository) BulkCreateArticles(ctx context.Context, args []CreateArticleParams) ([]BulkCreateResult, error) {
+	results := make([]BulkCreateResult, len(args))
+	for start := 0; start < len(args); start += bulkCreateChunkSize {
+		end := min(start+bulkCreateChunkSize, len(args))
+		if err := r.bulkCreateChunk(ctx, args[start:end], results[start:end]); err != nil {
+			if ctx.Err() != nil {
+				return nil, classifyPgError(fmt.Errorf("failed to create articles: %w", err))
+			}
+			for i := start; i < end; i++ {
+				results[i].Article, results[i].Err = r.CreateArticle(ctx, args[i])
+			}
+		}
+	}
+	return results, nil
+}
+
+func (r *postgresRepository) bulkCreateChunk(ctx context.Context, args []CreateArticleParams, results []BulkCreateResult) error {
+	params := make([]sqlcdb.BulkCreateArticlesParams, len(args))
+	for i, arg := range args {
+		params[i] = sqlcdb.BulkCreateArticlesParams{
+			ID:              arg.ID,
+			Title:           arg.Title,
+			Description:     arg.Description,
+			URL:             arg.URL,
+			PublicationDate: arg.PublicationDate,
+			SourceName:      arg.SourceName,
+			Category:        arg.Category,
+			RelevanceScore:  arg.RelevanceScore,
+			Latitude:        arg.Latitude,
+			Longitude:       arg.Longitude,
+			URLHash:         URLHash(arg.URL),
+			ContentHash:     ArticleContentHash(arg.Title, arg.Description, arg.URL),
+			Language:        arg.Language,
+			City:            arg.City,
+			Region:          arg.Region,
+			Country:         arg.Country,
+		}
+	}
+
+	var batchErr error
+	br := r.q.BulkCreateArticles(ctx, params)
+	br.QueryRow(func(i int, row sqlcdb.BulkCreateArticlesRow, err error) {
+		if err != nil {
+			if batchErr == nil {
+				batchErr = err
+			}
+			return
+		}
+		results[i].Article = Article(row)
+	})
+	if err := br.Close(); batchErr == nil {
+		batchErr = err
+	}
+	return batchErr
+}
+
+// UpdateArticle replaces the fields of an existing article
+func (r *postgresRepository) UpdateArticle(ctx context.Context, arg UpdateArticleParams) (Article, error) {
+	row, err := r.q.UpdateArticle(ctx, sqlcdb.UpdateArticleParams{
+		Title:           arg.Title,
+		Description:     arg.Description,
+		URL:             arg.URL,
+		PublicationDate: arg.PublicationDate,
+		SourceName:      arg.SourceName,
+		Category:        arg.Category,
+		RelevanceScore:  arg.RelevanceScore,
+		Latitude:        arg.Latitude,
+		Longitude:       arg.Longitude,
+		URLHash:         URLHash(arg.URL),
+		ContentHash:     ArticleContentHash(arg.Title, arg.Description, arg.URL),
+		Language:        arg.Language,
+		City:            arg.City,
+		Region:          arg.Region,
+		Country:         arg.Country,
+		ID:              arg.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Article{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", arg.ID)
+	}
+	if err != nil {
+		return Article{}, classifyPgError(fmt.Errorf("failed to update article %s: %w", arg.ID, err))
+	}
+	return Article(row), nil
+}
+
+// DeleteArticle removes an article together with its summary and user events
+func (r *postgresRepository) DeleteArticle(ctx context.Context, id string) error {
+	deleted, err := r.q.DeleteArticle(ctx, id)
+	if err != nil {
+		return classifyPgError(fmt.Errorf("failed to delete article %s: %w", id, err))
+	}
+	if deleted == 0 {
+		return errs.Errorf(errs.ErrNotFound, "article not found: %s", id)
+	}
+	return nil
+}
+
+// GetArticleByID retrieves an article by ID
+func (r *postgresRepository) GetArticleByID(ctx context.Context, id string) (Article, error) {
+	row, err := r.q.GetArticleByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Article{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", id)
+	}
+	if err != nil {
+		return Article{}, classifyPgError(fmt.Errorf("failed to get article %s: %w", id, err))
+	}
+	return Article(row), nil
+}
+
+// GetArticlesByCategory retrieves articles by category
+func (r *postgresRepository) GetArticlesByCategory(ctx context.Context, arg GetArticlesByCategoryParams) ([]Article, error) {
+	rows, err := r.q.GetArticlesByCategory(ctx, sqlcdb.GetArticlesByCategoryParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// GetArticlesBySource retrieves articles by source
+func (r *postgresRepository) GetArticlesBySource(ctx context.Context, arg GetArticlesBySourceParams) ([]Article, error) {
+	rows, err := r.q.GetArticlesBySource(ctx, sqlcdb.GetArticlesBySourceParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// GetArticlesByScore retrieves articles by minimum score
+func (r *postgresRepository) GetArticlesByScore(ctx context.Context, arg GetArticlesByScoreParams) ([]Article, error) {
+	rows, err := r.q.GetArticlesByScore(ctx, sqlcdb.GetArticlesByScoreParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// GetArticlesByPlace returns the top articles placed in a country, region or
+// named city
+func (r *postgresRepository) GetArticlesByPlace(ctx context.Context, arg GetArticlesByPlaceParams) ([]Article, error) {
+	rows, err := r.q.GetArticlesByPlace(ctx, sqlcdb.GetArticlesByPlaceParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// SearchArticles performs full-text search
+func (r *postgresRepository) SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error) {
+	rows, err := r.q.SearchArticles(ctx, sqlcdb.SearchArticlesParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+
+	results := make([]SearchArticlesRow, len(rows))
+	for i, row := range rows {
+		results[i] = SearchArticlesRow{
+			Article: Article{
+				ID: row.ID, Title: row.Title, Description: row.Description, URL: row.URL,
+				PublicationDate: row.PublicationDate, SourceName: row.SourceName, Category: row.Category,
+				RelevanceScore: row.RelevanceScore, Latitude: row.Latitude, Longitude: row.Longitude,
+				Language: row.Language, City: row.City, Region: row.Region, Country: row.Country,
+			},
+			SearchScore: row.SearchScore,
+		}
+	}
+	return results, nil
+}
+
+// GetNearbyArticles retrieves articles within a specified radius
+func (r *postgresRepository) GetNearbyArticles(ctx context.Context, arg GetNearbyArticlesParams) ([]GetNearbyArticlesRow, error) {
+	rows, err := r.q.GetNearbyArticles(ctx, sqlcdb.GetNearbyArticlesParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+
+	results := make([]GetNearbyArticlesRow, len(rows))
+	for i, row := range rows {
+		results[i] = GetNearbyArticlesRow{
+			Article: Article{
+				ID: row.ID, Title: row.Title, Description: row.Description, URL: row.URL,
+				PublicationDate: row.PublicationDate, SourceName: row.SourceName, Category: row.Category,
+				RelevanceScore: row.RelevanceScore, Latitude: row.Latitude, Longitude: row.Longitude,
+				Language: row.Language, City: row.City, Region: row.Region, Country: row.Country,
+			},
+			DistanceMeters: row.DistanceMeters,
+		}
+	}
+	return results, nil
+}
+
+// GetRecentEventsByGeohash retrieves recent events for trending calculation,
+// from the hourly rollups where they have been rolled up
+func (r *postgresRepository) GetRecentEventsByGeohash(ctx context.Context, since time.Time) ([]GetRecentEventsByGeohashRow, error) {
+	rows, err := r.q.GetRecentEventsByGeohash(ctx, since)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+
+	results := make([]GetRecentEventsByGeohashRow, len(rows))
+	for i, row := range rows {
+		results[i] = GetRecentEventsByGeohashRow{
+			UserEvent: UserEvent{
+				ID:         row.ID,
+				ArticleID:  row.ArticleID,
+				Event:      string(row.Event),
+				OccurredAt: row.OccurredAt,
+				UserLat:    row.UserLat,
+				UserLon:    row.UserLon,
+			},
+			Latitude:  row.Latitude,
+			Longitude: row.Longitude,
+			Count:     row.Count,
+		}
+	}
+	return results, nil
+}
+
+// CreateArticleSummary creates or updates an article summary
+func (r *postgresRepository) CreateArticleSummary(ctx context.Context, arg CreateArticleSummaryParams) (ArticleSummary, error) {
+	row, err := r.q.CreateArticleSummary(ctx, sqlcdb.CreateArticleSummaryParams{
+		ArticleID:      arg.ArticleID,
+		LlmSummary:     arg.LLMSummary,
+		Model:          arg.Model,
+		Sentiment:      arg.Sentiment,
+		SentimentScore: arg.SentimentScore,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ArticleSummary{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", arg.ArticleID)
+	}
+	if err != nil {
+		return ArticleSummary{}, classifyPgError(fmt.Errorf("failed to create summary for %s: %w", arg.ArticleID, err))
+	}
+	return summaryFromRow(row), nil
+}
+
+// GetArticleSummary retrieves an article summary
+func (r *postgresRepository) GetArticleSummary(ctx context.Context, articleID string) (ArticleSummary, error) {
+	row, err := r.q.GetArticleSummary(ctx, articleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ArticleSummary{}, errs.Errorf(errs.ErrNotFound, "summary not found: %s", articleID)
+	}
+	if err != nil {
+		return ArticleSummary{}, classifyPgError(fmt.Errorf("failed to get summary for %s: %w", articleID, err))
+	}
+	return summaryFromRow(row), nil
+}
+
+// CreateUserEvent creates a user event
+func (r *postgresRepository) CreateUserEvent(ctx context.Context, arg CreateUserEventParams) (UserEvent, error) {
+	row, err := r.q.CreateUserEvent(ctx, sqlcdb.CreateUserEventParams{
+		ArticleID: arg.ArticleID,
+		Event:     arg.Event,
+		UserLat:   arg.UserLat,
+		UserLon:   arg.UserLon,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return UserEvent{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", arg.ArticleID)
+	}
+	if err != nil {
+		return UserEvent{}, classifyPgError(fmt.Errorf("failed to create user event: %w", err))
+	}
+	return UserEvent{
+		ID:         row.ID,
+		ArticleID:  row.ArticleID,
+		Event:      string(row.Event),
+		OccurredAt: row.OccurredAt,
+		UserLat:    row.UserLat,
+		UserLon:    row.UserLon,
+	}, nil
+}
+
+// GetArticlesWithoutSummary retrieves articles without summaries
+func (r *postgresRepository) GetArticlesWithoutSummary(ctx context.Context, limit int32) ([]Article, error) {
+	rows, err := r.q.GetArticlesWithoutSummary(ctx, limit)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// GetArticleEventCounts counts an article's user events since arg.Since by type
+func (r *postgresRepository) GetArticleEventCounts(ctx context.Context, arg GetArticleEventCountsParams) ([]ArticleEventCount, error) {
+	rows, err := r.q.GetArticleEventCounts(ctx, sqlcdb.GetArticleEventCountsParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+
+	counts := make([]ArticleEventCount, len(rows))
+	for i, row := range rows {
+		counts[i] = ArticleEventCount{Event: string(row.Event), Count: row.Count}
+	}
+	return counts, nil
+}
+
+// ListArticles pages through every article, newest first
+func (r *postgresRepository) ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error) {
+	params := sqlcdb.ListArticlesParams{BeforeDate: arg.BeforeDate, Limit: arg.Limit}
+	// An empty id can't be sent as a uuid, and the first page doesn't need one.
+	if arg.BeforeDate != nil {
+		params.BeforeID = &arg.BeforeID
+	}
+	rows, err := r.q.ListArticles(ctx, params)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// GetArticleVersions returns the stored version of the article under each
+// canonical URL hash, aligned with urlHashes
+func (r *postgresRepository) GetArticleVersions(ctx context.Context, urlHashes []string) ([]ArticleVersion, error) {
+	rows, err := r.q.GetArticleVersions(ctx, urlHashes)
+	if err != nil {
+		return nil, classifyPgError(fmt.Errorf("failed to load article versions: %w", err))
+	}
+	byURL := make(map[string]ArticleVersion, len(rows))
+	for _, row := range rows {
+		byURL[row.URLHash] = ArticleVersion{ID: row.ID, ContentHash: row.ContentHash}
+	}
+
+	versions := make([]ArticleVersion, len(urlHashes))
+	for i, urlHash := range urlHashes {
+		versions[i] = byURL[urlHash]
+	}
+	return versions, nil
+}
+
+// CreateArticleEmbeddings stores the embeddings, replacing earlier ones of the
+// same articles, one statement per model
+func (r *postgresRepository) CreateArticleEmbeddings(ctx context.Context, args []CreateArticleEmbeddingParams) error {
+	byModel := make(map[string]*sqlcdb.CreateArticleEmbeddingsParams)
+	var models []string
+	for _, arg := range args {
+		params, ok := byModel[arg.Model]
+		if !ok {
+			params = &sqlcdb.CreateArticleEmbeddingsParams{Model: arg.Model}
+			byModel[arg.Model] = params
+			models = append(models, arg.Model)
+		}
+		params.ArticleIds = append(params.ArticleIds, arg.ArticleID)
+		params.ContentHashes = append(params.ContentHashes, arg.ContentHash)
+		params.Embeddings = append(params.Embeddings, vectorLiteral(arg.Embedding))
+	}
+	for _, model := range models {
+		if err := r.q.CreateArticleEmbeddings(ctx, *byModel[model]); err != nil {
+			return classifyPgError(fmt.Errorf("failed to store %d embeddings: %w", len(byModel[model].ArticleIds), err))
+		}
+	}
+	return nil
+}
+
+// GetArticlesWithoutEmbedding returns the newest articles with no embedding
+// from arg.Model, or whose content changed since they were embedded
+func (r *postgresRepository) GetArticlesWithoutEmbedding(ctx context.Context, arg GetArticlesWithoutEmbeddingParams) ([]Article, error) {
+	rows, err := r.q.GetArticlesWithoutEmbedding(ctx, sqlcdb.GetArticlesWithoutEmbeddingParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// SemanticSearchArticles ranks articles by the cosine similarity of their
+// embedding from arg.Model to arg.Embedding
+func (r *postgresRepository) SemanticSearchArticles(ctx context.Context, arg SemanticSearchArticlesParams) ([]SemanticSearchArticlesRow, error) {
+	rows, err := r.q.SemanticSearchArticles(ctx, sqlcdb.SemanticSearchArticlesParams{
+		Embedding:       vectorLiteral(arg.Embedding),
+		Model:           arg.Model,
+		Language:        arg.Language,
+		Country:         arg.Country,
+		PublishedAfter:  arg.PublishedAfter,
+		PublishedBefore: arg.PublishedBefore,
+		Sentiment:       arg.Sentiment,
+		Entities:        arg.Entities,
+		MinSimilarity:   arg.MinSimilarity,
+		Limit:           arg.Limit,
+		Offset:          arg.Offset,
+	})
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+
+	results := make([]SemanticSearchArticlesRow, len(rows))
+	for i, row := range rows {
+		results[i] = SemanticSearchArticlesRow{
+			Article: Article{
+				ID: row.ID, Title: row.Title, Description: row.Description, URL: row.URL,
+				PublicationDate: row.PublicationDate, SourceName: row.SourceName, Category: row.Category,
+				RelevanceScore: row.RelevanceScore, Latitude: row.Latitude, Longitude: row.Longitude,
+				Language: row.Language, City: row.City, Region: row.Region, Country: row.Country,
+			},
+			Similarity: row.Similarity,
+		}
+	}
+	return results, nil
+}
+
+// ReplaceArticleEntities stores the entities of each article, replacing the
+// earlier ones, one statement per article
+func (r *postgresRepository) ReplaceArticleEntities(ctx context.Context, args []ReplaceArticleEntitiesParams) error {
+	for _, arg := range args {
+		params := sqlcdb.ReplaceArticleEntitiesParams{
+			ArticleID:   arg.ArticleID,
+			ContentHash: arg.ContentHash,
+			EntityTypes: []string{},
+			Normalized:  []string{},
+			Names:       []string{},
+		}
+		seen := make(map[ArticleEntity]bool)
+		for _, entity := range arg.Entities {
+			normalized := NormalizeEntity(entity.Name)
+			key := ArticleEntity{Type: entity.Type, Name: normalized}
+			if normalized == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			params.EntityTypes = append(params.EntityTypes, entity.Type)
+			params.Normalized = append(params.Normalized, normalized)
+			params.Names = append(params.Names, entity.Name)
+		}
+		if err := r.q.ReplaceArticleEntities(ctx, params); err != nil {
+			return classifyPgError(fmt.Errorf("failed to store entities of %s: %w", arg.ArticleID, err))
+		}
+	}
+	return nil
+}
+
+// GetArticleEntities returns the entities stored for an article, by type and name
+func (r *postgresRepository) GetArticleEntities(ctx context.Context, articleID string) ([]ArticleEntity, error) {
+	rows, err := r.q.GetArticleEntities(ctx, articleID)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	entities := make([]ArticleEntity, len(rows))
+	for i, row := range rows {
+		entities[i] = ArticleEntity{Type: row.EntityType, Name: row.Name}
+	}
+	return entities, nil
+}
+
+// GetArticlesWithoutEntities returns the newest articles whose entities were
+// never extracted, or whose content changed since they were
+func (r *postgresRepository) GetArticlesWithoutEntities(ctx context.Context, limit int32) ([]Article, error) {
+	rows, err := r.q.GetArticlesWithoutEntities(ctx, limit)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// SetArticlePlaces stores the place of each article and records its
+// coordinates as geocoded, one statement per article
+func (r *postgresRepository) SetArticlePlaces(ctx context.Context, args []SetArticlePlaceParams) error {
+	for _, arg := range args {
+		if err := r.q.SetArticlePlace(ctx, sqlcdb.SetArticlePlaceParams(arg)); err != nil {
+			return classifyPgError(fmt.Errorf("failed to store place of %s: %w", arg.ArticleID, err))
+		}
+	}
+	return nil
+}
+
+// GetArticlesWithoutPlace returns the newest articles with coordinates but no
+// place whose coordinates were never geocoded, or moved since they were
+func (r *postgresRepository) GetArticlesWithoutPlace(ctx context.Context, limit int32) ([]Article, error) {
+	rows, err := r.q.GetArticlesWithoutPlace(ctx, limit)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return articlesFromRows(rows), nil
+}
+
+// CreateFeedback stores feedback on an article
+func (r *postgresRepository) CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (Feedback, error) {
+	row, err := r.q.CreateFeedback(ctx, sqlcdb.CreateFeedbackParams(arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Feedback{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", arg.ArticleID)
+	}
+	if err != nil {
+		return Feedback{}, classifyPgError(fmt.Errorf("failed to store feedback on %s: %w", arg.ArticleID, err))
+	}
+	return Feedback(row), nil
+}
+
+// GetFeedbackStats counts the feedback given since since by target and summary model
+func (r *postgresRepository) GetFeedbackStats(ctx context.Context, since time.Time) ([]FeedbackStat, error) {
+	rows, err := r.q.GetFeedbackStats(ctx, since)
+	if err != nil {
+		return nil, classifyPgError(fmt.Errorf("failed to count feedback: %w", err))
+	}
+	stats := make([]FeedbackStat, len(rows))
+	for i, row := range rows {
+		stats[i] = FeedbackStat(row)
+	}
+	return stats, nil
+}
+
+// GetSummariesWithNegativeFeedback returns the most disliked stored summaries
+func (r *postgresRepository) GetSummariesWithNegativeFeedback(ctx context.Context, arg GetSummariesWithNegativeFeedbackParams) ([]SummaryFeedback, error) {
+	rows, err := r.q.GetSummariesWithNegativeFeedback(ctx, sqlcdb.GetSummariesWithNegativeFeedbackParams(arg))
+	if err != nil {
+		return nil, classifyPgError(fmt.Errorf("failed to rank summaries by feedback: %w", err))
+	}
+	summaries := make([]SummaryFeedback, len(rows))
+	for i, row := range rows {
+		summaries[i] = SummaryFeedback(row)
+	}
+	return summaries, nil
+}
+
+// subscriptionsFromRows converts generated subscription rows
+func subscriptionsFromRows(rows []sqlcdb.Subscription) []Subscription {
+	subscriptions := make([]Subscription, len(rows))
+	for i, row := range rows {
+		subscriptions[i] = Subscription(row)
+	}
+	return subscriptions
+}
+
+// CreateSubscription stores a new subscription
+func (r *postgresRepository) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error) {
+	row, err := r.q.CreateSubscription(ctx, sqlcdb.CreateSubscriptionParams(arg))
+	if err != nil {
+		return Subscription{}, classifyPgError(fmt.Errorf("failed to store subscription: %w", err))
+	}
+	return Subscription(row), nil
+}
+
+// GetSubscription retrieves a subscription by ID
+func (r *postgresRepository) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	row, err := r.q.GetSubscription(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+	}
+	if err != nil {
+		return Subscription{}, classifyPgError(fmt.Errorf("failed to get subscription %s: %w", id, err))
+	}
+	return Subscription(row), nil
+}
+
+// ListSubscriptions returns an owner's subscriptions, oldest first
+func (r *postgresRepository) ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error) {
+	rows, err := r.q.ListSubscriptions(ctx, sqlcdb.ListSubscriptionsParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return subscriptionsFromRows(rows), nil
+}
+
+// UpdateSubscription replaces a subscription's fields
+func (r *postgresRepository) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (Subscription, error) {
+	row, err := r.q.UpdateSubscription(ctx, sqlcdb.UpdateSubscriptionParams(arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", arg.ID)
+	}
+	if err != nil {
+		return Subscription{}, classifyPgError(fmt.Errorf("failed to update subscription %s: %w", arg.ID, err))
+	}
+	return Subscription(row), nil
+}
+
+// DeleteSubscription deletes a subscription and its delivery history
+func (r *postgresRepository) DeleteSubscription(ctx context.Context, id string) error {
+	deleted, err := r.q.DeleteSubscription(ctx, id)
+	if err != nil {
+		return classifyPgError(fmt.Errorf("failed to delete subscription %s: %w", id, err))
+	}
+	if deleted == 0 {
+		return errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+	}
+	return nil
+}
+
+// GetDueSubscriptions returns the verified, enabled digests due at
+// arg.Before, most overdue first
+func (r *postgresRepository) GetDueSubscriptions(ctx context.Context, arg GetDueSubscriptionsParams) ([]Subscription, error) {
+	rows, err := r.q.GetDueSubscriptions(ctx, sqlcdb.GetDueSubscriptionsParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return subscriptionsFromRows(rows), nil
+}
+
+// GetAlertSubscriptions returns the verified, enabled alerts
+func (r *postgresRepository) GetAlertSubscriptions(ctx context.Context) ([]Subscription, error) {
+	rows, err := r.q.GetAlertSubscriptions(ctx)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	return subscriptionsFromRows(rows), nil
+}
+
+// ClaimSubscriptionRun moves a digest's next run from arg.Due to arg.Next,
+// reporting false when another instance already did
+func (r *postgresRepository) ClaimSubscriptionRun(ctx context.Context, arg ClaimSubscriptionRunParams) (bool, error) {
+	claimed, err := r.q.ClaimSubscriptionRun(ctx, sqlcdb.ClaimSubscriptionRunParams(arg))
+	if err != nil {
+		return false, classifyPgError(fmt.Errorf("failed to claim run of subscription %s: %w", arg.ID, err))
+	}
+	return claimed > 0, nil
+}
+
+// CreateSubscriptionDelivery records a message sent for a subscription
+func (r *postgresRepository) CreateSubscriptionDelivery(ctx context.Context, arg CreateSubscriptionDeliveryParams) (SubscriptionDelivery, error) {
+	row, err := r.q.CreateSubscriptionDelivery(ctx, sqlcdb.CreateSubscriptionDeliveryParams(arg))
+	if err != nil {
+		return SubscriptionDelivery{}, classifyPgError(fmt.Errorf("failed to store delivery of %s: %w", arg.SubscriptionID, err))
+	}
+	return SubscriptionDelivery(row), nil
+}
+
+// RecordSubscriptionFailure counts a failed message, disabling the
+// subscription with arg.Reason once arg.Threshold failed in a row
+func (r *postgresRepository) RecordSubscriptionFailure(ctx context.Context, arg RecordSubscriptionFailureParams) (Subscription, error) {
+	row, err := r.q.RecordSubscriptionFailure(ctx, sqlcdb.RecordSubscriptionFailureParams(arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", arg.ID)
+	}
+	if err != nil {
+		return Subscription{}, classifyPgError(fmt.Errorf("failed to record failure of subscription %s: %w", arg.ID, err))
+	}
+	return Subscription(row), nil
+}
+
+// ResetSubscriptionFailures clears the failure count after a message was delivered
+func (r *postgresRepository) ResetSubscriptionFailures(ctx context.Context, id string) error {
+	if err := r.q.ResetSubscriptionFailures(ctx, id); err != nil {
+		return classifyPgError(fmt.Errorf("failed to reset failures of subscription %s: %w", id, err))
+	}
+	return nil
+}
+
+// EnableSubscription clears a subscription's failures and disabled state
+func (r *postgresRepository) EnableSubscription(ctx context.Context, id string) (Subscription, error) {
+	row, err := r.q.EnableSubscription(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+	}
+	if err != nil {
+		return Subscription{}, classifyPgError(fmt.Errorf("failed to enable subscription %s: %w", id, err))
+	}
+	return Subscription(row), nil
+}
+
+// GetNotificationTemplate retrieves an owner's template
+func (r *postgresRepository) GetNotificationTemplate(ctx context.Context, arg GetNotificationTemplateParams) (NotificationTemplate, error) {
+	row, err := r.q.GetNotificationTemplate(ctx, sqlcdb.GetNotificationTemplateParams(arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotificationTemplate{}, errs.Errorf(errs.ErrNotFound, "notification template not found: %s", arg.Name)
+	}
+	if err != nil {
+		return NotificationTemplate{}, classifyPgError(fmt.Errorf("failed to get notification template %s: %w", arg.Name, err))
+	}
+	return NotificationTemplate(row), nil
+}
+
+// ListNotificationTemplates returns an owner's templates by name
+func (r *postgresRepository) ListNotificationTemplates(ctx context.Context, owner string) ([]NotificationTemplate, error) {
+	rows, err := r.q.ListNotificationTemplates(ctx, owner)
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	templates := make([]NotificationTemplate, len(rows))
+	for i, row := range rows {
+		templates[i] = NotificationTemplate(row)
+	}
+	return templates, nil
+}
+
+// UpsertNotificationTemplate stores an owner's template, replacing any
+// stored under its name
+func (r *postgresRepository) UpsertNotificationTemplate(ctx context.Context, arg UpsertNotificationTemplateParams) (NotificationTemplate, error) {
+	row, err := r.q.UpsertNotificationTemplate(ctx, sqlcdb.UpsertNotificationTemplateParams(arg))
+	if err != nil {
+		return NotificationTemplate{}, classifyPgError(fmt.Errorf("failed to store notification template %s: %w", arg.Name, err))
+	}
+	return NotificationTemplate(row), nil
+}
+
+// DeleteNotificationTemplate deletes an owner's template
+func (r *postgresRepository) DeleteNotificationTemplate(ctx context.Context, arg DeleteNotificationTemplateParams) error {
+	deleted, err := r.q.DeleteNotificationTemplate(ctx, sqlcdb.DeleteNotificationTemplateParams(arg))
+	if err != nil {
+		return classifyPgError(fmt.Errorf("failed to delete notification template %s: %w", arg.Name, err))
+	}
+	if deleted == 0 {
+		return errs.Errorf(errs.ErrNotFound, "notification template not found: %s", arg.Name)
+	}
+	return nil
+}
+
+// ListSubscriptionDeliveries returns a subscription's deliveries, newest first
+func (r *postgresRepository) ListSubscriptionDeliveries(ctx context.Context, arg ListSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	rows, err := r.q.ListSubscriptionDeliveries(ctx, sqlcdb.ListSubscriptionDeliveriesParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	deliveries := make([]SubscriptionDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = SubscriptionDelivery(row)
+	}
+	return deliveries, nil
+}
+
+// SearchSubscriptionDeliveries returns the deliveries matching arg across
+// subscriptions, newest first
+func (r *postgresRepository) SearchSubscriptionDeliveries(ctx context.Context, arg SearchSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	rows, err := r.q.SearchSubscriptionDeliveries(ctx, sqlcdb.SearchSubscriptionDeliveriesParams(arg))
+	if err != nil {
+		return nil, classifyPgError(err)
+	}
+	deliveries := make([]SubscriptionDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = SubscriptionDelivery(row)
+	}
+	return deliveries, nil
+}
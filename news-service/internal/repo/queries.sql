@@ -1,107 +1,852 @@
 -- name: CreateArticle :one
-INSERT INTO articles (
-    id, title, description, url, publication_date, source_name, 
-    category, relevance_score, latitude, longitude
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
-) ON CONFLICT (id) DO UPDATE SET
-    title = EXCLUDED.title,
-    description = EXCLUDED.description,
-    url = EXCLUDED.url,
-    publication_date = EXCLUDED.publication_date,
-    source_name = EXCLUDED.source_name,
-    category = EXCLUDED.category,
-    relevance_score = EXCLUDED.relevance_score,
-    latitude = EXCLUDED.latitude,
-    longitude = EXCLUDED.longitude
-RETURNING *;
+-- Upserts by canonical URL: re-ingesting a known article updates it in place
+-- and keeps its original id. article_urls holds the URL's unique claim, since
+-- a unique index on the partitioned articles table would have to include
+-- publication_date; an update that changes publication_date moves the row to
+-- its new partition.
+WITH claimed AS (
+    INSERT INTO article_urls (article_id, url_hash)
+    VALUES (COALESCE(NULLIF(sqlc.arg(id)::text, '')::uuid, gen_random_uuid()), sqlc.arg(url_hash)::text)
+    ON CONFLICT (url_hash) DO UPDATE SET url_hash = EXCLUDED.url_hash
+    RETURNING article_id
+), updated AS (
+    UPDATE articles a SET
+        title = sqlc.arg(title),
+        description = sqlc.narg(description),
+        url = sqlc.arg(url),
+        publication_date = sqlc.arg(publication_date),
+        source_name = sqlc.arg(source_name),
+        category = sqlc.arg(category),
+        relevance_score = sqlc.arg(relevance_score),
+        latitude = sqlc.narg(latitude),
+        longitude = sqlc.narg(longitude),
+        url_hash = sqlc.arg(url_hash)::text,
+        content_hash = sqlc.arg(content_hash)::text,
+        language = sqlc.arg(language)::text,
+        city = sqlc.arg(city)::text,
+        region = sqlc.arg(region)::text,
+        country = sqlc.arg(country)::text
+    FROM claimed
+    WHERE a.id = claimed.article_id
+    RETURNING a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+        a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country
+), inserted AS (
+    INSERT INTO articles (
+        id, title, description, url, publication_date, source_name,
+        category, relevance_score, latitude, longitude, url_hash, content_hash, language,
+        city, region, country
+    )
+    SELECT claimed.article_id,
+        sqlc.arg(title), sqlc.narg(description), sqlc.arg(url), sqlc.arg(publication_date),
+        sqlc.arg(source_name), sqlc.arg(category), sqlc.arg(relevance_score),
+        sqlc.narg(latitude), sqlc.narg(longitude), sqlc.arg(url_hash)::text,
+        sqlc.arg(content_hash)::text, sqlc.arg(language)::text,
+        sqlc.arg(city)::text, sqlc.arg(region)::text, sqlc.arg(country)::text
+    FROM claimed
+    WHERE NOT EXISTS (SELECT 1 FROM updated)
+    RETURNING id, title, description, url, publication_date, source_name,
+        category, relevance_score, latitude, longitude, language, city, region, country
+)
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM updated
+UNION ALL
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM inserted;
 
 -- name: GetArticleByID :one
-SELECT * FROM articles WHERE id = $1;
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM articles WHERE id = $1;
 
 -- name: GetArticlesByCategory :many
-SELECT * FROM articles 
-WHERE $1 = ANY(category)
-ORDER BY publication_date DESC
-LIMIT $2;
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM articles 
+WHERE EXISTS (SELECT 1 FROM unnest(category) c WHERE lower(c) = lower(sqlc.arg(name)::text))
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (sqlc.arg(country)::text = '' OR country = sqlc.arg(country)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = articles.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = articles.id AND m.normalized = f.name
+        )
+    )
+ORDER BY publication_date DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetArticlesBySource :many
-SELECT * FROM articles 
-WHERE source_name = $1
-ORDER BY publication_date DESC
-LIMIT $2;
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM articles 
+WHERE lower(source_name) = lower(sqlc.arg(name)::text)
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (sqlc.arg(country)::text = '' OR country = sqlc.arg(country)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = articles.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = articles.id AND m.normalized = f.name
+        )
+    )
+ORDER BY publication_date DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetArticlesByScore :many
-SELECT * FROM articles 
-WHERE relevance_score >= $1
-ORDER BY relevance_score DESC, publication_date DESC
-LIMIT $2;
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM articles 
+WHERE relevance_score >= sqlc.arg(min)::float8
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (sqlc.arg(country)::text = '' OR country = sqlc.arg(country)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = articles.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = articles.id AND m.normalized = f.name
+        )
+    )
+    AND (NOT sqlc.arg(unlocated)::bool OR latitude IS NULL OR longitude IS NULL)
+ORDER BY relevance_score DESC, publication_date DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetArticlesByPlace :many
+-- The top articles placed in a country, or in one of its regions when region
+-- is given, by relevance then recency. place matches a city or region name
+-- case-insensitively, for queries such as "news in Berlin"; every filter is
+-- skipped when "".
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM articles
+WHERE (sqlc.arg(country)::text = '' OR country = sqlc.arg(country)::text)
+    AND (sqlc.arg(region)::text = '' OR region = sqlc.arg(region)::text)
+    AND (sqlc.arg(place)::text = ''
+        OR lower(city) = lower(sqlc.arg(place)::text)
+        OR lower(region) = lower(sqlc.arg(place)::text))
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = articles.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = articles.id AND m.normalized = f.name
+        )
+    )
+ORDER BY relevance_score DESC, publication_date DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: SearchArticles :many
+-- Ranked full-text search over the weighted title/description tsvector
+-- (GIN-indexed). websearch_to_tsquery accepts "quoted phrases", OR and -term;
+-- rank normalization 32 maps ts_rank into [0, 1) so it can be used as SearchScore.
 SELECT 
-    *,
-    (0.6 * ts_rank(tsv, plainto_tsquery('english', $1)) + 0.4 * relevance_score) as search_score
-FROM articles 
-WHERE tsv @@ plainto_tsquery('english', $1)
-ORDER BY search_score DESC, publication_date DESC
-LIMIT $2;
+    id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country,
+    ts_rank(tsv, q, 32)::float8 AS search_score
+FROM articles, websearch_to_tsquery('english', sqlc.arg(query)::text) AS q
+WHERE tsv @@ q
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (sqlc.arg(country)::text = '' OR country = sqlc.arg(country)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = articles.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = articles.id AND m.normalized = f.name
+        )
+    )
+ORDER BY search_score DESC, relevance_score DESC, publication_date DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetNearbyArticles :many
 SELECT 
-    *,
+    id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country,
     earth_distance(
-        ll_to_earth($1, $2), 
+        ll_to_earth(sqlc.arg(lat)::float8, sqlc.arg(lon)::float8), 
         ll_to_earth(latitude, longitude)
-    ) as distance_meters
+    )::float8 AS distance_meters
 FROM articles 
 WHERE latitude IS NOT NULL 
     AND longitude IS NOT NULL
+    AND earth_box(ll_to_earth(sqlc.arg(lat)::float8, sqlc.arg(lon)::float8), sqlc.arg(radius)::float8 * 1000)
+        @> ll_to_earth(latitude, longitude)
     AND earth_distance(
-        ll_to_earth($1, $2), 
+        ll_to_earth(sqlc.arg(lat)::float8, sqlc.arg(lon)::float8), 
         ll_to_earth(latitude, longitude)
-    ) <= $3 * 1000
-ORDER BY distance_meters ASC
-LIMIT $4;
+    ) <= sqlc.arg(radius)::float8 * 1000
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (sqlc.arg(country)::text = '' OR country = sqlc.arg(country)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = articles.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = articles.id AND m.normalized = f.name
+        )
+    )
+ORDER BY distance_meters ASC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetRecentEventsByGeohash :many
+-- Located events since a time, for trending. Hours before the rollup
+-- watermark come from the hourly rollups, one row per article, tile and event
+-- type at the readers' centroid, dated mid-hour; later events are raw.
+WITH watermark AS (
+    SELECT COALESCE(
+        (SELECT rolled_up_to FROM user_event_rollup_watermark), '-infinity'::timestamptz
+    ) AS rolled_up_to
+)
 SELECT 
-    ue.*,
+    ue.id, ue.article_id, ue.event, ue.occurred_at, ue.user_lat, ue.user_lon,
     a.latitude,
-    a.longitude
+    a.longitude,
+    1::bigint AS count
 FROM user_events ue
 JOIN articles a ON ue.article_id = a.id
+CROSS JOIN watermark w
 WHERE 
     a.latitude IS NOT NULL 
     AND a.longitude IS NOT NULL
-    AND ue.occurred_at >= $1
+    AND ue.occurred_at >= GREATEST(sqlc.arg(since)::timestamptz, w.rolled_up_to)
     AND ue.user_lat IS NOT NULL 
     AND ue.user_lon IS NOT NULL
-ORDER BY ue.occurred_at DESC;
+UNION ALL
+SELECT
+    0::bigint AS id, r.article_id, r.event, r.bucket + interval '30 minutes' AS occurred_at, r.user_lat, r.user_lon,
+    a.latitude,
+    a.longitude,
+    r.count
+FROM user_event_rollups r
+JOIN articles a ON r.article_id = a.id
+CROSS JOIN watermark w
+WHERE
+    a.latitude IS NOT NULL
+    AND a.longitude IS NOT NULL
+    AND r.bucket >= date_trunc('hour', sqlc.arg(since)::timestamptz)
+    AND r.bucket < w.rolled_up_to
+    AND r.user_lat IS NOT NULL
+    AND r.user_lon IS NOT NULL
+ORDER BY occurred_at DESC;
 
 -- name: CreateArticleSummary :one
+-- Returns no row when the article doesn't exist. The sentiment is replaced
+-- with the summary, so a summary whose sentiment failed stores none.
 INSERT INTO article_summaries (
-    article_id, llm_summary, model
-) VALUES (
-    $1, $2, $3
-) ON CONFLICT (article_id) DO UPDATE SET
+    article_id, llm_summary, model, sentiment, sentiment_score
+)
+SELECT $1, $2, $3, $4, $5
+WHERE EXISTS (SELECT 1 FROM articles WHERE id = $1)
+ON CONFLICT (article_id) DO UPDATE SET
     llm_summary = EXCLUDED.llm_summary,
     model = EXCLUDED.model,
+    sentiment = EXCLUDED.sentiment,
+    sentiment_score = EXCLUDED.sentiment_score,
     generated_at = now()
-RETURNING *;
+RETURNING article_id, llm_summary, model, generated_at, sentiment, sentiment_score;
 
 -- name: GetArticleSummary :one
-SELECT * FROM article_summaries WHERE article_id = $1;
+SELECT article_id, llm_summary, model, generated_at, sentiment, sentiment_score FROM article_summaries WHERE article_id = $1;
 
 -- name: CreateUserEvent :one
+-- Returns no row when the article doesn't exist.
 INSERT INTO user_events (
     article_id, event, user_lat, user_lon
-) VALUES (
-    $1, $2, $3, $4
-) RETURNING *;
+)
+SELECT $1, $2, $3, $4
+WHERE EXISTS (SELECT 1 FROM articles WHERE id = $1)
+RETURNING id, article_id, event, occurred_at, user_lat, user_lon;
 
 -- name: GetArticlesWithoutSummary :many
-SELECT a.* FROM articles a
+SELECT a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+    a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country
+FROM articles a
 LEFT JOIN article_summaries s ON a.id = s.article_id
 WHERE s.article_id IS NULL
+ORDER BY a.publication_date DESC
 LIMIT $1;
 
+-- name: UpdateArticle :one
+-- Moves the article's URL claim along with its URL; a URL claimed by another
+-- article fails with a unique violation.
+WITH claimed AS (
+    INSERT INTO article_urls (url_hash, article_id)
+    SELECT sqlc.arg(url_hash)::text, a.id FROM articles a WHERE a.id = sqlc.arg(id)
+    ON CONFLICT (article_id) DO UPDATE SET url_hash = EXCLUDED.url_hash
+    RETURNING article_id
+)
+UPDATE articles SET
+    title = sqlc.arg(title),
+    description = sqlc.narg(description),
+    url = sqlc.arg(url),
+    publication_date = sqlc.arg(publication_date),
+    source_name = sqlc.arg(source_name),
+    category = sqlc.arg(category),
+    relevance_score = sqlc.arg(relevance_score),
+    latitude = sqlc.narg(latitude),
+    longitude = sqlc.narg(longitude),
+    url_hash = sqlc.arg(url_hash)::text,
+    content_hash = sqlc.arg(content_hash)::text,
+    language = sqlc.arg(language)::text,
+    city = sqlc.arg(city)::text,
+    region = sqlc.arg(region)::text,
+    country = sqlc.arg(country)::text
+FROM claimed
+WHERE id = claimed.article_id
+RETURNING id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country;
+
+-- name: DeleteArticle :execrows
+-- Removes the article's URL claim, summary, embedding, entities, geocoding,
+-- feedback and user events with it; the partitioned tables can't cascade
+-- through foreign keys.
+WITH urls AS (
+    DELETE FROM article_urls WHERE article_id = $1
+), summaries AS (
+    DELETE FROM article_summaries WHERE article_id = $1
+), embeddings AS (
+    DELETE FROM article_embeddings WHERE article_id = $1
+), entities AS (
+    DELETE FROM article_entities WHERE article_id = $1
+), extractions AS (
+    DELETE FROM article_entity_extractions WHERE article_id = $1
+), geocodes AS (
+    DELETE FROM article_geocodes WHERE article_id = $1
+), feedback AS (
+    DELETE FROM feedback WHERE article_id = $1
+), events AS (
+    DELETE FROM user_events WHERE article_id = $1
+)
+DELETE FROM articles WHERE articles.id = $1;
+
+-- name: GetArticleEventCounts :many
+-- Counts hours before the rollup watermark from the hourly rollups, whole
+-- hours at a time, and the events after it from user_events.
+WITH watermark AS (
+    SELECT COALESCE(
+        (SELECT rolled_up_to FROM user_event_rollup_watermark), '-infinity'::timestamptz
+    ) AS rolled_up_to
+)
+SELECT event, sum(count)::bigint AS count
+FROM (
+    SELECT r.event, r.count
+    FROM user_event_rollups r, watermark w
+    WHERE r.article_id = sqlc.arg(article_id)
+        AND r.bucket >= date_trunc('hour', sqlc.arg(since)::timestamptz)
+        AND r.bucket < w.rolled_up_to
+    UNION ALL
+    SELECT e.event, 1
+    FROM user_events e, watermark w
+    WHERE e.article_id = sqlc.arg(article_id)
+        AND e.occurred_at >= GREATEST(sqlc.arg(since)::timestamptz, w.rolled_up_to)
+) counts
+GROUP BY event;
+
+-- name: ListArticles :many
+-- Pages through every article, newest first, e.g. for exports. Pages are
+-- keyed on the last row of the previous one, so inserts don't shift them.
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM articles
+WHERE sqlc.narg(before_date)::timestamptz IS NULL
+    OR (publication_date, id) < (sqlc.narg(before_date)::timestamptz, sqlc.narg(before_id)::uuid)
+ORDER BY publication_date DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: BulkCreateArticles :batchone
+-- CreateArticle for many articles, pipelined in one round trip.
+WITH claimed AS (
+    INSERT INTO article_urls (article_id, url_hash)
+    VALUES (COALESCE(NULLIF(sqlc.arg(id)::text, '')::uuid, gen_random_uuid()), sqlc.arg(url_hash)::text)
+    ON CONFLICT (url_hash) DO UPDATE SET url_hash = EXCLUDED.url_hash
+    RETURNING article_id
+), updated AS (
+    UPDATE articles a SET
+        title = sqlc.arg(title),
+        description = sqlc.narg(description),
+        url = sqlc.arg(url),
+        publication_date = sqlc.arg(publication_date),
+        source_name = sqlc.arg(source_name),
+        category = sqlc.arg(category),
+        relevance_score = sqlc.arg(relevance_score),
+        latitude = sqlc.narg(latitude),
+        longitude = sqlc.narg(longitude),
+        url_hash = sqlc.arg(url_hash)::text,
+        content_hash = sqlc.arg(content_hash)::text,
+        language = sqlc.arg(language)::text,
+        city = sqlc.arg(city)::text,
+        region = sqlc.arg(region)::text,
+        country = sqlc.arg(country)::text
+    FROM claimed
+    WHERE a.id = claimed.article_id
+    RETURNING a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+        a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country
+), inserted AS (
+    INSERT INTO articles (
+        id, title, description, url, publication_date, source_name,
+        category, relevance_score, latitude, longitude, url_hash, content_hash, language,
+        city, region, country
+    )
+    SELECT claimed.article_id,
+        sqlc.arg(title), sqlc.narg(description), sqlc.arg(url), sqlc.arg(publication_date),
+        sqlc.arg(source_name), sqlc.arg(category), sqlc.arg(relevance_score),
+        sqlc.narg(latitude), sqlc.narg(longitude), sqlc.arg(url_hash)::text,
+        sqlc.arg(content_hash)::text, sqlc.arg(language)::text,
+        sqlc.arg(city)::text, sqlc.arg(region)::text, sqlc.arg(country)::text
+    FROM claimed
+    WHERE NOT EXISTS (SELECT 1 FROM updated)
+    RETURNING id, title, description, url, publication_date, source_name,
+        category, relevance_score, latitude, longitude, language, city, region, country
+)
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM updated
+UNION ALL
+SELECT id, title, description, url, publication_date, source_name,
+    category, relevance_score, latitude, longitude, language, city, region, country
+FROM inserted;
+
+-- name: GetArticleVersions :many
+-- The stored ID and content hash of the articles with the given canonical URL
+-- hashes, for skipping unchanged articles on re-ingestion.
+SELECT id, url_hash::text AS url_hash, COALESCE(content_hash, '')::text AS content_hash
+FROM articles
+WHERE url_hash = ANY(sqlc.arg(url_hashes)::text[]);
+
+-- name: CreateMonthlyPartition :one
+-- Creates the partition of parent for the month of month, reporting whether
+-- it was created (see create_monthly_partition in the migrations).
+SELECT create_monthly_partition(sqlc.arg(parent)::text::regclass, sqlc.arg(month)::date)::bool AS created;
+
+-- name: GetEventRollupWatermark :one
+SELECT rolled_up_to FROM user_event_rollup_watermark;
+
+-- name: SetEventRollupWatermark :exec
+INSERT INTO user_event_rollup_watermark (id, rolled_up_to) VALUES (true, $1)
+ON CONFLICT (id) DO UPDATE SET rolled_up_to = EXCLUDED.rolled_up_to;
+
+-- name: GetFirstEventTime :one
+-- When the oldest stored event occurred, or now when there are none.
+SELECT COALESCE(min(occurred_at), now())::timestamptz AS first_occurred_at FROM user_events;
+
+-- name: GetEventsBetween :many
+SELECT article_id, event, user_lat, user_lon
+FROM user_events
+WHERE occurred_at >= sqlc.arg(start_time) AND occurred_at < sqlc.arg(end_time);
+
+-- name: DeleteEventRollupBucket :exec
+DELETE FROM user_event_rollups WHERE bucket = $1;
+
+-- name: InsertEventRollups :exec
+-- Rows without a location have an empty geohash, and their coordinates are ignored.
+INSERT INTO user_event_rollups (bucket, article_id, geohash, event, count, user_lat, user_lon)
+SELECT sqlc.arg(bucket)::timestamptz, u.article_id, u.geohash, u.event::event_type, u.count,
+    CASE WHEN u.geohash = '' THEN NULL ELSE u.user_lat END,
+    CASE WHEN u.geohash = '' THEN NULL ELSE u.user_lon END
+FROM (
+    SELECT unnest(sqlc.arg(article_ids)::uuid[]) AS article_id,
+        unnest(sqlc.arg(geohashes)::text[]) AS geohash,
+        unnest(sqlc.arg(events)::text[]) AS event,
+        unnest(sqlc.arg(counts)::bigint[]) AS count,
+        unnest(sqlc.arg(user_lats)::float8[]) AS user_lat,
+        unnest(sqlc.arg(user_lons)::float8[]) AS user_lon
+) AS u;
+
+-- name: DeleteEventsBefore :execrows
+DELETE FROM user_events WHERE occurred_at < $1;
+
+-- name: DeleteEventRollupsBefore :execrows
+DELETE FROM user_event_rollups WHERE bucket < $1;
+
+-- name: CreateArticleEmbeddings :exec
+-- Stores one embedding per article, replacing any earlier one. Embeddings are
+-- pgvector text literals such as '[0.1,0.2]'; deleted articles are skipped.
+INSERT INTO article_embeddings (article_id, model, content_hash, embedding, embedded_at)
+SELECT e.article_id, sqlc.arg(model)::text, e.content_hash, e.embedding::vector, now()
+FROM (
+    SELECT unnest(sqlc.arg(article_ids)::uuid[]) AS article_id,
+        unnest(sqlc.arg(content_hashes)::text[]) AS content_hash,
+        unnest(sqlc.arg(embeddings)::text[]) AS embedding
+) AS e
+WHERE EXISTS (SELECT 1 FROM articles a WHERE a.id = e.article_id)
+ON CONFLICT (article_id) DO UPDATE SET
+    model = EXCLUDED.model,
+    content_hash = EXCLUDED.content_hash,
+    embedding = EXCLUDED.embedding,
+    embedded_at = EXCLUDED.embedded_at;
+
+-- name: GetArticlesWithoutEmbedding :many
+-- Articles with no embedding from model, or whose content changed since it
+-- was embedded, newest first.
+SELECT a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+    a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country
+FROM articles a
+LEFT JOIN article_embeddings e ON e.article_id = a.id AND e.model = sqlc.arg(model)::text
+WHERE e.article_id IS NULL
+    OR (a.content_hash IS NOT NULL AND e.content_hash <> a.content_hash)
+ORDER BY a.publication_date DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SemanticSearchArticles :many
+-- Ranks articles by the cosine similarity of their embedding from model to
+-- the query's, a pgvector text literal, leaving out those below
+-- min_similarity. Scans every embedding of the model; see 0008.
+SELECT
+    a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+    a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country,
+    (1 - (e.embedding <=> sqlc.arg(embedding)::text::vector))::float8 AS similarity
+FROM article_embeddings e
+JOIN articles a ON a.id = e.article_id
+WHERE e.model = sqlc.arg(model)::text
+    AND (sqlc.arg(language)::text = '' OR a.language = sqlc.arg(language)::text)
+    AND (sqlc.arg(country)::text = '' OR a.country = sqlc.arg(country)::text)
+    AND (sqlc.narg(published_after)::timestamptz IS NULL OR a.publication_date >= sqlc.narg(published_after)::timestamptz)
+    AND (sqlc.narg(published_before)::timestamptz IS NULL OR a.publication_date < sqlc.narg(published_before)::timestamptz)
+    AND (sqlc.arg(sentiment)::text = '' OR EXISTS (
+        SELECT 1 FROM article_summaries s
+        WHERE s.article_id = a.id AND s.sentiment = sqlc.arg(sentiment)::text
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM unnest(sqlc.arg(entities)::text[]) AS f(name)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM article_entities m
+            WHERE m.article_id = a.id AND m.normalized = f.name
+        )
+    )
+    AND 1 - (e.embedding <=> sqlc.arg(embedding)::text::vector) >= sqlc.arg(min_similarity)::float8
+ORDER BY e.embedding <=> sqlc.arg(embedding)::text::vector, a.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CreateFeedback :one
+-- Returns no row when the article doesn't exist.
+INSERT INTO feedback (
+    article_id, target, rating, query, comment, summary_model
+)
+SELECT $1, $2, $3, $4, $5, $6
+WHERE EXISTS (SELECT 1 FROM articles WHERE id = $1)
+RETURNING id, article_id, target, rating, query, comment, summary_model, created_at;
+
+-- name: GetFeedbackStats :many
+-- Counts feedback given since since by target and the model of the summary
+-- rated, empty for results.
+SELECT
+    target,
+    coalesce(summary_model, '')::text AS model,
+    count(*) FILTER (WHERE rating = 'up') AS up,
+    count(*) FILTER (WHERE rating = 'down') AS down
+FROM feedback
+WHERE created_at >= sqlc.arg(since)
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
+-- name: GetSummariesWithNegativeFeedback :many
+-- Stored summaries with at least min_down thumbs down, most disliked first.
+-- Only feedback given since the summary was generated counts, so a
+-- regenerated summary starts afresh.
+SELECT
+    s.article_id, s.model, s.generated_at,
+    count(*) FILTER (WHERE f.rating = 'up') AS up,
+    count(*) FILTER (WHERE f.rating = 'down') AS down
+FROM article_summaries s
+JOIN feedback f ON f.article_id = s.article_id
+    AND f.target = 'summary'
+    AND f.created_at >= s.generated_at
+GROUP BY s.article_id, s.model, s.generated_at
+HAVING count(*) FILTER (WHERE f.rating = 'down') >= sqlc.arg(min_down)::bigint
+ORDER BY count(*) FILTER (WHERE f.rating = 'down') - count(*) FILTER (WHERE f.rating = 'up') DESC, s.article_id
+LIMIT sqlc.arg('limit');
+
+-- name: ReplaceArticleEntities :exec
+-- Stores the entities extracted from an article's content, replacing earlier
+-- ones and recording the content hash; a deleted article is skipped. Entity
+-- names must be distinct per type once normalized.
+WITH article AS (
+    SELECT id FROM articles WHERE id = sqlc.arg(article_id)
+), extraction AS (
+    INSERT INTO article_entity_extractions (article_id, content_hash, extracted_at)
+    SELECT id, sqlc.arg(content_hash)::text, now() FROM article
+    ON CONFLICT (article_id) DO UPDATE SET
+        content_hash = EXCLUDED.content_hash,
+        extracted_at = EXCLUDED.extracted_at
+), removed AS (
+    DELETE FROM article_entities
+    WHERE article_id = sqlc.arg(article_id)
+        AND (entity_type, normalized) NOT IN (
+            SELECT unnest(sqlc.arg(entity_types)::text[]), unnest(sqlc.arg(normalized)::text[])
+        )
+)
+INSERT INTO article_entities (article_id, entity_type, name, normalized)
+SELECT a.id, e.entity_type, e.name, e.normalized
+FROM article a, (
+    SELECT unnest(sqlc.arg(entity_types)::text[]) AS entity_type,
+        unnest(sqlc.arg(names)::text[]) AS name,
+        unnest(sqlc.arg(normalized)::text[]) AS normalized
+) AS e
+ON CONFLICT (article_id, entity_type, normalized) DO UPDATE SET name = EXCLUDED.name;
+
+-- name: GetArticleEntities :many
+SELECT entity_type, name FROM article_entities
+WHERE article_id = $1
+ORDER BY entity_type, name;
+
+-- name: GetArticlesWithoutEntities :many
+-- Articles whose entities were never extracted, or whose content changed
+-- since they were, newest first.
+SELECT a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+    a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country
+FROM articles a
+LEFT JOIN article_entity_extractions x ON x.article_id = a.id
+WHERE x.article_id IS NULL
+    OR (a.content_hash IS NOT NULL AND x.content_hash <> a.content_hash)
+ORDER BY a.publication_date DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SetArticlePlace :exec
+-- Stores the place geocoded from an article's coordinates and records that
+-- they were geocoded; an article deleted or moved since it was read is
+-- skipped.
+WITH article AS (
+    SELECT a.id FROM articles a
+    WHERE a.id = sqlc.arg(article_id)
+        AND a.latitude = sqlc.arg(latitude)::float8
+        AND a.longitude = sqlc.arg(longitude)::float8
+), geocode AS (
+    INSERT INTO article_geocodes (article_id, latitude, longitude, geocoded_at)
+    SELECT id, sqlc.arg(latitude)::float8, sqlc.arg(longitude)::float8, now() FROM article
+    ON CONFLICT (article_id) DO UPDATE SET
+        latitude = EXCLUDED.latitude,
+        longitude = EXCLUDED.longitude,
+        geocoded_at = EXCLUDED.geocoded_at
+)
+UPDATE articles SET
+    city = sqlc.arg(city)::text,
+    region = sqlc.arg(region)::text,
+    country = sqlc.arg(country)::text
+WHERE articles.id IN (SELECT article.id FROM article);
+
+-- name: GetArticlesWithoutPlace :many
+-- Articles with coordinates but no place whose coordinates were never
+-- geocoded, or moved since they were, newest first.
+SELECT a.id, a.title, a.description, a.url, a.publication_date, a.source_name,
+    a.category, a.relevance_score, a.latitude, a.longitude, a.language, a.city, a.region, a.country
+FROM articles a
+LEFT JOIN article_geocodes g ON g.article_id = a.id
+WHERE a.latitude IS NOT NULL
+    AND a.longitude IS NOT NULL
+    AND a.country = ''
+    AND (g.article_id IS NULL OR g.latitude <> a.latitude OR g.longitude <> a.longitude)
+ORDER BY a.publication_date DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CreateSubscription :one
+INSERT INTO subscriptions (
+    owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
+)
+RETURNING id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason;
+
+-- name: GetSubscription :one
+SELECT id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason
+FROM subscriptions
+WHERE id = $1;
+
+-- name: ListSubscriptions :many
+SELECT id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason
+FROM subscriptions
+WHERE owner = sqlc.arg(owner)
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: UpdateSubscription :one
+-- Replaces every field a subscription's owner or its verification can change.
+UPDATE subscriptions SET
+    kind = sqlc.arg(kind),
+    channel = sqlc.arg(channel),
+    target = sqlc.arg(target),
+    query = sqlc.narg(query),
+    category = sqlc.narg(category),
+    language = sqlc.narg(language),
+    country = sqlc.narg(country),
+    latitude = sqlc.narg(latitude),
+    longitude = sqlc.narg(longitude),
+    radius_km = sqlc.narg(radius_km),
+    schedule = sqlc.narg(schedule),
+    timezone = sqlc.arg(timezone),
+    calendar = sqlc.narg(calendar),
+    verification_hash = sqlc.narg(verification_hash),
+    verified_at = sqlc.narg(verified_at),
+    next_run_at = sqlc.narg(next_run_at),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason;
+
+-- name: DeleteSubscription :execrows
+-- Deletes the subscription's delivery history with it.
+DELETE FROM subscriptions WHERE id = $1;
+
+-- name: GetDueSubscriptions :many
+-- Verified, enabled digests whose next run is due at before, most overdue first.
+SELECT id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason
+FROM subscriptions
+WHERE kind = 'digest'
+    AND verified_at IS NOT NULL
+    AND disabled_at IS NULL
+    AND next_run_at <= sqlc.arg(before)::timestamptz
+ORDER BY next_run_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: GetAlertSubscriptions :many
+-- Verified, enabled alerts, for matching against stored articles.
+SELECT id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason
+FROM subscriptions
+WHERE kind = 'alert'
+    AND verified_at IS NOT NULL
+    AND disabled_at IS NULL
+ORDER BY id;
+
+-- name: ClaimSubscriptionRun :execrows
+-- Moves a digest's next run from due to next, unless another instance
+-- already did, so each run is sent once.
+UPDATE subscriptions SET next_run_at = sqlc.narg(next), last_run_at = sqlc.arg(due)::timestamptz
+WHERE id = sqlc.arg(id)
+    AND next_run_at = sqlc.arg(due)::timestamptz;
+
+-- name: CreateSubscriptionDelivery :one
+INSERT INTO subscription_deliveries (
+    subscription_id, kind, status, articles, error, channel, attempt, latency_ms, response
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING id, subscription_id, kind, status, articles, error, delivered_at,
+    channel, attempt, latency_ms, response;
+
+-- name: ListSubscriptionDeliveries :many
+-- A subscription's deliveries, newest first.
+SELECT id, subscription_id, kind, status, articles, error, delivered_at,
+    channel, attempt, latency_ms, response
+FROM subscription_deliveries
+WHERE subscription_id = sqlc.arg(subscription_id)
+ORDER BY delivered_at DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: SearchSubscriptionDeliveries :many
+-- Deliveries across all subscriptions, newest first, optionally of one
+-- subscription, channel or status and made since a time.
+SELECT id, subscription_id, kind, status, articles, error, delivered_at,
+    channel, attempt, latency_ms, response
+FROM subscription_deliveries
+WHERE (sqlc.narg(subscription_id)::uuid IS NULL OR subscription_id = sqlc.narg(subscription_id)::uuid)
+    AND (sqlc.narg(channel)::text IS NULL OR channel = sqlc.narg(channel)::text)
+    AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+    AND (sqlc.narg(since)::timestamptz IS NULL OR delivered_at >= sqlc.narg(since)::timestamptz)
+ORDER BY delivered_at DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: RecordSubscriptionFailure :one
+-- Counts a failed message, disabling the subscription with reason once
+-- threshold messages failed in a row; a threshold of 0 never disables.
+UPDATE subscriptions SET
+    failures = failures + 1,
+    disabled_at = CASE
+        WHEN disabled_at IS NULL AND sqlc.arg(threshold)::int > 0 AND failures + 1 >= sqlc.arg(threshold)::int THEN now()
+        ELSE disabled_at
+    END,
+    disabled_reason = CASE
+        WHEN disabled_at IS NULL AND sqlc.arg(threshold)::int > 0 AND failures + 1 >= sqlc.arg(threshold)::int THEN sqlc.narg(reason)
+        ELSE disabled_reason
+    END
+WHERE id = sqlc.arg(id)
+RETURNING id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason;
+
+-- name: ResetSubscriptionFailures :exec
+UPDATE subscriptions SET failures = 0 WHERE id = $1 AND failures <> 0;
+
+-- name: EnableSubscription :one
+-- Clears a subscription's failures and disabled state.
+UPDATE subscriptions SET
+    failures = 0,
+    disabled_at = NULL,
+    disabled_reason = NULL,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, owner, kind, channel, target, query, category, language, country, latitude, longitude,
+    radius_km, schedule, timezone, calendar, secret, verification_hash, verified_at, next_run_at,
+    last_run_at, created_at, updated_at, failures, disabled_at, disabled_reason;
+
+-- name: GetNotificationTemplate :one
+SELECT owner, name, subject, body, created_at, updated_at
+FROM notification_templates
+WHERE owner = $1 AND name = $2;
+
+-- name: ListNotificationTemplates :many
+SELECT owner, name, subject, body, created_at, updated_at
+FROM notification_templates
+WHERE owner = $1
+ORDER BY name;
+
+-- name: UpsertNotificationTemplate :one
+INSERT INTO notification_templates (
+    owner, name, subject, body
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (owner, name) DO UPDATE
+SET subject = EXCLUDED.subject,
+    body = EXCLUDED.body,
+    updated_at = now()
+RETURNING owner, name, subject, body, created_at, updated_at;
+
+-- name: DeleteNotificationTemplate :execrows
+DELETE FROM notification_templates
+WHERE owner = $1 AND name = $2;
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// splitRepository sends writes to the primary and spreads reads round-robin
+// across read replicas.
+type splitRepository struct {
+	WriteRepository
+	primary  ReadRepository
+	replicas []ReadRepository
+	next     atomic.Uint64
+}
+
+// NewSplitRepository routes reads to replicas and writes to primary. With no
+// replicas every call goes to primary.
+func NewSplitRepository(primary Repository, replicas ...ReadRepository) Repository {
+	if len(replicas) == 0 {
+		return primary
+	}
+	return &splitRepository{
+		WriteRepository: primary,
+		primary:         primary,
+		replicas:        replicas,
+	}
+}
+
+// reader picks the replica to serve the next read
+func (r *splitRepository) reader() ReadRepository {
+	n := r.next.Add(1)
+	return r.replicas[n%uint64(len(r.replicas))]
+}
+
+func (r *splitRepository) GetArticleByID(ctx context.Context, id string) (Article, error) {
+	return r.reader().GetArticleByID(ctx, id)
+}
+
+func (r *splitRepository) GetArticlesByCategory(ctx context.Context, arg GetArticlesByCategoryParams) ([]Article, error) {
+	return r.reader().GetArticlesByCategory(ctx, arg)
+}
+
+func (r *splitRepository) GetArticlesBySource(ctx context.Context, arg GetArticlesBySourceParams) ([]Article, error) {
+	return r.reader().GetArticlesBySource(ctx, arg)
+}
+
+func (r *splitRepository) GetArticlesByScore(ctx context.Context, arg GetArticlesByScoreParams) ([]Article, error) {
+	return r.reader().GetArticlesByScore(ctx, arg)
+}
+
+func (r *splitRepository) GetArticlesByPlace(ctx context.Context, arg GetArticlesByPlaceParams) ([]Article, error) {
+	return r.reader().GetArticlesByPlace(ctx, arg)
+}
+
+func (r *splitRepository) SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error) {
+	return r.reader().SearchArticles(ctx, arg)
+}
+
+func (r *splitRepository) GetNearbyArticles(ctx context.Context, arg GetNearbyArticlesParams) ([]GetNearbyArticlesRow, error) {
+	return r.reader().GetNearbyArticles(ctx, arg)
+}
+
+func (r *splitRepository) GetRecentEventsByGeohash(ctx context.Context, since time.Time) ([]GetRecentEventsByGeohashRow, error) {
+	return r.reader().GetRecentEventsByGeohash(ctx, since)
+}
+
+// GetArticleSummary reads from the primary: summaries are usually read right
+// after being written, so replica lag would cause needless regeneration.
+func (r *splitRepository) GetArticleSummary(ctx context.Context, articleID string) (ArticleSummary, error) {
+	return r.primary.GetArticleSummary(ctx, articleID)
+}
+
+func (r *splitRepository) GetArticlesWithoutSummary(ctx context.Context, limit int32) ([]Article, error) {
+	return r.reader().GetArticlesWithoutSummary(ctx, limit)
+}
+
+func (r *splitRepository) GetArticleEventCounts(ctx context.Context, arg GetArticleEventCountsParams) ([]ArticleEventCount, error) {
+	return r.reader().GetArticleEventCounts(ctx, arg)
+}
+
+func (r *splitRepository) ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error) {
+	return r.reader().ListArticles(ctx, arg)
+}
+
+// GetArticleVersions reads from the primary: a lagging replica could report
+// an article as unchanged after the primary already stored a newer version.
+func (r *splitRepository) GetArticleVersions(ctx context.Context, urlHashes []string) ([]ArticleVersion, error) {
+	return r.primary.GetArticleVersions(ctx, urlHashes)
+}
+
+func (r *splitRepository) SemanticSearchArticles(ctx context.Context, arg SemanticSearchArticlesParams) ([]SemanticSearchArticlesRow, error) {
+	return r.reader().SemanticSearchArticles(ctx, arg)
+}
+
+func (r *splitRepository) GetArticlesWithoutEmbedding(ctx context.Context, arg GetArticlesWithoutEmbeddingParams) ([]Article, error) {
+	return r.reader().GetArticlesWithoutEmbedding(ctx, arg)
+}
+
+func (r *splitRepository) GetArticleEntities(ctx context.Context, articleID string) ([]ArticleEntity, error) {
+	return r.reader().GetArticleEntities(ctx, articleID)
+}
+
+func (r *splitRepository) GetArticlesWithoutEntities(ctx context.Context, limit int32) ([]Article, error) {
+	return r.reader().GetArticlesWithoutEntities(ctx, limit)
+}
+
+func (r *splitRepository) GetArticlesWithoutPlace(ctx context.Context, limit int32) ([]Article, error) {
+	return r.reader().GetArticlesWithoutPlace(ctx, limit)
+}
+
+func (r *splitRepository) GetFeedbackStats(ctx context.Context, since time.Time) ([]FeedbackStat, error) {
+	return r.reader().GetFeedbackStats(ctx, since)
+}
+
+func (r *splitRepository) GetSummariesWithNegativeFeedback(ctx context.Context, arg GetSummariesWithNegativeFeedbackParams) ([]SummaryFeedback, error) {
+	return r.reader().GetSummariesWithNegativeFeedback(ctx, arg)
+}
+
+func (r *splitRepository) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	return r.reader().GetSubscription(ctx, id)
+}
+
+func (r *splitRepository) ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error) {
+	return r.reader().ListSubscriptions(ctx, arg)
+}
+
+func (r *splitRepository) GetDueSubscriptions(ctx context.Context, arg GetDueSubscriptionsParams) ([]Subscription, error) {
+	return r.reader().GetDueSubscriptions(ctx, arg)
+}
+
+func (r *splitRepository) GetAlertSubscriptions(ctx context.Context) ([]Subscription, error) {
+	return r.reader().GetAlertSubscriptions(ctx)
+}
+
+func (r *splitRepository) ListSubscriptionDeliveries(ctx context.Context, arg ListSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	return r.reader().ListSubscriptionDeliveries(ctx, arg)
+}
+
+func (r *splitRepository) GetNotificationTemplate(ctx context.Context, arg GetNotificationTemplateParams) (NotificationTemplate, error) {
+	return r.reader().GetNotificationTemplate(ctx, arg)
+}
+
+func (r *splitRepository) ListNotificationTemplates(ctx context.Context, owner string) ([]NotificationTemplate, error) {
+	return r.reader().ListNotificationTemplates(ctx, owner)
+}
+
+func (r *splitRepository) SearchSubscriptionDeliveries(ctx context.Context, arg SearchSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	return r.reader().SearchSubscriptionDeliveries(ctx, arg)
+}
@@ -0,0 +1,705 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// subscriptionKey is the Redis key of a stored subscription
+func subscriptionKey(id string) string {
+	return fmt.Sprintf("subscription:%s", id)
+}
+
+// ownerSubscriptionsKey is the Redis sorted set of an owner's subscription
+// IDs, scored by the Unix time they were created
+func ownerSubscriptionsKey(owner string) string {
+	return fmt.Sprintf("subscriptions:owner:%s", owner)
+}
+
+// subscriptionDeliveriesKey is the Redis sorted set of a subscription's
+// deliveries, scored by the Unix time they were made
+func subscriptionDeliveriesKey(id string) string {
+	return fmt.Sprintf("subscription:%s:deliveries", id)
+}
+
+// deliveryLogKey is the Redis sorted set of the latest deliveries of every
+// subscription, scored like subscriptionDeliveriesKey and capped at
+// deliveryLogSize
+const deliveryLogKey = "subscription_deliveries"
+
+// deliveryLogSize is how many deliveries deliveryLogKey keeps
+const deliveryLogSize = 10000
+
+// subscriptionRunKey claims the run of a digest due at due
+func subscriptionRunKey(id string, due time.Time) string {
+	return fmt.Sprintf("subscription:%s:run:%d", id, due.Unix())
+}
+
+// dueSubscriptionsKey is the Redis sorted set of verified digest IDs, scored
+// by the Unix time of their next run; alertSubscriptionsKey is the set of
+// verified alert IDs
+const (
+	dueSubscriptionsKey   = "subscriptions:due"
+	alertSubscriptionsKey = "subscriptions:alerts"
+)
+
+// subscriptionRunClaimTTL is how long a digest run stays claimed, well past
+// the time instances take to move it on
+const subscriptionRunClaimTTL = 24 * time.Hour
+
+// CreateSubscription stores a new subscription
+func (r *repository) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error) {
+	now := time.Now()
+	subscription := Subscription{
+		ID:               NewArticleID(),
+		Owner:            arg.Owner,
+		Kind:             arg.Kind,
+		Channel:          arg.Channel,
+		Target:           arg.Target,
+		Query:            arg.Query,
+		Category:         arg.Category,
+		Language:         arg.Language,
+		Country:          arg.Country,
+		Latitude:         arg.Latitude,
+		Longitude:        arg.Longitude,
+		RadiusKm:         arg.RadiusKm,
+		Schedule:         arg.Schedule,
+		Timezone:         arg.Timezone,
+		Calendar:         arg.Calendar,
+		Secret:           arg.Secret,
+		VerificationHash: arg.VerificationHash,
+		VerifiedAt:       arg.VerifiedAt,
+		NextRunAt:        arg.NextRunAt,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		r.subscriptions[subscription.ID] = subscription
+		r.subscriptionsMu.Unlock()
+		return subscription, nil
+	}
+
+	if err := r.writeSubscription(ctx, subscription); err != nil {
+		return Subscription{}, err
+	}
+	member := redis.Z{Score: float64(now.UnixMicro()) / 1e6, Member: subscription.ID}
+	if err := r.cache.ZAdd(ctx, ownerSubscriptionsKey(subscription.Owner), member); err != nil {
+		return Subscription{}, fmt.Errorf("failed to index subscription %s: %w", subscription.ID, err)
+	}
+	return subscription, nil
+}
+
+// GetSubscription retrieves a subscription by ID
+func (r *repository) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		subscription, ok := r.subscriptions[id]
+		if !ok {
+			return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+		}
+		return subscription, nil
+	}
+
+	data, err := r.cache.Get(ctx, subscriptionKey(id))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+	}
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to get subscription %s: %w", id, err)
+	}
+	var subscription Subscription
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		return Subscription{}, fmt.Errorf("failed to decode subscription %s: %w", id, err)
+	}
+	return subscription, nil
+}
+
+// ListSubscriptions returns an owner's subscriptions, oldest first
+func (r *repository) ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error) {
+	var subscriptions []Subscription
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		for _, subscription := range r.subscriptions {
+			if subscription.Owner == arg.Owner {
+				subscriptions = append(subscriptions, subscription)
+			}
+		}
+		r.subscriptionsMu.Unlock()
+		sortSubscriptions(subscriptions)
+		return paginate(subscriptions, arg.Offset, arg.Limit), nil
+	}
+
+	ids, err := r.cache.ZRangeByScore(ctx, ownerSubscriptionsKey(arg.Owner), math.Inf(-1), math.Inf(1), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	return r.subscriptionsByID(ctx, paginate(ids, arg.Offset, arg.Limit))
+}
+
+// UpdateSubscription replaces a subscription's fields
+func (r *repository) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (Subscription, error) {
+	apply := func(subscription Subscription) Subscription {
+		subscription.Kind = arg.Kind
+		subscription.Channel = arg.Channel
+		subscription.Target = arg.Target
+		subscription.Query = arg.Query
+		subscription.Category = arg.Category
+		subscription.Language = arg.Language
+		subscription.Country = arg.Country
+		subscription.Latitude = arg.Latitude
+		subscription.Longitude = arg.Longitude
+		subscription.RadiusKm = arg.RadiusKm
+		subscription.Schedule = arg.Schedule
+		subscription.Timezone = arg.Timezone
+		subscription.Calendar = arg.Calendar
+		subscription.VerificationHash = arg.VerificationHash
+		subscription.VerifiedAt = arg.VerifiedAt
+		subscription.NextRunAt = arg.NextRunAt
+		subscription.UpdatedAt = time.Now()
+		return subscription
+	}
+
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		subscription, ok := r.subscriptions[arg.ID]
+		if !ok {
+			return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", arg.ID)
+		}
+		subscription = apply(subscription)
+		r.subscriptions[arg.ID] = subscription
+		return subscription, nil
+	}
+
+	subscription, err := r.GetSubscription(ctx, arg.ID)
+	if err != nil {
+		return Subscription{}, err
+	}
+	subscription = apply(subscription)
+	if err := r.writeSubscription(ctx, subscription); err != nil {
+		return Subscription{}, err
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription deletes a subscription and its delivery history
+func (r *repository) DeleteSubscription(ctx context.Context, id string) error {
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		if _, ok := r.subscriptions[id]; !ok {
+			return errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+		}
+		delete(r.subscriptions, id)
+		delete(r.deliveries, id)
+		return nil
+	}
+
+	subscription, err := r.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.cache.Del(ctx, subscriptionKey(id), subscriptionDeliveriesKey(id)); err != nil {
+		return fmt.Errorf("failed to delete subscription %s: %w", id, err)
+	}
+	if err := r.cache.ZRem(ctx, ownerSubscriptionsKey(subscription.Owner), id); err != nil {
+		return fmt.Errorf("failed to unindex subscription %s: %w", id, err)
+	}
+	if err := r.cache.ZRem(ctx, dueSubscriptionsKey, id); err != nil {
+		return fmt.Errorf("failed to unindex subscription %s: %w", id, err)
+	}
+	if err := r.cache.SRem(ctx, alertSubscriptionsKey, id); err != nil {
+		return fmt.Errorf("failed to unindex subscription %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetDueSubscriptions returns the verified, enabled digests due at
+// arg.Before, most overdue first
+func (r *repository) GetDueSubscriptions(ctx context.Context, arg GetDueSubscriptionsParams) ([]Subscription, error) {
+	var due []Subscription
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		for _, subscription := range r.subscriptions {
+			if subscription.Kind == SubscriptionDigest && subscription.VerifiedAt != nil && subscription.DisabledAt == nil &&
+				subscription.NextRunAt != nil && !subscription.NextRunAt.After(arg.Before) {
+				due = append(due, subscription)
+			}
+		}
+		r.subscriptionsMu.Unlock()
+		sort.Slice(due, func(i, j int) bool {
+			if !due[i].NextRunAt.Equal(*due[j].NextRunAt) {
+				return due[i].NextRunAt.Before(*due[j].NextRunAt)
+			}
+			return due[i].ID < due[j].ID
+		})
+		return paginate(due, 0, arg.Limit), nil
+	}
+
+	ids, err := r.cache.ZRangeByScore(ctx, dueSubscriptionsKey, math.Inf(-1), float64(arg.Before.Unix()), int64(arg.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due subscriptions: %w", err)
+	}
+	return r.subscriptionsByID(ctx, ids)
+}
+
+// GetAlertSubscriptions returns the verified, enabled alerts
+func (r *repository) GetAlertSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var alerts []Subscription
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		for _, subscription := range r.subscriptions {
+			if subscription.Kind == SubscriptionAlert && subscription.VerifiedAt != nil && subscription.DisabledAt == nil {
+				alerts = append(alerts, subscription)
+			}
+		}
+		r.subscriptionsMu.Unlock()
+		sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+		return alerts, nil
+	}
+
+	ids, err := r.cache.SMembers(ctx, alertSubscriptionsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert subscriptions: %w", err)
+	}
+	sort.Strings(ids)
+	return r.subscriptionsByID(ctx, ids)
+}
+
+// ClaimSubscriptionRun moves a digest's next run from arg.Due to arg.Next,
+// reporting false when another instance already did
+func (r *repository) ClaimSubscriptionRun(ctx context.Context, arg ClaimSubscriptionRunParams) (bool, error) {
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		subscription, ok := r.subscriptions[arg.ID]
+		if !ok || subscription.NextRunAt == nil || !subscription.NextRunAt.Equal(arg.Due) {
+			return false, nil
+		}
+		due := arg.Due
+		subscription.NextRunAt, subscription.LastRunAt = arg.Next, &due
+		r.subscriptions[arg.ID] = subscription
+		return true, nil
+	}
+
+	claimed, err := r.cache.SetNX(ctx, subscriptionRunKey(arg.ID, arg.Due), "1", subscriptionRunClaimTTL)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim run of subscription %s: %w", arg.ID, err)
+	}
+	if !claimed {
+		return false, nil
+	}
+	subscription, err := r.GetSubscription(ctx, arg.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if subscription.NextRunAt == nil || !subscription.NextRunAt.Equal(arg.Due) {
+		return false, nil
+	}
+	due := arg.Due
+	subscription.NextRunAt, subscription.LastRunAt = arg.Next, &due
+	if err := r.writeSubscription(ctx, subscription); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateSubscriptionDelivery records a message sent for a subscription
+func (r *repository) CreateSubscriptionDelivery(ctx context.Context, arg CreateSubscriptionDeliveryParams) (SubscriptionDelivery, error) {
+	delivery := SubscriptionDelivery{
+		ID:             NewArticleID(),
+		SubscriptionID: arg.SubscriptionID,
+		Kind:           arg.Kind,
+		Status:         arg.Status,
+		Articles:       arg.Articles,
+		Error:          arg.Error,
+		DeliveredAt:    time.Now(),
+		Channel:        arg.Channel,
+		Attempt:        arg.Attempt,
+		LatencyMs:      arg.LatencyMs,
+		Response:       arg.Response,
+	}
+
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		if _, ok := r.subscriptions[arg.SubscriptionID]; !ok {
+			return SubscriptionDelivery{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", arg.SubscriptionID)
+		}
+		r.deliveries[arg.SubscriptionID] = append(r.deliveries[arg.SubscriptionID], delivery)
+		return delivery, nil
+	}
+
+	if _, err := r.GetSubscription(ctx, arg.SubscriptionID); err != nil {
+		return SubscriptionDelivery{}, err
+	}
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return SubscriptionDelivery{}, fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	member := redis.Z{Score: float64(delivery.DeliveredAt.UnixMicro()) / 1e6, Member: data}
+	if err := r.cache.ZAdd(ctx, subscriptionDeliveriesKey(arg.SubscriptionID), member); err != nil {
+		return SubscriptionDelivery{}, fmt.Errorf("failed to store delivery of %s: %w", arg.SubscriptionID, err)
+	}
+	if err := r.cache.ZAdd(ctx, deliveryLogKey, member); err != nil {
+		return SubscriptionDelivery{}, fmt.Errorf("failed to log delivery of %s: %w", arg.SubscriptionID, err)
+	}
+	if err := r.cache.ZRemRangeByRank(ctx, deliveryLogKey, 0, -deliveryLogSize-1); err != nil {
+		return SubscriptionDelivery{}, fmt.Errorf("failed to trim delivery log: %w", err)
+	}
+	return delivery, nil
+}
+
+// ListSubscriptionDeliveries returns a subscription's deliveries, newest first
+func (r *repository) ListSubscriptionDeliveries(ctx context.Context, arg ListSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	var deliveries []SubscriptionDelivery
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		stored := r.deliveries[arg.SubscriptionID]
+		for i := len(stored) - 1; i >= 0; i-- {
+			deliveries = append(deliveries, stored[i])
+		}
+		r.subscriptionsMu.Unlock()
+		return paginate(deliveries, arg.Offset, arg.Limit), nil
+	}
+
+	stop := int64(-1)
+	if arg.Limit > 0 {
+		stop = int64(arg.Offset + arg.Limit - 1)
+	}
+	members, err := r.cache.ZRevRangeWithScores(ctx, subscriptionDeliveriesKey(arg.SubscriptionID), int64(arg.Offset), stop)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deliveries of %s: %w", arg.SubscriptionID, err)
+	}
+	return decodeDeliveries(members), nil
+}
+
+// SearchSubscriptionDeliveries returns the deliveries matching arg across
+// subscriptions, newest first. The Redis backend searches the latest
+// deliveryLogSize deliveries.
+func (r *repository) SearchSubscriptionDeliveries(ctx context.Context, arg SearchSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	var deliveries []SubscriptionDelivery
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		for _, stored := range r.deliveries {
+			deliveries = append(deliveries, stored...)
+		}
+		r.subscriptionsMu.Unlock()
+		sort.Slice(deliveries, func(i, j int) bool {
+			if !deliveries[i].DeliveredAt.Equal(deliveries[j].DeliveredAt) {
+				return deliveries[i].DeliveredAt.After(deliveries[j].DeliveredAt)
+			}
+			return deliveries[i].ID < deliveries[j].ID
+		})
+	} else {
+		members, err := r.cache.ZRevRangeWithScores(ctx, deliveryLogKey, 0, -1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read delivery log: %w", err)
+		}
+		deliveries = decodeDeliveries(members)
+	}
+
+	matches := deliveries[:0]
+	for _, delivery := range deliveries {
+		if (arg.SubscriptionID == nil || delivery.SubscriptionID == *arg.SubscriptionID) &&
+			(arg.Channel == nil || delivery.Channel == *arg.Channel) &&
+			(arg.Status == nil || delivery.Status == *arg.Status) &&
+			(arg.Since == nil || !delivery.DeliveredAt.Before(*arg.Since)) {
+			matches = append(matches, delivery)
+		}
+	}
+	return paginate(matches, arg.Offset, arg.Limit), nil
+}
+
+// decodeDeliveries decodes deliveries stored as sorted set members,
+// skipping any that don't decode
+func decodeDeliveries(members []redis.Z) []SubscriptionDelivery {
+	var deliveries []SubscriptionDelivery
+	for _, member := range members {
+		data, ok := member.Member.(string)
+		if !ok {
+			continue
+		}
+		var delivery SubscriptionDelivery
+		if err := json.Unmarshal([]byte(data), &delivery); err == nil {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// RecordSubscriptionFailure counts a failed message, disabling the
+// subscription with arg.Reason once arg.Threshold failed in a row
+func (r *repository) RecordSubscriptionFailure(ctx context.Context, arg RecordSubscriptionFailureParams) (Subscription, error) {
+	return r.modifySubscription(ctx, arg.ID, func(subscription *Subscription) {
+		subscription.Failures++
+		if subscription.DisabledAt == nil && arg.Threshold > 0 && subscription.Failures >= arg.Threshold {
+			now := time.Now()
+			subscription.DisabledAt, subscription.DisabledReason = &now, arg.Reason
+		}
+	})
+}
+
+// ResetSubscriptionFailures clears the failure count after a message was delivered
+func (r *repository) ResetSubscriptionFailures(ctx context.Context, id string) error {
+	_, err := r.modifySubscription(ctx, id, func(subscription *Subscription) {
+		subscription.Failures = 0
+	})
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// EnableSubscription clears a subscription's failures and disabled state
+func (r *repository) EnableSubscription(ctx context.Context, id string) (Subscription, error) {
+	return r.modifySubscription(ctx, id, func(subscription *Subscription) {
+		subscription.Failures = 0
+		subscription.DisabledAt, subscription.DisabledReason = nil, nil
+		subscription.UpdatedAt = time.Now()
+	})
+}
+
+// modifySubscription applies modify to a stored subscription and stores it
+func (r *repository) modifySubscription(ctx context.Context, id string, modify func(*Subscription)) (Subscription, error) {
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		subscription, ok := r.subscriptions[id]
+		if !ok {
+			return Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+		}
+		modify(&subscription)
+		r.subscriptions[id] = subscription
+		return subscription, nil
+	}
+
+	subscription, err := r.GetSubscription(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	modify(&subscription)
+	if err := r.writeSubscription(ctx, subscription); err != nil {
+		return Subscription{}, err
+	}
+	return subscription, nil
+}
+
+// writeSubscription stores a subscription in Redis and keeps the due digest
+// and alert indexes in step with it
+func (r *repository) writeSubscription(ctx context.Context, subscription Subscription) error {
+	data, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscription: %w", err)
+	}
+	if err := r.cache.Set(ctx, subscriptionKey(subscription.ID), data, 0); err != nil {
+		return fmt.Errorf("failed to store subscription %s: %w", subscription.ID, err)
+	}
+
+	// Disabled subscriptions leave the indexes the deliverer reads
+	active := subscription.VerifiedAt != nil && subscription.DisabledAt == nil
+	if active && subscription.Kind == SubscriptionDigest && subscription.NextRunAt != nil {
+		member := redis.Z{Score: float64(subscription.NextRunAt.Unix()), Member: subscription.ID}
+		err = r.cache.ZAdd(ctx, dueSubscriptionsKey, member)
+	} else {
+		err = r.cache.ZRem(ctx, dueSubscriptionsKey, subscription.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to index subscription %s: %w", subscription.ID, err)
+	}
+	if active && subscription.Kind == SubscriptionAlert {
+		err = r.cache.SAdd(ctx, alertSubscriptionsKey, subscription.ID)
+	} else {
+		err = r.cache.SRem(ctx, alertSubscriptionsKey, subscription.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to index subscription %s: %w", subscription.ID, err)
+	}
+	return nil
+}
+
+// subscriptionsByID reads the subscriptions with the given IDs, in order,
+// skipping any deleted since they were listed
+func (r *repository) subscriptionsByID(ctx context.Context, ids []string) ([]Subscription, error) {
+	subscriptions := make([]Subscription, 0, len(ids))
+	for _, id := range ids {
+		subscription, err := r.GetSubscription(ctx, id)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+// sortSubscriptions orders subscriptions oldest first
+func sortSubscriptions(subscriptions []Subscription) {
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+		}
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+}
+
+// ownerTemplate identifies a template in memory
+type ownerTemplate struct {
+	owner string
+	name  string
+}
+
+// notificationTemplateKey is the Redis key of an owner's template
+func notificationTemplateKey(owner, name string) string {
+	return fmt.Sprintf("notification_template:%s:%s", owner, name)
+}
+
+// ownerTemplatesKey is the Redis set of the template names an owner has overridden
+func ownerTemplatesKey(owner string) string {
+	return fmt.Sprintf("notification_templates:%s", owner)
+}
+
+// GetNotificationTemplate retrieves an owner's template
+func (r *repository) GetNotificationTemplate(ctx context.Context, arg GetNotificationTemplateParams) (NotificationTemplate, error) {
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		template, ok := r.templates[ownerTemplate{arg.Owner, arg.Name}]
+		if !ok {
+			return NotificationTemplate{}, errs.Errorf(errs.ErrNotFound, "notification template not found: %s", arg.Name)
+		}
+		return template, nil
+	}
+
+	data, err := r.cache.Get(ctx, notificationTemplateKey(arg.Owner, arg.Name))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return NotificationTemplate{}, errs.Errorf(errs.ErrNotFound, "notification template not found: %s", arg.Name)
+	}
+	if err != nil {
+		return NotificationTemplate{}, fmt.Errorf("failed to get notification template %s: %w", arg.Name, err)
+	}
+	var template NotificationTemplate
+	if err := json.Unmarshal(data, &template); err != nil {
+		return NotificationTemplate{}, fmt.Errorf("failed to decode notification template %s: %w", arg.Name, err)
+	}
+	return template, nil
+}
+
+// ListNotificationTemplates returns an owner's templates by name
+func (r *repository) ListNotificationTemplates(ctx context.Context, owner string) ([]NotificationTemplate, error) {
+	var templates []NotificationTemplate
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		for key, template := range r.templates {
+			if key.owner == owner {
+				templates = append(templates, template)
+			}
+		}
+		r.subscriptionsMu.Unlock()
+		sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+		return templates, nil
+	}
+
+	names, err := r.cache.SMembers(ctx, ownerTemplatesKey(owner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification templates: %w", err)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		template, err := r.GetNotificationTemplate(ctx, GetNotificationTemplateParams{Owner: owner, Name: name})
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// UpsertNotificationTemplate stores an owner's template, replacing any
+// stored under its name
+func (r *repository) UpsertNotificationTemplate(ctx context.Context, arg UpsertNotificationTemplateParams) (NotificationTemplate, error) {
+	now := time.Now()
+	template := NotificationTemplate{
+		Owner:     arg.Owner,
+		Name:      arg.Name,
+		Subject:   arg.Subject,
+		Body:      arg.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		key := ownerTemplate{arg.Owner, arg.Name}
+		if stored, ok := r.templates[key]; ok {
+			template.CreatedAt = stored.CreatedAt
+		}
+		r.templates[key] = template
+		return template, nil
+	}
+
+	stored, err := r.GetNotificationTemplate(ctx, GetNotificationTemplateParams{Owner: arg.Owner, Name: arg.Name})
+	if err == nil {
+		template.CreatedAt = stored.CreatedAt
+	} else if !errors.Is(err, errs.ErrNotFound) {
+		return NotificationTemplate{}, err
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return NotificationTemplate{}, fmt.Errorf("failed to marshal notification template: %w", err)
+	}
+	if err := r.cache.Set(ctx, notificationTemplateKey(arg.Owner, arg.Name), data, 0); err != nil {
+		return NotificationTemplate{}, fmt.Errorf("failed to store notification template %s: %w", arg.Name, err)
+	}
+	if err := r.cache.SAdd(ctx, ownerTemplatesKey(arg.Owner), arg.Name); err != nil {
+		return NotificationTemplate{}, fmt.Errorf("failed to index notification template %s: %w", arg.Name, err)
+	}
+	return template, nil
+}
+
+// DeleteNotificationTemplate deletes an owner's template
+func (r *repository) DeleteNotificationTemplate(ctx context.Context, arg DeleteNotificationTemplateParams) error {
+	if r.cache == nil {
+		r.subscriptionsMu.Lock()
+		defer r.subscriptionsMu.Unlock()
+		key := ownerTemplate{arg.Owner, arg.Name}
+		if _, ok := r.templates[key]; !ok {
+			return errs.Errorf(errs.ErrNotFound, "notification template not found: %s", arg.Name)
+		}
+		delete(r.templates, key)
+		return nil
+	}
+
+	if _, err := r.GetNotificationTemplate(ctx, GetNotificationTemplateParams(arg)); err != nil {
+		return err
+	}
+	if err := r.cache.Del(ctx, notificationTemplateKey(arg.Owner, arg.Name)); err != nil {
+		return fmt.Errorf("failed to delete notification template %s: %w", arg.Name, err)
+	}
+	if err := r.cache.SRem(ctx, ownerTemplatesKey(arg.Owner), arg.Name); err != nil {
+		return fmt.Errorf("failed to unindex notification template %s: %w", arg.Name, err)
+	}
+	return nil
+}
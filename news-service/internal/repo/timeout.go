@@ -0,0 +1,327 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+)
+
+// timeoutRepository bounds every repository call with a per-operation deadline,
+// so a hung backend can't hold a request (and its connections) open indefinitely.
+type timeoutRepository struct {
+	repo    Repository
+	timeout time.Duration
+}
+
+// NewTimeoutRepository wraps repo so every call runs with at most timeout
+// and is timed in metrics. A non-positive timeout leaves calls unbounded.
+func NewTimeoutRepository(repo Repository, timeout time.Duration) Repository {
+	return &timeoutRepository{repo: repo, timeout: timeout}
+}
+
+// begin derives the operation context; done cancels it, records how long the
+// operation took and reports whether the operation's own deadline (rather
+// than the caller's) cut it off
+func (r *timeoutRepository) begin(ctx context.Context, op string) (context.Context, func(error) error) {
+	start := time.Now()
+	opCtx, cancel := context.WithCancel(ctx)
+	if r.timeout > 0 {
+		opCtx, cancel = context.WithTimeout(ctx, r.timeout)
+	}
+	return opCtx, func(err error) error {
+		defer cancel()
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		metrics.RepositoryDuration.WithLabelValues(op, result).Observe(time.Since(start).Seconds())
+		if err != nil && opCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			metrics.DeadlineExceeded.WithLabelValues("repository", op).Inc()
+			return errs.Errorf(errs.ErrUnavailable, "%s timed out after %s: %w", op, r.timeout, err)
+		}
+		return err
+	}
+}
+
+func (r *timeoutRepository) GetArticleByID(ctx context.Context, id string) (Article, error) {
+	ctx, done := r.begin(ctx, "GetArticleByID")
+	article, err := r.repo.GetArticleByID(ctx, id)
+	return article, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesByCategory(ctx context.Context, arg GetArticlesByCategoryParams) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesByCategory")
+	articles, err := r.repo.GetArticlesByCategory(ctx, arg)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesBySource(ctx context.Context, arg GetArticlesBySourceParams) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesBySource")
+	articles, err := r.repo.GetArticlesBySource(ctx, arg)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesByScore(ctx context.Context, arg GetArticlesByScoreParams) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesByScore")
+	articles, err := r.repo.GetArticlesByScore(ctx, arg)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesByPlace(ctx context.Context, arg GetArticlesByPlaceParams) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesByPlace")
+	articles, err := r.repo.GetArticlesByPlace(ctx, arg)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error) {
+	ctx, done := r.begin(ctx, "SearchArticles")
+	rows, err := r.repo.SearchArticles(ctx, arg)
+	return rows, done(err)
+}
+
+func (r *timeoutRepository) GetNearbyArticles(ctx context.Context, arg GetNearbyArticlesParams) ([]GetNearbyArticlesRow, error) {
+	ctx, done := r.begin(ctx, "GetNearbyArticles")
+	rows, err := r.repo.GetNearbyArticles(ctx, arg)
+	return rows, done(err)
+}
+
+func (r *timeoutRepository) GetRecentEventsByGeohash(ctx context.Context, since time.Time) ([]GetRecentEventsByGeohashRow, error) {
+	ctx, done := r.begin(ctx, "GetRecentEventsByGeohash")
+	rows, err := r.repo.GetRecentEventsByGeohash(ctx, since)
+	return rows, done(err)
+}
+
+func (r *timeoutRepository) GetArticleSummary(ctx context.Context, articleID string) (ArticleSummary, error) {
+	ctx, done := r.begin(ctx, "GetArticleSummary")
+	summary, err := r.repo.GetArticleSummary(ctx, articleID)
+	return summary, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesWithoutSummary(ctx context.Context, limit int32) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesWithoutSummary")
+	articles, err := r.repo.GetArticlesWithoutSummary(ctx, limit)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticleEventCounts(ctx context.Context, arg GetArticleEventCountsParams) ([]ArticleEventCount, error) {
+	ctx, done := r.begin(ctx, "GetArticleEventCounts")
+	counts, err := r.repo.GetArticleEventCounts(ctx, arg)
+	return counts, done(err)
+}
+
+func (r *timeoutRepository) ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error) {
+	ctx, done := r.begin(ctx, "ListArticles")
+	articles, err := r.repo.ListArticles(ctx, arg)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticleVersions(ctx context.Context, urlHashes []string) ([]ArticleVersion, error) {
+	ctx, done := r.begin(ctx, "GetArticleVersions")
+	versions, err := r.repo.GetArticleVersions(ctx, urlHashes)
+	return versions, done(err)
+}
+
+func (r *timeoutRepository) SemanticSearchArticles(ctx context.Context, arg SemanticSearchArticlesParams) ([]SemanticSearchArticlesRow, error) {
+	ctx, done := r.begin(ctx, "SemanticSearchArticles")
+	rows, err := r.repo.SemanticSearchArticles(ctx, arg)
+	return rows, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesWithoutEmbedding(ctx context.Context, arg GetArticlesWithoutEmbeddingParams) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesWithoutEmbedding")
+	articles, err := r.repo.GetArticlesWithoutEmbedding(ctx, arg)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticleEntities(ctx context.Context, articleID string) ([]ArticleEntity, error) {
+	ctx, done := r.begin(ctx, "GetArticleEntities")
+	entities, err := r.repo.GetArticleEntities(ctx, articleID)
+	return entities, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesWithoutEntities(ctx context.Context, limit int32) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesWithoutEntities")
+	articles, err := r.repo.GetArticlesWithoutEntities(ctx, limit)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetArticlesWithoutPlace(ctx context.Context, limit int32) ([]Article, error) {
+	ctx, done := r.begin(ctx, "GetArticlesWithoutPlace")
+	articles, err := r.repo.GetArticlesWithoutPlace(ctx, limit)
+	return articles, done(err)
+}
+
+func (r *timeoutRepository) GetFeedbackStats(ctx context.Context, since time.Time) ([]FeedbackStat, error) {
+	ctx, done := r.begin(ctx, "GetFeedbackStats")
+	stats, err := r.repo.GetFeedbackStats(ctx, since)
+	return stats, done(err)
+}
+
+func (r *timeoutRepository) GetSummariesWithNegativeFeedback(ctx context.Context, arg GetSummariesWithNegativeFeedbackParams) ([]SummaryFeedback, error) {
+	ctx, done := r.begin(ctx, "GetSummariesWithNegativeFeedback")
+	summaries, err := r.repo.GetSummariesWithNegativeFeedback(ctx, arg)
+	return summaries, done(err)
+}
+
+func (r *timeoutRepository) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	ctx, done := r.begin(ctx, "GetSubscription")
+	subscription, err := r.repo.GetSubscription(ctx, id)
+	return subscription, done(err)
+}
+
+func (r *timeoutRepository) ListSubscriptions(ctx context.Context, arg ListSubscriptionsParams) ([]Subscription, error) {
+	ctx, done := r.begin(ctx, "ListSubscriptions")
+	subscriptions, err := r.repo.ListSubscriptions(ctx, arg)
+	return subscriptions, done(err)
+}
+
+func (r *timeoutRepository) GetNotificationTemplate(ctx context.Context, arg GetNotificationTemplateParams) (NotificationTemplate, error) {
+	ctx, done := r.begin(ctx, "GetNotificationTemplate")
+	template, err := r.repo.GetNotificationTemplate(ctx, arg)
+	return template, done(err)
+}
+
+func (r *timeoutRepository) ListNotificationTemplates(ctx context.Context, owner string) ([]NotificationTemplate, error) {
+	ctx, done := r.begin(ctx, "ListNotificationTemplates")
+	templates, err := r.repo.ListNotificationTemplates(ctx, owner)
+	return templates, done(err)
+}
+
+func (r *timeoutRepository) GetDueSubscriptions(ctx context.Context, arg GetDueSubscriptionsParams) ([]Subscription, error) {
+	ctx, done := r.begin(ctx, "GetDueSubscriptions")
+	subscriptions, err := r.repo.GetDueSubscriptions(ctx, arg)
+	return subscriptions, done(err)
+}
+
+func (r *timeoutRepository) GetAlertSubscriptions(ctx context.Context) ([]Subscription, error) {
+	ctx, done := r.begin(ctx, "GetAlertSubscriptions")
+	subscriptions, err := r.repo.GetAlertSubscriptions(ctx)
+	return subscriptions, done(err)
+}
+
+func (r *timeoutRepository) ListSubscriptionDeliveries(ctx context.Context, arg ListSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	ctx, done := r.begin(ctx, "ListSubscriptionDeliveries")
+	deliveries, err := r.repo.ListSubscriptionDeliveries(ctx, arg)
+	return deliveries, done(err)
+}
+
+func (r *timeoutRepository) SearchSubscriptionDeliveries(ctx context.Context, arg SearchSubscriptionDeliveriesParams) ([]SubscriptionDelivery, error) {
+	ctx, done := r.begin(ctx, "SearchSubscriptionDeliveries")
+	deliveries, err := r.repo.SearchSubscriptionDeliveries(ctx, arg)
+	return deliveries, done(err)
+}
+
+func (r *timeoutRepository) CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error) {
+	ctx, done := r.begin(ctx, "CreateArticle")
+	article, err := r.repo.CreateArticle(ctx, arg)
+	return article, done(err)
+}
+
+func (r *timeoutRepository) BulkCreateArticles(ctx context.Context, args []CreateArticleParams) ([]BulkCreateResult, error) {
+	ctx, done := r.begin(ctx, "BulkCreateArticles")
+	results, err := r.repo.BulkCreateArticles(ctx, args)
+	return results, done(err)
+}
+
+func (r *timeoutRepository) CreateArticleSummary(ctx context.Context, arg CreateArticleSummaryParams) (ArticleSummary, error) {
+	ctx, done := r.begin(ctx, "CreateArticleSummary")
+	summary, err := r.repo.CreateArticleSummary(ctx, arg)
+	return summary, done(err)
+}
+
+func (r *timeoutRepository) CreateArticleEmbeddings(ctx context.Context, args []CreateArticleEmbeddingParams) error {
+	ctx, done := r.begin(ctx, "CreateArticleEmbeddings")
+	return done(r.repo.CreateArticleEmbeddings(ctx, args))
+}
+
+func (r *timeoutRepository) ReplaceArticleEntities(ctx context.Context, args []ReplaceArticleEntitiesParams) error {
+	ctx, done := r.begin(ctx, "ReplaceArticleEntities")
+	return done(r.repo.ReplaceArticleEntities(ctx, args))
+}
+
+func (r *timeoutRepository) SetArticlePlaces(ctx context.Context, args []SetArticlePlaceParams) error {
+	ctx, done := r.begin(ctx, "SetArticlePlaces")
+	return done(r.repo.SetArticlePlaces(ctx, args))
+}
+
+func (r *timeoutRepository) CreateUserEvent(ctx context.Context, arg CreateUserEventParams) (UserEvent, error) {
+	ctx, done := r.begin(ctx, "CreateUserEvent")
+	event, err := r.repo.CreateUserEvent(ctx, arg)
+	return event, done(err)
+}
+
+func (r *timeoutRepository) CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (Feedback, error) {
+	ctx, done := r.begin(ctx, "CreateFeedback")
+	feedback, err := r.repo.CreateFeedback(ctx, arg)
+	return feedback, done(err)
+}
+
+func (r *timeoutRepository) UpdateArticle(ctx context.Context, arg UpdateArticleParams) (Article, error) {
+	ctx, done := r.begin(ctx, "UpdateArticle")
+	article, err := r.repo.UpdateArticle(ctx, arg)
+	return article, done(err)
+}
+
+func (r *timeoutRepository) DeleteArticle(ctx context.Context, id string) error {
+	ctx, done := r.begin(ctx, "DeleteArticle")
+	return done(r.repo.DeleteArticle(ctx, id))
+}
+
+func (r *timeoutRepository) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error) {
+	ctx, done := r.begin(ctx, "CreateSubscription")
+	subscription, err := r.repo.CreateSubscription(ctx, arg)
+	return subscription, done(err)
+}
+
+func (r *timeoutRepository) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (Subscription, error) {
+	ctx, done := r.begin(ctx, "UpdateSubscription")
+	subscription, err := r.repo.UpdateSubscription(ctx, arg)
+	return subscription, done(err)
+}
+
+func (r *timeoutRepository) DeleteSubscription(ctx context.Context, id string) error {
+	ctx, done := r.begin(ctx, "DeleteSubscription")
+	return done(r.repo.DeleteSubscription(ctx, id))
+}
+
+func (r *timeoutRepository) ClaimSubscriptionRun(ctx context.Context, arg ClaimSubscriptionRunParams) (bool, error) {
+	ctx, done := r.begin(ctx, "ClaimSubscriptionRun")
+	claimed, err := r.repo.ClaimSubscriptionRun(ctx, arg)
+	return claimed, done(err)
+}
+
+func (r *timeoutRepository) CreateSubscriptionDelivery(ctx context.Context, arg CreateSubscriptionDeliveryParams) (SubscriptionDelivery, error) {
+	ctx, done := r.begin(ctx, "CreateSubscriptionDelivery")
+	delivery, err := r.repo.CreateSubscriptionDelivery(ctx, arg)
+	return delivery, done(err)
+}
+
+func (r *timeoutRepository) RecordSubscriptionFailure(ctx context.Context, arg RecordSubscriptionFailureParams) (Subscription, error) {
+	ctx, done := r.begin(ctx, "RecordSubscriptionFailure")
+	subscription, err := r.repo.RecordSubscriptionFailure(ctx, arg)
+	return subscription, done(err)
+}
+
+func (r *timeoutRepository) ResetSubscriptionFailures(ctx context.Context, id string) error {
+	ctx, done := r.begin(ctx, "ResetSubscriptionFailures")
+	return done(r.repo.ResetSubscriptionFailures(ctx, id))
+}
+
+func (r *timeoutRepository) EnableSubscription(ctx context.Context, id string) (Subscription, error) {
+	ctx, done := r.begin(ctx, "EnableSubscription")
+	subscription, err := r.repo.EnableSubscription(ctx, id)
+	return subscription, done(err)
+}
+
+func (r *timeoutRepository) UpsertNotificationTemplate(ctx context.Context, arg UpsertNotificationTemplateParams) (NotificationTemplate, error) {
+	ctx, done := r.begin(ctx, "UpsertNotificationTemplate")
+	template, err := r.repo.UpsertNotificationTemplate(ctx, arg)
+	return template, done(err)
+}
+
+func (r *timeoutRepository) DeleteNotificationTemplate(ctx context.Context, arg DeleteNotificationTemplateParams) error {
+	ctx, done := r.begin(ctx, "DeleteNotificationTemplate")
+	return done(r.repo.DeleteNotificationTemplate(ctx, arg))
+}
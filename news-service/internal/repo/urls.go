@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters that never change which article a URL points at
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "mc_cid": true, "mc_eid": true,
+	"igshid": true, "ref": true, "ref_src": true, "cmpid": true, "ocid": true, "smid": true,
+}
+
+// CanonicalURL normalizes an article URL so that links differing only in
+// scheme, letter case of the host, a leading "www.", default ports, fragments,
+// trailing slashes, tracking parameters or query parameter order compare equal.
+// Unparseable input is returned trimmed but otherwise unchanged.
+func CanonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	// http and https serve the same article
+	u.Scheme = "https"
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	// Drop tracking parameters and sort the rest
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// URLHash returns the dedup key of an article URL: the hex SHA-256 of its canonical form
+func URLHash(raw string) string {
+	sum := sha256.Sum256([]byte(CanonicalURL(raw)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ArticleContentHash returns the hex SHA-256 of the fields that decide whether
+// a re-ingested article changed: title, description and URL. Other fields,
+// such as categories or the relevance score, are not compared.
+func ArticleContentHash(title string, description *string, rawURL string) string {
+	desc := ""
+	if description != nil {
+		desc = *description
+	}
+	sum := sha256.Sum256([]byte(title + "\n" + desc + "\n" + strings.TrimSpace(rawURL)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package categorize
+
+import (
+	"context"
+	"sync"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/llm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Classifier fills in the categories of articles that arrive without any,
+// asking the LLM to place them in llm.Categories before they are stored.
+// Articles it can't classify are stored without categories, as before.
+type Classifier struct {
+	llm         llm.LLMClient
+	concurrency int
+}
+
+// NewClassifier creates a classifier making up to concurrency LLM calls at
+// once for a batch
+func NewClassifier(client llm.LLMClient, concurrency int) *Classifier {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Classifier{llm: client, concurrency: concurrency}
+}
+
+// Classify sets the categories of arg when it has none. A nil Classifier
+// leaves it unchanged.
+func (c *Classifier) Classify(ctx context.Context, arg *repo.CreateArticleParams) {
+	if c == nil || len(arg.Category) > 0 {
+		return
+	}
+
+	description := ""
+	if arg.Description != nil {
+		description = *arg.Description
+	}
+	categories, err := c.llm.Categorize(llm.WithEndpoint(ctx, "ingest"), arg.Title, description)
+	if err != nil {
+		metrics.ArticlesCategorized.WithLabelValues("failed").Inc()
+		log.Warn().Err(err).Str("url", arg.URL).Msg("Failed to categorize article")
+		return
+	}
+	metrics.ArticlesCategorized.WithLabelValues("categorized").Inc()
+	arg.Category = categories
+}
+
+// ClassifyAll sets the categories of the articles among args that have none,
+// classifying several at once
+func (c *Classifier) ClassifyAll(ctx context.Context, args []repo.CreateArticleParams) {
+	if c == nil {
+		return
+	}
+
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for i := range args {
+		if len(args[i].Category) > 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(arg *repo.CreateArticleParams) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.Classify(ctx, arg)
+		}(&args[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,148 @@
+// Package embeddings keeps article embeddings current in the background, so
+// the semantic search strategy can rank articles by meaning.
+package embeddings
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/llm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Embedder embeds texts with the model it names
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	EmbeddingModel() string
+}
+
+// Options tunes a Backfiller. Zero values use the defaults noted on each field.
+type Options struct {
+	// BatchSize is the number of articles embedded per request, default 64
+	BatchSize int
+	// IdleInterval is the wait before looking again once every article has a
+	// current embedding, or after a batch fails, default 1m
+	IdleInterval time.Duration
+}
+
+// Backfiller embeds articles that have no embedding from the current model,
+// or whose content changed since they were embedded, newest first
+type Backfiller struct {
+	repo     repo.Repository
+	embedder Embedder
+	opts     Options
+
+	done chan bool
+	wg   sync.WaitGroup
+}
+
+// NewBackfiller creates a backfiller that embeds the articles of repository with embedder
+func NewBackfiller(repository repo.Repository, embedder Embedder, opts Options) *Backfiller {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 64
+	}
+	if opts.IdleInterval <= 0 {
+		opts.IdleInterval = time.Minute
+	}
+	return &Backfiller{
+		repo:     repository,
+		embedder: embedder,
+		opts:     opts,
+		done:     make(chan bool),
+	}
+}
+
+// Start runs the backfill until Stop is called or ctx is cancelled
+func (b *Backfiller) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		<-b.done
+	}()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			wait := b.opts.IdleInterval
+			if b.runBatch(ctx) {
+				wait = 0
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Str("model", b.embedder.EmbeddingModel()).Int("batch_size", b.opts.BatchSize).Msg("Embedding backfill started")
+}
+
+// Stop stops the backfill and waits for the batch in progress to finish
+func (b *Backfiller) Stop() {
+	close(b.done)
+	b.wg.Wait()
+	log.Info().Msg("Embedding backfill stopped")
+}
+
+// runBatch embeds one batch of articles and reports whether to fetch the next
+// batch right away: false when the batch wasn't full or failed
+func (b *Backfiller) runBatch(ctx context.Context) bool {
+	model := b.embedder.EmbeddingModel()
+	articles, err := b.repo.GetArticlesWithoutEmbedding(ctx, repo.GetArticlesWithoutEmbeddingParams{
+		Model: model,
+		Limit: int32(b.opts.BatchSize),
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to list articles without embeddings")
+		}
+		return false
+	}
+	if len(articles) == 0 {
+		return false
+	}
+
+	texts := make([]string, len(articles))
+	for i, article := range articles {
+		description := ""
+		if article.Description != nil {
+			description = *article.Description
+		}
+		texts[i] = llm.EmbeddingText(article.Title, description)
+	}
+	vectors, err := b.embedder.Embed(ctx, texts)
+	if err != nil {
+		if ctx.Err() == nil {
+			metrics.EmbeddingBackfill.WithLabelValues("error").Add(float64(len(articles)))
+			log.Warn().Err(err).Int("articles", len(articles)).Msg("Failed to embed articles, backing off")
+		}
+		return false
+	}
+
+	params := make([]repo.CreateArticleEmbeddingParams, len(articles))
+	for i, article := range articles {
+		params[i] = repo.CreateArticleEmbeddingParams{
+			ArticleID:   article.ID,
+			Model:       model,
+			ContentHash: repo.ArticleContentHash(article.Title, article.Description, article.URL),
+			Embedding:   vectors[i],
+		}
+	}
+	if err := b.repo.CreateArticleEmbeddings(ctx, params); err != nil {
+		if ctx.Err() == nil {
+			metrics.EmbeddingBackfill.WithLabelValues("error").Add(float64(len(articles)))
+			log.Error().Err(err).Int("articles", len(articles)).Msg("Failed to store article embeddings")
+		}
+		return false
+	}
+	metrics.EmbeddingBackfill.WithLabelValues("embedded").Add(float64(len(articles)))
+	return len(articles) == b.opts.BatchSize
+}
@@ -0,0 +1,201 @@
+// Package entities extracts the people, organizations and locations articles
+// mention in the background, so queries can be filtered by them.
+package entities
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/llm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Extractor extracts the named entities of an article
+type Extractor interface {
+	ExtractEntities(ctx context.Context, title, description string) (*llm.ArticleEntities, error)
+}
+
+// Options tunes a Backfiller. Zero values use the defaults noted on each field.
+type Options struct {
+	// BatchSize is the number of articles fetched and stored at a time, default 32
+	BatchSize int
+	// Concurrency bounds extractions running at once, default 4
+	Concurrency int
+	// IdleInterval is the wait before looking again once every article's
+	// entities are current, or after an extraction fails, default 1m
+	IdleInterval time.Duration
+}
+
+// Backfiller extracts the entities of articles that were never extracted, or
+// whose content changed since they were, newest first
+type Backfiller struct {
+	repo      repo.Repository
+	extractor Extractor
+	opts      Options
+
+	done chan bool
+	wg   sync.WaitGroup
+}
+
+// NewBackfiller creates a backfiller that extracts the entities of the
+// articles of repository with extractor
+func NewBackfiller(repository repo.Repository, extractor Extractor, opts Options) *Backfiller {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 32
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 4
+	}
+	if opts.IdleInterval <= 0 {
+		opts.IdleInterval = time.Minute
+	}
+	return &Backfiller{
+		repo:      repository,
+		extractor: extractor,
+		opts:      opts,
+		done:      make(chan bool),
+	}
+}
+
+// Start runs the backfill until Stop is called or ctx is cancelled
+func (b *Backfiller) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		<-b.done
+	}()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			wait := b.opts.IdleInterval
+			if b.runBatch(ctx) {
+				wait = 0
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Int("batch_size", b.opts.BatchSize).Int("concurrency", b.opts.Concurrency).Msg("Entity backfill started")
+}
+
+// Stop stops the backfill and waits for the batch in progress to finish
+func (b *Backfiller) Stop() {
+	close(b.done)
+	b.wg.Wait()
+	log.Info().Msg("Entity backfill stopped")
+}
+
+// runBatch extracts the entities of one batch of articles, stores those that
+// succeeded and reports whether to fetch the next batch right away: false
+// when the batch wasn't full or any extraction failed, which suggests the
+// provider is struggling
+func (b *Backfiller) runBatch(ctx context.Context) bool {
+	articles, err := b.repo.GetArticlesWithoutEntities(ctx, int32(b.opts.BatchSize))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to list articles without entities")
+		}
+		return false
+	}
+	if len(articles) == 0 {
+		return false
+	}
+
+	var mu sync.Mutex
+	var params []repo.ReplaceArticleEntitiesParams
+	failed := 0
+	jobs := make(chan repo.Article)
+	var wg sync.WaitGroup
+	for i := 0; i < b.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for article := range jobs {
+				extracted, ok := b.extract(ctx, article)
+				mu.Lock()
+				if ok {
+					params = append(params, extracted)
+				} else {
+					failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, article := range articles {
+		select {
+		case jobs <- article:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	if len(params) > 0 {
+		if err := b.repo.ReplaceArticleEntities(ctx, params); err != nil {
+			if ctx.Err() == nil {
+				metrics.EntityBackfill.WithLabelValues("error").Add(float64(len(params)))
+				log.Error().Err(err).Int("articles", len(params)).Msg("Failed to store article entities")
+			}
+			return false
+		}
+		metrics.EntityBackfill.WithLabelValues("extracted").Add(float64(len(params)))
+	}
+	if failed > 0 {
+		log.Warn().Int("failed", failed).Int("articles", len(articles)).Msg("Entity extraction failed, backing off")
+		return false
+	}
+	return len(articles) == b.opts.BatchSize
+}
+
+// extract extracts one article's entities and reports whether it succeeded
+func (b *Backfiller) extract(ctx context.Context, article repo.Article) (repo.ReplaceArticleEntitiesParams, bool) {
+	description := ""
+	if article.Description != nil {
+		description = *article.Description
+	}
+	extracted, err := b.extractor.ExtractEntities(ctx, article.Title, description)
+	if err != nil {
+		if ctx.Err() == nil {
+			metrics.EntityBackfill.WithLabelValues("error").Inc()
+			log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to extract article entities")
+		}
+		return repo.ReplaceArticleEntitiesParams{}, false
+	}
+
+	var found []repo.ArticleEntity
+	for _, group := range []struct {
+		entityType string
+		names      []string
+	}{
+		{repo.EntityPerson, extracted.People},
+		{repo.EntityOrganization, extracted.Organizations},
+		{repo.EntityLocation, extracted.Locations},
+	} {
+		for _, name := range group.names {
+			found = append(found, repo.ArticleEntity{Type: group.entityType, Name: name})
+		}
+	}
+	return repo.ReplaceArticleEntitiesParams{
+		ArticleID:   article.ID,
+		ContentHash: repo.ArticleContentHash(article.Title, article.Description, article.URL),
+		Entities:    found,
+	}, true
+}
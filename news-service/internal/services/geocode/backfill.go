@@ -0,0 +1,145 @@
+package geocode
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// BackfillOptions tunes a Backfiller. Zero values use the defaults noted on
+// each field.
+type BackfillOptions struct {
+	// BatchSize is the number of articles fetched and stored at a time, default 20
+	BatchSize int
+	// IdleInterval is the wait before looking again once every article is
+	// geocoded, or after a lookup fails, default 5m
+	IdleInterval time.Duration
+}
+
+// Backfiller reverse geocodes, in the background, the articles with
+// coordinates but no place: those stored before geocoding was enabled, or
+// ingested while the geocoder was failing. Lookups run one at a time, as the
+// geocoder spaces out its requests anyway.
+type Backfiller struct {
+	repo     repo.Repository
+	geocoder Geocoder
+	opts     BackfillOptions
+
+	done chan bool
+	wg   sync.WaitGroup
+}
+
+// NewBackfiller creates a backfiller that places the articles of repository
+// with geocoder
+func NewBackfiller(repository repo.Repository, geocoder Geocoder, opts BackfillOptions) *Backfiller {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 20
+	}
+	if opts.IdleInterval <= 0 {
+		opts.IdleInterval = 5 * time.Minute
+	}
+	return &Backfiller{
+		repo:     repository,
+		geocoder: geocoder,
+		opts:     opts,
+		done:     make(chan bool),
+	}
+}
+
+// Start runs the backfill until Stop is called or ctx is cancelled
+func (b *Backfiller) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		<-b.done
+	}()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			wait := b.opts.IdleInterval
+			if b.runBatch(ctx) {
+				wait = 0
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Int("batch_size", b.opts.BatchSize).Msg("Place backfill started")
+}
+
+// Stop stops the backfill and waits for the batch in progress to finish
+func (b *Backfiller) Stop() {
+	close(b.done)
+	b.wg.Wait()
+	log.Info().Msg("Place backfill stopped")
+}
+
+// runBatch geocodes one batch of articles, stores the places found so far
+// and reports whether to fetch the next batch right away: false when the
+// batch wasn't full or a lookup failed, which suggests the geocoder is
+// struggling
+func (b *Backfiller) runBatch(ctx context.Context) bool {
+	articles, err := b.repo.GetArticlesWithoutPlace(ctx, int32(b.opts.BatchSize))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to list articles without a place")
+		}
+		return false
+	}
+	if len(articles) == 0 {
+		return false
+	}
+
+	var params []repo.SetArticlePlaceParams
+	failed := false
+	for _, article := range articles {
+		place, err := b.geocoder.Place(ctx, *article.Latitude, *article.Longitude)
+		if err != nil {
+			if ctx.Err() == nil {
+				metrics.PlaceBackfill.WithLabelValues("error").Inc()
+				log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to geocode article, backing off")
+			}
+			failed = true
+			break
+		}
+		params = append(params, repo.SetArticlePlaceParams{
+			ArticleID: article.ID,
+			Latitude:  *article.Latitude,
+			Longitude: *article.Longitude,
+			City:      place.City,
+			Region:    place.Region,
+			Country:   place.Country,
+		})
+	}
+
+	if len(params) > 0 {
+		if err := b.repo.SetArticlePlaces(ctx, params); err != nil {
+			if ctx.Err() == nil {
+				metrics.PlaceBackfill.WithLabelValues("error").Add(float64(len(params)))
+				log.Error().Err(err).Int("articles", len(params)).Msg("Failed to store article places")
+			}
+			return false
+		}
+		for _, param := range params {
+			if param.Country == "" {
+				metrics.PlaceBackfill.WithLabelValues("unknown").Inc()
+			} else {
+				metrics.PlaceBackfill.WithLabelValues("geocoded").Inc()
+			}
+		}
+	}
+	return !failed && len(articles) == b.opts.BatchSize
+}
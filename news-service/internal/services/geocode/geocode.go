@@ -0,0 +1,178 @@
+// Package geocode looks up the administrative areas that contain a location,
+// so nearby queries that find nothing around a small town can widen to the
+// surrounding metro area or region, the place names of article
+// coordinates, so articles can be filtered by country, and the places a name
+// may refer to, so ambiguous place names in queries aren't silently resolved.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Area levels, from the smallest
+const (
+	LevelCity   = "city"
+	LevelCounty = "county"
+	LevelState  = "state"
+)
+
+// Area is an administrative area containing a location, as a circle around
+// its centre that covers its bounding box
+type Area struct {
+	Name     string  `json:"name"`
+	Level    string  `json:"level"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km"`
+}
+
+// Place names the settlement, region and country at a location. Country is
+// an ISO 3166-1 alpha-2 code; fields are "" when unknown.
+type Place struct {
+	City    string `json:"city"`
+	Region  string `json:"region"`
+	Country string `json:"country"`
+}
+
+// Location is a place a name may refer to. Importance ranks how prominent it
+// is, from 0 to 1, so the Paris in France outranks the one in Texas.
+type Location struct {
+	Name       string  `json:"name"`
+	Region     string  `json:"region,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	Importance float64 `json:"importance"`
+}
+
+// Geocoder returns the areas containing a location, smallest first, the
+// place at a location, and the locations a place name may refer to, most
+// important first
+type Geocoder interface {
+	Areas(ctx context.Context, lat, lon float64) ([]Area, error)
+	Place(ctx context.Context, lat, lon float64) (Place, error)
+	Search(ctx context.Context, name, country string) ([]Location, error)
+}
+
+// areasTTL is how long a location's areas and place are cached; boundaries
+// rarely move
+const areasTTL = 7 * 24 * time.Hour
+
+// CachedGeocoder remembers the areas of locations in Redis, by coordinates
+// rounded to about a kilometre, so repeated queries from a town don't wait on
+// the geocoder or spend its quota
+type CachedGeocoder struct {
+	geocoder Geocoder
+	cache    *cache.RedisCache
+}
+
+// NewCachedGeocoder caches the answers of geocoder in redisCache
+func NewCachedGeocoder(geocoder Geocoder, redisCache *cache.RedisCache) *CachedGeocoder {
+	return &CachedGeocoder{geocoder: geocoder, cache: redisCache}
+}
+
+// Areas returns the cached areas of the location, asking the geocoder on a miss
+func (c *CachedGeocoder) Areas(ctx context.Context, lat, lon float64) ([]Area, error) {
+	key := cache.GeocodeKey(lat, lon)
+	if data, err := c.cache.Get(ctx, key); err == nil {
+		var areas []Area
+		if err := json.Unmarshal(data, &areas); err == nil {
+			return areas, nil
+		}
+	}
+
+	areas, err := c.geocoder.Areas(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cache.Set(ctx, key, areas, areasTTL); err != nil {
+		log.Warn().Err(err).Msg("Failed to cache geocoded areas")
+	}
+	return areas, nil
+}
+
+// Place returns the cached place at the location, asking the geocoder on a miss
+func (c *CachedGeocoder) Place(ctx context.Context, lat, lon float64) (Place, error) {
+	key := cache.PlaceKey(lat, lon)
+	if data, err := c.cache.Get(ctx, key); err == nil {
+		var place Place
+		if err := json.Unmarshal(data, &place); err == nil {
+			return place, nil
+		}
+	}
+
+	place, err := c.geocoder.Place(ctx, lat, lon)
+	if err != nil {
+		return Place{}, err
+	}
+	if err := c.cache.Set(ctx, key, place, areasTTL); err != nil {
+		log.Warn().Err(err).Msg("Failed to cache geocoded place")
+	}
+	return place, nil
+}
+
+// Search returns the cached locations named name, in country when it isn't
+// "", asking the geocoder on a miss
+func (c *CachedGeocoder) Search(ctx context.Context, name, country string) ([]Location, error) {
+	key := cache.GeocodeSearchKey(name, country)
+	if data, err := c.cache.Get(ctx, key); err == nil {
+		var locations []Location
+		if err := json.Unmarshal(data, &locations); err == nil {
+			return locations, nil
+		}
+	}
+
+	locations, err := c.geocoder.Search(ctx, name, country)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cache.Set(ctx, key, locations, areasTTL); err != nil {
+		log.Warn().Err(err).Msg("Failed to cache geocoded locations")
+	}
+	return locations, nil
+}
+
+// boundingRadiusKm is the distance from the centre of a bounding box to its
+// farthest corner
+func boundingRadiusKm(centerLat, centerLon, minLat, maxLat, minLon, maxLon float64) float64 {
+	radius := 0.0
+	for _, lat := range []float64{minLat, maxLat} {
+		for _, lon := range []float64{minLon, maxLon} {
+			radius = math.Max(radius, distanceKm(centerLat, centerLon, lat, lon))
+		}
+	}
+	return radius
+}
+
+// distanceKm is the great-circle distance between two points
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	lat1Rad, lat2Rad := lat1*math.Pi/180, lat2*math.Pi/180
+	deltaLat := (lat2 - lat1) * math.Pi / 180
+	deltaLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// NormalizeCountry returns the ISO 3166-1 alpha-2 code of a country code
+// given in either case, reporting false when it isn't two letters. An empty
+// code is valid and matches every country.
+func NormalizeCountry(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return "", true
+	}
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return "", false
+	}
+	return code, true
+}
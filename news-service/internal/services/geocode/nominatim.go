@@ -0,0 +1,272 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"news-system/internal/version"
+
+	"golang.org/x/time/rate"
+)
+
+// nominatimZooms are the reverse lookup zoom levels of each area level:
+// Nominatim returns the area containing the point at the zoom's detail
+var nominatimZooms = []struct {
+	level string
+	zoom  int
+}{
+	{LevelCity, 10},
+	{LevelCounty, 8},
+	{LevelState, 5},
+}
+
+// NominatimGeocoder geocodes with a Nominatim server, such as
+// OpenStreetMap's. Lookups are spaced out to stay within its usage policy of
+// one request per second.
+type NominatimGeocoder struct {
+	baseURL string
+	client  *http.Client
+	limiter *rate.Limiter
+	timeout time.Duration
+}
+
+// NewNominatimGeocoder creates a geocoder for the Nominatim server at baseURL
+// whose lookups, of up to three requests each, take at most timeout
+func NewNominatimGeocoder(baseURL string, timeout time.Duration) *NominatimGeocoder {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &NominatimGeocoder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{},
+		limiter: rate.NewLimiter(rate.Every(time.Second), 1),
+		timeout: timeout,
+	}
+}
+
+type nominatimPlace struct {
+	Name        string           `json:"name"`
+	DisplayName string           `json:"display_name"`
+	BoundingBox []string         `json:"boundingbox"`
+	Address     nominatimAddress `json:"address"`
+	Error       string           `json:"error"`
+}
+
+// nominatimAddress is the part of a reverse lookup's address details naming
+// the settlement, state and country; smaller settlements have no city
+type nominatimAddress struct {
+	City         string `json:"city"`
+	Town         string `json:"town"`
+	Village      string `json:"village"`
+	Municipality string `json:"municipality"`
+	State        string `json:"state"`
+	CountryCode  string `json:"country_code"`
+}
+
+// Areas looks up the city, county and state containing the location, leaving
+// out levels Nominatim has no area for and repeats of the level below, e.g.
+// a city that is its own county
+func (g *NominatimGeocoder) Areas(ctx context.Context, lat, lon float64) ([]Area, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	var areas []Area
+	for _, level := range nominatimZooms {
+		area, ok, err := g.reverse(ctx, lat, lon, level.zoom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the %s around %.4f,%.4f: %w", level.level, lat, lon, err)
+		}
+		if !ok || (len(areas) > 0 && areas[len(areas)-1].Name == area.Name && areas[len(areas)-1].RadiusKm >= area.RadiusKm) {
+			continue
+		}
+		area.Level = level.level
+		areas = append(areas, area)
+	}
+	return areas, nil
+}
+
+// Place looks up the city, region and country of the location in a single
+// request at city zoom. A location outside any country, e.g. at sea, has no
+// place.
+func (g *NominatimGeocoder) Place(ctx context.Context, lat, lon float64) (Place, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	place, err := g.lookup(ctx, lat, lon, nominatimZooms[0].zoom, true)
+	if err != nil {
+		return Place{}, fmt.Errorf("failed to look up the place at %.4f,%.4f: %w", lat, lon, err)
+	}
+	if place.Error != "" {
+		return Place{}, nil
+	}
+	address := place.Address
+	city := address.City
+	for _, settlement := range []string{address.Town, address.Village, address.Municipality} {
+		if city != "" {
+			break
+		}
+		city = settlement
+	}
+	return Place{
+		City:    city,
+		Region:  address.State,
+		Country: strings.ToUpper(address.CountryCode),
+	}, nil
+}
+
+// searchLimit bounds the locations a search returns
+const searchLimit = 5
+
+// nominatimLocation is a search result
+type nominatimLocation struct {
+	Name        string           `json:"name"`
+	DisplayName string           `json:"display_name"`
+	Lat         string           `json:"lat"`
+	Lon         string           `json:"lon"`
+	Importance  float64          `json:"importance"`
+	Address     nominatimAddress `json:"address"`
+}
+
+// Search looks up the settlements and regions named name, in country when it
+// isn't "", most important first. A place Nominatim lists more than once,
+// e.g. as both a boundary and a settlement, is returned once.
+func (g *NominatimGeocoder) Search(ctx context.Context, name, country string) ([]Location, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"format":         {"jsonv2"},
+		"q":              {name},
+		"layer":          {"address"},
+		"addressdetails": {"1"},
+		"limit":          {strconv.Itoa(searchLimit)},
+	}
+	if country != "" {
+		query.Set("countrycodes", strings.ToLower(country))
+	}
+	var results []nominatimLocation
+	if err := g.get(ctx, "/search?"+query.Encode(), &results); err != nil {
+		return nil, fmt.Errorf("failed to search for %q: %w", name, err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Importance > results[j].Importance
+	})
+	var locations []Location
+	seen := make(map[Location]bool)
+	for _, result := range results {
+		lat, errLat := strconv.ParseFloat(result.Lat, 64)
+		lon, errLon := strconv.ParseFloat(result.Lon, 64)
+		if errLat != nil || errLon != nil {
+			continue
+		}
+		name := result.Name
+		if name == "" {
+			name, _, _ = strings.Cut(result.DisplayName, ",")
+		}
+		place := Location{
+			Name:    name,
+			Region:  result.Address.State,
+			Country: strings.ToUpper(result.Address.CountryCode),
+		}
+		if seen[place] {
+			continue
+		}
+		seen[place] = true
+		place.Lat, place.Lon, place.Importance = lat, lon, result.Importance
+		locations = append(locations, place)
+	}
+	return locations, nil
+}
+
+// reverse returns the area containing the location at zoom, reporting false
+// when there is none, e.g. at sea
+func (g *NominatimGeocoder) reverse(ctx context.Context, lat, lon float64, zoom int) (Area, bool, error) {
+	place, err := g.lookup(ctx, lat, lon, zoom, false)
+	if err != nil {
+		return Area{}, false, err
+	}
+	if place.Error != "" || len(place.BoundingBox) != 4 {
+		return Area{}, false, nil
+	}
+
+	// The bounding box is min lat, max lat, min lon, max lon
+	var box [4]float64
+	for i, value := range place.BoundingBox {
+		var err error
+		if box[i], err = strconv.ParseFloat(value, 64); err != nil {
+			return Area{}, false, fmt.Errorf("invalid bounding box %v", place.BoundingBox)
+		}
+	}
+	centerLat, centerLon := (box[0]+box[1])/2, (box[2]+box[3])/2
+	name := place.Name
+	if name == "" {
+		name, _, _ = strings.Cut(place.DisplayName, ",")
+	}
+	return Area{
+		Name:     name,
+		Lat:      centerLat,
+		Lon:      centerLon,
+		RadiusKm: boundingRadiusKm(centerLat, centerLon, box[0], box[1], box[2], box[3]),
+	}, true, nil
+}
+
+// lookup makes a reverse lookup of the location at zoom, with the address
+// details of the place found when details is set
+func (g *NominatimGeocoder) lookup(ctx context.Context, lat, lon float64, zoom int, details bool) (nominatimPlace, error) {
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nominatimPlace{}, err
+	}
+
+	query := url.Values{
+		"format": {"jsonv2"},
+		"lat":    {strconv.FormatFloat(lat, 'f', 6, 64)},
+		"lon":    {strconv.FormatFloat(lon, 'f', 6, 64)},
+		"zoom":   {strconv.Itoa(zoom)},
+	}
+	if details {
+		query.Set("addressdetails", "1")
+	}
+	var place nominatimPlace
+	if err := g.get(ctx, "/reverse?"+query.Encode(), &place); err != nil {
+		return nominatimPlace{}, err
+	}
+	return place, nil
+}
+
+// get requests path of the Nominatim server and decodes its JSON response
+// into target
+func (g *NominatimGeocoder) get(ctx context.Context, path string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("nominatim returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
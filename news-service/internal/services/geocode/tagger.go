@@ -0,0 +1,62 @@
+package geocode
+
+import (
+	"context"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Tagger fills in the city, region and country of articles that arrive with
+// coordinates but without a place, before they are stored. Articles whose
+// place can't be looked up are stored without one.
+type Tagger struct {
+	geocoder Geocoder
+}
+
+// NewTagger creates a tagger looking places up with geocoder
+func NewTagger(geocoder Geocoder) *Tagger {
+	return &Tagger{geocoder: geocoder}
+}
+
+// Tag sets the place of arg from its coordinates when it has none. A nil
+// Tagger leaves it unchanged.
+func (t *Tagger) Tag(ctx context.Context, arg *repo.CreateArticleParams) {
+	if arg.City != "" || arg.Region != "" || arg.Country != "" {
+		return
+	}
+	if place, ok := t.Lookup(ctx, arg.Latitude, arg.Longitude); ok {
+		arg.City, arg.Region, arg.Country = place.City, place.Region, place.Country
+	}
+}
+
+// TagAll sets the places of the articles among args that have none. Lookups
+// run one at a time, as the geocoder spaces out its requests anyway.
+func (t *Tagger) TagAll(ctx context.Context, args []repo.CreateArticleParams) {
+	for i := range args {
+		t.Tag(ctx, &args[i])
+	}
+}
+
+// Lookup returns the place at the coordinates, reporting false when there
+// are none, the tagger is nil or the lookup fails
+func (t *Tagger) Lookup(ctx context.Context, lat, lon *float64) (Place, bool) {
+	if t == nil || lat == nil || lon == nil {
+		return Place{}, false
+	}
+
+	place, err := t.geocoder.Place(ctx, *lat, *lon)
+	switch {
+	case err != nil:
+		metrics.ArticlesGeocoded.WithLabelValues("failed").Inc()
+		log.Warn().Err(err).Float64("lat", *lat).Float64("lon", *lon).Msg("Failed to geocode article")
+		return Place{}, false
+	case place.Country == "":
+		metrics.ArticlesGeocoded.WithLabelValues("unknown").Inc()
+	default:
+		metrics.ArticlesGeocoded.WithLabelValues("geocoded").Inc()
+	}
+	return place, true
+}
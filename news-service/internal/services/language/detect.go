@@ -0,0 +1,155 @@
+// Package language detects the language of article text and validates the
+// ISO 639-1 codes articles are tagged and filtered with.
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minStopwordHits is how many stopwords of the winning language a Latin-script
+// text must contain before the detection is trusted
+const minStopwordHits = 2
+
+// stopwords holds frequent function words of the Latin-script languages told
+// apart by vocabulary; words shared by several languages count for each
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "for", "on", "with", "that", "by", "as", "at", "from", "this", "are", "was", "has", "its", "be", "new", "after", "over"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "für", "auf", "den", "dem", "ein", "eine", "von", "zu", "im", "sich", "auch", "wird", "bei", "nach", "über"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "pour", "dans", "sur", "au", "avec", "que", "qui", "par", "pas", "aux", "ce", "sont", "après"},
+	"es": {"el", "la", "los", "las", "y", "de", "del", "es", "una", "por", "para", "con", "que", "en", "se", "al", "su", "como", "más", "tras"},
+	"it": {"il", "lo", "gli", "e", "di", "della", "che", "è", "una", "per", "con", "non", "del", "nel", "sono", "alla", "dopo", "anche"},
+	"pt": {"o", "os", "as", "e", "do", "da", "dos", "das", "em", "um", "uma", "para", "com", "não", "que", "no", "na", "ao", "após"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "met", "voor", "op", "dat", "zijn", "ook", "bij", "naar", "wordt", "na"},
+}
+
+// stopwordIndex maps each stopword to the languages it belongs to
+var stopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], lang)
+		}
+	}
+	return index
+}()
+
+// scripts identifies languages that are the main user of their writing system
+var scripts = []struct {
+	lang  string
+	table *unicode.RangeTable
+}{
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"el", unicode.Greek},
+	{"he", unicode.Hebrew},
+	{"hi", unicode.Devanagari},
+	{"ko", unicode.Hangul},
+	{"th", unicode.Thai},
+}
+
+// Detect returns the ISO 639-1 code of the language text is most likely
+// written in, or "" when it can't tell. Non-Latin scripts are recognised by
+// their characters and Latin-script languages by their stopwords, which is
+// reliable for a headline plus description but not for a few words.
+func Detect(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for _, lang := range stopwordIndex[word] {
+			counts[lang]++
+		}
+	}
+
+	best, bestCount, tied := "", 0, false
+	for lang, count := range counts {
+		switch {
+		case count > bestCount:
+			best, bestCount, tied = lang, count, false
+		case count == bestCount:
+			tied = true
+		}
+	}
+	if bestCount < minStopwordHits || tied {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language of the dominant non-Latin script in text, if any
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	letters, kana, han := 0, 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, s := range scripts {
+				if unicode.Is(s.table, r) {
+					counts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese mixes kana with kanji; Han characters alone are Chinese
+	if kana > 0 && (kana+han)*2 > letters {
+		return "ja"
+	}
+	if han*2 > letters {
+		return "zh"
+	}
+	for lang, count := range counts {
+		if count*2 > letters {
+			return lang
+		}
+	}
+	return ""
+}
+
+// ForArticle returns the language to store for an article: the normalized
+// explicit language when one was given, otherwise the one detected from its
+// title and description
+func ForArticle(explicit, title string, description *string) string {
+	if lang, ok := Normalize(explicit); ok && lang != "" {
+		return lang
+	}
+	text := title
+	if description != nil {
+		text += "\n" + *description
+	}
+	return Detect(text)
+}
+
+// Normalize lower-cases a language code and checks it is a two-letter ISO
+// 639-1 code, accepting regional tags such as "en-US" as their base language.
+// An empty code is valid and means "unknown" or "any".
+func Normalize(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if base, _, found := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-"); found {
+		code = base
+	}
+	if code == "" {
+		return "", true
+	}
+	if len(code) != 2 || code[0] < 'a' || code[0] > 'z' || code[1] < 'a' || code[1] > 'z' {
+		return "", false
+	}
+	return code, true
+}
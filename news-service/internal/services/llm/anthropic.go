@@ -0,0 +1,328 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"news-system/internal/version"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com/v1"
+	anthropicVersion = "2023-06-01"
+	// extractionTool is the tool the model is made to call with the extraction as its input
+	extractionTool = "record_extraction"
+	// maxErrorBody bounds how much of an error response is read into the error
+	maxErrorBody = 4096
+)
+
+// AnthropicClient talks to the Anthropic Messages API. Structured extraction
+// uses a forced tool call whose input schema is extractionSchema.
+type AnthropicClient struct {
+	httpClient *http.Client
+	apiKey     string
+	model      string
+	baseURL    string
+	summary    *summaryPrompt
+}
+
+// NewAnthropicClient creates a client for the Anthropic API
+func NewAnthropicClient(apiKey, model string, summaryOpts SummaryOptions) (*AnthropicClient, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("Anthropic API key is required")
+	}
+	if model == "" {
+		model = "claude-3-5-haiku-latest"
+	}
+
+	summary, err := newSummaryPrompt(summaryOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &AnthropicClient{
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    anthropicBaseURL,
+		summary:    summary,
+	}, nil
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature *float64             `json:"temperature,omitempty"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text,omitempty"`
+		Name  string          `json:"name,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// Model returns the model name
+func (c *AnthropicClient) Model() string {
+	return c.model
+}
+
+// EmbeddingModel is empty: Anthropic has no embeddings API
+func (c *AnthropicClient) EmbeddingModel() string {
+	return ""
+}
+
+// Embed fails with ErrEmbeddingsUnsupported, as Anthropic has no embeddings API
+func (c *AnthropicClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, ErrEmbeddingsUnsupported
+}
+
+// CheckCredentials verifies that the API key is accepted and can use the configured model
+func (c *AnthropicClient) CheckCredentials(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodGet, "/models/"+url.PathEscape(c.model), nil, nil); err != nil {
+		return fmt.Errorf("Anthropic rejected model %s: %w", c.model, err)
+	}
+	return nil
+}
+
+// Extract asks the model for the entities, intents, categories and radius in
+// a query, falling back to the keyword heuristics if the call fails
+func (c *AnthropicClient) Extract(ctx context.Context, query string) (*Extraction, error) {
+	extraction, err := c.extract(ctx, query)
+	if err != nil {
+		return fallbackExtract(ctx, query, err)
+	}
+	return extraction, nil
+}
+
+// extract forces a call of the extraction tool and decodes its input
+func (c *AnthropicClient) extract(ctx context.Context, query string) (*Extraction, error) {
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+
+	temperature := 0.0
+	var resp anthropicResponse
+	err := c.do(ctx, http.MethodPost, "/messages", anthropicRequest{
+		Model:       c.model,
+		System:      extractionPrompt,
+		Messages:    []anthropicMessage{{Role: "user", Content: query}},
+		MaxTokens:   1024,
+		Temperature: &temperature,
+		Tools: []anthropicTool{{
+			Name:        extractionTool,
+			Description: "Record the search parameters extracted from the query.",
+			InputSchema: extractionSchema,
+		}},
+		ToolChoice: &anthropicToolChoice{Type: "tool", Name: extractionTool},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	addUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == extractionTool {
+			return parseExtraction(block.Input)
+		}
+	}
+	return nil, fmt.Errorf("model did not call %s (stop reason %s)", extractionTool, resp.StopReason)
+}
+
+// Summarize asks the model for a short summary of an article, rendered from
+// the configured prompt templates
+func (c *AnthropicClient) Summarize(ctx context.Context, title, description, sourceName, publicationDate string) (string, error) {
+	prompt, err := c.summary.render(title, description, sourceName, publicationDate)
+	if err != nil {
+		return "", err
+	}
+
+	temperature := c.summary.temperature
+	var resp anthropicResponse
+	err = c.do(ctx, http.MethodPost, "/messages", anthropicRequest{
+		Model:       summaryModel(ctx, c.model),
+		System:      c.summary.systemFor(ctx),
+		Messages:    []anthropicMessage{{Role: "user", Content: prompt}},
+		MaxTokens:   c.summary.maxTokens,
+		Temperature: &temperature,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("summary request failed: %w", err)
+	}
+	addUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	summary := strings.TrimSpace(text.String())
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// AnalyzeSentiment forces a call of the sentiment tool and decodes its input
+func (c *AnthropicClient) AnalyzeSentiment(ctx context.Context, title, description string) (*Sentiment, error) {
+	temperature := 0.0
+	var resp anthropicResponse
+	err := c.do(ctx, http.MethodPost, "/messages", anthropicRequest{
+		Model:       c.model,
+		System:      sentimentPrompt,
+		Messages:    []anthropicMessage{{Role: "user", Content: articleInput(title, description)}},
+		MaxTokens:   sentimentMaxTokens,
+		Temperature: &temperature,
+		Tools: []anthropicTool{{
+			Name:        sentimentTool,
+			Description: "Record the sentiment of the article.",
+			InputSchema: sentimentSchema,
+		}},
+		ToolChoice: &anthropicToolChoice{Type: "tool", Name: sentimentTool},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("sentiment request failed: %w", err)
+	}
+	addUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == sentimentTool {
+			return parseSentiment(block.Input)
+		}
+	}
+	return nil, fmt.Errorf("model did not call %s (stop reason %s)", sentimentTool, resp.StopReason)
+}
+
+// Categorize forces a call of the categories tool and decodes its input
+func (c *AnthropicClient) Categorize(ctx context.Context, title, description string) ([]string, error) {
+	temperature := 0.0
+	var resp anthropicResponse
+	err := c.do(ctx, http.MethodPost, "/messages", anthropicRequest{
+		Model:       c.model,
+		System:      categorizePrompt,
+		Messages:    []anthropicMessage{{Role: "user", Content: articleInput(title, description)}},
+		MaxTokens:   categorizeMaxTokens,
+		Temperature: &temperature,
+		Tools: []anthropicTool{{
+			Name:        categorizeTool,
+			Description: "Record the categories of the article.",
+			InputSchema: categorizeSchema,
+		}},
+		ToolChoice: &anthropicToolChoice{Type: "tool", Name: categorizeTool},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("categorize request failed: %w", err)
+	}
+	addUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == categorizeTool {
+			return parseCategories(block.Input)
+		}
+	}
+	return nil, fmt.Errorf("model did not call %s (stop reason %s)", categorizeTool, resp.StopReason)
+}
+
+// ExtractEntities forces a call of the entities tool and decodes its input
+func (c *AnthropicClient) ExtractEntities(ctx context.Context, title, description string) (*ArticleEntities, error) {
+	temperature := 0.0
+	var resp anthropicResponse
+	err := c.do(ctx, http.MethodPost, "/messages", anthropicRequest{
+		Model:       c.model,
+		System:      entitiesPrompt,
+		Messages:    []anthropicMessage{{Role: "user", Content: articleInput(title, description)}},
+		MaxTokens:   entitiesMaxTokens,
+		Temperature: &temperature,
+		Tools: []anthropicTool{{
+			Name:        entitiesTool,
+			Description: "Record the named entities of the article.",
+			InputSchema: entitiesSchema,
+		}},
+		ToolChoice: &anthropicToolChoice{Type: "tool", Name: entitiesTool},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("entities request failed: %w", err)
+	}
+	addUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == entitiesTool {
+			return parseEntities(block.Input)
+		}
+	}
+	return nil, fmt.Errorf("model did not call %s (stop reason %s)", entitiesTool, resp.StopReason)
+}
+
+// do sends a request to the API and decodes a successful response into out
+func (c *AnthropicClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("User-Agent", version.UserAgent())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &StatusError{Provider: "anthropic", StatusCode: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(detail))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/metrics"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// CachedClient caches extractions in Redis, so repeated queries skip the
+// provider. Summaries are cached per article by the news service already and
+// pass straight through.
+type CachedClient struct {
+	Client
+	cache *cache.RedisCache
+	ttl   time.Duration
+	// scope separates cached extractions of different providers, models and
+	// prompts, so changing any of them doesn't serve stale answers
+	scope string
+	// calls collapses concurrent misses for the same query into one provider call
+	calls singleflight.Group
+}
+
+// NewCachedClient wraps client with an extraction cache whose entries live
+// for ttl. scope identifies the provider and model behind client.
+func NewCachedClient(client Client, redisCache *cache.RedisCache, ttl time.Duration, scope string) *CachedClient {
+	return &CachedClient{
+		Client: client,
+		cache:  redisCache,
+		ttl:    ttl,
+		scope:  scope + "|" + extractionPrompt,
+	}
+}
+
+// Extract returns the cached extraction of query, asking the wrapped client
+// on a miss. Heuristic fallbacks aren't cached, so the model answers the
+// query again once it recovers.
+func (c *CachedClient) Extract(ctx context.Context, query string) (*Extraction, error) {
+	normalized := normalizeQuery(query)
+	key := cache.LLMExtractKey(c.hash(normalized))
+
+	data, err := c.cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		var extraction Extraction
+		if err := json.Unmarshal(data, &extraction); err == nil {
+			metrics.LLMCacheRequests.WithLabelValues("hit").Inc()
+			return &extraction, nil
+		}
+		metrics.LLMCacheRequests.WithLabelValues("error").Inc()
+	case errors.Is(err, cache.ErrKeyNotFound):
+		metrics.LLMCacheRequests.WithLabelValues("miss").Inc()
+	default:
+		metrics.LLMCacheRequests.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Msg("Failed to read cached LLM extraction")
+	}
+
+	value, err, _ := c.calls.Do(key, func() (interface{}, error) {
+		// Other callers may be waiting on this call, so it outlives a cancelled first caller
+		callCtx := context.WithoutCancel(ctx)
+		extraction, err := c.Client.Extract(callCtx, query)
+		if err != nil {
+			return nil, err
+		}
+		if !extraction.Fallback {
+			if err := c.cache.Set(callCtx, key, extraction, c.ttl); err != nil {
+				log.Warn().Err(err).Msg("Failed to cache LLM extraction")
+			}
+		}
+		return extraction, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*Extraction), nil
+}
+
+// hash identifies a normalized query within the client's scope
+func (c *CachedClient) hash(normalized string) string {
+	sum := sha256.Sum256([]byte(c.scope + "|" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeQuery folds case and whitespace, which don't change what a query asks for
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
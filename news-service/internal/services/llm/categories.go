@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Categories is the taxonomy articles are classified into
+var Categories = []string{
+	"Technology",
+	"Business",
+	"Sports",
+	"Health",
+	"Science",
+	"Environment",
+	"Politics",
+	"Entertainment",
+}
+
+const (
+	// categorizeTool is the tool Anthropic models are made to call with the categories as its input
+	categorizeTool = "record_categories"
+	// categorizeMaxTokens bounds the reply, which is a short JSON list
+	categorizeMaxTokens = 64
+	// maxArticleCategories bounds the categories kept per article
+	maxArticleCategories = 2
+)
+
+// categorizePrompt instructs the model how to classify an article
+var categorizePrompt = `You classify news articles into categories from their title and description.
+
+Return categories: the one or two categories from this list that best describe the article, the best first: ` + strings.Join(Categories, ", ") + `.
+
+Only use a second category when the article clearly belongs to both.`
+
+// categorizeSchema is the JSON schema the model's categories must follow
+var categorizeSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"categories": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string", "enum": Categories},
+		},
+	},
+	"required":             []string{"categories"},
+	"additionalProperties": false,
+}
+
+// parseCategories decodes a model's categories, keeping the first
+// maxArticleCategories that are in the taxonomy, spelled as it spells them
+func parseCategories(data []byte) ([]string, error) {
+	var reply struct {
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("invalid categories JSON: %w", err)
+	}
+
+	var categories []string
+	for _, name := range reply.Categories {
+		for _, category := range Categories {
+			if strings.EqualFold(strings.TrimSpace(name), category) && !slices.Contains(categories, category) {
+				categories = append(categories, category)
+				break
+			}
+		}
+		if len(categories) == maxArticleCategories {
+			break
+		}
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("model returned no known category: %v", reply.Categories)
+	}
+	return categories, nil
+}
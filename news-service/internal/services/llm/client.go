@@ -14,8 +14,14 @@ type Extraction struct {
 	Concepts []string `json:"concepts"`
 	Intent   []Intent `json:"intent"`
 	RadiusKm *float64 `json:"radius_km,omitempty"`
+	// Area is the administrative area around the user a query asks to
+	// cover instead of a distance, e.g. "across the state": "city",
+	// "county" or "state"
+	Area *string `json:"area,omitempty"`
 	SourceNames []string `json:"source_names,omitempty"`
 	Categories []string `json:"categories,omitempty"`
+	// Fallback is set when the keyword heuristics answered instead of the model
+	Fallback bool `json:"-"`
 }
 
 type Intent struct {
@@ -30,5 +36,17 @@ type LLMClient interface {
 	
 	// Summarize an article in 2-3 sentences
 	Summarize(ctx context.Context, title, description, sourceName, publicationDate string) (string, error)
+	
+	// Embed returns an embedding vector of each text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	
+	// AnalyzeSentiment judges whether an article is positive, negative or neutral
+	AnalyzeSentiment(ctx context.Context, title, description string) (*Sentiment, error)
+	
+	// Categorize classifies an article into one or two of Categories
+	Categorize(ctx context.Context, title, description string) ([]string, error)
+	
+	// ExtractEntities names the people, organizations and locations an article mentions
+	ExtractEntities(ctx context.Context, title, description string) (*ArticleEntities, error)
 }
 
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrEmbeddingsUnsupported is returned by Embed when the provider, or its
+// configuration, offers no embedding model
+var ErrEmbeddingsUnsupported = errors.New("LLM provider has no embedding model configured")
+
+// maxEmbeddingChars bounds the article text embedded, well within the input
+// limit of the embedding models
+const maxEmbeddingChars = 4000
+
+// EmbeddingText is the text of an article that is embedded for semantic
+// search: its title and description
+func EmbeddingText(title, description string) string {
+	text := strings.TrimSpace(title)
+	if description = strings.TrimSpace(description); description != "" {
+		text += "\n\n" + description
+	}
+	return truncateText(text, maxEmbeddingChars)
+}
+
+// toFloat32 narrows an embedding to the precision it is stored and compared at
+func toFloat32(values []float64) []float32 {
+	narrowed := make([]float32, len(values))
+	for i, value := range values {
+		narrowed[i] = float32(value)
+	}
+	return narrowed
+}
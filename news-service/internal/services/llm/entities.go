@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ArticleEntities are the named entities an article mentions, spelled as the
+// model spelled them
+type ArticleEntities struct {
+	People        []string `json:"people"`
+	Organizations []string `json:"organizations"`
+	Locations     []string `json:"locations"`
+}
+
+const (
+	// entitiesTool is the tool Anthropic models are made to call with the entities as its input
+	entitiesTool = "record_entities"
+	// entitiesMaxTokens bounds the reply, a JSON object of short name lists
+	entitiesMaxTokens = 300
+	// maxEntitiesPerType bounds the entities kept of each type
+	maxEntitiesPerType = 10
+)
+
+// entitiesPrompt instructs the model which entities to extract
+const entitiesPrompt = `You extract the named entities a news article is about from its title and description.
+
+Return:
+- people: the people named, by full name where the article gives it.
+- organizations: the companies, agencies, parties, teams and other organizations named.
+- locations: the countries, regions, cities and places named.
+
+Use each entity's common canonical name, e.g. "SpaceX" rather than "Space Exploration Technologies Corp.", and list each once. Leave a list empty when the article names none; don't include generic terms such as "the government" or "scientists".`
+
+// entitiesSchema is the JSON schema the model's entities must follow
+var entitiesSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"people":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"organizations": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"locations":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+	},
+	"required":             []string{"people", "organizations", "locations"},
+	"additionalProperties": false,
+}
+
+// parseEntities decodes a model's entities, dropping blank names and repeats
+// and keeping up to maxEntitiesPerType of each type
+func parseEntities(data []byte) (*ArticleEntities, error) {
+	var entities ArticleEntities
+	if err := json.Unmarshal(data, &entities); err != nil {
+		return nil, fmt.Errorf("invalid entities JSON: %w", err)
+	}
+	entities.People = cleanEntityNames(entities.People)
+	entities.Organizations = cleanEntityNames(entities.Organizations)
+	entities.Locations = cleanEntityNames(entities.Locations)
+	return &entities, nil
+}
+
+// cleanEntityNames trims names and drops blank ones and case-insensitive repeats
+func cleanEntityNames(names []string) []string {
+	seen := make(map[string]bool)
+	cleaned := []string{}
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, name)
+		if len(cleaned) == maxEntitiesPerType {
+			break
+		}
+	}
+	return cleaned
+}
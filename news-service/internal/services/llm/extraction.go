@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"news-system/internal/metrics"
+
+	"github.com/rs/zerolog/log"
+)
+
+// extractionPrompt instructs the model how to read a news query. The intent
+// types are the retrieval strategies the news service knows how to run.
+const extractionPrompt = `You extract structured search parameters from queries to a news search engine.
+
+Return:
+- entities: people, organizations ("orgs") and locations named in the query, with canonical capitalization.
+- concepts: topics or themes that are not named entities, e.g. "Artificial Intelligence".
+- intent: one or more of the retrieval strategies below, each with a confidence between 0 and 1:
+  - "category": the query asks for a news category or topic area
+  - "source": the query asks for articles from a specific publisher
+  - "score": the query asks for the best, most relevant or highest quality articles
+  - "nearby": the query asks for news near a place or near the user
+  - "search": anything else; a free-text search
+- categories: news categories the query asks for, using title case names such as Technology, Business, Sports, Health, Science, Environment, Entertainment or Politics.
+- source_names: publishers the query asks for, e.g. "Reuters", "New York Times", "BBC".
+- radius_km: the search radius if the query states a distance (convert miles to kilometers), otherwise null.
+- area: "city", "county" or "state" if the query asks for news across the user's city, county or state instead of stating a distance, e.g. "across the state" or "citywide", otherwise null.
+
+Use empty arrays for anything the query does not mention.`
+
+// extractionSchema is the JSON schema the model's output must follow. Strict
+// structured outputs require every property to be listed as required and no
+// additional properties, so optional values are nullable instead.
+var extractionSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"entities": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"people":    stringArraySchema,
+				"orgs":      stringArraySchema,
+				"locations": stringArraySchema,
+			},
+			"required":             []string{"people", "orgs", "locations"},
+			"additionalProperties": false,
+		},
+		"concepts": stringArraySchema,
+		"intent": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{
+						"type": "string",
+						"enum": []string{"category", "source", "score", "nearby", "search"},
+					},
+					"confidence": map[string]interface{}{"type": "number"},
+				},
+				"required":             []string{"type", "confidence"},
+				"additionalProperties": false,
+			},
+		},
+		"categories":   stringArraySchema,
+		"source_names": stringArraySchema,
+		"radius_km":    map[string]interface{}{"type": []string{"number", "null"}},
+		"area": map[string]interface{}{
+			"type": []string{"string", "null"},
+			"enum": []interface{}{"city", "county", "state", nil},
+		},
+	},
+	"required":             []string{"entities", "concepts", "intent", "categories", "source_names", "radius_km", "area"},
+	"additionalProperties": false,
+}
+
+var stringArraySchema = map[string]interface{}{
+	"type":  "array",
+	"items": map[string]interface{}{"type": "string"},
+}
+
+// parseExtraction decodes a model's extraction, dropping values the service can't use
+func parseExtraction(data []byte) (*Extraction, error) {
+	var extraction Extraction
+	if err := json.Unmarshal(data, &extraction); err != nil {
+		return nil, fmt.Errorf("invalid extraction JSON: %w", err)
+	}
+	if extraction.RadiusKm != nil && *extraction.RadiusKm <= 0 {
+		extraction.RadiusKm = nil
+	}
+	if extraction.Area != nil && !validArea(*extraction.Area) {
+		extraction.Area = nil
+	}
+	if len(extraction.Intent) == 0 {
+		extraction.Intent = []Intent{{Type: "search", Confidence: 0.5}}
+	}
+	return &extraction, nil
+}
+
+// validArea reports whether area is an area level a query can ask to cover
+func validArea(area string) bool {
+	switch area {
+	case "city", "county", "state":
+		return true
+	}
+	return false
+}
+
+// fallbackExtract answers a query with the keyword heuristics after the
+// provider failed, unless the caller gave up on the query altogether
+func fallbackExtract(ctx context.Context, query string, err error) (*Extraction, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	log.Warn().Err(err).Str("query", query).Msg("LLM extraction failed, falling back to heuristics")
+	metrics.LLMFallbacks.WithLabelValues("extract").Inc()
+	extraction := HeuristicExtract(query)
+	extraction.Fallback = true
+	return extraction, nil
+}
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// distancePattern matches a distance stated in a query, e.g. "within 5 km"
+// or "20 miles"
+var distancePattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(km|kms|kilometers?|kilometres?|mi|miles?)\b`)
+
+// kmPerMile converts the miles of a stated distance to kilometers
+const kmPerMile = 1.609344
+
+// areaPhrases map phrases asking for news across an area around the user to
+// its level, most specific first
+var areaPhrases = []struct {
+	area    string
+	phrases []string
+}{
+	{"city", []string{"citywide", "city-wide", "across the city", "across town", "in my city", "in the city"}},
+	{"county", []string{"countywide", "county-wide", "across the county", "in my county", "in the county"}},
+	{"state", []string{"statewide", "state-wide", "across the state", "in my state", "in the state"}},
+}
+
+// HeuristicExtract is the keyword-based extractor used when the model can't be
+// reached. It only knows a fixed list of categories, sources, places and names.
+func HeuristicExtract(query string) *Extraction {
+	queryLower := strings.ToLower(query)
+
+	// Simple keyword-based extraction for testing
+	var entities struct {
+		People        []string `json:"people"`
+		Organizations []string `json:"orgs"`
+		Locations     []string `json:"locations"`
+	}
+	var concepts []string
+	var intent []Intent
+	var categories []string
+	var sourceNames []string
+
+	// Detect score-based queries
+	if strings.Contains(queryLower, "score") || strings.Contains(queryLower, "relevance") || strings.Contains(queryLower, "above") || strings.Contains(queryLower, "threshold") || strings.Contains(queryLower, "high quality") || strings.Contains(queryLower, "best") {
+		intent = append(intent, Intent{Type: "score", Confidence: 0.9})
+	}
+
+	// Detect categories
+	if strings.Contains(queryLower, "technology") || strings.Contains(queryLower, "tech") {
+		categories = append(categories, "Technology")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "business") || strings.Contains(queryLower, "finance") {
+		categories = append(categories, "Business")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "sports") {
+		categories = append(categories, "Sports")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "health") || strings.Contains(queryLower, "medical") {
+		categories = append(categories, "Health")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "science") {
+		categories = append(categories, "Science")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "environment") || strings.Contains(queryLower, "climate") {
+		categories = append(categories, "Environment")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "entertainment") || strings.Contains(queryLower, "movie") || strings.Contains(queryLower, "gaming") {
+		categories = append(categories, "Entertainment")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "politics") || strings.Contains(queryLower, "government") {
+		categories = append(categories, "Politics")
+		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
+	}
+
+	// Detect sources
+	if strings.Contains(queryLower, "new york times") || strings.Contains(queryLower, "nyt") {
+		sourceNames = append(sourceNames, "New York Times")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "reuters") {
+		sourceNames = append(sourceNames, "Reuters")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "bbc") {
+		sourceNames = append(sourceNames, "BBC")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "cnn") {
+		sourceNames = append(sourceNames, "CNN")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "dw") {
+		sourceNames = append(sourceNames, "DW")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "technews") {
+		sourceNames = append(sourceNames, "TechNews")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "spacenews") {
+		sourceNames = append(sourceNames, "SpaceNews")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "financedaily") {
+		sourceNames = append(sourceNames, "FinanceDaily")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "healthscience") {
+		sourceNames = append(sourceNames, "HealthScience")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+	if strings.Contains(queryLower, "globalnews") {
+		sourceNames = append(sourceNames, "GlobalNews")
+		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
+	}
+
+	// Detect locations
+	if strings.Contains(queryLower, "paris") {
+		entities.Locations = append(entities.Locations, "Paris")
+		intent = append(intent, Intent{Type: "nearby", Confidence: 0.8})
+	}
+	if strings.Contains(queryLower, "new york") || strings.Contains(queryLower, "nyc") {
+		entities.Locations = append(entities.Locations, "New York")
+		intent = append(intent, Intent{Type: "nearby", Confidence: 0.8})
+	}
+	if strings.Contains(queryLower, "london") {
+		entities.Locations = append(entities.Locations, "London")
+		intent = append(intent, Intent{Type: "nearby", Confidence: 0.8})
+	}
+	if strings.Contains(queryLower, "near") || strings.Contains(queryLower, "nearby") || strings.Contains(queryLower, "local") || strings.Contains(queryLower, "location") {
+		intent = append(intent, Intent{Type: "nearby", Confidence: 0.7})
+	}
+
+	// Detect a stated distance, or an area around the user
+	var radiusKm *float64
+	if match := distancePattern.FindStringSubmatch(queryLower); match != nil {
+		if distance, err := strconv.ParseFloat(match[1], 64); err == nil && distance > 0 {
+			if strings.HasPrefix(match[2], "mi") {
+				distance *= kmPerMile
+			}
+			radiusKm = &distance
+		}
+	}
+	var area *string
+	for _, level := range areaPhrases {
+		if area != nil {
+			break
+		}
+		for _, phrase := range level.phrases {
+			if strings.Contains(queryLower, phrase) {
+				area = &level.area
+				break
+			}
+		}
+	}
+	if (radiusKm != nil || area != nil) && !slices.ContainsFunc(intent, func(i Intent) bool { return i.Type == "nearby" }) {
+		intent = append(intent, Intent{Type: "nearby", Confidence: 0.7})
+	}
+
+	// Detect people
+	if strings.Contains(queryLower, "elon musk") {
+		entities.People = append(entities.People, "Elon Musk")
+	}
+	if strings.Contains(queryLower, "john smith") {
+		entities.People = append(entities.People, "John Smith")
+	}
+
+	// Detect organizations
+	if strings.Contains(queryLower, "spacex") {
+		entities.Organizations = append(entities.Organizations, "SpaceX")
+	}
+	if strings.Contains(queryLower, "tesla") {
+		entities.Organizations = append(entities.Organizations, "Tesla")
+	}
+
+	// Add concepts
+	if strings.Contains(queryLower, "ai") || strings.Contains(queryLower, "artificial intelligence") {
+		concepts = append(concepts, "Artificial Intelligence")
+	}
+	if strings.Contains(queryLower, "climate change") {
+		concepts = append(concepts, "Climate Change")
+	}
+	if strings.Contains(queryLower, "stock market") {
+		concepts = append(concepts, "Stock Market")
+	}
+
+	// Default to search if no specific intent detected
+	if len(intent) == 0 {
+		intent = append(intent, Intent{Type: "search", Confidence: 0.7})
+	}
+
+	return &Extraction{
+		Entities:    entities,
+		Concepts:    concepts,
+		Intent:      intent,
+		Categories:  categories,
+		SourceNames: sourceNames,
+		RadiusKm:    radiusKm,
+		Area:        area,
+	}
+}
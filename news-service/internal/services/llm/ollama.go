@@ -0,0 +1,283 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"news-system/internal/version"
+)
+
+// ollamaExtractTimeout is the default extraction timeout for local models,
+// which answer more slowly than hosted ones, especially on CPU
+const ollamaExtractTimeout = 15 * time.Second
+
+// OllamaClient talks to a local Ollama server, so the service can run without
+// reaching any hosted API. Extraction passes extractionSchema as the response
+// format, which Ollama enforces while sampling.
+type OllamaClient struct {
+	httpClient     *http.Client
+	baseURL        string
+	model          string
+	embeddingModel string
+	summary        *summaryPrompt
+	// extractTimeout replaces the hosted providers' extraction timeout
+	extractTimeout time.Duration
+}
+
+// NewOllamaClient creates a client for the Ollama server at baseURL, e.g. http://localhost:11434
+func NewOllamaClient(baseURL, model, embeddingModel string, extractTimeout time.Duration, summaryOpts SummaryOptions) (*OllamaClient, error) {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "llama3.2"
+	}
+	if embeddingModel == "" {
+		embeddingModel = "nomic-embed-text"
+	}
+	if extractTimeout <= 0 {
+		extractTimeout = ollamaExtractTimeout
+	}
+
+	summary, err := newSummaryPrompt(summaryOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &OllamaClient{
+		httpClient:     &http.Client{Timeout: 5 * time.Minute},
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		model:          model,
+		embeddingModel: embeddingModel,
+		summary:        summary,
+		extractTimeout: extractTimeout,
+	}, nil
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   interface{}     `json:"format,omitempty"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Message ollamaMessage `json:"message"`
+	// PromptEvalCount and EvalCount are the prompt and completion token counts
+	PromptEvalCount int64 `json:"prompt_eval_count"`
+	EvalCount       int64 `json:"eval_count"`
+}
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+}
+
+// Model returns the model name
+func (c *OllamaClient) Model() string {
+	return c.model
+}
+
+// EmbeddingModel returns the embedding model name
+func (c *OllamaClient) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+// CheckCredentials verifies that the server is reachable and has the model
+// pulled. Ollama has no credentials; the name satisfies Client.
+func (c *OllamaClient) CheckCredentials(ctx context.Context) error {
+	err := c.do(ctx, "/api/show", map[string]string{"model": c.model}, nil)
+	if err != nil {
+		return fmt.Errorf("Ollama at %s can't serve model %s (pull it with `ollama pull %s`): %w", c.baseURL, c.model, c.model, err)
+	}
+	return nil
+}
+
+// Extract asks the model for the entities, intents, categories and radius in
+// a query, falling back to the keyword heuristics if the call fails
+func (c *OllamaClient) Extract(ctx context.Context, query string) (*Extraction, error) {
+	extraction, err := c.extract(ctx, query)
+	if err != nil {
+		return fallbackExtract(ctx, query, err)
+	}
+	return extraction, nil
+}
+
+// extract constrains the reply to extractionSchema and parses it
+func (c *OllamaClient) extract(ctx context.Context, query string) (*Extraction, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.extractTimeout)
+	defer cancel()
+
+	var resp ollamaChatResponse
+	err := c.do(ctx, "/api/chat", ollamaChatRequest{
+		Model: c.model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: extractionPrompt},
+			{Role: "user", Content: query},
+		},
+		Format:  extractionSchema,
+		Options: ollamaOptions{Temperature: 0},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	addUsage(ctx, resp.PromptEvalCount, resp.EvalCount)
+	return parseExtraction([]byte(resp.Message.Content))
+}
+
+// Summarize asks the model for a short summary of an article, rendered from
+// the configured prompt templates
+func (c *OllamaClient) Summarize(ctx context.Context, title, description, sourceName, publicationDate string) (string, error) {
+	prompt, err := c.summary.render(title, description, sourceName, publicationDate)
+	if err != nil {
+		return "", err
+	}
+
+	var resp ollamaChatResponse
+	err = c.do(ctx, "/api/chat", ollamaChatRequest{
+		Model: summaryModel(ctx, c.model),
+		Messages: []ollamaMessage{
+			{Role: "system", Content: c.summary.systemFor(ctx)},
+			{Role: "user", Content: prompt},
+		},
+		Options: ollamaOptions{Temperature: c.summary.temperature, NumPredict: c.summary.maxTokens},
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("summary request failed: %w", err)
+	}
+	addUsage(ctx, resp.PromptEvalCount, resp.EvalCount)
+
+	summary := strings.TrimSpace(resp.Message.Content)
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// AnalyzeSentiment constrains the reply to sentimentSchema and parses it
+func (c *OllamaClient) AnalyzeSentiment(ctx context.Context, title, description string) (*Sentiment, error) {
+	var resp ollamaChatResponse
+	err := c.do(ctx, "/api/chat", ollamaChatRequest{
+		Model: c.model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: sentimentPrompt},
+			{Role: "user", Content: articleInput(title, description)},
+		},
+		Format:  sentimentSchema,
+		Options: ollamaOptions{Temperature: 0, NumPredict: sentimentMaxTokens},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("sentiment request failed: %w", err)
+	}
+	addUsage(ctx, resp.PromptEvalCount, resp.EvalCount)
+	return parseSentiment([]byte(resp.Message.Content))
+}
+
+// Categorize constrains the reply to categorizeSchema and parses it
+func (c *OllamaClient) Categorize(ctx context.Context, title, description string) ([]string, error) {
+	var resp ollamaChatResponse
+	err := c.do(ctx, "/api/chat", ollamaChatRequest{
+		Model: c.model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: categorizePrompt},
+			{Role: "user", Content: articleInput(title, description)},
+		},
+		Format:  categorizeSchema,
+		Options: ollamaOptions{Temperature: 0, NumPredict: categorizeMaxTokens},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("categorize request failed: %w", err)
+	}
+	addUsage(ctx, resp.PromptEvalCount, resp.EvalCount)
+	return parseCategories([]byte(resp.Message.Content))
+}
+
+// ExtractEntities constrains the reply to entitiesSchema and parses it
+func (c *OllamaClient) ExtractEntities(ctx context.Context, title, description string) (*ArticleEntities, error) {
+	var resp ollamaChatResponse
+	err := c.do(ctx, "/api/chat", ollamaChatRequest{
+		Model: c.model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: entitiesPrompt},
+			{Role: "user", Content: articleInput(title, description)},
+		},
+		Format:  entitiesSchema,
+		Options: ollamaOptions{Temperature: 0, NumPredict: entitiesMaxTokens},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("entities request failed: %w", err)
+	}
+	addUsage(ctx, resp.PromptEvalCount, resp.EvalCount)
+	return parseEntities([]byte(resp.Message.Content))
+}
+
+// Embed returns the embeddings of texts from the embedding model, which must
+// be pulled like the chat model
+func (c *OllamaClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var resp ollamaEmbedResponse
+	if err := c.do(ctx, "/api/embed", ollamaEmbedRequest{Model: c.embeddingModel, Input: texts}, &resp); err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	addUsage(ctx, resp.PromptEvalCount, 0)
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("Ollama returned %d embeddings for %d inputs", len(resp.Embeddings), len(texts))
+	}
+	return resp.Embeddings, nil
+}
+
+// do posts body to the server and decodes a successful response into out
+func (c *OllamaClient) do(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &StatusError{Provider: "ollama", StatusCode: resp.StatusCode, Status: resp.Status, Detail: strings.TrimSpace(string(detail))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
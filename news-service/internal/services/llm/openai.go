@@ -3,197 +3,356 @@ package llm
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
+
+	"news-system/internal/version"
 
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
-	"github.com/rs/zerolog/log"
+	"github.com/openai/openai-go/v2/shared"
 )
 
+// extractTimeout bounds the extraction call, which every query waits on,
+// before falling back to the heuristics
+const extractTimeout = 5 * time.Second
+
+// OpenAIClient talks to the OpenAI API, or to an Azure OpenAI deployment
 type OpenAIClient struct {
-	client openai.Client
-	model  string
+	client         openai.Client
+	model          string
+	embeddingModel string
+	summary        *summaryPrompt
+	// Azure addresses a deployment rather than a model, and has no models endpoint
+	azure         bool
+	azureEndpoint string
+	// embeddingOpts point embedding requests at their own Azure deployment
+	embeddingOpts []option.RequestOption
 }
 
-func NewOpenAIClient(apiKey, model string) (*OpenAIClient, error) {
+func NewOpenAIClient(apiKey, model, embeddingModel string, summaryOpts SummaryOptions) (*OpenAIClient, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
-
-	client := openai.NewClient(option.WithAPIKey(apiKey))
-
 	if model == "" {
 		model = "gpt-4o-mini"
 	}
+	if embeddingModel == "" {
+		embeddingModel = "text-embedding-3-small"
+	}
 
+	client := openai.NewClient(option.WithAPIKey(apiKey), option.WithHeader("User-Agent", version.UserAgent()))
+	c, err := newOpenAIClient(client, model, summaryOpts, false)
+	if err != nil {
+		return nil, err
+	}
+	c.embeddingModel = embeddingModel
+	return c, nil
+}
+
+// NewAzureOpenAIClient creates a client for a deployment of an Azure OpenAI
+// resource, e.g. endpoint https://my-resource.openai.azure.com. Requests
+// carry the deployment in their path, so the model name is ignored there.
+// Embeddings need a deployment of their own, embeddingDeployment; without one
+// Embed fails with ErrEmbeddingsUnsupported.
+func NewAzureOpenAIClient(endpoint, apiKey, deployment, embeddingDeployment, apiVersion string, summaryOpts SummaryOptions) (*OpenAIClient, error) {
+	if endpoint == "" || apiKey == "" || deployment == "" {
+		return nil, fmt.Errorf("Azure OpenAI endpoint, API key and deployment are required")
+	}
+	if apiVersion == "" {
+		apiVersion = "2024-10-21"
+	}
+
+	client := openai.NewClient(
+		option.WithBaseURL(azureDeploymentURL(endpoint, deployment)),
+		option.WithQuery("api-version", apiVersion),
+		option.WithHeader("Api-Key", apiKey),
+		// Don't send an OPENAI_API_KEY picked up from the environment to Azure
+		option.WithHeaderDel("Authorization"),
+		option.WithHeader("User-Agent", version.UserAgent()),
+	)
+	c, err := newOpenAIClient(client, deployment, summaryOpts, true)
+	if err != nil {
+		return nil, err
+	}
+	c.azureEndpoint = endpoint
+	if embeddingDeployment != "" {
+		c.embeddingModel = embeddingDeployment
+		c.embeddingOpts = []option.RequestOption{option.WithBaseURL(azureDeploymentURL(endpoint, embeddingDeployment))}
+	}
+	return c, nil
+}
+
+// azureDeploymentURL is the base URL of the requests to a deployment
+func azureDeploymentURL(endpoint, deployment string) string {
+	return strings.TrimSuffix(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/"
+}
+
+func newOpenAIClient(client openai.Client, model string, summaryOpts SummaryOptions, azure bool) (*OpenAIClient, error) {
+	summary, err := newSummaryPrompt(summaryOpts)
+	if err != nil {
+		return nil, err
+	}
 	return &OpenAIClient{
-		client: client,
-		model:  model,
+		client:  client,
+		model:   model,
+		summary: summary,
+		azure:   azure,
 	}, nil
 }
 
+// Model returns the model name, or the deployment name for Azure OpenAI
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
+
+// EmbeddingModel returns the embedding model name, or its deployment name for
+// Azure OpenAI
+func (c *OpenAIClient) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+// CheckCredentials verifies that the API key is accepted and can use the configured model
+func (c *OpenAIClient) CheckCredentials(ctx context.Context) error {
+	if c.azure {
+		// A one-token completion is the cheapest call that proves access to the deployment
+		_, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+			Model:               c.model,
+			Messages:            []openai.ChatCompletionMessageParamUnion{openai.UserMessage("ping")},
+			MaxCompletionTokens: openai.Int(1),
+		})
+		if err != nil {
+			return fmt.Errorf("Azure OpenAI rejected deployment %s: %w", c.model, err)
+		}
+		return nil
+	}
+	if _, err := c.client.Models.Get(ctx, c.model); err != nil {
+		return fmt.Errorf("OpenAI rejected model %s: %w", c.model, err)
+	}
+	return nil
+}
+
+// Extract asks the model for the entities, intents, categories and radius in a
+// query, constrained to extractionSchema. If the API call fails or returns
+// something unusable it falls back to the keyword heuristics, so queries keep
+// working through an outage, only less precisely.
 func (c *OpenAIClient) Extract(ctx context.Context, query string) (*Extraction, error) {
-	// For now, return a mock extraction to avoid complex OpenAI API usage
-	// TODO: Implement actual OpenAI API call when the types are properly understood
-	log.Info().Str("query", query).Msg("Mock extraction - OpenAI API not yet implemented")
-	
-	queryLower := strings.ToLower(query)
-	
-	// Simple keyword-based extraction for testing
-	var entities struct {
-		People        []string `json:"people"`
-		Organizations []string `json:"orgs"`
-		Locations     []string `json:"locations"`
-	}
-	var concepts []string
-	var intent []Intent
-	var categories []string
-	var sourceNames []string
-	
-	// Detect score-based queries
-	if strings.Contains(queryLower, "score") || strings.Contains(queryLower, "relevance") || strings.Contains(queryLower, "above") || strings.Contains(queryLower, "threshold") || strings.Contains(queryLower, "high quality") || strings.Contains(queryLower, "best") {
-		intent = append(intent, Intent{Type: "score", Confidence: 0.9})
-	}
-	
-	// Detect categories
-	if strings.Contains(queryLower, "technology") || strings.Contains(queryLower, "tech") {
-		categories = append(categories, "Technology")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "business") || strings.Contains(queryLower, "finance") {
-		categories = append(categories, "Business")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "sports") {
-		categories = append(categories, "Sports")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "health") || strings.Contains(queryLower, "medical") {
-		categories = append(categories, "Health")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "science") {
-		categories = append(categories, "Science")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "environment") || strings.Contains(queryLower, "climate") {
-		categories = append(categories, "Environment")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "entertainment") || strings.Contains(queryLower, "movie") || strings.Contains(queryLower, "gaming") {
-		categories = append(categories, "Entertainment")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "politics") || strings.Contains(queryLower, "government") {
-		categories = append(categories, "Politics")
-		intent = append(intent, Intent{Type: "category", Confidence: 0.9})
-	}
-	
-	// Detect sources
-	if strings.Contains(queryLower, "new york times") || strings.Contains(queryLower, "nyt") {
-		sourceNames = append(sourceNames, "New York Times")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "reuters") {
-		sourceNames = append(sourceNames, "Reuters")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "bbc") {
-		sourceNames = append(sourceNames, "BBC")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "cnn") {
-		sourceNames = append(sourceNames, "CNN")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "dw") {
-		sourceNames = append(sourceNames, "DW")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "technews") {
-		sourceNames = append(sourceNames, "TechNews")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "spacenews") {
-		sourceNames = append(sourceNames, "SpaceNews")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "financedaily") {
-		sourceNames = append(sourceNames, "FinanceDaily")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "healthscience") {
-		sourceNames = append(sourceNames, "HealthScience")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	if strings.Contains(queryLower, "globalnews") {
-		sourceNames = append(sourceNames, "GlobalNews")
-		intent = append(intent, Intent{Type: "source", Confidence: 0.9})
-	}
-	
-	// Detect locations
-	if strings.Contains(queryLower, "paris") {
-		entities.Locations = append(entities.Locations, "Paris")
-		intent = append(intent, Intent{Type: "nearby", Confidence: 0.8})
-	}
-	if strings.Contains(queryLower, "new york") || strings.Contains(queryLower, "nyc") {
-		entities.Locations = append(entities.Locations, "New York")
-		intent = append(intent, Intent{Type: "nearby", Confidence: 0.8})
-	}
-	if strings.Contains(queryLower, "london") {
-		entities.Locations = append(entities.Locations, "London")
-		intent = append(intent, Intent{Type: "nearby", Confidence: 0.8})
-	}
-	if strings.Contains(queryLower, "near") || strings.Contains(queryLower, "nearby") || strings.Contains(queryLower, "local") || strings.Contains(queryLower, "location") {
-		intent = append(intent, Intent{Type: "nearby", Confidence: 0.7})
-	}
-	
-	// Detect people
-	if strings.Contains(queryLower, "elon musk") {
-		entities.People = append(entities.People, "Elon Musk")
-	}
-	if strings.Contains(queryLower, "john smith") {
-		entities.People = append(entities.People, "John Smith")
-	}
-	
-	// Detect organizations
-	if strings.Contains(queryLower, "spacex") {
-		entities.Organizations = append(entities.Organizations, "SpaceX")
-	}
-	if strings.Contains(queryLower, "tesla") {
-		entities.Organizations = append(entities.Organizations, "Tesla")
-	}
-	
-	// Add concepts
-	if strings.Contains(queryLower, "ai") || strings.Contains(queryLower, "artificial intelligence") {
-		concepts = append(concepts, "Artificial Intelligence")
-	}
-	if strings.Contains(queryLower, "climate change") {
-		concepts = append(concepts, "Climate Change")
-	}
-	if strings.Contains(queryLower, "stock market") {
-		concepts = append(concepts, "Stock Market")
-	}
-	
-	// Default to search if no specific intent detected
-	if len(intent) == 0 {
-		intent = append(intent, Intent{Type: "search", Confidence: 0.7})
-	}
-	
-	return &Extraction{
-		Entities:    entities,
-		Concepts:    concepts,
-		Intent:      intent,
-		Categories:  categories,
-		SourceNames: sourceNames,
-	}, nil
+	extraction, err := c.extract(ctx, query)
+	if err != nil {
+		return fallbackExtract(ctx, query, err)
+	}
+	return extraction, nil
 }
 
+// extract makes the structured-output chat completion call
+func (c *OpenAIClient) extract(ctx context.Context, query string) (*Extraction, error) {
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+
+	completion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(extractionPrompt),
+			openai.UserMessage(query),
+		},
+		Temperature: openai.Float(0),
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "query_extraction",
+					Strict: openai.Bool(true),
+					Schema: extractionSchema,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+	addUsage(ctx, completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion returned no choices")
+	}
+	message := completion.Choices[0].Message
+	if message.Refusal != "" {
+		return nil, fmt.Errorf("model refused: %s", message.Refusal)
+	}
+
+	return parseExtraction([]byte(message.Content))
+}
+
+// Summarize asks the model for a short summary of an article, rendered from
+// the configured prompt templates
 func (c *OpenAIClient) Summarize(ctx context.Context, title, description, sourceName, publicationDate string) (string, error) {
-	// For now, return a mock summary to avoid complex OpenAI API usage
-	// TODO: Implement actual OpenAI API call when the types are properly understood
-	log.Info().Str("title", title).Msg("Mock summarization - OpenAI API not yet implemented")
-	
-	return fmt.Sprintf("This article discusses %s, published by %s on %s. %s", 
-		title, sourceName, publicationDate, description), nil
+	prompt, err := c.summary.render(title, description, sourceName, publicationDate)
+	if err != nil {
+		return "", err
+	}
+
+	model := summaryModel(ctx, c.model)
+	// ResilientClient retries summaries, so its circuit breaker sees every failure
+	opts := []option.RequestOption{option.WithMaxRetries(0)}
+	if c.azure && model != c.model {
+		opts = append(opts, option.WithBaseURL(azureDeploymentURL(c.azureEndpoint, model)))
+	}
+	completion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(c.summary.systemFor(ctx)),
+			openai.UserMessage(prompt),
+		},
+		MaxCompletionTokens: openai.Int(int64(c.summary.maxTokens)),
+		Temperature:         openai.Float(c.summary.temperature),
+	}, opts...)
+	if err != nil {
+		return "", fmt.Errorf("summary completion failed: %w", err)
+	}
+	addUsage(ctx, completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("summary completion returned no choices")
+	}
+	summary := strings.TrimSpace(completion.Choices[0].Message.Content)
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// AnalyzeSentiment asks the model for an article's sentiment, constrained to
+// sentimentSchema
+func (c *OpenAIClient) AnalyzeSentiment(ctx context.Context, title, description string) (*Sentiment, error) {
+	completion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(sentimentPrompt),
+			openai.UserMessage(articleInput(title, description)),
+		},
+		MaxCompletionTokens: openai.Int(sentimentMaxTokens),
+		Temperature:         openai.Float(0),
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "article_sentiment",
+					Strict: openai.Bool(true),
+					Schema: sentimentSchema,
+				},
+			},
+		},
+	}, option.WithMaxRetries(0))
+	if err != nil {
+		return nil, fmt.Errorf("sentiment completion failed: %w", err)
+	}
+	addUsage(ctx, completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("sentiment completion returned no choices")
+	}
+	message := completion.Choices[0].Message
+	if message.Refusal != "" {
+		return nil, fmt.Errorf("model refused: %s", message.Refusal)
+	}
+	return parseSentiment([]byte(message.Content))
+}
+
+// Categorize asks the model for an article's categories, constrained to
+// categorizeSchema
+func (c *OpenAIClient) Categorize(ctx context.Context, title, description string) ([]string, error) {
+	completion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(categorizePrompt),
+			openai.UserMessage(articleInput(title, description)),
+		},
+		MaxCompletionTokens: openai.Int(categorizeMaxTokens),
+		Temperature:         openai.Float(0),
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "article_categories",
+					Strict: openai.Bool(true),
+					Schema: categorizeSchema,
+				},
+			},
+		},
+	}, option.WithMaxRetries(0))
+	if err != nil {
+		return nil, fmt.Errorf("categorize completion failed: %w", err)
+	}
+	addUsage(ctx, completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("categorize completion returned no choices")
+	}
+	message := completion.Choices[0].Message
+	if message.Refusal != "" {
+		return nil, fmt.Errorf("model refused: %s", message.Refusal)
+	}
+	return parseCategories([]byte(message.Content))
+}
+
+// ExtractEntities asks the model for the named entities of an article,
+// constrained to entitiesSchema
+func (c *OpenAIClient) ExtractEntities(ctx context.Context, title, description string) (*ArticleEntities, error) {
+	completion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(entitiesPrompt),
+			openai.UserMessage(articleInput(title, description)),
+		},
+		MaxCompletionTokens: openai.Int(entitiesMaxTokens),
+		Temperature:         openai.Float(0),
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "article_entities",
+					Strict: openai.Bool(true),
+					Schema: entitiesSchema,
+				},
+			},
+		},
+	}, option.WithMaxRetries(0))
+	if err != nil {
+		return nil, fmt.Errorf("entities completion failed: %w", err)
+	}
+	addUsage(ctx, completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("entities completion returned no choices")
+	}
+	message := completion.Choices[0].Message
+	if message.Refusal != "" {
+		return nil, fmt.Errorf("model refused: %s", message.Refusal)
+	}
+	return parseEntities([]byte(message.Content))
+}
+
+// Embed returns the embeddings of texts from the embedding model
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if c.embeddingModel == "" {
+		return nil, ErrEmbeddingsUnsupported
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	resp, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: c.embeddingModel,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	}, c.embeddingOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	addUsage(ctx, resp.Usage.PromptTokens, 0)
+
+	embeddings := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || int(data.Index) >= len(texts) {
+			return nil, fmt.Errorf("embedding response has index %d for %d inputs", data.Index, len(texts))
+		}
+		embeddings[data.Index] = toFloat32(data.Embedding)
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("embedding response is missing input %d", i)
+		}
+	}
+	return embeddings, nil
 }
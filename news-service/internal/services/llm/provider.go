@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Providers selectable with LLM_PROVIDER
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAzure     = "azure"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// Client is an LLMClient whose credentials can be verified without running a query
+type Client interface {
+	LLMClient
+	// CheckCredentials verifies that the provider accepts the key for the configured model
+	CheckCredentials(ctx context.Context) error
+	// Model names the model answering, as recorded with stored summaries
+	Model() string
+	// EmbeddingModel names the model behind Embed, as recorded with stored
+	// embeddings; empty when the provider can't embed
+	EmbeddingModel() string
+}
+
+// Options selects a provider and configures it
+type Options struct {
+	Provider string
+	APIKey   string
+	// Model is the model name, or the deployment name for Azure OpenAI; empty
+	// uses the provider's default (Azure has none)
+	Model string
+	// EmbeddingModel is the model for Embed, or its deployment for Azure
+	// OpenAI; empty uses the provider's default (Azure has none, and
+	// Anthropic can't embed)
+	EmbeddingModel string
+	// AzureEndpoint is the Azure OpenAI resource, e.g. https://my-resource.openai.azure.com
+	AzureEndpoint   string
+	AzureAPIVersion string
+	// OllamaURL is the local Ollama server, e.g. http://localhost:11434
+	OllamaURL string
+	// OllamaExtractTimeout bounds extraction with Ollama; zero uses 15s
+	OllamaExtractTimeout time.Duration
+	Summary              SummaryOptions
+}
+
+// NewClient creates the client for the configured provider
+func NewClient(opts Options) (Client, error) {
+	switch opts.Provider {
+	case "", ProviderOpenAI:
+		return NewOpenAIClient(opts.APIKey, opts.Model, opts.EmbeddingModel, opts.Summary)
+	case ProviderAzure:
+		return NewAzureOpenAIClient(opts.AzureEndpoint, opts.APIKey, opts.Model, opts.EmbeddingModel, opts.AzureAPIVersion, opts.Summary)
+	case ProviderAnthropic:
+		return NewAnthropicClient(opts.APIKey, opts.Model, opts.Summary)
+	case ProviderOllama:
+		return NewOllamaClient(opts.OllamaURL, opts.Model, opts.EmbeddingModel, opts.OllamaExtractTimeout, opts.Summary)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", opts.Provider)
+	}
+}
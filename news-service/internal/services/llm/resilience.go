@@ -0,0 +1,360 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrCircuitOpen is returned without calling the provider while the circuit
+// breaker is open after repeated failures
+var ErrCircuitOpen = errors.New("LLM provider unavailable: circuit breaker open")
+
+// StatusError is a provider's non-success HTTP response
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Status     string
+	Detail     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %s: %s", e.Provider, e.Status, e.Detail)
+}
+
+// ResilienceOptions tunes a ResilientClient. Zero values use the defaults
+// noted on each field, except MaxRetries, which is used as given.
+type ResilienceOptions struct {
+	// MaxRetries is how many times a failed summary is retried
+	MaxRetries int
+	// RetryBaseDelay is the wait before the first retry, doubled for each
+	// further one with jitter, default 250ms
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the wait between retries, default 4s
+	RetryMaxDelay time.Duration
+	// SummaryTimeout bounds each summary attempt, default 30s
+	SummaryTimeout time.Duration
+	// FailureThreshold is the number of consecutive failed calls that opens
+	// the circuit, default 5
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before one call is let
+	// through to probe the provider, default 30s
+	OpenDuration time.Duration
+}
+
+// ResilientClient wraps a Client with retries, per-call timeouts and a
+// circuit breaker. Summaries that fail with a rate limit, a server error or a
+// network error are retried with exponential backoff. After FailureThreshold
+// consecutive failures, calls fail fast for OpenDuration instead of each
+// waiting out a timeout: extraction answers with the keyword heuristics, and
+// Summarize returns ErrCircuitOpen so callers can fall back to
+// ExtractiveSummary. Extraction isn't retried, because every query waits on
+// it and the heuristics answer at once.
+type ResilientClient struct {
+	Client
+	opts    ResilienceOptions
+	breaker *breaker
+}
+
+// NewResilientClient wraps client with the retry and circuit breaker policy in opts
+func NewResilientClient(client Client, opts ResilienceOptions) *ResilientClient {
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = 250 * time.Millisecond
+	}
+	if opts.RetryMaxDelay <= 0 {
+		opts.RetryMaxDelay = 4 * time.Second
+	}
+	if opts.SummaryTimeout <= 0 {
+		opts.SummaryTimeout = 30 * time.Second
+	}
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = 30 * time.Second
+	}
+	metrics.LLMCircuitState.Set(float64(circuitClosed))
+	return &ResilientClient{
+		Client:  client,
+		opts:    opts,
+		breaker: &breaker{threshold: opts.FailureThreshold, openFor: opts.OpenDuration},
+	}
+}
+
+// Extract asks the wrapped client unless the circuit is open. The wrapped
+// clients fall back to the heuristics themselves when the provider fails, and
+// those fallbacks count as failures.
+func (c *ResilientClient) Extract(ctx context.Context, query string) (*Extraction, error) {
+	if !c.breaker.allow() {
+		return fallbackExtract(ctx, query, ErrCircuitOpen)
+	}
+	extraction, err := c.Client.Extract(ctx, query)
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.abandon()
+	case err != nil || extraction.Fallback:
+		c.breaker.failure()
+	default:
+		c.breaker.success()
+	}
+	return extraction, err
+}
+
+// Summarize asks the wrapped client, retrying rate limits, server errors and
+// network errors, unless the circuit is open
+func (c *ResilientClient) Summarize(ctx context.Context, title, description, sourceName, publicationDate string) (string, error) {
+	if !c.breaker.allow() {
+		return "", ErrCircuitOpen
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.opts.SummaryTimeout)
+		summary, err := c.Client.Summarize(attemptCtx, title, description, sourceName, publicationDate)
+		cancel()
+		if err == nil {
+			c.breaker.success()
+			return summary, nil
+		}
+		if ctx.Err() != nil {
+			c.breaker.abandon()
+			return "", err
+		}
+		if !retryable(err) {
+			// The provider answered; the request itself was at fault
+			c.breaker.success()
+			return "", err
+		}
+		if attempt == c.opts.MaxRetries {
+			c.breaker.failure()
+			return "", err
+		}
+
+		delay := c.backoff(attempt)
+		metrics.LLMRetries.WithLabelValues("summarize").Inc()
+		log.Debug().Err(err).Int("attempt", attempt+1).Dur("delay", delay).Msg("Retrying LLM summary")
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			c.breaker.abandon()
+			return "", err
+		}
+	}
+}
+
+// AnalyzeSentiment asks the wrapped client once, unless the circuit is open.
+// Sentiment is optional enrichment, so it isn't retried.
+func (c *ResilientClient) AnalyzeSentiment(ctx context.Context, title, description string) (*Sentiment, error) {
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, c.opts.SummaryTimeout)
+	defer cancel()
+	sentiment, err := c.Client.AnalyzeSentiment(attemptCtx, title, description)
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.abandon()
+	case err != nil && retryable(err):
+		c.breaker.failure()
+	default:
+		c.breaker.success()
+	}
+	return sentiment, err
+}
+
+// Categorize asks the wrapped client once, unless the circuit is open. The
+// article is stored without categories when it fails, so it isn't retried.
+func (c *ResilientClient) Categorize(ctx context.Context, title, description string) ([]string, error) {
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, c.opts.SummaryTimeout)
+	defer cancel()
+	categories, err := c.Client.Categorize(attemptCtx, title, description)
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.abandon()
+	case err != nil && retryable(err):
+		c.breaker.failure()
+	default:
+		c.breaker.success()
+	}
+	return categories, err
+}
+
+// ExtractEntities asks the wrapped client once, unless the circuit is open.
+// The entity backfill tries the article again later when it fails.
+func (c *ResilientClient) ExtractEntities(ctx context.Context, title, description string) (*ArticleEntities, error) {
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, c.opts.SummaryTimeout)
+	defer cancel()
+	entities, err := c.Client.ExtractEntities(attemptCtx, title, description)
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.abandon()
+	case err != nil && retryable(err):
+		c.breaker.failure()
+	default:
+		c.breaker.success()
+	}
+	return entities, err
+}
+
+// backoff is the wait before retry number attempt+1: the base delay doubled
+// per attempt, capped, with up to half of it randomized so clients that failed
+// together don't retry together
+func (c *ResilientClient) backoff(attempt int) time.Duration {
+	delay := c.opts.RetryBaseDelay << attempt
+	if delay > c.opts.RetryMaxDelay || delay <= 0 {
+		delay = c.opts.RetryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryable reports whether err is worth retrying: rate limits, server
+// errors, timeouts and network failures
+func retryable(err error) bool {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
+
+// Circuit states, as reported by news_llm_circuit_state
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// breaker counts consecutive failures and opens after threshold of them.
+// Once openFor has passed, one probe call is let through: success closes
+// the circuit, failure opens it again.
+type breaker struct {
+	threshold int
+	openFor   time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	// probing is set while the half-open probe call is in flight
+	probing bool
+}
+
+// allow reports whether a call may go to the provider
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.openFor {
+			metrics.LLMCircuitRejections.Inc()
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return true
+	case circuitHalfOpen:
+		if b.probing {
+			metrics.LLMCircuitRejections.Inc()
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// success records a call the provider answered
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	if b.state != circuitClosed {
+		log.Info().Msg("LLM provider recovered, circuit breaker closed")
+		b.setState(circuitClosed)
+	}
+}
+
+// failure records a call the provider failed
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= b.threshold) {
+		log.Warn().Int("failures", b.failures).Dur("open_for", b.openFor).Msg("LLM provider failing, circuit breaker open")
+		b.openedAt = time.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// abandon records a call the caller gave up on, which says nothing about the provider
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) setState(state int) {
+	b.state = state
+	metrics.LLMCircuitState.Set(float64(state))
+}
+
+// ExtractiveSummary summarizes an article from its own text when the model
+// can't: the first two sentences of the description, or the title when there
+// is no description
+func ExtractiveSummary(title, description string) string {
+	text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(description), ".…"))
+	if text == "" {
+		return strings.TrimSpace(title)
+	}
+
+	sentences := 0
+	for i, r := range text {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		// A sentence ends at punctuation followed by a space
+		if next := i + 1; next < len(text) && text[next] == ' ' {
+			if sentences++; sentences == 2 {
+				text = text[:next]
+				break
+			}
+		}
+	}
+	if !strings.HasSuffix(text, ".") && !strings.HasSuffix(text, "!") && !strings.HasSuffix(text, "?") {
+		text += "."
+	}
+	return truncateText(text, 400)
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Sentiment labels
+const (
+	SentimentPositive = "positive"
+	SentimentNegative = "negative"
+	SentimentNeutral  = "neutral"
+)
+
+// Sentiment is the tone of an article
+type Sentiment struct {
+	// Label is positive, negative or neutral
+	Label string `json:"label"`
+	// Score runs from -1 (most negative) to 1 (most positive)
+	Score float64 `json:"score"`
+}
+
+// ValidateSentiment rejects labels other than positive, negative and neutral;
+// "" is accepted as no sentiment
+func ValidateSentiment(label string) error {
+	switch label {
+	case "", SentimentPositive, SentimentNegative, SentimentNeutral:
+		return nil
+	}
+	return fmt.Errorf("unknown sentiment %q: expected %q, %q or %q", label, SentimentPositive, SentimentNegative, SentimentNeutral)
+}
+
+// sentimentTool is the tool Anthropic models are made to call with the sentiment as its input
+const sentimentTool = "record_sentiment"
+
+const (
+	// sentimentMaxTokens bounds the reply, which is a small JSON object
+	sentimentMaxTokens = 64
+	// articleMaxDescriptionChars truncates longer descriptions in sentiment,
+	// category and entity requests, like the summary prompt's default
+	articleMaxDescriptionChars = 2000
+)
+
+// sentimentPrompt instructs the model how to judge an article's tone
+const sentimentPrompt = `You judge the sentiment of news articles from their title and description.
+
+Return:
+- label: "positive" if the news is mainly good, hopeful or favourable, "negative" if it is mainly bad, alarming or unfavourable, and "neutral" if it is factual, mixed or neither.
+- score: a number from -1 (very negative) to 1 (very positive), near 0 for neutral articles.
+
+Judge the events reported, not the writing style.`
+
+// sentimentSchema is the JSON schema the model's sentiment must follow
+var sentimentSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"label": map[string]interface{}{
+			"type": "string",
+			"enum": []string{SentimentPositive, SentimentNegative, SentimentNeutral},
+		},
+		"score": map[string]interface{}{"type": "number"},
+	},
+	"required":             []string{"label", "score"},
+	"additionalProperties": false,
+}
+
+// articleInput is the user message of a sentiment, category or entity request
+func articleInput(title, description string) string {
+	if description = strings.TrimSpace(description); description == "" {
+		return "Title: " + title
+	}
+	return "Title: " + title + "\nDescription: " + truncateText(description, articleMaxDescriptionChars)
+}
+
+// parseSentiment decodes a model's sentiment, keeping the score within [-1, 1]
+func parseSentiment(data []byte) (*Sentiment, error) {
+	var sentiment Sentiment
+	if err := json.Unmarshal(data, &sentiment); err != nil {
+		return nil, fmt.Errorf("invalid sentiment JSON: %w", err)
+	}
+	sentiment.Label = strings.ToLower(strings.TrimSpace(sentiment.Label))
+	if sentiment.Label == "" {
+		return nil, fmt.Errorf("model returned no sentiment label")
+	}
+	if err := ValidateSentiment(sentiment.Label); err != nil {
+		return nil, err
+	}
+	if math.IsNaN(sentiment.Score) {
+		sentiment.Score = 0
+	}
+	sentiment.Score = math.Max(-1, math.Min(1, sentiment.Score))
+	return &sentiment, nil
+}
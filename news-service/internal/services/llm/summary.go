@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultSummarySystemPrompt is used when SummaryOptions.SystemPrompt is empty
+const defaultSummarySystemPrompt = `You summarize news articles for a news search engine. Write 2-3 factual sentences in the language of the article, using only the information given. Do not add opinions, speculation or a preamble.`
+
+// defaultSummaryTemplate is used when SummaryOptions.TemplateFile is empty
+const defaultSummaryTemplate = `Title: {{.Title}}
+Source: {{.Source}}
+Published: {{.PublishedAt}}
+
+{{.Description}}`
+
+// SummaryOptions configures Summarize. Zero values use the defaults, except
+// Temperature, which is used as given.
+type SummaryOptions struct {
+	// SystemPrompt sets the summarizer's instructions
+	SystemPrompt string
+	// TemplateFile is a text/template file rendering the user prompt from
+	// .Title, .Description, .Source and .PublishedAt
+	TemplateFile string
+	// MaxTokens caps the length of a summary
+	MaxTokens int
+	// Temperature trades determinism for variety, from 0 to 2
+	Temperature float64
+	// MaxDescriptionChars truncates longer descriptions before they are rendered
+	MaxDescriptionChars int
+}
+
+// Summary styles selectable per request with WithSummaryOverride; the
+// configured prompt is the default style
+const (
+	SummaryStyleBrief    = "brief"
+	SummaryStyleDetailed = "detailed"
+	SummaryStyleBullets  = "bullets"
+)
+
+// summaryStyles are the instructions each style adds to the system prompt
+var summaryStyles = map[string]string{
+	SummaryStyleBrief:    "Write a single sentence of at most 25 words.",
+	SummaryStyleDetailed: "Write 4-5 sentences covering who, what, when, where and why.",
+	SummaryStyleBullets:  "Write 3 short bullet points, each starting with \"- \".",
+}
+
+// ValidateSummaryStyle reports whether style is empty or a known summary style
+func ValidateSummaryStyle(style string) error {
+	if _, ok := summaryStyles[style]; style != "" && !ok {
+		return fmt.Errorf("unknown summary style %q, expected %q, %q or %q", style, SummaryStyleBrief, SummaryStyleDetailed, SummaryStyleBullets)
+	}
+	return nil
+}
+
+// SummaryOverride changes how one Summarize call is made; zero fields keep
+// the configured settings
+type SummaryOverride struct {
+	// Style is one of the SummaryStyle constants
+	Style string
+	// Model replaces the configured model, or the deployment for Azure OpenAI
+	Model string
+}
+
+type summaryOverrideKey struct{}
+
+// WithSummaryOverride makes the Summarize calls made with ctx use override
+func WithSummaryOverride(ctx context.Context, override SummaryOverride) context.Context {
+	return context.WithValue(ctx, summaryOverrideKey{}, override)
+}
+
+// summaryOverrideFrom returns the override ctx carries, if any
+func summaryOverrideFrom(ctx context.Context) SummaryOverride {
+	override, _ := ctx.Value(summaryOverrideKey{}).(SummaryOverride)
+	return override
+}
+
+// summaryModel returns the model a Summarize call made with ctx uses
+func summaryModel(ctx context.Context, configured string) string {
+	if model := summaryOverrideFrom(ctx).Model; model != "" {
+		return model
+	}
+	return configured
+}
+
+// summaryInput is the data summary templates are rendered with
+type summaryInput struct {
+	Title       string
+	Description string
+	Source      string
+	PublishedAt string
+}
+
+// summaryPrompt renders summary requests from the configured templates and
+// carries the sampling settings every provider sends with them
+type summaryPrompt struct {
+	system      string
+	user        *template.Template
+	maxChars    int
+	maxTokens   int
+	temperature float64
+}
+
+func newSummaryPrompt(opts SummaryOptions) (*summaryPrompt, error) {
+	text := defaultSummaryTemplate
+	if opts.TemplateFile != "" {
+		data, err := os.ReadFile(opts.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read summary template: %w", err)
+		}
+		text = string(data)
+	}
+	user, err := template.New("summary").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid summary template: %w", err)
+	}
+
+	system := opts.SystemPrompt
+	if system == "" {
+		system = defaultSummarySystemPrompt
+	}
+	maxChars := opts.MaxDescriptionChars
+	if maxChars <= 0 {
+		maxChars = 2000
+	}
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 150
+	}
+	return &summaryPrompt{
+		system:      system,
+		user:        user,
+		maxChars:    maxChars,
+		maxTokens:   maxTokens,
+		temperature: opts.Temperature,
+	}, nil
+}
+
+// systemFor returns the system prompt of a Summarize call made with ctx
+func (p *summaryPrompt) systemFor(ctx context.Context) string {
+	if instruction, ok := summaryStyles[summaryOverrideFrom(ctx).Style]; ok {
+		return p.system + "\n\n" + instruction
+	}
+	return p.system
+}
+
+// render returns the user prompt for an article
+func (p *summaryPrompt) render(title, description, sourceName, publicationDate string) (string, error) {
+	var b strings.Builder
+	err := p.user.Execute(&b, summaryInput{
+		Title:       title,
+		Description: truncateText(description, p.maxChars),
+		Source:      sourceName,
+		PublishedAt: publicationDate,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render summary template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// truncateText cuts text to at most maxChars characters, backing up to the
+// last word boundary when there is one in the second half and marking the cut
+// with an ellipsis. The same input always yields the same output, so prompts
+// (and anything keyed on them) stay stable.
+func truncateText(text string, maxChars int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+
+	runes := []rune(text)[:maxChars]
+	cut := len(runes)
+	for i := len(runes) - 1; i >= maxChars/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
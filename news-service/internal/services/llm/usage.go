@@ -0,0 +1,230 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+)
+
+// Operations whose token usage is tracked
+const (
+	OperationExtract    = "extract"
+	OperationSummarize  = "summarize"
+	OperationEmbed      = "embed"
+	OperationSentiment  = "sentiment"
+	OperationCategorize = "categorize"
+	OperationEntities   = "entities"
+)
+
+// EndpointUnattributed labels usage from calls whose context names no endpoint
+const EndpointUnattributed = "none"
+
+type endpointKey struct{}
+
+// WithEndpoint attributes the LLM calls made with ctx to endpoint, e.g. the
+// API route or background job that needed them
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// endpointFrom returns the endpoint ctx was attributed to
+func endpointFrom(ctx context.Context) string {
+	if endpoint, ok := ctx.Value(endpointKey{}).(string); ok && endpoint != "" {
+		return endpoint
+	}
+	return EndpointUnattributed
+}
+
+type usageKey struct{}
+
+// callUsage accumulates the tokens a provider reports during one call
+type callUsage struct {
+	mu                 sync.Mutex
+	prompt, completion int64
+}
+
+// addUsage records tokens a provider reported for a request made with ctx.
+// It does nothing unless a UsageTracker is metering the call.
+func addUsage(ctx context.Context, prompt, completion int64) {
+	if usage, ok := ctx.Value(usageKey{}).(*callUsage); ok {
+		usage.mu.Lock()
+		usage.prompt += prompt
+		usage.completion += completion
+		usage.mu.Unlock()
+	}
+}
+
+// Pricing is the cost of the models' tokens in USD per million
+type Pricing struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+	// EmbeddingPerMillion prices the input tokens of the embedding model
+	EmbeddingPerMillion float64
+}
+
+// cost returns the cost in USD of the prompt and completion tokens of one
+// call of operation
+func (p Pricing) cost(operation string, prompt, completion int64) float64 {
+	if operation == OperationEmbed {
+		return float64(prompt) * p.EmbeddingPerMillion / 1e6
+	}
+	return (float64(prompt)*p.PromptPerMillion + float64(completion)*p.CompletionPerMillion) / 1e6
+}
+
+// UsageTotals is the token usage of one model, operation and endpoint
+type UsageTotals struct {
+	Model            string  `json:"model"`
+	Operation        string  `json:"operation"`
+	Endpoint         string  `json:"endpoint"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// UsageReport is the token usage recorded since the process started
+type UsageReport struct {
+	Since            time.Time     `json:"since"`
+	Calls            int64         `json:"calls"`
+	PromptTokens     int64         `json:"prompt_tokens"`
+	CompletionTokens int64         `json:"completion_tokens"`
+	CostUSD          float64       `json:"cost_usd"`
+	Usage            []UsageTotals `json:"usage"`
+}
+
+// UsageTracker wraps a Client and counts the tokens of each Extract,
+// Summarize, AnalyzeSentiment, Categorize, ExtractEntities and Embed call by
+// model, operation and endpoint, in Prometheus and in totals kept for the admin API. It also times each call in Prometheus. It should wrap the provider client directly,
+// so retried attempts are each counted and cached answers cost nothing.
+type UsageTracker struct {
+	Client
+	pricing Pricing
+	since   time.Time
+
+	mu     sync.Mutex
+	totals map[[3]string]*UsageTotals
+}
+
+// NewUsageTracker wraps client, costing its tokens at pricing
+func NewUsageTracker(client Client, pricing Pricing) *UsageTracker {
+	return &UsageTracker{
+		Client:  client,
+		pricing: pricing,
+		since:   time.Now(),
+		totals:  make(map[[3]string]*UsageTotals),
+	}
+}
+
+// Extract asks the wrapped client and records the tokens it used
+func (t *UsageTracker) Extract(ctx context.Context, query string) (*Extraction, error) {
+	usage, start := &callUsage{}, time.Now()
+	extraction, err := t.Client.Extract(context.WithValue(ctx, usageKey{}, usage), query)
+	t.record(ctx, t.Model(), OperationExtract, usage, time.Since(start), err)
+	return extraction, err
+}
+
+// Summarize asks the wrapped client and records the tokens it used
+func (t *UsageTracker) Summarize(ctx context.Context, title, description, sourceName, publicationDate string) (string, error) {
+	usage, start := &callUsage{}, time.Now()
+	summary, err := t.Client.Summarize(context.WithValue(ctx, usageKey{}, usage), title, description, sourceName, publicationDate)
+	t.record(ctx, summaryModel(ctx, t.Model()), OperationSummarize, usage, time.Since(start), err)
+	return summary, err
+}
+
+// AnalyzeSentiment asks the wrapped client and records the tokens it used
+func (t *UsageTracker) AnalyzeSentiment(ctx context.Context, title, description string) (*Sentiment, error) {
+	usage, start := &callUsage{}, time.Now()
+	sentiment, err := t.Client.AnalyzeSentiment(context.WithValue(ctx, usageKey{}, usage), title, description)
+	t.record(ctx, t.Model(), OperationSentiment, usage, time.Since(start), err)
+	return sentiment, err
+}
+
+// Categorize asks the wrapped client and records the tokens it used
+func (t *UsageTracker) Categorize(ctx context.Context, title, description string) ([]string, error) {
+	usage, start := &callUsage{}, time.Now()
+	categories, err := t.Client.Categorize(context.WithValue(ctx, usageKey{}, usage), title, description)
+	t.record(ctx, t.Model(), OperationCategorize, usage, time.Since(start), err)
+	return categories, err
+}
+
+// ExtractEntities asks the wrapped client and records the tokens it used
+func (t *UsageTracker) ExtractEntities(ctx context.Context, title, description string) (*ArticleEntities, error) {
+	usage, start := &callUsage{}, time.Now()
+	entities, err := t.Client.ExtractEntities(context.WithValue(ctx, usageKey{}, usage), title, description)
+	t.record(ctx, t.Model(), OperationEntities, usage, time.Since(start), err)
+	return entities, err
+}
+
+// Embed asks the wrapped client and records the tokens it used, under the
+// embedding model
+func (t *UsageTracker) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	usage, start := &callUsage{}, time.Now()
+	embeddings, err := t.Client.Embed(context.WithValue(ctx, usageKey{}, usage), texts)
+	if !errors.Is(err, ErrEmbeddingsUnsupported) {
+		t.record(ctx, t.EmbeddingModel(), OperationEmbed, usage, time.Since(start), err)
+	}
+	return embeddings, err
+}
+
+// record adds one call's usage and duration to the metrics and totals. Calls
+// that failed before the provider answered report no tokens but still count
+// as calls.
+func (t *UsageTracker) record(ctx context.Context, model, operation string, usage *callUsage, duration time.Duration, err error) {
+	endpoint := endpointFrom(ctx)
+	usage.mu.Lock()
+	prompt, completion := usage.prompt, usage.completion
+	usage.mu.Unlock()
+	cost := t.pricing.cost(operation, prompt, completion)
+
+	metrics.LLMTokens.WithLabelValues(model, operation, endpoint, "prompt").Add(float64(prompt))
+	metrics.LLMTokens.WithLabelValues(model, operation, endpoint, "completion").Add(float64(completion))
+	metrics.LLMCost.WithLabelValues(model, operation, endpoint).Add(cost)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.LLMCallDuration.WithLabelValues(model, operation, result).Observe(duration.Seconds())
+
+	key := [3]string{model, operation, endpoint}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals, ok := t.totals[key]
+	if !ok {
+		totals = &UsageTotals{Model: model, Operation: operation, Endpoint: endpoint}
+		t.totals[key] = totals
+	}
+	totals.Calls++
+	totals.PromptTokens += prompt
+	totals.CompletionTokens += completion
+	totals.CostUSD += cost
+}
+
+// Report returns the usage recorded so far, ordered by model, operation and endpoint
+func (t *UsageTracker) Report() UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := UsageReport{Since: t.since, Usage: make([]UsageTotals, 0, len(t.totals))}
+	for _, totals := range t.totals {
+		report.Usage = append(report.Usage, *totals)
+		report.Calls += totals.Calls
+		report.PromptTokens += totals.PromptTokens
+		report.CompletionTokens += totals.CompletionTokens
+		report.CostUSD += totals.CostUSD
+	}
+	sort.Slice(report.Usage, func(i, j int) bool {
+		a, b := report.Usage[i], report.Usage[j]
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		return a.Endpoint < b.Endpoint
+	})
+	return report
+}
@@ -0,0 +1,443 @@
+// Package moderation keeps the queue of articles flagged for editors to
+// review: by readers' reports once they reach a threshold, and by the spam
+// heuristics and safety blocklist articles are screened with when stored.
+// Editors approve, demote or reject flagged articles; demoted articles rank
+// below the rest of their results page, rejected ones are deleted. Every
+// review is recorded in an audit log.
+package moderation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// Sources an article can be flagged by
+const (
+	SourceReader = "reader"
+	SourceSpam   = "spam"
+	SourceSafety = "safety"
+)
+
+// Review actions
+const (
+	ActionApprove = "approve"
+	ActionDemote  = "demote"
+	ActionReject  = "reject"
+)
+
+// ReviewerSystem is the reviewer of automatic actions
+const ReviewerSystem = "system"
+
+// Reasons an article can be reported for
+const (
+	ReasonSpam          = "spam"
+	ReasonOffensive     = "offensive"
+	ReasonWrongCategory = "wrong_category"
+	ReasonBrokenLink    = "broken_link"
+)
+
+var reasons = map[string]bool{
+	ReasonSpam:          true,
+	ReasonOffensive:     true,
+	ReasonWrongCategory: true,
+	ReasonBrokenLink:    true,
+}
+
+// ValidateReason rejects reasons other than the ones above
+func ValidateReason(reason string) error {
+	if !reasons[reason] {
+		return errs.Errorf(errs.ErrInvalid, "invalid reason %q: expected %q, %q, %q or %q",
+			reason, ReasonSpam, ReasonOffensive, ReasonWrongCategory, ReasonBrokenLink)
+	}
+	return nil
+}
+
+// Report is one client's report of an article, or one automatic flag
+type Report struct {
+	// Source is reader for reports, spam or safety for automatic flags
+	Source     string    `json:"source"`
+	Reason     string    `json:"reason"`
+	Comment    string    `json:"comment,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// Outcome is the state of an article after a report
+type Outcome struct {
+	// Reports counts the clients that reported the article and the automatic flags
+	Reports int64
+	Flagged bool
+	Demoted bool
+}
+
+// Flagged is an article awaiting review
+type Flagged struct {
+	ArticleID string         `json:"article_id"`
+	Reports   int64          `json:"reports"`
+	Reasons   map[string]int `json:"reasons"`
+	Sources   map[string]int `json:"sources"`
+	Demoted   bool           `json:"demoted"`
+	// Recent lists the reports, newest first
+	Recent []Report `json:"recent"`
+}
+
+// Options tunes a Queue
+type Options struct {
+	// Threshold is the number of clients whose reports flag an article; default 3
+	Threshold int
+	// AutoDemote demotes articles as soon as they are flagged, rather than
+	// when an editor decides to
+	AutoDemote bool
+	// AuditMaxEntries bounds the reviews kept in the audit log; default 10000
+	AuditMaxEntries int
+}
+
+// Review is an editor's decision on a flagged article
+type Review struct {
+	Reviewer string `json:"reviewer,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// AuditEntry records one review and what the article was flagged for then
+type AuditEntry struct {
+	Action    string         `json:"action"`
+	ArticleID string         `json:"article_id"`
+	Reviewer  string         `json:"reviewer,omitempty"`
+	Note      string         `json:"note,omitempty"`
+	Reasons   map[string]int `json:"reasons"`
+	Sources   map[string]int `json:"sources"`
+	At        time.Time      `json:"at"`
+}
+
+// Queue stores reports in Redis: a hash of reports per article keyed by
+// client, so a client reporting an article again replaces its earlier report,
+// or by source for automatic flags, a sorted set of flagged articles scored by
+// their report count, a set of demoted articles and a capped list of reviews
+type Queue struct {
+	cache *cache.RedisCache
+	opts  Options
+}
+
+// NewQueue creates a moderation queue
+func NewQueue(cache *cache.RedisCache, opts Options) *Queue {
+	if opts.Threshold < 1 {
+		opts.Threshold = 3
+	}
+	if opts.AuditMaxEntries < 1 {
+		opts.AuditMaxEntries = 10000
+	}
+	return &Queue{cache: cache, opts: opts}
+}
+
+// Report records client's report of an article, flagging the article once
+// Threshold clients reported it. Clients are stored hashed.
+func (q *Queue) Report(ctx context.Context, articleID, client string, report Report) (Outcome, error) {
+	report.Source = SourceReader
+	outcome, err := q.add(ctx, articleID, clientHash(client), report, q.opts.Threshold)
+	if err != nil {
+		return Outcome{}, err
+	}
+	metrics.ArticleReports.WithLabelValues(report.Reason).Inc()
+	return outcome, nil
+}
+
+// Flag flags an article right away for an automatic finding, replacing an
+// earlier flag from the same source
+func (q *Queue) Flag(ctx context.Context, articleID string, report Report) (Outcome, error) {
+	return q.add(ctx, articleID, "auto:"+report.Source, report, 1)
+}
+
+// add stores report under field and flags the article once it has threshold reports
+func (q *Queue) add(ctx context.Context, articleID, field string, report Report, threshold int) (Outcome, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	var count *redis.IntCmd
+	err = q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, cache.ModerationReportsKey(articleID), field, data)
+		count = pipe.HLen(ctx, cache.ModerationReportsKey(articleID))
+		return nil
+	})
+	if err != nil {
+		return Outcome{}, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to store report of %s: %w", articleID, err))
+	}
+
+	outcome := Outcome{Reports: count.Val()}
+	if outcome.Reports < int64(threshold) {
+		return outcome, nil
+	}
+	outcome.Flagged = true
+	outcome.Demoted = q.opts.AutoDemote
+	var added *redis.IntCmd
+	err = q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		added = pipe.ZAdd(ctx, cache.ModerationQueueKey(), redis.Z{Score: float64(outcome.Reports), Member: articleID})
+		if q.opts.AutoDemote {
+			pipe.SAdd(ctx, cache.ModerationDemotedKey(), articleID)
+		}
+		return nil
+	})
+	if err != nil {
+		return Outcome{}, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to flag %s: %w", articleID, err))
+	}
+	// Later reports only raise the flagged article's score
+	if added.Val() > 0 {
+		metrics.ArticlesFlagged.WithLabelValues(report.Source).Inc()
+		if q.opts.AutoDemote {
+			metrics.ArticlesDemoted.WithLabelValues("auto").Inc()
+			q.record(ctx, AuditEntry{
+				Action:    ActionDemote,
+				ArticleID: articleID,
+				Reviewer:  ReviewerSystem,
+				Note:      "demoted when flagged by " + report.Source,
+				Reasons:   map[string]int{report.Reason: 1},
+				Sources:   map[string]int{report.Source: 1},
+			})
+		}
+	}
+	return outcome, nil
+}
+
+// Flagged returns up to limit flagged articles, most reported first
+func (q *Queue) Flagged(ctx context.Context, limit int) ([]Flagged, error) {
+	members, err := q.cache.ZRevRangeWithScores(ctx, cache.ModerationQueueKey(), 0, int64(limit)-1)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to read moderation queue: %w", err))
+	}
+
+	reports := make([]*redis.MapStringStringCmd, len(members))
+	demoted := make([]*redis.BoolCmd, len(members))
+	err = q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			id := member.Member.(string)
+			reports[i] = pipe.HGetAll(ctx, cache.ModerationReportsKey(id))
+			demoted[i] = pipe.SIsMember(ctx, cache.ModerationDemotedKey(), id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to read reports: %w", err))
+	}
+
+	flagged := make([]Flagged, 0, len(members))
+	for i, member := range members {
+		item := Flagged{
+			ArticleID: member.Member.(string),
+			Reports:   int64(member.Score),
+			Demoted:   demoted[i].Val(),
+		}
+		item.Recent, item.Reasons, item.Sources = decodeReports(reports[i].Val())
+		flagged = append(flagged, item)
+	}
+	return flagged, nil
+}
+
+// Approve keeps an article as it is: its reports, flag and demotion are
+// dropped and the review is recorded
+func (q *Queue) Approve(ctx context.Context, articleID string, review Review) (AuditEntry, error) {
+	return q.review(ctx, articleID, ActionApprove, review, func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, cache.ModerationReportsKey(articleID))
+		pipe.ZRem(ctx, cache.ModerationQueueKey(), articleID)
+		pipe.SRem(ctx, cache.ModerationDemotedKey(), articleID)
+	})
+}
+
+// Demote ranks an article below the rest of its results page and closes its
+// review, so only new reports flag it again
+func (q *Queue) Demote(ctx context.Context, articleID string, review Review) (AuditEntry, error) {
+	entry, err := q.review(ctx, articleID, ActionDemote, review, func(pipe redis.Pipeliner) {
+		pipe.SAdd(ctx, cache.ModerationDemotedKey(), articleID)
+		pipe.Del(ctx, cache.ModerationReportsKey(articleID))
+		pipe.ZRem(ctx, cache.ModerationQueueKey(), articleID)
+	})
+	if err == nil {
+		metrics.ArticlesDemoted.WithLabelValues("admin").Inc()
+	}
+	return entry, err
+}
+
+// Reject removes an article with remove, then drops its reports, flag and
+// demotion and records the review. Nothing is recorded when remove fails.
+func (q *Queue) Reject(ctx context.Context, articleID string, review Review, remove func(ctx context.Context, articleID string) error) (AuditEntry, error) {
+	entry, err := q.pending(ctx, articleID)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	if err := remove(ctx, articleID); err != nil {
+		return AuditEntry{}, err
+	}
+	if err := q.Dismiss(ctx, articleID); err != nil {
+		return AuditEntry{}, err
+	}
+	entry.Action, entry.Reviewer, entry.Note = ActionReject, review.Reviewer, review.Note
+	q.finish(ctx, entry)
+	return entry, nil
+}
+
+// review applies an editor's action to an article's moderation state and
+// records it with what the article was flagged for
+func (q *Queue) review(ctx context.Context, articleID, action string, review Review, apply func(pipe redis.Pipeliner)) (AuditEntry, error) {
+	entry, err := q.pending(ctx, articleID)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	err = q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		apply(pipe)
+		return nil
+	})
+	if err != nil {
+		return AuditEntry{}, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to %s %s: %w", action, articleID, err))
+	}
+	entry.Action, entry.Reviewer, entry.Note = action, review.Reviewer, review.Note
+	q.finish(ctx, entry)
+	return entry, nil
+}
+
+// pending returns an audit entry holding what an article is flagged for now
+func (q *Queue) pending(ctx context.Context, articleID string) (AuditEntry, error) {
+	var reports *redis.MapStringStringCmd
+	err := q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		reports = pipe.HGetAll(ctx, cache.ModerationReportsKey(articleID))
+		return nil
+	})
+	if err != nil {
+		return AuditEntry{}, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to read reports of %s: %w", articleID, err))
+	}
+	entry := AuditEntry{ArticleID: articleID}
+	_, entry.Reasons, entry.Sources = decodeReports(reports.Val())
+	return entry, nil
+}
+
+// finish counts and records a completed review
+func (q *Queue) finish(ctx context.Context, entry AuditEntry) {
+	metrics.ModerationReviews.WithLabelValues(entry.Action).Inc()
+	q.record(ctx, entry)
+}
+
+// record appends entry to the audit log. The action has already been taken,
+// so a failure to record it is logged rather than returned.
+func (q *Queue) record(ctx context.Context, entry AuditEntry) {
+	entry.At = time.Now().UTC()
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LPush(ctx, cache.ModerationAuditKey(), data)
+			pipe.LTrim(ctx, cache.ModerationAuditKey(), 0, int64(q.opts.AuditMaxEntries)-1)
+			return nil
+		})
+	}
+	if err != nil {
+		log.Error().Err(err).
+			Str("action", entry.Action).
+			Str("article_id", entry.ArticleID).
+			Str("reviewer", entry.Reviewer).
+			Msg("Failed to record moderation review")
+	}
+}
+
+// Audit returns up to limit of the newest reviews, of articleID's only when
+// it isn't empty
+func (q *Queue) Audit(ctx context.Context, articleID string, limit int) ([]AuditEntry, error) {
+	stop := int64(limit) - 1
+	if articleID != "" {
+		stop = -1
+	}
+	var cmd *redis.StringSliceCmd
+	err := q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = pipe.LRange(ctx, cache.ModerationAuditKey(), 0, stop)
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to read moderation audit log: %w", err))
+	}
+
+	entries := make([]AuditEntry, 0, min(len(cmd.Val()), limit))
+	for _, value := range cmd.Val() {
+		var entry AuditEntry
+		if json.Unmarshal([]byte(value), &entry) != nil || (articleID != "" && entry.ArticleID != articleID) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) == limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// Dismiss drops an article's reports, flag and demotion without recording a
+// review, for articles that were deleted
+func (q *Queue) Dismiss(ctx context.Context, articleID string) error {
+	err := q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, cache.ModerationReportsKey(articleID))
+		pipe.ZRem(ctx, cache.ModerationQueueKey(), articleID)
+		pipe.SRem(ctx, cache.ModerationDemotedKey(), articleID)
+		return nil
+	})
+	if err != nil {
+		return errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to dismiss reports of %s: %w", articleID, err))
+	}
+	return nil
+}
+
+// Demoted reports which of articleIDs are demoted
+func (q *Queue) Demoted(ctx context.Context, articleIDs []string) (map[string]bool, error) {
+	cmds := make([]*redis.BoolCmd, len(articleIDs))
+	err := q.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range articleIDs {
+			cmds[i] = pipe.SIsMember(ctx, cache.ModerationDemotedKey(), id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demoted articles: %w", err)
+	}
+	demoted := make(map[string]bool)
+	for i, cmd := range cmds {
+		if cmd.Val() {
+			demoted[articleIDs[i]] = true
+		}
+	}
+	return demoted, nil
+}
+
+// decodeReports decodes an article's stored reports, newest first, and
+// counts them by reason and source
+func decodeReports(values map[string]string) (recent []Report, reasons, sources map[string]int) {
+	reasons, sources = make(map[string]int), make(map[string]int)
+	for _, value := range values {
+		var report Report
+		if json.Unmarshal([]byte(value), &report) != nil {
+			continue
+		}
+		// Reports stored before automatic flags existed all came from readers
+		if report.Source == "" {
+			report.Source = SourceReader
+		}
+		reasons[report.Reason]++
+		sources[report.Source]++
+		recent = append(recent, report)
+	}
+	sort.Slice(recent, func(a, b int) bool {
+		return recent[a].ReportedAt.After(recent[b].ReportedAt)
+	})
+	return recent, reasons, sources
+}
+
+// clientHash keeps API keys and IPs out of Redis while still telling clients apart
+func clientHash(client string) string {
+	sum := sha256.Sum256([]byte(client))
+	return hex.EncodeToString(sum[:16])
+}
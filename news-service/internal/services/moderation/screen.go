@@ -0,0 +1,150 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"news-system/internal/bus"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// spamPhrases are wording typical of advertising and scams rather than news
+var spamPhrases = []string{
+	"click here", "buy now", "act now", "limited time offer", "100% free",
+	"free money", "work from home", "earn cash", "risk-free", "miracle cure",
+	"crypto giveaway", "double your", "you won't believe",
+}
+
+const (
+	// minShoutingLetters is the shortest title, in letters, judged for shouting
+	minShoutingLetters = 12
+	// maxTitleExclamations is the most exclamation marks a title has before it looks like spam
+	maxTitleExclamations = 2
+	// maxDescriptionLinks is the most links a description has before it looks like spam
+	maxDescriptionLinks = 2
+)
+
+// ArticleReader reads the stored article a screened event refers to
+type ArticleReader interface {
+	GetArticleByID(ctx context.Context, id string) (repo.Article, error)
+}
+
+// Screener flags stored articles that look like spam or contain a term of
+// the safety blocklist
+type Screener struct {
+	queue     *Queue
+	articles  ArticleReader
+	blocklist []string
+}
+
+// NewScreener creates a screener flagging articles in queue. blocklist terms
+// match case-insensitively anywhere in the title or description.
+func NewScreener(queue *Queue, articles ArticleReader, blocklist []string) *Screener {
+	terms := make([]string, 0, len(blocklist))
+	for _, term := range blocklist {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return &Screener{queue: queue, articles: articles, blocklist: terms}
+}
+
+// Screen returns the automatic flags article earns, if any
+func (s *Screener) Screen(article repo.Article) []Report {
+	description := ""
+	if article.Description != nil {
+		description = *article.Description
+	}
+	text := strings.ToLower(article.Title + "\n" + description)
+	now := time.Now().UTC()
+
+	var flags []Report
+	if signals := spamSignals(article.Title, description, text); len(signals) > 0 {
+		flags = append(flags, Report{
+			Source:     SourceSpam,
+			Reason:     ReasonSpam,
+			Comment:    strings.Join(signals, "; "),
+			ReportedAt: now,
+		})
+	}
+	var matched []string
+	for _, term := range s.blocklist {
+		if strings.Contains(text, term) {
+			matched = append(matched, term)
+		}
+	}
+	if len(matched) > 0 {
+		flags = append(flags, Report{
+			Source:     SourceSafety,
+			Reason:     ReasonOffensive,
+			Comment:    "blocklisted: " + strings.Join(matched, ", "),
+			ReportedAt: now,
+		})
+	}
+	return flags
+}
+
+// spamSignals lists why an article looks like spam. text is the lowercased
+// title and description.
+func spamSignals(title, description, text string) []string {
+	var signals []string
+	letters, upper := 0, 0
+	for _, r := range title {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= minShoutingLetters && upper*10 >= letters*8 {
+		signals = append(signals, "title in capitals")
+	}
+	if strings.Count(title, "!") > maxTitleExclamations {
+		signals = append(signals, "title exclamations")
+	}
+	if strings.Count(strings.ToLower(description), "http") > maxDescriptionLinks {
+		signals = append(signals, "links in description")
+	}
+	for _, phrase := range spamPhrases {
+		if strings.Contains(text, phrase) {
+			signals = append(signals, "phrase \""+phrase+"\"")
+		}
+	}
+	return signals
+}
+
+// Subscribe screens the articles this instance creates or updates, as they
+// are announced on events. The returned function stops screening.
+func (s *Screener) Subscribe(events *bus.Bus) func() {
+	return events.Subscribe("moderation-screen", func(ctx context.Context, event bus.Event) {
+		// Other instances screen the articles they store themselves
+		if event.Origin != events.Origin() {
+			return
+		}
+		var payload bus.ArticlePayload
+		if err := event.Decode(&payload); err != nil || payload.ArticleID == "" {
+			return
+		}
+		article, err := s.articles.GetArticleByID(ctx, payload.ArticleID)
+		if err != nil {
+			log.Warn().Err(err).Str("article_id", payload.ArticleID).Msg("Failed to read article to screen")
+			return
+		}
+		for _, flag := range s.Screen(article) {
+			if _, err := s.queue.Flag(ctx, article.ID, flag); err != nil {
+				log.Warn().Err(err).Str("article_id", article.ID).Str("source", flag.Source).Msg("Failed to flag article")
+				continue
+			}
+			log.Info().
+				Str("article_id", article.ID).
+				Str("source", flag.Source).
+				Str("signals", flag.Comment).
+				Msg("Article flagged for moderation")
+		}
+	}, bus.ArticleCreated, bus.ArticleUpdated)
+}
@@ -0,0 +1,100 @@
+package news
+
+import (
+	"context"
+	"time"
+
+	"news-system/internal/metrics"
+)
+
+// archiveSearch configures reading the archive behind the hot window, see
+// EnableArchiveSearch
+type archiveSearch struct {
+	hotWindow time.Duration
+}
+
+// cutoff returns the publication date before which articles are archived at
+// now. It moves by the hour, so first pages planned within the same hour
+// share search cache entries.
+func (a *archiveSearch) cutoff(now time.Time) *time.Time {
+	cutoff := now.UTC().Add(-a.hotWindow).Truncate(time.Hour)
+	return &cutoff
+}
+
+// archived reports whether an article lies behind plan's archive cutoff
+func archived(plan queryPlan, article ArticleDTO) bool {
+	return plan.ArchiveCutoff != nil && article.PublicationDate.Before(*plan.ArchiveCutoff)
+}
+
+// tiers splits plan at its archive cutoff into the plan of the hot window
+// and that of the archive. hasHot and hasArchive report which of them its
+// publication window reaches into.
+func tiers(plan queryPlan) (hot, archive queryPlan, hasHot, hasArchive bool) {
+	cutoff := *plan.ArchiveCutoff
+	hot, archive = plan, plan
+	hasHot = plan.PublishedBefore == nil || plan.PublishedBefore.After(cutoff)
+	hasArchive = plan.PublishedAfter == nil || plan.PublishedAfter.Before(cutoff)
+	if hasArchive {
+		hot.PublishedAfter = &cutoff
+	}
+	if hasHot {
+		archive.PublishedBefore = &cutoff
+	}
+	return hot, archive, hasHot, hasArchive
+}
+
+// retrieveTiered fetches a page of plan's articles from the hot window first,
+// and from the archive only once the page runs past the hot window's last
+// article. Archived articles come after every article of the hot window.
+func (s *NewsService) retrieveTiered(ctx context.Context, plan queryPlan, page repoPage, useCache bool) ([]ArticleDTO, error) {
+	hot, archive, hasHot, hasArchive := tiers(plan)
+	if !hasArchive {
+		return s.retrieveStrategy(ctx, hot, page, useCache)
+	}
+	if !hasHot {
+		metrics.ArchiveReads.WithLabelValues("window").Inc()
+		return s.retrieveStrategy(ctx, archive, page, useCache)
+	}
+
+	var articles []ArticleDTO
+	hotTotal := plan.HotTotal
+	if hotTotal == nil || int(page.Offset) < *hotTotal {
+		var err error
+		articles, err = s.retrieveStrategy(ctx, hot, page, useCache)
+		if err != nil || len(articles) == int(page.Limit) {
+			return articles, err
+		}
+		total := int(page.Offset) + len(articles)
+		hotTotal = &total
+	}
+
+	metrics.ArchiveReads.WithLabelValues("past_hot").Inc()
+	found, err := s.retrieveStrategy(ctx, archive, repoPage{
+		Limit:  page.Limit - int32(len(articles)),
+		Offset: max(page.Offset-int32(*hotTotal), 0),
+	}, useCache)
+	if err != nil {
+		return nil, err
+	}
+	return append(articles, found...), nil
+}
+
+// noteHotTotal records in plan how many articles its hot window holds, once
+// articles, the page at page, has run past them, so the pages after it start
+// reading the archive at the right offset
+func noteHotTotal(plan queryPlan, page repoPage, articles []ArticleDTO) queryPlan {
+	if plan.ArchiveCutoff == nil || plan.HotTotal != nil {
+		return plan
+	}
+	hot := 0
+	for _, article := range articles {
+		if !archived(plan, article) {
+			hot++
+		}
+	}
+	if hot < len(articles) || len(articles) < int(page.Limit) {
+		total := int(page.Offset) + hot
+		plan.HotTotal = &total
+	}
+	return plan
+}
@@ -0,0 +1,542 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"news-system/internal/bus"
+	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/language"
+	"news-system/internal/services/llm"
+	"news-system/internal/validation"
+
+	"github.com/rs/zerolog/log"
+)
+
+// summaryTimeout bounds generating one article summary, including the LLM
+// client's retries; callers stop waiting when their own context ends
+const summaryTimeout = 3 * time.Minute
+
+// recentEventsWindow is the period an article's engagement counts cover, matching the trending window
+const recentEventsWindow = 24 * time.Hour
+
+// ArticleDetail is a single article with its stored summary and recent engagement
+type ArticleDetail struct {
+	ArticleDTO
+	SummaryModel       *string     `json:"summary_model,omitempty"`
+	SummaryGeneratedAt *time.Time  `json:"summary_generated_at,omitempty"`
+	// Entities are the people, organizations and locations the article
+	// mentions, once the entity backfill has extracted them
+	Entities     []repo.ArticleEntity `json:"entities,omitempty"`
+	RecentEvents EventCounts          `json:"recent_events"`
+}
+
+// EventCounts is the number of user events of each type within a window
+type EventCounts struct {
+	Window string           `json:"window"`
+	Total  int64            `json:"total"`
+	ByType map[string]int64 `json:"by_type"`
+	// UniqueReaders approximates the distinct users behind the events
+	UniqueReaders int64 `json:"unique_readers"`
+}
+
+// ArticleRequest is the editable content of an article, as sent to PUT /articles/{id}
+type ArticleRequest struct {
+	Title           string    `json:"title" validate:"required,max=500"`
+	Description     *string   `json:"description,omitempty"`
+	URL             string    `json:"url" validate:"required,url"`
+	PublicationDate time.Time `json:"publication_date" validate:"required"`
+	SourceName      string    `json:"source_name" validate:"required,max=100"`
+	Category        []string  `json:"category"`
+	RelevanceScore  float64   `json:"relevance_score" validate:"min=0,max=1"`
+	Latitude        *float64  `json:"latitude,omitempty" validate:"omitempty,min=-90,max=90"`
+	Longitude       *float64  `json:"longitude,omitempty" validate:"omitempty,min=-180,max=180"`
+	// Language is the article's ISO 639-1 code; detected from the text when omitted
+	Language        string    `json:"language,omitempty"`
+}
+
+// Validate checks the request against its validate tags, then against the
+// constraints tags can't express
+func (r ArticleRequest) Validate() error {
+	if err := validation.Struct(r); err != nil {
+		return err
+	}
+	switch {
+	case strings.TrimSpace(r.Title) == "":
+		return errs.New(errs.ErrInvalid, "title is required (max 500 characters)")
+	case !strings.HasPrefix(r.URL, "http://") && !strings.HasPrefix(r.URL, "https://"):
+		return errs.New(errs.ErrInvalid, "url must be an http(s) URL")
+	case strings.TrimSpace(r.SourceName) == "":
+		return errs.New(errs.ErrInvalid, "source_name is required (max 100 characters)")
+	case (r.Latitude == nil) != (r.Longitude == nil):
+		return errs.New(errs.ErrInvalid, "latitude and longitude must be given together")
+	}
+	if _, ok := language.Normalize(r.Language); !ok {
+		return errs.New(errs.ErrInvalid, "language must be an ISO 639-1 code such as \"en\"")
+	}
+	return nil
+}
+
+// ArticleDTO converts the request to the ingestion format
+func (r ArticleRequest) ArticleDTO() ArticleDTO {
+	return ArticleDTO{
+		Title:           r.Title,
+		Description:     r.Description,
+		URL:             strings.TrimSpace(r.URL),
+		PublicationDate: r.PublicationDate,
+		SourceName:      r.SourceName,
+		Category:        r.Category,
+		RelevanceScore:  r.RelevanceScore,
+		Latitude:        r.Latitude,
+		Longitude:       r.Longitude,
+		Language:        r.Language,
+	}
+}
+
+// GetArticle returns an article with its stored LLM summary, if one was
+// generated, its extracted entities and its user event counts over the last
+// recentEventsWindow. Summary, entity and event lookups are best-effort: the article is returned without
+// them rather than failing.
+func (s *NewsService) GetArticle(ctx context.Context, id string) (*ArticleDetail, error) {
+	article, err := s.cachedArticle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &ArticleDetail{
+		ArticleDTO: s.convertToDTO(article),
+		RecentEvents: EventCounts{
+			Window: recentEventsWindow.String(),
+			ByType: make(map[string]int64),
+		},
+	}
+
+	summary, err := s.cachedSummary(ctx, article.ID, article.PublicationDate)
+	switch {
+	case err == nil:
+		detail.LLMSummary = &summary.LLMSummary
+		detail.SummaryModel = &summary.Model
+		detail.SummaryGeneratedAt = &summary.GeneratedAt
+		detail.Sentiment = summary.Sentiment
+		detail.SentimentScore = summary.SentimentScore
+	case !errors.Is(err, errs.ErrNotFound):
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to load article summary")
+	}
+
+	if detail.Entities, err = s.repo.GetArticleEntities(ctx, article.ID); err != nil {
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to load article entities")
+	}
+
+	counts, err := s.repo.GetArticleEventCounts(ctx, repo.GetArticleEventCountsParams{
+		ArticleID: id,
+		Since:     time.Now().Add(-recentEventsWindow),
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to load article event counts")
+	}
+	for _, count := range counts {
+		detail.RecentEvents.ByType[count.Event] = count.Count
+		detail.RecentEvents.Total += count.Count
+	}
+
+	if s.readers != nil {
+		uniqueReaders, err := s.readers.ArticleReaders(ctx, id)
+		if err != nil {
+			log.Warn().Err(err).Str("article_id", id).Msg("Failed to count article readers")
+		}
+		detail.RecentEvents.UniqueReaders = uniqueReaders
+	}
+
+	return detail, nil
+}
+
+// UpdateArticle replaces the content of an article and announces the change
+func (s *NewsService) UpdateArticle(ctx context.Context, id string, req ArticleRequest) (ArticleDTO, error) {
+	if err := req.Validate(); err != nil {
+		return ArticleDTO{}, err
+	}
+
+	// The place follows the coordinates, which may have moved
+	place, _ := s.tagger.Lookup(ctx, req.Latitude, req.Longitude)
+	article, err := s.repo.UpdateArticle(ctx, repo.UpdateArticleParams{
+		ID:              id,
+		Title:           req.Title,
+		Description:     req.Description,
+		URL:             strings.TrimSpace(req.URL),
+		PublicationDate: req.PublicationDate,
+		SourceName:      req.SourceName,
+		Category:        req.Category,
+		RelevanceScore:  req.RelevanceScore,
+		Latitude:        req.Latitude,
+		Longitude:       req.Longitude,
+		Language:        language.ForArticle(req.Language, req.Title, req.Description),
+		City:            place.City,
+		Region:          place.Region,
+		Country:         place.Country,
+	})
+	if err != nil {
+		return ArticleDTO{}, err
+	}
+
+	s.invalidateArticle(ctx, id)
+	s.events.Emit(bus.ArticleUpdated, articlePayload(article))
+	return s.convertToDTO(article), nil
+}
+
+// BatchStatus is the outcome of one article of a batch create
+type BatchStatus string
+
+const (
+	BatchCreated BatchStatus = "created"
+	BatchUpdated BatchStatus = "updated"
+	// BatchSkipped marks an ingested article whose content matched the stored version
+	BatchSkipped BatchStatus = "skipped"
+	BatchFailed  BatchStatus = "error"
+)
+
+// BatchItemResult is the outcome of the article at Index of a batch create
+type BatchItemResult struct {
+	Index   int
+	Status  BatchStatus
+	Article *ArticleDTO
+	Err     error
+}
+
+// CreateArticles creates or updates many articles at once. Invalid articles
+// fail on their own without affecting the rest; an error is returned only if
+// the batch could not be written at all.
+func (s *NewsService) CreateArticles(ctx context.Context, reqs []ArticleRequest) ([]BatchItemResult, error) {
+	results := make([]BatchItemResult, len(reqs))
+	params := make([]repo.CreateArticleParams, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		results[i].Index = i
+		if err := req.Validate(); err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+			continue
+		}
+		// A fresh ID tells a created article from an updated one, which keeps its own
+		params = append(params, repo.CreateArticleParams{
+			ID:              repo.NewArticleID(),
+			Title:           req.Title,
+			Description:     req.Description,
+			URL:             strings.TrimSpace(req.URL),
+			PublicationDate: req.PublicationDate,
+			SourceName:      req.SourceName,
+			Category:        req.Category,
+			RelevanceScore:  req.RelevanceScore,
+			Latitude:        req.Latitude,
+			Longitude:       req.Longitude,
+			Language:        language.ForArticle(req.Language, req.Title, req.Description),
+		})
+		indexes = append(indexes, i)
+	}
+	if len(params) == 0 {
+		return results, nil
+	}
+	s.classifier.ClassifyAll(ctx, params)
+	s.tagger.TagAll(ctx, params)
+
+	stored, err := s.repo.BulkCreateArticles(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, res := range stored {
+		result := &results[indexes[j]]
+		if res.Err != nil {
+			result.Status = BatchFailed
+			result.Err = res.Err
+			continue
+		}
+
+		eventType := bus.ArticleCreated
+		result.Status = BatchCreated
+		if res.Article.ID != params[j].ID {
+			eventType = bus.ArticleUpdated
+			result.Status = BatchUpdated
+			s.invalidateArticle(ctx, res.Article.ID)
+		}
+		dto := s.convertToDTO(res.Article)
+		result.Article = &dto
+		s.events.Emit(eventType, articlePayload(res.Article))
+	}
+	return results, nil
+}
+
+// DeleteArticle removes an article and announces the deletion
+func (s *NewsService) DeleteArticle(ctx context.Context, id string) error {
+	article, err := s.repo.GetArticleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteArticle(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateArticle(ctx, id)
+	if s.moderation != nil {
+		if err := s.moderation.Dismiss(ctx, id); err != nil {
+			log.Warn().Err(err).Str("article_id", id).Msg("Failed to drop reports of deleted article")
+		}
+	}
+
+	s.events.Emit(bus.ArticleDeleted, articlePayload(article))
+	return nil
+}
+
+// cachedArticle reads an article through the news:article: cache. Recent
+// articles are cached briefly, as they are still being corrected.
+func (s *NewsService) cachedArticle(ctx context.Context, id string) (repo.Article, error) {
+	if s.cache == nil {
+		return s.repo.GetArticleByID(ctx, id)
+	}
+
+	var cached repo.Article
+	if err := s.cache.GetPayload(ctx, cache.ArticleKey(id), articleSchema, &cached); err == nil {
+		return cached, nil
+	}
+
+	article, err := s.repo.GetArticleByID(ctx, id)
+	if err != nil {
+		return repo.Article{}, err
+	}
+	if err := s.cache.SetPayload(ctx, cache.ArticleKey(id), articleSchema, article, cache.ArticleTTLFor(article.PublicationDate)); err != nil {
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to cache article")
+	}
+	return article, nil
+}
+
+// cachedSummary reads an article's stored summary through the news:summary: cache
+func (s *NewsService) cachedSummary(ctx context.Context, id string, publishedAt time.Time) (repo.ArticleSummary, error) {
+	if s.cache == nil {
+		return s.repo.GetArticleSummary(ctx, id)
+	}
+
+	var cached repo.ArticleSummary
+	if err := s.cache.GetPayload(ctx, cache.SummaryKey(id), summarySchema, &cached); err == nil {
+		return cached, nil
+	}
+
+	summary, err := s.repo.GetArticleSummary(ctx, id)
+	if err != nil {
+		return repo.ArticleSummary{}, err
+	}
+	s.cacheSummary(ctx, summary, publishedAt)
+	return summary, nil
+}
+
+// cacheSummary stores summary under news:summary: for as long as an article of its age stays stable
+func (s *NewsService) cacheSummary(ctx context.Context, summary repo.ArticleSummary, publishedAt time.Time) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.SetPayload(ctx, cache.SummaryKey(summary.ArticleID), summarySchema, summary, cache.SummaryTTLFor(publishedAt)); err != nil {
+		log.Warn().Err(err).Str("article_id", summary.ArticleID).Msg("Failed to cache article summary")
+	}
+}
+
+// SummarizeArticle makes sure article has a stored summary, generating one
+// when it has none yet, and reports whether it generated one. Unlike query
+// results, which serve extractive fallbacks and summaries that couldn't be
+// stored, it fails then.
+func (s *NewsService) SummarizeArticle(ctx context.Context, article repo.Article) (bool, error) {
+	result, err := s.articleSummary(ctx, s.convertToDTO(article))
+	switch {
+	case err != nil:
+		return false, err
+	case result.fallbackErr != nil:
+		return false, result.fallbackErr
+	}
+	return result.generated, result.storeErr
+}
+
+// RegenerateSummary summarizes an article again, replacing its stored summary
+// even when it has one, in the style and with the model of override when
+// given. Unlike query results it never falls back to an extractive summary,
+// so editors see why the model failed.
+func (s *NewsService) RegenerateSummary(ctx context.Context, id string, override llm.SummaryOverride) (repo.ArticleSummary, error) {
+	if err := llm.ValidateSummaryStyle(override.Style); err != nil {
+		return repo.ArticleSummary{}, errs.Wrap(errs.ErrInvalid, err)
+	}
+	// Read past the cache, as the article may have just been corrected
+	article, err := s.repo.GetArticleByID(ctx, id)
+	if err != nil {
+		return repo.ArticleSummary{}, err
+	}
+
+	genCtx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+	description := ""
+	if article.Description != nil {
+		description = *article.Description
+	}
+	sentiment := s.judgeSentiment(genCtx, article.ID, article.Title, description)
+	text, err := s.llm.Summarize(llm.WithSummaryOverride(genCtx, override), article.Title, description, article.SourceName, article.PublicationDate.Format(time.RFC3339))
+	if err != nil {
+		return repo.ArticleSummary{}, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to summarize article %s: %w", id, err))
+	}
+
+	model := override.Model
+	if model == "" {
+		model = s.summaryModel()
+	}
+	summary, err := s.repo.CreateArticleSummary(genCtx, summaryParams(article.ID, text, model, <-sentiment))
+	if err != nil {
+		return repo.ArticleSummary{}, err
+	}
+	s.cacheSummary(genCtx, summary, article.PublicationDate)
+	s.events.Emit(bus.SummaryGenerated, bus.SummaryPayload{ArticleID: article.ID})
+	return summary, nil
+}
+
+// articleSummaryResult is an article's summary and where it came from
+type articleSummaryResult struct {
+	text string
+	// sentiment is the article's tone judged with the summary, nil when it wasn't
+	sentiment *llm.Sentiment
+	// generated is set when the summary was written for this call, or for a
+	// concurrent one it shared
+	generated bool
+	// storeErr is why a generated summary couldn't be stored
+	storeErr error
+	// fallbackErr is why the model couldn't summarize the article, when text
+	// was extracted from the article instead
+	fallbackErr error
+}
+
+// articleSummary returns an article's stored summary, generating and storing
+// one when there is none yet. Concurrent callers asking for the same article
+// share one generation.
+func (s *NewsService) articleSummary(ctx context.Context, article ArticleDTO) (articleSummaryResult, error) {
+	stored, err := s.cachedSummary(ctx, article.ID, article.PublicationDate)
+	if err == nil {
+		return articleSummaryResult{text: stored.LLMSummary, sentiment: storedSentiment(stored)}, nil
+	}
+	if !errors.Is(err, errs.ErrNotFound) {
+		log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to load article summary")
+	}
+
+	calls := s.summaries.DoChan(article.ID, func() (interface{}, error) {
+		// Other callers may be waiting on this generation, so it outlives a cancelled first caller
+		genCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), summaryTimeout)
+		defer cancel()
+
+		description := ""
+		if article.Description != nil {
+			description = *article.Description
+		}
+		sentiment := s.judgeSentiment(genCtx, article.ID, article.Title, description)
+		text, err := s.llm.Summarize(genCtx, article.Title, description, article.SourceName, article.PublicationDate.Format(time.RFC3339))
+		if err != nil {
+			// Serve the article's own text rather than nothing, but don't store
+			// it, so the model summarizes the article once it recovers
+			metrics.LLMFallbacks.WithLabelValues("summarize").Inc()
+			return articleSummaryResult{text: llm.ExtractiveSummary(article.Title, description), fallbackErr: err}, nil
+		}
+
+		result := articleSummaryResult{text: text, sentiment: <-sentiment, generated: true}
+		summary, err := s.repo.CreateArticleSummary(genCtx, summaryParams(article.ID, text, s.summaryModel(), result.sentiment))
+		if err != nil {
+			// Queries still serve the summary; the next one tries to store it again
+			log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to store article summary")
+			result.storeErr = err
+		} else {
+			s.cacheSummary(genCtx, summary, article.PublicationDate)
+		}
+		s.events.Emit(bus.SummaryGenerated, bus.SummaryPayload{ArticleID: article.ID})
+		return result, nil
+	})
+	// The generation carries on for other callers if this one gives up
+	select {
+	case call := <-calls:
+		if call.Err != nil {
+			return articleSummaryResult{}, call.Err
+		}
+		return call.Val.(articleSummaryResult), nil
+	case <-ctx.Done():
+		return articleSummaryResult{}, ctx.Err()
+	}
+}
+
+// judgeSentiment starts judging an article's sentiment, when enabled, while
+// its summary is generated. The channel yields nil when it's disabled or the
+// model couldn't judge it; the summary is stored either way.
+func (s *NewsService) judgeSentiment(ctx context.Context, articleID, title, description string) <-chan *llm.Sentiment {
+	result := make(chan *llm.Sentiment, 1)
+	if !s.sentiment {
+		result <- nil
+		return result
+	}
+	go func() {
+		sentiment, err := s.llm.AnalyzeSentiment(ctx, title, description)
+		if err != nil {
+			log.Warn().Err(err).Str("article_id", articleID).Msg("Failed to judge article sentiment")
+			sentiment = nil
+		}
+		result <- sentiment
+	}()
+	return result
+}
+
+// summaryParams stores a generated summary with the sentiment judged alongside it
+func summaryParams(articleID, text, model string, sentiment *llm.Sentiment) repo.CreateArticleSummaryParams {
+	params := repo.CreateArticleSummaryParams{
+		ArticleID:  articleID,
+		LLMSummary: text,
+		Model:      model,
+	}
+	if sentiment != nil {
+		params.Sentiment = &sentiment.Label
+		params.SentimentScore = &sentiment.Score
+	}
+	return params
+}
+
+// storedSentiment returns the sentiment stored with summary, nil when it has none
+func storedSentiment(summary repo.ArticleSummary) *llm.Sentiment {
+	if summary.Sentiment == nil {
+		return nil
+	}
+	sentiment := &llm.Sentiment{Label: *summary.Sentiment}
+	if summary.SentimentScore != nil {
+		sentiment.Score = *summary.SentimentScore
+	}
+	return sentiment
+}
+
+// summaryModel names the model stored summaries are attributed to, "" when
+// the client doesn't say
+func (s *NewsService) summaryModel() string {
+	if named, ok := s.llm.(interface{ Model() string }); ok {
+		return named.Model()
+	}
+	return ""
+}
+
+// invalidateArticle drops the cached copies of a changed or deleted article
+func (s *NewsService) invalidateArticle(ctx context.Context, id string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Del(ctx, cache.ArticleKey(id), cache.SummaryKey(id)); err != nil {
+		log.Warn().Err(err).Str("article_id", id).Msg("Failed to invalidate cached article")
+	}
+}
+
+// articlePayload describes an article on the event bus
+func articlePayload(article repo.Article) bus.ArticlePayload {
+	return bus.ArticlePayload{
+		ArticleID:  article.ID,
+		Title:      article.Title,
+		URL:        article.URL,
+		SourceName: article.SourceName,
+		Category:   article.Category,
+	}
+}
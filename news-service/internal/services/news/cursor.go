@@ -0,0 +1,107 @@
+package news
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/services/geocode"
+)
+
+// ErrInvalidCursor is returned when a next_cursor/prev_cursor value can't be decoded
+var ErrInvalidCursor = errs.New(errs.ErrInvalid, "invalid cursor")
+
+// queryPlan is a strategy together with its resolved parameters. Encoded as an
+// opaque cursor it lets follow-up pages skip intent extraction and page through
+// exactly the same result set as the first page.
+type queryPlan struct {
+	Strategy string   `json:"s"`
+	Intent   string   `json:"i,omitempty"`
+	Entities []string `json:"e,omitempty"`
+	// Strategy parameters: search or semantic text, category/source name, score threshold, location
+	Query    string   `json:"q,omitempty"`
+	Name     string   `json:"n,omitempty"`
+	MinScore float64  `json:"m,omitempty"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	Radius   float64  `json:"r,omitempty"`
+	// Place is the city or region name a nearby query without coordinates
+	// is matched against articles' reverse geocoded places
+	Place string `json:"pl,omitempty"`
+	// ExpandedArea is the area a nearby query was widened to; Lat, Lon and
+	// Radius then cover it
+	ExpandedArea *geocode.Area `json:"x,omitempty"`
+	// AreaLevel is the level of the area around the user a first page asked
+	// to cover, e.g. "state" for "across the state"; Radius approximates it
+	// until the area is looked up
+	AreaLevel string `json:"-"`
+	// Language restricts results to one ISO 639-1 code; "" matches every article
+	Language string `json:"l,omitempty"`
+	// Country restricts results to one ISO 3166-1 alpha-2 code; "" matches every article
+	Country string `json:"co,omitempty"`
+	// Sentiment restricts results to one sentiment label; "" matches every article
+	Sentiment string `json:"se,omitempty"`
+	// EntityFilter restricts results to articles mentioning every one of these
+	// normalized entity names; none matches every article
+	EntityFilter []string `json:"ef,omitempty"`
+	// TZ is the IANA zone dates are resolved and returned in; "" is UTC
+	TZ string `json:"tz,omitempty"`
+	// PublishedAfter and PublishedBefore bound the publication date to the
+	// window a relative date in the query resolved to, e.g. "today"
+	PublishedAfter  *time.Time `json:"pa,omitempty"`
+	PublishedBefore *time.Time `json:"pb,omitempty"`
+	// ArchiveCutoff is the publication date before which articles are read
+	// from the archive, pinned by the first page; nil reads one tier
+	ArchiveCutoff *time.Time `json:"ac,omitempty"`
+	// HotTotal is how many articles the hot window holds, once a page has run
+	// past them; archive offsets are counted from it
+	HotTotal *int `json:"ht,omitempty"`
+	// Offset of the first article of the page
+	Offset int `json:"o,omitempty"`
+}
+
+// encodeCursor returns the opaque cursor for the page of plan starting at offset
+func encodeCursor(plan queryPlan, offset int) string {
+	plan.Offset = offset
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) (queryPlan, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return queryPlan{}, ErrInvalidCursor
+	}
+
+	var plan queryPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return queryPlan{}, ErrInvalidCursor
+	}
+
+	switch plan.Strategy {
+	case "category", "source", "score", "search", "semantic":
+	case "place":
+		if plan.Place == "" {
+			return queryPlan{}, fmt.Errorf("%w: place cursor without a place", ErrInvalidCursor)
+		}
+	case "nearby":
+		if plan.Lat == nil || plan.Lon == nil {
+			return queryPlan{}, fmt.Errorf("%w: nearby cursor without coordinates", ErrInvalidCursor)
+		}
+	default:
+		return queryPlan{}, fmt.Errorf("%w: unknown strategy %q", ErrInvalidCursor, plan.Strategy)
+	}
+	if _, err := loadTimeZone(plan.TZ); err != nil {
+		return queryPlan{}, fmt.Errorf("%w: unknown time zone %q", ErrInvalidCursor, plan.TZ)
+	}
+	if plan.Offset < 0 || (plan.HotTotal != nil && *plan.HotTotal < 0) {
+		return queryPlan{}, fmt.Errorf("%w: negative offset", ErrInvalidCursor)
+	}
+	return plan, nil
+}
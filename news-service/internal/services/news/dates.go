@@ -0,0 +1,66 @@
+package news
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"news-system/internal/errs"
+)
+
+// loadTimeZone resolves a tz parameter, an IANA zone name such as
+// "Asia/Kolkata"; "" is UTC. "Local" is refused, since it would mean the
+// server's zone rather than the user's.
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid tz %q: expected an IANA time zone such as \"Asia/Kolkata\"", name)
+	}
+	return loc, nil
+}
+
+// datePhrase matches the relative dates a query can restrict publication to
+var datePhrase = regexp.MustCompile(`(?i)\b(today|yesterday|this week|this month)(?:'s)?\b`)
+
+// publishedWindow resolves the relative date a query names, such as "today's
+// news", to the publication window it covers in loc: local day, week (from
+// Monday) or month boundaries, so a day is the user's day rather than the
+// server's. It returns the query without the phrase, which would otherwise
+// have to appear in the text of every full-text match, and whether one was
+// found.
+func publishedWindow(query string, now time.Time, loc *time.Location) (after, before time.Time, rest string, ok bool) {
+	match := datePhrase.FindStringSubmatchIndex(query)
+	if match == nil {
+		return time.Time{}, time.Time{}, query, false
+	}
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	switch strings.ToLower(query[match[2]:match[3]]) {
+	case "today":
+		after, before = today, today.AddDate(0, 0, 1)
+	case "yesterday":
+		after, before = today.AddDate(0, 0, -1), today
+	case "this week":
+		after = today.AddDate(0, 0, -((int(local.Weekday())+6)%7))
+		before = after.AddDate(0, 0, 7)
+	case "this month":
+		after = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+		before = after.AddDate(0, 1, 0)
+	}
+
+	rest = strings.Join(strings.Fields(query[:match[0]]+" "+query[match[1]:]), " ")
+	return after, before, rest, true
+}
+
+// inLocation returns t in loc, or nil when t is nil
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
@@ -1,5 +1,7 @@
 package news
 
+import "news-system/internal/validation"
+
 // SearchRequest represents a search query request
 type SearchRequest struct {
 	Query string `json:"query" validate:"required,min=1,max=500"`
@@ -37,6 +39,22 @@ type TrendingRequest struct {
 	Lat   float64 `json:"lat" validate:"min=-90,max=90"`
 	Lon   float64 `json:"lon" validate:"min=-180,max=180"`
 	Limit int     `json:"limit" validate:"min=1,max=50"`
+	// Lang, Country, Sentiment, Entities and TZ filter and format as on QueryRequest
+	Lang      string   `json:"lang,omitempty"`
+	Country   string   `json:"country,omitempty"`
+	Sentiment string   `json:"sentiment,omitempty"`
+	Entities  []string `json:"entities,omitempty" validate:"omitempty,max=5"`
+	TZ        string   `json:"tz,omitempty"`
+	// Category restricts trending to the articles of one category
+	Category string `json:"category,omitempty"`
+	// Mode is "local" (the default), trending around Lat/Lon, or "global",
+	// trending everywhere, where Lat/Lon are ignored
+	Mode string `json:"mode,omitempty" validate:"omitempty,oneof=local global"`
+	// Window is the horizon trending is computed over: 1h, 6h or 24h
+	Window string `json:"window,omitempty" validate:"omitempty,oneof=1h 6h 24h"`
+	// SkipSummaries leaves out LLM summaries, for callers whose plan doesn't
+	// include them or who asked for none, before any is looked up
+	SkipSummaries bool `json:"-"`
 }
 
 // AutoRequest represents an automatic intent routing request
@@ -56,6 +74,8 @@ type ErrorResponse struct {
 type ErrorInfo struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	// Details lists the fields that failed validation, for VALIDATION_ERROR
+	Details []validation.FieldError `json:"details,omitempty"`
 }
 
 // Common error codes
@@ -66,6 +86,9 @@ const (
 	ErrCodeRateLimit      = "RATE_LIMIT"
 	ErrCodeBadRequest     = "BAD_REQUEST"
 	ErrCodeUnauthorized   = "UNAUTHORIZED"
+	ErrCodeForbidden      = "FORBIDDEN"
+	ErrCodeConflict       = "CONFLICT"
+	ErrCodeUnavailable    = "UNAVAILABLE"
 )
 
 // NewErrorResponse creates a new error response
@@ -0,0 +1,96 @@
+package news
+
+import (
+	"context"
+
+	"news-system/internal/metrics"
+	"news-system/internal/services/geocode"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultRadiusKm is the radius of a nearby query that names none
+const defaultRadiusKm = 10.0
+
+// maxStatedRadiusKm bounds the radius a query states, like that of a request
+const maxStatedRadiusKm = 200.0
+
+// areaRadiusKm approximates the radius of each area level a nearby query can
+// ask to cover, for when the area around the user can't be looked up
+var areaRadiusKm = map[string]float64{
+	geocode.LevelCity:   15,
+	geocode.LevelCounty: 50,
+	geocode.LevelState:  200,
+}
+
+// nearbyExpansion configures widening empty nearby queries, see EnableNearbyExpansion
+type nearbyExpansion struct {
+	geocoder    geocode.Geocoder
+	maxRadiusKm float64
+}
+
+// expandNearby retries a nearby query that found nothing over each area
+// containing its location in turn, smallest first, returning the plan and
+// articles of the first that has any. An area larger than the maximum radius
+// is searched at that radius around the query's location instead. The
+// query's own plan is returned when no area has articles or the lookup fails.
+func (s *NewsService) expandNearby(ctx context.Context, plan queryPlan, page repoPage) (queryPlan, []ArticleDTO) {
+	areas, err := s.expansion.geocoder.Areas(ctx, *plan.Lat, *plan.Lon)
+	if err != nil {
+		metrics.NearbyExpansions.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Msg("Failed to look up the areas around a nearby query")
+		return plan, nil
+	}
+
+	for _, area := range areas {
+		expanded := plan
+		lat, lon, radius := area.Lat, area.Lon, area.RadiusKm
+		if radius > s.expansion.maxRadiusKm {
+			lat, lon, radius = *plan.Lat, *plan.Lon, s.expansion.maxRadiusKm
+		}
+		if radius <= plan.Radius {
+			continue
+		}
+		expanded.Lat, expanded.Lon, expanded.Radius = &lat, &lon, radius
+		expanded.ExpandedArea = &area
+
+		articles, err := s.retrieve(ctx, expanded, page, true)
+		if err != nil {
+			metrics.NearbyExpansions.WithLabelValues("error").Inc()
+			log.Warn().Err(err).Str("area", area.Name).Msg("Failed to search the area around a nearby query")
+			return plan, nil
+		}
+		if len(articles) > 0 {
+			metrics.NearbyExpansions.WithLabelValues(area.Level).Inc()
+			return expanded, articles
+		}
+	}
+	metrics.NearbyExpansions.WithLabelValues("empty").Inc()
+	return plan, nil
+}
+
+// resolveArea points a nearby plan that asked to cover an area around the
+// user, e.g. "across the state", at the area of that level containing its
+// location. An area larger than the maximum radius is searched at that radius
+// around the location instead. The plan keeps its approximate radius when the
+// lookup fails or finds no area of the level.
+func (s *NewsService) resolveArea(ctx context.Context, plan queryPlan) queryPlan {
+	areas, err := s.expansion.geocoder.Areas(ctx, *plan.Lat, *plan.Lon)
+	if err != nil {
+		log.Warn().Err(err).Str("level", plan.AreaLevel).Msg("Failed to look up the area a nearby query asked for")
+		return plan
+	}
+	for _, area := range areas {
+		if area.Level != plan.AreaLevel {
+			continue
+		}
+		lat, lon, radius := area.Lat, area.Lon, area.RadiusKm
+		if radius > s.expansion.maxRadiusKm {
+			lat, lon, radius = *plan.Lat, *plan.Lon, s.expansion.maxRadiusKm
+		}
+		plan.Lat, plan.Lon, plan.Radius = &lat, &lon, radius
+		plan.ExpandedArea = &area
+		return plan
+	}
+	return plan
+}
@@ -0,0 +1,119 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxFeedbackCommentChars bounds a feedback comment
+	maxFeedbackCommentChars = 2000
+	// maxFeedbackQueryChars matches the longest query accepted
+	maxFeedbackQueryChars = 500
+)
+
+// FeedbackRequest is a reader's thumbs up or down on an article's summary, or
+// on its relevance as a result of query
+type FeedbackRequest struct {
+	ArticleID string `json:"article_id"`
+	// Target is "summary" or "result"
+	Target string `json:"target"`
+	// Rating is "up" or "down"
+	Rating  string `json:"rating"`
+	Query   string `json:"query,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// FeedbackReport aggregates the feedback given since Since, with the stored
+// summaries readers disliked most, worth regenerating first
+type FeedbackReport struct {
+	Since     time.Time              `json:"since"`
+	Stats     []repo.FeedbackStat    `json:"stats"`
+	Summaries []repo.SummaryFeedback `json:"summaries"`
+}
+
+// SubmitFeedback stores feedback on an article. Feedback on a summary records
+// the model of the stored summary, so regenerating it starts a fresh count.
+func (s *NewsService) SubmitFeedback(ctx context.Context, req FeedbackRequest) (repo.Feedback, error) {
+	if req.ArticleID == "" {
+		return repo.Feedback{}, errs.New(errs.ErrInvalid, "article_id is required")
+	}
+	if req.Target != repo.FeedbackTargetSummary && req.Target != repo.FeedbackTargetResult {
+		return repo.Feedback{}, errs.Errorf(errs.ErrInvalid, "invalid target %q: expected %q or %q", req.Target, repo.FeedbackTargetSummary, repo.FeedbackTargetResult)
+	}
+	if req.Rating != repo.FeedbackUp && req.Rating != repo.FeedbackDown {
+		return repo.Feedback{}, errs.Errorf(errs.ErrInvalid, "invalid rating %q: expected %q or %q", req.Rating, repo.FeedbackUp, repo.FeedbackDown)
+	}
+	query, comment := strings.TrimSpace(req.Query), strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(query) > maxFeedbackQueryChars {
+		return repo.Feedback{}, errs.Errorf(errs.ErrInvalid, "query exceeds %d characters", maxFeedbackQueryChars)
+	}
+	if utf8.RuneCountInString(comment) > maxFeedbackCommentChars {
+		return repo.Feedback{}, errs.Errorf(errs.ErrInvalid, "comment exceeds %d characters", maxFeedbackCommentChars)
+	}
+
+	arg := repo.CreateFeedbackParams{
+		ArticleID: req.ArticleID,
+		Target:    req.Target,
+		Rating:    req.Rating,
+		Query:     optionalString(query),
+		Comment:   optionalString(comment),
+	}
+	if req.Target == repo.FeedbackTargetSummary {
+		// Readers may have seen an extractive fallback, which has no stored summary
+		summary, err := s.repo.GetArticleSummary(ctx, req.ArticleID)
+		switch {
+		case err == nil:
+			arg.SummaryModel = &summary.Model
+		case !errors.Is(err, errs.ErrNotFound):
+			log.Warn().Err(err).Str("article_id", req.ArticleID).Msg("Failed to load article summary")
+		}
+	}
+
+	feedback, err := s.repo.CreateFeedback(ctx, arg)
+	if err != nil {
+		return repo.Feedback{}, err
+	}
+	metrics.Feedback.WithLabelValues(feedback.Target, feedback.Rating).Inc()
+	return feedback, nil
+}
+
+// FeedbackReport counts the feedback given since since and lists up to limit
+// stored summaries with at least minDown thumbs down since they were generated
+func (s *NewsService) FeedbackReport(ctx context.Context, since time.Time, minDown int64, limit int32) (*FeedbackReport, error) {
+	stats, err := s.repo.GetFeedbackStats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := s.repo.GetSummariesWithNegativeFeedback(ctx, repo.GetSummariesWithNegativeFeedbackParams{
+		MinDown: minDown,
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		stats = []repo.FeedbackStat{}
+	}
+	if summaries == nil {
+		summaries = []repo.SummaryFeedback{}
+	}
+	return &FeedbackReport{Since: since, Stats: stats, Summaries: summaries}, nil
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
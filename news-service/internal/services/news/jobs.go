@@ -0,0 +1,37 @@
+package news
+
+import (
+	"context"
+	"errors"
+
+	"news-system/internal/errs"
+	"news-system/internal/jobqueue"
+	"news-system/internal/services/llm"
+)
+
+// SummaryQueue is the job queue article summaries are regenerated from
+const SummaryQueue = "summaries"
+
+// SummaryJob asks for an article's summary to be regenerated, optionally
+// with another style or model
+type SummaryJob struct {
+	ArticleID string `json:"article_id"`
+	Style     string `json:"style,omitempty"`
+	Model     string `json:"model,omitempty"`
+}
+
+// RunSummaryJob regenerates the summary a SummaryQueue job asks for. A
+// missing article or invalid override fails the job for good; an LLM
+// failure is retried.
+func (s *NewsService) RunSummaryJob(ctx context.Context, job jobqueue.Job) error {
+	var req SummaryJob
+	if err := job.Decode(&req); err != nil {
+		return err
+	}
+	ctx = llm.WithEndpoint(ctx, "summary-job")
+	_, err := s.RegenerateSummary(ctx, req.ArticleID, llm.SummaryOverride{Style: req.Style, Model: req.Model})
+	if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrInvalid) {
+		return jobqueue.Permanent(err)
+	}
+	return err
+}
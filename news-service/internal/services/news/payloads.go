@@ -0,0 +1,27 @@
+package news
+
+import "news-system/internal/cache"
+
+// Schemas of the payloads the news service caches. Bump a Version, with a
+// migration from the previous one, when a change to the cached type can't
+// be decoded from its old JSON; added and removed fields need neither.
+var (
+	// articleSchema is a repo.Article under news:article:
+	articleSchema = cache.Schema{
+		Name:       "article",
+		Version:    1,
+		Migrations: map[int]cache.Migration{0: cache.SameShape},
+	}
+	// summarySchema is a repo.ArticleSummary under news:summary:
+	summarySchema = cache.Schema{
+		Name:       "summary",
+		Version:    1,
+		Migrations: map[int]cache.Migration{0: cache.SameShape},
+	}
+	// searchSchema is the []ArticleDTO of a hot search under cache:v1:search:
+	searchSchema = cache.Schema{
+		Name:       "search",
+		Version:    1,
+		Migrations: map[int]cache.Migration{0: cache.SameShape},
+	}
+)
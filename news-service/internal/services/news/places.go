@@ -0,0 +1,52 @@
+package news
+
+import (
+	"context"
+
+	"news-system/internal/metrics"
+	"news-system/internal/services/geocode"
+
+	"github.com/rs/zerolog/log"
+)
+
+// placeResolution configures looking up the places a place name may refer
+// to, see EnablePlaceResolution
+type placeResolution struct {
+	geocoder       geocode.Geocoder
+	ambiguityRatio float64
+}
+
+// resolvePlace looks up the places a place plan's name may refer to. When one
+// of them is clearly the most important, the plan is narrowed to its country,
+// unless the request named one. When several have an importance of at least
+// the ambiguity ratio of the most important, none is picked: they are
+// returned as candidates and the plan matches the name in every country.
+func (s *NewsService) resolvePlace(ctx context.Context, plan queryPlan) (queryPlan, []geocode.Location) {
+	locations, err := s.places.geocoder.Search(ctx, plan.Place, plan.Country)
+	if err != nil {
+		metrics.PlaceResolutions.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Str("place", plan.Place).Msg("Failed to look up the places a query names")
+		return plan, nil
+	}
+	if len(locations) == 0 {
+		metrics.PlaceResolutions.WithLabelValues("unknown").Inc()
+		return plan, nil
+	}
+
+	var candidates []geocode.Location
+	for _, location := range locations {
+		if location.Importance >= locations[0].Importance*s.places.ambiguityRatio {
+			candidates = append(candidates, location)
+		}
+	}
+	if len(candidates) > 1 {
+		metrics.PlaceResolutions.WithLabelValues("ambiguous").Inc()
+		return plan, candidates
+	}
+
+	metrics.PlaceResolutions.WithLabelValues("resolved").Inc()
+	if plan.Country == "" {
+		plan.Country = locations[0].Country
+	}
+	return plan, nil
+}
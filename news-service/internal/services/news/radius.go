@@ -0,0 +1,45 @@
+package news
+
+import (
+	"context"
+
+	"news-system/internal/metrics"
+
+	"github.com/rs/zerolog/log"
+)
+
+// radiusExpansion configures widening the radius of thin nearby queries that
+// ask for min_results, see EnableRadiusExpansion
+type radiusExpansion struct {
+	factor      float64
+	maxRadiusKm float64
+}
+
+// widenRadius retries a nearby query that found fewer than minResults
+// articles, at most limit, at its radius multiplied by the step factor, again
+// and again up to the maximum radius, until one finds enough. It returns the
+// plan and articles of the first radius that does, or of the widest searched.
+// The plan searched last before a failed lookup is returned with its articles.
+func (s *NewsService) widenRadius(ctx context.Context, plan queryPlan, page repoPage, articles []ArticleDTO, minResults, limit int) (queryPlan, []ArticleDTO) {
+	target := min(minResults, limit)
+	if len(articles) >= target || plan.Radius >= s.radius.maxRadiusKm {
+		return plan, articles
+	}
+	for plan.Radius < s.radius.maxRadiusKm {
+		widened := plan
+		widened.Radius = min(plan.Radius*s.radius.factor, s.radius.maxRadiusKm)
+		found, err := s.retrieve(ctx, widened, page, true)
+		if err != nil {
+			metrics.RadiusExpansions.WithLabelValues("error").Inc()
+			log.Warn().Err(err).Float64("radius_km", widened.Radius).Msg("Failed to search a widened nearby radius")
+			return plan, articles
+		}
+		plan, articles = widened, found
+		if len(articles) >= target {
+			metrics.RadiusExpansions.WithLabelValues("satisfied").Inc()
+			return plan, articles
+		}
+	}
+	metrics.RadiusExpansions.WithLabelValues("short").Inc()
+	return plan, articles
+}
@@ -0,0 +1,89 @@
+package news
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RankingWeights weigh the signals blended into the score results are ranked
+// by. Each signal lies in [0, 1] before weighting, and one an article lacks,
+// like a full-text score outside the search strategy, counts as 0.
+type RankingWeights struct {
+	// Text weighs the full-text score, relative to the best one on the page
+	Text float64
+	// Semantic weighs the cosine similarity of the article to the query
+	Semantic float64
+	// Relevance weighs the article's relevance_score
+	Relevance float64
+	// Recency weighs how recently the article was published, halving every
+	// RecencyHalfLife
+	Recency         float64
+	RecencyHalfLife time.Duration
+}
+
+// DefaultRankingWeights favour matching the query, then quality, then freshness
+var DefaultRankingWeights = RankingWeights{
+	Text:            0.5,
+	Semantic:        0.5,
+	Relevance:       0.3,
+	Recency:         0.2,
+	RecencyHalfLife: 48 * time.Hour,
+}
+
+// SetRankingWeights replaces the weights results are ranked with
+func (s *NewsService) SetRankingWeights(weights RankingWeights) {
+	s.ranking = weights
+}
+
+// hybridRank orders articles by their blended score, highest first, breaking
+// ties by publication date and then ID so equal scores rank the same way
+// every time
+func (w RankingWeights) hybridRank(articles []ArticleDTO, now time.Time) {
+	maxText := 0.0
+	for _, article := range articles {
+		if article.SearchScore != nil && *article.SearchScore > maxText {
+			maxText = *article.SearchScore
+		}
+	}
+
+	scores := make(map[string]float64, len(articles))
+	for _, article := range articles {
+		scores[article.ID] = w.score(article, maxText, now)
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		a, b := articles[i], articles[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		if !a.PublicationDate.Equal(b.PublicationDate) {
+			return a.PublicationDate.After(b.PublicationDate)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// score blends an article's signals; maxText is the best full-text score
+// among the articles ranked with it
+func (w RankingWeights) score(article ArticleDTO, maxText float64, now time.Time) float64 {
+	score := w.Relevance * clamp01(article.RelevanceScore)
+	if article.SearchScore != nil && maxText > 0 {
+		score += w.Text * clamp01(*article.SearchScore/maxText)
+	}
+	if article.Similarity != nil {
+		score += w.Semantic * clamp01(*article.Similarity)
+	}
+	if w.RecencyHalfLife > 0 {
+		age := now.Sub(article.PublicationDate)
+		if age < 0 {
+			age = 0
+		}
+		score += w.Recency * math.Exp2(-float64(age)/float64(w.RecencyHalfLife))
+	}
+	return score
+}
+
+// clamp01 limits v to [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
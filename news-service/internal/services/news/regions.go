@@ -0,0 +1,71 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"news-system/internal/errs"
+	"news-system/internal/repo"
+	"news-system/internal/services/geocode"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RegionResponse is the local news of a country, or of one of its regions,
+// for clients without a precise location
+type RegionResponse struct {
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+	// Top are the most relevant articles placed there, newest first among equals
+	Top []ArticleDTO `json:"top"`
+	// Trending are the articles placed there that readers engage with most
+	Trending []ArticleDTO `json:"trending"`
+}
+
+// GetRegion returns the top articles placed in the country, or in the region
+// of it when region isn't "", together with the articles of trendingIDs, the
+// place's trending articles in order. Trending articles that no longer exist
+// are left out, and demoted articles rank below the rest of each list.
+func (s *NewsService) GetRegion(ctx context.Context, country, region string, limit int, trendingIDs []string) (*RegionResponse, error) {
+	code, ok := geocode.NormalizeCountry(country)
+	if !ok || code == "" {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid country %q: expected an ISO 3166-1 alpha-2 code such as \"IN\"", country)
+	}
+
+	top, err := s.repo.GetArticlesByPlace(ctx, repo.GetArticlesByPlaceParams{
+		Country: code,
+		Region:  region,
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RegionResponse{
+		Country:  code,
+		Region:   region,
+		Top:      s.convertToDTOs(top),
+		Trending: make([]ArticleDTO, 0, len(trendingIDs)),
+	}
+	for _, id := range trendingIDs {
+		article, err := s.cachedArticle(ctx, id)
+		if err != nil {
+			if !errors.Is(err, errs.ErrNotFound) {
+				log.Warn().Err(err).Str("article_id", id).Msg("Failed to load trending article")
+			}
+			continue
+		}
+		response.Trending = append(response.Trending, s.convertToDTO(article))
+	}
+
+	if s.moderation != nil {
+		for _, articles := range [][]ArticleDTO{response.Top, response.Trending} {
+			s.markDemoted(ctx, articles)
+			sort.SliceStable(articles, func(i, j int) bool {
+				return !articles[i].Demoted && articles[j].Demoted
+			})
+		}
+	}
+	return response, nil
+}
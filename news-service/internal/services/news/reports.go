@@ -0,0 +1,92 @@
+package news
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"news-system/internal/errs"
+	"news-system/internal/services/moderation"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxReportCommentChars bounds a report's comment
+const maxReportCommentChars = 1000
+
+// Moderator keeps readers' reports of articles and knows which articles
+// moderation demoted
+type Moderator interface {
+	Report(ctx context.Context, articleID, client string, report moderation.Report) (moderation.Outcome, error)
+	Dismiss(ctx context.Context, articleID string) error
+	Demoted(ctx context.Context, articleIDs []string) (map[string]bool, error)
+}
+
+// ReportRequest is a reader's report of a bad article
+type ReportRequest struct {
+	// Reason is spam, offensive, wrong_category or broken_link
+	Reason  string `json:"reason"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// EnableModeration takes readers' reports of articles into moderator and
+// ranks the articles it demoted below the rest of their page
+func (s *NewsService) EnableModeration(moderator Moderator) {
+	s.moderation = moderator
+}
+
+// ReportArticle records client's report of an article. A client reporting
+// the same article again replaces its earlier report.
+func (s *NewsService) ReportArticle(ctx context.Context, id, client string, req ReportRequest) (moderation.Outcome, error) {
+	if s.moderation == nil {
+		return moderation.Outcome{}, errs.New(errs.ErrUnavailable, "article reports are disabled")
+	}
+	if err := moderation.ValidateReason(req.Reason); err != nil {
+		return moderation.Outcome{}, err
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > maxReportCommentChars {
+		return moderation.Outcome{}, errs.Errorf(errs.ErrInvalid, "comment exceeds %d characters", maxReportCommentChars)
+	}
+	if _, err := s.cachedArticle(ctx, id); err != nil {
+		return moderation.Outcome{}, err
+	}
+
+	outcome, err := s.moderation.Report(ctx, id, client, moderation.Report{
+		Reason:     req.Reason,
+		Comment:    comment,
+		ReportedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return moderation.Outcome{}, err
+	}
+	if outcome.Flagged {
+		log.Info().
+			Str("article_id", id).
+			Int64("reports", outcome.Reports).
+			Bool("demoted", outcome.Demoted).
+			Msg("Article flagged for moderation")
+	}
+	return outcome, nil
+}
+
+// markDemoted marks the demoted articles for rankArticles. Results are served
+// unmarked when the moderator can't be reached.
+func (s *NewsService) markDemoted(ctx context.Context, articles []ArticleDTO) {
+	if len(articles) == 0 {
+		return
+	}
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	demoted, err := s.moderation.Demoted(ctx, ids)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read demoted articles")
+		return
+	}
+	for i := range articles {
+		articles[i].Demoted = demoted[articles[i].ID]
+	}
+}
@@ -0,0 +1,87 @@
+package news
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// embedQueryTimeout bounds embedding a query, which the semantic strategy
+// waits on like extraction
+const embedQueryTimeout = 5 * time.Second
+
+// semanticSearch configures the semantic strategy, see EnableSemanticSearch
+type semanticSearch struct {
+	model         string
+	minResults    int
+	minSimilarity float64
+}
+
+// semanticFallback runs the semantic strategy for a keyword search that found
+// too few articles, returning its plan and articles when it found more, and
+// the keyword search's otherwise
+func (s *NewsService) semanticFallback(ctx context.Context, plan queryPlan, page repoPage, keyword []ArticleDTO) (queryPlan, []ArticleDTO) {
+	semanticPlan := plan
+	semanticPlan.Strategy = "semantic"
+	found, err := s.retrieve(ctx, semanticPlan, page, true)
+	switch {
+	case err != nil:
+		metrics.SemanticSearches.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Str("query", plan.Query).Msg("Semantic search failed, keeping keyword results")
+		return plan, keyword
+	case len(found) <= len(keyword):
+		metrics.SemanticSearches.WithLabelValues("kept").Inc()
+		return plan, keyword
+	}
+	metrics.SemanticSearches.WithLabelValues("used").Inc()
+	return semanticPlan, found
+}
+
+// getSemanticArticles embeds the query and retrieves the articles most similar to it.
+// Follow-up pages embed the query again, as cursors are too small to carry it.
+func (s *NewsService) getSemanticArticles(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
+	if s.semantic == nil {
+		return nil, errs.New(errs.ErrInvalid, "semantic search is not enabled")
+	}
+
+	embedCtx, cancel := context.WithTimeout(ctx, embedQueryTimeout)
+	embeddings, err := s.llm.Embed(embedCtx, []string{plan.Query})
+	cancel()
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrUnavailable, fmt.Errorf("failed to embed query: %w", err))
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("embedding the query returned %d embeddings", len(embeddings))
+	}
+
+	articles, err := s.repo.SemanticSearchArticles(ctx, repo.SemanticSearchArticlesParams{
+		Embedding:       embeddings[0],
+		Model:           s.semantic.model,
+		MinSimilarity:   s.semantic.minSimilarity,
+		Language:        plan.Language,
+		Country:         plan.Country,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]ArticleDTO, len(articles))
+	for i, article := range articles {
+		dto := s.convertToDTO(article.Article)
+		dto.Similarity = &article.Similarity
+		dtos[i] = dto
+	}
+	return dtos, nil
+}
@@ -4,14 +4,28 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"news-system/internal/bus"
 	"news-system/internal/cache"
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+	"news-system/internal/plans"
 	"news-system/internal/repo"
+	"news-system/internal/services/categorize"
+	"news-system/internal/services/geocode"
+	"news-system/internal/services/language"
 	"news-system/internal/services/llm"
+	"news-system/internal/services/queryaudit"
+	"news-system/internal/services/readers"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
 )
 
 // NewsService handles news retrieval and processing
@@ -19,30 +33,189 @@ type NewsService struct {
 	repo repo.Repository
 	cache *cache.RedisCache
 	llm   llm.LLMClient
+	events *bus.Bus
+	readers *readers.Counter
+	hot     HotQueryDetector
+	audit   QueryAuditor
+	shadow  *shadowRunner
+	semantic *semanticSearch
+	expansion *nearbyExpansion
+	radius    *radiusExpansion
+	places    *placeResolution
+	unlocated *unlocatedMix
+	archive   *archiveSearch
+	ranking  RankingWeights
+	moderation Moderator
+	// sentiment judges each article's tone alongside its summary
+	sentiment bool
+	classifier *categorize.Classifier
+	tagger     *geocode.Tagger
+	// summaries collapses concurrent generations of the same article's summary
+	summaries singleflight.Group
+}
+
+// HotQueryDetector reports whether a query is searched often enough that its
+// results are worth caching
+type HotQueryDetector interface {
+	IsHot(query string) bool
+}
+
+// QueryAuditor records how queries were planned and answered
+type QueryAuditor interface {
+	Record(entry queryaudit.Entry)
 }
 
 // NewNewsService creates a new NewsService
-func NewNewsService(repo repo.Repository, cache *cache.RedisCache, llm llm.LLMClient) *NewsService {
+func NewNewsService(repo repo.Repository, cache *cache.RedisCache, llm llm.LLMClient, events *bus.Bus, readers *readers.Counter) *NewsService {
 	return &NewsService{
-		repo:  repo,
-		cache: cache,
-		llm:   llm,
+		repo:    repo,
+		cache:   cache,
+		llm:     llm,
+		events:  events,
+		readers: readers,
+		ranking: DefaultRankingWeights,
+	}
+}
+
+// EnableHotQueryCache caches the first page of search results for queries
+// hot reports as hot, for SearchTTL. Other queries always go to the database,
+// so the cache only holds results that are likely to be asked for again.
+func (s *NewsService) EnableHotQueryCache(hot HotQueryDetector) {
+	s.hot = hot
+}
+
+// EnableQueryAudit records every query, with its extraction, strategy and
+// returned article IDs, to audit
+func (s *NewsService) EnableQueryAudit(audit QueryAuditor) {
+	s.audit = audit
+}
+
+// EnableShadowStrategy runs the named shadow strategy alongside the
+// production strategy for sampleRate (0 to 1) of first pages, with at most
+// maxInFlight shadow runs at once, and hands each comparison to recorder.
+// Shadow results are never returned to callers.
+func (s *NewsService) EnableShadowStrategy(name string, sampleRate float64, maxInFlight int, recorder ShadowRecorder) error {
+	runner, err := newShadowRunner(name, sampleRate, maxInFlight, recorder)
+	if err != nil {
+		return err
 	}
+	s.shadow = runner
+	return nil
+}
+
+// EnableSemanticSearch answers first pages whose keyword search found fewer
+// than minResults articles with the semantic strategy instead, when it finds
+// more: articles ranked by the cosine similarity of their embedding from
+// model to the query's, leaving out those below minSimilarity
+func (s *NewsService) EnableSemanticSearch(model string, minResults int, minSimilarity float64) {
+	s.semantic = &semanticSearch{model: model, minResults: minResults, minSimilarity: minSimilarity}
+}
+
+// EnableNearbyExpansion widens first pages of nearby queries that found
+// nothing to the areas geocoder reports around their location, such as the
+// metro area or region of a small town, searching at most maxRadiusKm
+func (s *NewsService) EnableNearbyExpansion(geocoder geocode.Geocoder, maxRadiusKm float64) {
+	s.expansion = &nearbyExpansion{geocoder: geocoder, maxRadiusKm: maxRadiusKm}
+}
+
+// EnableRadiusExpansion lets first pages of nearby queries ask for
+// min_results: when their radius finds fewer articles, it is multiplied by
+// factor and searched again, up to maxRadiusKm, until one finds enough
+func (s *NewsService) EnableRadiusExpansion(factor, maxRadiusKm float64) {
+	s.radius = &radiusExpansion{factor: factor, maxRadiusKm: maxRadiusKm}
+}
+
+// EnablePlaceResolution looks up the places the name of a place query may
+// refer to with geocoder. A name whose places include several of an
+// importance of at least ambiguityRatio of the most important one, like
+// "Springfield", returns them as candidates instead of picking one; any
+// other is narrowed to the country of its most important place.
+func (s *NewsService) EnablePlaceResolution(geocoder geocode.Geocoder, ambiguityRatio float64) {
+	s.places = &placeResolution{geocoder: geocoder, ambiguityRatio: ambiguityRatio}
+}
+
+// EnableUnlocatedMix blends the most relevant articles without coordinates,
+// which nearby queries and local trending never find, into their first pages
+// when these hold fewer than minResults articles, up to minResults. Only
+// articles with a relevance score of at least minRelevance are blended in,
+// by policy: UnlocatedMixFill or UnlocatedMixInterleave.
+func (s *NewsService) EnableUnlocatedMix(policy string, minResults int, minRelevance float64) {
+	s.unlocated = &unlocatedMix{policy: policy, minResults: minResults, minRelevance: minRelevance}
+}
+
+// EnableArchiveSearch answers queries from the articles published within
+// hotWindow first. Older articles, the archive, are read only once a page runs
+// past the last article of the hot window, and come back marked archived.
+// On Postgres they are the older monthly partitions of articles.
+func (s *NewsService) EnableArchiveSearch(hotWindow time.Duration) {
+	s.archive = &archiveSearch{hotWindow: hotWindow}
+}
+
+// EnableSentiment judges the sentiment of each article alongside its
+// summary, storing it with the summary for queries to filter on
+func (s *NewsService) EnableSentiment() {
+	s.sentiment = true
+}
+
+// EnableCategorization classifies articles created without categories with
+// classifier before storing them
+func (s *NewsService) EnableCategorization(classifier *categorize.Classifier) {
+	s.classifier = classifier
+}
+
+// EnableGeocoding fills in the place of articles created or updated with
+// coordinates from tagger before storing them
+func (s *NewsService) EnableGeocoding(tagger *geocode.Tagger) {
+	s.tagger = tagger
 }
 
 // QueryRequest represents a unified news query request
 type QueryRequest struct {
-	Query    string   `json:"query" validate:"required,min=1,max=500"`
+	Query    string   `json:"query" validate:"required_without=Cursor,max=500"`
 	Lat      *float64 `json:"lat,omitempty" validate:"omitempty,min=-90,max=90"`
 	Lon      *float64 `json:"lon,omitempty" validate:"omitempty,min=-180,max=180"`
 	Radius   *float64 `json:"radius_km,omitempty" validate:"omitempty,min=0.1,max=200"`
 	Limit    int      `json:"limit" validate:"min=1,max=50"`
+	// Cursor continues a previous query from its next_cursor or prev_cursor
+	Cursor   string   `json:"cursor,omitempty"`
+	// Lang restricts results to articles in one language (ISO 639-1, e.g. "en" or "de")
+	Lang     string   `json:"lang,omitempty"`
+	// Country restricts results to articles placed in one country (ISO 3166-1 alpha-2, e.g. "IN")
+	Country  string   `json:"country,omitempty"`
+	// Sentiment restricts results to articles whose summary was judged
+	// positive, negative or neutral
+	Sentiment string `json:"sentiment,omitempty"`
+	// Entities restricts results to articles that mention every one of the
+	// named people, organizations or locations, matched case-insensitively
+	Entities []string `json:"entities,omitempty" validate:"omitempty,max=5"`
+	// TZ is the IANA time zone (e.g. "Asia/Kolkata") relative dates such as
+	// "today" are resolved in and dates are returned in; "" is UTC
+	TZ string `json:"tz,omitempty"`
+	// MinResults widens the radius of a nearby query that finds fewer
+	// articles, up to limit, step by step; 0 keeps the radius
+	MinResults int `json:"min_results,omitempty" validate:"omitempty,min=1,max=50"`
+	// IncludeSummary asks for LLM summaries (true) or for none (false);
+	// nil leaves it to the caller's plan
+	IncludeSummary *bool `json:"include_summary,omitempty"`
+	// SkipSummaries leaves out LLM summaries, for callers whose plan doesn't
+	// include them or who asked for none, before any is looked up
+	SkipSummaries bool `json:"-"`
 }
 
 // QueryResponse represents the unified response format
 type QueryResponse struct {
-	Articles []ArticleDTO `json:"articles"`
-	Meta     MetaInfo     `json:"meta"`
+	Articles       []ArticleDTO       `json:"articles"`
+	TrendingTopics []TrendingTopicDTO `json:"trending_topics,omitempty"`
+	Meta           MetaInfo           `json:"meta"`
+	// Opaque cursors for the adjacent pages, omitted at either end
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// TrendingTopicDTO represents a category trending around the requested location
+type TrendingTopicDTO struct {
+	Category string  `json:"category"`
+	Score    float64 `json:"score"`
 }
 
 // MetaInfo represents metadata about the response
@@ -52,6 +225,24 @@ type MetaInfo struct {
 	Intent      string      `json:"intent"`
 	Entities    []string    `json:"entities"`
 	Strategy    string      `json:"strategy"`
+	// Trending tile the response was served from, if any
+	Geohash          string `json:"geohash,omitempty"`
+	GeohashPrecision int    `json:"geohash_precision,omitempty"`
+	// Approximate distinct readers in that tile over the trending window
+	UniqueReaders *int64 `json:"unique_readers,omitempty"`
+	// Area a nearby query covered, because it asked to or because its own
+	// radius had no articles
+	ExpandedArea *geocode.Area `json:"expanded_area,omitempty"`
+	// Radius a nearby query searched, wider than requested when it was
+	// widened to find min_results articles
+	EffectiveRadiusKm float64 `json:"effective_radius_km,omitempty"`
+	// Places the place a query names may refer to, when several are about
+	// as likely; the query matched the name in all of them
+	LocationCandidates []geocode.Location `json:"location_candidates,omitempty"`
+	// Publication window a relative date in the query, e.g. "today",
+	// resolved to in the request's time zone
+	PublishedAfter  *time.Time `json:"published_after,omitempty"`
+	PublishedBefore *time.Time `json:"published_before,omitempty"`
 }
 
 // QueryInfo represents information about the query
@@ -71,88 +262,396 @@ type ArticleDTO struct {
 	Category        []string   `json:"category"`
 	RelevanceScore  float64    `json:"relevance_score"`
 	LLMSummary      *string    `json:"llm_summary,omitempty"`
+	// Sentiment and SentimentScore come with the summary, when it was judged
+	Sentiment       *string    `json:"sentiment,omitempty"`
+	SentimentScore  *float64   `json:"sentiment_score,omitempty"`
 	Latitude        *float64   `json:"latitude,omitempty"`
 	Longitude       *float64   `json:"longitude,omitempty"`
+	Language        string     `json:"language,omitempty"`
+	// City, Region and Country name the place at the article's coordinates
+	City            string     `json:"city,omitempty"`
+	Region          string     `json:"region,omitempty"`
+	Country         string     `json:"country,omitempty"`
 	DistanceMeters  *float64   `json:"distance_meters,omitempty"`
 	SearchScore     *float64   `json:"search_score,omitempty"`
+	// Similarity is the cosine similarity to the query, for the semantic strategy
+	Similarity      *float64   `json:"similarity,omitempty"`
+	// TrendingScore is the article's trending score around the location, on /trending
+	TrendingScore   *float64   `json:"trending_score,omitempty"`
+	// Unlocated articles have no coordinates, and were blended into thin
+	// nearby or trending results for their relevance
+	Unlocated       bool       `json:"unlocated,omitempty"`
+	// Archived articles were published before the hot window and read from
+	// the archive, which may answer more slowly
+	Archived        bool       `json:"archived,omitempty"`
+	// Demoted articles rank below the rest of their page
+	Demoted         bool       `json:"-"`
 }
 
 // Query processes a unified news query using LLM to determine intent and route to appropriate strategy
-func (s *NewsService) Query(ctx context.Context, req QueryRequest) (*QueryResponse, error) {
+func (s *NewsService) Query(ctx context.Context, req QueryRequest) (resp *QueryResponse, err error) {
 	// Set default limit if not provided
 	if req.Limit <= 0 {
 		req.Limit = 5
 	}
 
-	// Use LLM to extract entities, concepts, and determine intent
-	extraction, err := s.llm.Extract(ctx, req.Query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract query intent: %w", err)
+	// Follow-up pages replay the plan pinned in the cursor; first pages are planned from the query
+	var plan queryPlan
+	var extraction *llm.Extraction
+	start := time.Now()
+	if s.audit != nil {
+		defer func() {
+			s.auditQuery(ctx, req, extraction, plan, resp, err, time.Since(start))
+		}()
+	}
+	if req.Cursor != "" {
+		var err error
+		plan, err = decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Use LLM to extract entities, concepts, and determine intent
+		var err error
+		extraction, err = s.llm.Extract(ctx, req.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract query intent: %w", err)
+		}
+
+		plan, err = s.planQuery(extraction, req)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Determine the appropriate data retrieval strategy
-	strategy := s.determineStrategy(extraction, req)
+	// Pin the edge of the hot window, so every page splits the results at the same date
+	if s.archive != nil && req.Cursor == "" {
+		plan.ArchiveCutoff = s.archive.cutoff(time.Now())
+	}
 
-	// Retrieve articles based on the determined strategy
-	var articles []ArticleDTO
-	var err2 error
+	// "Springfield" names many places; a clear favourite narrows the search to its country
+	var candidates []geocode.Location
+	if s.places != nil && req.Cursor == "" && plan.Strategy == "place" {
+		plan, candidates = s.resolvePlace(ctx, plan)
+	}
 
-	switch strategy {
-	case "category":
-		articles, err2 = s.getArticlesByCategory(ctx, extraction, req)
-	case "source":
-		articles, err2 = s.getArticlesBySource(ctx, extraction, req)
-	case "score":
-		articles, err2 = s.getArticlesByScore(ctx, extraction, req)
-	case "search":
-		articles, err2 = s.searchArticles(ctx, extraction, req)
-	case "nearby":
-		articles, err2 = s.getNearbyArticles(ctx, extraction, req)
-	default:
-		// Default to search if intent is unclear
-		articles, err2 = s.searchArticles(ctx, extraction, req)
-		strategy = "search"
+	// "across the state" covers the user's own state rather than a rough radius
+	if s.expansion != nil && plan.AreaLevel != "" {
+		plan = s.resolveArea(ctx, plan)
 	}
 
+	// Retrieve one article more than requested to learn whether a next page exists
+	page := repoPage{Limit: int32(req.Limit + 1), Offset: int32(plan.Offset)}
+	articles, err2 := s.retrieve(ctx, plan, page, true)
 	if err2 != nil {
 		return nil, fmt.Errorf("failed to retrieve articles: %w", err2)
 	}
 
-	// Enrich articles with LLM summaries
-	articles = s.enrichArticles(ctx, articles)
+	// Keyword search misses articles that say the same thing in other words
+	if s.semantic != nil && req.Cursor == "" && plan.Strategy == "search" && len(articles) < s.semantic.minResults {
+		plan, articles = s.semanticFallback(ctx, plan, page, articles)
+	}
 
-	// Rank articles based on strategy
-	articles = s.rankArticles(articles, strategy, req)
+	// A sparse area may hold enough articles a little further out
+	if s.radius != nil && req.Cursor == "" && plan.Strategy == "nearby" && req.MinResults > 0 {
+		plan, articles = s.widenRadius(ctx, plan, page, articles, req.MinResults, req.Limit)
+	}
+
+	// A small town may have no articles of its own, but its region does
+	if s.expansion != nil && req.Cursor == "" && plan.Strategy == "nearby" && len(articles) == 0 {
+		plan, articles = s.expandNearby(ctx, plan, page)
+	}
+
+	// Later pages read the archive from where this one stopped
+	plan = noteHotTotal(plan, page, articles)
+
+	// Articles without coordinates are invisible to nearby queries, so
+	// thin results make room for the most relevant of them
+	if s.unlocated != nil && req.Cursor == "" && plan.Strategy == "nearby" {
+		articles = s.mixUnlocated(ctx, articles, repo.GetArticlesByScoreParams{
+			Language:        plan.Language,
+			Country:         plan.Country,
+			PublishedAfter:  plan.PublishedAfter,
+			PublishedBefore: plan.PublishedBefore,
+			Sentiment:       plan.Sentiment,
+			Entities:        plan.EntityFilter,
+		}, req.Limit)
+	}
 
 	// Limit results
-	if len(articles) > req.Limit {
+	hasNext := len(articles) > req.Limit
+	if hasNext {
 		articles = articles[:req.Limit]
 	}
 
+	if s.moderation != nil {
+		s.markDemoted(ctx, articles)
+	}
+
+	// Compare a sample of first pages with the shadow strategy, before summaries add to the time
+	if s.shadow != nil && req.Cursor == "" {
+		s.shadow.run(ctx, s, req, plan, s.rankArticles(append([]ArticleDTO(nil), articles...), plan.Strategy, req), time.Since(start))
+	}
+
+	// Enrich articles with LLM summaries
+	if !req.SkipSummaries {
+		articles = s.enrichArticles(ctx, articles)
+	}
+
+	// Rank articles based on strategy
+	articles = s.rankArticles(articles, plan.Strategy, req)
+
+	// Dates are returned in the request's time zone, like the window they were filtered by
+	loc, err := loadTimeZone(plan.TZ)
+	if err != nil {
+		return nil, err
+	}
+	for i := range articles {
+		articles[i].Archived = archived(plan, articles[i])
+		articles[i].PublicationDate = articles[i].PublicationDate.In(loc)
+	}
+
 	// Build response
 	response := &QueryResponse{
 		Articles: articles,
 		Meta: MetaInfo{
-			Total:    len(articles),
-			Intent:   s.getBestIntent(extraction),
-			Entities: s.getAllEntities(extraction),
-			Strategy: strategy,
+			Total:              len(articles),
+			Intent:             plan.Intent,
+			Entities:           plan.Entities,
+			Strategy:           plan.Strategy,
+			ExpandedArea:       plan.ExpandedArea,
+			EffectiveRadiusKm:  nearbyRadius(plan),
+			LocationCandidates: candidates,
+			PublishedAfter:     inLocation(plan.PublishedAfter, loc),
+			PublishedBefore:    inLocation(plan.PublishedBefore, loc),
 			Query: &QueryInfo{
 				Endpoint: "query",
 				Params: map[string]interface{}{
-					"query":  req.Query,
-					"lat":    req.Lat,
-					"lon":    req.Lon,
-					"radius": req.Radius,
-					"limit":  req.Limit,
+					"query":       req.Query,
+					"lat":         req.Lat,
+					"lon":         req.Lon,
+					"radius":      req.Radius,
+					"limit":       req.Limit,
+					"min_results": req.MinResults,
+					"cursor":      req.Cursor,
+					"lang":        req.Lang,
+					"country":     req.Country,
+					"sentiment":   req.Sentiment,
+					"entities":    req.Entities,
+					"tz":          req.TZ,
 				},
 			},
 		},
 	}
+	if hasNext {
+		response.NextCursor = encodeCursor(plan, plan.Offset+req.Limit)
+	}
+	if plan.Offset > 0 {
+		prev := plan.Offset - req.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		response.PrevCursor = encodeCursor(plan, prev)
+	}
 
 	return response, nil
 }
 
+// nearbyRadius returns the radius a nearby plan searches, or 0 for other strategies
+func nearbyRadius(plan queryPlan) float64 {
+	if plan.Strategy != "nearby" {
+		return 0
+	}
+	return plan.Radius
+}
+
+// repoPage is the window of a result set a repository list call returns
+type repoPage struct {
+	Limit  int32
+	Offset int32
+}
+
+// retrieve fetches a page of articles with plan's strategy. useCache lets hot
+// searches be answered from the search cache.
+func (s *NewsService) retrieve(ctx context.Context, plan queryPlan, page repoPage, useCache bool) ([]ArticleDTO, error) {
+	if plan.ArchiveCutoff != nil {
+		return s.retrieveTiered(ctx, plan, page, useCache)
+	}
+	return s.retrieveStrategy(ctx, plan, page, useCache)
+}
+
+// retrieveStrategy fetches a page of articles with plan's strategy, from
+// every article plan's publication window covers
+func (s *NewsService) retrieveStrategy(ctx context.Context, plan queryPlan, page repoPage, useCache bool) ([]ArticleDTO, error) {
+	switch plan.Strategy {
+	case "category":
+		return s.getArticlesByCategory(ctx, plan, page)
+	case "source":
+		return s.getArticlesBySource(ctx, plan, page)
+	case "score":
+		return s.getArticlesByScore(ctx, plan, page)
+	case "nearby":
+		return s.getNearbyArticles(ctx, plan, page)
+	case "place":
+		return s.getArticlesByPlace(ctx, plan, page)
+	case "semantic":
+		return s.getSemanticArticles(ctx, plan, page)
+	default:
+		if !useCache {
+			return s.searchArticlesUncached(ctx, plan, page)
+		}
+		return s.searchArticles(ctx, plan, page)
+	}
+}
+
+// auditQuery records a query with how it was planned and what it returned
+func (s *NewsService) auditQuery(ctx context.Context, req QueryRequest, extraction *llm.Extraction, plan queryPlan, resp *QueryResponse, err error, took time.Duration) {
+	entry := queryaudit.Entry{
+		Query:      req.Query,
+		Lat:        req.Lat,
+		Lon:        req.Lon,
+		RadiusKm:   req.Radius,
+		Limit:      req.Limit,
+		Lang:       req.Lang,
+		Offset:     plan.Offset,
+		Plan:       plans.FromContext(ctx).Plan.Name,
+		Extraction: extraction,
+		Strategy:   plan.Strategy,
+		Intent:     plan.Intent,
+		ArticleIDs: []string{},
+		DurationMs: took.Milliseconds(),
+	}
+	switch plan.Strategy {
+	case "category", "source":
+		entry.Target = plan.Name
+	case "score":
+		entry.Target = strconv.FormatFloat(plan.MinScore, 'f', 2, 64)
+	case "nearby":
+		if plan.Lat != nil && plan.Lon != nil {
+			entry.Target = fmt.Sprintf("%.5f,%.5f within %gkm", *plan.Lat, *plan.Lon, plan.Radius)
+		}
+	case "place":
+		entry.Target = plan.Place
+	case "search", "semantic":
+		entry.Target = plan.Query
+	}
+	if resp != nil {
+		for _, article := range resp.Articles {
+			entry.ArticleIDs = append(entry.ArticleIDs, article.ID)
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	s.audit.Record(entry)
+}
+
+// planQuery picks the retrieval strategy and resolves its parameters
+func (s *NewsService) planQuery(extraction *llm.Extraction, req QueryRequest) (queryPlan, error) {
+	lang, ok := language.Normalize(req.Lang)
+	if !ok {
+		return queryPlan{}, errs.Errorf(errs.ErrInvalid, "invalid lang %q: expected an ISO 639-1 code such as \"en\"", req.Lang)
+	}
+	country, ok := geocode.NormalizeCountry(req.Country)
+	if !ok {
+		return queryPlan{}, errs.Errorf(errs.ErrInvalid, "invalid country %q: expected an ISO 3166-1 alpha-2 code such as \"IN\"", req.Country)
+	}
+	if err := llm.ValidateSentiment(req.Sentiment); err != nil {
+		return queryPlan{}, errs.Wrap(errs.ErrInvalid, err)
+	}
+	entityFilter, err := normalizeEntityFilter(req.Entities)
+	if err != nil {
+		return queryPlan{}, err
+	}
+	loc, err := loadTimeZone(req.TZ)
+	if err != nil {
+		return queryPlan{}, err
+	}
+
+	plan := queryPlan{
+		Strategy:     s.determineStrategy(extraction, req),
+		Intent:       s.getBestIntent(extraction),
+		Entities:     s.getAllEntities(extraction),
+		Language:     lang,
+		Country:      country,
+		Sentiment:    req.Sentiment,
+		EntityFilter: entityFilter,
+		TZ:           req.TZ,
+	}
+	query := req.Query
+	if after, before, rest, ok := publishedWindow(req.Query, time.Now(), loc); ok {
+		plan.PublishedAfter, plan.PublishedBefore = &after, &before
+		query = rest
+	}
+
+	switch plan.Strategy {
+	case "category":
+		plan.Name = s.resolveCategory(extraction)
+	case "source":
+		plan.Name = s.resolveSource(extraction)
+	case "score":
+		plan.MinScore = s.resolveMinScore(req.Query)
+	case "nearby":
+		// Without coordinates a named place, e.g. "news in Berlin", is matched
+		// against the places articles were reverse geocoded to
+		if (req.Lat == nil || req.Lon == nil) && len(extraction.Entities.Locations) > 0 {
+			plan.Strategy = "place"
+			plan.Place = strings.TrimSpace(extraction.Entities.Locations[0])
+			break
+		}
+		lat, lon, err := s.resolveLocation(req)
+		if err != nil {
+			return queryPlan{}, err
+		}
+		plan.Lat, plan.Lon = &lat, &lon
+
+		// An explicit radius wins over one stated in the query, e.g. "within
+		// 5 km", which wins over an area it asks to cover, e.g. "across the state"
+		plan.Radius = defaultRadiusKm
+		if req.Radius != nil {
+			plan.Radius = *req.Radius
+		} else if extraction.RadiusKm != nil {
+			plan.Radius = min(*extraction.RadiusKm, maxStatedRadiusKm)
+		} else if extraction.Area != nil {
+			plan.AreaLevel = *extraction.Area
+			plan.Radius = areaRadiusKm[plan.AreaLevel]
+		}
+	default:
+		// Default to search if intent is unclear
+		plan.Strategy = "search"
+		plan.Query = query
+
+		// "today's news" has nothing left to search for: list the window by score
+		if query == "" && plan.PublishedAfter != nil {
+			plan.Strategy = "score"
+		}
+	}
+
+	return plan, nil
+}
+
+// maxEntityFilter bounds the entities a query can be restricted to
+const maxEntityFilter = 5
+
+// normalizeEntityFilter normalizes the entity names a query is restricted to
+// the way stored entities are matched, dropping repeats
+func normalizeEntityFilter(names []string) ([]string, error) {
+	var normalized []string
+	for _, name := range names {
+		entity := repo.NormalizeEntity(name)
+		if entity == "" {
+			return nil, errs.New(errs.ErrInvalid, "entities must not be blank")
+		}
+		if !slices.Contains(normalized, entity) {
+			normalized = append(normalized, entity)
+		}
+	}
+	if len(normalized) > maxEntityFilter {
+		return nil, errs.Errorf(errs.ErrInvalid, "at most %d entities can be given, got %d", maxEntityFilter, len(normalized))
+	}
+	return normalized, nil
+}
+
 // determineStrategy determines the best data retrieval strategy based on LLM extraction and request
 func (s *NewsService) determineStrategy(extraction *llm.Extraction, req QueryRequest) string {
 	// Check for explicit location-based queries
@@ -243,8 +742,8 @@ func (s *NewsService) hasCategoryEntities(entities []string) bool {
 	return false
 }
 
-// getArticlesByCategory retrieves articles by category
-func (s *NewsService) getArticlesByCategory(ctx context.Context, extraction *llm.Extraction, req QueryRequest) ([]ArticleDTO, error) {
+// resolveCategory picks the category to list from the extracted categories
+func (s *NewsService) resolveCategory(extraction *llm.Extraction) string {
 	// Extract category from entities or use a default
 	category := "Technology" // Default
 	for _, cat := range extraction.Categories {
@@ -253,11 +752,22 @@ func (s *NewsService) getArticlesByCategory(ctx context.Context, extraction *llm
 			break
 		}
 	}
+	return category
+}
 
+// getArticlesByCategory retrieves articles by category
+func (s *NewsService) getArticlesByCategory(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
 	// Get articles from repository
 	articles, err := s.repo.GetArticlesByCategory(ctx, repo.GetArticlesByCategoryParams{
-		Name:  category,
-		Limit: int32(req.Limit),
+		Name:            plan.Name,
+		Language:        plan.Language,
+		Country:         plan.Country,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
 	})
 	if err != nil {
 		return nil, err
@@ -267,8 +777,8 @@ func (s *NewsService) getArticlesByCategory(ctx context.Context, extraction *llm
 	return s.convertToDTOs(articles), nil
 }
 
-// getArticlesBySource retrieves articles by source
-func (s *NewsService) getArticlesBySource(ctx context.Context, extraction *llm.Extraction, req QueryRequest) ([]ArticleDTO, error) {
+// resolveSource picks the source to list from the extracted source names
+func (s *NewsService) resolveSource(extraction *llm.Extraction) string {
 	// Extract source from entities
 	source := "TechNews" // Default
 	for _, src := range extraction.SourceNames {
@@ -277,11 +787,22 @@ func (s *NewsService) getArticlesBySource(ctx context.Context, extraction *llm.E
 			break
 		}
 	}
+	return source
+}
 
+// getArticlesBySource retrieves articles by source
+func (s *NewsService) getArticlesBySource(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
 	// Get articles from repository
 	articles, err := s.repo.GetArticlesBySource(ctx, repo.GetArticlesBySourceParams{
-		Name:  source,
-		Limit: int32(req.Limit),
+		Name:            plan.Name,
+		Language:        plan.Language,
+		Country:         plan.Country,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
 	})
 	if err != nil {
 		return nil, err
@@ -291,13 +812,13 @@ func (s *NewsService) getArticlesBySource(ctx context.Context, extraction *llm.E
 	return s.convertToDTOs(articles), nil
 }
 
-// getArticlesByScore retrieves articles by relevance score
-func (s *NewsService) getArticlesByScore(ctx context.Context, extraction *llm.Extraction, req QueryRequest) ([]ArticleDTO, error) {
+// resolveMinScore reads a score threshold from the query text
+func (s *NewsService) resolveMinScore(query string) float64 {
 	// Use a default threshold for high-quality articles
 	minScore := 0.8 // Default to 0.8 for high-quality articles
 	
 	// Try to extract score threshold from the query
-	queryLower := strings.ToLower(req.Query)
+	queryLower := strings.ToLower(query)
 	if strings.Contains(queryLower, "above") || strings.Contains(queryLower, "threshold") {
 		// Look for numbers in the query
 		re := regexp.MustCompile(`(\d+\.?\d*)`)
@@ -308,11 +829,22 @@ func (s *NewsService) getArticlesByScore(ctx context.Context, extraction *llm.Ex
 			}
 		}
 	}
+	return minScore
+}
 
+// getArticlesByScore retrieves articles by relevance score
+func (s *NewsService) getArticlesByScore(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
 	// Get articles from repository
 	articles, err := s.repo.GetArticlesByScore(ctx, repo.GetArticlesByScoreParams{
-		Min:   minScore,
-		Limit: int32(req.Limit),
+		Min:             plan.MinScore,
+		Language:        plan.Language,
+		Country:         plan.Country,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
 	})
 	if err != nil {
 		return nil, err
@@ -322,15 +854,41 @@ func (s *NewsService) getArticlesByScore(ctx context.Context, extraction *llm.Ex
 	return s.convertToDTOs(articles), nil
 }
 
-// searchArticles performs full-text search
-func (s *NewsService) searchArticles(ctx context.Context, extraction *llm.Extraction, req QueryRequest) ([]ArticleDTO, error) {
-	// Use the original query for search
-	query := req.Query
+// searchArticles performs full-text search, through the search cache for hot queries
+func (s *NewsService) searchArticles(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
+	if s.cache == nil || s.hot == nil || page.Offset != 0 || !s.hot.IsHot(plan.Query) {
+		return s.searchArticlesUncached(ctx, plan, page)
+	}
+
+	key := cache.SearchKey(plan.Query, plan.Language, plan.Country, plan.Sentiment, plan.EntityFilter, plan.PublishedAfter, plan.PublishedBefore, int(page.Limit))
+	var cached []ArticleDTO
+	if err := s.cache.GetPayload(ctx, key, searchSchema, &cached); err == nil {
+		return cached, nil
+	}
+
+	dtos, err := s.searchArticlesUncached(ctx, plan, page)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.SetPayload(ctx, key, searchSchema, dtos, cache.SearchTTL); err != nil {
+		log.Warn().Err(err).Str("query", plan.Query).Msg("Failed to cache search results")
+	}
+	return dtos, nil
+}
 
+// searchArticlesUncached runs a full-text search against the repository
+func (s *NewsService) searchArticlesUncached(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
 	// Get articles from repository
 	articles, err := s.repo.SearchArticles(ctx, repo.SearchArticlesParams{
-		Query: query,
-		Limit: int32(req.Limit),
+		Query:           plan.Query,
+		Language:        plan.Language,
+		Country:         plan.Country,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
 	})
 	if err != nil {
 		return nil, err
@@ -347,34 +905,49 @@ func (s *NewsService) searchArticles(ctx context.Context, extraction *llm.Extrac
 	return dtos, nil
 }
 
-// getNearbyArticles retrieves articles within a specified radius
-func (s *NewsService) getNearbyArticles(ctx context.Context, extraction *llm.Extraction, req QueryRequest) ([]ArticleDTO, error) {
-	// Check if we have coordinates
-	if req.Lat == nil || req.Lon == nil {
-		// Try to extract coordinates from the query if available
-		if len(extraction.Entities.Locations) > 0 {
-			// For now, use a default location if coordinates aren't provided
-			// In a real implementation, you'd geocode the location names
-			defaultLat := 37.7749 // San Francisco
-			defaultLon := -122.4194
-			req.Lat = &defaultLat
-			req.Lon = &defaultLon
-		} else {
-			return nil, fmt.Errorf("latitude and longitude are required for nearby search")
-		}
+// resolveLocation returns the coordinates a nearby query is centred on
+func (s *NewsService) resolveLocation(req QueryRequest) (float64, float64, error) {
+	if req.Lat != nil && req.Lon != nil {
+		return *req.Lat, *req.Lon, nil
 	}
+	return 0, 0, errs.New(errs.ErrInvalid, "latitude and longitude are required for nearby search")
+}
 
-	radius := 10.0 // Default 10km
-	if req.Radius != nil {
-		radius = *req.Radius
+// getArticlesByPlace retrieves the articles reverse geocoded to a city or
+// region named like plan's place
+func (s *NewsService) getArticlesByPlace(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
+	articles, err := s.repo.GetArticlesByPlace(ctx, repo.GetArticlesByPlaceParams{
+		Country:         plan.Country,
+		Place:           plan.Place,
+		Language:        plan.Language,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return s.convertToDTOs(articles), nil
+}
 
+// getNearbyArticles retrieves articles within a specified radius
+func (s *NewsService) getNearbyArticles(ctx context.Context, plan queryPlan, page repoPage) ([]ArticleDTO, error) {
 	// Get articles from repository
 	articles, err := s.repo.GetNearbyArticles(ctx, repo.GetNearbyArticlesParams{
-		Lat:    *req.Lat,
-		Lon:    *req.Lon,
-		Radius:  radius,
-		Limit:   int32(req.Limit),
+		Lat:             *plan.Lat,
+		Lon:             *plan.Lon,
+		Radius:          plan.Radius,
+		Language:        plan.Language,
+		Country:         plan.Country,
+		PublishedAfter:  plan.PublishedAfter,
+		PublishedBefore: plan.PublishedBefore,
+		Sentiment:       plan.Sentiment,
+		Entities:        plan.EntityFilter,
+		Limit:           page.Limit,
+		Offset:          page.Offset,
 	})
 	if err != nil {
 		return nil, err
@@ -391,78 +964,62 @@ func (s *NewsService) getNearbyArticles(ctx context.Context, extraction *llm.Ext
 	return dtos, nil
 }
 
-// enrichArticles enriches articles with LLM-generated summaries
+// enrichArticles adds each article's summary, reusing stored summaries and
+// generating only the missing ones
 func (s *NewsService) enrichArticles(ctx context.Context, articles []ArticleDTO) []ArticleDTO {
-	// Process articles concurrently
-	type result struct {
-		index int
-		summary string
-		err    error
-	}
-
-	results := make(chan result, len(articles))
-	
-	for i, article := range articles {
-		go func(idx int, art ArticleDTO) {
-			description := ""
-			if art.Description != nil {
-				description = *art.Description
-			}
-			summary, err := s.llm.Summarize(ctx, art.Title, description, art.SourceName, art.PublicationDate.Format(time.RFC3339))
-			results <- result{index: idx, summary: summary, err: err}
-		}(i, article)
-	}
-
-	// Collect results
-	summaries := make([]string, len(articles))
-	for i := 0; i < len(articles); i++ {
-		res := <-results
-		if res.err == nil {
-			summaries[res.index] = res.summary
-		}
-	}
-
-	// Apply summaries
+	var wg sync.WaitGroup
 	for i := range articles {
-		if summaries[i] != "" {
-			articles[i].LLMSummary = &summaries[i]
-		}
+		wg.Add(1)
+		go func(article *ArticleDTO) {
+			defer wg.Done()
+			summary, err := s.articleSummary(ctx, *article)
+			switch {
+			case err != nil:
+				metrics.SummaryRequests.WithLabelValues("error").Inc()
+				log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to summarize article")
+				return
+			case summary.fallbackErr != nil:
+				metrics.SummaryRequests.WithLabelValues("fallback").Inc()
+			case summary.generated:
+				metrics.SummaryRequests.WithLabelValues("generated").Inc()
+			default:
+				metrics.SummaryRequests.WithLabelValues("stored").Inc()
+			}
+			article.LLMSummary = &summary.text
+			if summary.sentiment != nil {
+				article.Sentiment = &summary.sentiment.Label
+				article.SentimentScore = &summary.sentiment.Score
+			}
+		}(&articles[i])
 	}
+	wg.Wait()
 
 	return articles
 }
 
-// rankArticles ranks articles based on the strategy used
+// rankArticles ranks articles based on the strategy used: nearby results by
+// distance, all others by the hybrid score of s.ranking. Demoted articles
+// follow the rest.
 func (s *NewsService) rankArticles(articles []ArticleDTO, strategy string, req QueryRequest) []ArticleDTO {
-	switch strategy {
-	case "category", "source":
-		// Rank by publication date (most recent first)
-		sort.Slice(articles, func(i, j int) bool {
-			return articles[i].PublicationDate.After(articles[j].PublicationDate)
-		})
-	case "score":
-		// Rank by relevance score (highest first)
-		sort.Slice(articles, func(i, j int) bool {
-			return articles[i].RelevanceScore > articles[j].RelevanceScore
-		})
-	case "search":
-		// Rank by search score if available, otherwise by relevance score
-		sort.Slice(articles, func(i, j int) bool {
-			if articles[i].SearchScore != nil && articles[j].SearchScore != nil {
-				return *articles[i].SearchScore > *articles[j].SearchScore
-			}
-			return articles[i].RelevanceScore > articles[j].RelevanceScore
-		})
-	case "nearby":
-		// Rank by distance (closest first)
-		sort.Slice(articles, func(i, j int) bool {
+	if strategy == "nearby" {
+		// Rank by distance (closest first), articles without one after the rest
+		sort.SliceStable(articles, func(i, j int) bool {
 			if articles[i].DistanceMeters != nil && articles[j].DistanceMeters != nil {
 				return *articles[i].DistanceMeters < *articles[j].DistanceMeters
 			}
-			return false
+			return articles[i].DistanceMeters != nil && articles[j].DistanceMeters == nil
 		})
+		if s.unlocated != nil {
+			articles = s.unlocated.arrange(articles)
+		}
+	} else {
+		s.ranking.hybridRank(articles, time.Now())
 	}
 
+	// Demoted articles keep their order among themselves
+	sort.SliceStable(articles, func(i, j int) bool {
+		return !articles[i].Demoted && articles[j].Demoted
+	})
 	return articles
 }
 
@@ -501,11 +1058,15 @@ func (s *NewsService) convertToDTO(article repo.Article) ArticleDTO {
 		Title:           article.Title,
 		Description:     article.Description,
 		URL:             article.URL,
-		PublicationDate: article.PublicationDate,
+		PublicationDate: article.PublicationDate.UTC(),
 		SourceName:      article.SourceName,
 		Category:        article.Category,
 		RelevanceScore:  article.RelevanceScore,
 		Latitude:        article.Latitude,
 		Longitude:       article.Longitude,
+		Language:        article.Language,
+		City:            article.City,
+		Region:          article.Region,
+		Country:         article.Country,
 	}
 }
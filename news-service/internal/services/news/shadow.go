@@ -0,0 +1,132 @@
+package news
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"news-system/internal/metrics"
+	"news-system/internal/services/llm"
+	"news-system/internal/services/shadow"
+)
+
+// shadowTimeout bounds a shadow run, which no caller waits on
+const shadowTimeout = 10 * time.Second
+
+// ShadowRecorder stores the comparison of a shadow run with production
+type ShadowRecorder interface {
+	Record(ctx context.Context, result shadow.Result)
+}
+
+// shadowPlanner plans a query the way a shadow strategy would, given the
+// request and the production plan
+type shadowPlanner func(s *NewsService, req QueryRequest, production queryPlan) (queryPlan, error)
+
+// shadowPlanners are the strategies that can be evaluated in shadow mode
+var shadowPlanners = map[string]shadowPlanner{
+	// search answers every query with full-text search, bypassing intent routing
+	"search": func(s *NewsService, req QueryRequest, production queryPlan) (queryPlan, error) {
+		_, _, query, _ := publishedWindow(req.Query, time.Now(), time.UTC)
+		return queryPlan{Strategy: "search", Intent: "search", Query: query, Language: production.Language, Country: production.Country, Sentiment: production.Sentiment, EntityFilter: production.EntityFilter,
+			TZ: production.TZ, PublishedAfter: production.PublishedAfter, PublishedBefore: production.PublishedBefore}, nil
+	},
+	// heuristic routes with the keyword extractor instead of the LLM
+	"heuristic": func(s *NewsService, req QueryRequest, production queryPlan) (queryPlan, error) {
+		return s.planQuery(llm.HeuristicExtract(req.Query), req)
+	},
+}
+
+// ShadowStrategies lists the names EnableShadowStrategy accepts
+func ShadowStrategies() []string {
+	names := make([]string, 0, len(shadowPlanners))
+	for name := range shadowPlanners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shadowRunner runs a shadow strategy in the background for a sample of queries
+type shadowRunner struct {
+	name       string
+	planner    shadowPlanner
+	sampleRate float64
+	recorder   ShadowRecorder
+	// slots holds a token per shadow run in flight
+	slots chan struct{}
+}
+
+func newShadowRunner(name string, sampleRate float64, maxInFlight int, recorder ShadowRecorder) (*shadowRunner, error) {
+	planner, ok := shadowPlanners[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown shadow strategy %q: expected one of %s", name, strings.Join(ShadowStrategies(), ", "))
+	}
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	return &shadowRunner{
+		name:       name,
+		planner:    planner,
+		sampleRate: sampleRate,
+		recorder:   recorder,
+		slots:      make(chan struct{}, maxInFlight),
+	}, nil
+}
+
+// run starts a shadow run of req if it falls in the sample and a slot is
+// free. articles are the ranked production results, which took took.
+func (r *shadowRunner) run(ctx context.Context, s *NewsService, req QueryRequest, plan queryPlan, articles []ArticleDTO, took time.Duration) {
+	if r.sampleRate <= 0 || (r.sampleRate < 1 && rand.Float64() >= r.sampleRate) {
+		return
+	}
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		metrics.ShadowRuns.WithLabelValues(r.name, "dropped").Inc()
+		return
+	}
+
+	production := shadow.Run{Strategy: plan.Strategy, ArticleIDs: articleIDs(articles), DurationMs: millis(took)}
+	// The request may finish first, so the run can't share its context
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shadowTimeout)
+	go func() {
+		defer cancel()
+		defer func() { <-r.slots }()
+
+		start := time.Now()
+		candidate := shadow.Run{Strategy: r.name, ArticleIDs: []string{}}
+		shadowPlan, err := r.planner(s, req, plan)
+		if err == nil {
+			candidate.Strategy = shadowPlan.Strategy
+			// Both read the archive behind the same edge of the hot window
+			shadowPlan.ArchiveCutoff = plan.ArchiveCutoff
+			var found []ArticleDTO
+			// Skip the search cache so the shadow neither fills it nor is timed against it
+			found, err = s.retrieve(ctx, shadowPlan, repoPage{Limit: int32(req.Limit)}, false)
+			if err == nil {
+				candidate.ArticleIDs = articleIDs(s.rankArticles(found, shadowPlan.Strategy, req))
+			}
+		}
+		candidate.DurationMs = millis(time.Since(start))
+		if err != nil {
+			candidate.Error = err.Error()
+		}
+
+		r.recorder.Record(ctx, shadow.NewResult(r.name, req.Query, production, candidate))
+	}()
+}
+
+func articleIDs(articles []ArticleDTO) []string {
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	return ids
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
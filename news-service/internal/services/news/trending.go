@@ -0,0 +1,174 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+
+	"news-system/internal/errs"
+	"news-system/internal/repo"
+	"news-system/internal/services/geocode"
+	"news-system/internal/services/language"
+	"news-system/internal/services/llm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Trending modes: around a location, or everywhere
+const (
+	TrendingLocal  = "local"
+	TrendingGlobal = "global"
+)
+
+// TrendingArticle is an article's trending score around a location
+type TrendingArticle struct {
+	ArticleID string
+	Score     float64
+}
+
+// GetTrending returns the articles of scores, the location's trending
+// articles best first, that pass the request's filters, at most req.Limit.
+// Articles that no longer exist are left out, and demoted articles rank
+// below the rest.
+func (s *NewsService) GetTrending(ctx context.Context, req TrendingRequest, scores []TrendingArticle) (*QueryResponse, error) {
+	if req.Limit <= 0 {
+		req.Limit = 5
+	}
+	if req.Mode == "" {
+		req.Mode = TrendingLocal
+	}
+	lang, ok := language.Normalize(req.Lang)
+	if !ok {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid lang %q: expected an ISO 639-1 code such as \"en\"", req.Lang)
+	}
+	country, ok := geocode.NormalizeCountry(req.Country)
+	if !ok {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid country %q: expected an ISO 3166-1 alpha-2 code such as \"IN\"", req.Country)
+	}
+	if err := llm.ValidateSentiment(req.Sentiment); err != nil {
+		return nil, errs.Wrap(errs.ErrInvalid, err)
+	}
+	entities, err := normalizeEntityFilter(req.Entities)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadTimeZone(req.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	articles := make([]ArticleDTO, 0, req.Limit)
+	for _, score := range scores {
+		if len(articles) == req.Limit {
+			break
+		}
+		article, err := s.cachedArticle(ctx, score.ArticleID)
+		if err != nil {
+			if !errors.Is(err, errs.ErrNotFound) {
+				log.Warn().Err(err).Str("article_id", score.ArticleID).Msg("Failed to load trending article")
+			}
+			continue
+		}
+		if (lang != "" && article.Language != lang) || (country != "" && article.Country != country) {
+			continue
+		}
+		if !s.trendingMatches(ctx, article, req.Sentiment, entities) {
+			continue
+		}
+
+		dto := s.convertToDTO(article)
+		dto.TrendingScore = &score.Score
+		articles = append(articles, dto)
+	}
+
+	// Only articles with coordinates trend around a location, so thin
+	// results make room for the most relevant articles without
+	if s.unlocated != nil && req.Mode == TrendingLocal && req.Category == "" {
+		articles = s.unlocated.arrange(s.mixUnlocated(ctx, articles, repo.GetArticlesByScoreParams{
+			Language:  lang,
+			Country:   country,
+			Sentiment: req.Sentiment,
+			Entities:  entities,
+		}, req.Limit))
+	}
+
+	if s.moderation != nil {
+		s.markDemoted(ctx, articles)
+		sort.SliceStable(articles, func(i, j int) bool {
+			return !articles[i].Demoted && articles[j].Demoted
+		})
+	}
+
+	if !req.SkipSummaries {
+		articles = s.enrichArticles(ctx, articles)
+	}
+
+	for i := range articles {
+		articles[i].PublicationDate = articles[i].PublicationDate.In(loc)
+	}
+
+	params := map[string]interface{}{
+		"limit":     req.Limit,
+		"lang":      req.Lang,
+		"country":   req.Country,
+		"sentiment": req.Sentiment,
+		"entities":  req.Entities,
+		"tz":        req.TZ,
+		"category":  req.Category,
+		"mode":      req.Mode,
+		"window":    req.Window,
+	}
+	if req.Mode != TrendingGlobal {
+		params["lat"], params["lon"] = req.Lat, req.Lon
+	}
+
+	return &QueryResponse{
+		Articles: articles,
+		Meta: MetaInfo{
+			Total:    len(articles),
+			Intent:   "trending",
+			Strategy: "trending",
+			Query: &QueryInfo{
+				Endpoint: "trending",
+				Params:   params,
+			},
+		},
+	}, nil
+}
+
+// trendingMatches reports whether article has the sentiment and mentions
+// every one of the normalized entities, where "" and none match any article.
+// An article whose summary or entities can't be read doesn't match.
+func (s *NewsService) trendingMatches(ctx context.Context, article repo.Article, sentiment string, entities []string) bool {
+	if sentiment != "" {
+		summary, err := s.cachedSummary(ctx, article.ID, article.PublicationDate)
+		if err != nil {
+			if !errors.Is(err, errs.ErrNotFound) {
+				log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to load trending article summary")
+			}
+			return false
+		}
+		if summary.Sentiment == nil || *summary.Sentiment != sentiment {
+			return false
+		}
+	}
+
+	if len(entities) > 0 {
+		stored, err := s.repo.GetArticleEntities(ctx, article.ID)
+		if err != nil {
+			log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to load trending article entities")
+			return false
+		}
+		names := make([]string, len(stored))
+		for i, entity := range stored {
+			names[i] = repo.NormalizeEntity(entity.Name)
+		}
+		for _, entity := range entities {
+			if !slices.Contains(names, entity) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package news
+
+import (
+	"context"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Policies for blending articles without coordinates into thin nearby and
+// trending results
+const (
+	// UnlocatedMixOff never blends them in
+	UnlocatedMixOff = "off"
+	// UnlocatedMixFill places them after the located articles
+	UnlocatedMixFill = "fill"
+	// UnlocatedMixInterleave alternates them with the located articles
+	UnlocatedMixInterleave = "interleave"
+)
+
+// unlocatedMix configures blending articles without coordinates into thin
+// results, see EnableUnlocatedMix
+type unlocatedMix struct {
+	policy       string
+	minResults   int
+	minRelevance float64
+}
+
+// mixUnlocated adds the most relevant articles without coordinates that
+// pass filter to articles, a first page of nearby or local trending results,
+// when it holds fewer than minResults, bringing it up to minResults and at
+// most limit. Added articles are marked Unlocated and have no distance.
+// articles is returned unchanged when the lookup fails.
+func (s *NewsService) mixUnlocated(ctx context.Context, articles []ArticleDTO, filter repo.GetArticlesByScoreParams, limit int) []ArticleDTO {
+	target := min(s.unlocated.minResults, limit)
+	if len(articles) >= target {
+		return articles
+	}
+
+	filter.Min = s.unlocated.minRelevance
+	filter.Unlocated = true
+	filter.Limit, filter.Offset = int32(target-len(articles)), 0
+	unlocated, err := s.repo.GetArticlesByScore(ctx, filter)
+	if err != nil {
+		metrics.UnlocatedMixes.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Msg("Failed to look up articles without coordinates for thin results")
+		return articles
+	}
+	if len(unlocated) == 0 {
+		metrics.UnlocatedMixes.WithLabelValues("empty").Inc()
+		return articles
+	}
+
+	metrics.UnlocatedMixes.WithLabelValues(s.unlocated.policy).Inc()
+	for _, article := range s.convertToDTOs(unlocated) {
+		article.Unlocated = true
+		articles = append(articles, article)
+	}
+	return articles
+}
+
+// arrange orders the unlocated articles of articles, ranked, among the
+// located ones by the policy: after them, or alternating with them. Each
+// keeps its order.
+func (m *unlocatedMix) arrange(articles []ArticleDTO) []ArticleDTO {
+	var located, unlocated []ArticleDTO
+	for _, article := range articles {
+		if article.Unlocated {
+			unlocated = append(unlocated, article)
+		} else {
+			located = append(located, article)
+		}
+	}
+	if len(unlocated) == 0 {
+		return articles
+	}
+	if m.policy != UnlocatedMixInterleave {
+		return append(located, unlocated...)
+	}
+
+	mixed := make([]ArticleDTO, 0, len(articles))
+	for len(located) > 0 || len(unlocated) > 0 {
+		if len(located) > 0 {
+			mixed = append(mixed, located[0])
+			located = located[1:]
+		}
+		if len(unlocated) > 0 {
+			mixed = append(mixed, unlocated[0])
+			unlocated = unlocated[1:]
+		}
+	}
+	return mixed
+}
@@ -0,0 +1,291 @@
+// Package queryaudit keeps a sampled record of how queries were answered:
+// the query, what the LLM extracted from it, the strategy it was routed to
+// and the articles returned, so relevance regressions can be investigated
+// after the fact.
+package queryaudit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/llm"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// queueSize bounds the entries waiting to be written; more are dropped
+	queueSize = 1000
+	// trimInterval is how often entries beyond the retention limits are removed
+	trimInterval = time.Minute
+	// searchPageSize is the number of entries read per round trip when searching
+	searchPageSize = 500
+	// maxScanned bounds how many entries one search reads, newest first
+	maxScanned = 20000
+)
+
+// Entry is one audited query
+type Entry struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+
+	Query    string   `json:"query"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	RadiusKm *float64 `json:"radius_km,omitempty"`
+	Limit    int      `json:"limit"`
+	Lang     string   `json:"lang,omitempty"`
+	// Offset is the position of the page, non-zero for pages reached through a cursor
+	Offset int `json:"offset"`
+	// Plan is the API plan of the caller
+	Plan string `json:"plan,omitempty"`
+
+	// Extraction is what the LLM read from the query; cursor pages reuse the
+	// first page's plan and have none
+	Extraction *llm.Extraction `json:"extraction,omitempty"`
+	Strategy   string          `json:"strategy,omitempty"`
+	Intent     string          `json:"intent,omitempty"`
+	// Target is what the strategy looked up: a category or source name, a
+	// minimum score, a location or the search text
+	Target string `json:"target,omitempty"`
+
+	ArticleIDs []string `json:"article_ids"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Filter selects entries in Search. Zero values match everything.
+type Filter struct {
+	// Query matches entries whose query contains it, case-insensitively
+	Query     string
+	Strategy  string
+	ArticleID string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// Log writes sampled entries to Redis in the background and keeps them for
+// retention, up to maxEntries. Entries are written by a single goroutine so
+// recording never delays a response; when Redis falls behind they are dropped.
+type Log struct {
+	cache      *cache.RedisCache
+	sampleRate float64
+	retention  time.Duration
+	maxEntries int64
+
+	queue   chan Entry
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewLog creates a log that records sampleRate (0 to 1) of queries
+func NewLog(cache *cache.RedisCache, sampleRate float64, retention time.Duration, maxEntries int) *Log {
+	if retention <= 0 {
+		retention = 72 * time.Hour
+	}
+	if maxEntries <= 0 {
+		maxEntries = 100000
+	}
+	return &Log{
+		cache:      cache,
+		sampleRate: sampleRate,
+		retention:  retention,
+		maxEntries: int64(maxEntries),
+		queue:      make(chan Entry, queueSize),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// Start begins writing recorded entries and trimming old ones
+func (l *Log) Start(ctx context.Context) {
+	go func() {
+		defer close(l.stopped)
+		ticker := time.NewTicker(trimInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case entry := <-l.queue:
+				l.write(ctx, entry)
+			case <-ticker.C:
+				l.trim(ctx)
+			case <-l.done:
+				l.drain(ctx)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Float64("sample_rate", l.sampleRate).Dur("retention", l.retention).Msg("Query audit log started")
+}
+
+// Stop writes the entries still queued and stops the writer
+func (l *Log) Stop() {
+	close(l.done)
+	<-l.stopped
+	log.Info().Msg("Query audit log stopped")
+}
+
+// Record queues entry if it falls in the sample; it never blocks
+func (l *Log) Record(entry Entry) {
+	if l.sampleRate <= 0 || (l.sampleRate < 1 && rand.Float64() >= l.sampleRate) {
+		return
+	}
+	if entry.ID == "" {
+		entry.ID = repo.NewArticleID()
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	select {
+	case l.queue <- entry:
+	default:
+		metrics.QueryAuditEntries.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Search returns the newest entries matching filter. It reads at most
+// maxScanned entries, so narrow broad filters with Since and Until.
+func (l *Log) Search(ctx context.Context, filter Filter) ([]Entry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	min, max := "-inf", "+inf"
+	if !filter.Since.IsZero() {
+		min = fmt.Sprint(filter.Since.UnixMilli())
+	}
+	if !filter.Until.IsZero() {
+		max = fmt.Sprint(filter.Until.UnixMilli())
+	}
+	query := strings.ToLower(filter.Query)
+
+	entries := []Entry{}
+	for offset := int64(0); offset < maxScanned; offset += searchPageSize {
+		var cmd *redis.StringSliceCmd
+		err := l.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			cmd = pipe.ZRevRangeByScore(ctx, cache.QueryAuditIndexKey(), &redis.ZRangeBy{
+				Min: min, Max: max, Offset: offset, Count: searchPageSize,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query audit index: %w", err)
+		}
+		ids := cmd.Val()
+		if len(ids) == 0 {
+			break
+		}
+
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = cache.QueryAuditEntryKey(id)
+		}
+		values, err := l.cache.MGet(ctx, keys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query audit entries: %w", err)
+		}
+
+		for _, value := range values {
+			if value == nil {
+				// Expired before the index was trimmed
+				continue
+			}
+			var entry Entry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				continue
+			}
+			if !matches(entry, filter, query) {
+				continue
+			}
+			entries = append(entries, entry)
+			if len(entries) == filter.Limit {
+				return entries, nil
+			}
+		}
+		if len(ids) < searchPageSize {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// matches reports whether entry passes filter; query is the lowercased filter.Query
+func matches(entry Entry, filter Filter, query string) bool {
+	if query != "" && !strings.Contains(strings.ToLower(entry.Query), query) {
+		return false
+	}
+	if filter.Strategy != "" && entry.Strategy != filter.Strategy {
+		return false
+	}
+	if filter.ArticleID != "" {
+		for _, id := range entry.ArticleIDs {
+			if id == filter.ArticleID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// write stores an entry and indexes it by time
+func (l *Log) write(ctx context.Context, entry Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		metrics.QueryAuditEntries.WithLabelValues("failed").Inc()
+		return
+	}
+
+	err = l.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, cache.QueryAuditEntryKey(entry.ID), data, l.retention)
+		pipe.ZAdd(ctx, cache.QueryAuditIndexKey(), redis.Z{Score: float64(entry.Time.UnixMilli()), Member: entry.ID})
+		return nil
+	})
+	if err != nil {
+		metrics.QueryAuditEntries.WithLabelValues("failed").Inc()
+		log.Warn().Err(err).Msg("Failed to write query audit entry")
+		return
+	}
+	metrics.QueryAuditEntries.WithLabelValues("written").Inc()
+}
+
+// trim drops index entries older than the retention period or beyond maxEntries;
+// the entries themselves expire on their own
+func (l *Log) trim(ctx context.Context) {
+	cutoff := time.Now().Add(-l.retention).UnixMilli()
+	err := l.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, cache.QueryAuditIndexKey(), "-inf", fmt.Sprint(cutoff))
+		pipe.ZRemRangeByRank(ctx, cache.QueryAuditIndexKey(), 0, -l.maxEntries-1)
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to trim query audit log")
+	}
+}
+
+// drain writes whatever is still queued, giving up once the queue is empty
+func (l *Log) drain(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	for {
+		select {
+		case entry := <-l.queue:
+			l.write(ctx, entry)
+		default:
+			return
+		}
+	}
+}
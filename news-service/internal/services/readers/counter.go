@@ -0,0 +1,134 @@
+package readers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"news-system/internal/cache"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Window is the period unique reader counts cover, matching the trending window
+const Window = 24 * time.Hour
+
+// bucket is the granularity of the HyperLogLogs a window is made of
+const bucket = time.Hour
+
+// Counter tracks approximately how many distinct users read each article and
+// each geohash tile, with Redis HyperLogLogs. Each HyperLogLog covers one hour
+// and counts are taken over the union of the hours in Window, so a user who
+// reads an article a thousand times counts once. Memory is bounded per key
+// (at most 12KB) regardless of the number of readers, with a standard error
+// of about 0.8%.
+type Counter struct {
+	cache      *cache.RedisCache
+	precisions []int
+}
+
+// NewCounter creates a counter that tracks tiles at the given geohash
+// precisions, which should match the trending tiles
+func NewCounter(cache *cache.RedisCache, precisions []int) *Counter {
+	var valid []int
+	for _, p := range precisions {
+		if p >= 1 && p <= 12 {
+			valid = append(valid, p)
+		}
+	}
+	if len(valid) == 0 {
+		valid = []int{5}
+	}
+	return &Counter{cache: cache, precisions: valid}
+}
+
+// Add records that a user read an article, from a location when known. The
+// user ID only needs to be stable per user, e.g. an account or session ID;
+// HyperLogLogs keep no trace of it.
+func (c *Counter) Add(ctx context.Context, userID, articleID string, lat, lon *float64) error {
+	if userID == "" {
+		return nil
+	}
+	hour := time.Now().Truncate(bucket).Unix()
+
+	err := c.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		key := cache.ArticleReadersKey(articleID, hour)
+		pipe.PFAdd(ctx, key, userID)
+		pipe.Expire(ctx, key, cache.ReadersTTL)
+
+		if lat != nil && lon != nil {
+			for _, precision := range c.precisions {
+				key := cache.TileReadersKey(cache.GenerateGeohash(*lat, *lon, precision), hour)
+				pipe.PFAdd(ctx, key, userID)
+				pipe.Expire(ctx, key, cache.ReadersTTL)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record reader of article %s: %w", articleID, err)
+	}
+	return nil
+}
+
+// ArticleReaders returns the approximate number of distinct readers of an article over Window
+func (c *Counter) ArticleReaders(ctx context.Context, articleID string) (int64, error) {
+	counts, err := c.ArticleReadersMany(ctx, []string{articleID})
+	if err != nil {
+		return 0, err
+	}
+	return counts[articleID], nil
+}
+
+// ArticleReadersMany returns the approximate distinct readers of several articles over Window in one round trip
+func (c *Counter) ArticleReadersMany(ctx context.Context, articleIDs []string) (map[string]int64, error) {
+	return c.count(ctx, articleIDs, cache.ArticleReadersKey)
+}
+
+// TileReaders returns the approximate number of distinct readers in a geohash tile over Window
+func (c *Counter) TileReaders(ctx context.Context, geohash string) (int64, error) {
+	counts, err := c.count(ctx, []string{geohash}, cache.TileReadersKey)
+	if err != nil {
+		return 0, err
+	}
+	return counts[geohash], nil
+}
+
+// count runs one PFCOUNT per ID over the union of its hourly HyperLogLogs
+func (c *Counter) count(ctx context.Context, ids []string, key func(string, int64) string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	hours := hoursInWindow(time.Now())
+	cmds := make(map[string]*redis.IntCmd, len(ids))
+	err := c.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			keys := make([]string, len(hours))
+			for i, hour := range hours {
+				keys[i] = key(id, hour)
+			}
+			cmds[id] = pipe.PFCount(ctx, keys...)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to count unique readers: %w", err)
+	}
+	for id, cmd := range cmds {
+		counts[id] = cmd.Val()
+	}
+	return counts, nil
+}
+
+// hoursInWindow returns the start of every hourly bucket overlapping Window up to now
+func hoursInWindow(now time.Time) []int64 {
+	current := now.Truncate(bucket)
+	n := int(Window / bucket)
+	hours := make([]int64, n)
+	for i := range hours {
+		hours[i] = current.Add(-time.Duration(i) * bucket).Unix()
+	}
+	return hours
+}
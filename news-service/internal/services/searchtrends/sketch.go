@@ -0,0 +1,234 @@
+package searchtrends
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CountMinSketch estimates how often terms were added in fixed memory. An
+// estimate is never below the true count and overestimates it by at most
+// epsilon times the total number of additions with probability 1-delta.
+type CountMinSketch struct {
+	width    uint32
+	depth    int
+	counters []uint32
+}
+
+// NewCountMinSketch creates a sketch with error bound epsilon and failure probability delta
+func NewCountMinSketch(epsilon, delta float64) *CountMinSketch {
+	if epsilon <= 0 || epsilon >= 1 {
+		epsilon = 0.001
+	}
+	if delta <= 0 || delta >= 1 {
+		delta = 0.01
+	}
+	width := uint32(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	return &CountMinSketch{
+		width:    width,
+		depth:    depth,
+		counters: make([]uint32, int(width)*depth),
+	}
+}
+
+// Add counts one occurrence of term and returns its new estimate
+func (s *CountMinSketch) Add(term string) uint32 {
+	h1, h2 := sketchHashes(term)
+	estimate := uint32(math.MaxUint32)
+	for row := 0; row < s.depth; row++ {
+		i := s.index(row, h1, h2)
+		if s.counters[i] < math.MaxUint32 {
+			s.counters[i]++
+		}
+		if s.counters[i] < estimate {
+			estimate = s.counters[i]
+		}
+	}
+	return estimate
+}
+
+// Estimate returns how many times term was added, possibly overestimated
+func (s *CountMinSketch) Estimate(term string) uint32 {
+	h1, h2 := sketchHashes(term)
+	estimate := uint32(math.MaxUint32)
+	for row := 0; row < s.depth; row++ {
+		if c := s.counters[s.index(row, h1, h2)]; c < estimate {
+			estimate = c
+		}
+	}
+	return estimate
+}
+
+// Reset clears every counter
+func (s *CountMinSketch) Reset() {
+	for i := range s.counters {
+		s.counters[i] = 0
+	}
+}
+
+// index returns the counter of term in a row, using double hashing for the row hashes
+func (s *CountMinSketch) index(row int, h1, h2 uint32) int {
+	return row*int(s.width) + int((h1+uint32(row)*h2)%s.width)
+}
+
+// sketchHashes derives the two base hashes for double hashing
+func sketchHashes(term string) (uint32, uint32) {
+	h := fnv.New64a()
+	h.Write([]byte(term))
+	sum := h.Sum64()
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+// HotQueries detects the queries searched most often in the last window
+// without storing every query string. Counts are kept in two count-min
+// sketches, one for the current window and one for the previous, and read as
+// a sliding window by weighting the previous one by how much of it is still
+// in range. Only the topK terms with the highest estimates are remembered,
+// so memory stays fixed however many distinct queries are seen.
+//
+// Counts are local to the instance; each instance sees the share of traffic
+// routed to it, which is enough to spot queries that are hot overall.
+type HotQueries struct {
+	window    time.Duration
+	threshold uint32
+	topK      int
+
+	mu          sync.Mutex
+	current     *CountMinSketch
+	previous    *CountMinSketch
+	windowStart time.Time
+	candidates  map[string]struct{}
+}
+
+// NewHotQueries creates a detector that considers a query hot once it was
+// searched threshold times within window, remembering at most topK queries
+func NewHotQueries(window time.Duration, threshold, topK int) *HotQueries {
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+	if threshold <= 0 {
+		threshold = 20
+	}
+	if topK <= 0 {
+		topK = 100
+	}
+	return &HotQueries{
+		window:      window,
+		threshold:   uint32(threshold),
+		topK:        topK,
+		current:     NewCountMinSketch(0.001, 0.01),
+		previous:    NewCountMinSketch(0.001, 0.01),
+		windowStart: time.Now().Truncate(window),
+		candidates:  make(map[string]struct{}, topK),
+	}
+}
+
+// Add counts a normalized query
+func (h *HotQueries) Add(term string) {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.rotate(now)
+	h.current.Add(term)
+	if _, ok := h.candidates[term]; ok || len(h.candidates) < h.topK {
+		h.candidates[term] = struct{}{}
+		return
+	}
+
+	// Replace the coldest candidate if this term has overtaken it
+	weight := h.previousWeight(now)
+	estimate := h.estimate(term, weight)
+	coldest, coldestEstimate := "", math.MaxFloat64
+	for candidate := range h.candidates {
+		if e := h.estimate(candidate, weight); e < coldestEstimate {
+			coldest, coldestEstimate = candidate, e
+		}
+	}
+	if estimate > coldestEstimate {
+		delete(h.candidates, coldest)
+		h.candidates[term] = struct{}{}
+	}
+}
+
+// IsHot reports whether a normalized query has reached the hot threshold
+func (h *HotQueries) IsHot(term string) bool {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.rotate(now)
+	return h.estimate(term, h.previousWeight(now)) >= float64(h.threshold)
+}
+
+// Top returns up to limit hot queries, hottest first, scored by estimated searches in the last window
+func (h *HotQueries) Top(limit int) []SearchTrend {
+	now := time.Now()
+
+	h.mu.Lock()
+	h.rotate(now)
+	weight := h.previousWeight(now)
+	hot := make([]SearchTrend, 0, len(h.candidates))
+	for term := range h.candidates {
+		if e := h.estimate(term, weight); e >= float64(h.threshold) {
+			hot = append(hot, SearchTrend{Term: term, Score: math.Round(e)})
+		}
+	}
+	h.mu.Unlock()
+
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Score != hot[j].Score {
+			return hot[i].Score > hot[j].Score
+		}
+		return hot[i].Term < hot[j].Term
+	})
+	if limit > 0 && len(hot) > limit {
+		hot = hot[:limit]
+	}
+	return hot
+}
+
+// Window returns the window hot queries are counted over
+func (h *HotQueries) Window() time.Duration {
+	return h.window
+}
+
+// rotate moves to the window containing now, discarding sketches that fell out of range
+func (h *HotQueries) rotate(now time.Time) {
+	start := now.Truncate(h.window)
+	if !start.After(h.windowStart) {
+		return
+	}
+
+	if start.Sub(h.windowStart) == h.window {
+		h.current, h.previous = h.previous, h.current
+	} else {
+		// Idle for more than a window, so nothing counted so far is in range
+		h.previous.Reset()
+	}
+	h.current.Reset()
+	h.windowStart = start
+
+	// Forget candidates that are no longer counted at all
+	for term := range h.candidates {
+		if h.previous.Estimate(term) == 0 {
+			delete(h.candidates, term)
+		}
+	}
+}
+
+// previousWeight returns the fraction of the previous window still inside the sliding window
+func (h *HotQueries) previousWeight(now time.Time) float64 {
+	elapsed := now.Sub(h.windowStart)
+	return 1 - float64(elapsed)/float64(h.window)
+}
+
+// estimate returns the sliding-window count of term
+func (h *HotQueries) estimate(term string, previousWeight float64) float64 {
+	return float64(h.current.Estimate(term)) + previousWeight*float64(h.previous.Estimate(term))
+}
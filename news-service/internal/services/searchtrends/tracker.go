@@ -0,0 +1,264 @@
+package searchtrends
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// GlobalRegion aggregates queries from every location, including requests without coordinates
+const GlobalRegion = "global"
+
+// minTermCount is the number of searches a term needs in the current window to be considered rising
+const minTermCount = 2
+
+// maxTermLength bounds the length of stored terms
+const maxTermLength = 100
+
+var (
+	emailPattern = regexp.MustCompile(`[[:alnum:]._%+\-]+@[[:alnum:].\-]+\.[[:alpha:]]{2,}`)
+	urlPattern   = regexp.MustCompile(`(https?://|www\.)\S+`)
+	digitPattern = regexp.MustCompile(`[\d][\d\s\-().+]{5,}[\d]`)
+	punctPattern = regexp.MustCompile(`[^\p{L}\p{N}\s'-]+`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// SearchTrend is a search term whose volume is rising in a region
+type SearchTrend struct {
+	Term  string  `json:"term"`
+	Score float64 `json:"score"`
+}
+
+// Tracker logs normalized search queries per region and time window and
+// periodically computes which terms are rising compared to the previous window.
+type Tracker struct {
+	cache           *cache.RedisCache
+	window          time.Duration
+	regionPrecision int
+	hot             *HotQueries
+	ticker          *time.Ticker
+	done            chan bool
+}
+
+// NewTracker creates a new search trend tracker. Hot queries are detected
+// with hot when it is non-nil.
+func NewTracker(cache *cache.RedisCache, window time.Duration, regionPrecision int, hot *HotQueries) *Tracker {
+	if window <= 0 {
+		window = time.Hour
+	}
+	if regionPrecision <= 0 {
+		regionPrecision = 3
+	}
+	return &Tracker{
+		cache:           cache,
+		window:          window,
+		regionPrecision: regionPrecision,
+		hot:             hot,
+		done:            make(chan bool),
+	}
+}
+
+// Normalize lowercases a query and scrubs anything that could identify a
+// person (emails, URLs, phone and account numbers). It returns "" when
+// nothing worth logging remains.
+func Normalize(query string) string {
+	term := strings.ToLower(query)
+	term = emailPattern.ReplaceAllString(term, " ")
+	term = urlPattern.ReplaceAllString(term, " ")
+	term = digitPattern.ReplaceAllString(term, " ")
+	term = punctPattern.ReplaceAllString(term, " ")
+	term = strings.TrimSpace(spacePattern.ReplaceAllString(term, " "))
+
+	if len(term) > maxTermLength {
+		term = strings.TrimSpace(term[:maxTermLength])
+	}
+	if len(term) < 2 {
+		return ""
+	}
+	return term
+}
+
+// Region returns the region a location belongs to
+func (t *Tracker) Region(lat, lon *float64) string {
+	if lat == nil || lon == nil {
+		return GlobalRegion
+	}
+	return cache.GenerateGeohash(*lat, *lon, t.regionPrecision)
+}
+
+// Window returns the aggregation window
+func (t *Tracker) Window() time.Duration {
+	return t.window
+}
+
+// IsHot reports whether a query is currently searched often enough to be hot
+func (t *Tracker) IsHot(query string) bool {
+	if t.hot == nil {
+		return false
+	}
+	term := Normalize(query)
+	return term != "" && t.hot.IsHot(term)
+}
+
+// HotQueries returns up to limit queries searched most often right now,
+// scored by their estimated searches over HotWindow
+func (t *Tracker) HotQueries(limit int) []SearchTrend {
+	if t.hot == nil {
+		return nil
+	}
+	return t.hot.Top(limit)
+}
+
+// HotWindow returns the window hot queries are counted over, zero when hot query detection is off
+func (t *Tracker) HotWindow() time.Duration {
+	if t.hot == nil {
+		return 0
+	}
+	return t.hot.Window()
+}
+
+// Record logs a search query for its region and for the global region
+func (t *Tracker) Record(ctx context.Context, query string, lat, lon *float64) error {
+	term := Normalize(query)
+	if term == "" {
+		return nil
+	}
+	if t.hot != nil {
+		t.hot.Add(term)
+	}
+
+	bucket := t.bucket(time.Now())
+	regions := []string{GlobalRegion}
+	if region := t.Region(lat, lon); region != GlobalRegion {
+		regions = append(regions, region)
+	}
+
+	for _, region := range regions {
+		// Keep the current and previous window around for comparison
+		if err := t.cache.IncrSortedSetMember(ctx, cache.SearchTermsKey(region, bucket), term, 1, 3*t.window); err != nil {
+			return fmt.Errorf("failed to record search term: %w", err)
+		}
+	}
+	return t.cache.SAdd(ctx, cache.SearchRegionsKey(), regions)
+}
+
+// Start begins the background rising-terms computation
+func (t *Tracker) Start(ctx context.Context, interval time.Duration) {
+	t.ticker = time.NewTicker(interval)
+
+	go func() {
+		for {
+			select {
+			case <-t.ticker.C:
+				if err := t.computeAll(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to compute search trends")
+				}
+			case <-t.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Dur("window", t.window).Msg("Search trend tracker started")
+}
+
+// Stop stops the background computation
+func (t *Tracker) Stop() {
+	if t.ticker != nil {
+		t.ticker.Stop()
+	}
+	close(t.done)
+	log.Info().Msg("Search trend tracker stopped")
+}
+
+// computeAll recomputes rising terms for every region seen so far
+func (t *Tracker) computeAll(ctx context.Context) error {
+	regions, err := t.cache.SMembers(ctx, cache.SearchRegionsKey())
+	if err != nil {
+		return fmt.Errorf("failed to list search regions: %w", err)
+	}
+
+	now := time.Now()
+	for _, region := range regions {
+		if err := t.computeRegion(ctx, region, now); err != nil {
+			log.Warn().Err(err).Str("region", region).Msg("Failed to compute search trends for region")
+		}
+	}
+	return nil
+}
+
+// computeRegion scores terms by how much their volume grew against the previous window
+func (t *Tracker) computeRegion(ctx context.Context, region string, now time.Time) error {
+	current, err := t.cache.ZRevRangeWithScores(ctx, cache.SearchTermsKey(region, t.bucket(now)), 0, -1)
+	if err != nil {
+		return err
+	}
+	previous, err := t.cache.ZRevRangeWithScores(ctx, cache.SearchTermsKey(region, t.bucket(now.Add(-t.window))), 0, -1)
+	if err != nil {
+		return err
+	}
+
+	previousCounts := make(map[string]float64, len(previous))
+	for _, z := range previous {
+		if term, ok := z.Member.(string); ok {
+			previousCounts[term] = z.Score
+		}
+	}
+
+	var trends []redis.Z
+	for _, z := range current {
+		term, ok := z.Member.(string)
+		if !ok || z.Score < minTermCount {
+			continue
+		}
+		// Growth ratio dampened by volume so a single burst doesn't dominate
+		growth := (z.Score + 1) / (previousCounts[term] + 1)
+		if growth <= 1 {
+			continue
+		}
+		trends = append(trends, redis.Z{
+			Score:  math.Log(growth) * math.Log1p(z.Score),
+			Member: term,
+		})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Score > trends[j].Score
+	})
+
+	return t.cache.ReplaceSortedSet(ctx, cache.SearchTrendsKey(region), trends, 2*t.window)
+}
+
+// GetTrends returns the top rising search terms for a region
+func (t *Tracker) GetTrends(ctx context.Context, region string, limit int) ([]SearchTrend, error) {
+	scores, err := t.cache.ZRevRangeWithScores(ctx, cache.SearchTrendsKey(region), 0, int64(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search trends: %w", err)
+	}
+
+	trends := make([]SearchTrend, 0, len(scores))
+	for _, score := range scores {
+		term, ok := score.Member.(string)
+		if !ok {
+			continue
+		}
+		trends = append(trends, SearchTrend{Term: term, Score: score.Score})
+	}
+	return trends, nil
+}
+
+// bucket returns the start of the window containing ts as a unix timestamp
+func (t *Tracker) bucket(ts time.Time) int64 {
+	return ts.Truncate(t.window).Unix()
+}
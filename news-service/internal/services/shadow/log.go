@@ -0,0 +1,210 @@
+// Package shadow records how a shadow retrieval strategy, run alongside the
+// production strategy on a sample of queries, compares with it, so a new
+// strategy can be judged on real traffic before it answers any.
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/metrics"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Run is how one strategy answered a query
+type Run struct {
+	Strategy   string   `json:"strategy"`
+	ArticleIDs []string `json:"article_ids"`
+	// DurationMs covers planning, retrieval and ranking, but not summaries
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Result compares the shadow run of a query with the production run
+type Result struct {
+	Time time.Time `json:"time"`
+	// Shadow names the configured shadow strategy
+	Shadow     string `json:"shadow"`
+	Query      string `json:"query"`
+	Production Run    `json:"production"`
+	Candidate  Run    `json:"candidate"`
+	// Overlap is the share of articles both runs returned, relative to the larger run
+	Overlap float64 `json:"overlap"`
+	// Identical is set when both runs returned the same articles in the same order
+	Identical bool `json:"identical"`
+}
+
+// NewResult compares two runs of query
+func NewResult(shadow, query string, production, candidate Run) Result {
+	result := Result{Time: time.Now(), Shadow: shadow, Query: query, Production: production, Candidate: candidate}
+	if production.Error != "" || candidate.Error != "" {
+		return result
+	}
+
+	inProduction := make(map[string]bool, len(production.ArticleIDs))
+	for _, id := range production.ArticleIDs {
+		inProduction[id] = true
+	}
+	shared := 0
+	for _, id := range candidate.ArticleIDs {
+		if inProduction[id] {
+			shared++
+		}
+	}
+	larger := len(production.ArticleIDs)
+	if len(candidate.ArticleIDs) > larger {
+		larger = len(candidate.ArticleIDs)
+	}
+
+	result.Overlap = 1
+	if larger > 0 {
+		result.Overlap = float64(shared) / float64(larger)
+	}
+	result.Identical = len(production.ArticleIDs) == len(candidate.ArticleIDs)
+	for i := 0; result.Identical && i < len(production.ArticleIDs); i++ {
+		result.Identical = production.ArticleIDs[i] == candidate.ArticleIDs[i]
+	}
+	return result
+}
+
+// Log keeps the newest results in a capped Redis list
+type Log struct {
+	cache      *cache.RedisCache
+	maxResults int64
+}
+
+// NewLog creates a log that keeps up to maxResults results
+func NewLog(cache *cache.RedisCache, maxResults int) *Log {
+	if maxResults <= 0 {
+		maxResults = 10000
+	}
+	return &Log{cache: cache, maxResults: int64(maxResults)}
+}
+
+// Record stores a result, dropping the oldest beyond the cap
+func (l *Log) Record(ctx context.Context, result Result) {
+	outcome := "recorded"
+	if result.Candidate.Error != "" {
+		outcome = "failed"
+	} else {
+		metrics.ShadowOverlap.WithLabelValues(result.Shadow).Observe(result.Overlap)
+	}
+	metrics.ShadowDuration.WithLabelValues(result.Shadow, "production").Observe(result.Production.DurationMs / 1000)
+	metrics.ShadowDuration.WithLabelValues(result.Shadow, "shadow").Observe(result.Candidate.DurationMs / 1000)
+
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = l.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LPush(ctx, cache.ShadowResultsKey(), data)
+			pipe.LTrim(ctx, cache.ShadowResultsKey(), 0, l.maxResults-1)
+			return nil
+		})
+	}
+	if err != nil {
+		outcome = "unrecorded"
+	}
+	metrics.ShadowRuns.WithLabelValues(result.Shadow, outcome).Inc()
+}
+
+// Latency summarizes one side's durations, in milliseconds
+type Latency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+}
+
+// Summary aggregates results
+type Summary struct {
+	Results int `json:"results"`
+	// Errors counts results where either run failed; they're left out of the rest
+	Errors          int     `json:"errors"`
+	MeanOverlap     float64 `json:"mean_overlap"`
+	IdenticalShare  float64 `json:"identical_share"`
+	StrategyChanges int     `json:"strategy_changes"`
+	Production      Latency `json:"production_latency"`
+	Shadow          Latency `json:"shadow_latency"`
+}
+
+// Recent returns up to limit of the newest results with a summary of them
+func (l *Log) Recent(ctx context.Context, limit int) ([]Result, Summary, error) {
+	var cmd *redis.StringSliceCmd
+	err := l.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = pipe.LRange(ctx, cache.ShadowResultsKey(), 0, int64(limit)-1)
+		return nil
+	})
+	if err != nil {
+		return nil, Summary{}, fmt.Errorf("failed to read shadow results: %w", err)
+	}
+
+	results := make([]Result, 0, len(cmd.Val()))
+	for _, value := range cmd.Val() {
+		var result Result
+		if err := json.Unmarshal([]byte(value), &result); err == nil {
+			results = append(results, result)
+		}
+	}
+	return results, Summarize(results), nil
+}
+
+// Summarize aggregates results
+func Summarize(results []Result) Summary {
+	summary := Summary{Results: len(results)}
+	var overlap float64
+	var identical int
+	var production, candidate []float64
+	for _, result := range results {
+		if result.Production.Error != "" || result.Candidate.Error != "" {
+			summary.Errors++
+			continue
+		}
+		overlap += result.Overlap
+		if result.Identical {
+			identical++
+		}
+		if result.Production.Strategy != result.Candidate.Strategy {
+			summary.StrategyChanges++
+		}
+		production = append(production, result.Production.DurationMs)
+		candidate = append(candidate, result.Candidate.DurationMs)
+	}
+	if answered := len(production); answered > 0 {
+		summary.MeanOverlap = overlap / float64(answered)
+		summary.IdenticalShare = float64(identical) / float64(answered)
+	}
+	summary.Production = summarizeLatency(production)
+	summary.Shadow = summarizeLatency(candidate)
+	return summary
+}
+
+func summarizeLatency(durations []float64) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sort.Float64s(durations)
+	var total float64
+	for _, d := range durations {
+		total += d
+	}
+	return Latency{
+		Mean: total / float64(len(durations)),
+		P50:  percentile(durations, 0.50),
+		P95:  percentile(durations, 0.95),
+		P99:  percentile(durations, 0.99),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,210 @@
+// Package summaries fills in article summaries in the background, so query
+// results rarely wait on the LLM for an article nobody has asked about yet.
+package summaries
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// Summarizer generates and stores an article's summary unless it already has
+// one, reporting whether it generated one
+type Summarizer interface {
+	SummarizeArticle(ctx context.Context, article repo.Article) (bool, error)
+}
+
+// Options tunes a Backfiller. Zero values use the defaults noted on each field.
+type Options struct {
+	// Rate caps summaries generated per second, default 1
+	Rate float64
+	// Concurrency bounds summaries generated at once, default 4
+	Concurrency int
+	// BatchSize is the number of unsummarized articles fetched at a time, default 100
+	BatchSize int
+	// IdleInterval is the wait before looking again once every article has a
+	// summary, or after a batch fails entirely, default 1m
+	IdleInterval time.Duration
+	// RetryAfter is how long an article whose summary failed is left alone
+	// while others succeed, default 1h
+	RetryAfter time.Duration
+}
+
+// Backfiller pulls articles without summaries, newest first, and summarizes
+// them with bounded concurrency and rate
+type Backfiller struct {
+	repo       repo.Repository
+	summarizer Summarizer
+	opts       Options
+	limiter    *rate.Limiter
+
+	// failed maps articles whose summary failed to when they may be retried;
+	// only the backfill goroutine touches it
+	failed map[string]time.Time
+
+	done chan bool
+	wg   sync.WaitGroup
+}
+
+// NewBackfiller creates a backfiller that reads unsummarized articles from
+// repository and summarizes them with summarizer
+func NewBackfiller(repository repo.Repository, summarizer Summarizer, opts Options) *Backfiller {
+	if opts.Rate <= 0 {
+		opts.Rate = 1
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 4
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 100
+	}
+	if opts.IdleInterval <= 0 {
+		opts.IdleInterval = time.Minute
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Hour
+	}
+	return &Backfiller{
+		repo:       repository,
+		summarizer: summarizer,
+		opts:       opts,
+		limiter:    rate.NewLimiter(rate.Limit(opts.Rate), 1),
+		failed:     make(map[string]time.Time),
+		done:       make(chan bool),
+	}
+}
+
+// Start runs the backfill until Stop is called or ctx is cancelled
+func (b *Backfiller) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		<-b.done
+	}()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			wait := b.opts.IdleInterval
+			if b.runBatch(ctx) {
+				wait = 0
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Float64("rate", b.opts.Rate).Int("concurrency", b.opts.Concurrency).Msg("Summary backfill started")
+}
+
+// Stop stops the backfill and waits for summaries in progress to finish
+func (b *Backfiller) Stop() {
+	close(b.done)
+	b.wg.Wait()
+	log.Info().Msg("Summary backfill stopped")
+}
+
+// runBatch summarizes one batch of unsummarized articles and reports whether
+// to fetch the next batch right away: false when there was nothing left to do
+// or every summary failed, which suggests the provider is down
+func (b *Backfiller) runBatch(ctx context.Context) bool {
+	now := time.Now()
+	for id, retryAt := range b.failed {
+		if now.After(retryAt) {
+			delete(b.failed, id)
+		}
+	}
+
+	// Articles waiting to be retried come back from the repository too, so
+	// ask for enough to fill a batch without them
+	articles, err := b.repo.GetArticlesWithoutSummary(ctx, int32(b.opts.BatchSize+len(b.failed)))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to list articles without summaries")
+		}
+		return false
+	}
+	var batch []repo.Article
+	for _, article := range articles {
+		if _, waiting := b.failed[article.ID]; !waiting && len(batch) < b.opts.BatchSize {
+			batch = append(batch, article)
+		}
+	}
+	if len(batch) == 0 {
+		return false
+	}
+
+	var mu sync.Mutex
+	var failed []string
+	jobs := make(chan repo.Article)
+	var wg sync.WaitGroup
+	for i := 0; i < b.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for article := range jobs {
+				if !b.summarize(ctx, article) {
+					mu.Lock()
+					failed = append(failed, article.ID)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+feed:
+	for _, article := range batch {
+		if err := b.limiter.Wait(ctx); err != nil {
+			break
+		}
+		select {
+		case jobs <- article:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	if len(failed) == len(batch) {
+		log.Warn().Int("articles", len(batch)).Msg("Every summary in the batch failed, backing off")
+		return false
+	}
+	for _, id := range failed {
+		b.failed[id] = now.Add(b.opts.RetryAfter)
+	}
+	return true
+}
+
+// summarize summarizes one article and reports whether it succeeded
+func (b *Backfiller) summarize(ctx context.Context, article repo.Article) bool {
+	generated, err := b.summarizer.SummarizeArticle(ctx, article)
+	switch {
+	case err != nil:
+		if ctx.Err() == nil {
+			metrics.SummaryBackfill.WithLabelValues("error").Inc()
+			log.Warn().Err(err).Str("article_id", article.ID).Msg("Failed to backfill article summary")
+		}
+		return false
+	case generated:
+		metrics.SummaryBackfill.WithLabelValues("generated").Inc()
+	default:
+		// A query summarized it since the batch was fetched
+		metrics.SummaryBackfill.WithLabelValues("stored").Inc()
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"news-system/internal/errs"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+)
+
+// maxUserIDChars bounds the client-chosen reader ID of an event
+const maxUserIDChars = 128
+
+// EventRequest is a view or click reported by a client
+type EventRequest struct {
+	ArticleID string `json:"article_id"`
+	// Event is "view" or "click"
+	Event string `json:"event"`
+	// UserID is an opaque, stable ID of the reader, used to count distinct
+	// readers; events without one are stored but not counted
+	UserID string `json:"user_id,omitempty"`
+	// Lat and Lon locate the reader, for trending by location; both or
+	// neither are set
+	Lat *float64 `json:"lat,omitempty"`
+	Lon *float64 `json:"lon,omitempty"`
+	// ClientIP and UserAgent describe the client that reported the event
+	// over HTTP, for filtering; they are never read from the body
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// EventResult is the outcome of one event of a batch: the stored event, or
+// the error that rejected it
+type EventResult struct {
+	Index int
+	Event repo.UserEvent
+	Err   error
+}
+
+// IngestEvent validates an event reported by a client and records it
+func (ts *TrendingScorer) IngestEvent(ctx context.Context, req EventRequest) (repo.UserEvent, error) {
+	event, err := ts.ingestEvent(ctx, req)
+	eventType, status := req.Event, "stored"
+	if eventType != repo.EventView && eventType != repo.EventClick {
+		eventType = "unknown"
+	}
+	var ignored *IgnoredError
+	switch {
+	case errors.As(err, &ignored):
+		status = ignored.Reason
+	case err != nil:
+		status = "rejected"
+	}
+	metrics.EventsIngested.WithLabelValues(eventType, status).Inc()
+	return event, err
+}
+
+// IngestEvents ingests a batch of events one by one, so an invalid event
+// doesn't reject the others. It fails as a whole only when the context is
+// done.
+func (ts *TrendingScorer) IngestEvents(ctx context.Context, reqs []EventRequest) ([]EventResult, error) {
+	results := make([]EventResult, len(reqs))
+	for i, req := range reqs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		event, err := ts.IngestEvent(ctx, req)
+		results[i] = EventResult{Index: i, Event: event, Err: err}
+	}
+	return results, nil
+}
+
+// ingestEvent checks the event type, the reader's coordinates and that the
+// article exists, and filters duplicates and likely bots, before recording
+// the event
+func (ts *TrendingScorer) ingestEvent(ctx context.Context, req EventRequest) (repo.UserEvent, error) {
+	articleID := strings.TrimSpace(req.ArticleID)
+	if articleID == "" {
+		return repo.UserEvent{}, errs.New(errs.ErrInvalid, "article_id is required")
+	}
+	if req.Event != repo.EventView && req.Event != repo.EventClick {
+		return repo.UserEvent{}, errs.Errorf(errs.ErrInvalid, "invalid event %q: expected %q or %q", req.Event, repo.EventView, repo.EventClick)
+	}
+	if (req.Lat == nil) != (req.Lon == nil) {
+		return repo.UserEvent{}, errs.New(errs.ErrInvalid, "lat and lon must be given together")
+	}
+	if req.Lat != nil && (*req.Lat < -90 || *req.Lat > 90 || *req.Lon < -180 || *req.Lon > 180) {
+		return repo.UserEvent{}, errs.Errorf(errs.ErrInvalid, "invalid coordinates %g,%g: expected lat in [-90, 90] and lon in [-180, 180]", *req.Lat, *req.Lon)
+	}
+	if len(req.UserID) > maxUserIDChars {
+		return repo.UserEvent{}, errs.Errorf(errs.ErrInvalid, "user_id exceeds %d characters", maxUserIDChars)
+	}
+
+	if err := ts.filterEvent(ctx, req, articleID); err != nil {
+		return repo.UserEvent{}, err
+	}
+	if _, err := ts.repo.GetArticleByID(ctx, articleID); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return repo.UserEvent{}, errs.Errorf(errs.ErrNotFound, "article not found: %s", articleID)
+		}
+		return repo.UserEvent{}, err
+	}
+	return ts.RecordEvent(ctx, req.UserID, repo.CreateUserEventParams{
+		ArticleID: articleID,
+		Event:     req.Event,
+		UserLat:   req.Lat,
+		UserLon:   req.Lon,
+	})
+}
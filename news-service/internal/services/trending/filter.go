@@ -0,0 +1,139 @@
+package trending
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"news-system/internal/cache"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// EventFilterOptions tunes how reported events are filtered before they
+// are stored, so trending can't be pushed by replaying or scripting events
+type EventFilterOptions struct {
+	// DedupWindow is how long a reader's view or click of an article makes
+	// their repeats of it count for nothing; 0 keeps repeats
+	DedupWindow time.Duration
+	// MaxEventsPerMinute is how many events a reader may report in a
+	// minute before it is taken for a bot; 0 disables the check
+	MaxEventsPerMinute int
+	// BotBlockDuration is how long the events of a reader taken for a bot
+	// are dropped
+	BotBlockDuration time.Duration
+}
+
+// defaultBotBlockDuration is how long a reader taken for a bot is blocked
+// when no duration is configured
+const defaultBotBlockDuration = time.Hour
+
+// Reasons an event is ignored
+const (
+	IgnoredDuplicate = "duplicate"
+	IgnoredBot       = "bot"
+)
+
+// IgnoredError reports an event that was accepted but not stored, because
+// its reader just reported it or looks like a bot. Retrying it won't help,
+// and it isn't the client's fault either.
+type IgnoredError struct {
+	Reason string
+}
+
+func (e *IgnoredError) Error() string { return fmt.Sprintf("event ignored: %s", e.Reason) }
+
+// botAgents are User-Agent substrings of crawlers, scripts and headless browsers
+var botAgents = []string{
+	"bot", "crawl", "spider", "slurp", "headless", "phantomjs", "scrapy",
+	"curl/", "wget/", "python-requests", "python-urllib", "go-http-client",
+}
+
+// EnableEventFilter drops the events a reader repeats within
+// opts.DedupWindow and those of likely bots: clients whose User-Agent names
+// a crawler or script, and readers reporting more than
+// opts.MaxEventsPerMinute events, who are then blocked for
+// opts.BotBlockDuration. Readers are told apart by user ID, or by IP for
+// events without one.
+func (ts *TrendingScorer) EnableEventFilter(opts EventFilterOptions) {
+	if opts.BotBlockDuration <= 0 {
+		opts.BotBlockDuration = defaultBotBlockDuration
+	}
+	ts.filter = &opts
+}
+
+// filterEvent returns an IgnoredError when the event of articleID shouldn't
+// be stored. The filter fails open: when Redis can't be reached the event
+// is stored.
+func (ts *TrendingScorer) filterEvent(ctx context.Context, req EventRequest, articleID string) error {
+	if ts.filter == nil {
+		return nil
+	}
+	if likelyBot(req.UserAgent) {
+		return &IgnoredError{Reason: IgnoredBot}
+	}
+	reader := eventReader(req)
+	if reader == "" {
+		return nil
+	}
+
+	var flagged, count *redis.IntCmd
+	var first *redis.BoolCmd
+	rateKey := cache.EventRateKey(reader, time.Now().Unix()/60)
+	err := ts.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		flagged = pipe.Exists(ctx, cache.EventBotKey(reader))
+		if ts.filter.MaxEventsPerMinute > 0 {
+			count = pipe.Incr(ctx, rateKey)
+			pipe.Expire(ctx, rateKey, 2*time.Minute)
+		}
+		if ts.filter.DedupWindow > 0 {
+			first = pipe.SetNX(ctx, cache.EventSeenKey(reader, articleID, req.Event), 1, ts.filter.DedupWindow)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("article_id", articleID).Msg("Failed to filter event, storing it unfiltered")
+		return nil
+	}
+
+	switch {
+	case flagged.Val() > 0:
+		return &IgnoredError{Reason: IgnoredBot}
+	case count != nil && count.Val() > int64(ts.filter.MaxEventsPerMinute):
+		if err := ts.cache.Set(ctx, cache.EventBotKey(reader), "1", ts.filter.BotBlockDuration); err != nil {
+			log.Warn().Err(err).Msg("Failed to block likely bot")
+		} else if count.Val() == int64(ts.filter.MaxEventsPerMinute)+1 {
+			log.Info().Str("reader", reader).Dur("blocked_for", ts.filter.BotBlockDuration).Msg("Blocked events of likely bot")
+		}
+		return &IgnoredError{Reason: IgnoredBot}
+	case first != nil && !first.Val():
+		return &IgnoredError{Reason: IgnoredDuplicate}
+	}
+	return nil
+}
+
+// likelyBot reports whether userAgent names a crawler, script or headless
+// browser. Events without one, e.g. from queues, pass.
+func likelyBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range botAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventReader identifies the reader of an event for filtering: by user ID,
+// else by client IP, else "" when it can't be told apart
+func eventReader(req EventRequest) string {
+	switch {
+	case req.UserID != "":
+		return "user:" + req.UserID
+	case req.ClientIP != "":
+		return "ip:" + req.ClientIP
+	}
+	return ""
+}
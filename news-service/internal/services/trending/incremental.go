@@ -0,0 +1,346 @@
+package trending
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// incrementalOverlap is how far before the last update incremental updates
+// read events from, for events stored a little after they occurred, e.g.
+// through the event queue. Event IDs keep those already applied from
+// counting twice.
+const incrementalOverlap = time.Minute
+
+// incrementalState is where incremental updates left off, shared by every
+// instance through Redis. It expires with the sorted sets it describes, so
+// the first update after trending has gone stale is a full recompute.
+type incrementalState struct {
+	// LastRunAt is when the scores were last brought up to date, which
+	// they have decayed from since
+	LastRunAt time.Time `json:"last_run_at"`
+	// LastFullAt is when trending was last recomputed in full
+	LastFullAt time.Time `json:"last_full_at"`
+	// LastEventID is the highest ID of the events applied
+	LastEventID int64 `json:"last_event_id"`
+	// EventCount and TileCount carry the metadata of the last full
+	// recompute forward, with the events applied since counted in
+	EventCount int `json:"event_count"`
+	TileCount  int `json:"tile_count"`
+}
+
+// EnableIncrementalUpdates makes each tick apply only the events stored
+// since the last one: the stored scores decay by the time passed, as if
+// recomputed, and the new events' scores are added on. Trending is still
+// recomputed in full every fullInterval, which drops the events that have
+// left a window and refreshes unique reader weights; until then new
+// articles go unweighted. One instance updates trending at a time.
+func (ts *TrendingScorer) EnableIncrementalUpdates(fullInterval time.Duration) {
+	ts.fullInterval = fullInterval
+}
+
+// update brings trending up to date: in full when incremental updates are
+// off or a full recompute is due, else from the events since the last update
+func (ts *TrendingScorer) update(ctx context.Context) error {
+	if ts.fullInterval <= 0 {
+		return ts.computeAllTiles(ctx)
+	}
+
+	token, leased, err := ts.cache.AcquireLease(ctx, cache.TrendingLeaseKey(), cache.TrendingTTL)
+	if err != nil {
+		return fmt.Errorf("failed to take trending lease: %w", err)
+	}
+	if !leased {
+		log.Debug().Msg("Trending is being updated by another instance")
+		return nil
+	}
+	defer ts.cache.ReleaseLease(context.WithoutCancel(ctx), cache.TrendingLeaseKey(), token)
+
+	now := time.Now()
+	state, ok := ts.loadState(ctx)
+	if !ok || now.Sub(state.LastFullAt) >= ts.fullInterval {
+		return ts.computeAllTiles(ctx)
+	}
+	return ts.applyNewEvents(ctx, state, now)
+}
+
+// loadState reads where incremental updates left off, reporting false when
+// there is no usable state and trending must be recomputed in full
+func (ts *TrendingScorer) loadState(ctx context.Context) (incrementalState, bool) {
+	data, err := ts.cache.Get(ctx, cache.TrendingStateKey())
+	if err != nil {
+		if !errors.Is(err, cache.ErrKeyNotFound) {
+			log.Warn().Err(err).Msg("Failed to read incremental trending state, recomputing in full")
+		}
+		return incrementalState{}, false
+	}
+	var state incrementalState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Warn().Err(err).Msg("Invalid incremental trending state, recomputing in full")
+		return incrementalState{}, false
+	}
+	return state, true
+}
+
+// saveState records where incremental updates left off
+func (ts *TrendingScorer) saveState(ctx context.Context, state incrementalState) {
+	if err := ts.cache.Set(ctx, cache.TrendingStateKey(), state, cache.TrendingTTL); err != nil {
+		log.Warn().Err(err).Msg("Failed to store incremental trending state")
+	}
+}
+
+// recordFullRecompute records a full recompute from events as of now for
+// the incremental updates that follow it: their starting point, the reader
+// weights they apply, and the sorted sets they decay, dropping those the
+// recompute left unwritten
+func (ts *TrendingScorer) recordFullRecompute(ctx context.Context, now time.Time, events []repo.GetRecentEventsByGeohashRow, weights map[string]float64, meta TrendingMeta) {
+	if ts.fullInterval <= 0 {
+		return
+	}
+
+	state := incrementalState{
+		LastRunAt:  now,
+		LastFullAt: now,
+		EventCount: meta.EventCount,
+		TileCount:  meta.TileCount,
+	}
+	for _, event := range events {
+		if event.ID > state.LastEventID {
+			state.LastEventID = event.ID
+		}
+	}
+
+	if ts.weighByReaders {
+		if err := ts.cache.Set(ctx, cache.TrendingWeightsKey(), weights, ts.fullInterval+cache.TrendingTTL); err != nil {
+			log.Warn().Err(err).Msg("Failed to store trending reader weights")
+		}
+	}
+	for _, window := range Windows {
+		if err := ts.dropStaleSets(ctx, window, now); err != nil {
+			log.Warn().Err(err).Str("window", window.Name).Msg("Failed to drop stale trending keys")
+		}
+	}
+	ts.saveState(ctx, state)
+}
+
+// dropStaleSets deletes the sorted sets over window that were last written
+// before a full recompute at now, which found no events for them
+func (ts *TrendingScorer) dropStaleSets(ctx context.Context, window Window, now time.Time) error {
+	registry := window.key(cache.TrendingSetsKey())
+	stale, err := ts.cache.ZRangeByScore(ctx, registry, 0, float64(now.Unix()-1), 0)
+	if err != nil {
+		return err
+	}
+	if len(stale) > 0 {
+		if err := ts.cache.Del(ctx, stale...); err != nil {
+			return err
+		}
+		members := make([]interface{}, len(stale))
+		for i, key := range stale {
+			members[i] = key
+		}
+		if err := ts.cache.ZRem(ctx, registry, members...); err != nil {
+			return err
+		}
+	}
+	// The registry outlives the keys it lists by at most a full interval
+	// once incremental updates stop
+	return ts.cache.Expire(ctx, registry, ts.fullInterval+cache.TrendingTTL)
+}
+
+// storeScores replaces the sorted set at key, which holds trending data over
+// window, with members. With incremental updates on, the key is registered
+// for them to decay.
+func (ts *TrendingScorer) storeScores(ctx context.Context, window Window, key string, members []redis.Z) error {
+	if err := ts.cache.ReplaceSortedSet(ctx, key, members, cache.TrendingTTL); err != nil {
+		return err
+	}
+	if ts.fullInterval > 0 && len(members) > 0 {
+		registry := window.key(cache.TrendingSetsKey())
+		if err := ts.cache.ZAdd(ctx, registry, redis.Z{Score: float64(time.Now().Unix()), Member: key}); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to register trending key for incremental updates")
+		}
+	}
+	return nil
+}
+
+// applyNewEvents brings trending up to date as of now from the events
+// stored since the update state describes
+func (ts *TrendingScorer) applyNewEvents(ctx context.Context, state incrementalState, now time.Time) error {
+	start := time.Now()
+
+	recent, err := ts.repo.GetRecentEventsByGeohash(ctx, state.LastRunAt.Add(-incrementalOverlap))
+	if err != nil {
+		return fmt.Errorf("failed to get new events: %w", err)
+	}
+	// Hourly rollups have no ID and are long past any update
+	var events []repo.GetRecentEventsByGeohashRow
+	lastEventID := state.LastEventID
+	for _, event := range recent {
+		if event.ID > state.LastEventID {
+			events = append(events, event)
+			if event.ID > lastEventID {
+				lastEventID = event.ID
+			}
+		}
+	}
+
+	articles := ts.eventArticles(ctx, events)
+	weights := ts.fullRecomputeWeights(ctx)
+	elapsed := now.Sub(state.LastRunAt)
+	for _, window := range Windows {
+		if err := ts.updateWindow(ctx, window, elapsed, events, articles, weights, now); err != nil {
+			// Some sets may have decayed or taken the events already, so
+			// only a full recompute can tell where they stand
+			ts.cache.Del(ctx, cache.TrendingStateKey())
+			return fmt.Errorf("failed to update %s trending: %w", window.Name, err)
+		}
+	}
+
+	var eventTotal int64
+	for _, event := range events {
+		eventTotal += eventCount(event)
+	}
+	state.LastRunAt = now
+	state.LastEventID = lastEventID
+	state.EventCount += int(eventTotal)
+	ts.saveState(ctx, state)
+	ts.publishMeta(ctx, TrendingMeta{
+		LastComputedAt: now,
+		EventCount:     state.EventCount,
+		TileCount:      state.TileCount,
+	})
+
+	metrics.TrendingComputeDuration.WithLabelValues("incremental").Observe(time.Since(start).Seconds())
+	metrics.TrendingEvents.WithLabelValues("incremental").Add(float64(eventTotal))
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int64("events", eventTotal).
+		Dur("since_full", now.Sub(state.LastFullAt)).
+		Msg("Completed incremental trending update")
+
+	return nil
+}
+
+// updateWindow decays the sorted sets over window by elapsed and adds the
+// scores of events to them. Tiles are trimmed back to their top tileKeyLimit
+// articles, as many as a full recompute serves from them.
+func (ts *TrendingScorer) updateWindow(ctx context.Context, window Window, elapsed time.Duration, events []repo.GetRecentEventsByGeohashRow, articles map[string]repo.Article, weights map[string]float64, now time.Time) error {
+	registry := window.key(cache.TrendingSetsKey())
+	registered, err := ts.cache.ZRangeWithScores(ctx, registry, 0, -1)
+	if err != nil {
+		return fmt.Errorf("failed to read trending keys: %w", err)
+	}
+	keys := make([]string, 0, len(registered))
+	for _, z := range registered {
+		if key, ok := z.Member.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	// Scores decay exponentially with event age, so decaying every stored
+	// score alike ages the events behind it by elapsed
+	factor := math.Exp(-elapsed.Hours() / window.decay().Hours())
+	if err := ts.cache.ScaleSortedSets(ctx, keys, factor, cache.TrendingTTL); err != nil {
+		return err
+	}
+
+	scores, tiles := ts.eventScores(window, events, articles, weights)
+	return ts.cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, members := range scores {
+			for member, score := range members {
+				pipe.ZIncrBy(ctx, key, score, member)
+			}
+			if tiles[key] {
+				pipe.ZRemRangeByRank(ctx, key, 0, -tileKeyLimit-1)
+			}
+			pipe.Expire(ctx, key, cache.TrendingTTL)
+			pipe.ZAdd(ctx, registry, redis.Z{Score: float64(now.Unix()), Member: key})
+		}
+		pipe.Expire(ctx, registry, ts.fullInterval+cache.TrendingTTL)
+		return nil
+	})
+}
+
+// eventScores returns, per sorted set key, the scores events add to its
+// members over window, as computeTileScore, computeGlobalScores and
+// computePlaceScores score them, and which of the keys are tiles
+func (ts *TrendingScorer) eventScores(window Window, events []repo.GetRecentEventsByGeohashRow, articles map[string]repo.Article, weights map[string]float64) (map[string]map[string]float64, map[string]bool) {
+	scores := make(map[string]map[string]float64)
+	tiles := make(map[string]bool)
+	add := func(key, member string, score float64) {
+		if scores[key] == nil {
+			scores[key] = make(map[string]float64)
+		}
+		scores[key][member] += score
+	}
+
+	for _, event := range events {
+		weight := 1.0
+		if w, ok := weights[event.ArticleID]; ok {
+			weight = w
+		}
+		article := articles[event.ArticleID]
+
+		global := globalEventScore(event, window.decay()) * weight
+		add(window.key(cache.GlobalTrendingKey("")), event.ArticleID, global)
+		for _, category := range article.Category {
+			add(window.key(cache.GlobalTrendingTopicsKey()), category, global)
+			if normalized := NormalizeCategory(category); normalized != "" {
+				add(window.key(cache.GlobalTrendingKey(normalized)), event.ArticleID, global)
+			}
+		}
+
+		local := ts.calculateEventScore(event, window.decay()) * weight
+		if window == DefaultWindow && article.Country != "" {
+			add(cache.PlaceTrendingKey(article.Country, ""), event.ArticleID, local)
+			if article.Region != "" {
+				add(cache.PlaceTrendingKey(article.Country, article.Region), event.ArticleID, local)
+			}
+		}
+
+		if event.UserLat == nil || event.UserLon == nil {
+			continue
+		}
+		for _, precision := range ts.precisions {
+			geohash := cache.GenerateGeohash(*event.UserLat, *event.UserLon, precision)
+			tile := window.key(cache.TrendingKey(geohash, tileKeyLimit))
+			tiles[tile] = true
+			add(tile, event.ArticleID, local)
+			for _, category := range article.Category {
+				add(window.key(cache.TrendingTopicsKey(geohash)), category, local)
+				if normalized := NormalizeCategory(category); normalized != "" {
+					add(window.key(cache.CategoryTrendingKey(geohash, normalized)), event.ArticleID, local)
+				}
+			}
+		}
+	}
+	return scores, tiles
+}
+
+// fullRecomputeWeights returns the unique reader weights of the last full
+// recompute, or nil when unique reader weighting is off or they're gone
+func (ts *TrendingScorer) fullRecomputeWeights(ctx context.Context) map[string]float64 {
+	if !ts.weighByReaders {
+		return nil
+	}
+	data, err := ts.cache.Get(ctx, cache.TrendingWeightsKey())
+	if err != nil {
+		return nil
+	}
+	var weights map[string]float64
+	if err := json.Unmarshal(data, &weights); err != nil {
+		log.Debug().Err(err).Msg("Invalid trending reader weights, applying new events unweighted")
+		return nil
+	}
+	return weights
+}
@@ -0,0 +1,174 @@
+package trending
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"news-system/internal/cache"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// EnableNeighborTiles makes trending lookups blend in the 8 tiles around the
+// location's tile, weighted by how close the location is to each, so a
+// reader near a tile boundary sees what trends just across it too
+func (ts *TrendingScorer) EnableNeighborTiles() {
+	ts.neighbors = true
+}
+
+// GetNearbyTrendingScores retrieves the trending scores of the tile geohash
+// over window, with those of its neighbouring tiles blended in by distance
+// from lat/lon when neighbour tiles are enabled
+func (ts *TrendingScorer) GetNearbyTrendingScores(ctx context.Context, window Window, lat, lon float64, geohash string, limit int) ([]TrendingScore, error) {
+	if !ts.neighbors {
+		return ts.GetTrendingScores(ctx, window, geohash, limit)
+	}
+
+	merged, err := ts.mergeNeighborhood(ctx, lat, lon, geohash, func(tile string) string {
+		return window.key(cache.TrendingKey(tile, tileKeyLimit))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]TrendingScore, 0, limit)
+	for _, z := range topMembers(merged, limit) {
+		scores = append(scores, TrendingScore{ArticleID: z.Member.(string), Score: z.Score})
+	}
+	return scores, nil
+}
+
+// GetNearbyCategoryTrendingScores retrieves the trending scores of the
+// articles of category in the tile geohash, blended with its neighbours
+// like GetNearbyTrendingScores
+func (ts *TrendingScorer) GetNearbyCategoryTrendingScores(ctx context.Context, window Window, lat, lon float64, geohash, category string, limit int) ([]TrendingScore, error) {
+	if !ts.neighbors {
+		return ts.GetCategoryTrendingScores(ctx, window, geohash, category, limit)
+	}
+
+	merged, err := ts.mergeNeighborhood(ctx, lat, lon, geohash, func(tile string) string {
+		return window.key(cache.CategoryTrendingKey(tile, category))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]TrendingScore, 0, limit)
+	for _, z := range topMembers(merged, limit) {
+		scores = append(scores, TrendingScore{ArticleID: z.Member.(string), Score: z.Score})
+	}
+	return scores, nil
+}
+
+// GetNearbyTrendingTopics retrieves the trending categories of the tile
+// geohash, blended with its neighbours like GetNearbyTrendingScores
+func (ts *TrendingScorer) GetNearbyTrendingTopics(ctx context.Context, window Window, lat, lon float64, geohash string, limit int) ([]TrendingTopic, error) {
+	if !ts.neighbors {
+		return ts.GetTrendingTopics(ctx, window, geohash, limit)
+	}
+
+	merged, err := ts.mergeNeighborhood(ctx, lat, lon, geohash, func(tile string) string {
+		return window.key(cache.TrendingTopicsKey(tile))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make([]TrendingTopic, 0, limit)
+	for _, z := range topMembers(merged, limit) {
+		topics = append(topics, TrendingTopic{Category: z.Member.(string), Score: z.Score})
+	}
+	return topics, nil
+}
+
+// mergeNeighborhood sums the top members of the tile geohash and of its
+// neighbours, each neighbour's scores scaled by neighborWeight. The tile's
+// own read must succeed; a neighbour that can't be read is left out.
+func (ts *TrendingScorer) mergeNeighborhood(ctx context.Context, lat, lon float64, geohash string, key func(tile string) string) (map[string]float64, error) {
+	neighbors, err := cache.GeohashNeighbors(geohash)
+	if err != nil {
+		return nil, err
+	}
+
+	tiles := append([]string{geohash}, neighbors...)
+	weights := make([]float64, len(tiles))
+	weights[0] = 1
+	for i, tile := range neighbors {
+		weights[i+1] = neighborWeight(lat, lon, tile)
+	}
+
+	results := make([][]redis.Z, len(tiles))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, tile := range tiles {
+		if weights[i] == 0 {
+			continue
+		}
+		g.Go(func() error {
+			members, err := ts.cache.ZRevRangeWithScoresHedged(gctx, key(tile), 0, tileKeyLimit-1)
+			if err != nil {
+				if i == 0 {
+					return err
+				}
+				log.Warn().Err(err).Str("geohash", tile).Msg("Failed to read neighbouring trending tile")
+				return nil
+			}
+			results[i] = members
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]float64)
+	for i, members := range results {
+		for _, z := range members {
+			if member, ok := z.Member.(string); ok {
+				merged[member] += z.Score * weights[i]
+			}
+		}
+	}
+	return merged, nil
+}
+
+// neighborWeight is how much a neighbouring tile counts for a reader at
+// lat/lon: 1 at its edge, falling linearly to 0 one tile width away. It is
+// measured in tile widths and heights, so the weight doesn't depend on the
+// precision or, for odd precisions, on the tiles being twice as wide as high.
+func neighborWeight(lat, lon float64, tile string) float64 {
+	minLat, minLon, maxLat, maxLon, err := cache.GeohashBoundingBox(tile)
+	if err != nil {
+		return 0
+	}
+
+	dLat := math.Max(0, math.Max(minLat-lat, lat-maxLat)) / (maxLat - minLat)
+	// Across the antimeridian the neighbour lies a full turn away
+	dLon := math.Max(0, math.Max(minLon-lon, lon-maxLon))
+	if dLon > 180 {
+		dLon = math.Max(0, 360-dLon-(maxLon-minLon))
+	}
+	dLon /= maxLon - minLon
+
+	return math.Max(0, 1-math.Hypot(dLat, dLon))
+}
+
+// topMembers returns the limit highest scored members, ties broken by member
+func topMembers(scores map[string]float64, limit int) []redis.Z {
+	members := make([]redis.Z, 0, len(scores))
+	for member, score := range scores {
+		members = append(members, redis.Z{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score > members[j].Score
+		}
+		return members[i].Member.(string) < members[j].Member.(string)
+	})
+	if len(members) > limit {
+		members = members[:limit]
+	}
+	return members
+}
@@ -0,0 +1,142 @@
+package trending
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"news-system/internal/cache"
+	"news-system/internal/repo"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// NormalizeCategory returns the form categories are keyed by in trending:
+// trimmed and lower-cased, like the category index
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// addCategoryScore adds score to articleID in the scores of category
+func addCategoryScore(scores map[string]map[string]float64, category, articleID string, score float64) {
+	category = NormalizeCategory(category)
+	if category == "" {
+		return
+	}
+	if scores[category] == nil {
+		scores[category] = make(map[string]float64)
+	}
+	scores[category][articleID] += score
+}
+
+// storeCategoryScores replaces the trending articles of every category in
+// scores over window, stored under key(category). Categories that stop
+// trending expire with cache.TrendingTTL.
+func (ts *TrendingScorer) storeCategoryScores(ctx context.Context, window Window, scores map[string]map[string]float64, key func(category string) string) error {
+	for category, articleScores := range scores {
+		if err := ts.storeScores(ctx, window, key(category), sortedMembers(articleScores)); err != nil {
+			return fmt.Errorf("failed to store %s trending scores: %w", category, err)
+		}
+	}
+	return nil
+}
+
+// computeGlobalScores stores the articles and categories trending
+// everywhere over window, from every event including those without a
+// location. Events score by type and age only, as there is no reader
+// location to decay by.
+func (ts *TrendingScorer) computeGlobalScores(ctx context.Context, window Window, events []repo.GetRecentEventsByGeohashRow, articles map[string]repo.Article, weights map[string]float64) {
+	articleScores := make(map[string]float64)
+	topicScores := make(map[string]float64)
+	categoryScores := make(map[string]map[string]float64)
+	for _, event := range events {
+		score := globalEventScore(event, window.decay())
+		if weight, ok := weights[event.ArticleID]; ok {
+			score *= weight
+		}
+		articleScores[event.ArticleID] += score
+		for _, category := range articles[event.ArticleID].Category {
+			topicScores[category] += score
+			addCategoryScore(categoryScores, category, event.ArticleID, score)
+		}
+	}
+
+	if err := ts.storeScores(ctx, window, window.key(cache.GlobalTrendingKey("")), sortedMembers(articleScores)); err != nil {
+		log.Warn().Err(err).Msg("Failed to store global trending scores")
+		return
+	}
+	if err := ts.storeScores(ctx, window, window.key(cache.GlobalTrendingTopicsKey()), sortedMembers(topicScores)); err != nil {
+		log.Warn().Err(err).Msg("Failed to store global trending topics")
+	}
+	if err := ts.storeCategoryScores(ctx, window, categoryScores, func(category string) string {
+		return window.key(cache.GlobalTrendingKey(category))
+	}); err != nil {
+		log.Warn().Err(err).Msg("Failed to store global category trending scores")
+	}
+}
+
+// sortedMembers turns scores into sorted set members
+func sortedMembers(scores map[string]float64) []redis.Z {
+	members := make([]redis.Z, 0, len(scores))
+	for member, score := range scores {
+		members = append(members, redis.Z{Score: score, Member: member})
+	}
+	return members
+}
+
+// GetCategoryTrendingScores retrieves the trending scores of the articles of
+// category, as normalized by NormalizeCategory, in a geohash tile over window
+func (ts *TrendingScorer) GetCategoryTrendingScores(ctx context.Context, window Window, geohash, category string, limit int) ([]TrendingScore, error) {
+	return ts.readScores(ctx, window.key(cache.CategoryTrendingKey(geohash, category)), limit)
+}
+
+// GetGlobalTrendingScores retrieves the trending scores of the articles
+// trending everywhere over window, or of those of category when it isn't ""
+func (ts *TrendingScorer) GetGlobalTrendingScores(ctx context.Context, window Window, category string, limit int) ([]TrendingScore, error) {
+	return ts.readScores(ctx, window.key(cache.GlobalTrendingKey(category)), limit)
+}
+
+// GetGlobalTrendingTopics retrieves the top categories trending everywhere over window
+func (ts *TrendingScorer) GetGlobalTrendingTopics(ctx context.Context, window Window, limit int) ([]TrendingTopic, error) {
+	scores, err := ts.cache.ZRevRangeWithScoresHedged(ctx, window.key(cache.GlobalTrendingTopicsKey()), 0, int64(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global trending topics: %w", err)
+	}
+	topics := make([]TrendingTopic, 0, len(scores))
+	for _, score := range scores {
+		if category, ok := score.Member.(string); ok {
+			topics = append(topics, TrendingTopic{Category: category, Score: score.Score})
+		}
+	}
+	return topics, nil
+}
+
+// ResolveCategoryTile picks the tile to serve a category's trending articles
+// for a location, like ResolveTile but by the tiles that have that category
+func (ts *TrendingScorer) ResolveCategoryTile(ctx context.Context, window Window, lat, lon float64, category string) (string, int) {
+	for _, precision := range ts.precisions {
+		geohash := cache.GenerateGeohash(lat, lon, precision)
+		if exists, err := ts.cache.Exists(ctx, window.key(cache.CategoryTrendingKey(geohash, category))); err == nil && exists {
+			return geohash, precision
+		}
+	}
+
+	coarsest := ts.precisions[len(ts.precisions)-1]
+	return cache.GenerateGeohash(lat, lon, coarsest), coarsest
+}
+
+// readScores reads the top limit members of a trending sorted set
+func (ts *TrendingScorer) readScores(ctx context.Context, key string, limit int) ([]TrendingScore, error) {
+	scores, err := ts.cache.ZRevRangeWithScoresHedged(ctx, key, 0, int64(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending scores: %w", err)
+	}
+	trendingScores := make([]TrendingScore, 0, len(scores))
+	for _, score := range scores {
+		if articleID, ok := score.Member.(string); ok {
+			trendingScores = append(trendingScores, TrendingScore{ArticleID: articleID, Score: score.Score})
+		}
+	}
+	return trendingScores, nil
+}
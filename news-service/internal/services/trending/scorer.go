@@ -9,18 +9,39 @@ import (
 	"sort"
 	"time"
 
+	"news-system/internal/bus"
 	"news-system/internal/cache"
+	"news-system/internal/metrics"
 	"news-system/internal/repo"
+	"news-system/internal/services/readers"
 
 	"github.com/go-redis/redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
+// DefaultPrecision is the geohash precision used when none is configured (~5km tiles)
+const DefaultPrecision = 5
+
+// tileKeyLimit is the limit component of the ZSET key each tile is stored under
+const tileKeyLimit = 50
+
 type TrendingScorer struct {
-	repo   repo.Repository
-	cache  *cache.RedisCache
-	ticker *time.Ticker
-	done   chan bool
+	repo       repo.Repository
+	cache      *cache.RedisCache
+	events     *bus.Bus
+	precisions []int
+	readers    *readers.Counter
+	// weighByReaders scales article scores by distinct readers per event
+	weighByReaders bool
+	// neighbors blends the tiles around a location's tile into lookups
+	neighbors      bool
+	// filter drops duplicate events and those of likely bots; nil keeps all
+	filter         *EventFilterOptions
+	// fullInterval is how often trending is recomputed in full between
+	// incremental updates; 0 recomputes it in full every tick
+	fullInterval   time.Duration
+	ticker         *time.Ticker
+	done           chan bool
 }
 
 type TrendingScore struct {
@@ -28,20 +49,56 @@ type TrendingScore struct {
 	Score     float64 `json:"score"`
 }
 
+// TrendingTopic is a category aggregated over the trending events of a tile
+type TrendingTopic struct {
+	Category string  `json:"category"`
+	Score    float64 `json:"score"`
+}
+
 type TrendingMeta struct {
 	LastComputedAt time.Time `json:"last_computed_at"`
 	EventCount     int       `json:"event_count"`
 	TileCount      int       `json:"tile_count"`
 }
 
-func NewTrendingScorer(repo repo.Repository, cache *cache.RedisCache) *TrendingScorer {
+// NewTrendingScorer creates a scorer that maintains tiles at every given geohash
+// precision, e.g. 4 for sparse rural areas and 6 for dense urban ones.
+func NewTrendingScorer(repo repo.Repository, cache *cache.RedisCache, events *bus.Bus, precisions []int, readers *readers.Counter) *TrendingScorer {
+	var valid []int
+	for _, p := range precisions {
+		if p >= 1 && p <= 12 {
+			valid = append(valid, p)
+		}
+	}
+	if len(valid) == 0 {
+		valid = []int{DefaultPrecision}
+	}
+	// Finest precision first so lookups prefer the most local tile
+	sort.Sort(sort.Reverse(sort.IntSlice(valid)))
+
 	return &TrendingScorer{
-		repo:  repo,
-		cache: cache,
-		done:  make(chan bool),
+		repo:       repo,
+		cache:      cache,
+		events:     events,
+		precisions: valid,
+		readers:    readers,
+		done:       make(chan bool),
 	}
 }
 
+// EnableUniqueReaderWeighting makes articles trend by how many distinct users
+// read them rather than by raw event volume, so one user repeatedly viewing an
+// article can't push it up. Only enable it once events carry user IDs (see
+// RecordEvent); articles whose events have none are left unweighted.
+func (ts *TrendingScorer) EnableUniqueReaderWeighting() {
+	ts.weighByReaders = true
+}
+
+// Precisions returns the configured tile precisions, finest first
+func (ts *TrendingScorer) Precisions() []int {
+	return ts.precisions
+}
+
 // Start begins the background trending computation
 func (ts *TrendingScorer) Start(ctx context.Context, interval time.Duration) {
 	ts.ticker = time.NewTicker(interval)
@@ -50,7 +107,7 @@ func (ts *TrendingScorer) Start(ctx context.Context, interval time.Duration) {
 		for {
 			select {
 			case <-ts.ticker.C:
-				if err := ts.computeAllTiles(ctx); err != nil {
+				if err := ts.update(ctx); err != nil {
 					log.Error().Err(err).Msg("Failed to compute trending tiles")
 				}
 			case <-ts.done:
@@ -64,6 +121,22 @@ func (ts *TrendingScorer) Start(ctx context.Context, interval time.Duration) {
 	log.Info().Dur("interval", interval).Msg("Trending scorer started")
 }
 
+// WarmUp rebuilds every trending tile from the durable event store. It is meant
+// to run once at startup, before /trending is served, so a restart doesn't leave
+// the ZSETs empty until the first tick.
+func (ts *TrendingScorer) WarmUp(ctx context.Context, timeout time.Duration) error {
+	warmCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := ts.update(warmCtx); err != nil {
+		return fmt.Errorf("failed to warm up trending tiles: %w", err)
+	}
+
+	log.Info().Dur("duration", time.Since(start)).Msg("Trending warm-up completed")
+	return nil
+}
+
 // Stop stops the background trending computation
 func (ts *TrendingScorer) Stop() {
 	if ts.ticker != nil {
@@ -77,8 +150,9 @@ func (ts *TrendingScorer) Stop() {
 func (ts *TrendingScorer) computeAllTiles(ctx context.Context) error {
 	start := time.Now()
 	
-	// Get recent events (last 24 hours)
-	since := time.Now().Add(-24 * time.Hour)
+	// Get recent events over the longest window; shorter windows narrow them
+	now := time.Now()
+	since := now.Add(-Windows[len(Windows)-1].Duration)
 	events, err := ts.repo.GetRecentEventsByGeohash(ctx, since)
 	if err != nil {
 		return fmt.Errorf("failed to get recent events: %w", err)
@@ -86,44 +160,72 @@ func (ts *TrendingScorer) computeAllTiles(ctx context.Context) error {
 	
 	if len(events) == 0 {
 		log.Info().Msg("No recent events to compute trending scores")
+		ts.recordFullRecompute(ctx, now, events, nil, TrendingMeta{LastComputedAt: now})
+		metrics.TrendingComputeDuration.WithLabelValues("full").Observe(time.Since(start).Seconds())
 		return nil
 	}
 	
-	// Group events by geohash tiles
-	tileEvents := ts.groupEventsByTile(events)
+	// Resolve the articles and reader weights once for all tiles and windows
+	articles := ts.eventArticles(ctx, events)
+	weights := ts.readerWeights(ctx, events)
 	
-	// Compute scores for each tile
+	// Compute scores for each tile of each window, counting the default window's tiles
 	tileCount := 0
-	for geohash, tileEventList := range tileEvents {
-		if err := ts.computeTileScore(ctx, geohash, tileEventList); err != nil {
-			log.Warn().Err(err).Str("geohash", geohash).Msg("Failed to compute tile score")
-			continue
+	for _, window := range Windows {
+		windowed := windowEvents(events, window, now)
+		for geohash, tileEventList := range ts.groupEventsByTile(windowed) {
+			if err := ts.computeTileScore(ctx, window, geohash, tileEventList, articles, weights); err != nil {
+				log.Warn().Err(err).Str("geohash", geohash).Str("window", window.Name).Msg("Failed to compute tile score")
+				continue
+			}
+			if window == DefaultWindow {
+				tileCount++
+			}
 		}
-		tileCount++
+		ts.computeGlobalScores(ctx, window, windowed, articles, weights)
 	}
+	ts.computePlaceScores(ctx, windowEvents(events, DefaultWindow, now), articles, weights)
 	
 	// Update global trending metadata
+	var eventTotal int64
+	for _, event := range events {
+		eventTotal += eventCount(event)
+	}
 	meta := TrendingMeta{
 		LastComputedAt: time.Now(),
-		EventCount:     len(events),
+		EventCount:     int(eventTotal),
 		TileCount:      tileCount,
 	}
+	ts.publishMeta(ctx, meta)
+	ts.recordFullRecompute(ctx, now, events, weights, meta)
 	
-	globalMetaKey := "news:trending:global:meta"
-	if data, err := json.Marshal(meta); err == nil {
-		ts.cache.Set(ctx, globalMetaKey, data, cache.TrendingTTL)
-	}
-	
+	metrics.TrendingComputeDuration.WithLabelValues("full").Observe(time.Since(start).Seconds())
+	metrics.TrendingEvents.WithLabelValues("full").Add(float64(eventTotal))
 	log.Info().
 		Dur("duration", time.Since(start)).
-		Int("events", len(events)).
+		Int64("events", eventTotal).
 		Int("tiles", tileCount).
 		Msg("Completed trending computation")
 	
 	return nil
 }
 
-// groupEventsByTile groups events by their geohash tile
+// publishMeta stores the global trending metadata and tells subscribers
+// trending has changed
+func (ts *TrendingScorer) publishMeta(ctx context.Context, meta TrendingMeta) {
+	globalMetaKey := "news:trending:global:meta"
+	if data, err := json.Marshal(meta); err == nil {
+		ts.cache.Set(ctx, globalMetaKey, data, cache.TrendingTTL)
+	}
+
+	ts.events.Emit(bus.TrendingRecomputed, bus.TrendingPayload{
+		Tiles:      meta.TileCount,
+		Events:     meta.EventCount,
+		Precisions: ts.precisions,
+	})
+}
+
+// groupEventsByTile groups events by their geohash tile at every configured precision
 func (ts *TrendingScorer) groupEventsByTile(events []repo.GetRecentEventsByGeohashRow) map[string][]repo.GetRecentEventsByGeohashRow {
 	tileEvents := make(map[string][]repo.GetRecentEventsByGeohashRow)
 	
@@ -132,26 +234,86 @@ func (ts *TrendingScorer) groupEventsByTile(events []repo.GetRecentEventsByGeoha
 			continue
 		}
 		
-		// Generate geohash for user location (precision 5)
-		geohash := cache.GenerateGeohash(*event.UserLat, *event.UserLon, 5)
-		tileEvents[geohash] = append(tileEvents[geohash], event)
+		// The same event contributes to one tile per precision
+		for _, precision := range ts.precisions {
+			geohash := cache.GenerateGeohash(*event.UserLat, *event.UserLon, precision)
+			tileEvents[geohash] = append(tileEvents[geohash], event)
+		}
 	}
 	
 	return tileEvents
 }
 
-// computeTileScore computes trending score for a specific geohash tile
-func (ts *TrendingScorer) computeTileScore(ctx context.Context, geohash string, events []repo.GetRecentEventsByGeohashRow) error {
+// eventArticles looks up every article referenced by the events, for their
+// categories and places; articles that can't be found are left zero
+func (ts *TrendingScorer) eventArticles(ctx context.Context, events []repo.GetRecentEventsByGeohashRow) map[string]repo.Article {
+	articles := make(map[string]repo.Article)
+	for _, event := range events {
+		if _, seen := articles[event.ArticleID]; seen {
+			continue
+		}
+		article, err := ts.repo.GetArticleByID(ctx, event.ArticleID)
+		if err != nil {
+			log.Debug().Err(err).Str("article_id", event.ArticleID).Msg("Failed to look up trending article")
+		}
+		articles[event.ArticleID] = article
+	}
+	return articles
+}
+
+// readerWeights returns, per article, its distinct readers per event over the
+// trending window, or nil when unique reader weighting is off. Articles with
+// no counted readers are left out and keep a weight of 1.
+func (ts *TrendingScorer) readerWeights(ctx context.Context, events []repo.GetRecentEventsByGeohashRow) map[string]float64 {
+	if !ts.weighByReaders || ts.readers == nil {
+		return nil
+	}
+
+	eventCounts := make(map[string]int64)
+	for _, event := range events {
+		eventCounts[event.ArticleID] += eventCount(event)
+	}
+	articleIDs := make([]string, 0, len(eventCounts))
+	for articleID := range eventCounts {
+		articleIDs = append(articleIDs, articleID)
+	}
+
+	counts, err := ts.readers.ArticleReadersMany(ctx, articleIDs)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to count unique readers, trending by event volume")
+		return nil
+	}
+
+	weights := make(map[string]float64, len(counts))
+	for articleID, readerCount := range counts {
+		if readerCount > 0 {
+			weights[articleID] = math.Min(1, float64(readerCount)/float64(eventCounts[articleID]))
+		}
+	}
+	return weights
+}
+
+// computeTileScore computes trending article and topic scores for a specific geohash tile over window
+func (ts *TrendingScorer) computeTileScore(ctx context.Context, window Window, geohash string, events []repo.GetRecentEventsByGeohashRow, articles map[string]repo.Article, weights map[string]float64) error {
 	if len(events) == 0 {
 		return nil
 	}
 
-	// Calculate trending scores for articles in this tile
+	// Calculate trending scores for articles and categories in this tile
 	articleScores := make(map[string]float64)
+	topicScores := make(map[string]float64)
+	categoryScores := make(map[string]map[string]float64)
 	
 	for _, event := range events {
-		score := ts.calculateEventScore(event)
+		score := ts.calculateEventScore(event, window.decay())
+		if weight, ok := weights[event.ArticleID]; ok {
+			score *= weight
+		}
 		articleScores[event.ArticleID] += score
+		for _, category := range articles[event.ArticleID].Category {
+			topicScores[category] += score
+			addCategoryScore(categoryScores, category, event.ArticleID, score)
+		}
 	}
 
 	// Convert to sorted list
@@ -167,35 +329,108 @@ func (ts *TrendingScorer) computeTileScore(ctx context.Context, geohash string,
 	sort.Slice(trendingScores, func(i, j int) bool {
 		return trendingScores[i].Score > trendingScores[j].Score
 	})
+	// Readers take at most tileKeyLimit articles from a tile
+	if len(trendingScores) > tileKeyLimit {
+		trendingScores = trendingScores[:tileKeyLimit]
+	}
 
 	// Store in Redis ZSET
-	trendingKey := cache.TrendingKey(geohash, 50) // Use default limit
-	
-	// Clear existing scores
-	ts.cache.Del(ctx, trendingKey)
-	
-	// Add new scores
+	trendingKey := window.key(cache.TrendingKey(geohash, tileKeyLimit))
+
+	members := make([]redis.Z, 0, len(trendingScores))
 	for _, trendingScore := range trendingScores {
-		ts.cache.ZAdd(ctx, trendingKey, redis.Z{
+		members = append(members, redis.Z{
 			Score:  trendingScore.Score,
 			Member: trendingScore.ArticleID,
 		})
 	}
-	
-	// Set TTL
-	ts.cache.Expire(ctx, trendingKey, cache.TrendingTTL)
-	
+
+	// Build the new snapshot aside and swap it in so readers never see a partial tile
+	if err := ts.storeScores(ctx, window, trendingKey, members); err != nil {
+		return err
+	}
+
+	topics := make([]redis.Z, 0, len(topicScores))
+	for category, score := range topicScores {
+		topics = append(topics, redis.Z{
+			Score:  score,
+			Member: category,
+		})
+	}
+	if err := ts.storeScores(ctx, window, window.key(cache.TrendingTopicsKey(geohash)), topics); err != nil {
+		return err
+	}
+	if err := ts.storeCategoryScores(ctx, window, categoryScores, func(category string) string {
+		return window.key(cache.CategoryTrendingKey(geohash, category))
+	}); err != nil {
+		return err
+	}
+
 	log.Info().
 		Str("geohash", geohash).
+		Int("precision", len(geohash)).
+		Str("window", window.Name).
 		Int("events", len(events)).
 		Int("articles", len(trendingScores)).
+		Int("topics", len(topics)).
 		Msg("Computed trending scores for tile")
 
 	return nil
 }
 
-// calculateEventScore calculates the trending score for a single event
-func (ts *TrendingScorer) calculateEventScore(event repo.GetRecentEventsByGeohashRow) float64 {
+// computePlaceScores stores the trending articles of every country and
+// region that the events' articles are placed in, so places can be served to
+// clients without a precise location. Articles without a place are left out.
+func (ts *TrendingScorer) computePlaceScores(ctx context.Context, events []repo.GetRecentEventsByGeohashRow, articles map[string]repo.Article, weights map[string]float64) {
+	placeScores := make(map[string]map[string]float64)
+	add := func(key, articleID string, score float64) {
+		if placeScores[key] == nil {
+			placeScores[key] = make(map[string]float64)
+		}
+		placeScores[key][articleID] += score
+	}
+	for _, event := range events {
+		article := articles[event.ArticleID]
+		if article.Country == "" {
+			continue
+		}
+		score := ts.calculateEventScore(event, DefaultWindow.decay())
+		if weight, ok := weights[event.ArticleID]; ok {
+			score *= weight
+		}
+		add(cache.PlaceTrendingKey(article.Country, ""), event.ArticleID, score)
+		if article.Region != "" {
+			add(cache.PlaceTrendingKey(article.Country, article.Region), event.ArticleID, score)
+		}
+	}
+
+	for key, scores := range placeScores {
+		members := make([]redis.Z, 0, len(scores))
+		for articleID, score := range scores {
+			members = append(members, redis.Z{Score: score, Member: articleID})
+		}
+		if err := ts.storeScores(ctx, DefaultWindow, key, members); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to store place trending scores")
+		}
+	}
+}
+
+// calculateEventScore calculates the trending score for a single event,
+// decaying with age by the time constant decay
+func (ts *TrendingScorer) calculateEventScore(event repo.GetRecentEventsByGeohashRow, decay time.Duration) float64 {
+	// Geographic decay (if user location and article location available)
+	var geoDecay float64 = 1.0
+	if event.UserLat != nil && event.UserLon != nil && event.Latitude != nil && event.Longitude != nil {
+		distance := ts.haversineDistance(*event.UserLat, *event.UserLon, *event.Latitude, *event.Longitude)
+		geoDecay = 1.0 / (1.0 + distance/10.0) // 10km characteristic distance
+	}
+
+	return globalEventScore(event, decay) * geoDecay
+}
+
+// globalEventScore is the trending score of a single event wherever it
+// happened: its type weight and time decay, with no geographic decay
+func globalEventScore(event repo.GetRecentEventsByGeohashRow, decay time.Duration) float64 {
 	// Event type weight
 	var eventWeight float64
 	switch event.Event {
@@ -207,23 +442,25 @@ func (ts *TrendingScorer) calculateEventScore(event repo.GetRecentEventsByGeohas
 		eventWeight = 1.0
 	}
 	
-	// Time decay (exponential decay with 6-hour half-life)
+	// Time decay (exponential, by a quarter of the window: 6 hours over 24)
 	timeDiff := time.Since(event.OccurredAt)
-	timeDecay := math.Exp(-timeDiff.Hours() / 6.0)
-	
-	// Geographic decay (if user location and article location available)
-	var geoDecay float64 = 1.0
-	if event.UserLat != nil && event.UserLon != nil && event.Latitude != nil && event.Longitude != nil {
-		distance := ts.haversineDistance(*event.UserLat, *event.UserLon, *event.Latitude, *event.Longitude)
-		geoDecay = 1.0 / (1.0 + distance/10.0) // 10km characteristic distance
-	}
+	timeDecay := math.Exp(-timeDiff.Hours() / decay.Hours())
 	
-	// Final score
-	score := eventWeight * timeDecay * geoDecay
+	// Final score, once per event an hourly rollup stands for
+	score := eventWeight * timeDecay * float64(eventCount(event))
 	
 	return score
 }
 
+// eventCount is the number of events a row stands for: 1 for a raw event, or
+// the hour's count for a rollup
+func eventCount(event repo.GetRecentEventsByGeohashRow) int64 {
+	if event.Count < 1 {
+		return 1
+	}
+	return event.Count
+}
+
 // haversineDistance calculates the distance between two points using the Haversine formula
 func (ts *TrendingScorer) haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371 // Earth's radius in kilometers
@@ -284,8 +521,9 @@ func (ts *TrendingScorer) SimulateUserEvents(ctx context.Context) error {
 			eventType = "click"
 		}
 		
-		// Create event
-		_, err := ts.repo.CreateUserEvent(ctx, repo.CreateUserEventParams{
+		// Create event from one of a small pool of simulated users
+		userID := fmt.Sprintf("sim-user-%d", rand.Intn(100))
+		_, err := ts.RecordEvent(ctx, userID, repo.CreateUserEventParams{
 			ArticleID: article.ID,
 			Event:     eventType,
 			UserLat:   &userLat,
@@ -304,12 +542,37 @@ func (ts *TrendingScorer) SimulateUserEvents(ctx context.Context) error {
 	return nil
 }
 
-// GetTrendingScores retrieves trending scores for a geohash tile
-func (ts *TrendingScorer) GetTrendingScores(ctx context.Context, geohash string, limit int) ([]TrendingScore, error) {
-	trendingKey := cache.TrendingKey(geohash, limit)
+// RecordEvent stores a user event and counts the user as a reader of the
+// article and of the tiles around their location. Events without a user ID
+// are stored but not counted as readers.
+func (ts *TrendingScorer) RecordEvent(ctx context.Context, userID string, arg repo.CreateUserEventParams) (repo.UserEvent, error) {
+	event, err := ts.repo.CreateUserEvent(ctx, arg)
+	if err != nil {
+		return repo.UserEvent{}, err
+	}
+
+	if ts.readers != nil {
+		if err := ts.readers.Add(ctx, userID, arg.ArticleID, arg.UserLat, arg.UserLon); err != nil {
+			log.Warn().Err(err).Str("article_id", arg.ArticleID).Msg("Failed to count unique reader")
+		}
+	}
+	return event, nil
+}
+
+// TileReaders returns the approximate number of distinct readers in a geohash tile over the trending window
+func (ts *TrendingScorer) TileReaders(ctx context.Context, geohash string) (int64, error) {
+	if ts.readers == nil {
+		return 0, nil
+	}
+	return ts.readers.TileReaders(ctx, geohash)
+}
+
+// GetTrendingScores retrieves trending scores for a geohash tile over window
+func (ts *TrendingScorer) GetTrendingScores(ctx context.Context, window Window, geohash string, limit int) ([]TrendingScore, error) {
+	trendingKey := window.key(cache.TrendingKey(geohash, tileKeyLimit))
 	
 	// Get top scores from Redis ZSET
-	scores, err := ts.cache.ZRevRangeWithScores(ctx, trendingKey, 0, int64(limit-1))
+	scores, err := ts.cache.ZRevRangeWithScoresHedged(ctx, trendingKey, 0, int64(limit-1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trending scores: %w", err)
 	}
@@ -329,20 +592,87 @@ func (ts *TrendingScorer) GetTrendingScores(ctx context.Context, geohash string,
 	return trendingScores, nil
 }
 
+// GetPlaceTrendingScores retrieves the trending scores of the articles placed
+// in a country, or in one of its regions when region isn't ""
+func (ts *TrendingScorer) GetPlaceTrendingScores(ctx context.Context, country, region string, limit int) ([]TrendingScore, error) {
+	scores, err := ts.cache.ZRevRangeWithScoresHedged(ctx, cache.PlaceTrendingKey(country, region), 0, int64(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get place trending scores: %w", err)
+	}
+
+	trendingScores := make([]TrendingScore, 0, len(scores))
+	for _, score := range scores {
+		articleID, ok := score.Member.(string)
+		if !ok {
+			continue
+		}
+		trendingScores = append(trendingScores, TrendingScore{
+			ArticleID: articleID,
+			Score:     score.Score,
+		})
+	}
+	return trendingScores, nil
+}
+
+// GetTrendingTopics retrieves the top trending categories for a geohash tile over window
+func (ts *TrendingScorer) GetTrendingTopics(ctx context.Context, window Window, geohash string, limit int) ([]TrendingTopic, error) {
+	scores, err := ts.cache.ZRevRangeWithScoresHedged(ctx, window.key(cache.TrendingTopicsKey(geohash)), 0, int64(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending topics: %w", err)
+	}
+
+	topics := make([]TrendingTopic, 0, len(scores))
+	for _, score := range scores {
+		category, ok := score.Member.(string)
+		if !ok {
+			continue
+		}
+		topics = append(topics, TrendingTopic{
+			Category: category,
+			Score:    score.Score,
+		})
+	}
+
+	return topics, nil
+}
+
+// ResolveTile picks the tile to serve for a location: the finest configured
+// precision that currently has trending data over window, falling back to
+// the coarsest one.
+func (ts *TrendingScorer) ResolveTile(ctx context.Context, window Window, lat, lon float64) (string, int) {
+	for _, precision := range ts.precisions {
+		geohash := cache.GenerateGeohash(lat, lon, precision)
+		if exists, err := ts.cache.Exists(ctx, window.key(cache.TrendingKey(geohash, tileKeyLimit))); err == nil && exists {
+			return geohash, precision
+		}
+	}
+
+	coarsest := ts.precisions[len(ts.precisions)-1]
+	return cache.GenerateGeohash(lat, lon, coarsest), coarsest
+}
+
 // ForceRecompute forces recomputation of trending scores for a location
 func (ts *TrendingScorer) ForceRecompute(ctx context.Context, lat, lon float64) error {
-	geohash := cache.GenerateGeohash(lat, lon, 5)
-	
-	// Get recent events for this tile
-	since := time.Now().Add(-24 * time.Hour) // Last 24 hours
+	// Get recent events over the longest window
+	now := time.Now()
+	since := now.Add(-Windows[len(Windows)-1].Duration)
 	events, err := ts.repo.GetRecentEventsByGeohash(ctx, since)
 	if err != nil {
 		return fmt.Errorf("failed to get recent events: %w", err)
 	}
 	
-	// Group events by tile
-	tileEvents := ts.groupEventsByTile(events)
+	articles := ts.eventArticles(ctx, events)
+	weights := ts.readerWeights(ctx, events)
 	
-	// Compute score for this specific tile
-	return ts.computeTileScore(ctx, geohash, tileEvents[geohash])
+	// Compute scores for the tiles covering this location at every precision, in every window
+	for _, window := range Windows {
+		tileEvents := ts.groupEventsByTile(windowEvents(events, window, now))
+		for _, precision := range ts.precisions {
+			geohash := cache.GenerateGeohash(lat, lon, precision)
+			if err := ts.computeTileScore(ctx, window, geohash, tileEvents[geohash], articles, weights); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,66 @@
+package trending
+
+import (
+	"time"
+
+	"news-system/internal/cache"
+	"news-system/internal/repo"
+)
+
+// Window is a horizon trending is computed over: the events of its last
+// Duration, decaying faster the shorter it is
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Windows lists the horizons trending is computed over, shortest first
+var Windows = []Window{
+	{Name: "1h", Duration: time.Hour},
+	{Name: "6h", Duration: 6 * time.Hour},
+	{Name: "24h", Duration: 24 * time.Hour},
+}
+
+// DefaultWindow is served when no window is asked for
+var DefaultWindow = Windows[len(Windows)-1]
+
+// ParseWindow returns the window named name, or DefaultWindow for ""
+func ParseWindow(name string) (Window, bool) {
+	if name == "" {
+		return DefaultWindow, true
+	}
+	for _, w := range Windows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+// key returns the key the trending data stored under key for the default
+// window is stored under for this one
+func (w Window) key(key string) string {
+	if w == DefaultWindow {
+		return key
+	}
+	return cache.TrendingWindowKey(key, w.Name)
+}
+
+// decay is the time constant the events of the window decay by, a quarter
+// of it: 6 hours over 24 hours, 15 minutes over one
+func (w Window) decay() time.Duration {
+	return w.Duration / 4
+}
+
+// windowEvents returns the events that occurred within window before now.
+// Events are read once for the longest window and narrowed for the others.
+func windowEvents(events []repo.GetRecentEventsByGeohashRow, window Window, now time.Time) []repo.GetRecentEventsByGeohashRow {
+	since := now.Add(-window.Duration)
+	var windowed []repo.GetRecentEventsByGeohashRow
+	for _, event := range events {
+		if !event.OccurredAt.Before(since) {
+			windowed = append(windowed, event)
+		}
+	}
+	return windowed
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+)
+
+// GCSStore keeps objects in a Google Cloud Storage bucket. Credentials come
+// from Application Default Credentials; signing URLs needs either a service
+// account key or the iam.serviceAccounts.signBlob permission.
+type GCSStore struct {
+	client *gcs.Client
+	bucket *gcs.BucketHandle
+	prefix string
+}
+
+// NewGCSStore creates a store for bucket
+func NewGCSStore(ctx context.Context, bucket, prefix string) (*GCSStore, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("GCS bucket is required")
+	}
+	client, err := gcs.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return &GCSStore{
+		client: client,
+		bucket: client.Bucket(bucket),
+		prefix: prefix,
+	}, nil
+}
+
+// Put uploads an object
+func (s *GCSStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	w := s.bucket.Object(s.prefix + key).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := io.Copy(w, body); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to upload object %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to upload object %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get downloads an object
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := s.bucket.Object(s.prefix + key).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object %s: %w", key, err)
+	}
+	return r, nil
+}
+
+// Delete removes an object
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	err := s.bucket.Object(s.prefix + key).Delete(ctx)
+	if err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL returns a V4 signed GET URL
+func (s *GCSStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	signed, err := s.bucket.SignedURL(s.prefix+key, &gcs.SignedURLOptions{
+		Scheme:  gcs.SigningSchemeV4,
+		Method:  "GET",
+		Expires: time.Now().Add(expiry),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign object %s: %w", key, err)
+	}
+	return signed, nil
+}
+
+// Close releases the GCS client
+func (s *GCSStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,28 @@
+package storage
+
+import "fmt"
+
+// AudioSummaryKey generates the object key for an article's audio summary
+func AudioSummaryKey(articleID, format string) string {
+	return fmt.Sprintf("audio/%s.%s", articleID, format)
+}
+
+// ReportKey generates the object key for a generated PDF report
+func ReportKey(name string) string {
+	return fmt.Sprintf("reports/%s.pdf", name)
+}
+
+// ExportKey generates the object key for the output file of an export job
+func ExportKey(jobID, format string) string {
+	return fmt.Sprintf("exports/%s.%s", jobID, format)
+}
+
+// ExportFileKey generates the object key for a file of a multi-file export job
+func ExportFileKey(jobID, name string) string {
+	return fmt.Sprintf("exports/%s/%s", jobID, name)
+}
+
+// ArchiveKey generates the object key for an article's archived content
+func ArchiveKey(articleID string) string {
+	return fmt.Sprintf("archive/articles/%s.json", articleID)
+}
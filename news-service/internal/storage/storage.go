@@ -0,0 +1,65 @@
+// Package storage keeps large artifacts (audio summaries, report PDFs, export
+// files, archived article content) in object storage: local disk for
+// development, S3 or GCS in production. Clients download objects through
+// short-lived signed URLs instead of through the API.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"news-system/internal/errs"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errs.New(errs.ErrNotFound, "object not found")
+
+// Store is an object store
+type Store interface {
+	// Put writes an object, replacing any existing object under key
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get opens an object for reading; the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the object without credentials until expiry
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Config selects and configures a Store
+type Config struct {
+	// Backend is "local", "s3" or "gcs"
+	Backend string
+	// Bucket is the S3 or GCS bucket
+	Bucket string
+	// Prefix is prepended to every key, so several deployments can share a bucket
+	Prefix string
+
+	// LocalDir is the directory objects are written to by the local backend
+	LocalDir string
+	// PublicURL is where the local backend's download handler is reachable
+	PublicURL string
+	// SigningKey signs the local backend's download URLs
+	SigningKey string
+
+	// S3Region and S3Endpoint configure the S3 client; set the endpoint for
+	// S3-compatible stores such as MinIO (path-style addressing is used then)
+	S3Region   string
+	S3Endpoint string
+}
+
+// New creates the Store selected by cfg.Backend
+func New(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir, cfg.PublicURL, cfg.SigningKey, cfg.Prefix)
+	case "s3":
+		return NewS3Store(ctx, cfg.Bucket, cfg.Prefix, cfg.S3Region, cfg.S3Endpoint)
+	case "gcs":
+		return NewGCSStore(ctx, cfg.Bucket, cfg.Prefix)
+	default:
+		return nil, fmt.Errorf("unknown object storage backend %q", cfg.Backend)
+	}
+}
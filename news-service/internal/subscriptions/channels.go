@@ -0,0 +1,239 @@
+package subscriptions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"news-system/internal/repo"
+	"news-system/internal/version"
+	"news-system/pkg/webhook"
+)
+
+// Sender delivers messages through one channel
+type Sender interface {
+	// Send delivers msg to the subscription's target. It returns what the
+	// target answered, for the delivery history, and marks failures that
+	// retrying won't fix with Rejected.
+	Send(ctx context.Context, sub repo.Subscription, msg Message) (string, error)
+}
+
+// RejectedError is a failure retrying the same message won't fix, such as
+// an endpoint answering 4xx
+type RejectedError struct {
+	Err error
+}
+
+func (e *RejectedError) Error() string { return e.Err.Error() }
+func (e *RejectedError) Unwrap() error { return e.Err }
+
+// Rejected marks err as not worth retrying
+func Rejected(err error) error {
+	return &RejectedError{Err: err}
+}
+
+// retryable reports whether a failed attempt may succeed when retried
+func retryable(err error) bool {
+	var rejected *RejectedError
+	return !errors.As(err, &rejected)
+}
+
+// Message is a verification token, digest or alert sent to a subscription.
+// Subject and Body are rendered from the owner's template of its kind
+// before it is handed to a Sender.
+type Message struct {
+	// Kind is repo.DeliveryVerification, repo.SubscriptionDigest or repo.SubscriptionAlert
+	Kind           string         `json:"kind"`
+	SubscriptionID string         `json:"subscription_id"`
+	Subject        string         `json:"subject"`
+	Body           string         `json:"body"`
+	Token          string         `json:"token,omitempty"`
+	Articles       []repo.Article `json:"articles,omitempty"`
+	// UnsubscribeURL unsubscribes the recipient, "" for verification messages
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+}
+
+// senderTimeout bounds a single delivery when the channel has no timeout of its own
+const senderTimeout = 10 * time.Second
+
+// responseSize is the most bytes of a response body kept in the delivery history
+const responseSize = 512
+
+// readResponse summarizes an HTTP response as its status and the start of
+// its body, and returns an error unless the status is 2xx. 429 and 5xx
+// may pass and are retried; any other status is rejected.
+func readResponse(resp *http.Response, target string) (string, error) {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, responseSize))
+	response := resp.Status
+	if text := strings.TrimSpace(string(body)); text != "" {
+		response += ": " + text
+	}
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return response, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return response, fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	default:
+		return response, Rejected(fmt.Errorf("%s rejected delivery with %d", target, resp.StatusCode))
+	}
+}
+
+// WebhookSender posts messages as JSON to the subscription's URL, signed
+// with its secret using the scheme in pkg/webhook
+type WebhookSender struct {
+	client *http.Client
+}
+
+// NewWebhookSender creates a webhook sender whose requests time out after timeout
+func NewWebhookSender(timeout time.Duration) *WebhookSender {
+	if timeout <= 0 {
+		timeout = senderTimeout
+	}
+	return &WebhookSender{client: &http.Client{Timeout: timeout}}
+}
+
+// Send posts msg to sub.Target
+func (s *WebhookSender) Send(ctx context.Context, sub repo.Subscription, msg Message) (string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(struct {
+		ID string `json:"id"`
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{id, msg, time.Now().UTC()})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Target, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "news-service-webhooks/"+version.Version)
+	req.Header.Set(webhook.EventIDHeader, id)
+	req.Header.Set(webhook.EventTypeHeader, "subscription."+msg.Kind)
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(sub.Secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return readResponse(resp, "endpoint")
+}
+
+// EmailSender sends messages as plain-text email through an SMTP server
+type EmailSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewEmailSender creates an email sender relaying through the SMTP server
+// at addr (host:port) from the address from, authenticating when username
+// is set
+func NewEmailSender(addr, from, username, password string) *EmailSender {
+	s := &EmailSender{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Send emails msg to sub.Target. net/smtp takes no context, so ctx only
+// stops a send that hasn't started, and reports only failed replies, so
+// the response of a sent email is empty. Permanent SMTP failures (5xx) are
+// rejected.
+func (s *EmailSender) Send(ctx context.Context, sub repo.Subscription, msg Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", sub.Target)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.UnsubscribeURL != "" {
+		// One-click unsubscribe (RFC 8058) lets mail clients offer the link themselves
+		fmt.Fprintf(&b, "List-Unsubscribe: <%s>\r\n", msg.UnsubscribeURL)
+		b.WriteString("List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{sub.Target}, []byte(b.String())); err != nil {
+		var reply *textproto.Error
+		if !errors.As(err, &reply) {
+			return "", fmt.Errorf("failed to send email: %w", err)
+		}
+		if err = fmt.Errorf("failed to send email: %w", err); reply.Code >= 500 {
+			err = Rejected(err)
+		}
+		return reply.Error(), err
+	}
+	return "", nil
+}
+
+// PushSender hands messages to a push gateway, which delivers them to the
+// subscription's device token
+type PushSender struct {
+	url    string
+	client *http.Client
+}
+
+// NewPushSender creates a push sender posting to the gateway at url
+func NewPushSender(url string, timeout time.Duration) *PushSender {
+	if timeout <= 0 {
+		timeout = senderTimeout
+	}
+	return &PushSender{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Send posts msg for the device sub.Target to the gateway
+func (s *PushSender) Send(ctx context.Context, sub repo.Subscription, msg Message) (string, error) {
+	articleIDs := make([]string, len(msg.Articles))
+	for i, article := range msg.Articles {
+		articleIDs[i] = article.ID
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"token": sub.Target,
+		"title": msg.Subject,
+		"body":  msg.Body,
+		"data": map[string]interface{}{
+			"kind":            msg.Kind,
+			"subscription_id": msg.SubscriptionID,
+			"article_ids":     articleIDs,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal push notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "news-service-push/"+version.Version)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return readResponse(resp, "push gateway")
+}
@@ -0,0 +1,348 @@
+package subscriptions
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"news-system/internal/bus"
+	"news-system/internal/cache"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// alertClaimTTL is how long an instance's claim on alerting a subscription
+// to an article is kept
+const alertClaimTTL = 24 * time.Hour
+
+// dueBatch is the number of due digests read per pass
+const dueBatch = 100
+
+// DelivererOptions tunes a Deliverer. Zero values use the defaults noted on
+// each field.
+type DelivererOptions struct {
+	// Interval is how often due digests are sent and the alert
+	// subscriptions reloaded, default 1m
+	Interval time.Duration
+	// Timeout bounds each attempt to send a message, default 10s
+	Timeout time.Duration
+	// DigestSize is the most articles a digest carries, default 10
+	DigestSize int
+}
+
+// Deliverer sends the digests of verified subscriptions as they come due,
+// and alerts as matching articles are stored. Instances sharing the
+// repository claim each digest run, and each alert when cache is set, so a
+// message is sent once rather than once per instance.
+type Deliverer struct {
+	service *Service
+	events  *bus.Bus
+	cache   *cache.RedisCache
+	opts    DelivererOptions
+
+	mu     sync.RWMutex
+	alerts []repo.Subscription
+
+	unsub func()
+	done  chan bool
+	wg    sync.WaitGroup
+}
+
+// NewDeliverer creates a deliverer for the subscriptions of service
+func NewDeliverer(service *Service, events *bus.Bus, cache *cache.RedisCache, opts DelivererOptions) *Deliverer {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = senderTimeout
+	}
+	if opts.DigestSize < 1 {
+		opts.DigestSize = 10
+	}
+	return &Deliverer{
+		service: service,
+		events:  events,
+		cache:   cache,
+		opts:    opts,
+		done:    make(chan bool),
+	}
+}
+
+// Start sends digests and alerts until Stop is called or ctx is cancelled
+func (d *Deliverer) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer cancel()
+		<-d.done
+	}()
+
+	d.loadAlerts(ctx)
+	d.unsub = d.events.Subscribe("subscription-alerts", func(_ context.Context, event bus.Event) {
+		d.alert(ctx, event)
+	}, bus.ArticleCreated)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.opts.Interval)
+		defer ticker.Stop()
+		for {
+			d.sendDigests(ctx)
+			select {
+			case <-ticker.C:
+				d.loadAlerts(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", d.opts.Interval).Msg("Subscription delivery started")
+}
+
+// Stop stops delivery and waits for the messages in progress to be sent
+func (d *Deliverer) Stop() {
+	if d.unsub != nil {
+		d.unsub()
+	}
+	close(d.done)
+	d.wg.Wait()
+	log.Info().Msg("Subscription delivery stopped")
+}
+
+// sendDigests sends every digest due by now
+func (d *Deliverer) sendDigests(ctx context.Context) {
+	for {
+		now := time.Now()
+		due, err := d.service.repo.GetDueSubscriptions(ctx, repo.GetDueSubscriptionsParams{Before: now, Limit: dueBatch})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error().Err(err).Msg("Failed to list due digests")
+			}
+			return
+		}
+		for _, subscription := range due {
+			if ctx.Err() != nil {
+				return
+			}
+			d.sendDigest(ctx, subscription, now)
+		}
+		if len(due) < dueBatch {
+			return
+		}
+	}
+}
+
+// sendDigest claims the due run of a digest, moving it to the schedule's
+// next run after now, and sends the articles published since the previous
+// run. A run with no new articles is skipped.
+func (d *Deliverer) sendDigest(ctx context.Context, subscription repo.Subscription, now time.Time) {
+	logger := log.With().Str("subscription_id", subscription.ID).Logger()
+	due := *subscription.NextRunAt
+	next, err := d.service.nextRun(subscription.Schedule, subscription.Timezone, subscription.Calendar, now)
+	if err != nil {
+		// Leave the run unclaimed rather than lose it; a calendar removed
+		// from the file needs the subscription updated
+		logger.Error().Err(err).Msg("Failed to schedule digest")
+		return
+	}
+	claimed, err := d.service.repo.ClaimSubscriptionRun(ctx, repo.ClaimSubscriptionRunParams{Next: next, ID: subscription.ID, Due: due})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to claim digest run")
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	since := subscription.VerifiedAt
+	if subscription.LastRunAt != nil {
+		since = subscription.LastRunAt
+	}
+	articles, err := d.digestArticles(ctx, subscription, since, &due)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to select digest articles")
+		return
+	}
+	if len(articles) == 0 {
+		return
+	}
+
+	err = d.service.deliver(ctx, subscription, Message{
+		Kind:           repo.SubscriptionDigest,
+		SubscriptionID: subscription.ID,
+		Articles:       articles,
+	}, d.opts.Timeout)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to send digest")
+	}
+}
+
+// digestArticles selects the articles of a digest published in [after, before)
+func (d *Deliverer) digestArticles(ctx context.Context, sub repo.Subscription, after, before *time.Time) ([]repo.Article, error) {
+	var language, country string
+	if sub.Language != nil {
+		language = *sub.Language
+	}
+	if sub.Country != nil {
+		country = *sub.Country
+	}
+	limit := int32(d.opts.DigestSize)
+	r := d.service.repo
+
+	switch {
+	case sub.Latitude != nil && sub.Longitude != nil && sub.RadiusKm != nil:
+		rows, err := r.GetNearbyArticles(ctx, repo.GetNearbyArticlesParams{
+			Lat: *sub.Latitude, Lon: *sub.Longitude, Radius: *sub.RadiusKm,
+			Language: language, Country: country, PublishedAfter: after, PublishedBefore: before, Limit: limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		articles := make([]repo.Article, len(rows))
+		for i, row := range rows {
+			articles[i] = row.Article
+		}
+		return articles, nil
+	case sub.Query != nil:
+		rows, err := r.SearchArticles(ctx, repo.SearchArticlesParams{
+			Query: *sub.Query, Language: language, Country: country, PublishedAfter: after, PublishedBefore: before, Limit: limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		articles := make([]repo.Article, len(rows))
+		for i, row := range rows {
+			articles[i] = row.Article
+		}
+		return articles, nil
+	case sub.Category != nil:
+		return r.GetArticlesByCategory(ctx, repo.GetArticlesByCategoryParams{
+			Name: *sub.Category, Language: language, Country: country, PublishedAfter: after, PublishedBefore: before, Limit: limit,
+		})
+	default:
+		return r.GetArticlesByScore(ctx, repo.GetArticlesByScoreParams{
+			Language: language, Country: country, PublishedAfter: after, PublishedBefore: before, Limit: limit,
+		})
+	}
+}
+
+// loadAlerts refreshes the verified alert subscriptions matched against new
+// articles, keeping the previous ones when they can't be read
+func (d *Deliverer) loadAlerts(ctx context.Context) {
+	alerts, err := d.service.repo.GetAlertSubscriptions(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to load alert subscriptions")
+		}
+		return
+	}
+	d.mu.Lock()
+	d.alerts = alerts
+	d.mu.Unlock()
+}
+
+// alert sends a newly stored article to every alert subscription it matches
+func (d *Deliverer) alert(ctx context.Context, event bus.Event) {
+	var payload bus.ArticlePayload
+	if err := event.Decode(&payload); err != nil || payload.ArticleID == "" {
+		return
+	}
+
+	d.mu.RLock()
+	alerts := d.alerts
+	d.mu.RUnlock()
+	if len(alerts) == 0 {
+		return
+	}
+
+	article, err := d.service.repo.GetArticleByID(ctx, payload.ArticleID)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn().Err(err).Str("article_id", payload.ArticleID).Msg("Failed to load article for alerts")
+		}
+		return
+	}
+
+	for _, subscription := range alerts {
+		if !alertMatches(subscription, article) || !d.claim(ctx, subscription.ID, article.ID) {
+			continue
+		}
+		err := d.service.deliver(ctx, subscription, Message{
+			Kind:           repo.SubscriptionAlert,
+			SubscriptionID: subscription.ID,
+			Articles:       []repo.Article{article},
+		}, d.opts.Timeout)
+		if err != nil {
+			log.Warn().Err(err).Str("subscription_id", subscription.ID).Str("article_id", article.ID).Msg("Failed to send alert")
+		}
+	}
+}
+
+// claim reports whether this instance should alert a subscription to an article
+func (d *Deliverer) claim(ctx context.Context, subscriptionID, articleID string) bool {
+	if d.cache == nil {
+		return true
+	}
+	ok, err := d.cache.SetNX(ctx, cache.SubscriptionAlertClaimKey(subscriptionID, articleID), d.events.Origin(), alertClaimTTL)
+	if err != nil {
+		// Better a duplicate alert than a lost one
+		log.Warn().Err(err).Str("subscription_id", subscriptionID).Msg("Failed to claim alert")
+		return true
+	}
+	return ok
+}
+
+// alertMatches reports whether article passes an alert's filters. A query
+// matches when the title or description contains each of its words.
+func alertMatches(sub repo.Subscription, article repo.Article) bool {
+	if sub.Language != nil && article.Language != *sub.Language {
+		return false
+	}
+	if sub.Country != nil && article.Country != *sub.Country {
+		return false
+	}
+	if sub.Category != nil && !slices.ContainsFunc(article.Category, func(category string) bool {
+		return strings.EqualFold(category, *sub.Category)
+	}) {
+		return false
+	}
+	if sub.Query != nil {
+		text := article.Title
+		if article.Description != nil {
+			text += " " + *article.Description
+		}
+		text = strings.ToLower(text)
+		for _, word := range strings.Fields(strings.ToLower(*sub.Query)) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	if sub.Latitude != nil && sub.Longitude != nil && sub.RadiusKm != nil {
+		if article.Latitude == nil || article.Longitude == nil {
+			return false
+		}
+		if distanceKm(*sub.Latitude, *sub.Longitude, *article.Latitude, *article.Longitude) > *sub.RadiusKm {
+			return false
+		}
+	}
+	return true
+}
+
+// distanceKm is the great-circle distance between two points
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	lat1Rad, lat2Rad := lat1*math.Pi/180, lat2*math.Pi/180
+	deltaLat := (lat2 - lat1) * math.Pi / 180
+	deltaLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
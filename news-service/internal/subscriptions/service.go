@@ -0,0 +1,807 @@
+// Package subscriptions manages readers' digest and alert subscriptions:
+// their filters, schedules and delivery channels, the verification that a
+// subscriber owns the email address or webhook endpoint messages go to, and
+// the history of every message sent.
+package subscriptions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"news-system/internal/errs"
+	"news-system/internal/ingest"
+	"news-system/internal/metrics"
+	"news-system/internal/repo"
+	"news-system/internal/services/geocode"
+	"news-system/internal/services/language"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultRadiusKm is the radius of a location filter given without one
+const defaultRadiusKm = 25
+
+// Request creates or replaces a subscription
+type Request struct {
+	// Kind is "digest", sent on Schedule, or "alert", sent as soon as a
+	// matching article is stored
+	Kind string `json:"kind"`
+	// Channel is "email", "webhook" or "push", and Target the address, URL
+	// or device token messages go to
+	Channel string  `json:"channel"`
+	Target  string  `json:"target"`
+	Filters Filters `json:"filters"`
+	// Schedule is the cron expression of a digest, run in Timezone (default
+	// UTC) on the working days of Calendar, when set
+	Schedule string `json:"schedule,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Calendar string `json:"calendar,omitempty"`
+}
+
+// Filters select the articles a subscription receives. At most one of
+// Query, Category and the location is set; none receives the top scored
+// articles. Lang and Country restrict any of them.
+type Filters struct {
+	Query    string   `json:"query,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Lang     string   `json:"lang,omitempty"`
+	Country  string   `json:"country,omitempty"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	RadiusKm *float64 `json:"radius_km,omitempty"`
+}
+
+// Subscription is a subscription as shown to its owner
+type Subscription struct {
+	ID       string  `json:"id"`
+	Kind     string  `json:"kind"`
+	Channel  string  `json:"channel"`
+	Target   string  `json:"target"`
+	Filters  Filters `json:"filters"`
+	Schedule string  `json:"schedule,omitempty"`
+	Timezone string  `json:"timezone"`
+	Calendar string  `json:"calendar,omitempty"`
+	// Verified is false until the owner confirms the token sent to Target;
+	// nothing but verification messages is sent before
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	NextRunAt  *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	// Disabled is set once Failures digests or alerts in a row failed
+	// every attempt; nothing is sent until the subscription is enabled
+	// again
+	Disabled       bool       `json:"disabled"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	Failures       int        `json:"failures"`
+	// Secret signs webhook deliveries; it is only returned on creation
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Options configures the links and images in rendered messages and the
+// retrying of failed digests and alerts. Zero values use the defaults noted
+// on each field.
+type Options struct {
+	// PublicURL is the public base URL of the API, for unsubscribe links;
+	// empty leaves them out
+	PublicURL string
+	// ImageURL is the preview image service for articles, with {url}
+	// standing for the article's escaped URL; empty leaves images out
+	ImageURL string
+	// Attempts is how many times a digest or alert is tried before it
+	// counts as failed, default 1. Verification messages are tried once.
+	Attempts int
+	// RetryBackoff is the wait before the first retry, doubling after
+	// each one, default 1s
+	RetryBackoff time.Duration
+	// DisableAfter disables a subscription once this many digests or
+	// alerts in a row failed every attempt; 0 never disables
+	DisableAfter int
+}
+
+// Service manages subscriptions on behalf of their owners. Every method
+// takes the owner, and a subscription of another owner is reported as not
+// found.
+type Service struct {
+	repo      repo.Repository
+	senders   map[string]Sender
+	calendars map[string]*ingest.Calendar
+	opts      Options
+}
+
+// NewService creates a service whose subscriptions can use the channels of
+// senders and the named calendars
+func NewService(repository repo.Repository, senders map[string]Sender, calendars map[string]*ingest.Calendar, opts Options) *Service {
+	if opts.Attempts < 1 {
+		opts.Attempts = 1
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = time.Second
+	}
+	return &Service{repo: repository, senders: senders, calendars: calendars, opts: opts}
+}
+
+// Owner returns the owner of the subscriptions created with apiKey: a hash,
+// so the key itself is never stored
+func Owner(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// Create stores a new subscription and sends its verification token to the
+// target. Push subscriptions are verified as created, since device tokens
+// come from the subscriber's own app.
+func (s *Service) Create(ctx context.Context, owner string, req Request) (Subscription, error) {
+	params, err := s.validate(req)
+	if err != nil {
+		return Subscription{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return Subscription{}, err
+	}
+	token, hash, err := s.verification(params.Channel)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	arg := repo.CreateSubscriptionParams{
+		Owner:            owner,
+		Kind:             params.Kind,
+		Channel:          params.Channel,
+		Target:           params.Target,
+		Query:            params.Query,
+		Category:         params.Category,
+		Language:         params.Language,
+		Country:          params.Country,
+		Latitude:         params.Latitude,
+		Longitude:        params.Longitude,
+		RadiusKm:         params.RadiusKm,
+		Schedule:         params.Schedule,
+		Timezone:         params.Timezone,
+		Calendar:         params.Calendar,
+		Secret:           secret,
+		VerificationHash: hash,
+	}
+	if hash == nil {
+		now := time.Now()
+		arg.VerifiedAt = &now
+		if arg.NextRunAt, err = s.nextRun(params.Schedule, params.Timezone, params.Calendar, now); err != nil {
+			return Subscription{}, err
+		}
+	}
+	subscription, err := s.repo.CreateSubscription(ctx, arg)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	if token != "" {
+		// The subscription stands even if the token didn't go out; the
+		// failure is in its delivery history and it can be sent again
+		if err := s.sendVerification(ctx, subscription, token); err != nil {
+			log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to send subscription verification")
+		}
+	}
+	view := toView(subscription)
+	view.Secret = subscription.Secret
+	return view, nil
+}
+
+// Get returns one of owner's subscriptions
+func (s *Service) Get(ctx context.Context, owner, id string) (Subscription, error) {
+	subscription, err := s.owned(ctx, owner, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return toView(subscription), nil
+}
+
+// List returns owner's subscriptions, oldest first
+func (s *Service) List(ctx context.Context, owner string, limit, offset int) ([]Subscription, error) {
+	stored, err := s.repo.ListSubscriptions(ctx, repo.ListSubscriptionsParams{
+		Owner:  owner,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := make([]Subscription, len(stored))
+	for i, subscription := range stored {
+		subscriptions[i] = toView(subscription)
+	}
+	return subscriptions, nil
+}
+
+// Update replaces a subscription. Changing its channel or target needs the
+// new target verified, so a token is sent to it; otherwise the subscription
+// stays verified and a digest's next run follows the new schedule.
+func (s *Service) Update(ctx context.Context, owner, id string, req Request) (Subscription, error) {
+	subscription, err := s.owned(ctx, owner, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	params, err := s.validate(req)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	var token string
+	if params.Channel != subscription.Channel || params.Target != subscription.Target {
+		var hash *string
+		if token, hash, err = s.verification(params.Channel); err != nil {
+			return Subscription{}, err
+		}
+		subscription.VerificationHash = hash
+		subscription.VerifiedAt = nil
+		if hash == nil {
+			now := time.Now()
+			subscription.VerifiedAt = &now
+		}
+	}
+
+	subscription.Kind = params.Kind
+	subscription.Channel = params.Channel
+	subscription.Target = params.Target
+	subscription.Query = params.Query
+	subscription.Category = params.Category
+	subscription.Language = params.Language
+	subscription.Country = params.Country
+	subscription.Latitude = params.Latitude
+	subscription.Longitude = params.Longitude
+	subscription.RadiusKm = params.RadiusKm
+	subscription.Schedule = params.Schedule
+	subscription.Timezone = params.Timezone
+	subscription.Calendar = params.Calendar
+	subscription.NextRunAt = nil
+	if subscription.VerifiedAt != nil {
+		if subscription.NextRunAt, err = s.nextRun(params.Schedule, params.Timezone, params.Calendar, time.Now()); err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	if subscription, err = s.update(ctx, subscription); err != nil {
+		return Subscription{}, err
+	}
+	if token != "" {
+		if err := s.sendVerification(ctx, subscription, token); err != nil {
+			log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to send subscription verification")
+		}
+	}
+	return toView(subscription), nil
+}
+
+// Delete deletes one of owner's subscriptions and its delivery history
+func (s *Service) Delete(ctx context.Context, owner, id string) error {
+	if _, err := s.owned(ctx, owner, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+// Verify confirms a subscription with the token sent to its target, after
+// which its digests or alerts are delivered. Verifying a verified
+// subscription again is a no-op.
+func (s *Service) Verify(ctx context.Context, owner, id, token string) (Subscription, error) {
+	subscription, err := s.owned(ctx, owner, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if subscription.VerifiedAt != nil {
+		return toView(subscription), nil
+	}
+
+	hash := hashToken(token)
+	if subscription.VerificationHash == nil || subtle.ConstantTimeCompare([]byte(hash), []byte(*subscription.VerificationHash)) != 1 {
+		return Subscription{}, errs.Errorf(errs.ErrInvalid, "invalid verification token")
+	}
+
+	now := time.Now()
+	subscription.VerificationHash = nil
+	subscription.VerifiedAt = &now
+	next, err := s.nextRun(subscription.Schedule, subscription.Timezone, subscription.Calendar, now)
+	if err != nil {
+		return Subscription{}, err
+	}
+	subscription.NextRunAt = next
+	if subscription, err = s.update(ctx, subscription); err != nil {
+		return Subscription{}, err
+	}
+	return toView(subscription), nil
+}
+
+// ResendVerification sends a new verification token to an unverified
+// subscription's target; the previous token stops working
+func (s *Service) ResendVerification(ctx context.Context, owner, id string) error {
+	subscription, err := s.owned(ctx, owner, id)
+	if err != nil {
+		return err
+	}
+	if subscription.VerifiedAt != nil {
+		return errs.Errorf(errs.ErrConflict, "subscription %s is already verified", id)
+	}
+
+	token, hash, err := s.verification(subscription.Channel)
+	if err != nil {
+		return err
+	}
+	subscription.VerificationHash = hash
+	if subscription, err = s.update(ctx, subscription); err != nil {
+		return err
+	}
+	if err := s.sendVerification(ctx, subscription, token); err != nil {
+		return errs.Wrap(errs.ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Deliveries returns the messages sent for one of owner's subscriptions,
+// newest first
+func (s *Service) Deliveries(ctx context.Context, owner, id string, limit, offset int) ([]repo.SubscriptionDelivery, error) {
+	if _, err := s.owned(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSubscriptionDeliveries(ctx, repo.ListSubscriptionDeliveriesParams{
+		SubscriptionID: id,
+		Limit:          int32(limit),
+		Offset:         int32(offset),
+	})
+}
+
+// SearchDeliveries returns the messages sent for any subscription, newest
+// first, for operators; arg's nil filters match every delivery
+func (s *Service) SearchDeliveries(ctx context.Context, arg repo.SearchSubscriptionDeliveriesParams) ([]repo.SubscriptionDelivery, error) {
+	if arg.Channel != nil {
+		switch *arg.Channel {
+		case repo.ChannelEmail, repo.ChannelWebhook, repo.ChannelPush:
+		default:
+			return nil, errs.Errorf(errs.ErrInvalid, "invalid channel %q: expected %q, %q or %q", *arg.Channel, repo.ChannelEmail, repo.ChannelWebhook, repo.ChannelPush)
+		}
+	}
+	if arg.Status != nil && *arg.Status != repo.DeliveryDelivered && *arg.Status != repo.DeliveryFailed {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid status %q: expected %q or %q", *arg.Status, repo.DeliveryDelivered, repo.DeliveryFailed)
+	}
+	return s.repo.SearchSubscriptionDeliveries(ctx, arg)
+}
+
+// Enable re-enables one of owner's subscriptions after it was disabled for
+// failing persistently, clearing its failures
+func (s *Service) Enable(ctx context.Context, owner, id string) (Subscription, error) {
+	subscription, err := s.owned(ctx, owner, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return s.enable(ctx, subscription)
+}
+
+// EnableAny re-enables any owner's subscription, for operators
+func (s *Service) EnableAny(ctx context.Context, id string) (Subscription, error) {
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return s.enable(ctx, subscription)
+}
+
+// enable clears a subscription's disabled state. A digest resumes from its
+// next run after now rather than catching up on the runs it missed.
+func (s *Service) enable(ctx context.Context, subscription repo.Subscription) (Subscription, error) {
+	if subscription.DisabledAt == nil && subscription.Failures == 0 {
+		return toView(subscription), nil
+	}
+	wasDisabled := subscription.DisabledAt != nil
+	subscription, err := s.repo.EnableSubscription(ctx, subscription.ID)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if wasDisabled && subscription.VerifiedAt != nil && subscription.Schedule != nil {
+		if subscription.NextRunAt, err = s.nextRun(subscription.Schedule, subscription.Timezone, subscription.Calendar, time.Now()); err != nil {
+			return Subscription{}, err
+		}
+		if subscription, err = s.update(ctx, subscription); err != nil {
+			return Subscription{}, err
+		}
+	}
+	log.Info().Str("subscription_id", subscription.ID).Msg("Subscription enabled")
+	return toView(subscription), nil
+}
+
+// owned returns subscription id when owner owns it
+func (s *Service) owned(ctx context.Context, owner, id string) (repo.Subscription, error) {
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return repo.Subscription{}, err
+	}
+	if subscription.Owner != owner {
+		return repo.Subscription{}, errs.Errorf(errs.ErrNotFound, "subscription not found: %s", id)
+	}
+	return subscription, nil
+}
+
+// update stores every field of subscription
+func (s *Service) update(ctx context.Context, subscription repo.Subscription) (repo.Subscription, error) {
+	return s.repo.UpdateSubscription(ctx, repo.UpdateSubscriptionParams{
+		Kind:             subscription.Kind,
+		Channel:          subscription.Channel,
+		Target:           subscription.Target,
+		Query:            subscription.Query,
+		Category:         subscription.Category,
+		Language:         subscription.Language,
+		Country:          subscription.Country,
+		Latitude:         subscription.Latitude,
+		Longitude:        subscription.Longitude,
+		RadiusKm:         subscription.RadiusKm,
+		Schedule:         subscription.Schedule,
+		Timezone:         subscription.Timezone,
+		Calendar:         subscription.Calendar,
+		VerificationHash: subscription.VerificationHash,
+		VerifiedAt:       subscription.VerifiedAt,
+		NextRunAt:        subscription.NextRunAt,
+		ID:               subscription.ID,
+	})
+}
+
+// validate checks a request and normalizes it into the stored fields
+func (s *Service) validate(req Request) (repo.Subscription, error) {
+	var sub repo.Subscription
+
+	switch req.Kind {
+	case repo.SubscriptionDigest, repo.SubscriptionAlert:
+		sub.Kind = req.Kind
+	default:
+		return sub, errs.Errorf(errs.ErrInvalid, "invalid kind %q: expected %q or %q", req.Kind, repo.SubscriptionDigest, repo.SubscriptionAlert)
+	}
+
+	target := strings.TrimSpace(req.Target)
+	switch req.Channel {
+	case repo.ChannelEmail:
+		address, err := mail.ParseAddress(target)
+		if err != nil || address.Name != "" {
+			return sub, errs.Errorf(errs.ErrInvalid, "invalid target %q: expected an email address", req.Target)
+		}
+		target = address.Address
+	case repo.ChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return sub, errs.Errorf(errs.ErrInvalid, "invalid target %q: expected an http or https URL", req.Target)
+		}
+	case repo.ChannelPush:
+		if target == "" {
+			return sub, errs.Errorf(errs.ErrInvalid, "target is required: expected a push device token")
+		}
+	default:
+		return sub, errs.Errorf(errs.ErrInvalid, "invalid channel %q: expected %q, %q or %q", req.Channel, repo.ChannelEmail, repo.ChannelWebhook, repo.ChannelPush)
+	}
+	if _, ok := s.senders[req.Channel]; !ok {
+		return sub, errs.Errorf(errs.ErrInvalid, "channel %q is not enabled on this server", req.Channel)
+	}
+	sub.Channel, sub.Target = req.Channel, target
+
+	f := req.Filters
+	selectors := 0
+	if query := strings.TrimSpace(f.Query); query != "" {
+		sub.Query = &query
+		selectors++
+	}
+	if category := strings.TrimSpace(f.Category); category != "" {
+		sub.Category = &category
+		selectors++
+	}
+	if f.Lat != nil || f.Lon != nil || f.RadiusKm != nil {
+		if f.Lat == nil || f.Lon == nil {
+			return sub, errs.Errorf(errs.ErrInvalid, "filters.lat and filters.lon must be set together")
+		}
+		if *f.Lat < -90 || *f.Lat > 90 || *f.Lon < -180 || *f.Lon > 180 {
+			return sub, errs.Errorf(errs.ErrInvalid, "invalid location %g,%g", *f.Lat, *f.Lon)
+		}
+		radius := float64(defaultRadiusKm)
+		if f.RadiusKm != nil {
+			radius = *f.RadiusKm
+		}
+		if radius < 0.1 || radius > 200 {
+			return sub, errs.Errorf(errs.ErrInvalid, "invalid filters.radius_km %g: expected 0.1-200", radius)
+		}
+		lat, lon := *f.Lat, *f.Lon
+		sub.Latitude, sub.Longitude, sub.RadiusKm = &lat, &lon, &radius
+		selectors++
+	}
+	if selectors > 1 {
+		return sub, errs.Errorf(errs.ErrInvalid, "filters.query, filters.category and the location can't be combined")
+	}
+	lang, ok := language.Normalize(f.Lang)
+	if !ok {
+		return sub, errs.Errorf(errs.ErrInvalid, "invalid filters.lang %q: expected an ISO 639-1 code such as \"en\"", f.Lang)
+	}
+	if lang != "" {
+		sub.Language = &lang
+	}
+	country, ok := geocode.NormalizeCountry(f.Country)
+	if !ok {
+		return sub, errs.Errorf(errs.ErrInvalid, "invalid filters.country %q: expected an ISO 3166-1 alpha-2 code such as \"IN\"", f.Country)
+	}
+	if country != "" {
+		sub.Country = &country
+	}
+
+	sub.Timezone = "UTC"
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+			return sub, errs.Errorf(errs.ErrInvalid, "invalid timezone %q: expected an IANA time zone such as \"Asia/Kolkata\"", req.Timezone)
+		}
+		sub.Timezone = req.Timezone
+	}
+	if sub.Kind == repo.SubscriptionAlert {
+		if req.Schedule != "" || req.Calendar != "" {
+			return sub, errs.Errorf(errs.ErrInvalid, "alerts are sent as articles arrive and take no schedule or calendar")
+		}
+		return sub, nil
+	}
+
+	if _, err := ingest.ParseSchedule(req.Schedule); err != nil {
+		return sub, errs.Errorf(errs.ErrInvalid, "invalid schedule %q: %v", req.Schedule, err)
+	}
+	schedule := strings.TrimSpace(req.Schedule)
+	sub.Schedule = &schedule
+	if req.Calendar != "" {
+		if _, ok := s.calendars[req.Calendar]; !ok {
+			return sub, errs.Errorf(errs.ErrInvalid, "unknown calendar %q", req.Calendar)
+		}
+		calendar := req.Calendar
+		sub.Calendar = &calendar
+	}
+	return sub, nil
+}
+
+// nextRun is the first run of a digest's schedule after t, nil for alerts
+// and for schedules with no run left
+func (s *Service) nextRun(schedule *string, timezone string, calendar *string, t time.Time) (*time.Time, error) {
+	if schedule == nil {
+		return nil, nil
+	}
+	parsed, err := ingest.ParseSchedule(*schedule)
+	if err != nil {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid schedule %q: %v", *schedule, err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errs.Errorf(errs.ErrInvalid, "invalid timezone %q", timezone)
+	}
+	var cal *ingest.Calendar
+	if calendar != nil {
+		if cal = s.calendars[*calendar]; cal == nil {
+			return nil, errs.Errorf(errs.ErrInvalid, "unknown calendar %q", *calendar)
+		}
+	}
+	adjusted, err := cal.Apply(parsed, loc)
+	if err != nil {
+		return nil, err
+	}
+	next := adjusted.Next(t)
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// verification returns a new verification token and the hash to store, or
+// neither for channels verified as created
+func (s *Service) verification(channel string) (string, *string, error) {
+	if channel == repo.ChannelPush {
+		return "", nil, nil
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return "", nil, err
+	}
+	hash := hashToken(token)
+	return token, &hash, nil
+}
+
+// sendVerification sends token to a subscription's target and records the delivery
+func (s *Service) sendVerification(ctx context.Context, subscription repo.Subscription, token string) error {
+	return s.send(ctx, subscription, Message{
+		Kind:           repo.DeliveryVerification,
+		SubscriptionID: subscription.ID,
+		Token:          token,
+	}, 1)
+}
+
+// deliver sends a digest or alert, each attempt bounded by timeout, and
+// retries failures that may pass with backoff. A message failing every
+// attempt counts towards disabling the subscription; a delivered one
+// clears the count.
+func (s *Service) deliver(ctx context.Context, subscription repo.Subscription, msg Message, timeout time.Duration) error {
+	backoff := s.opts.RetryBackoff
+	var err error
+	for attempt := 1; attempt <= s.opts.Attempts; attempt++ {
+		if attempt > 1 {
+			metrics.SubscriptionDeliveryRetries.WithLabelValues(subscription.Channel).Inc()
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = s.send(attemptCtx, subscription, msg, attempt)
+		cancel()
+		if err == nil || !retryable(err) || attempt == s.opts.Attempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+
+	if err == nil {
+		if subscription.Failures > 0 {
+			if err := s.repo.ResetSubscriptionFailures(ctx, subscription.ID); err != nil {
+				log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to reset subscription failures")
+			}
+		}
+		return nil
+	}
+	// Neither a send cut short by shutdown nor a template that doesn't
+	// render says anything about the target
+	var tmplErr *templateError
+	if ctx.Err() == nil && !errors.As(err, &tmplErr) {
+		s.recordFailure(ctx, subscription, err)
+	}
+	return err
+}
+
+// templateError is a message that failed to render from its template
+type templateError struct {
+	err error
+}
+
+func (e *templateError) Error() string { return e.err.Error() }
+func (e *templateError) Unwrap() error { return e.err }
+
+// recordFailure counts a message that failed every attempt against its
+// subscription, disabling it at DisableAfter failures in a row
+func (s *Service) recordFailure(ctx context.Context, subscription repo.Subscription, cause error) {
+	reason := fmt.Sprintf("%s failed %d times in a row: %v", subscription.Channel, s.opts.DisableAfter, cause)
+	updated, err := s.repo.RecordSubscriptionFailure(ctx, repo.RecordSubscriptionFailureParams{
+		Threshold: int32(s.opts.DisableAfter),
+		Reason:    &reason,
+		ID:        subscription.ID,
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to record subscription failure")
+		return
+	}
+	if subscription.DisabledAt == nil && updated.DisabledAt != nil {
+		metrics.SubscriptionsDisabled.WithLabelValues(subscription.Channel).Inc()
+		log.Warn().Str("subscription_id", subscription.ID).Str("channel", subscription.Channel).
+			Int32("failures", updated.Failures).Msg("Subscription disabled after failing persistently")
+	}
+}
+
+// send renders msg with the owner's template, sends it through the
+// subscription's channel and records the delivery attempt, whether or not
+// it succeeded
+func (s *Service) send(ctx context.Context, subscription repo.Subscription, msg Message, attempt int) error {
+	sender, ok := s.senders[subscription.Channel]
+	if !ok {
+		return fmt.Errorf("channel %q is not enabled", subscription.Channel)
+	}
+
+	if msg.Kind != repo.DeliveryVerification {
+		msg.UnsubscribeURL = s.unsubscribeURL(subscription)
+	}
+	var latency *int32
+	var response string
+	rendered, sendErr := s.render(ctx, subscription, msg)
+	if sendErr != nil {
+		// The template won't render any better on a retry
+		sendErr = Rejected(&templateError{err: sendErr})
+	} else {
+		msg.Subject, msg.Body = rendered.Subject, rendered.Body
+		start := time.Now()
+		response, sendErr = sender.Send(ctx, subscription, msg)
+		elapsed := time.Since(start)
+		metrics.SubscriptionDeliveryLatency.WithLabelValues(subscription.Channel).Observe(elapsed.Seconds())
+		ms := int32(elapsed.Milliseconds())
+		latency = &ms
+	}
+	status := repo.DeliveryDelivered
+	var message *string
+	if sendErr != nil {
+		status = repo.DeliveryFailed
+		text := sendErr.Error()
+		message = &text
+	}
+	metrics.SubscriptionDeliveries.WithLabelValues(subscription.Channel, msg.Kind, status).Inc()
+
+	arg := repo.CreateSubscriptionDeliveryParams{
+		SubscriptionID: subscription.ID,
+		Kind:           msg.Kind,
+		Status:         status,
+		Articles:       int32(len(msg.Articles)),
+		Error:          message,
+		Channel:        subscription.Channel,
+		Attempt:        int32(attempt),
+		LatencyMs:      latency,
+	}
+	if response != "" {
+		arg.Response = &response
+	}
+	// Recorded even when ctx timed out the attempt
+	if _, err := s.repo.CreateSubscriptionDelivery(context.WithoutCancel(ctx), arg); err != nil {
+		log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to record subscription delivery")
+	}
+	return sendErr
+}
+
+// toView converts a stored subscription for its owner, without its secret
+func toView(subscription repo.Subscription) Subscription {
+	view := Subscription{
+		ID:         subscription.ID,
+		Kind:       subscription.Kind,
+		Channel:    subscription.Channel,
+		Target:     subscription.Target,
+		Timezone:   subscription.Timezone,
+		Verified:   subscription.VerifiedAt != nil,
+		VerifiedAt: subscription.VerifiedAt,
+		NextRunAt:  subscription.NextRunAt,
+		LastRunAt:  subscription.LastRunAt,
+		Disabled:   subscription.DisabledAt != nil,
+		DisabledAt: subscription.DisabledAt,
+		Failures:   int(subscription.Failures),
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+		Filters: Filters{
+			Lat:      subscription.Latitude,
+			Lon:      subscription.Longitude,
+			RadiusKm: subscription.RadiusKm,
+		},
+	}
+	if subscription.Query != nil {
+		view.Filters.Query = *subscription.Query
+	}
+	if subscription.Category != nil {
+		view.Filters.Category = *subscription.Category
+	}
+	if subscription.Language != nil {
+		view.Filters.Lang = *subscription.Language
+	}
+	if subscription.Country != nil {
+		view.Filters.Country = *subscription.Country
+	}
+	if subscription.Schedule != nil {
+		view.Schedule = *subscription.Schedule
+	}
+	if subscription.Calendar != nil {
+		view.Calendar = *subscription.Calendar
+	}
+	if subscription.DisabledReason != nil {
+		view.DisabledReason = *subscription.DisabledReason
+	}
+	return view
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the stored form of a verification token
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
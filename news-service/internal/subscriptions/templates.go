@@ -0,0 +1,449 @@
+package subscriptions
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"news-system/internal/errs"
+	"news-system/internal/repo"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Template limits: a template's source, and what it renders, can't exceed these
+const (
+	maxSubjectChars  = 200
+	maxTemplateBytes = 20000
+	maxRenderedBytes = 64 << 10
+)
+
+// Template renders the subject and body of one kind of message
+type Template struct {
+	// Name is the message kind it renders: "verification", "digest" or "alert"
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// Custom is true for an owner's override, false for the built-in template
+	Custom    bool       `json:"custom"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// TemplateRequest replaces a template, or previews one when both fields
+// are empty
+type TemplateRequest struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Rendered is a rendered message
+type Rendered struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// TemplateData is what templates are executed with
+type TemplateData struct {
+	// Kind is the message kind, the template's name
+	Kind         string
+	Subscription Subscription
+	// Token is the verification token, set for verification messages only
+	Token    string
+	Articles []repo.Article
+	// Now is when the message is rendered, in the subscription's time zone
+	Now time.Time
+
+	unsubscribeURL string
+	sample         bool
+}
+
+// defaultTemplates are the built-in templates, used for every kind an owner
+// hasn't overridden. Templates use text/template with the functions of
+// templateFuncs.
+var defaultTemplates = map[string]Template{
+	repo.DeliveryVerification: {
+		Name:    repo.DeliveryVerification,
+		Subject: "Confirm your news subscription",
+		Body: `Confirm your {{.Subscription.Kind}} subscription {{.Subscription.ID}} with this verification token:
+
+{{.Token}}
+
+If you didn't subscribe, ignore this message.
+`,
+	},
+	repo.SubscriptionDigest: {
+		Name:    repo.SubscriptionDigest,
+		Subject: `Your news digest: {{len .Articles}} {{if eq (len .Articles) 1}}article{{else}}articles{{end}}`,
+		Body: `{{range $i, $a := .Articles}}{{if $i}}
+{{end}}{{$a.Title}} ({{$a.SourceName}}, {{date "Jan 2 15:04" $a.PublicationDate}})
+{{with summary $a}}{{truncate 300 .}}
+{{end}}{{$a.URL}}
+{{with image $a}}{{.}}
+{{end}}{{end}}{{with unsubscribe}}
+Unsubscribe: {{.}}
+{{end}}`,
+	},
+	repo.SubscriptionAlert: {
+		Name:    repo.SubscriptionAlert,
+		Subject: `{{with index .Articles 0}}{{.Title}}{{end}}`,
+		Body: `{{with index .Articles 0}}{{.Title}} ({{.SourceName}}, {{date "Jan 2 15:04" .PublicationDate}})
+{{with summary .}}{{truncate 500 .}}
+{{end}}{{.URL}}
+{{with image .}}{{.}}
+{{end}}{{end}}{{with unsubscribe}}
+Unsubscribe: {{.}}
+{{end}}`,
+	},
+}
+
+// templateNames lists the template names in a stable order
+var templateNames = []string{repo.DeliveryVerification, repo.SubscriptionDigest, repo.SubscriptionAlert}
+
+// ListTemplates returns the templates owner's messages are rendered with:
+// the owner's overrides and the built-in templates of the other kinds
+func (s *Service) ListTemplates(ctx context.Context, owner string) ([]Template, error) {
+	stored, err := s.repo.ListNotificationTemplates(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	custom := make(map[string]repo.NotificationTemplate, len(stored))
+	for _, template := range stored {
+		custom[template.Name] = template
+	}
+
+	templates := make([]Template, 0, len(templateNames))
+	for _, name := range templateNames {
+		if template, ok := custom[name]; ok {
+			templates = append(templates, fromStored(template))
+		} else {
+			templates = append(templates, defaultTemplates[name])
+		}
+	}
+	return templates, nil
+}
+
+// GetTemplate returns the template owner's messages of kind name are rendered with
+func (s *Service) GetTemplate(ctx context.Context, owner, name string) (Template, error) {
+	if _, ok := defaultTemplates[name]; !ok {
+		return Template{}, unknownTemplate(name)
+	}
+	return s.template(ctx, owner, name)
+}
+
+// PutTemplate overrides the template of kind name for owner's messages. The
+// template must parse and render the sample data.
+func (s *Service) PutTemplate(ctx context.Context, owner, name string, req TemplateRequest) (Template, error) {
+	if _, ok := defaultTemplates[name]; !ok {
+		return Template{}, unknownTemplate(name)
+	}
+	if strings.TrimSpace(req.Subject) == "" || strings.TrimSpace(req.Body) == "" {
+		return Template{}, errs.Errorf(errs.ErrInvalid, "subject and body are required")
+	}
+	if utf8.RuneCountInString(req.Subject) > maxSubjectChars || len(req.Body) > maxTemplateBytes {
+		return Template{}, errs.Errorf(errs.ErrInvalid, "subject must be at most %d characters and body at most %d bytes", maxSubjectChars, maxTemplateBytes)
+	}
+	if _, err := s.renderTemplate(ctx, Template{Name: name, Subject: req.Subject, Body: req.Body}, sampleData(name)); err != nil {
+		return Template{}, errs.Wrap(errs.ErrInvalid, err)
+	}
+
+	stored, err := s.repo.UpsertNotificationTemplate(ctx, repo.UpsertNotificationTemplateParams{
+		Owner:   owner,
+		Name:    name,
+		Subject: req.Subject,
+		Body:    req.Body,
+	})
+	if err != nil {
+		return Template{}, err
+	}
+	return fromStored(stored), nil
+}
+
+// DeleteTemplate drops owner's override of the template of kind name, so
+// the built-in template is used again
+func (s *Service) DeleteTemplate(ctx context.Context, owner, name string) error {
+	if _, ok := defaultTemplates[name]; !ok {
+		return unknownTemplate(name)
+	}
+	return s.repo.DeleteNotificationTemplate(ctx, repo.DeleteNotificationTemplateParams{Owner: owner, Name: name})
+}
+
+// PreviewTemplate renders the template of kind name against sample data:
+// the one in req when given, owner's current template otherwise
+func (s *Service) PreviewTemplate(ctx context.Context, owner, name string, req TemplateRequest) (Rendered, error) {
+	if _, ok := defaultTemplates[name]; !ok {
+		return Rendered{}, unknownTemplate(name)
+	}
+	template := Template{Name: name, Subject: req.Subject, Body: req.Body}
+	if req.Subject == "" && req.Body == "" {
+		current, err := s.template(ctx, owner, name)
+		if err != nil {
+			return Rendered{}, err
+		}
+		template = current
+	}
+	rendered, err := s.renderTemplate(ctx, template, sampleData(name))
+	if err != nil {
+		return Rendered{}, errs.Wrap(errs.ErrInvalid, err)
+	}
+	return rendered, nil
+}
+
+// Unsubscribe deletes a subscription on behalf of its recipient, who holds
+// the token of its unsubscribe link rather than the owner's API key
+func (s *Service) Unsubscribe(ctx context.Context, id, token string) error {
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	expected := unsubscribeToken(subscription)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return errs.Errorf(errs.ErrForbidden, "invalid unsubscribe token")
+	}
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+// render renders msg for a subscription with its owner's template of the
+// message's kind. A stored template that fails on real data falls back to
+// the built-in one, so a broken override never stops delivery.
+func (s *Service) render(ctx context.Context, subscription repo.Subscription, msg Message) (Rendered, error) {
+	data := TemplateData{
+		Kind:           msg.Kind,
+		Subscription:   toView(subscription),
+		Token:          msg.Token,
+		Articles:       msg.Articles,
+		Now:            time.Now(),
+		unsubscribeURL: msg.UnsubscribeURL,
+	}
+	if loc, err := time.LoadLocation(subscription.Timezone); err == nil {
+		data.Now = data.Now.In(loc)
+	}
+
+	template, err := s.template(ctx, subscription.Owner, msg.Kind)
+	if err != nil {
+		log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to load notification template, using the built-in one")
+		template = defaultTemplates[msg.Kind]
+	}
+	rendered, err := s.renderTemplate(ctx, template, data)
+	if err != nil && template.Custom {
+		log.Warn().Err(err).Str("subscription_id", subscription.ID).Str("template", msg.Kind).Msg("Failed to render notification template, using the built-in one")
+		rendered, err = s.renderTemplate(ctx, defaultTemplates[msg.Kind], data)
+	}
+	return rendered, err
+}
+
+// template returns owner's template of kind name, or the built-in one
+func (s *Service) template(ctx context.Context, owner, name string) (Template, error) {
+	stored, err := s.repo.GetNotificationTemplate(ctx, repo.GetNotificationTemplateParams{Owner: owner, Name: name})
+	if errors.Is(err, errs.ErrNotFound) {
+		return defaultTemplates[name], nil
+	}
+	if err != nil {
+		return Template{}, err
+	}
+	return fromStored(stored), nil
+}
+
+// renderTemplate parses and executes a template with data
+func (s *Service) renderTemplate(ctx context.Context, t Template, data TemplateData) (Rendered, error) {
+	funcs := s.templateFuncs(ctx, data)
+	subject, err := execute(t.Name+".subject", t.Subject, funcs, data)
+	if err != nil {
+		return Rendered{}, err
+	}
+	body, err := execute(t.Name+".body", t.Body, funcs, data)
+	if err != nil {
+		return Rendered{}, err
+	}
+	// Headers and notification titles are a single line
+	subject = strings.Join(strings.Fields(subject), " ")
+	if n := utf8.RuneCountInString(subject); n > maxSubjectChars {
+		subject = string([]rune(subject)[:maxSubjectChars-1]) + "…"
+	}
+	return Rendered{Subject: subject, Body: body}, nil
+}
+
+// templateFuncs are the functions templates can call:
+//
+//	summary  the article's LLM summary, or its description when it has none
+//	image    a preview image URL for the article, "" unless an image service is configured
+//	unsubscribe  the link that unsubscribes the recipient, "" unless a public URL is configured
+//	truncate n s cuts s at the last word before n characters, marked with …
+//	date layout t formats t, in the subscription's time zone, with a Go time layout
+func (s *Service) templateFuncs(ctx context.Context, data TemplateData) template.FuncMap {
+	loc := data.Now.Location()
+	return template.FuncMap{
+		"summary": func(article repo.Article) string {
+			if !data.sample {
+				if summary, err := s.repo.GetArticleSummary(ctx, article.ID); err == nil && summary.LLMSummary != "" {
+					return summary.LLMSummary
+				}
+			}
+			if article.Description != nil {
+				return *article.Description
+			}
+			return ""
+		},
+		"image": func(article repo.Article) string {
+			if s.opts.ImageURL == "" {
+				return ""
+			}
+			return strings.ReplaceAll(s.opts.ImageURL, "{url}", url.QueryEscape(article.URL))
+		},
+		"unsubscribe": func() string {
+			return data.unsubscribeURL
+		},
+		"truncate": truncate,
+		"date": func(layout string, t time.Time) string {
+			return t.In(loc).Format(layout)
+		},
+	}
+}
+
+// execute runs one template source, bounding what it renders
+func execute(name, source string, funcs template.FuncMap, data TemplateData) (string, error) {
+	parsed, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	out := &limitedBuffer{max: maxRenderedBytes}
+	if err := parsed.Execute(out, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// limitedBuffer fails writes past max bytes, stopping runaway templates
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("rendered message exceeds %d bytes", b.max)
+	}
+	return b.Buffer.Write(p)
+}
+
+// truncate cuts s at the last word before n characters, marked with …
+func truncate(n int, s string) string {
+	if n < 1 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	cut := string([]rune(s)[:n])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \t\n.,;:") + "…"
+}
+
+// unsubscribeURL is the link that unsubscribes a subscription's recipient,
+// "" when no public URL is configured
+func (s *Service) unsubscribeURL(subscription repo.Subscription) string {
+	if s.opts.PublicURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/v1/unsubscribe/%s?token=%s",
+		strings.TrimRight(s.opts.PublicURL, "/"), url.PathEscape(subscription.ID), unsubscribeToken(subscription))
+}
+
+// unsubscribeToken authenticates a subscription's unsubscribe link. It is
+// keyed with the subscription's secret, so it can't be forged from the ID.
+func unsubscribeToken(subscription repo.Subscription) string {
+	mac := hmac.New(sha256.New, []byte(subscription.Secret))
+	mac.Write([]byte("unsubscribe:" + subscription.ID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sampleData is what previews, and the check of a new template, render:
+// a digest of two articles, one of them for an alert
+func sampleData(kind string) TemplateData {
+	published := time.Date(2025, time.January, 10, 8, 30, 0, 0, time.UTC)
+	description := "City officials approved a plan on Thursday to add 40 kilometres of protected bike lanes over the next three years, connecting the main rail stations to the business district."
+	lat, lon := 12.9716, 77.5946
+	articles := []repo.Article{
+		{
+			ID:              "00000000-0000-0000-0000-000000000001",
+			Title:           "City approves network of protected bike lanes",
+			Description:     &description,
+			URL:             "https://example.com/news/bike-lanes",
+			PublicationDate: published,
+			SourceName:      "Example Times",
+			Category:        []string{"General"},
+			RelevanceScore:  0.82,
+			Latitude:        &lat,
+			Longitude:       &lon,
+			Language:        "en",
+			City:            "Bengaluru",
+			Region:          "Karnataka",
+			Country:         "IN",
+		},
+		{
+			ID:              "00000000-0000-0000-0000-000000000002",
+			Title:           "Startup raises funding for battery recycling plant",
+			URL:             "https://example.com/news/battery-recycling",
+			PublicationDate: published.Add(-3 * time.Hour),
+			SourceName:      "Example Business",
+			Category:        []string{"Business", "Technology"},
+			RelevanceScore:  0.74,
+			Language:        "en",
+		},
+	}
+
+	schedule := "0 8 * * *"
+	data := TemplateData{
+		Kind: kind,
+		Subscription: Subscription{
+			ID:       "00000000-0000-0000-0000-000000000000",
+			Kind:     repo.SubscriptionDigest,
+			Channel:  repo.ChannelEmail,
+			Target:   "reader@example.com",
+			Schedule: schedule,
+			Timezone: "UTC",
+			Verified: true,
+		},
+		Articles:       articles,
+		Now:            published.Add(30 * time.Minute),
+		unsubscribeURL: "https://example.com/unsubscribe",
+		sample:         true,
+	}
+	switch kind {
+	case repo.DeliveryVerification:
+		data.Subscription.Verified = false
+		data.Token = "0123456789abcdef0123456789abcdef"
+		data.Articles = nil
+	case repo.SubscriptionAlert:
+		data.Subscription.Kind = repo.SubscriptionAlert
+		data.Subscription.Schedule = ""
+		data.Articles = articles[:1]
+	}
+	return data
+}
+
+// fromStored converts an owner's stored template
+func fromStored(stored repo.NotificationTemplate) Template {
+	updatedAt := stored.UpdatedAt
+	return Template{
+		Name:      stored.Name,
+		Subject:   stored.Subject,
+		Body:      stored.Body,
+		Custom:    true,
+		UpdatedAt: &updatedAt,
+	}
+}
+
+// unknownTemplate reports a template name that isn't a message kind
+func unknownTemplate(name string) error {
+	return errs.Errorf(errs.ErrNotFound, "unknown template %q: expected %q, %q or %q", name,
+		repo.DeliveryVerification, repo.SubscriptionDigest, repo.SubscriptionAlert)
+}
@@ -0,0 +1,114 @@
+// Package validation enforces the validate struct tags of request types,
+// reporting each field that fails by its JSON name so clients can point at
+// the offending input.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"news-system/internal/errs"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate is safe for concurrent use and caches each struct's tags
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// FieldError describes one field that failed validation
+type FieldError struct {
+	// Field is the field's JSON name
+	Field string `json:"field"`
+	// Rule is the validate tag it failed, e.g. "max"
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error reports the fields of a request that failed validation. It is an
+// errs.ErrInvalid.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Error) Is(target error) bool { return target == errs.ErrInvalid }
+
+// Struct checks s, a struct or pointer to one, against its validate tags,
+// returning an *Error listing every field that fails them
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	fields := make([]FieldError, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		fields[i] = FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: message(fe)}
+	}
+	return &Error{Fields: fields}
+}
+
+// Details returns the fields err reports as failing validation, if it is or
+// wraps an *Error
+func Details(err error) []FieldError {
+	var validationErr *Error
+	if errors.As(err, &validationErr) {
+		return validationErr.Fields
+	}
+	return nil
+}
+
+// message describes a failed rule in words
+func message(fe validator.FieldError) string {
+	field := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "required_without":
+		return fmt.Sprintf("%s is required unless %s is given", field, strings.ToLower(fe.Param()))
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be %s %s characters long", field, bound, fe.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("%s must have %s %s items", field, bound, fe.Param())
+		default:
+			return fmt.Sprintf("%s must be %s %s", field, bound, fe.Param())
+		}
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "url":
+		return fmt.Sprintf("%s must be a URL", field)
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, fe.Tag())
+	}
+}
@@ -0,0 +1,69 @@
+// Package version reports the build the binary was produced from. The
+// variables are set at build time with
+//
+//	go build -ldflags "-X news-system/internal/version.Version=1.4.0 \
+//	  -X news-system/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X news-system/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Builds without ldflags fall back to the VCS details Go embeds, if any.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version is the release version, e.g. "1.4.0"
+	Version = "dev"
+	// Commit is the git commit the binary was built from
+	Commit = ""
+	// BuildDate is when the binary was built, in RFC 3339
+	BuildDate = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information, filling in the commit and build date
+// from the Go toolchain's VCS stamp when they weren't set with ldflags
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// UserAgent returns the User-Agent sent on outbound requests, e.g.
+// "news-service/1.4.0 (+a1b2c3d4e5f6)"
+func UserAgent() string {
+	info := Get()
+	return "news-service/" + info.Version + " (+" + info.Commit + ")"
+}
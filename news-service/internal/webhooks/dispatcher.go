@@ -0,0 +1,216 @@
+// Package webhooks delivers domain events from the internal bus to external
+// HTTP endpoints, signed with the scheme implemented in pkg/webhook.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"news-system/internal/bus"
+	"news-system/internal/cache"
+	"news-system/internal/metrics"
+	"news-system/internal/version"
+	"news-system/pkg/webhook"
+
+	"github.com/rs/zerolog/log"
+)
+
+// claimTTL is how long an instance's claim on delivering an event to an endpoint is kept
+const claimTTL = 24 * time.Hour
+
+// maxBackoff caps the delay between retries
+const maxBackoff = 5 * time.Minute
+
+// Options configures delivery
+type Options struct {
+	// Secret signs every delivery
+	Secret string
+	// Types limits the delivered event types; empty delivers every type
+	Types []bus.EventType
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts per event before giving up
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles per attempt
+	InitialBackoff time.Duration
+	// BreakerThreshold consecutive failed attempts open an endpoint's circuit for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Dispatcher delivers bus events to webhook endpoints. Each endpoint has its
+// own bus subscription, retry loop and circuit breaker, so a failing endpoint
+// never delays deliveries to the others.
+type Dispatcher struct {
+	bus       *bus.Bus
+	cache     *cache.RedisCache
+	client    *http.Client
+	opts      Options
+	endpoints []*endpoint
+	ctx       context.Context
+	cancel    context.CancelFunc
+	unsubs    []func()
+}
+
+type endpoint struct {
+	id      string
+	url     string
+	breaker *breaker
+}
+
+// NewDispatcher creates a dispatcher for the given endpoint URLs. When cache is
+// set, instances sharing the bus through Redis claim each delivery so an event
+// is sent to an endpoint once rather than once per instance.
+func NewDispatcher(events *bus.Bus, cache *cache.RedisCache, urls []string, opts Options) *Dispatcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = time.Second
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = time.Minute
+	}
+
+	d := &Dispatcher{
+		bus:    events,
+		cache:  cache,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+	}
+	for _, url := range urls {
+		hash := sha1.Sum([]byte(url))
+		d.endpoints = append(d.endpoints, &endpoint{
+			id:      hex.EncodeToString(hash[:6]),
+			url:     url,
+			breaker: newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		})
+	}
+	return d
+}
+
+// Start subscribes every endpoint to the bus
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.ctx, d.cancel = context.WithCancel(ctx)
+	for _, ep := range d.endpoints {
+		unsub := d.bus.Subscribe("webhook:"+ep.id, func(_ context.Context, event bus.Event) {
+			d.deliver(d.ctx, ep, event)
+		}, d.opts.Types...)
+		d.unsubs = append(d.unsubs, unsub)
+	}
+	log.Info().Int("endpoints", len(d.endpoints)).Msg("Webhook dispatcher started")
+}
+
+// Stop unsubscribes the endpoints and abandons pending retries
+func (d *Dispatcher) Stop() {
+	for _, unsub := range d.unsubs {
+		unsub()
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+	log.Info().Msg("Webhook dispatcher stopped")
+}
+
+// deliver sends an event to an endpoint, retrying with exponential backoff
+func (d *Dispatcher) deliver(ctx context.Context, ep *endpoint, event bus.Event) {
+	if !d.claim(ctx, ep, event) {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Str("event", string(event.Type)).Msg("Failed to marshal webhook event")
+		return
+	}
+
+	backoff := d.opts.InitialBackoff
+	for attempt := 1; attempt <= d.opts.MaxAttempts; attempt++ {
+		if !ep.breaker.allow(time.Now()) {
+			metrics.WebhookDeliveries.WithLabelValues(ep.id, "circuit_open").Inc()
+			log.Warn().Str("endpoint", ep.url).Str("event_id", event.ID).Msg("Webhook circuit open, dropping delivery")
+			return
+		}
+
+		retry, err := d.send(ctx, ep, event, body)
+		if err == nil {
+			ep.breaker.success()
+			metrics.WebhookDeliveries.WithLabelValues(ep.id, "delivered").Inc()
+			return
+		}
+		if ep.breaker.failure(time.Now()) {
+			log.Warn().Str("endpoint", ep.url).Dur("cooldown", d.opts.BreakerCooldown).Msg("Webhook circuit opened")
+		}
+		log.Warn().Err(err).Str("endpoint", ep.url).Str("event_id", event.ID).Int("attempt", attempt).Msg("Webhook delivery failed")
+		if !retry || attempt == d.opts.MaxAttempts {
+			break
+		}
+
+		// Full jitter keeps retries from many events from arriving in lockstep
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(backoff)) + 1)):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	metrics.WebhookDeliveries.WithLabelValues(ep.id, "failed").Inc()
+}
+
+// send makes one delivery attempt. It reports whether a failure is worth retrying.
+func (d *Dispatcher) send(ctx context.Context, ep *endpoint, event bus.Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "news-service-webhooks/"+version.Version)
+	req.Header.Set(webhook.EventIDHeader, event.ID)
+	req.Header.Set(webhook.EventTypeHeader, string(event.Type))
+	// Signed per attempt so retries carry a fresh timestamp
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(d.opts.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("endpoint rejected delivery with %d", resp.StatusCode)
+	}
+}
+
+// claim reports whether this instance should deliver event to ep
+func (d *Dispatcher) claim(ctx context.Context, ep *endpoint, event bus.Event) bool {
+	if d.cache == nil {
+		return true
+	}
+	ok, err := d.cache.SetNX(ctx, cache.WebhookClaimKey(ep.id, event.ID), d.bus.Origin(), claimTTL)
+	if err != nil {
+		// Better a duplicate, which receivers drop by event ID, than a lost delivery
+		log.Warn().Err(err).Str("event_id", event.ID).Msg("Failed to claim webhook delivery")
+		return true
+	}
+	return ok
+}
@@ -0,0 +1,19 @@
+-- Deduplicate articles by canonical URL.
+-- url_hash is the hex SHA-256 of the canonical URL (see repo.CanonicalURL) and
+-- is the conflict target for article upserts.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS url_hash TEXT;
+
+-- Backfill from the URL as stored, since SQL can't canonicalize it; 0017
+-- recomputes these hashes with repo.URLHash. Only the most recent row per URL
+-- gets a hash; older duplicates keep NULL so the unique index can be built
+-- without deleting data (and the events/summaries that reference it).
+UPDATE articles a
+SET url_hash = encode(sha256(convert_to(a.url, 'UTF8')), 'hex')
+FROM (
+  SELECT DISTINCT ON (url) id
+  FROM articles
+  ORDER BY url, publication_date DESC, id
+) latest
+WHERE a.id = latest.id AND a.url_hash IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_articles_url_hash ON articles (url_hash);
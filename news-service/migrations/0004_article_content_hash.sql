@@ -0,0 +1,10 @@
+-- Detect unchanged articles on re-ingestion.
+-- content_hash is the hex SHA-256 of title, description and URL joined by
+-- newlines (see repo.ArticleContentHash); the loader skips articles whose
+-- hash matches the stored one.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+UPDATE articles
+SET content_hash = encode(sha256(convert_to(
+    title || E'\n' || COALESCE(description, '') || E'\n' || url, 'UTF8')), 'hex')
+WHERE content_hash IS NULL;
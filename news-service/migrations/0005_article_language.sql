@@ -0,0 +1,7 @@
+-- Multilingual articles.
+-- language is the ISO 639-1 code detected at ingest time (or supplied by the
+-- publisher); '' means it could not be determined. Articles stored before
+-- this migration stay '' until they are re-ingested.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_articles_language ON articles (language);
@@ -0,0 +1,169 @@
+-- Partition articles by publication month and user events by the month they
+-- occurred in.
+-- Queries bounded by occurred_at only touch the months they cover, and lists
+-- ordered by publication_date read the newest partitions first, so hot-path
+-- queries stay fast as the corpus grows. Partitions are created ahead of time
+-- by repo.PartitionMaintainer through create_monthly_partition; rows outside
+-- every monthly partition land in the parent's default partition.
+--
+-- A unique index on a partitioned table must include the partition key, so:
+--  * the primary keys become (id, publication_date) and (id, occurred_at);
+--  * canonical URLs move to article_urls, which is the upsert conflict target
+--    and keeps each URL (and each article id) unique across partitions;
+--  * summaries and user events can no longer reference articles(id), and are
+--    removed by DeleteArticle rather than ON DELETE CASCADE.
+--
+-- Existing rows are copied into the new tables, which holds an exclusive lock
+-- on them for the duration of this migration.
+
+-- create_monthly_partition creates the partition of parent for the calendar
+-- month (in UTC) that month falls in, named <parent>_YYYY_MM. It returns
+-- false without creating anything when the partition already exists, or when
+-- the default partition already holds rows for that month, which would have to
+-- be moved by hand first.
+CREATE OR REPLACE FUNCTION create_monthly_partition(parent regclass, month date)
+RETURNS boolean
+LANGUAGE plpgsql AS $$
+DECLARE
+  parent_name text := parent::text;
+  lower_bound timestamptz := date_trunc('month', month::timestamp) AT TIME ZONE 'UTC';
+  upper_bound timestamptz := (date_trunc('month', month::timestamp) + interval '1 month') AT TIME ZONE 'UTC';
+  partition_name text := parent_name || to_char(month, '"_"YYYY"_"MM');
+  key_column text;
+  stray boolean;
+BEGIN
+  IF to_regclass(partition_name) IS NOT NULL THEN
+    RETURN false;
+  END IF;
+
+  SELECT a.attname INTO key_column
+  FROM pg_partitioned_table p
+  JOIN pg_attribute a ON a.attrelid = p.partrelid AND a.attnum = p.partattrs[0]
+  WHERE p.partrelid = parent;
+  IF key_column IS NULL THEN
+    RAISE EXCEPTION '% is not partitioned', parent_name;
+  END IF;
+
+  IF to_regclass(parent_name || '_default') IS NOT NULL THEN
+    EXECUTE format('SELECT EXISTS (SELECT 1 FROM %I WHERE %I >= $1 AND %I < $2)',
+                   parent_name || '_default', key_column, key_column)
+      INTO stray USING lower_bound, upper_bound;
+    IF stray THEN
+      RAISE WARNING '%_default holds rows for %; not creating %', parent_name, to_char(month, 'YYYY-MM'), partition_name;
+      RETURN false;
+    END IF;
+  END IF;
+
+  EXECUTE format('CREATE TABLE %I PARTITION OF %s FOR VALUES FROM (%L) TO (%L)',
+                 partition_name, parent_name, lower_bound, upper_bound);
+  RETURN true;
+END $$;
+
+-- Summaries and events stop referencing articles(id)
+ALTER TABLE article_summaries DROP CONSTRAINT IF EXISTS article_summaries_article_id_fkey;
+ALTER TABLE user_events DROP CONSTRAINT IF EXISTS user_events_article_id_fkey;
+
+-- Move the unpartitioned tables out of the way; their indexes are dropped
+-- with them below, after the copy
+ALTER TABLE articles RENAME TO articles_unpartitioned;
+ALTER INDEX articles_pkey RENAME TO articles_unpartitioned_pkey;
+ALTER TABLE user_events RENAME TO user_events_unpartitioned;
+ALTER INDEX user_events_pkey RENAME TO user_events_unpartitioned_pkey;
+ALTER SEQUENCE user_events_id_seq OWNED BY NONE;
+
+CREATE TABLE articles (
+  id               UUID NOT NULL,
+  title            TEXT NOT NULL,
+  description      TEXT,
+  url              TEXT NOT NULL,
+  publication_date TIMESTAMPTZ NOT NULL,
+  source_name      TEXT NOT NULL,
+  category         TEXT[] NOT NULL,
+  relevance_score  DOUBLE PRECISION NOT NULL,
+  latitude         DOUBLE PRECISION,
+  longitude        DOUBLE PRECISION,
+  tsv              tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+  ) STORED,
+  url_hash         TEXT,
+  content_hash     TEXT,
+  language         TEXT NOT NULL DEFAULT '',
+  PRIMARY KEY (id, publication_date)
+) PARTITION BY RANGE (publication_date);
+
+CREATE TABLE articles_default PARTITION OF articles DEFAULT;
+
+CREATE TABLE user_events (
+  id           BIGINT NOT NULL DEFAULT nextval('user_events_id_seq'),
+  article_id   UUID NOT NULL,
+  event        event_type NOT NULL,
+  occurred_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  user_lat     DOUBLE PRECISION,
+  user_lon     DOUBLE PRECISION,
+  PRIMARY KEY (id, occurred_at)
+) PARTITION BY RANGE (occurred_at);
+
+ALTER SEQUENCE user_events_id_seq OWNED BY user_events.id;
+
+CREATE TABLE user_events_default PARTITION OF user_events DEFAULT;
+
+-- Monthly partitions for the last two years of existing rows and the next
+-- three months; anything older stays in the default partition
+DO $$
+DECLARE
+  first_month date;
+  month date;
+BEGIN
+  SELECT date_trunc('month', GREATEST(
+           LEAST(
+             COALESCE((SELECT min(publication_date) FROM articles_unpartitioned), now()),
+             COALESCE((SELECT min(occurred_at) FROM user_events_unpartitioned), now())),
+           now() - interval '2 years') AT TIME ZONE 'UTC')::date
+    INTO first_month;
+  month := first_month;
+  WHILE month <= (date_trunc('month', now() AT TIME ZONE 'UTC') + interval '3 months')::date LOOP
+    PERFORM create_monthly_partition('articles', month);
+    PERFORM create_monthly_partition('user_events', month);
+    month := (month + interval '1 month')::date;
+  END LOOP;
+END $$;
+
+INSERT INTO articles (
+  id, title, description, url, publication_date, source_name, category,
+  relevance_score, latitude, longitude, url_hash, content_hash, language
+)
+SELECT id, title, description, url, publication_date, source_name, category,
+  relevance_score, latitude, longitude, url_hash, content_hash, language
+FROM articles_unpartitioned;
+
+INSERT INTO user_events (id, article_id, event, occurred_at, user_lat, user_lon)
+SELECT id, article_id, event, occurred_at, user_lat, user_lon
+FROM user_events_unpartitioned;
+
+-- Canonical URLs, unique across partitions. Legacy duplicates whose url_hash
+-- was left NULL by 0003 have no entry, as before.
+CREATE TABLE article_urls (
+  url_hash   TEXT PRIMARY KEY,
+  article_id UUID NOT NULL UNIQUE
+);
+
+INSERT INTO article_urls (url_hash, article_id)
+SELECT url_hash, id FROM articles WHERE url_hash IS NOT NULL;
+
+DROP TABLE articles_unpartitioned;
+DROP TABLE user_events_unpartitioned;
+
+-- Indexes on the parents are created on every partition, current and future
+CREATE INDEX IF NOT EXISTS idx_articles_pubdate_desc ON articles (publication_date DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_source ON articles (source_name);
+CREATE INDEX IF NOT EXISTS idx_articles_category_gin ON articles USING GIN (category);
+CREATE INDEX IF NOT EXISTS idx_articles_tsv_gin ON articles USING GIN (tsv);
+CREATE INDEX IF NOT EXISTS idx_articles_title_trgm ON articles USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_articles_relevance_score ON articles (relevance_score DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_url_hash ON articles (url_hash);
+CREATE INDEX IF NOT EXISTS idx_articles_language ON articles (language);
+
+CREATE INDEX IF NOT EXISTS idx_user_events_article_time ON user_events (article_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_events_time ON user_events (occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_events_location ON user_events (user_lat, user_lon) WHERE user_lat IS NOT NULL AND user_lon IS NOT NULL;
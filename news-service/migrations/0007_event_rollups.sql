@@ -0,0 +1,26 @@
+-- Hourly rollups of user events.
+-- repo.EventDownsampler folds each hour of raw user_events into one row per
+-- article, tile (geohash), and event type, with the centroid of the readers'
+-- locations, then deletes raw events past their retention. Rollups are kept
+-- longer and serve trending and per-article event counts for every hour they
+-- cover; raw events only for the hours after the watermark.
+CREATE TABLE IF NOT EXISTS user_event_rollups (
+  bucket      TIMESTAMPTZ NOT NULL,
+  article_id  UUID NOT NULL,
+  -- '' for events recorded without a location
+  geohash     TEXT NOT NULL,
+  event       event_type NOT NULL,
+  count       BIGINT NOT NULL,
+  user_lat    DOUBLE PRECISION,
+  user_lon    DOUBLE PRECISION,
+  PRIMARY KEY (bucket, article_id, geohash, event)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_event_rollups_article_bucket ON user_event_rollups (article_id, bucket DESC);
+
+-- rolled_up_to is the end of the last hour rolled up: events before it are
+-- counted from user_event_rollups, events from it on from user_events
+CREATE TABLE IF NOT EXISTS user_event_rollup_watermark (
+  id           BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+  rolled_up_to TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,24 @@
+-- Article embeddings for the semantic search strategy, written by the
+-- embedding backfill. Requires the pgvector extension (the pgvector/pgvector
+-- images ship it).
+--
+-- Each article keeps one embedding, from the model named in model; switching
+-- LLM_EMBEDDING_MODEL makes the backfill replace them all. content_hash is the
+-- article's content hash when it was embedded, so articles whose title,
+-- description or URL changed since are embedded again.
+--
+-- The vector column has no fixed dimension, as it depends on the model, so it
+-- can't carry an approximate nearest neighbour index: semantic search scans
+-- the embeddings of the model. Once the model is settled, an HNSW index on
+-- (embedding::vector(<dimensions>)) with vector_cosine_ops can be added.
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS article_embeddings (
+  article_id   UUID PRIMARY KEY,
+  model        TEXT NOT NULL,
+  content_hash TEXT NOT NULL,
+  embedding    vector NOT NULL,
+  embedded_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_embeddings_model ON article_embeddings (model);
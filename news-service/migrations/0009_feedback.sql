@@ -0,0 +1,21 @@
+-- Reader feedback: a thumbs up or down on an article's summary or on its
+-- relevance as a result, with the query it was answering and an optional
+-- comment. summary_model is the model of the summary rated, so feedback can
+-- be compared across models; feedback given before a summary was regenerated
+-- no longer counts against it.
+--
+-- articles is partitioned, so article_id can't reference it; DeleteArticle
+-- removes an article's feedback with it.
+CREATE TABLE IF NOT EXISTS feedback (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  article_id    UUID NOT NULL,
+  target        TEXT NOT NULL CHECK (target IN ('summary', 'result')),
+  rating        TEXT NOT NULL CHECK (rating IN ('up', 'down')),
+  query         TEXT,
+  comment       TEXT,
+  summary_model TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_article_created ON feedback (article_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_feedback_created ON feedback (created_at);
@@ -0,0 +1,9 @@
+-- Article sentiment, judged by the LLM when the article is summarized and
+-- stored with its summary: a label and a score from -1 (most negative) to 1
+-- (most positive). Summaries generated before this migration have none until
+-- they are regenerated, so a sentiment filter leaves their articles out.
+ALTER TABLE article_summaries
+  ADD COLUMN IF NOT EXISTS sentiment TEXT CHECK (sentiment IN ('positive', 'negative', 'neutral')),
+  ADD COLUMN IF NOT EXISTS sentiment_score DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_article_summaries_sentiment ON article_summaries (sentiment, article_id);
@@ -0,0 +1,25 @@
+-- Named entities mentioned by each article (people, organizations and
+-- locations), extracted by the LLM in the background and kept until the
+-- article is deleted.
+--
+-- article_entity_extractions records that an article was processed and the
+-- content hash it had then, so articles whose title, description or URL
+-- changed since are extracted again; it has a row even for articles that
+-- mention no entity. article_entities holds one row per entity, with its name
+-- as the model spelled it and normalized (lowercased, single-spaced) for the
+-- entity filter of the list and search queries.
+CREATE TABLE IF NOT EXISTS article_entity_extractions (
+  article_id   UUID PRIMARY KEY,
+  content_hash TEXT NOT NULL,
+  extracted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS article_entities (
+  article_id  UUID NOT NULL,
+  entity_type TEXT NOT NULL CHECK (entity_type IN ('person', 'organization', 'location')),
+  name        TEXT NOT NULL,
+  normalized  TEXT NOT NULL,
+  PRIMARY KEY (article_id, entity_type, normalized)
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_entities_normalized ON article_entities (normalized, article_id);
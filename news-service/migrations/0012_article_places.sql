@@ -0,0 +1,11 @@
+-- Place names of articles with coordinates.
+-- city, region and country are reverse geocoded from latitude and longitude
+-- at ingest time; country is an ISO 3166-1 alpha-2 code such as 'IN'. '' means
+-- the article has no coordinates, the lookup failed, or the place has no such
+-- area. Articles stored before this migration stay '' until they are
+-- re-ingested.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS city TEXT NOT NULL DEFAULT '';
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_articles_country ON articles (country);
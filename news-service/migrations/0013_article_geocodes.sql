@@ -0,0 +1,10 @@
+-- Coordinates each article was last reverse geocoded at by the place
+-- backfill, so articles whose location has no place (e.g. at sea) aren't
+-- looked up again until their coordinates change. Kept until the article is
+-- deleted.
+CREATE TABLE IF NOT EXISTS article_geocodes (
+  article_id  UUID PRIMARY KEY,
+  latitude    DOUBLE PRECISION NOT NULL,
+  longitude   DOUBLE PRECISION NOT NULL,
+  geocoded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
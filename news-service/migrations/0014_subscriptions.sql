@@ -0,0 +1,48 @@
+-- Subscriptions deliver articles to a reader's email address, webhook
+-- endpoint or push device: as a digest on a schedule, or as an alert when a
+-- matching article is stored. Each belongs to the API key that created it,
+-- identified by owner, a hash of the key. An email address or webhook
+-- endpoint receives nothing but its verification token until the token is
+-- confirmed; verification_hash is the hash of the token sent last.
+CREATE TABLE IF NOT EXISTS subscriptions (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner             TEXT NOT NULL,
+  kind              TEXT NOT NULL CHECK (kind IN ('digest', 'alert')),
+  channel           TEXT NOT NULL CHECK (channel IN ('email', 'webhook', 'push')),
+  target            TEXT NOT NULL,
+  query             TEXT,
+  category          TEXT,
+  language          TEXT,
+  country           TEXT,
+  latitude          DOUBLE PRECISION,
+  longitude         DOUBLE PRECISION,
+  radius_km         DOUBLE PRECISION,
+  schedule          TEXT,
+  timezone          TEXT NOT NULL DEFAULT 'UTC',
+  calendar          TEXT,
+  secret            TEXT NOT NULL,
+  verification_hash TEXT,
+  verified_at       TIMESTAMPTZ,
+  next_run_at       TIMESTAMPTZ,
+  last_run_at       TIMESTAMPTZ,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_owner_created ON subscriptions (owner, created_at);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_due ON subscriptions (next_run_at)
+  WHERE kind = 'digest' AND verified_at IS NOT NULL;
+
+-- Every message sent for a subscription, delivered or not, deleted with it
+CREATE TABLE IF NOT EXISTS subscription_deliveries (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+  kind            TEXT NOT NULL CHECK (kind IN ('verification', 'digest', 'alert')),
+  status          TEXT NOT NULL CHECK (status IN ('delivered', 'failed')),
+  articles        INTEGER NOT NULL DEFAULT 0,
+  error           TEXT,
+  delivered_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_deliveries_subscription
+  ON subscription_deliveries (subscription_id, delivered_at);
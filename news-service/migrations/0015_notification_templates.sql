@@ -0,0 +1,11 @@
+-- Notification templates a subscription owner has overridden, by message
+-- kind; kinds without a row use the built-in template
+CREATE TABLE IF NOT EXISTS notification_templates (
+  owner      TEXT NOT NULL,
+  name       TEXT NOT NULL CHECK (name IN ('verification', 'digest', 'alert')),
+  subject    TEXT NOT NULL,
+  body       TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (owner, name)
+);
@@ -0,0 +1,24 @@
+-- Every attempt to send a subscription message is recorded, with the channel
+-- it went through, how long it took and what the endpoint answered, so that
+-- operators can follow deliveries across all subscriptions.
+ALTER TABLE subscription_deliveries ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscription_deliveries ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE subscription_deliveries ADD COLUMN IF NOT EXISTS latency_ms INTEGER;
+ALTER TABLE subscription_deliveries ADD COLUMN IF NOT EXISTS response TEXT;
+
+UPDATE subscription_deliveries d SET channel = s.channel
+FROM subscriptions s
+WHERE d.subscription_id = s.id AND d.channel = '';
+
+CREATE INDEX IF NOT EXISTS idx_subscription_deliveries_delivered
+  ON subscription_deliveries (delivered_at);
+
+-- A subscription whose messages keep failing is disabled until it is enabled
+-- again; failures counts the messages that failed in a row.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS disabled_reason TEXT;
+
+DROP INDEX IF EXISTS idx_subscriptions_due;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_due ON subscriptions (next_run_at)
+  WHERE kind = 'digest' AND verified_at IS NOT NULL AND disabled_at IS NULL;
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"news-system/internal/migrate"
+	"news-system/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rehashBatchSize is the number of articles rewritten per statement
+const rehashBatchSize = 5000
+
+func init() {
+	migrate.Register(17, "0017_canonical_url_hash.go", rehashArticleURLs)
+}
+
+// rehashArticleURLs recomputes url_hash with repo.URLHash. 0003 backfilled it
+// from the URL as stored, which SQL can't canonicalize, so legacy rows didn't
+// match re-ingested copies of the same article. As in 0003, the most recent
+// article per canonical URL claims it and older duplicates keep NULL.
+func rehashArticleURLs(ctx context.Context, tx pgx.Tx) error {
+	rows, err := tx.Query(ctx, "SELECT id::text, url FROM articles ORDER BY publication_date DESC, id")
+	if err != nil {
+		return fmt.Errorf("failed to read article URLs: %w", err)
+	}
+	var ids, hashes []string
+	claimed := make(map[string]bool)
+	for rows.Next() {
+		var id, url string
+		if err := rows.Scan(&id, &url); err != nil {
+			rows.Close()
+			return err
+		}
+		if hash := repo.URLHash(url); !claimed[hash] {
+			claimed[hash] = true
+			ids = append(ids, id)
+			hashes = append(hashes, hash)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read article URLs: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM article_urls"); err != nil {
+		return fmt.Errorf("failed to clear article URLs: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE articles SET url_hash = NULL WHERE url_hash IS NOT NULL"); err != nil {
+		return fmt.Errorf("failed to clear article URL hashes: %w", err)
+	}
+	for start := 0; start < len(ids); start += rehashBatchSize {
+		end := min(start+rehashBatchSize, len(ids))
+		batchIDs, batchHashes := ids[start:end], hashes[start:end]
+		if _, err := tx.Exec(ctx, `
+UPDATE articles a SET url_hash = v.url_hash
+FROM unnest($1::text[], $2::text[]) AS v(id, url_hash)
+WHERE a.id = v.id::uuid`, batchIDs, batchHashes); err != nil {
+			return fmt.Errorf("failed to store article URL hashes: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+INSERT INTO article_urls (url_hash, article_id)
+SELECT url_hash, id::uuid FROM unnest($1::text[], $2::text[]) AS v(id, url_hash)`, batchIDs, batchHashes); err != nil {
+			return fmt.Errorf("failed to store article URLs: %w", err)
+		}
+	}
+	return nil
+}
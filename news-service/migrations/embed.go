@@ -0,0 +1,10 @@
+// Package migrations embeds the versioned SQL schema migrations so they ship
+// inside the binary. Files are named NNNN_description.sql and applied in order.
+// Data migrations SQL can't express are Go files named the same way, which
+// register themselves with migrate.Register.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS